@@ -0,0 +1,180 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Activator listens on a host socket for a container and starts it on the
+// first connection, proxying the connections to the service inside once it
+// accepts them. Together with WatchIdle shutting idle containers down it
+// scales LXC services to zero.
+type Activator struct {
+	c        *Container
+	opts     ActivatorOptions
+	listener net.Listener
+
+	// mu serializes starting the container
+	mu sync.Mutex
+
+	conns sync.WaitGroup
+	once  sync.Once
+}
+
+// NewActivator listens on the socket of the options for the container.
+// Serve accepts the connections.
+func NewActivator(c *Container, opts ActivatorOptions) (*Activator, error) {
+	if opts.Target == "" && opts.TargetPort == 0 {
+		return nil, fmt.Errorf("%s: no target", ErrActivationFailed)
+	}
+
+	listener, err := net.Listen(opts.Network, opts.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Activator{c: c, opts: opts, listener: listener}, nil
+}
+
+// Addr returns the address of the host socket.
+func (a *Activator) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+// activate starts or unfreezes the container if needed.
+func (a *Activator) activate(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.c.EnsureState(ctx, RUNNING)
+}
+
+// targetAddr returns the first IPv4 address of the container, the first
+// address if it has none, and the target port.
+func targetAddr(addrs []netip.Addr, port uint16) (string, error) {
+	if len(addrs) == 0 {
+		return "", ErrIPAddresses
+	}
+
+	addr := addrs[0]
+	for _, a := range addrs {
+		if a.Is4() {
+			addr = a
+			break
+		}
+	}
+	return net.JoinHostPort(addr.String(), strconv.Itoa(int(port))), nil
+}
+
+// dial connects to the service, retrying until it accepts connections or
+// the context is done.
+func (a *Activator) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+
+	for {
+		target := a.opts.Target
+		if target == "" {
+			deadline, _ := ctx.Deadline()
+			addrs, err := a.c.WaitIPAddrs(time.Until(deadline))
+			if err != nil {
+				return nil, err
+			}
+
+			if target, err = targetAddr(addrs, a.opts.TargetPort); err != nil {
+				return nil, err
+			}
+		}
+
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err == nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %v", ErrActivationFailed, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// proxy activates the container and copies between the connection and the
+// service until either side closes.
+func (a *Activator) proxy(conn net.Conn) {
+	defer a.conns.Done()
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.Timeout)
+	defer cancel()
+
+	if err := a.activate(ctx); err != nil {
+		return
+	}
+
+	upstream, err := a.dial(ctx)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src)
+		// pass the half close on
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	<-done
+	<-done
+}
+
+// Serve accepts connections until the activator is closed, starting the
+// container on the first one. Connections waiting longer than the timeout
+// of the options for the service are closed.
+func (a *Activator) Serve() error {
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		a.conns.Add(1)
+		go a.proxy(conn)
+	}
+}
+
+// Close stops listening, the connections being proxied keep going until
+// they are closed. Wait waits for them.
+func (a *Activator) Close() error {
+	var err error
+	a.once.Do(func() {
+		err = a.listener.Close()
+	})
+	return err
+}
+
+// Wait waits until the connections being proxied are closed.
+func (a *Activator) Wait() {
+	a.conns.Wait()
+}
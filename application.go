@@ -0,0 +1,156 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// applicationConfigItems returns the minimal configuration of an application
+// container: no init system, no console and ttys, the API filesystems and a
+// private /dev.
+func applicationConfigItems(name string, rootfs string, cmd []string, opts ApplicationOptions) ([]KeyValue, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("%s: %s", ErrSettingConfigItemFailed, "the command is empty")
+	}
+
+	init, err := containerInitItems(ContainerInit{
+		Cmd: cmd,
+		UID: opts.UID,
+		GID: opts.GID,
+		Cwd: opts.Cwd,
+		Env: opts.Env,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := []KeyValue{
+		{"lxc.uts.name", name},
+		{"lxc.rootfs.path", "dir:" + rootfs},
+		{"lxc.autodev", "1"},
+		{"lxc.mount.auto", "proc:mixed sys:mixed cgroup:mixed"},
+		{"lxc.mount.entry", "tmpfs dev/shm tmpfs rw,nosuid,nodev,create=dir 0 0"},
+		{"lxc.console.path", "none"},
+		{"lxc.tty.max", "0"},
+		{"lxc.cap.drop", "mac_admin mac_override sys_module sys_time"},
+	}
+
+	if opts.ReadOnly {
+		items = append(items, KeyValue{"lxc.rootfs.options", "ro"})
+	}
+
+	for _, mount := range opts.Mounts {
+		items = append(items, KeyValue{"lxc.mount.entry", mount})
+	}
+
+	if opts.Bridge != "" {
+		items = append(items,
+			KeyValue{"lxc.net.0.type", "veth"},
+			KeyValue{"lxc.net.0.link", opts.Bridge},
+			KeyValue{"lxc.net.0.flags", "up"},
+		)
+	} else {
+		items = append(items, KeyValue{"lxc.net.0.type", "empty"})
+	}
+
+	for _, kv := range init {
+		if kv.Value != "" {
+			items = append(items, kv)
+		}
+	}
+	return items, nil
+}
+
+// NewApplicationContainer defines a container running cmd from the
+// directory rootfs instead of an init system, e.g. to run a single service
+// like runc would. It writes a minimal configuration, see
+// ApplicationOptions for the optional settings. The container is started
+// with Start and stops once cmd exits.
+// Caller needs to call Release() on the returned container to release resources.
+func NewApplicationContainer(name string, rootfs string, cmd []string, opts ...ApplicationOptions) (*Container, error) {
+	var options ApplicationOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return nil, ErrNotSupported
+	}
+
+	rootfs, err := filepath.Abs(rootfs)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(rootfs); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s: %q is not a directory", ErrNewFailed, rootfs)
+	}
+
+	if options.Owner != "" && !validOwner(options.Owner) {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidOwner, options.Owner)
+	}
+
+	items, err := applicationConfigItems(name, rootfs, cmd, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *Container
+	if options.LXCPath != "" {
+		c, err = NewContainer(name, options.LXCPath)
+	} else {
+		c, err = NewContainer(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Defined() {
+		c.Release()
+		return nil, fmt.Errorf("%s: %q", ErrAlreadyDefined, name)
+	}
+
+	dir := filepath.Join(c.ConfigPath(), name)
+	_, err = os.Stat(dir)
+	created := os.IsNotExist(err)
+
+	fail := func(err error) (*Container, error) {
+		if created {
+			os.RemoveAll(dir)
+		}
+		c.Release()
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fail(err)
+	}
+
+	for _, kv := range items {
+		if err := c.SetConfigItem(kv.Key, kv.Value); err != nil {
+			return fail(fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value))
+		}
+	}
+
+	if err := c.SaveConfigFile(filepath.Join(dir, "config")); err != nil {
+		return fail(err)
+	}
+
+	if _, err := assignUUID(c.ConfigPath(), name); err != nil {
+		return fail(err)
+	}
+
+	if err := writeOwner(c.ConfigPath(), name, options.Owner); err != nil {
+		return fail(err)
+	}
+	return c, nil
+}
@@ -0,0 +1,145 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultAttachPath is a PATH matching the layout of common distributions, to
+// be used as AttachOptions.Path.
+const DefaultAttachPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// matchEnv returns the names of the variables of environ matching any of
+// the glob patterns, in the order of environ.
+func matchEnv(environ []string, patterns []string) []string {
+	var names []string
+
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// attachEnv applies EnvToKeepGlob and Path to the environment options.
+func attachEnv(options AttachOptions, environ []string) AttachOptions {
+	if len(options.EnvToKeepGlob) > 0 {
+		keep := append([]string{}, options.EnvToKeep...)
+		options.EnvToKeep = append(keep, matchEnv(environ, options.EnvToKeepGlob)...)
+	}
+
+	if options.Path != "" {
+		for _, kv := range options.Env {
+			if strings.HasPrefix(kv, "PATH=") {
+				return options
+			}
+		}
+		options.Env = append(append([]string{}, options.Env...), "PATH="+options.Path)
+	}
+	return options
+}
+
+// resolveAttachOptions resolves the options liblxc has no notion of into
+// the ones it understands.
+//
+// Caller needs to hold the lock
+func (c *Container) resolveAttachOptions(options AttachOptions) (AttachOptions, error) {
+	options, err := c.resolveAttachUser(options)
+	if err != nil {
+		return options, err
+	}
+
+	if options.CloseInheritedFds {
+		if err := closeInheritedFds(options.StdinFd, options.StdoutFd, options.StderrFd); err != nil {
+			return options, err
+		}
+	}
+
+	return attachEnv(options, os.Environ()), nil
+}
+
+// inheritableFds returns the fds listed in dir which aren't close-on-exec,
+// except stdin, stdout and stderr.
+func inheritableFds(dir string) ([]int, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fds []int
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil || fd <= 2 {
+			continue
+		}
+
+		// the fd of the directory is closed by now
+		flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0)
+		if err != nil {
+			continue
+		}
+
+		if flags&unix.FD_CLOEXEC == 0 {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, nil
+}
+
+// InheritableFds returns the fds of the calling process which processes
+// started through Attach, RunCommand and friends inherit, e.g. the log file
+// liblxc opened. Stdin, stdout and stderr aren't reported.
+func InheritableFds() ([]int, error) {
+	return inheritableFds("/proc/self/fd")
+}
+
+// closeInheritedFds marks the inheritable fds close-on-exec, except the
+// ones given.
+func closeInheritedFds(keep ...uintptr) error {
+	fds, err := InheritableFds()
+	if err != nil {
+		return err
+	}
+
+fds:
+	for _, fd := range fds {
+		for _, k := range keep {
+			if uintptr(fd) == k {
+				continue fds
+			}
+		}
+		unix.CloseOnExec(fd)
+	}
+	return nil
+}
+
+// appArmorEnabled returns true if the host confines processes with AppArmor.
+func appArmorEnabled() bool {
+	content, err := ioutil.ReadFile("/sys/module/apparmor/parameters/enabled")
+	return err == nil && strings.TrimSpace(string(content)) == "Y"
+}
+
+// attachLSMLabel returns the label of the security module of the host the
+// command should run under, empty to keep the one of the container.
+func attachLSMLabel(options AttachOptions, apparmor bool) string {
+	if options.AppArmorProfile != "" && (apparmor || options.SELinuxLabel == "") {
+		return options.AppArmorProfile
+	}
+	return options.SELinuxLabel
+}
@@ -0,0 +1,172 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a mutating operation on a container.
+type AuditRecord struct {
+	Time      time.Time     `json:"time"`
+	Operation string        `json:"operation"`
+	Container string        `json:"container"`
+	LXCPath   string        `json:"lxcpath"`
+	Args      []string      `json:"args,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+
+	// Caller metadata: the calling process and the file:line the
+	// operation was invoked from.
+	UID    int    `json:"uid"`
+	PID    int    `json:"pid"`
+	Caller string `json:"caller,omitempty"`
+}
+
+// AuditSink receives the records of the audit log.
+type AuditSink interface {
+	Record(record AuditRecord) error
+}
+
+// AuditFunc is an AuditSink calling the function for each record.
+type AuditFunc func(record AuditRecord) error
+
+// Record calls f(record).
+func (f AuditFunc) Record(record AuditRecord) error {
+	return f(record)
+}
+
+// auditWriter writes the records as JSON lines.
+type auditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAuditWriter returns an AuditSink writing one JSON object per record to w.
+func NewAuditWriter(w io.Writer) AuditSink {
+	return &auditWriter{w: w}
+}
+
+func (a *auditWriter) Record(record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.w.Write(append(data, '\n'))
+	return err
+}
+
+// AuditFile is an AuditSink appending JSON lines to a file.
+type AuditFile struct {
+	auditWriter
+	f *os.File
+}
+
+// OpenAuditFile opens (or creates) the file at path for appending records.
+func OpenAuditFile(path string) (*AuditFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditFile{auditWriter: auditWriter{w: f}, f: f}, nil
+}
+
+// Record appends the record and syncs it to disk.
+func (a *AuditFile) Record(record AuditRecord) error {
+	if err := a.auditWriter.Record(record); err != nil {
+		return err
+	}
+	return a.f.Sync()
+}
+
+// Close closes the file.
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.f.Close()
+}
+
+var (
+	auditMu   sync.RWMutex
+	auditSink AuditSink
+)
+
+// SetAuditSink enables the audit log, every mutating operation (Create,
+// Start, Stop, SetConfigItem, ...) is recorded into sink once it finished.
+// A nil sink disables it again, which is the default. Failing to record
+// doesn't change the result of the operation.
+func SetAuditSink(sink AuditSink) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	auditSink = sink
+}
+
+// operation starts the named operation, asking the interceptor whether it may
+// be executed. Unless it's denied the returned function records its result
+// and is meant to be deferred:
+//
+//	finish, err := c.operation("Start")
+//	if err != nil {
+//		return err
+//	}
+//	defer finish(&err)
+//
+// The lock must not be held.
+func (c *Container) operation(name string, args ...string) (func(err *error), error) {
+	auditMu.RLock()
+	sink := auditSink
+	auditMu.RUnlock()
+
+	var record AuditRecord
+	if sink != nil {
+		record = AuditRecord{
+			Time:      time.Now(),
+			Operation: name,
+			Container: c.Name(),
+			LXCPath:   c.ConfigPath(),
+			Args:      args,
+			UID:       os.Getuid(),
+			PID:       os.Getpid(),
+		}
+
+		// skip operation and the method calling it
+		if _, file, line, ok := runtime.Caller(2); ok {
+			record.Caller = fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+
+	finish := func(err *error) {
+		if sink == nil {
+			return
+		}
+
+		record.Duration = time.Since(record.Time)
+		if err != nil && *err != nil {
+			record.Error = (*err).Error()
+		}
+		sink.Record(record)
+	}
+
+	if err := c.intercept(name, args); err != nil {
+		finish(&err)
+		return nil, err
+	}
+	return finish, nil
+}
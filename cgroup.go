@@ -0,0 +1,619 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// detectCgroupUnified returns true if /sys/fs/cgroup is a cgroup2 mount.
+func detectCgroupUnified() bool {
+	var fs unix.Statfs_t
+	if err := unix.Statfs("/sys/fs/cgroup", &fs); err != nil {
+		return false
+	}
+	return fs.Type == unix.CGROUP2_SUPER_MAGIC
+}
+
+// CgroupUnified returns true if the host runs the pure cgroup v2 (unified)
+// hierarchy.
+func CgroupUnified() bool {
+	return RuntimeInfo().CgroupUnified
+}
+
+// cgroupItemAsLimit parses a cgroup v2 limit, mapping "max" to the largest
+// possible value.
+//
+// Caller needs to hold the lock
+func (c *Container) cgroupItemAsLimit(filename string, missing error) (ByteSize, error) {
+	value := c.cgroupItem(filename)[0]
+	if value == "max" {
+		return ByteSize(math.MaxInt64), nil
+	}
+
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return -1, missing
+	}
+	return ByteSize(size), nil
+}
+
+// SwapUsage returns the swap usage of the container in bytes.
+func (c *Container) SwapUsage() (ByteSize, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return -1, err
+	}
+
+	if CgroupUnified() {
+		return c.cgroupItemAsByteSize("memory.swap.current", ErrMemorySwapLimit)
+	}
+
+	memsw, err := c.cgroupItemAsByteSize("memory.memsw.usage_in_bytes", ErrMemorySwapLimit)
+	if err != nil {
+		return -1, err
+	}
+
+	mem, err := c.cgroupItemAsByteSize("memory.usage_in_bytes", ErrMemLimit)
+	if err != nil {
+		return -1, err
+	}
+	return memsw - mem, nil
+}
+
+// SwapLimit returns the swap limit of the container in bytes.
+func (c *Container) SwapLimit() (ByteSize, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return -1, err
+	}
+
+	if CgroupUnified() {
+		return c.cgroupItemAsLimit("memory.swap.max", ErrMemorySwapLimit)
+	}
+
+	memsw, err := c.cgroupItemAsByteSize("memory.memsw.limit_in_bytes", ErrMemorySwapLimit)
+	if err != nil {
+		return -1, err
+	}
+
+	mem, err := c.cgroupItemAsByteSize("memory.limit_in_bytes", ErrMemLimit)
+	if err != nil {
+		return -1, err
+	}
+	return memsw - mem, nil
+}
+
+// SetSwapLimit sets the swap limit of the container in bytes. On cgroup v1
+// hosts it sets memory.memsw.limit_in_bytes to the memory limit plus limit.
+func (c *Container) SetSwapLimit(limit ByteSize) (err error) {
+	finish, err := c.operation("SetSwapLimit", limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return err
+	}
+
+	if CgroupUnified() {
+		return c.setCgroupItemWithByteSize("memory.swap.max", limit, ErrSettingMemorySwapLimitFailed)
+	}
+
+	mem, err := c.cgroupItemAsByteSize("memory.limit_in_bytes", ErrMemLimit)
+	if err != nil {
+		return err
+	}
+	return c.setCgroupItemWithByteSize("memory.memsw.limit_in_bytes", mem+limit, ErrSettingMemorySwapLimitFailed)
+}
+
+// hugePageSizeName returns the name the kernel uses for the given huge page
+// size in hugetlb cgroup file names, e.g. "2MB" or "1GB".
+func hugePageSizeName(pageSize ByteSize) string {
+	switch {
+	case pageSize >= GB:
+		return fmt.Sprintf("%.fGB", pageSize/GB)
+	case pageSize >= MB:
+		return fmt.Sprintf("%.fMB", pageSize/MB)
+	}
+	return fmt.Sprintf("%.fKB", pageSize/KB)
+}
+
+// hugeTLBKey returns the hugetlb cgroup file for the given page size.
+func hugeTLBKey(pageSize ByteSize, v1, v2 string) string {
+	if CgroupUnified() {
+		return fmt.Sprintf("hugetlb.%s.%s", hugePageSizeName(pageSize), v2)
+	}
+	return fmt.Sprintf("hugetlb.%s.%s", hugePageSizeName(pageSize), v1)
+}
+
+// HugeTLBUsage returns the huge page usage of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) HugeTLBUsage(pageSize ByteSize) (ByteSize, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return -1, err
+	}
+
+	return c.cgroupItemAsByteSize(hugeTLBKey(pageSize, "usage_in_bytes", "current"), ErrHugeTLBLimit)
+}
+
+// HugeTLBLimit returns the huge page limit of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) HugeTLBLimit(pageSize ByteSize) (ByteSize, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return -1, err
+	}
+
+	return c.cgroupItemAsLimit(hugeTLBKey(pageSize, "limit_in_bytes", "max"), ErrHugeTLBLimit)
+}
+
+// SetHugeTLBLimit sets the huge page limit of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) SetHugeTLBLimit(pageSize ByteSize, limit ByteSize) (err error) {
+	finish, err := c.operation("SetHugeTLBLimit", pageSize.String(), limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return err
+	}
+
+	return c.setCgroupItemWithByteSize(hugeTLBKey(pageSize, "limit_in_bytes", "max"), limit, ErrSettingHugeTLBLimitFailed)
+}
+
+// RDMAResources represents the RDMA resources of a single device as found in
+// the rdma.max and rdma.current cgroup files. A value of math.MaxInt64 means
+// unlimited.
+type RDMAResources struct {
+	Device     string
+	HCAHandles int64
+	HCAObjects int64
+}
+
+// String returns the resources in the format expected by rdma.max.
+func (r RDMAResources) String() string {
+	format := func(v int64) string {
+		if v == math.MaxInt64 {
+			return "max"
+		}
+		return strconv.FormatInt(v, 10)
+	}
+	return fmt.Sprintf("%s hca_handle=%s hca_object=%s", r.Device, format(r.HCAHandles), format(r.HCAObjects))
+}
+
+// parseRDMAResources parses the content of rdma.max or rdma.current.
+func parseRDMAResources(lines []string) ([]RDMAResources, error) {
+	var resources []RDMAResources
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		r := RDMAResources{Device: fields[0], HCAHandles: math.MaxInt64, HCAObjects: math.MaxInt64}
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, ErrRDMALimit
+			}
+
+			value := int64(math.MaxInt64)
+			if parts[1] != "max" {
+				v, err := strconv.ParseInt(parts[1], 10, 64)
+				if err != nil {
+					return nil, ErrRDMALimit
+				}
+				value = v
+			}
+
+			switch parts[0] {
+			case "hca_handle":
+				r.HCAHandles = value
+			case "hca_object":
+				r.HCAObjects = value
+			}
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// RDMAUsage returns the RDMA resources currently used by the container, per
+// device.
+func (c *Container) RDMAUsage() ([]RDMAResources, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	return parseRDMAResources(c.cgroupItem("rdma.current"))
+}
+
+// RDMALimits returns the RDMA resource limits of the container, per device.
+func (c *Container) RDMALimits() ([]RDMAResources, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	return parseRDMAResources(c.cgroupItem("rdma.max"))
+}
+
+// SetRDMALimit sets the RDMA resource limits of the container for a single
+// device.
+func (c *Container) SetRDMALimit(limit RDMAResources) (err error) {
+	finish, err := c.operation("SetRDMALimit", limit.Device)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return err
+	}
+
+	if limit.Device == "" {
+		return ErrSettingRDMALimitFailed
+	}
+
+	if err := c.setCgroupItem("rdma.max", limit.String()); err != nil {
+		return ErrSettingRDMALimitFailed
+	}
+	return nil
+}
+
+// PressureValues represents one line of a pressure stall information file:
+// the share of wall time some (or all) tasks were stalled, averaged over 10,
+// 60 and 300 seconds, in percent, and the total stall time.
+type PressureValues struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  time.Duration
+}
+
+// Pressure represents the pressure stall information of a single resource.
+// Full is always zero for the CPU on kernels before 5.13.
+type Pressure struct {
+	Some PressureValues
+	Full PressureValues
+}
+
+// PressureStats represents the pressure stall information of a container.
+type PressureStats struct {
+	CPU    Pressure
+	Memory Pressure
+	IO     Pressure
+}
+
+// parsePressure parses the content of cpu.pressure, memory.pressure or
+// io.pressure.
+func parsePressure(lines []string) (Pressure, error) {
+	var p Pressure
+	found := false
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var values *PressureValues
+		switch fields[0] {
+		case "some":
+			values = &p.Some
+		case "full":
+			values = &p.Full
+		default:
+			return p, ErrPressureStats
+		}
+
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return p, ErrPressureStats
+			}
+
+			if parts[0] == "total" {
+				total, err := strconv.ParseUint(parts[1], 10, 64)
+				if err != nil {
+					return p, ErrPressureStats
+				}
+				values.Total = time.Duration(total) * time.Microsecond
+				continue
+			}
+
+			v, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return p, ErrPressureStats
+			}
+
+			switch parts[0] {
+			case "avg10":
+				values.Avg10 = v
+			case "avg60":
+				values.Avg60 = v
+			case "avg300":
+				values.Avg300 = v
+			}
+		}
+		found = true
+	}
+
+	if !found {
+		return p, ErrPressureStats
+	}
+	return p, nil
+}
+
+// PressureStats returns the pressure stall information (PSI) of the CPU,
+// memory and IO of the container, as found in its cgroup. Needs cgroup v2
+// and a kernel with PSI enabled.
+func (c *Container) PressureStats() (PressureStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var stats PressureStats
+
+	if err := c.makeSure(isRunning); err != nil {
+		return stats, err
+	}
+
+	if !CgroupUnified() {
+		return stats, ErrPressureStats
+	}
+
+	for _, r := range []struct {
+		file     string
+		pressure *Pressure
+	}{
+		{"cpu.pressure", &stats.CPU},
+		{"memory.pressure", &stats.Memory},
+		{"io.pressure", &stats.IO},
+	} {
+		p, err := parsePressure(c.cgroupItem(r.file))
+		if err != nil {
+			return stats, err
+		}
+		*r.pressure = p
+	}
+	return stats, nil
+}
+
+// slicePath returns the cgroup path of a systemd slice, where each dash
+// separated prefix of the name is a parent slice: "a-b.slice" is found at
+// "a.slice/a-b.slice".
+func slicePath(slice string) (string, error) {
+	if slice == "-.slice" {
+		return "", nil
+	}
+
+	name := strings.TrimSuffix(slice, ".slice")
+	if name == slice || name == "" || strings.Contains(name, "/") || strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") || strings.Contains(name, "--") {
+		return "", fmt.Errorf("%s: %q", ErrInvalidCgroupScope, slice)
+	}
+
+	var path []string
+	parts := strings.Split(name, "-")
+	for i := range parts {
+		path = append(path, strings.Join(parts[:i+1], "-")+".slice")
+	}
+	return strings.Join(path, "/"), nil
+}
+
+// SetCgroupScope places the cgroup of the container inside the given systemd
+// slice (through lxc.cgroup.dir), so containers started by a daemon don't end
+// up in the daemon's own cgroup. The cgroup is created directly on the
+// cgroupfs by liblxc when the container starts.
+func (c *Container) SetCgroupScope(opts CgroupScopeOptions) (err error) {
+	finish, err := c.operation("SetCgroupScope")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ErrNotSupported
+	}
+
+	slice, err := slicePath(opts.Slice)
+	if err != nil {
+		return err
+	}
+
+	scope := opts.Scope
+	if scope == "" {
+		scope = fmt.Sprintf("lxc-%s.scope", c.name())
+	}
+
+	if !strings.HasSuffix(scope, ".scope") || strings.Contains(scope, "/") {
+		return fmt.Errorf("%s: %q", ErrInvalidCgroupScope, scope)
+	}
+
+	return c.setConfigItem("lxc.cgroup.dir", path.Join(slice, scope))
+}
+
+// StartInScope starts the container with its cgroup placed in the given
+// systemd slice and scope. See SetCgroupScope.
+func (c *Container) StartInScope(opts CgroupScopeOptions) error {
+	if err := c.SetCgroupScope(opts); err != nil {
+		return err
+	}
+
+	return c.Start()
+}
+
+// validCgroupDir returns true if dir is a relative cgroup path staying
+// below the cgroup it is relative to.
+func validCgroupDir(dir string) bool {
+	if dir == "" {
+		return true
+	}
+
+	if path.IsAbs(dir) || path.Clean(dir) != dir {
+		return false
+	}
+
+	for _, part := range strings.Split(dir, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// cgroupPlacementItems validates the placement and returns its config items.
+func cgroupPlacementItems(p CgroupPlacement) ([]KeyValue, error) {
+	for _, dir := range []string{p.Dir, p.MonitorDir, p.ContainerDir, p.ContainerInner} {
+		if !validCgroupDir(dir) {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidCgroupPlacement, dir)
+		}
+	}
+
+	split := p.MonitorDir != "" || p.ContainerDir != ""
+	if split && (p.MonitorDir == "" || p.ContainerDir == "") {
+		return nil, fmt.Errorf("%s: %s", ErrInvalidCgroupPlacement, "the monitor and container dirs have to be set together")
+	}
+
+	if split && p.Dir != "" {
+		return nil, fmt.Errorf("%s: %s", ErrInvalidCgroupPlacement, "dir can't be combined with the monitor and container dirs")
+	}
+
+	if split && p.MonitorDir == p.ContainerDir {
+		return nil, fmt.Errorf("%s: %s", ErrInvalidCgroupPlacement, "the monitor and container dirs have to differ")
+	}
+
+	relative := "0"
+	if p.Relative {
+		relative = "1"
+	}
+
+	return []KeyValue{
+		{"lxc.cgroup.dir", p.Dir},
+		{"lxc.cgroup.dir.monitor", p.MonitorDir},
+		{"lxc.cgroup.dir.container", p.ContainerDir},
+		{"lxc.cgroup.dir.container.inner", p.ContainerInner},
+		{"lxc.cgroup.relative", relative},
+	}, nil
+}
+
+// CgroupPlacement returns the cgroup placement found in the configuration of
+// the container.
+func (c *Container) CgroupPlacement() (CgroupPlacement, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return CgroupPlacement{}, ErrNotDefined
+	}
+
+	value := func(key string) string {
+		if values := c.configItem(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	p := CgroupPlacement{Dir: value("lxc.cgroup.dir")}
+	if VersionAtLeast(4, 0, 0) {
+		p.MonitorDir = value("lxc.cgroup.dir.monitor")
+		p.ContainerDir = value("lxc.cgroup.dir.container")
+		p.ContainerInner = value("lxc.cgroup.dir.container.inner")
+		p.Relative = value("lxc.cgroup.relative") == "1"
+	}
+	return p, nil
+}
+
+// SetCgroupPlacement replaces the cgroup placement in the configuration of
+// the container, it applies on the next start. The dirs are relative paths,
+// the monitor and container dirs are set together or not at all.
+func (c *Container) SetCgroupPlacement(p CgroupPlacement) (err error) {
+	finish, err := c.operation("SetCgroupPlacement")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ErrNotSupported
+	}
+
+	items, err := cgroupPlacementItems(p)
+	if err != nil {
+		return err
+	}
+
+	if !VersionAtLeast(4, 0, 0) {
+		if p.MonitorDir != "" || p.ContainerDir != "" || p.ContainerInner != "" || p.Relative {
+			return ErrNotSupported
+		}
+		items = items[:1]
+	}
+
+	for _, kv := range items {
+		if err := c.clearConfigItem(kv.Key); err != nil {
+			return err
+		}
+
+		if kv.Value == "" {
+			continue
+		}
+
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+		}
+	}
+	return nil
+}
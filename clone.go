@@ -0,0 +1,215 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cloneMAC derives the MAC address of a network interface from the name of
+// the container, using the OUI liblxc uses for random addresses.
+func cloneMAC(name string, index int) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", name, index)))
+	return fmt.Sprintf("00:16:3e:%02x:%02x:%02x", h[0], h[1], h[2])
+}
+
+// patchHosts replaces the old hostname with the new one in the host names of
+// /etc/hosts, leaving comments untouched.
+func patchHosts(content string, old string, new string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		entry := line
+		comment := ""
+		if j := strings.Index(line, "#"); j >= 0 {
+			entry, comment = line[:j], line[j:]
+		}
+
+		fields := strings.Fields(entry)
+		changed := false
+		for k := 1; k < len(fields); k++ {
+			if fields[k] == old {
+				fields[k] = new
+				changed = true
+			} else if strings.HasPrefix(fields[k], old+".") {
+				fields[k] = new + strings.TrimPrefix(fields[k], old)
+				changed = true
+			}
+		}
+
+		if changed {
+			lines[i] = strings.Join(fields, "\t")
+			if comment != "" {
+				lines[i] += " " + comment
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// networkKey returns the config key of a network item for the liblxc version.
+func networkKey(index int, item string) string {
+	if VersionAtLeast(2, 1, 0) {
+		return fmt.Sprintf("lxc.net.%d.%s", index, item)
+	}
+	return fmt.Sprintf("lxc.network.%d.%s", index, item)
+}
+
+// regenerateMACs sets deterministic MAC addresses on the networks.
+//
+// Caller needs to hold the lock
+func (c *Container) regenerateMACs() error {
+	for i := 0; c.configItem(networkKey(i, "type"))[0] != ""; i++ {
+		if err := c.setConfigItem(networkKey(i, "hwaddr"), cloneMAC(c.name(), i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateHostname sets the hostname of the container to its name, both in its
+// configuration and in /etc/hostname and /etc/hosts of rootfs.
+//
+// Caller needs to hold the lock
+func (c *Container) updateHostname(rootfs string, old string) error {
+	key := "lxc.uts.name"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.utsname"
+	}
+
+	name := c.name()
+	if err := c.setConfigItem(key, name); err != nil {
+		return err
+	}
+	return updateHostnameFiles(rootfs, old, name)
+}
+
+// updateHostnameFiles replaces the old hostname by name in /etc/hostname and
+// /etc/hosts of rootfs, without following symlinks of the guest out of it.
+func updateHostnameFiles(rootfs string, old string, name string) error {
+	hostname, err := resolvePath(rootfs, "/etc/hostname")
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(hostname); err == nil {
+		if err := writeRootfsFile(hostname, []byte(name+"\n"), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	hosts, err := resolvePath(rootfs, "/etc/hosts")
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(hosts); err == nil && old != "" {
+		if err := refuseSymlink(hosts); err != nil {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(hosts)
+		if err != nil {
+			return err
+		}
+
+		if err := writeRootfsFile(hosts, []byte(patchHosts(string(content), old, name)), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishClone applies the identity changes requested by options to the new
+// clone and runs its PostClone callback. Clones which can't be finished are
+// destroyed, so the clone either succeeds as a whole or not at all.
+func finishClone(name string, lxcpath string, hostname string, options CloneOptions) error {
+	options.RegenerateMAC = options.RegenerateMAC && !options.KeepMAC
+	options.UpdateHostname = options.UpdateHostname && !options.KeepName
+
+	if !options.RegenerateMAC && !options.UpdateHostname && options.PostClone == nil {
+		return nil
+	}
+
+	c, err := NewContainer(name, lxcpath)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	err = func() error {
+		c.mu.Lock()
+		locked := true
+		defer func() {
+			if locked {
+				c.mu.Unlock()
+			}
+		}()
+
+		if options.RegenerateMAC {
+			if err := c.regenerateMACs(); err != nil {
+				return err
+			}
+		}
+
+		if !options.UpdateHostname && options.PostClone == nil {
+			return c.saveConfigFile(filepath.Join(lxcpath, name, "config"))
+		}
+
+		rootfs, unmount, err := c.mountRootfs()
+		if err != nil {
+			return err
+		}
+		defer unmount()
+
+		if options.UpdateHostname {
+			if err := c.updateHostname(rootfs, hostname); err != nil {
+				return err
+			}
+		}
+
+		if options.PostClone != nil {
+			c.rootfsMount = rootfs
+			c.mu.Unlock()
+			locked = false
+
+			err := options.PostClone(c)
+
+			c.mu.Lock()
+			locked = true
+			c.rootfsMount = ""
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return c.saveConfigFile(filepath.Join(lxcpath, name, "config"))
+	}()
+
+	if err != nil {
+		c.mu.Lock()
+		c.destroy()
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %v", ErrCloneFailed, err)
+	}
+	return nil
+}
+
+// MountedRootfs returns the path the root filesystem is accessible at on the
+// host while a CloneOptions.PostClone callback runs, an empty string
+// otherwise.
+func (c *Container) MountedRootfs() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.rootfsMount
+}
@@ -0,0 +1,346 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+/*
+Package compose runs a set of containers described in a YAML file on a single
+host, similar to docker-compose:
+
+	lxcpath: /var/lib/lxc
+	containers:
+	  db:
+	    image: docker.io/library/postgres:16
+	    config:
+	      lxc.cgroup2.memory.max: 1G
+	    mounts:
+	      - /srv/db:var/lib/postgresql/data
+	  app:
+	    image: ghcr.io/example/app:latest
+	    networks: [lxcbr0]
+	    depends_on: [db]
+*/
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/go-lxc"
+)
+
+// Container describes a single container of a project.
+type Container struct {
+	// Image is the OCI image the container is created from.
+	Image string `yaml:"image"`
+
+	// Config items set when the container is created.
+	Config map[string]string `yaml:"config"`
+
+	// Mounts are bind mounts in the form "<host path>:<container path>[:ro]".
+	Mounts []string `yaml:"mounts"`
+
+	// Networks are the bridges the container gets a veth interface on.
+	Networks []string `yaml:"networks"`
+
+	// DependsOn lists the containers started before this one.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// Project is a set of containers.
+type Project struct {
+	// LXCPath the containers are created in (default: lxc.DefaultConfigPath()).
+	LXCPath string `yaml:"lxcpath"`
+
+	Containers map[string]Container `yaml:"containers"`
+}
+
+// Status is the state of a container of a project.
+type Status struct {
+	Name    string
+	Defined bool
+	State   lxc.State
+	IPv4    []string
+}
+
+// Parse parses a project definition.
+func Parse(data []byte) (*Project, error) {
+	var p Project
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Containers) == 0 {
+		return nil, fmt.Errorf("no containers defined")
+	}
+
+	for name, c := range p.Containers {
+		if !validName(name) {
+			return nil, fmt.Errorf("invalid container name %q", name)
+		}
+
+		if c.Image == "" {
+			return nil, fmt.Errorf("container %q: no image", name)
+		}
+
+		for _, dep := range c.DependsOn {
+			if _, ok := p.Containers[dep]; !ok {
+				return nil, fmt.Errorf("container %q: %s: %q", name, lxc.ErrUnknownDependency, dep)
+			}
+		}
+
+		for _, m := range c.Mounts {
+			if _, err := mountEntry(m); err != nil {
+				return nil, fmt.Errorf("container %q: %v", name, err)
+			}
+		}
+	}
+
+	if p.LXCPath == "" {
+		p.LXCPath = lxc.DefaultConfigPath()
+	}
+	return &p, nil
+}
+
+// Load reads and parses the project definition at path.
+func Load(path string) (*Project, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// validName returns true if name can be used as the name of a container
+// below the lxcpath.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// names returns the sorted names of the containers.
+func (p *Project) names() []string {
+	var names []string
+	for name := range p.Containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mountEntry converts a mount into a lxc.mount.entry value.
+func mountEntry(mount string) (string, error) {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 || !filepath.IsAbs(parts[0]) || parts[1] == "" {
+		return "", fmt.Errorf("invalid mount %q", mount)
+	}
+
+	options := "bind,create=dir"
+	if len(parts) == 3 {
+		if parts[2] != "ro" && parts[2] != "rw" {
+			return "", fmt.Errorf("invalid mount %q", mount)
+		}
+		options += "," + parts[2]
+	}
+
+	return fmt.Sprintf("%s %s none %s 0 0", parts[0], strings.TrimPrefix(parts[1], "/"), options), nil
+}
+
+// configItems returns the config items of a container in the order they are
+// set.
+func (c Container) configItems() []lxc.KeyValue {
+	var items []lxc.KeyValue
+
+	var keys []string
+	for key := range c.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		items = append(items, lxc.KeyValue{Key: key, Value: c.Config[key]})
+	}
+
+	for _, m := range c.Mounts {
+		entry, _ := mountEntry(m)
+		items = append(items, lxc.KeyValue{Key: "lxc.mount.entry", Value: entry})
+	}
+
+	for i, network := range c.Networks {
+		prefix := fmt.Sprintf("lxc.net.%d.", i)
+		items = append(items,
+			lxc.KeyValue{Key: prefix + "type", Value: "veth"},
+			lxc.KeyValue{Key: prefix + "link", Value: network},
+			lxc.KeyValue{Key: prefix + "flags", Value: "up"},
+		)
+	}
+
+	return items
+}
+
+// create creates the container from its image and applies its configuration.
+// The container is destroyed if it can't be configured, as Up leaves the
+// configuration of existing containers untouched.
+func (p *Project) create(ctx context.Context, c *lxc.Container, spec Container) error {
+	if err := c.CreateFromImageContext(ctx, spec.Image, lxc.DefaultImageOptions); err != nil {
+		return err
+	}
+
+	if err := p.configure(c, spec); err != nil {
+		c.Destroy()
+		return err
+	}
+	return nil
+}
+
+// configure applies the configuration of the container.
+func (p *Project) configure(c *lxc.Container, spec Container) error {
+	if len(spec.Networks) > 0 {
+		if err := c.ClearConfigItem("lxc.net"); err != nil {
+			return err
+		}
+	}
+
+	for _, kv := range spec.configItems() {
+		if err := c.SetConfigItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s", err, kv)
+		}
+	}
+
+	return c.SaveConfigFile(c.ConfigFileName())
+}
+
+// containers returns the containers of the project, releasing them is up to
+// the caller.
+func (p *Project) containers() (map[string]*lxc.Container, error) {
+	containers := make(map[string]*lxc.Container)
+	for _, name := range p.names() {
+		c, err := lxc.NewContainer(name, p.LXCPath)
+		if err != nil {
+			release(containers)
+			return nil, err
+		}
+		containers[name] = c
+	}
+	return containers, nil
+}
+
+func release(containers map[string]*lxc.Container) {
+	for _, c := range containers {
+		c.Release()
+	}
+}
+
+// Up creates the containers which don't exist yet and starts them in the
+// order of their dependencies. The configuration of existing containers is
+// left untouched.
+func (p *Project) Up(ctx context.Context) error {
+	containers, err := p.containers()
+	if err != nil {
+		return err
+	}
+	defer release(containers)
+
+	m := lxc.NewDependencyManager()
+	for _, name := range p.names() {
+		c := containers[name]
+		if !c.Defined() {
+			if err := p.create(ctx, c, p.Containers[name]); err != nil {
+				return fmt.Errorf("container %q: %v", name, err)
+			}
+		}
+
+		if err := m.Add(c, nil); err != nil {
+			return err
+		}
+	}
+
+	for name, spec := range p.Containers {
+		if len(spec.DependsOn) == 0 {
+			continue
+		}
+
+		if err := m.Require(name, spec.DependsOn...); err != nil {
+			return err
+		}
+	}
+
+	return m.StartAll(ctx)
+}
+
+// Down stops the containers in the reverse order of their dependencies,
+// shutting each down cleanly within timeout, and destroys them.
+func (p *Project) Down(timeout time.Duration) error {
+	containers, err := p.containers()
+	if err != nil {
+		return err
+	}
+	defer release(containers)
+
+	m := lxc.NewDependencyManager()
+	for _, name := range p.names() {
+		if containers[name].Defined() {
+			if err := m.Add(containers[name], nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, spec := range p.Containers {
+		if !containers[name].Defined() {
+			continue
+		}
+
+		for _, dep := range spec.DependsOn {
+			if containers[dep].Defined() {
+				if err := m.Require(name, dep); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := m.StopAll(timeout); err != nil {
+		return err
+	}
+
+	for _, name := range p.names() {
+		c := containers[name]
+		if !c.Defined() {
+			continue
+		}
+
+		if err := c.Destroy(); err != nil {
+			return fmt.Errorf("container %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Ps returns the status of the containers, sorted by name.
+func (p *Project) Ps() ([]Status, error) {
+	containers, err := p.containers()
+	if err != nil {
+		return nil, err
+	}
+	defer release(containers)
+
+	var status []Status
+	for _, name := range p.names() {
+		c := containers[name]
+		s := Status{Name: name, Defined: c.Defined(), State: c.State()}
+
+		if c.Running() {
+			s.IPv4, _ = c.IPv4Addresses()
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
@@ -0,0 +1,74 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package compose
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lxc/go-lxc"
+)
+
+const project = `
+lxcpath: /tmp/compose
+containers:
+  db:
+    image: docker.io/library/postgres:16
+    config:
+      lxc.cgroup2.memory.max: 1G
+    mounts:
+      - /srv/db:/var/lib/postgresql/data
+  app:
+    image: ghcr.io/example/app:latest
+    mounts:
+      - /srv/static:srv/static:ro
+    networks: [lxcbr0]
+    depends_on: [db]
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(project))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if p.LXCPath != "/tmp/compose" || !reflect.DeepEqual(p.names(), []string{"app", "db"}) {
+		t.Errorf("unexpected project %+v", p)
+	}
+
+	if !reflect.DeepEqual(p.Containers["app"].configItems(), []lxc.KeyValue{
+		{Key: "lxc.mount.entry", Value: "/srv/static srv/static none bind,create=dir,ro 0 0"},
+		{Key: "lxc.net.0.type", Value: "veth"},
+		{Key: "lxc.net.0.link", Value: "lxcbr0"},
+		{Key: "lxc.net.0.flags", Value: "up"},
+	}) {
+		t.Errorf("unexpected config items %v", p.Containers["app"].configItems())
+	}
+
+	if !reflect.DeepEqual(p.Containers["db"].configItems(), []lxc.KeyValue{
+		{Key: "lxc.cgroup2.memory.max", Value: "1G"},
+		{Key: "lxc.mount.entry", Value: "/srv/db var/lib/postgresql/data none bind,create=dir 0 0"},
+	}) {
+		t.Errorf("unexpected config items %v", p.Containers["db"].configItems())
+	}
+
+	for _, invalid := range []string{
+		"containers: {}",
+		"containers: {app: {config: {}}}",
+		"containers: {app: {image: alpine, depends_on: [db]}}",
+		"containers: {app: {image: alpine, mounts: [srv:/srv]}}",
+		"containers: {app: {image: alpine, ports: [80]}}",
+		"containers: {../app: {image: alpine}}",
+		"containers: {a/b: {image: alpine}}",
+		"containers: {..: {image: alpine}}",
+		"containers: {.: {image: alpine}}",
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
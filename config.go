@@ -0,0 +1,284 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// KeyValue represents a single config item. Keys which can be set multiple
+// times (e.g. lxc.mount.entry) show up once per value.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// String returns the item in the config file format.
+func (kv KeyValue) String() string {
+	return fmt.Sprintf("%s = %s", kv.Key, kv.Value)
+}
+
+// configValues returns the values of the given key. Keys like lxc.cgroup
+// return fully qualified "key = value" lines for their subkeys which are
+// split up here.
+//
+// Caller needs to hold the lock
+func (c *Container) configValues(key string) []KeyValue {
+	var values []KeyValue
+
+	for _, v := range c.configItem(key) {
+		if v == "" {
+			continue
+		}
+
+		if strings.HasPrefix(v, key+".") {
+			parts := strings.SplitN(v, " = ", 2)
+			if len(parts) == 2 {
+				values = append(values, KeyValue{Key: parts[0], Value: parts[1]})
+				continue
+			}
+		}
+		values = append(values, KeyValue{Key: key, Value: v})
+	}
+	return values
+}
+
+// DumpConfig returns the complete configuration of the container, in the
+// order of the keys reported by liblxc. Network devices are expanded into
+// their indexed subkeys (lxc.net.0.type, lxc.net.0.link, ...).
+func (c *Container) DumpConfig() ([]KeyValue, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	netPrefix := "lxc.net"
+	if !VersionAtLeast(2, 1, 0) {
+		netPrefix = "lxc.network"
+	}
+
+	var config []KeyValue
+	seen := make(map[string]bool)
+	add := func(values []KeyValue) {
+		for _, kv := range values {
+			if seen[kv.Key+"\x00"+kv.Value] {
+				continue
+			}
+			seen[kv.Key+"\x00"+kv.Value] = true
+			config = append(config, kv)
+		}
+	}
+
+	for _, key := range c.configKeys() {
+		// Skip the prefix handlers (lxc.cgroup., lxc.hook., ...), their
+		// values are reported by the unqualified key.
+		if key == "" || strings.HasSuffix(key, ".") {
+			continue
+		}
+
+		if key != netPrefix {
+			add(c.configValues(key))
+			continue
+		}
+
+		i := 0
+		for _, v := range c.configItem(netPrefix) {
+			if v == "" {
+				continue
+			}
+
+			prefix := fmt.Sprintf("%s.%d", netPrefix, i)
+			for _, subkey := range c.configKeys(prefix) {
+				if subkey == "" {
+					continue
+				}
+				add(c.configValues(prefix + "." + subkey))
+			}
+			i++
+		}
+	}
+
+	return config, nil
+}
+
+// ConfigChange represents a config item whose value differs between two
+// containers.
+type ConfigChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// ConfigDiff represents the differences between two container configurations.
+type ConfigDiff struct {
+	// Added holds the items only found in the second configuration.
+	Added []KeyValue
+	// Removed holds the items only found in the first configuration.
+	Removed []KeyValue
+	// Changed holds the single valued items found in both configurations
+	// with different values.
+	Changed []ConfigChange
+}
+
+// Empty returns true if the configurations are identical.
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// compareConfigs compares two configurations. Keys set more than once on
+// either side are compared as lists, where the order is ignored and each
+// value is either added or removed.
+func compareConfigs(a, b []KeyValue) ConfigDiff {
+	group := func(config []KeyValue) ([]string, map[string][]string) {
+		var keys []string
+		values := make(map[string][]string)
+		for _, kv := range config {
+			if _, ok := values[kv.Key]; !ok {
+				keys = append(keys, kv.Key)
+			}
+			values[kv.Key] = append(values[kv.Key], kv.Value)
+		}
+		return keys, values
+	}
+
+	// without returns the values of x which aren't found in y, keeping
+	// duplicates.
+	without := func(x, y []string) []string {
+		count := make(map[string]int)
+		for _, v := range y {
+			count[v]++
+		}
+
+		var ret []string
+		for _, v := range x {
+			if count[v] > 0 {
+				count[v]--
+				continue
+			}
+			ret = append(ret, v)
+		}
+		return ret
+	}
+
+	aKeys, aValues := group(a)
+	bKeys, bValues := group(b)
+
+	var diff ConfigDiff
+	for _, key := range aKeys {
+		old := aValues[key]
+		updated, ok := bValues[key]
+		if !ok {
+			for _, v := range old {
+				diff.Removed = append(diff.Removed, KeyValue{Key: key, Value: v})
+			}
+			continue
+		}
+
+		if len(old) == 1 && len(updated) == 1 {
+			if old[0] != updated[0] {
+				diff.Changed = append(diff.Changed, ConfigChange{Key: key, Old: old[0], New: updated[0]})
+			}
+			continue
+		}
+
+		for _, v := range without(old, updated) {
+			diff.Removed = append(diff.Removed, KeyValue{Key: key, Value: v})
+		}
+		for _, v := range without(updated, old) {
+			diff.Added = append(diff.Added, KeyValue{Key: key, Value: v})
+		}
+	}
+
+	for _, key := range bKeys {
+		if _, ok := aValues[key]; ok {
+			continue
+		}
+
+		for _, v := range bValues[key] {
+			diff.Added = append(diff.Added, KeyValue{Key: key, Value: v})
+		}
+	}
+
+	return diff
+}
+
+// CompareConfigs returns the differences between the configurations of the
+// given containers, as if a was changed into b.
+func CompareConfigs(a, b *Container) (ConfigDiff, error) {
+	aConfig, err := a.DumpConfig()
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	bConfig, err := b.DumpConfig()
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	return compareConfigs(aConfig, bConfig), nil
+}
+
+// volatileConfigKeys are the patterns of the keys liblxc or go-lxc generate
+// for a container, e.g. random MACs, which aren't drift.
+var volatileConfigKeys = []string{
+	"lxc.net.*.hwaddr",
+	"lxc.network.*.hwaddr",
+}
+
+// isVolatileConfigKey returns whether the key is generated for the
+// container rather than configured.
+func isVolatileConfigKey(key string) bool {
+	for _, pattern := range volatileConfigKeys {
+		if ok, _ := filepath.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutVolatile returns the items whose keys aren't volatile.
+func withoutVolatile(config []KeyValue) []KeyValue {
+	var ret []KeyValue
+	for _, kv := range config {
+		if !isVolatileConfigKey(kv.Key) {
+			ret = append(ret, kv)
+		}
+	}
+	return ret
+}
+
+// DetectDrift returns the differences between the golden config file,
+// saved with SaveConfigFile when the container was provisioned, and the
+// current configuration, as if the golden one was changed into it.
+// Volatile keys like generated MACs are ignored, includes are compared as
+// lxc.include items.
+func (c *Container) DetectDrift(goldenPath string) (ConfigDiff, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return ConfigDiff{}, ErrNotDefined
+	}
+
+	content, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	config, err := c.unexpandedConfig()
+	if err != nil {
+		return ConfigDiff{}, err
+	}
+
+	golden := withoutVolatile(parseConfigLines(string(content)))
+	return compareConfigs(golden, withoutVolatile(config)), nil
+}
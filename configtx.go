@@ -0,0 +1,188 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// configChange is a change of a ConfigTransaction, clearing the key if
+// clear is set and setting it to value otherwise.
+type configChange struct {
+	key   string
+	value string
+	clear bool
+}
+
+// ConfigTransaction collects config changes of a container to apply them
+// together, see Container.Begin.
+type ConfigTransaction struct {
+	c *Container
+
+	mu      sync.Mutex
+	changes []configChange
+	done    bool
+}
+
+// Begin starts a config transaction. Its changes are only applied to the
+// container by Commit, either all of them or none.
+func (c *Container) Begin() *ConfigTransaction {
+	return &ConfigTransaction{c: c}
+}
+
+// add records the change unless the transaction is done.
+func (tx *ConfigTransaction) add(change configChange) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTransactionDone
+	}
+
+	tx.changes = append(tx.changes, change)
+	return nil
+}
+
+// Set sets the value of the given config item on Commit.
+func (tx *ConfigTransaction) Set(key string, value string) error {
+	return tx.add(configChange{key: key, value: value})
+}
+
+// Clear clears the value of the given config item on Commit.
+func (tx *ConfigTransaction) Clear(key string) error {
+	return tx.add(configChange{key: key, clear: true})
+}
+
+// Rollback discards the changes of the transaction.
+func (tx *ConfigTransaction) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTransactionDone
+	}
+
+	tx.done = true
+	tx.changes = nil
+	return nil
+}
+
+// Commit applies the changes of the transaction in order. Keys unknown to
+// liblxc are refused before changing anything, and if a change fails the
+// configuration of the container is restored as it was before, so that it
+// is left untouched.
+func (tx *ConfigTransaction) Commit() (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTransactionDone
+	}
+	tx.done = true
+
+	c := tx.c
+
+	finish, err := c.operation("CommitConfig")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	return c.applyConfigChanges(tx.changes)
+}
+
+// applyConfigChanges applies the changes in order, all of them or none: the
+// keys are checked first and the configuration is restored if a change
+// fails nonetheless.
+//
+// Caller needs to hold the lock
+func (c *Container) applyConfigChanges(changes []configChange) (err error) {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, change := range changes {
+		if !IsSupportedConfigItem(change.key) {
+			return fmt.Errorf("%s: %q", ErrUnknownConfigItem, change.key)
+		}
+	}
+
+	f, err := ioutil.TempFile("", "go-lxc-config")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := c.saveConfigFile(f.Name()); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.clear {
+			err = c.clearConfigItem(change.key)
+		} else {
+			err = c.setConfigItem(change.key, change.value)
+		}
+
+		if err != nil {
+			if change.clear {
+				err = fmt.Errorf("%s: %q", err, change.key)
+			} else {
+				err = fmt.Errorf("%s: %s = %s", err, change.key, change.value)
+			}
+
+			if rerr := c.reloadConfig(f.Name()); rerr != nil {
+				return fmt.Errorf("%s, restoring the config failed: %v", err, rerr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// SetConfigItems sets the values of the given config items under a single
+// lock, so that concurrent callers never observe or cause a partially
+// applied set. The keys are checked up front and applied in sorted order;
+// if one fails the configuration is left untouched.
+func (c *Container) SetConfigItems(items map[string]string) (err error) {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	finish, err := c.operation("SetConfigItems", keys...)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	changes := make([]configChange, len(keys))
+	for i, key := range keys {
+		changes[i] = configChange{key: key, value: items[key]}
+	}
+	return c.applyConfigChanges(changes)
+}
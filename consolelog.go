@@ -0,0 +1,68 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// consolePollInterval is how often FollowConsole drains the console ring
+// buffer.
+const consolePollInterval = 250 * time.Millisecond
+
+// consoleBufferEnabled returns whether lxc.console.buffer.size enables the
+// console ring buffer.
+func consoleBufferEnabled(values []string) bool {
+	return len(values) > 0 && values[0] != "" && values[0] != "0"
+}
+
+// FollowConsole streams the console output of the container to w until ctx
+// is done, like lxc-console --show-log with follow. It keeps following
+// across restarts of the container and waits for it to start if it's
+// stopped.
+//
+// The output is read from the console ring buffer, which needs
+// lxc.console.buffer.size to be set, and is cleared from it once written
+// to w. Output written right before the container stops may be lost with
+// the buffer.
+func (c *Container) FollowConsole(ctx context.Context, w io.Writer) error {
+	if !c.Defined() {
+		return ErrNotDefined
+	}
+
+	if !consoleBufferEnabled(c.ConfigItem("lxc.console.buffer.size")) {
+		return ErrNoConsoleBuffer
+	}
+
+	ticker := time.NewTicker(consolePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if c.Running() {
+			data, err := c.ConsoleLog(ConsoleLogOptions{ReadLog: true, ClearLog: true})
+			if err != nil && c.Running() {
+				return err
+			}
+
+			// a failed read of a stopping container is retried on the
+			// next start
+			if err == nil && len(data) > 0 {
+				if _, err := w.Write(data); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -12,8 +12,10 @@ package lxc
 import "C"
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -33,7 +35,11 @@ type Container struct {
 	mu        sync.RWMutex
 	container *C.struct_lxc_container
 
-	verbosity Verbosity
+	verbosity    Verbosity
+	hooks        *hookListener
+	notify       *notifySocket
+	freezeMethod FreezeMethod
+	rootfsMount  string
 }
 
 // Snapshot struct
@@ -57,19 +63,19 @@ const (
 
 func (c *Container) makeSure(flags int) error {
 	if flags&isDefined != 0 && !c.defined() {
-		return fmt.Errorf("%s: %q", ErrNotDefined, c.name())
+		return fmt.Errorf("%w: %q", ErrNotDefined, c.name())
 	}
 
 	if flags&isNotDefined != 0 && c.defined() {
-		return fmt.Errorf("%s: %q", ErrAlreadyDefined, c.name())
+		return fmt.Errorf("%w: %q", ErrAlreadyDefined, c.name())
 	}
 
 	if flags&isRunning != 0 && !c.running() {
-		return fmt.Errorf("%s: %q", ErrNotRunning, c.name())
+		return fmt.Errorf("%w: %q", ErrNotRunning, c.name())
 	}
 
 	if flags&isNotRunning != 0 && c.running() {
-		return fmt.Errorf("%s: %q", ErrAlreadyRunning, c.name())
+		return fmt.Errorf("%w: %q", ErrAlreadyRunning, c.name())
 	}
 
 	if flags&isPrivileged != 0 && os.Geteuid() != 0 {
@@ -114,6 +120,16 @@ func (c *Container) Release() error {
 
 	c.container = nil
 
+	if c.hooks != nil {
+		c.hooks.close()
+		c.hooks = nil
+	}
+
+	if c.notify != nil {
+		c.notify.close()
+		c.notify = nil
+	}
+
 	return nil
 }
 
@@ -188,7 +204,13 @@ func (c *Container) Controllable() bool {
 }
 
 // CreateSnapshot creates a new snapshot.
-func (c *Container) CreateSnapshot() (*Snapshot, error) {
+func (c *Container) CreateSnapshot() (_ *Snapshot, err error) {
+	finish, err := c.operation("CreateSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -208,7 +230,13 @@ func (c *Container) CreateSnapshot() (*Snapshot, error) {
 }
 
 // RestoreSnapshot creates a new container based on a snapshot.
-func (c *Container) RestoreSnapshot(snapshot Snapshot, name string) error {
+func (c *Container) RestoreSnapshot(snapshot Snapshot, name string) (err error) {
+	finish, err := c.operation("RestoreSnapshot", snapshot.Name, name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -226,14 +254,27 @@ func (c *Container) RestoreSnapshot(snapshot Snapshot, name string) error {
 	csnapname := C.CString(snapshot.Name)
 	defer C.free(unsafe.Pointer(csnapname))
 
+	restoreIdentity := c.keepIdentity()
 	if !bool(C.go_lxc_snapshot_restore(c.container, csnapname, cname)) {
 		return ErrRestoreSnapshotFailed
 	}
-	return nil
+
+	if name == c.name() {
+		return restoreIdentity(name)
+	}
+
+	_, err = assignUUID(c.configPath(), name)
+	return err
 }
 
 // DestroySnapshot destroys the specified snapshot.
-func (c *Container) DestroySnapshot(snapshot Snapshot) error {
+func (c *Container) DestroySnapshot(snapshot Snapshot) (err error) {
+	finish, err := c.operation("DestroySnapshot", snapshot.Name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -255,7 +296,13 @@ func (c *Container) DestroySnapshot(snapshot Snapshot) error {
 }
 
 // DestroyAllSnapshots destroys all the snapshot.
-func (c *Container) DestroyAllSnapshots() error {
+func (c *Container) DestroyAllSnapshots() (err error) {
+	finish, err := c.operation("DestroyAllSnapshots")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -273,6 +320,89 @@ func (c *Container) DestroyAllSnapshots() error {
 	return nil
 }
 
+// RollbackToSnapshot restores the container in place from the snapshot,
+// like lxc-snapshot -r. A running container is stopped first and started
+// again afterwards. The name and the MAC addresses of the container are
+// kept.
+func (c *Container) RollbackToSnapshot(snapshot Snapshot) (err error) {
+	finish, err := c.operation("RollbackToSnapshot", snapshot.Name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	var macs []string
+	for i := 0; c.configItem(networkKey(i, "type"))[0] != ""; i++ {
+		macs = append(macs, c.configItem(networkKey(i, "hwaddr"))[0])
+	}
+
+	running := c.running()
+	if running && !bool(C.go_lxc_stop(c.container)) {
+		return ErrStopFailed
+	}
+
+	cname := C.CString(c.name())
+	defer C.free(unsafe.Pointer(cname))
+
+	csnapname := C.CString(snapshot.Name)
+	defer C.free(unsafe.Pointer(csnapname))
+
+	// liblxc replaces the container, reload its configuration
+	restoreIdentity := c.keepIdentity()
+	if !bool(C.go_lxc_snapshot_restore(c.container, csnapname, cname)) {
+		return ErrRestoreSnapshotFailed
+	}
+
+	if err := restoreIdentity(c.name()); err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.configPath(), c.name(), "config")
+	if err := c.reloadConfig(path); err != nil {
+		return err
+	}
+
+	for i, mac := range macs {
+		if mac == "" || c.configItem(networkKey(i, "type"))[0] == "" {
+			continue
+		}
+
+		if err := c.setConfigItem(networkKey(i, "hwaddr"), mac); err != nil {
+			return err
+		}
+	}
+
+	if err := c.saveConfigFile(path); err != nil {
+		return err
+	}
+
+	if !running {
+		return nil
+	}
+
+	cleanup, err := c.prepareStart()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if !bool(C.go_lxc_start(c.container, 0, nil)) {
+		return ErrStartFailed
+	}
+	return nil
+}
+
 // Snapshots returns the list of container snapshots.
 func (c *Container) Snapshots() ([]Snapshot, error) {
 	c.mu.RLock()
@@ -315,7 +445,12 @@ func (c *Container) state() State {
 		return StateMap["STOPPED"]
 	}
 
-	return StateMap[C.GoString(C.go_lxc_state(c.container))]
+	state := StateMap[C.GoString(C.go_lxc_state(c.container))]
+	if state == RUNNING && c.frozenByCgroup() {
+		// liblxc doesn't know about cgroup.freeze
+		return FROZEN
+	}
+	return state
 }
 
 // State returns the state of the container.
@@ -326,17 +461,24 @@ func (c *Container) State() State {
 	return c.state()
 }
 
+// initPid returns the process ID of the container's init process.
+//
+// Caller needs to hold the lock
+func (c *Container) initPid() int {
+	if c.container == nil {
+		return -1
+	}
+
+	return int(C.go_lxc_init_pid(c.container))
+}
+
 // InitPid returns the process ID of the container's init process
 // seen from outside the container.
 func (c *Container) InitPid() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if c.container == nil {
-		return -1
-	}
-
-	return int(C.go_lxc_init_pid(c.container))
+	return c.initPid()
 }
 
 // InitPidFd returns the pidfd of the container's init process.
@@ -458,32 +600,70 @@ func (c *Container) SetVerbosity(verbosity Verbosity) {
 	c.verbosity = verbosity
 }
 
-// Freeze freezes the running container.
-func (c *Container) Freeze() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.container == nil {
-		return ErrNotDefined
+// Freeze freezes the running container. On unified cgroup hosts where liblxc
+// fails to, e.g. older liblxc versions, it falls back to writing cgroup.freeze,
+// see FreezeMethod.
+func (c *Container) Freeze() (err error) {
+	finish, err := c.operation("Freeze")
+	if err != nil {
+		return err
 	}
+	defer finish(&err)
 
-	// check the state using lockless version
-	if c.state() == FROZEN {
-		return ErrAlreadyFrozen
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.container == nil {
+			return ErrNotDefined
+		}
+
+		// check the state using lockless version
+		if c.state() == FROZEN {
+			return ErrAlreadyFrozen
+		}
+
+		return c.freeze()
+	})
+}
+
+// freeze freezes the container through liblxc, falling back to
+// cgroup.freeze on unified hosts.
+//
+// Caller needs to hold the lock
+func (c *Container) freeze() error {
+	if bool(C.go_lxc_freeze(c.container)) {
+		c.freezeMethod = FreezeLiblxc
+		return nil
 	}
 
-	if !bool(C.go_lxc_freeze(c.container)) {
+	if !CgroupUnified() {
 		return ErrFreezeFailed
 	}
 
-	return nil
+	return c.freezeCgroup()
 }
 
 // Unfreeze thaws the frozen container.
-func (c *Container) Unfreeze() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Container) Unfreeze() (err error) {
+	finish, err := c.operation("Unfreeze")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return c.unfreeze()
+	})
+}
 
+// unfreeze thaws the container the way it was frozen.
+//
+// Caller needs to hold the lock
+func (c *Container) unfreeze() error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
@@ -497,15 +677,28 @@ func (c *Container) Unfreeze() error {
 		return ErrNotFrozen
 	}
 
+	// liblxc reports RUNNING if it was frozen through cgroup.freeze,
+	// possibly by another Container
+	if c.freezeMethod == FreezeCgroup || StateMap[C.GoString(C.go_lxc_state(c.container))] == RUNNING {
+		return c.unfreezeCgroup()
+	}
+
 	if !bool(C.go_lxc_unfreeze(c.container)) {
 		return ErrUnfreezeFailed
 	}
 
+	c.freezeMethod = FreezeNone
 	return nil
 }
 
 // Create creates the container using given TemplateOptions
-func (c *Container) Create(options TemplateOptions) error {
+func (c *Container) Create(options TemplateOptions) (err error) {
+	finish, err := c.operation("Create", options.Template)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -513,6 +706,10 @@ func (c *Container) Create(options TemplateOptions) error {
 		return err
 	}
 
+	if options.Owner != "" && !validOwner(options.Owner) {
+		return fmt.Errorf("%s: %q", ErrInvalidOwner, options.Owner)
+	}
+
 	bdevspecs := buildBdevSpecs(options.BackendSpecs)
 
 	// use download template if not set
@@ -578,82 +775,204 @@ func (c *Container) Create(options TemplateOptions) error {
 	cbackend := C.CString(options.Backend.String())
 	defer C.free(unsafe.Pointer(cbackend))
 
-	ret := false
+	// the download template fetches the image through the proxy
+	proxy := ""
+	if options.Template == "download" {
+		config, err := LoadDownloadConfig(c.configPath())
+		if err != nil {
+			return err
+		}
+		proxy = config.Proxy
+	}
+
+	var cargs **C.char
 	if args != nil {
-		cargs := makeNullTerminatedArgs(args)
+		cargs = makeNullTerminatedArgs(args)
 		if cargs == nil {
 			return ErrAllocationFailed
 		}
 		defer freeNullTerminatedArgs(cargs, len(args))
-
-		ret = bool(C.go_lxc_create(c.container, ctemplate, cbackend, bdevspecs, C.int(c.verbosity), cargs))
-	} else {
-		ret = bool(C.go_lxc_create(c.container, ctemplate, cbackend, bdevspecs, C.int(c.verbosity), nil))
 	}
 
+	ret := withProxyEnv(proxy, func() bool {
+		return bool(C.go_lxc_create(c.container, ctemplate, cbackend, bdevspecs, C.int(c.verbosity), cargs))
+	})
+
 	if !ret {
 		return ErrCreateFailed
 	}
-	return nil
-}
 
-// Start starts the container.
-func (c *Container) Start() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.container == nil {
-		return ErrNotDefined
+	if _, err := assignUUID(c.configPath(), c.name()); err != nil {
+		return err
 	}
 
-	if err := c.makeSure(isNotRunning); err != nil {
+	if err := writeOwner(c.configPath(), c.name(), options.Owner); err != nil {
 		return err
 	}
 
-	if !bool(C.go_lxc_start(c.container, 0, nil)) {
-		return ErrStartFailed
+	distribution := options.Distro
+	if options.Template != "download" {
+		distribution = options.Template
 	}
-	return nil
+	return c.createQuirks(distribution, options.Release)
 }
 
-// StartWithArgs starts the container using given arguments.
-func (c *Container) StartWithArgs(args []string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+// prepareStart runs the checks and applies the temporary configuration
+// shared by every way of starting the container. The returned function
+// undoes the temporary configuration, it is called once liblxc started the
+// container or failed to. The lock must be held.
+func (c *Container) prepareStart() (func(), error) {
 	if c.container == nil {
-		return ErrNotDefined
+		return nil, ErrNotDefined
 	}
 
 	if err := c.makeSure(isNotRunning); err != nil {
+		return nil, err
+	}
+
+	if err := c.startQuirks(); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkPortConflicts(); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkProjectQuotas(); err != nil {
+		return nil, err
+	}
+
+	if c.notify != nil {
+		c.notify.reset()
+	}
+
+	restore, err := c.applyVolatile()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := c.provisionSwap()
+	if err != nil {
+		release()
+		restore()
+		return nil, err
+	}
+
+	return func() {
+		release()
+		restore()
+	}, nil
+}
+
+// Start starts the container.
+func (c *Container) Start() (err error) {
+	finish, err := c.operation("Start")
+	if err != nil {
 		return err
 	}
+	defer finish(&err)
 
-	if !bool(C.go_lxc_start(c.container, 0, makeNullTerminatedArgs(args))) {
-		return ErrStartFailed
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		cleanup, err := c.prepareStart()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if !bool(C.go_lxc_start(c.container, 0, nil)) {
+			return ErrStartFailed
+		}
+		return nil
+	})
+}
+
+// StartWithArgs starts the container using given arguments.
+func (c *Container) StartWithArgs(args []string) (err error) {
+	finish, err := c.operation("StartWithArgs", args...)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer finish(&err)
+
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		cleanup, err := c.prepareStart()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if !bool(C.go_lxc_start(c.container, 0, makeNullTerminatedArgs(args))) {
+			return ErrStartFailed
+		}
+		return nil
+	})
 }
 
 // StartExecute starts a container. It runs a minimal init as PID 1 and the
 // requested program as the second process.
-func (c *Container) StartExecute(args []string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.container == nil {
-		return ErrNotDefined
+func (c *Container) StartExecute(args []string) (err error) {
+	finish, err := c.operation("StartExecute", args...)
+	if err != nil {
+		return err
 	}
+	defer finish(&err)
 
-	if err := c.makeSure(isNotRunning); err != nil {
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		cleanup, err := c.prepareStart()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if !bool(C.go_lxc_start(c.container, 1, makeNullTerminatedArgs(args))) {
+			return ErrStartFailed
+		}
+		return nil
+	})
+}
+
+// StartAndFreeze starts the container and freezes it as soon as liblxc
+// reports it running, before the lock is released, which allows keeping
+// pre-started containers around to be thawed on demand by Unfreeze. The
+// container isn't frozen before its init runs: init and whatever it started
+// meanwhile are frozen wherever they got to. The container is stopped again
+// if it can't be frozen.
+func (c *Container) StartAndFreeze() (err error) {
+	finish, err := c.operation("StartAndFreeze")
+	if err != nil {
 		return err
 	}
+	defer finish(&err)
 
-	if !bool(C.go_lxc_start(c.container, 1, makeNullTerminatedArgs(args))) {
-		return ErrStartFailed
-	}
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
 
-	return nil
+		cleanup, err := c.prepareStart()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if !bool(C.go_lxc_start(c.container, 0, nil)) {
+			return ErrStartFailed
+		}
+
+		if err := c.freeze(); err != nil {
+			C.go_lxc_stop(c.container)
+			return err
+		}
+		return nil
+	})
 }
 
 // Execute executes the given command in a temporary container.
@@ -688,26 +1007,130 @@ func (c *Container) Execute(args ...string) ([]byte, error) {
 }
 
 // Stop stops the container.
-func (c *Container) Stop() error {
+func (c *Container) Stop() (err error) {
+	finish, err := c.operation("Stop")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.container == nil {
+			return ErrNotDefined
+		}
+
+		if err := c.makeSure(isRunning); err != nil {
+			return err
+		}
+
+		if !bool(C.go_lxc_stop(c.container)) {
+			return ErrStopFailed
+		}
+		return nil
+	})
+}
+
+// Reboot reboots the container.
+func (c *Container) Reboot() (err error) {
+	finish, err := c.operation("Reboot")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.container == nil {
+			return ErrNotDefined
+		}
+
+		if err := c.makeSure(isRunning); err != nil {
+			return err
+		}
+
+		if !bool(C.go_lxc_reboot(c.container)) {
+			return ErrRebootFailed
+		}
+		return nil
+	})
+}
+
+// Shutdown shuts down the container.
+func (c *Container) Shutdown(timeout time.Duration) (err error) {
+	finish, err := c.operation("Shutdown", timeout.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	return c.retry(func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.container == nil {
+			return ErrNotDefined
+		}
+
+		if err := c.makeSure(isRunning); err != nil {
+			return err
+		}
+
+		if !bool(C.go_lxc_shutdown(c.container, C.int(timeout.Seconds()))) {
+			return ErrShutdownFailed
+		}
+		return nil
+	})
+}
+
+// Destroy destroys the container.
+func (c *Container) Destroy() (err error) {
+	finish, err := c.operation("Destroy")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.destroy()
+}
+
+// destroy destroys the container, releasing its swap file.
+//
+// Caller needs to hold the lock
+func (c *Container) destroy() error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
 
-	if err := c.makeSure(isRunning); err != nil {
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
 		return err
 	}
 
-	if !bool(C.go_lxc_stop(c.container)) {
-		return ErrStopFailed
+	if err := releaseSwap(c.configPath(), c.name()); err != nil {
+		return err
+	}
+
+	if !bool(C.go_lxc_destroy(c.container)) {
+		return ErrDestroyFailed
 	}
 	return nil
 }
 
-// Reboot reboots the container.
-func (c *Container) Reboot() error {
+// DestroyWithAllSnapshots destroys the container and its snapshots
+func (c *Container) DestroyWithAllSnapshots() (err error) {
+	finish, err := c.operation("DestroyWithAllSnapshots")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -715,37 +1138,122 @@ func (c *Container) Reboot() error {
 		return ErrNotDefined
 	}
 
-	if err := c.makeSure(isRunning); err != nil {
+	if err := c.makeSure(isDefined | isNotRunning | isGreaterEqualThanLXC11); err != nil {
 		return err
 	}
 
-	if !bool(C.go_lxc_reboot(c.container)) {
-		return ErrRebootFailed
+	if err := releaseSwap(c.configPath(), c.name()); err != nil {
+		return err
+	}
+
+	if !bool(C.go_lxc_destroy_with_snapshots(c.container)) {
+		return ErrDestroyWithAllSnapshotsFailed
 	}
 	return nil
 }
 
-// Shutdown shuts down the container.
-func (c *Container) Shutdown(timeout time.Duration) error {
+// Clone clones the container using given arguments with specified backend.
+func (c *Container) Clone(name string, options CloneOptions) (err error) {
+	finish, err := c.operation("Clone", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.container == nil {
-		return ErrNotDefined
+	// FIXME: bdevdata, newsize and hookargs
+	//
+	// bdevdata:
+	// zfs requires zfsroot
+	// lvm requires lvname/vgname/thinpool as well as fstype and fssize
+	// btrfs requires nothing
+	// dir requires nothing
+	//
+	// newsize: for blockdev-backed backingstores
+	//
+	// hookargs: additional arguments to pass to the clone hook script
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
+		return err
 	}
 
-	if err := c.makeSure(isRunning); err != nil {
+	// use Directory backend if not set
+	if options.Backend == 0 {
+		options.Backend = Directory
+	}
+
+	var flags int
+	if options.KeepName {
+		flags |= C.LXC_CLONE_KEEPNAME
+	}
+	if options.KeepMAC {
+		flags |= C.LXC_CLONE_KEEPMACADDR
+	}
+	if options.Snapshot {
+		flags |= C.LXC_CLONE_SNAPSHOT
+	}
+
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	cbackend := C.CString(options.Backend.String())
+	defer C.free(unsafe.Pointer(cbackend))
+
+	// the hostname of the original is replaced in the clone's /etc/hosts
+	key := "lxc.uts.name"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.utsname"
+	}
+	hostname := c.configItem(key)[0]
+	if hostname == "" {
+		hostname = c.name()
+	}
+
+	lxcpath := c.configPath()
+	if options.ConfigPath != "" {
+		clxcpath := C.CString(options.ConfigPath)
+		defer C.free(unsafe.Pointer(clxcpath))
+
+		if !bool(C.go_lxc_clone(c.container, cname, clxcpath, C.int(flags), cbackend)) {
+			return ErrCloneFailed
+		}
+		lxcpath = options.ConfigPath
+	} else {
+		if !bool(C.go_lxc_clone(c.container, cname, nil, C.int(flags), cbackend)) {
+			return ErrCloneFailed
+		}
+	}
+
+	if _, err := assignUUID(lxcpath, name); err != nil {
 		return err
 	}
 
-	if !bool(C.go_lxc_shutdown(c.container, C.int(timeout.Seconds()))) {
-		return ErrShutdownFailed
+	// the clone belongs to the owner of the original
+	owner, err := readOwner(c.configPath(), c.name())
+	if err != nil {
+		return err
 	}
-	return nil
+
+	if err := writeOwner(lxcpath, name, owner); err != nil {
+		return err
+	}
+
+	return finishClone(name, lxcpath, hostname, options)
 }
 
-// Destroy destroys the container.
-func (c *Container) Destroy() error {
+// ConvertStorage moves the rootfs of the container to another backend store.
+// The container is cloned into the target backend under a temporary name,
+// the original is renamed out of the way and the clone takes over its name,
+// keeping the hostname and MAC addresses. The original is only destroyed once
+// the clone replaced it, if the swap fails it gets its name back.
+func (c *Container) ConvertStorage(target BackendStore, opts ConvertStorageOptions) (err error) {
+	finish, err := c.operation("ConvertStorage", target.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -757,93 +1265,149 @@ func (c *Container) Destroy() error {
 		return err
 	}
 
-	if !bool(C.go_lxc_destroy(c.container)) {
-		return ErrDestroyFailed
+	if target == 0 || target == Best {
+		return ErrUnknownBackendStore
+	}
+
+	if c.backendStore() == target {
+		return nil
+	}
+
+	var csnapshots *C.struct_lxc_snapshot
+	size := int(C.go_lxc_snapshot_list(c.container, &csnapshots))
+	freeSnapshots(csnapshots, size)
+	if size > 0 && !opts.DestroySnapshots {
+		return fmt.Errorf("%s: %q", ErrHasSnapshots, c.name())
+	}
+
+	name := c.name()
+	lxcpath := c.configPath()
+
+	tmpName := opts.TemporaryName
+	if tmpName == "" {
+		tmpName = name + "-convert"
+	}
+
+	backupName := opts.BackupName
+	if backupName == "" {
+		backupName = name + "-orig"
+	}
+
+	ctmpname := C.CString(tmpName)
+	defer C.free(unsafe.Pointer(ctmpname))
+
+	cbackend := C.CString(target.String())
+	defer C.free(unsafe.Pointer(cbackend))
+
+	if !bool(C.go_lxc_clone(c.container, ctmpname, nil, C.LXC_CLONE_KEEPNAME|C.LXC_CLONE_KEEPMACADDR, cbackend)) {
+		return ErrCloneFailed
+	}
+
+	tmp, err := NewContainer(tmpName, lxcpath)
+	if err != nil {
+		return err
+	}
+	defer tmp.Release()
+
+	tmp.mu.Lock()
+	defer tmp.mu.Unlock()
+
+	// liblxc doesn't rename containers with snapshots
+	if size > 0 && !bool(C.go_lxc_snapshot_destroy_all(c.container)) {
+		tmp.destroy()
+		return ErrConvertStorageFailed
 	}
-	return nil
-}
 
-// DestroyWithAllSnapshots destroys the container and its snapshots
-func (c *Container) DestroyWithAllSnapshots() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	restoreIdentity := c.keepIdentity()
 
-	if c.container == nil {
-		return ErrNotDefined
+	if err := c.rename(backupName); err != nil {
+		tmp.destroy()
+		return fmt.Errorf("%s: %v", ErrConvertStorageFailed, err)
 	}
 
-	if err := c.makeSure(isDefined | isNotRunning | isGreaterEqualThanLXC11); err != nil {
+	if err := tmp.rename(name); err != nil {
+		tmp.destroy()
+		if err := c.rename(name); err != nil {
+			return fmt.Errorf("%s: original container left as %q", ErrConvertStorageFailed, backupName)
+		}
+		return fmt.Errorf("%s: %v", ErrConvertStorageFailed, err)
+	}
+
+	if err := restoreIdentity(name); err != nil {
 		return err
 	}
 
-	if !bool(C.go_lxc_destroy_with_snapshots(c.container)) {
-		return ErrDestroyWithAllSnapshotsFailed
+	// c takes over the handle of the clone, the original is released with tmp
+	c.container, tmp.container = tmp.container, c.container
+	if !bool(C.go_lxc_destroy(tmp.container)) {
+		return fmt.Errorf("%s: original container left as %q", ErrConvertStorageFailed, backupName)
+	}
+
+	C.go_lxc_clear_config(c.container)
+	if !bool(C.go_lxc_load_config(c.container, nil)) {
+		return ErrLoadConfigFailed
 	}
 	return nil
 }
 
-// Clone clones the container using given arguments with specified backend.
-func (c *Container) Clone(name string, options CloneOptions) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// FIXME: bdevdata, newsize and hookargs
-	//
-	// bdevdata:
-	// zfs requires zfsroot
-	// lvm requires lvname/vgname/thinpool as well as fstype and fssize
-	// btrfs requires nothing
-	// dir requires nothing
-	//
-	// newsize: for blockdev-backed backingstores
-	//
-	// hookargs: additional arguments to pass to the clone hook script
-	if err := c.makeSure(isDefined | isNotRunning); err != nil {
-		return err
+// backendStore returns the backend store of the container's rootfs.
+//
+// Caller needs to hold the lock
+func (c *Container) backendStore() BackendStore {
+	key := "lxc.rootfs.path"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.rootfs"
 	}
 
-	// use Directory backend if not set
-	if options.Backend == 0 {
-		options.Backend = Directory
+	rootfs := c.configItem(key)[0]
+	if strings.HasPrefix(rootfs, "/dev/") {
+		return LVM
 	}
 
-	var flags int
-	if options.KeepName {
-		flags |= C.LXC_CLONE_KEEPNAME
-	}
-	if options.KeepMAC {
-		flags |= C.LXC_CLONE_KEEPMACADDR
-	}
-	if options.Snapshot {
-		flags |= C.LXC_CLONE_SNAPSHOT
+	parts := strings.SplitN(rootfs, ":", 2)
+	if len(parts) != 2 {
+		return Directory
 	}
 
-	cname := C.CString(name)
-	defer C.free(unsafe.Pointer(cname))
+	switch parts[0] {
+	case "overlay", "overlayfs":
+		return Overlayfs
+	case "loop":
+		return Loopback
+	}
 
-	cbackend := C.CString(options.Backend.String())
-	defer C.free(unsafe.Pointer(cbackend))
+	if backend, ok := backendStoreMap[parts[0]]; ok {
+		return backend
+	}
+	return Directory
+}
 
-	if options.ConfigPath != "" {
-		clxcpath := C.CString(options.ConfigPath)
-		defer C.free(unsafe.Pointer(clxcpath))
+// BackendStore returns the backend store of the container's rootfs.
+func (c *Container) BackendStore() BackendStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-		if !bool(C.go_lxc_clone(c.container, cname, clxcpath, C.int(flags), cbackend)) {
-			return ErrCloneFailed
-		}
-	} else {
-		if !bool(C.go_lxc_clone(c.container, cname, nil, C.int(flags), cbackend)) {
-			return ErrCloneFailed
-		}
-	}
-	return nil
+	return c.backendStore()
 }
 
 // Rename renames the container.
-func (c *Container) Rename(name string) error {
+func (c *Container) Rename(name string) (err error) {
+	finish, err := c.operation("Rename", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.rename(name)
+}
+
+// rename renames the container, keeping its identity.
+//
+// Caller needs to hold the lock
+func (c *Container) rename(name string) error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
@@ -855,10 +1419,11 @@ func (c *Container) Rename(name string) error {
 	cname := C.CString(name)
 	defer C.free(unsafe.Pointer(cname))
 
+	restoreIdentity := c.keepIdentity()
 	if !bool(C.go_lxc_rename(c.container, cname)) {
 		return ErrRenameFailed
 	}
-	return nil
+	return restoreIdentity(name)
 }
 
 // Wait waits for container to reach a particular state.
@@ -916,6 +1481,46 @@ func (c *Container) ConfigItem(key string) []string {
 	return c.configItem(key)
 }
 
+// Caller needs to hold the lock
+func (c *Container) configItemE(key string) ([]string, error) {
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+
+	if int(C.go_lxc_get_config_item_len(c.container, ckey)) < 0 {
+		return nil, fmt.Errorf("%s: %q", ErrUnknownConfigItem, key)
+	}
+
+	return nonEmpty(c.configItem(key)), nil
+}
+
+// ConfigItemE returns the values of the given config item. Unlike ConfigItem
+// it fails for keys unknown to liblxc and returns no values for unset keys.
+// liblxc doesn't tell unset keys apart from keys set to an empty value.
+func (c *Container) ConfigItemE(key string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.configItemE(key)
+}
+
+// LookupConfigItem returns the value of the given config item and whether
+// it's set, values of keys accumulating several values are separated by
+// newlines. Unknown keys are reported as unset.
+func (c *Container) LookupConfigItem(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values, err := c.configItemE(key)
+	if err != nil || len(values) == 0 {
+		return "", false
+	}
+	return strings.Join(values, "\n"), true
+}
+
 func (c *Container) setConfigItem(key string, value string) error {
 	if c.container == nil {
 		return ErrNotDefined
@@ -934,7 +1539,13 @@ func (c *Container) setConfigItem(key string, value string) error {
 }
 
 // SetConfigItem sets the value of the given config item.
-func (c *Container) SetConfigItem(key string, value string) error {
+func (c *Container) SetConfigItem(key string, value string) (err error) {
+	finish, err := c.operation("SetConfigItem", key, value)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1006,8 +1617,53 @@ func (c *Container) CgroupItem(key string) []string {
 	return c.cgroupItem(key)
 }
 
+// ReadCgroupItems returns the values of the given cgroup items, reading all
+// of them in a single call into liblxc, which is cheaper than calling
+// CgroupItem for each of them in stats collection loops. Items which can't be
+// read are left out.
+func (c *Container) ReadCgroupItems(keys []string) (map[string][]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]string, len(keys))
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	ckeys := makeNullTerminatedArgs(keys)
+	if ckeys == nil {
+		return nil, ErrAllocationFailed
+	}
+	defer freeNullTerminatedArgs(ckeys, len(keys))
+
+	cvalues := C.go_lxc_get_cgroup_items(c.container, ckeys)
+	if cvalues == nil {
+		return nil, ErrAllocationFailed
+	}
+	defer freeNullTerminatedArgs(cvalues, len(keys))
+
+	values := (*[(1 << 29) - 1]*C.char)(unsafe.Pointer(cvalues))[:len(keys):len(keys)]
+	for i, key := range keys {
+		if values[i] == nil {
+			continue
+		}
+		ret[key] = strings.Split(strings.TrimSpace(C.GoString(values[i])), "\n")
+	}
+	return ret, nil
+}
+
 // SetCgroupItem sets the value of given cgroup subsystem value.
-func (c *Container) SetCgroupItem(key string, value string) error {
+func (c *Container) SetCgroupItem(key string, value string) (err error) {
+	finish, err := c.operation("SetCgroupItem", key, value)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1015,22 +1671,32 @@ func (c *Container) SetCgroupItem(key string, value string) error {
 }
 
 // ClearConfig completely clears the containers in-memory configuration.
+// See ClearConfigE to learn whether it was cleared.
 func (c *Container) ClearConfig() {
+	c.ClearConfigE()
+}
+
+// ClearConfigE is like ClearConfig but returns an error if the configuration
+// wasn't cleared, e.g. as the interceptor denied it.
+func (c *Container) ClearConfigE() (err error) {
+	finish, err := c.operation("ClearConfig")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.container == nil {
-		return
+		return ErrNotDefined
 	}
 
 	C.go_lxc_clear_config(c.container)
+	return nil
 }
 
-// ClearConfigItem clears the value of given config item.
-func (c *Container) ClearConfigItem(key string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+func (c *Container) clearConfigItem(key string) error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
@@ -1044,11 +1710,21 @@ func (c *Container) ClearConfigItem(key string) error {
 	return nil
 }
 
-// ConfigKeys returns the names of the config items.
-func (c *Container) ConfigKeys(key ...string) []string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// ClearConfigItem clears the value of given config item.
+func (c *Container) ClearConfigItem(key string) (err error) {
+	finish, err := c.operation("ClearConfigItem", key)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.clearConfigItem(key)
+}
 
+func (c *Container) configKeys(key ...string) []string {
 	if c.container == nil {
 		return nil
 	}
@@ -1074,11 +1750,50 @@ func (c *Container) ConfigKeys(key ...string) []string {
 	return strings.Split(ret, "\n")
 }
 
+// ConfigKeys returns the names of the config items.
+func (c *Container) ConfigKeys(key ...string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.configKeys(key...)
+}
+
 // LoadConfigFile loads the configuration file from given path.
-func (c *Container) LoadConfigFile(path string) error {
+func (c *Container) LoadConfigFile(path string) (err error) {
+	finish, err := c.operation("LoadConfigFile", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.loadConfigFile(path)
+}
+
+// loadConfigFile loads the configuration file from given path on top of the
+// current configuration.
+//
+// Caller needs to hold the lock
+func (c *Container) loadConfigFile(path string) error {
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	if !bool(C.go_lxc_load_config(c.container, cpath)) {
+		return ErrLoadConfigFailed
+	}
+	return nil
+}
+
+// reloadConfig replaces the in-memory configuration with the one in path.
+//
+// Caller needs to hold the lock
+func (c *Container) reloadConfig(path string) error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
@@ -1086,6 +1801,7 @@ func (c *Container) LoadConfigFile(path string) error {
 	cpath := C.CString(path)
 	defer C.free(unsafe.Pointer(cpath))
 
+	C.go_lxc_clear_config(c.container)
 	if !bool(C.go_lxc_load_config(c.container, cpath)) {
 		return ErrLoadConfigFailed
 	}
@@ -1107,7 +1823,13 @@ func (c *Container) saveConfigFile(path string) error {
 }
 
 // SaveConfigFile saves the configuration file to given path.
-func (c *Container) SaveConfigFile(path string) error {
+func (c *Container) SaveConfigFile(path string) (err error) {
+	finish, err := c.operation("SaveConfigFile", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1173,7 +1895,13 @@ func (c *Container) MemoryLimit() (ByteSize, error) {
 }
 
 // SetMemoryLimit sets memory limit of the container in bytes.
-func (c *Container) SetMemoryLimit(limit ByteSize) error {
+func (c *Container) SetMemoryLimit(limit ByteSize) (err error) {
+	finish, err := c.operation("SetMemoryLimit", limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1197,7 +1925,13 @@ func (c *Container) SoftMemoryLimit() (ByteSize, error) {
 }
 
 // SetSoftMemoryLimit sets soft  memory limit of the container in bytes.
-func (c *Container) SetSoftMemoryLimit(limit ByteSize) error {
+func (c *Container) SetSoftMemoryLimit(limit ByteSize) (err error) {
+	finish, err := c.operation("SetSoftMemoryLimit", limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1209,6 +1943,9 @@ func (c *Container) SetSoftMemoryLimit(limit ByteSize) error {
 }
 
 // KernelMemoryUsage returns current kernel memory allocation of the container in bytes.
+//
+// Deprecated: Kernel memory accounting only exists on cgroup v1 and was
+// removed from recent kernels. ErrNotSupported is returned on cgroup v2 hosts.
 func (c *Container) KernelMemoryUsage() (ByteSize, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -1217,10 +1954,16 @@ func (c *Container) KernelMemoryUsage() (ByteSize, error) {
 		return -1, err
 	}
 
+	if CgroupUnified() {
+		return -1, ErrNotSupported
+	}
+
 	return c.cgroupItemAsByteSize("memory.kmem.usage_in_bytes", ErrKMemLimit)
 }
 
 // KernelMemoryLimit returns kernel memory limit of the container in bytes.
+//
+// Deprecated: See KernelMemoryUsage.
 func (c *Container) KernelMemoryLimit() (ByteSize, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -1229,11 +1972,23 @@ func (c *Container) KernelMemoryLimit() (ByteSize, error) {
 		return -1, err
 	}
 
+	if CgroupUnified() {
+		return -1, ErrNotSupported
+	}
+
 	return c.cgroupItemAsByteSize("memory.kmem.limit_in_bytes", ErrKMemLimit)
 }
 
 // SetKernelMemoryLimit sets kernel memory limit of the container in bytes.
-func (c *Container) SetKernelMemoryLimit(limit ByteSize) error {
+//
+// Deprecated: See KernelMemoryUsage.
+func (c *Container) SetKernelMemoryLimit(limit ByteSize) (err error) {
+	finish, err := c.operation("SetKernelMemoryLimit", limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1241,6 +1996,10 @@ func (c *Container) SetKernelMemoryLimit(limit ByteSize) error {
 		return err
 	}
 
+	if CgroupUnified() {
+		return ErrNotSupported
+	}
+
 	return c.setCgroupItemWithByteSize("memory.kmem.limit_in_bytes", limit, ErrSettingKMemoryLimitFailed)
 }
 
@@ -1269,7 +2028,13 @@ func (c *Container) MemorySwapLimit() (ByteSize, error) {
 }
 
 // SetMemorySwapLimit sets memory+swap limit of the container in bytes.
-func (c *Container) SetMemorySwapLimit(limit ByteSize) error {
+func (c *Container) SetMemorySwapLimit(limit ByteSize) (err error) {
+	finish, err := c.operation("SetMemorySwapLimit", limit.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1388,7 +2153,8 @@ func (c *Container) CPUStats() (map[string]int64, error) {
 // Returns "ttyfd" on success, -1 on failure. The returned "ttyfd" is
 // used to keep the tty allocated. The caller should close "ttyfd" to
 // indicate that it is done with the allocated console so that it can
-// be allocated by another caller.
+// be allocated by another caller. See AllocateConsole for the number of the
+// allocated tty and resizing it.
 func (c *Container) ConsoleFd(ttynum int) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1409,10 +2175,43 @@ func (c *Container) ConsoleFd(ttynum int) (int, error) {
 	return ret, nil
 }
 
+// AllocateConsole allocates the console tty ttynum of the container, -1 for
+// the first available one and 0 for the console itself. Unlike ConsoleFd it
+// reports the allocated tty and keeps it until the returned ConsoleTTY is
+// closed.
+func (c *Container) AllocateConsole(ttynum int) (*ConsoleTTY, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	cttynum := C.int(ttynum)
+	cmainfd := C.int(-1)
+
+	ttyfd := int(C.go_lxc_console_getfds(c.container, &cttynum, &cmainfd))
+	if ttyfd < 0 {
+		return nil, ErrAttachFailed
+	}
+
+	return &ConsoleTTY{Num: int(cttynum), Fd: int(cmainfd), ttyFd: ttyfd}, nil
+}
+
 // Console allocates and runs a console tty from container
 //
 // This function will not return until the console has been exited by the user.
-func (c *Container) Console(options ConsoleOptions) error {
+func (c *Container) Console(options ConsoleOptions) (err error) {
+	finish, err := c.operation("Console")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1424,6 +2223,16 @@ func (c *Container) Console(options ConsoleOptions) error {
 		return err
 	}
 
+	if options.Recorder != nil {
+		fd, restore, err := options.Recorder.interpose(options.StdinFd, options.StdoutFd)
+		if err != nil {
+			return err
+		}
+		defer restore()
+
+		options.StdinFd, options.StdoutFd, options.StderrFd = fd, fd, fd
+	}
+
 	ret := bool(C.go_lxc_console(c.container,
 		C.int(options.Tty),
 		C.int(options.StdinFd),
@@ -1439,7 +2248,13 @@ func (c *Container) Console(options ConsoleOptions) error {
 
 // AttachShell attaches a shell to the container.
 // It clears all environment variables before attaching.
-func (c *Container) AttachShell(options AttachOptions) error {
+func (c *Container) AttachShell(options AttachOptions) (err error) {
+	finish, err := c.operation("AttachShell")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1451,6 +2266,21 @@ func (c *Container) AttachShell(options AttachOptions) error {
 		return err
 	}
 
+	options, err = c.resolveAttachOptions(options)
+	if err != nil {
+		return err
+	}
+
+	if options.Recorder != nil {
+		fd, restore, err := options.Recorder.interpose(options.StdinFd, options.StdoutFd)
+		if err != nil {
+			return err
+		}
+		defer restore()
+
+		options.StdinFd, options.StdoutFd, options.StderrFd = fd, fd, fd
+	}
+
 	cenv := makeNullTerminatedArgs(options.Env)
 	if cenv == nil {
 		return ErrAllocationFailed
@@ -1468,6 +2298,12 @@ func (c *Container) AttachShell(options AttachOptions) error {
 
 	groups := makeGroups(options.Groups)
 
+	extras, freeExtras, err := makeExtraAttachOpts(options)
+	if err != nil {
+		return err
+	}
+	defer freeExtras()
+
 	ret := int(C.go_lxc_attach(c.container,
 		C.bool(options.ClearEnv),
 		C.int(options.Namespaces),
@@ -1482,7 +2318,11 @@ func (c *Container) AttachShell(options AttachOptions) error {
 		cenv,
 		cenvToKeep,
 		C.int(attachFlags(options)),
+		extras,
 	))
+	if ret == C.GO_LXC_RLIMITS_FAILED {
+		return ErrSettingRlimitsFailed
+	}
 	if ret < 0 {
 		return ErrAttachFailed
 	}
@@ -1496,12 +2336,75 @@ func attachFlags(opts AttachOptions) int {
 		flags |= C.LXC_ATTACH_REMOUNT_PROC_SYS
 	}
 
+	if opts.KeepCaps {
+		flags &^= C.LXC_ATTACH_DROP_CAPABILITIES
+	}
+
 	if opts.ElevatedPrivileges {
 		flags &^= (C.LXC_ATTACH_MOVE_TO_CGROUP | C.LXC_ATTACH_DROP_CAPABILITIES | C.LXC_ATTACH_LSM_EXEC)
 	}
 	return flags
 }
 
+// makeExtraAttachOpts returns the options liblxc has no notion of for the
+// C helpers, the returned function frees them.
+func makeExtraAttachOpts(opts AttachOptions) (*C.struct_extra_attach_opts, func(), error) {
+	extras := (*C.struct_extra_attach_opts)(C.calloc(1, C.sizeof_struct_extra_attach_opts))
+	if extras == nil {
+		return nil, nil, ErrAllocationFailed
+	}
+
+	extras.umask = -1
+	if opts.Umask != nil {
+		extras.umask = C.int(*opts.Umask & 0777)
+	}
+	extras.no_new_privs = C.bool(opts.NoNewPrivs)
+
+	free := func() {
+		C.free(unsafe.Pointer(extras.lsm_label))
+		C.free(unsafe.Pointer(extras.rlimit_resources))
+		C.free(unsafe.Pointer(extras.rlimit_soft))
+		C.free(unsafe.Pointer(extras.rlimit_hard))
+		C.free(unsafe.Pointer(extras))
+	}
+
+	if n := len(opts.Rlimits); n > 0 {
+		extras.rlimit_resources = (*C.int)(C.calloc(C.size_t(n), C.sizeof_int))
+		extras.rlimit_soft = (*C.uint64_t)(C.calloc(C.size_t(n), C.sizeof_uint64_t))
+		extras.rlimit_hard = (*C.uint64_t)(C.calloc(C.size_t(n), C.sizeof_uint64_t))
+		if extras.rlimit_resources == nil || extras.rlimit_soft == nil || extras.rlimit_hard == nil {
+			free()
+			return nil, nil, ErrAllocationFailed
+		}
+
+		resources := (*[1 << 20]C.int)(unsafe.Pointer(extras.rlimit_resources))[:n:n]
+		soft := (*[1 << 20]C.uint64_t)(unsafe.Pointer(extras.rlimit_soft))[:n:n]
+		hard := (*[1 << 20]C.uint64_t)(unsafe.Pointer(extras.rlimit_hard))[:n:n]
+
+		for i, r := range opts.Rlimits {
+			if err := r.validate(); err != nil {
+				free()
+				return nil, nil, err
+			}
+
+			resources[i] = C.int(rlimitResources[r.Resource])
+			soft[i] = C.uint64_t(r.Soft)
+			hard[i] = C.uint64_t(r.Hard)
+		}
+		extras.nr_rlimits = C.int(n)
+	}
+
+	if label := attachLSMLabel(opts, appArmorEnabled()); label != "" {
+		if !VersionAtLeast(4, 0, 0) {
+			free()
+			return nil, nil, ErrNotSupported
+		}
+		extras.lsm_label = C.CString(label)
+	}
+
+	return extras, free, nil
+}
+
 func makeGroups(groups []int) C.struct_lxc_groups_t {
 	if len(groups) == 0 {
 		return C.struct_lxc_groups_t{size: 0, list: nil}
@@ -1526,6 +2429,11 @@ func (c *Container) runCommandStatus(args []string, options AttachOptions) (int,
 		return -1, err
 	}
 
+	options, err := c.resolveAttachOptions(options)
+	if err != nil {
+		return -1, err
+	}
+
 	cargs := makeNullTerminatedArgs(args)
 	if cargs == nil {
 		return -1, ErrAllocationFailed
@@ -1549,6 +2457,12 @@ func (c *Container) runCommandStatus(args []string, options AttachOptions) (int,
 
 	groups := makeGroups(options.Groups)
 
+	extras, freeExtras, err := makeExtraAttachOpts(options)
+	if err != nil {
+		return -1, err
+	}
+	defer freeExtras()
+
 	ret := int(C.go_lxc_attach_run_wait(
 		c.container,
 		C.bool(options.ClearEnv),
@@ -1565,8 +2479,12 @@ func (c *Container) runCommandStatus(args []string, options AttachOptions) (int,
 		cenvToKeep,
 		cargs,
 		C.int(attachFlags(options)),
+		extras,
 	))
 
+	if ret == C.GO_LXC_RLIMITS_FAILED {
+		return -1, ErrSettingRlimitsFailed
+	}
 	if ret < 0 {
 		return ret, nil
 	}
@@ -1579,7 +2497,13 @@ func (c *Container) runCommandStatus(args []string, options AttachOptions) (int,
 // The process will wait for the command to finish and return the result of
 // waitpid(), i.e. the process' exit status. An error is returned only when
 // invocation of the command completely fails.
-func (c *Container) RunCommandStatus(args []string, options AttachOptions) (int, error) {
+func (c *Container) RunCommandStatus(args []string, options AttachOptions) (status int, err error) {
+	finish, err := c.operation("RunCommandStatus", args...)
+	if err != nil {
+		return -1, err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1587,7 +2511,13 @@ func (c *Container) RunCommandStatus(args []string, options AttachOptions) (int,
 }
 
 // RunCommandNoWait runs the given command and returns without waiting it to finish.
-func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (int, error) {
+func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (pid int, err error) {
+	finish, err := c.operation("RunCommandNoWait", args...)
+	if err != nil {
+		return -1, err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1599,6 +2529,11 @@ func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (int,
 		return -1, err
 	}
 
+	options, err = c.resolveAttachOptions(options)
+	if err != nil {
+		return -1, err
+	}
+
 	cargs := makeNullTerminatedArgs(args)
 	if cargs == nil {
 		return -1, ErrAllocationFailed
@@ -1622,6 +2557,12 @@ func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (int,
 
 	groups := makeGroups(options.Groups)
 
+	extras, freeExtras, err := makeExtraAttachOpts(options)
+	if err != nil {
+		return -1, err
+	}
+	defer freeExtras()
+
 	var attachedPid C.pid_t
 	ret := int(C.go_lxc_attach_no_wait(
 		c.container,
@@ -1640,8 +2581,12 @@ func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (int,
 		cargs,
 		&attachedPid,
 		C.int(attachFlags(options)),
+		extras,
 	))
 
+	if ret == C.GO_LXC_RLIMITS_FAILED {
+		return -1, ErrSettingRlimitsFailed
+	}
 	if ret < 0 {
 		return ret, ErrAttachFailed
 	}
@@ -1649,10 +2594,124 @@ func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (int,
 	return int(attachedPid), nil
 }
 
+// RunCommandAsync runs the given command and returns without waiting it to finish.
+//
+// Unlike RunCommandNoWait, the command is not a child of the calling process.
+// The current executable is run as helper which attaches and reaps the
+// command, reporting its exit status back over a pipe, so the result can't
+// be lost to a SIGCHLD handler or a waitpid(-1) loop elsewhere in the host
+// process. Use the returned AttachedProcess to wait for the command.
+func (c *Container) RunCommandAsync(args []string, options AttachOptions) (p *AttachedProcess, err error) {
+	finish, err := c.operation("RunCommandAsync", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if len(args) == 0 {
+		return nil, ErrInsufficientNumberOfArguments
+	}
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	options, err = c.resolveAttachOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range options.Rlimits {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the helper gets the resolved options
+	options.User = ""
+	options.EnvToKeepGlob = nil
+	options.CloseInheritedFds = false
+	options.Recorder = nil
+
+	payload, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	// the files only close the duplicates, the fds belong to the caller
+	var stdio []*os.File
+	defer func() {
+		for _, f := range stdio {
+			f.Close()
+		}
+	}()
+	for _, fd := range []uintptr{options.StdinFd, options.StdoutFd, options.StderrFd} {
+		dup, err := unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			return nil, err
+		}
+		stdio = append(stdio, os.NewFile(uintptr(dup), "attach stdio"))
+	}
+
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	optionsR, optionsW, err := os.Pipe()
+	if err != nil {
+		statusR.Close()
+		statusW.Close()
+		return nil, err
+	}
+
+	cmd := exec.Command(exe, append([]string{attachHelperArg, c.name(), c.configPath()}, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdio[0], stdio[1], stdio[2]
+	cmd.ExtraFiles = []*os.File{statusW, optionsR}
+
+	err = cmd.Start()
+
+	// only the helper keeps these ends, so the pipe is closed once it exits
+	statusW.Close()
+	optionsR.Close()
+
+	if err != nil {
+		statusR.Close()
+		optionsW.Close()
+		return nil, err
+	}
+	helper := cmd.Process.Pid
+	cmd.Process.Release()
+
+	// the write fails if the helper exited, which newAttachedProcess reports
+	optionsW.Write(payload)
+	optionsW.Close()
+
+	return newAttachedProcess(helper, statusR)
+}
+
 // RunCommand attachs a shell and runs the command within the container.
 // The process will wait for the command to finish and return a success status. An error
 // is returned only when invocation of the command completely fails.
-func (c *Container) RunCommand(args []string, options AttachOptions) (bool, error) {
+func (c *Container) RunCommand(args []string, options AttachOptions) (ok bool, err error) {
+	finish, err := c.operation("RunCommand", args...)
+	if err != nil {
+		return false, err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1903,6 +2962,196 @@ func (c *Container) IPv6Addresses() ([]string, error) {
 	return convertArgs(result), nil
 }
 
+// getIPs returns the addresses of the given family and scope on the
+// interface. Empty interface and family match all interfaces and families.
+//
+// Caller needs to hold the lock
+func (c *Container) getIPs(interfaceName string, family string, scope int) ([]string, bool) {
+	var cinterface, cfamily *C.char
+
+	if interfaceName != "" {
+		cinterface = C.CString(interfaceName)
+		defer C.free(unsafe.Pointer(cinterface))
+	}
+
+	if family != "" {
+		cfamily = C.CString(family)
+		defer C.free(unsafe.Pointer(cfamily))
+	}
+
+	result := C.go_lxc_get_ips(c.container, cinterface, cfamily, C.int(scope))
+	if result == nil {
+		return nil, false
+	}
+	return convertArgs(result), true
+}
+
+// ips returns the addresses of the given family and scope on the interface.
+//
+// Caller needs to hold the lock
+func (c *Container) ips(interfaceName string, family string, scope int) []net.IP {
+	result, _ := c.getIPs(interfaceName, family, scope)
+
+	var ips []net.IP
+	for _, v := range result {
+		if ip := net.ParseIP(v); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// inet6Indices returns the interface indices inside the container's network
+// namespace, as needed to look up link-local IPv6 addresses.
+//
+// Caller needs to hold the lock
+func (c *Container) inet6Indices() map[string]int {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/net/if_inet6", int(C.go_lxc_init_pid(c.container))))
+	if err != nil {
+		return nil
+	}
+
+	return parseIfInet6(string(content))
+}
+
+// parseIfInet6 parses /proc/net/if_inet6 into a map of interface names to
+// interface indices.
+func parseIfInet6(content string) map[string]int {
+	indices := make(map[string]int)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 6 {
+			continue
+		}
+
+		index, err := strconv.ParseInt(fields[1], 16, 32)
+		if err != nil {
+			continue
+		}
+		indices[fields[5]] = int(index)
+	}
+	return indices
+}
+
+func checkAddressFamily(family string) error {
+	switch family {
+	case "", "inet", "inet6":
+		return nil
+	}
+	return fmt.Errorf("%s: %q", ErrUnknownAddressFamily, family)
+}
+
+func (c *Container) ipAddressesWithOptions(opts IPAddressOptions) (map[string][]net.IP, error) {
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	if err := checkAddressFamily(opts.Family); err != nil {
+		return nil, err
+	}
+	inet := opts.Family == "" || opts.Family == "inet"
+	inet6 := opts.Family == "" || opts.Family == "inet6"
+
+	interfaces := []string{opts.Interface}
+	if opts.Interface == "" {
+		result := C.go_lxc_get_interfaces(c.container)
+		if result == nil {
+			return nil, ErrInterfaces
+		}
+		interfaces = convertArgs(result)
+	}
+
+	var indices map[string]int
+	if opts.IncludeLinkLocal && inet6 {
+		indices = c.inet6Indices()
+	}
+
+	addresses := make(map[string][]net.IP)
+	for _, iface := range interfaces {
+		if iface == "lo" && opts.Interface == "" {
+			continue
+		}
+
+		var ips []net.IP
+		if inet {
+			for _, ip := range c.ips(iface, "inet", 0) {
+				if !ip.IsLinkLocalUnicast() || opts.IncludeLinkLocal {
+					ips = append(ips, ip)
+				}
+			}
+		}
+
+		if inet6 {
+			ips = append(ips, c.ips(iface, "inet6", opts.Scope)...)
+
+			// Link-local addresses are scoped to their interface index.
+			if index, ok := indices[iface]; ok && index != opts.Scope {
+				ips = append(ips, c.ips(iface, "inet6", index)...)
+			}
+		}
+
+		if len(ips) > 0 {
+			addresses[iface] = ips
+		}
+	}
+
+	return addresses, nil
+}
+
+// IPAddressesWithOptions returns the IP addresses matching the given options,
+// indexed by interface name.
+func (c *Container) IPAddressesWithOptions(opts IPAddressOptions) (map[string][]net.IP, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ipAddressesWithOptions(opts)
+}
+
+// WaitIPAddressesWithOptions waits until IPAddressesWithOptions returns
+// something (or a global unicast address if WaitForGlobal is set) or time outs.
+func (c *Container) WaitIPAddressesWithOptions(opts IPAddressOptions, timeout time.Duration) (map[string][]net.IP, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if err := checkAddressFamily(opts.Family); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for {
+		result, err := c.ipAddressesWithOptions(opts)
+
+		if err == nil && hasAddress(result, opts.WaitForGlobal) {
+			return result, nil
+		}
+
+		time.Sleep(1 * time.Second)
+
+		if time.Since(now) >= timeout {
+			return nil, ErrIPAddresses
+		}
+	}
+}
+
+func hasAddress(addresses map[string][]net.IP, global bool) bool {
+	for _, ips := range addresses {
+		for _, ip := range ips {
+			if !global || ip.IsGlobalUnicast() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // LogFile returns the name of the logfile.
 func (c *Container) LogFile() string {
 	c.mu.RLock()
@@ -1963,7 +3212,13 @@ func (c *Container) SetLogLevel(level LogLevel) error {
 }
 
 // AddDeviceNode adds specified device to the container.
-func (c *Container) AddDeviceNode(source string, destination ...string) error {
+func (c *Container) AddDeviceNode(source string, destination ...string) (err error) {
+	finish, err := c.operation("AddDeviceNode", append([]string{source}, destination...)...)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -1995,7 +3250,13 @@ func (c *Container) AddDeviceNode(source string, destination ...string) error {
 }
 
 // RemoveDeviceNode removes the specified device from the container.
-func (c *Container) RemoveDeviceNode(source string, destination ...string) error {
+func (c *Container) RemoveDeviceNode(source string, destination ...string) (err error) {
+	finish, err := c.operation("RemoveDeviceNode", append([]string{source}, destination...)...)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -2027,10 +3288,23 @@ func (c *Container) RemoveDeviceNode(source string, destination ...string) error
 }
 
 // Checkpoint checkpoints the container.
-func (c *Container) Checkpoint(opts CheckpointOptions) error {
+func (c *Container) Checkpoint(opts CheckpointOptions) (err error) {
+	finish, err := c.operation("Checkpoint", opts.Directory)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.checkpoint(opts)
+}
+
+// checkpoint checkpoints the container with CRIU.
+//
+// Caller needs to hold the lock
+func (c *Container) checkpoint(opts CheckpointOptions) error {
 	if c.container == nil {
 		return ErrNotDefined
 	}
@@ -2052,7 +3326,13 @@ func (c *Container) Checkpoint(opts CheckpointOptions) error {
 }
 
 // Restore restores the container from a checkpoint.
-func (c *Container) Restore(opts RestoreOptions) error {
+func (c *Container) Restore(opts RestoreOptions) (err error) {
+	finish, err := c.operation("Restore", opts.Directory)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -2076,7 +3356,13 @@ func (c *Container) Restore(opts RestoreOptions) error {
 }
 
 // Migrate migrates the container.
-func (c *Container) Migrate(cmd uint, opts MigrateOptions) error {
+func (c *Container) Migrate(cmd uint, opts MigrateOptions) (err error) {
+	finish, err := c.operation("Migrate", opts.Directory)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -2136,7 +3422,13 @@ func (c *Container) Migrate(cmd uint, opts MigrateOptions) error {
 }
 
 // AttachInterface attaches specified netdev to the container.
-func (c *Container) AttachInterface(source, destination string) error {
+func (c *Container) AttachInterface(source, destination string) (err error) {
+	finish, err := c.operation("AttachInterface", source, destination)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -2161,7 +3453,13 @@ func (c *Container) AttachInterface(source, destination string) error {
 }
 
 // DetachInterface detaches specified netdev from the container.
-func (c *Container) DetachInterface(source string) error {
+func (c *Container) DetachInterface(source string) (err error) {
+	finish, err := c.operation("DetachInterface", source)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -2183,7 +3481,13 @@ func (c *Container) DetachInterface(source string) error {
 }
 
 // DetachInterfaceRename detaches specified netdev from the container and renames it.
-func (c *Container) DetachInterfaceRename(source, target string) error {
+func (c *Container) DetachInterfaceRename(source, target string) (err error) {
+	finish, err := c.operation("DetachInterfaceRename", source, target)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
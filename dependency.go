@@ -0,0 +1,228 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthCheck returns nil once a started container is ready to be depended
+// on, or an error if it won't become ready before ctx is done.
+type HealthCheck func(ctx context.Context, c *Container) error
+
+// WaitRunning is a HealthCheck waiting for the container to be RUNNING.
+func WaitRunning(ctx context.Context, c *Container) error {
+	for !c.Wait(RUNNING, time.Second) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitNotifyReady is a HealthCheck waiting for the guest to send READY=1
+// over the socket set up by NotifySocket.
+func WaitNotifyReady(ctx context.Context, c *Container) error {
+	return c.WaitReady(ctx)
+}
+
+// DependencyManager starts and stops a set of containers honoring the
+// dependencies among them.
+type DependencyManager struct {
+	mu         sync.Mutex
+	names      []string
+	containers map[string]*Container
+	health     map[string]HealthCheck
+	deps       map[string][]string
+}
+
+// NewDependencyManager returns an empty DependencyManager.
+func NewDependencyManager() *DependencyManager {
+	return &DependencyManager{
+		containers: make(map[string]*Container),
+		health:     make(map[string]HealthCheck),
+		deps:       make(map[string][]string),
+	}
+}
+
+// Add adds the container, its dependents are started once health returns
+// (default: WaitRunning).
+func (m *DependencyManager) Add(c *Container, health HealthCheck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := c.Name()
+	if _, ok := m.containers[name]; ok {
+		return fmt.Errorf("%s: %q", ErrAlreadyDefined, name)
+	}
+
+	if health == nil {
+		health = WaitRunning
+	}
+
+	m.names = append(m.names, name)
+	m.containers[name] = c
+	m.health[name] = health
+	return nil
+}
+
+// Require declares that the named container needs the given dependencies,
+// e.g. Require("app", "db") starts "db" before "app" and stops it after.
+func (m *DependencyManager) Require(name string, dependencies ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range append([]string{name}, dependencies...) {
+		if _, ok := m.containers[n]; !ok {
+			return fmt.Errorf("%s: %q", ErrUnknownDependency, n)
+		}
+	}
+
+	m.deps[name] = append(m.deps[name], dependencies...)
+	return nil
+}
+
+// topologicalOrder sorts names so that every name comes after its
+// dependencies, keeping the given order otherwise.
+func topologicalOrder(names []string, deps map[string][]string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	var order []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%s: %q", ErrDependencyCycle, name)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Order returns the names of the containers in the order they are started.
+func (m *DependencyManager) Order() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return topologicalOrder(m.names, m.deps)
+}
+
+// StartAll starts the containers which aren't running yet. Each container is
+// started once the health checks of all its dependencies passed, independent
+// containers are started concurrently. The first failure aborts the
+// containers which didn't start yet.
+func (m *DependencyManager) StartAll(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := topologicalOrder(m.names, m.deps)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ready := make(map[string]chan struct{})
+	for _, name := range order {
+		ready[name] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failure error
+
+	for _, name := range order {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			err := func() error {
+				for _, dep := range m.deps[name] {
+					select {
+					case <-ready[dep]:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+
+				c := m.containers[name]
+				if !c.Running() {
+					if err := c.Start(); err != nil {
+						return err
+					}
+				}
+				return m.health[name](ctx, c)
+			}()
+
+			if err != nil {
+				once.Do(func() {
+					failure = fmt.Errorf("%s: %q", err, name)
+					cancel()
+				})
+				return
+			}
+			close(ready[name])
+		}(name)
+	}
+	wg.Wait()
+
+	return failure
+}
+
+// StopAll stops the running containers in the reverse order of StartAll,
+// shutting each down cleanly within timeout before stopping it.
+func (m *DependencyManager) StopAll(timeout time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := topologicalOrder(m.names, m.deps)
+	if err != nil {
+		return err
+	}
+
+	for i := len(order) - 1; i >= 0; i-- {
+		c := m.containers[order[i]]
+		if !c.Running() {
+			continue
+		}
+
+		if err := c.Shutdown(timeout); err != nil {
+			if err := c.Stop(); err != nil {
+				return fmt.Errorf("%s: %q", err, order[i])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DeviceProfile is a named set of devices applied to containers as a unit,
+// e.g. all USB serial adapters of a host.
+type DeviceProfile struct {
+	Name string `json:"name"`
+	// Devices are host device nodes allowed and bind mounted into the
+	// container at the same path.
+	Devices []string `json:"devices,omitempty"`
+	// Allow are additional device cgroup rules, e.g. "c 188:* rwm".
+	Allow []string `json:"allow,omitempty"`
+	// Mounts are additional lxc.mount.entry values.
+	Mounts []string `json:"mounts,omitempty"`
+}
+
+// deviceProfileDir returns the directory the device profiles of lxcpath are
+// stored in.
+func deviceProfileDir(lxcpath string) string {
+	return filepath.Join(lxcpath, "device-profiles")
+}
+
+// validProfileName returns true if name can be used as a file name.
+func validProfileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\x00")
+}
+
+// SaveDeviceProfile stores the profile under lxcpath (default:
+// DefaultConfigPath()), replacing a profile of the same name.
+func SaveDeviceProfile(profile DeviceProfile, lxcpath ...string) error {
+	if !validProfileName(profile.Name) {
+		return fmt.Errorf("%s: %q", ErrInvalidProfile, profile.Name)
+	}
+
+	for _, rule := range profile.Allow {
+		if _, err := ParseDeviceRule(rule); err != nil {
+			return fmt.Errorf("%s: %v", ErrInvalidProfile, err)
+		}
+	}
+
+	dir := deviceProfileDir(profileConfigPath(lxcpath))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(profile, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, profile.Name+".json"), content, 0644)
+}
+
+// LoadDeviceProfile returns the named profile stored under lxcpath.
+func LoadDeviceProfile(name string, lxcpath ...string) (DeviceProfile, error) {
+	var profile DeviceProfile
+
+	if !validProfileName(name) {
+		return profile, fmt.Errorf("%s: %q", ErrInvalidProfile, name)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(deviceProfileDir(profileConfigPath(lxcpath)), name+".json"))
+	if os.IsNotExist(err) {
+		return profile, fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	} else if err != nil {
+		return profile, err
+	}
+
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return profile, fmt.Errorf("%s: %v", ErrInvalidProfile, err)
+	}
+	profile.Name = name
+	return profile, nil
+}
+
+// DeleteDeviceProfile removes the named profile stored under lxcpath.
+// Containers it was applied to keep their devices.
+func DeleteDeviceProfile(name string, lxcpath ...string) error {
+	if !validProfileName(name) {
+		return fmt.Errorf("%s: %q", ErrInvalidProfile, name)
+	}
+
+	err := os.Remove(filepath.Join(deviceProfileDir(profileConfigPath(lxcpath)), name+".json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	}
+	return err
+}
+
+// DeviceProfiles returns the names of the profiles stored under lxcpath.
+func DeviceProfiles(lxcpath ...string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(deviceProfileDir(profileConfigPath(lxcpath)), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(match), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// profileConfigPath returns the optional lxcpath argument or the default.
+func profileConfigPath(lxcpath []string) string {
+	if len(lxcpath) > 0 && lxcpath[0] != "" {
+		return lxcpath[0]
+	}
+	return DefaultConfigPath()
+}
+
+// deviceProfileItems returns the config items the profile adds. Device
+// nodes missing on the host are an error.
+func deviceProfileItems(profile DeviceProfile) ([]KeyValue, error) {
+	var items []KeyValue
+
+	for _, path := range profile.Devices {
+		rule, err := deviceRuleForNode(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrInvalidProfile, err)
+		}
+
+		items = append(items,
+			KeyValue{devicesConfigKey("allow"), rule.String()},
+			KeyValue{"lxc.mount.entry", fmt.Sprintf("%s %s none bind,optional,create=file", path, strings.TrimPrefix(path, "/"))},
+		)
+	}
+
+	for _, v := range profile.Allow {
+		rule, err := ParseDeviceRule(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrInvalidProfile, err)
+		}
+		items = append(items, KeyValue{devicesConfigKey("allow"), rule.String()})
+	}
+
+	for _, entry := range profile.Mounts {
+		items = append(items, KeyValue{"lxc.mount.entry", entry})
+	}
+	return items, nil
+}
+
+// removeConfigValue removes one occurrence of value from the given key,
+// keeping the other values in order.
+//
+// Caller needs to hold the lock
+func (c *Container) removeConfigValue(key string, value string) error {
+	values := c.configItem(key)
+
+	if err := c.clearConfigItem(key); err != nil {
+		return err
+	}
+
+	removed := false
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		if v == value && !removed {
+			removed = true
+			continue
+		}
+
+		if err := c.setConfigItem(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedDeviceProfilesPath returns the file recording the items added by
+// each profile applied to the container.
+//
+// Caller needs to hold the lock
+func (c *Container) appliedDeviceProfilesPath() string {
+	return filepath.Join(c.configPath(), c.name(), "device-profiles.json")
+}
+
+// appliedDeviceProfiles returns the items added by each applied profile.
+//
+// Caller needs to hold the lock
+func (c *Container) appliedDeviceProfiles() (map[string][]KeyValue, error) {
+	applied := make(map[string][]KeyValue)
+
+	content, err := ioutil.ReadFile(c.appliedDeviceProfilesPath())
+	if os.IsNotExist(err) {
+		return applied, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// saveAppliedDeviceProfiles records the applied profiles and saves the
+// configuration of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) saveAppliedDeviceProfiles(applied map[string][]KeyValue) error {
+	path := c.appliedDeviceProfilesPath()
+
+	if len(applied) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		content, err := json.MarshalIndent(applied, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config"))
+}
+
+// AppliedDeviceProfiles returns the names of the device profiles applied to
+// the container.
+func (c *Container) AppliedDeviceProfiles() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	applied, err := c.appliedDeviceProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range applied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ApplyProfile adds the devices of the named profile stored under the
+// lxcpath of the container to its configuration and saves it. The devices
+// are available on the next start. Applying a profile again replaces the
+// devices it added before.
+func (c *Container) ApplyProfile(name string) (err error) {
+	finish, err := c.operation("ApplyProfile", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	profile, err := LoadDeviceProfile(name, c.configPath())
+	if err != nil {
+		return err
+	}
+
+	items, err := deviceProfileItems(profile)
+	if err != nil {
+		return err
+	}
+
+	applied, err := c.appliedDeviceProfiles()
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range applied[name] {
+		if err := c.removeConfigValue(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+
+	for _, kv := range items {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+		}
+	}
+
+	applied[name] = items
+	return c.saveAppliedDeviceProfiles(applied)
+}
+
+// RemoveProfile removes the devices added by the named profile from the
+// configuration of the container and saves it. The items recorded when the
+// profile was applied are removed, so this works even if the profile was
+// changed or deleted in the meantime.
+func (c *Container) RemoveProfile(name string) (err error) {
+	finish, err := c.operation("RemoveProfile", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	applied, err := c.appliedDeviceProfiles()
+	if err != nil {
+		return err
+	}
+
+	items, ok := applied[name]
+	if !ok {
+		return fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	}
+
+	for _, kv := range items {
+		if err := c.removeConfigValue(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+
+	delete(applied, name)
+	return c.saveAppliedDeviceProfiles(applied)
+}
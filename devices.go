@@ -0,0 +1,207 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeviceType represents the type of a device in a device cgroup rule.
+type DeviceType string
+
+const (
+	// DeviceTypeAll matches all devices
+	DeviceTypeAll DeviceType = "a"
+	// DeviceTypeChar matches character devices
+	DeviceTypeChar DeviceType = "c"
+	// DeviceTypeBlock matches block devices
+	DeviceTypeBlock DeviceType = "b"
+)
+
+// DeviceWildcard matches any major or minor number.
+const DeviceWildcard = -1
+
+// DeviceRule represents a single device cgroup rule such as "c 1:3 rwm".
+type DeviceRule struct {
+	Type  DeviceType
+	Major int
+	Minor int
+	// Access is any combination of "r" (read), "w" (write) and "m" (mknod).
+	Access string
+}
+
+// String returns the rule in the format used by devices.allow and devices.deny.
+func (r DeviceRule) String() string {
+	if r.Type == DeviceTypeAll {
+		return string(DeviceTypeAll)
+	}
+
+	number := func(n int) string {
+		if n == DeviceWildcard {
+			return "*"
+		}
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%s %s:%s %s", r.Type, number(r.Major), number(r.Minor), r.Access)
+}
+
+// ParseDeviceRule parses a device cgroup rule such as "c 1:3 rwm" or "a".
+func ParseDeviceRule(s string) (DeviceRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+	}
+
+	rule := DeviceRule{Type: DeviceType(fields[0]), Major: DeviceWildcard, Minor: DeviceWildcard, Access: "rwm"}
+	switch rule.Type {
+	case DeviceTypeAll:
+		if len(fields) != 1 {
+			return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+		}
+		return rule, nil
+	case DeviceTypeChar, DeviceTypeBlock:
+	default:
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+	}
+
+	if len(fields) != 3 {
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+	}
+
+	numbers := strings.SplitN(fields[1], ":", 2)
+	if len(numbers) != 2 {
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+	}
+
+	parse := func(v string) (int, error) {
+		if v == "*" {
+			return DeviceWildcard, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+		}
+		return n, nil
+	}
+
+	var err error
+	if rule.Major, err = parse(numbers[0]); err != nil {
+		return DeviceRule{}, err
+	}
+	if rule.Minor, err = parse(numbers[1]); err != nil {
+		return DeviceRule{}, err
+	}
+
+	if fields[2] == "" || strings.Trim(fields[2], "rwm") != "" {
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, s)
+	}
+	rule.Access = fields[2]
+
+	return rule, nil
+}
+
+// DevicePolicy represents the device access policy of a container. Deny
+// rules are applied before allow rules, so the usual policy is to deny
+// DeviceTypeAll and to allow the required devices.
+type DevicePolicy struct {
+	Allow []DeviceRule
+	Deny  []DeviceRule
+}
+
+// devicesConfigKey returns the config key for the given devices cgroup
+// file. On cgroup v2 hosts liblxc compiles these rules into a BPF device
+// program.
+func devicesConfigKey(file string) string {
+	if CgroupUnified() {
+		return "lxc.cgroup2.devices." + file
+	}
+	return "lxc.cgroup.devices." + file
+}
+
+// deviceRules parses the device rules stored under the given config key.
+//
+// Caller needs to hold the lock
+func (c *Container) deviceRules(key string) ([]DeviceRule, error) {
+	var rules []DeviceRule
+
+	for _, v := range c.configItem(key) {
+		if v == "" {
+			continue
+		}
+
+		rule, err := ParseDeviceRule(v)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// DevicePolicy returns the device policy of the container as found in its
+// configuration.
+func (c *Container) DevicePolicy() (DevicePolicy, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return DevicePolicy{}, err
+	}
+
+	deny, err := c.deviceRules(devicesConfigKey("deny"))
+	if err != nil {
+		return DevicePolicy{}, err
+	}
+
+	allow, err := c.deviceRules(devicesConfigKey("allow"))
+	if err != nil {
+		return DevicePolicy{}, err
+	}
+
+	return DevicePolicy{Allow: allow, Deny: deny}, nil
+}
+
+// SetDevicePolicy replaces the device policy in the configuration of the
+// container. The policy is applied on the next start of the container.
+func (c *Container) SetDevicePolicy(policy DevicePolicy) (err error) {
+	finish, err := c.operation("SetDevicePolicy")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	deny := devicesConfigKey("deny")
+	allow := devicesConfigKey("allow")
+
+	for _, key := range []string{deny, allow} {
+		if err := c.clearConfigItem(key); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range policy.Deny {
+		if err := c.setConfigItem(deny, rule.String()); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range policy.Allow {
+		if err := c.setConfigItem(allow, rule.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
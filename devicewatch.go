@@ -0,0 +1,267 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// udevMonitorGroup is the netlink group udev broadcasts processed events on,
+// the device nodes exist by then.
+const udevMonitorGroup = 2
+
+// DeviceFilter selects the host devices a DeviceWatcher passes through.
+type DeviceFilter struct {
+	// Subsystem of the device, e.g. "tty" or "usb".
+	Subsystem string
+	// DevName is a glob matched against the device node name relative to
+	// /dev, e.g. "ttyUSB*".
+	DevName string
+	// Properties are udev properties the device needs to have, e.g.
+	// {"ID_VENDOR_ID": "0403"}.
+	Properties map[string]string
+}
+
+// Match returns true if the udev properties of a device match the filter.
+func (f DeviceFilter) Match(properties map[string]string) bool {
+	if properties["DEVNAME"] == "" {
+		return false
+	}
+
+	if f.Subsystem != "" && properties["SUBSYSTEM"] != f.Subsystem {
+		return false
+	}
+
+	if f.DevName != "" {
+		if ok, _ := filepath.Match(f.DevName, strings.TrimPrefix(properties["DEVNAME"], "/dev/")); !ok {
+			return false
+		}
+	}
+
+	for key, value := range f.Properties {
+		if properties[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// DeviceEvent reports a device passed to or removed from the container.
+type DeviceEvent struct {
+	// Action is the udev action, "add" or "remove".
+	Action string
+	// Path is the device node, e.g. "/dev/ttyUSB0".
+	Path string
+	// Properties are the udev properties of the device.
+	Properties map[string]string
+	// Err is set if the device couldn't be added or removed.
+	Err error
+}
+
+// parseUevent parses a netlink uevent message, either a kernel message
+// ("add@/devices/...\0KEY=value\0...") or a libudev one with its binary
+// header.
+func parseUevent(msg []byte) (map[string]string, error) {
+	if bytes.HasPrefix(msg, []byte("libudev\x00")) {
+		// prefix[8], magic, header_size, properties_off, properties_len, ...
+		if len(msg) < 24 {
+			return nil, fmt.Errorf("short udev message")
+		}
+
+		off := binary.LittleEndian.Uint32(msg[16:20])
+		length := binary.LittleEndian.Uint32(msg[20:24])
+		if uint64(off)+uint64(length) > uint64(len(msg)) {
+			return nil, fmt.Errorf("invalid udev message")
+		}
+		msg = msg[off : off+length]
+	} else {
+		i := bytes.IndexByte(msg, 0)
+		if i < 0 || !bytes.Contains(msg[:i], []byte("@")) {
+			return nil, fmt.Errorf("invalid uevent message")
+		}
+		msg = msg[i+1:]
+	}
+
+	properties := make(map[string]string)
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		parts := strings.SplitN(string(field), "=", 2)
+		if len(parts) == 2 {
+			properties[parts[0]] = parts[1]
+		}
+	}
+
+	if properties["DEVNAME"] != "" && !strings.HasPrefix(properties["DEVNAME"], "/") {
+		properties["DEVNAME"] = "/dev/" + properties["DEVNAME"]
+	}
+	return properties, nil
+}
+
+// DeviceWatcher passes host devices matching a filter through to a running
+// container as they appear and removes them once they are gone.
+type DeviceWatcher struct {
+	c      *Container
+	filter DeviceFilter
+	fd     int
+	events chan DeviceEvent
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// handle adds or removes the device described by the properties.
+func (w *DeviceWatcher) handle(properties map[string]string) {
+	action := properties["ACTION"]
+	if (action != "add" && action != "remove") || !w.filter.Match(properties) {
+		return
+	}
+
+	event := DeviceEvent{Action: action, Path: properties["DEVNAME"], Properties: properties}
+
+	if action == "add" {
+		event.Err = w.c.AddDeviceNode(event.Path)
+	} else if err := w.c.RemoveDeviceNode(event.Path); err != nil {
+		// the node is gone on the host already, remove it in the container
+		options := DefaultAttachOptions
+		options.Path = DefaultAttachPath
+
+		status, err := w.c.RunCommandStatus([]string{"rm", "-f", event.Path}, options)
+		if err == nil && status != 0 {
+			err = fmt.Errorf("%s: %q", ErrRemoveDeviceNodeFailed, event.Path)
+		}
+		event.Err = err
+
+		if !CgroupUnified() && properties["MAJOR"] != "" {
+			kind := "c"
+			if properties["SUBSYSTEM"] == "block" {
+				kind = "b"
+			}
+			w.c.SetCgroupItem("devices.deny", fmt.Sprintf("%s %s:%s rwm", kind, properties["MAJOR"], properties["MINOR"]))
+		}
+	}
+
+	// never block on the reader, the netlink socket would overflow
+	select {
+	case w.events <- event:
+	default:
+	}
+}
+
+// trustedUevent returns true if the credentials passed along with a netlink
+// message show it was sent by root, as libudev requires.
+func trustedUevent(oob []byte) bool {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return false
+	}
+
+	for _, msg := range msgs {
+		if creds, err := unix.ParseUnixCredentials(&msg); err == nil {
+			return creds.Uid == 0
+		}
+	}
+	return false
+}
+
+func (w *DeviceWatcher) run() {
+	defer close(w.done)
+	defer close(w.events)
+	defer unix.Close(w.fd)
+
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, unix.CmsgSpace(unix.SizeofUcred))
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, 500)
+		if err != nil && err != unix.EINTR {
+			return
+		}
+		if n <= 0 {
+			continue
+		}
+
+		size, oobn, _, _, err := unix.Recvmsg(w.fd, buf, oob, 0)
+		if err != nil || !trustedUevent(oob[:oobn]) {
+			continue
+		}
+
+		properties, err := parseUevent(buf[:size])
+		if err != nil {
+			continue
+		}
+		w.handle(properties)
+	}
+}
+
+// Events returns the channel receiving an event for every device added or
+// removed. It is closed once the watcher is closed. Events are dropped
+// while the buffer of the channel is full, so it should be drained.
+func (w *DeviceWatcher) Events() <-chan DeviceEvent {
+	return w.events
+}
+
+// Close stops watching the devices. Devices already passed through are left
+// in place.
+func (w *DeviceWatcher) Close() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+// WatchDevices starts passing host devices matching filter through to the
+// running container, following the udev events of the host.
+func (c *Container) WatchDevices(filter DeviceFilter) (*DeviceWatcher, error) {
+	c.mu.RLock()
+	err := c.makeSure(isRunning | isPrivileged)
+	c.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+
+	// the credentials of the sender are checked for every message
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_PASSCRED, 1); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: udevMonitorGroup}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	w := &DeviceWatcher{
+		c:      c,
+		filter: filter,
+		fd:     fd,
+		events: make(chan DeviceEvent, 16),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+
+	return w, nil
+}
@@ -0,0 +1,248 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiskUsageInfo represents the disk usage of a container.
+type DiskUsageInfo struct {
+	Backend BackendStore
+	// Rootfs is the size of the root filesystem as seen by the container.
+	Rootfs ByteSize
+	// Delta is the space used exclusively by the container, e.g. the upper
+	// directory of an overlay clone or the exclusive data of a btrfs
+	// subvolume. It equals Rootfs for backends without sharing.
+	Delta ByteSize
+	// Snapshots is the space used by the snapshots of the container.
+	Snapshots ByteSize
+}
+
+// Total returns the space used exclusively by the container and its snapshots.
+func (d DiskUsageInfo) Total() ByteSize {
+	return d.Delta + d.Snapshots
+}
+
+// parseBtrfsQgroup parses the output of "btrfs qgroup show -f --raw" and
+// returns the referenced and exclusive sizes.
+func parseBtrfsQgroup(out string) (ByteSize, ByteSize, error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "0/") {
+			continue
+		}
+
+		rfer, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return -1, -1, err
+		}
+
+		excl, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return -1, -1, err
+		}
+		return ByteSize(rfer), ByteSize(excl), nil
+	}
+	return -1, -1, fmt.Errorf("%s: %q", ErrDiskUsage, "btrfs")
+}
+
+// parseZfsUsage parses the output of "zfs get -Hp -o value
+// referenced,usedbydataset,usedbysnapshots".
+func parseZfsUsage(out string) (DiskUsageInfo, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 3 {
+		return DiskUsageInfo{}, fmt.Errorf("%s: %q", ErrDiskUsage, "zfs")
+	}
+
+	var values [3]ByteSize
+	for i, v := range fields {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return DiskUsageInfo{}, fmt.Errorf("%s: %q", ErrDiskUsage, "zfs")
+		}
+		values[i] = ByteSize(n)
+	}
+
+	return DiskUsageInfo{Backend: ZFS, Rootfs: values[0], Delta: values[1], Snapshots: values[2]}, nil
+}
+
+// parseLvs parses the output of "lvs --noheadings --units b --nosuffix -o
+// lv_size,data_percent". Thin volumes only account for their allocated data.
+func parseLvs(out string) (ByteSize, ByteSize, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return -1, -1, fmt.Errorf("%s: %q", ErrDiskUsage, "lvm")
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("%s: %q", ErrDiskUsage, "lvm")
+	}
+
+	used := ByteSize(size)
+	if len(fields) > 1 {
+		percent, err := strconv.ParseFloat(fields[1], 64)
+		if err == nil {
+			used = ByteSize(float64(size) * percent / 100)
+		}
+	}
+	return ByteSize(size), used, nil
+}
+
+// rootfsDiskUsage returns the disk usage of the root filesystem.
+//
+// Caller needs to hold the lock
+func (c *Container) rootfsDiskUsage() (DiskUsageInfo, error) {
+	key := "lxc.rootfs.path"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.rootfs"
+	}
+
+	rootfs := c.configItem(key)[0]
+	backend := c.backendStore()
+	usage := DiskUsageInfo{Backend: backend}
+
+	source := rootfs
+	if parts := strings.SplitN(rootfs, ":", 2); len(parts) == 2 && !strings.HasPrefix(rootfs, "/") {
+		source = parts[1]
+	}
+
+	switch backend {
+	case Overlayfs:
+		// overlay:<lower>:<upper>
+		parts := strings.Split(source, ":")
+		if len(parts) != 2 {
+			return usage, fmt.Errorf("%s: %q", ErrDiskUsage, rootfs)
+		}
+
+		lower, err := diskUsage(parts[0])
+		if err != nil {
+			return usage, err
+		}
+
+		upper, err := diskUsage(parts[1])
+		if err != nil {
+			return usage, err
+		}
+		usage.Rootfs, usage.Delta = lower+upper, upper
+	case Btrfs:
+		out, err := exec.Command("btrfs", "qgroup", "show", "-f", "--raw", source).Output()
+		if err == nil {
+			if usage.Rootfs, usage.Delta, err = parseBtrfsQgroup(string(out)); err == nil {
+				return usage, nil
+			}
+		}
+
+		// quotas are disabled, fall back to walking the subvolume
+		size, err := diskUsage(source)
+		if err != nil {
+			return usage, err
+		}
+		usage.Rootfs, usage.Delta = size, size
+	case ZFS:
+		out, err := exec.Command("zfs", "get", "-Hp", "-o", "value", "referenced,usedbydataset,usedbysnapshots", source).Output()
+		if err != nil {
+			return usage, fmt.Errorf("%s: %v", ErrDiskUsage, err)
+		}
+		return parseZfsUsage(string(out))
+	case LVM:
+		out, err := exec.Command("lvs", "--noheadings", "--units", "b", "--nosuffix", "-o", "lv_size,data_percent", source).Output()
+		if err != nil {
+			return usage, fmt.Errorf("%s: %v", ErrDiskUsage, err)
+		}
+
+		if usage.Rootfs, usage.Delta, err = parseLvs(string(out)); err != nil {
+			return usage, err
+		}
+	default:
+		size, err := diskUsage(source)
+		if err != nil {
+			return usage, err
+		}
+		usage.Rootfs, usage.Delta = size, size
+	}
+
+	return usage, nil
+}
+
+// DiskUsage returns the disk usage of the root filesystem and the snapshots
+// of the container, using the tools of its backend (btrfs qgroups, zfs, lvs)
+// or walking the filesystem.
+func (c *Container) DiskUsage() (DiskUsageInfo, error) {
+	usage, err := func() (DiskUsageInfo, error) {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		if err := c.makeSure(isDefined); err != nil {
+			return DiskUsageInfo{}, err
+		}
+
+		return c.rootfsDiskUsage()
+	}()
+	if err != nil {
+		return usage, err
+	}
+
+	// zfs reports the space used by its snapshots already
+	if usage.Backend == ZFS {
+		return usage, nil
+	}
+
+	snapshots, err := c.Snapshots()
+	if err == ErrNoSnapshot {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, err
+	}
+
+	for _, s := range snapshots {
+		size, err := diskUsage(filepath.Join(s.Path, s.Name))
+		if err != nil {
+			return usage, err
+		}
+		usage.Snapshots += size
+	}
+
+	return usage, nil
+}
+
+// DiskUsage returns the disk usage of all defined containers in the given
+// lxcpath, keyed by container name.
+func DiskUsage(lxcpath ...string) (map[string]DiskUsageInfo, error) {
+	names, err := DefinedContainerNamesE(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := listContainers(names, lxcpath...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		for _, c := range containers {
+			c.Release()
+		}
+	}()
+
+	usage := make(map[string]DiskUsageInfo)
+	for _, c := range containers {
+		u, err := c.DiskUsage()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q", err, c.Name())
+		}
+		usage[c.Name()] = u
+	}
+
+	return usage, nil
+}
@@ -6,3 +6,5 @@ LXC (LinuX Containers) is an operating system–level virtualization method for
 LXC combines cgroups and namespace support to provide an isolated environment for applications.
 */
 package lxc
+
+//go:generate go run mkunsupported.go
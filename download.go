@@ -0,0 +1,312 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// segmentMinSize is the minimum size of a blob downloaded in segments.
+const segmentMinSize = 4 * 1024 * 1024
+
+var (
+	downloadConfigMu sync.RWMutex
+	downloadConfig   = DefaultDownloadConfig
+
+	// proxyEnvMu serializes changes of the proxy environment variables
+	proxyEnvMu sync.Mutex
+)
+
+// proxyEnv are the environment variables the download template and the
+// tools it runs take the proxy from.
+var proxyEnv = []string{"http_proxy", "https_proxy", "HTTP_PROXY", "HTTPS_PROXY"}
+
+// GlobalDownloadConfig returns the download configuration used by image pulls
+// that don't specify one.
+func GlobalDownloadConfig() DownloadConfig {
+	downloadConfigMu.RLock()
+	defer downloadConfigMu.RUnlock()
+
+	return downloadConfig
+}
+
+// validate checks the proxy URL of the configuration.
+func (config DownloadConfig) validate() error {
+	if config.Proxy != "" {
+		if _, err := url.Parse(config.Proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetGlobalDownloadConfig sets the download configuration used by image
+// pulls that don't specify one, unless the lxcpath of the container has one
+// of its own, see SaveDownloadConfig.
+func SetGlobalDownloadConfig(config DownloadConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	downloadConfigMu.Lock()
+	defer downloadConfigMu.Unlock()
+
+	downloadConfig = config
+	return nil
+}
+
+// downloadConfigFile returns the file the download configuration of lxcpath
+// is stored in.
+func downloadConfigFile(lxcpath string) string {
+	return filepath.Join(lxcpath, "download.json")
+}
+
+// SaveDownloadConfig stores the download configuration of the containers
+// under lxcpath (default: DefaultConfigPath()), which then takes precedence
+// over the global one for them.
+func SaveDownloadConfig(config DownloadConfig, lxcpath ...string) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	path := profileConfigPath(lxcpath)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(downloadConfigFile(path), content, 0644)
+}
+
+// LoadDownloadConfig returns the download configuration used for the
+// containers under lxcpath, GlobalDownloadConfig() if none is stored.
+func LoadDownloadConfig(lxcpath ...string) (DownloadConfig, error) {
+	content, err := ioutil.ReadFile(downloadConfigFile(profileConfigPath(lxcpath)))
+	if os.IsNotExist(err) {
+		return GlobalDownloadConfig(), nil
+	} else if err != nil {
+		return DownloadConfig{}, err
+	}
+
+	var config DownloadConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return DownloadConfig{}, err
+	}
+	return config, nil
+}
+
+// DeleteDownloadConfig removes the download configuration stored under
+// lxcpath, the global one applies to its containers again.
+func DeleteDownloadConfig(lxcpath ...string) error {
+	err := os.Remove(downloadConfigFile(profileConfigPath(lxcpath)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// withProxyEnv runs fn with the proxy environment variables set to proxy, so
+// the programs liblxc runs meanwhile, e.g. the download template, use it.
+// The variables are restored afterwards.
+func withProxyEnv(proxy string, fn func() bool) bool {
+	if proxy == "" {
+		return fn()
+	}
+
+	proxyEnvMu.Lock()
+	defer proxyEnvMu.Unlock()
+
+	for _, key := range proxyEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, value)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Setenv(key, proxy)
+	}
+	return fn()
+}
+
+// httpClient returns a client using the proxy of the configuration.
+func (config DownloadConfig) httpClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Proxy != "" {
+		proxy, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// baseURLs returns the mirrors followed by the registry itself.
+func (config DownloadConfig) baseURLs(scheme string, registry string) []string {
+	var bases []string
+	for _, mirror := range config.Mirrors {
+		bases = append(bases, strings.TrimSuffix(mirror, "/"))
+	}
+	return append(bases, scheme+"://"+registry)
+}
+
+// rateLimiter throttles downloads to a number of bytes per second, shared
+// by all readers.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate ByteSize
+	next time.Time
+}
+
+// wait blocks until n more bytes may be consumed.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || l.rate <= 0 || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// limitedReader is a reader throttled by a rateLimiter.
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	// don't read ahead more than a second worth of data
+	if r.limiter != nil {
+		if n := int(r.limiter.rate); n > 0 && len(p) > n {
+			p = p[:n]
+		}
+	}
+
+	n, err := r.r.Read(p)
+	if werr := r.limiter.wait(r.ctx, n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+// downloadSegments fetches the blob into path with parallel range requests.
+// It returns false if the registry doesn't support ranges.
+func (r *registryClient) downloadSegments(d ociDescriptor, path string, segments int) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(d.Size); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+
+	size := (d.Size + int64(segments) - 1) / int64(segments)
+	errs := make([]error, segments)
+	var wg sync.WaitGroup
+
+	for i := 0; i < segments; i++ {
+		start := int64(i) * size
+		end := start + size - 1
+		if end >= d.Size {
+			end = d.Size - 1
+		}
+
+		wg.Add(1)
+		go func(i int, start int64, end int64) {
+			defer wg.Done()
+
+			header := http.Header{"Range": []string{fmt.Sprintf("bytes=%d-%d", start, end)}}
+			resp, err := r.doContext(ctx, "blobs/"+d.Digest, header)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusPartialContent {
+				errs[i] = errRangesUnsupported
+				cancel()
+				return
+			}
+
+			body := limitedReader{ctx, io.LimitReader(resp.Body, end-start+1), r.limiter}
+			if _, err := io.Copy(&offsetWriter{f, start}, body); err != nil {
+				errs[i] = err
+				cancel()
+			}
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == errRangesUnsupported {
+			return false, os.Remove(path)
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return true, err
+		}
+	}
+
+	return true, r.ctx.Err()
+}
+
+// offsetWriter writes sequentially from an offset of a file.
+type offsetWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// errRangesUnsupported is used internally when a registry ignores ranges.
+var errRangesUnsupported = fmt.Errorf("%s: %s", ErrRegistryFailed, "range requests are not supported")
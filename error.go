@@ -7,6 +7,9 @@
 package lxc
 
 const (
+	// ErrActivationFailed - activating the container failed
+	ErrActivationFailed = lxcError("activating the container failed")
+
 	// ErrAddDeviceNodeFailed - adding device to container failed
 	ErrAddDeviceNodeFailed = lxcError("adding device to container failed")
 
@@ -22,6 +25,9 @@ const (
 	// ErrAlreadyRunning - container is already running
 	ErrAlreadyRunning = lxcError("container is already running")
 
+	// ErrApplyLayerFailed - applying the image layer failed
+	ErrApplyLayerFailed = lxcError("applying the image layer failed")
+
 	// ErrAttachFailed - attaching to the container failed
 	ErrAttachFailed = lxcError("attaching to the container failed")
 
@@ -46,15 +52,27 @@ const (
 	// ErrCloseAllFdsFailed - setting close_all_fds flag for container failed
 	ErrCloseAllFdsFailed = lxcError("setting close_all_fds flag for container failed")
 
+	// ErrConvertStorageFailed - converting the storage backend of the container failed
+	ErrConvertStorageFailed = lxcError("converting the storage backend of the container failed")
+
 	// ErrCreateFailed - creating the container failed
 	ErrCreateFailed = lxcError("creating the container failed")
 
 	// ErrCreateSnapshotFailed - snapshotting the container failed
 	ErrCreateSnapshotFailed = lxcError("snapshotting the container failed")
 
+	// ErrCreateVolumeFailed - creating the volume failed
+	ErrCreateVolumeFailed = lxcError("creating the volume failed")
+
 	// ErrDaemonizeFailed - setting daemonize flag for container failed
 	ErrDaemonizeFailed = lxcError("setting daemonize flag for container failed")
 
+	// ErrDeleteVolumeFailed - deleting the volume failed
+	ErrDeleteVolumeFailed = lxcError("deleting the volume failed")
+
+	// ErrDependencyCycle - dependencies of the containers form a cycle
+	ErrDependencyCycle = lxcError("dependencies of the containers form a cycle")
+
 	// ErrDestroyAllSnapshotsFailed - destroying all snapshots failed
 	ErrDestroyAllSnapshotsFailed = lxcError("destroying all snapshots failed")
 
@@ -70,18 +88,108 @@ const (
 	// ErrDetachInterfaceFailed - detaching specified netdev to the container failed
 	ErrDetachInterfaceFailed = lxcError("detaching specified netdev to the container failed")
 
+	// ErrDigestMismatch - content does not match its digest
+	ErrDigestMismatch = lxcError("content does not match its digest")
+
+	// ErrDiskUsage - getting the disk usage of the container failed
+	ErrDiskUsage = lxcError("getting the disk usage of the container failed")
+
 	// ErrExecuteFailed - executing the command in a temporary container failed
 	ErrExecuteFailed = lxcError("executing the command in a temporary container failed")
 
+	// ErrExportFailed - exporting the container failed
+	ErrExportFailed = lxcError("exporting the container failed")
+
 	// ErrFreezeFailed - freezing the container failed
 	ErrFreezeFailed = lxcError("freezing the container failed")
 
+	// ErrGPUNotFound - no matching GPU found on the host
+	ErrGPUNotFound = lxcError("no matching GPU found on the host")
+
+	// ErrGroupNotFound - group not found in the container
+	ErrGroupNotFound = lxcError("group not found in the container")
+
+	// ErrHasSnapshots - container has snapshots
+	ErrHasSnapshots = lxcError("container has snapshots")
+
+	// ErrHibernateFailed - hibernating the container failed
+	ErrHibernateFailed = lxcError("hibernating the container failed")
+
+	// ErrHostResources - getting the resources of the host failed
+	ErrHostResources = lxcError("getting the resources of the host failed")
+
+	// ErrHugeTLBLimit - your kernel does not support cgroup hugetlb controller
+	ErrHugeTLBLimit = lxcError("your kernel does not support cgroup hugetlb controller")
+
+	// ErrImportFailed - importing the container failed
+	ErrImportFailed = lxcError("importing the container failed")
+
+	// ErrIncludeCycle - config includes form a cycle
+	ErrIncludeCycle = lxcError("config includes form a cycle")
+
+	// ErrIncludeFailed - reading the included config failed
+	ErrIncludeFailed = lxcError("reading the included config failed")
+
+	// ErrIncludeNotFound - config is not included
+	ErrIncludeNotFound = lxcError("config is not included")
+
 	// ErrInsufficientNumberOfArguments - insufficient number of arguments were supplied
 	ErrInsufficientNumberOfArguments = lxcError("insufficient number of arguments were supplied")
 
+	// ErrInsufficientResources - host has insufficient resources left for the container
+	ErrInsufficientResources = lxcError("host has insufficient resources left for the container")
+
 	// ErrInterfaces - getting interface names for the container failed
 	ErrInterfaces = lxcError("getting interface names for the container failed")
 
+	// ErrInvalidCgroupPlacement - invalid cgroup placement
+	ErrInvalidCgroupPlacement = lxcError("invalid cgroup placement")
+
+	// ErrInvalidCgroupScope - invalid systemd slice or scope name
+	ErrInvalidCgroupScope = lxcError("invalid systemd slice or scope name")
+
+	// ErrInvalidDeviceRule - invalid device cgroup rule
+	ErrInvalidDeviceRule = lxcError("invalid device cgroup rule")
+
+	// ErrInvalidFdName - invalid file descriptor name
+	ErrInvalidFdName = lxcError("invalid file descriptor name")
+
+	// ErrInvalidIDMap - invalid idmap entry
+	ErrInvalidIDMap = lxcError("invalid idmap entry")
+
+	// ErrInvalidImageReference - invalid image reference
+	ErrInvalidImageReference = lxcError("invalid image reference")
+
+	// ErrInvalidLimit - invalid resource limit
+	ErrInvalidLimit = lxcError("invalid resource limit")
+
+	// ErrInvalidNetwork - invalid network device
+	ErrInvalidNetwork = lxcError("invalid network device")
+
+	// ErrInvalidOwner - invalid owner
+	ErrInvalidOwner = lxcError("invalid owner")
+
+	// ErrInvalidPoolSize - invalid pool size
+	ErrInvalidPoolSize = lxcError("invalid pool size")
+
+	// ErrInvalidProfile - invalid profile
+	ErrInvalidProfile = lxcError("invalid profile")
+
+	// ErrInvalidSeccompProfile - invalid seccomp profile
+	ErrInvalidSeccompProfile = lxcError("invalid seccomp profile")
+
+	// ErrInvalidTmpfs - invalid tmpfs mount
+	ErrInvalidTmpfs = lxcError("invalid tmpfs mount")
+
+	// ErrInvalidUUID - invalid UUID
+	ErrInvalidUUID = lxcError("invalid UUID")
+
+	// ErrInvalidVolatileKey - invalid volatile key
+	ErrInvalidVolatileKey = lxcError("invalid volatile key")
+
+	// ErrInvalidVolume - invalid volume
+	ErrInvalidVolume = lxcError("invalid volume")
+
 	// ErrIPAddresses - getting IP addresses of the container failed
 	ErrIPAddresses = lxcError("getting IP addresses of the container failed")
 
@@ -97,6 +205,9 @@ const (
 	// ErrKMemLimit - your kernel does not support cgroup kernel memory controller
 	ErrKMemLimit = lxcError("your kernel does not support cgroup kernel memory controller")
 
+	// ErrListFailed - listing the containers failed
+	ErrListFailed = lxcError("listing the containers failed")
+
 	// ErrLoadConfigFailed - loading config file for the container failed
 	ErrLoadConfigFailed = lxcError("loading config file for the container failed")
 
@@ -109,24 +220,84 @@ const (
 	// ErrMethodNotAllowed - the requested method is not currently supported with unprivileged containers
 	ErrMethodNotAllowed = lxcError("the requested method is not currently supported with unprivileged containers")
 
+	// ErrMountRootfsFailed - mounting the root filesystem of the container failed
+	ErrMountRootfsFailed = lxcError("mounting the root filesystem of the container failed")
+
+	// ErrNUMANodeNotFound - NUMA node not found on the host
+	ErrNUMANodeNotFound = lxcError("NUMA node not found on the host")
+
+	// ErrNetNSStats - getting the network namespace statistics failed
+	ErrNetNSStats = lxcError("getting the network namespace statistics failed")
+
 	// ErrNewFailed - allocating the container failed
 	ErrNewFailed = lxcError("allocating the container failed")
 
+	// ErrNoConsoleBuffer - container has no console ring buffer
+	ErrNoConsoleBuffer = lxcError("container has no console ring buffer (lxc.console.buffer.size)")
+
+	// ErrNoLogFile - container has no log file
+	ErrNoLogFile = lxcError("container has no log file (lxc.log.file)")
+
+	// ErrNoNotifySocket - container has no notify socket
+	ErrNoNotifySocket = lxcError("container has no notify socket")
+
 	// ErrNoSnapshot - container has no snapshot
 	ErrNoSnapshot = lxcError("container has no snapshot")
 
+	// ErrNoSwap - container has no swap
+	ErrNoSwap = lxcError("container has no swap")
+
 	// ErrNotDefined - container is not defined
 	ErrNotDefined = lxcError("container is not defined")
 
 	// ErrNotFrozen - container is not frozen
 	ErrNotFrozen = lxcError("container is not frozen")
 
+	// ErrNotHibernated - container is not hibernated
+	ErrNotHibernated = lxcError("container is not hibernated")
+
 	// ErrNotRunning - container is not running
 	ErrNotRunning = lxcError("container is not running")
 
 	// ErrNotSupported - method is not supported by this LXC version
 	ErrNotSupported = lxcError("method is not supported by this LXC version")
 
+	// ErrOperationDenied - operation was denied by the interceptor
+	ErrOperationDenied = lxcError("operation was denied by the interceptor")
+
+	// ErrPlatformNotFound - image is not available for the platform
+	ErrPlatformNotFound = lxcError("image is not available for the platform")
+
+	// ErrPoolClosed - pool is closed
+	ErrPoolClosed = lxcError("pool is closed")
+
+	// ErrPortConflict - host port is in use
+	ErrPortConflict = lxcError("host port is in use")
+
+	// ErrPressureStats - your kernel does not support pressure stall information
+	ErrPressureStats = lxcError("your kernel does not support pressure stall information")
+
+	// ErrProfileNotFound - profile not found
+	ErrProfileNotFound = lxcError("profile not found")
+
+	// ErrProjectNotFound - project not found
+	ErrProjectNotFound = lxcError("project not found")
+
+	// ErrProjectQuota - project quota exceeded
+	ErrProjectQuota = lxcError("project quota exceeded")
+
+	// ErrPullFailed - pulling the image failed
+	ErrPullFailed = lxcError("pulling the image failed")
+
+	// ErrRDMALimit - your kernel does not support cgroup rdma controller
+	ErrRDMALimit = lxcError("your kernel does not support cgroup rdma controller")
+
+	// ErrRealtimeLimit - your kernel does not support realtime group scheduling
+	ErrRealtimeLimit = lxcError("your kernel does not support realtime group scheduling")
+
+	// ErrRegistryFailed - registry request failed
+	ErrRegistryFailed = lxcError("registry request failed")
+
 	// ErrRebootFailed - rebooting the container failed
 	ErrRebootFailed = lxcError("rebooting the container failed")
 
@@ -142,6 +313,12 @@ const (
 	// ErrRestoreSnapshotFailed - restoring the container failed
 	ErrRestoreSnapshotFailed = lxcError("restoring the container failed")
 
+	// ErrRotateLogFailed - rotating the log failed
+	ErrRotateLogFailed = lxcError("rotating the log failed")
+
+	// ErrRuntimeCheckFailed - host doesn't provide what liblxc needs at runtime
+	ErrRuntimeCheckFailed = lxcError("host doesn't provide what liblxc needs at runtime")
+
 	// ErrSaveConfigFailed - saving config file for the container failed
 	ErrSaveConfigFailed = lxcError("saving config file for the container failed")
 
@@ -154,6 +331,9 @@ const (
 	// ErrSettingConfigPathFailed - setting config file for the container failed
 	ErrSettingConfigPathFailed = lxcError("setting config file for the container failed")
 
+	// ErrSettingHugeTLBLimitFailed - setting hugetlb limit for the container failed
+	ErrSettingHugeTLBLimitFailed = lxcError("setting hugetlb limit for the container failed")
+
 	// ErrSettingKMemoryLimitFailed - setting kernel memory limit for the container failed
 	ErrSettingKMemoryLimitFailed = lxcError("setting kernel memory limit for the container failed")
 
@@ -163,12 +343,24 @@ const (
 	// ErrSettingMemorySwapLimitFailed - setting memory+swap limit for the container failed
 	ErrSettingMemorySwapLimitFailed = lxcError("setting memory+swap limit for the container failed")
 
+	// ErrSettingRDMALimitFailed - setting rdma limit for the container failed
+	ErrSettingRDMALimitFailed = lxcError("setting rdma limit for the container failed")
+
+	// ErrSettingRlimitsFailed - setting the resource limits of the command failed
+	ErrSettingRlimitsFailed = lxcError("setting the resource limits of the command failed")
+
 	// ErrSettingSoftMemoryLimitFailed - setting soft memory limit for the container failed
 	ErrSettingSoftMemoryLimitFailed = lxcError("setting soft memory limit for the container failed")
 
+	// ErrShiftFailed - shifting the ownership of the file failed
+	ErrShiftFailed = lxcError("shifting the ownership of the file failed")
+
 	// ErrShutdownFailed - shutting down the container failed
 	ErrShutdownFailed = lxcError("shutting down the container failed")
 
+	// ErrSocketNameTooLong - the name of the unix socket is too long
+	ErrSocketNameTooLong = lxcError("the name of the unix socket is too long")
+
 	// ErrSoftMemLimit - your kernel does not support cgroup memory controller
 	ErrSoftMemLimit = lxcError("your kernel does not support cgroup memory controller")
 
@@ -178,15 +370,63 @@ const (
 	// ErrStopFailed - stopping the container failed
 	ErrStopFailed = lxcError("stopping the container failed")
 
+	// ErrSwapFailed - setting up the swap of the container failed
+	ErrSwapFailed = lxcError("setting up the swap of the container failed")
+
 	// ErrTemplateNotAllowed - unprivileged users only allowed to use "download" template
 	ErrTemplateNotAllowed = lxcError("unprivileged users only allowed to use \"download\" template")
 
+	// ErrTransactionDone - the config transaction was already committed or rolled back
+	ErrTransactionDone = lxcError("the config transaction was already committed or rolled back")
+
+	// ErrUUIDNotFound - no container with the UUID
+	ErrUUIDNotFound = lxcError("no container with the UUID")
+
 	// ErrUnfreezeFailed - unfreezing the container failed
 	ErrUnfreezeFailed = lxcError("unfreezing the container failed")
 
+	// ErrUnknownDependency - unknown container in dependencies
+	ErrUnknownDependency = lxcError("unknown container in dependencies")
+
+	// ErrUnknownAddressFamily - unknown address family
+	ErrUnknownAddressFamily = lxcError("unknown address family")
+
 	// ErrUnknownBackendStore - unknown backend type
 	ErrUnknownBackendStore = lxcError("unknown backend type")
 
+	// ErrUnknownConfigItem - unknown config item
+	ErrUnknownConfigItem = lxcError("unknown config item")
+
+	// ErrUnsafeRootfsPath - refusing to follow a symlink in the rootfs
+	ErrUnsafeRootfsPath = lxcError("refusing to follow a symlink in the rootfs")
+
+	// ErrUnstableState - only STOPPED, RUNNING and FROZEN can be ensured
+	ErrUnstableState = lxcError("only STOPPED, RUNNING and FROZEN can be ensured")
+
+	// ErrUserNotFound - user not found in the container
+	ErrUserNotFound = lxcError("user not found in the container")
+
+	// ErrVFInUse - virtual function is in use
+	ErrVFInUse = lxcError("virtual function is in use")
+
+	// ErrVerificationFailed - verifying the image failed
+	ErrVerificationFailed = lxcError("verifying the image failed")
+
+	// ErrVolumeExists - volume already exists
+	ErrVolumeExists = lxcError("volume already exists")
+
+	// ErrVolumeIDMapMismatch - volume is shifted to a different idmap
+	ErrVolumeIDMapMismatch = lxcError("volume is shifted to a different idmap")
+
+	// ErrVolumeInUse - volume is in use
+	ErrVolumeInUse = lxcError("volume is in use")
+
+	// ErrVolumeNotFound - volume not found
+	ErrVolumeNotFound = lxcError("volume not found")
+
+	// ErrWatchdogTimeout - container heartbeat timed out
+	ErrWatchdogTimeout = lxcError("container heartbeat timed out")
+
 	// ErrReleaseFailed - releasing the container failed
 	ErrReleaseFailed = lxcError("releasing the container failed")
 )
@@ -0,0 +1,350 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of event published on an EventBus.
+type EventType string
+
+const (
+	// EventState reports a container changing state.
+	EventState EventType = "state"
+	// EventOOM reports processes of a container killed by the OOM killer.
+	EventOOM EventType = "oom"
+	// EventHook reports a hook of a container running.
+	EventHook EventType = "hook"
+	// EventAudit reports a mutating operation on a container.
+	EventAudit EventType = "audit"
+	// EventIdle reports an idle container being shut down, see WatchIdle.
+	EventIdle EventType = "idle"
+)
+
+// Event is a single event published on an EventBus. Only the field of its
+// type is set among State, OOMKills, Hook, Audit and Idle.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      EventType `json:"type"`
+	Container string    `json:"container"`
+	LXCPath   string    `json:"lxcpath,omitempty"`
+
+	// State is the new state of the container.
+	State string `json:"state,omitempty"`
+	// OOMKills is the number of processes killed since the previous
+	// event.
+	OOMKills int          `json:"oom_kills,omitempty"`
+	Hook     *HookEvent   `json:"hook,omitempty"`
+	Audit    *AuditRecord `json:"audit,omitempty"`
+	// Idle is how long the container was idle.
+	Idle time.Duration `json:"idle,omitempty"`
+}
+
+// EventFilter selects the events delivered to a subscriber, an empty
+// field matches all events.
+type EventFilter struct {
+	Types      []EventType
+	Containers []string
+}
+
+// Match returns whether the event passes the filter.
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			found = found || t == e.Type
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Containers) > 0 {
+		found := false
+		for _, name := range f.Containers {
+			found = found || name == e.Container
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// EventSink receives the events of a subscription.
+type EventSink interface {
+	Send(e Event) error
+}
+
+// EventFunc is an EventSink calling the function for each event.
+type EventFunc func(e Event) error
+
+// Send calls f(e).
+func (f EventFunc) Send(e Event) error {
+	return f(e)
+}
+
+// EventChannel is an EventSink delivering the events on a channel. Events
+// are dropped while the channel is full, so a slow reader doesn't hold up
+// the bus.
+type EventChannel struct {
+	events chan Event
+}
+
+// NewEventChannel returns an EventChannel buffering size events.
+func NewEventChannel(size int) *EventChannel {
+	return &EventChannel{events: make(chan Event, size)}
+}
+
+// Send queues the event, dropping it if the channel is full.
+func (ch *EventChannel) Send(e Event) error {
+	select {
+	case ch.events <- e:
+	default:
+	}
+	return nil
+}
+
+// Events returns the channel receiving the events.
+func (ch *EventChannel) Events() <-chan Event {
+	return ch.events
+}
+
+// eventWriter writes the events as JSON lines.
+type eventWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEventWriter returns an EventSink writing one JSON object per event to
+// w.
+func NewEventWriter(w io.Writer) EventSink {
+	return &eventWriter{w: w}
+}
+
+func (ew *eventWriter) Send(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	_, err = ew.w.Write(append(data, '\n'))
+	return err
+}
+
+// EventFile is an EventSink appending JSON lines to a file.
+type EventFile struct {
+	eventWriter
+	f *os.File
+}
+
+// OpenEventFile opens (or creates) the file at path for appending events.
+func OpenEventFile(path string) (*EventFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventFile{eventWriter: eventWriter{w: f}, f: f}, nil
+}
+
+// Close closes the file.
+func (ef *EventFile) Close() error {
+	ef.mu.Lock()
+	defer ef.mu.Unlock()
+
+	return ef.f.Close()
+}
+
+type subscription struct {
+	filter EventFilter
+	sink   EventSink
+}
+
+// EventBus delivers the state, OOM, hook and audit events of containers to
+// the subscribers whose filter matches, so they consume one stream. Sources
+// are added with WatchState, WatchHooks and by setting the bus as audit
+// sink, Publish adds events of other sources. Errors of sinks are dropped.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[int]subscription
+	next int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewEventBus returns an EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[int]subscription),
+		stop: make(chan struct{}),
+	}
+}
+
+// Subscribe delivers the events matching the filter to the sink until the
+// returned function is called.
+func (b *EventBus) Subscribe(filter EventFilter, sink EventSink) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.subs[id] = subscription{filter: filter, sink: sink}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs, id)
+	}
+}
+
+// Publish delivers the event to the matching subscribers, in the calling
+// goroutine. The time is set if zero.
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		if sub.filter.Match(e) {
+			sub.sink.Send(e)
+		}
+	}
+}
+
+// Record publishes the audit record, which makes the bus an AuditSink:
+//
+//	SetAuditSink(bus)
+func (b *EventBus) Record(record AuditRecord) error {
+	b.Publish(Event{
+		Time:      record.Time,
+		Type:      EventAudit,
+		Container: record.Container,
+		LXCPath:   record.LXCPath,
+		Audit:     &record,
+	})
+	return nil
+}
+
+// WatchHooks publishes the events of the hooks of the container, see
+// HookEvents, until the container is released or the bus closed.
+func (b *EventBus) WatchHooks(c *Container, types ...string) error {
+	events, err := c.HookEvents(types...)
+	if err != nil {
+		return err
+	}
+
+	name, lxcpath := c.Name(), c.ConfigPath()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				b.Publish(Event{Type: EventHook, Container: name, LXCPath: lxcpath, Hook: &event})
+			}
+		}
+	}()
+	return nil
+}
+
+// parseOOMKills returns the oom_kill counter of memory.events (cgroup2) or
+// memory.oom_control (cgroup1), -1 if missing.
+func parseOOMKills(lines []string) int {
+	for _, line := range lines {
+		scanner := bufio.NewScanner(strings.NewReader(line))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 || fields[0] != "oom_kill" {
+				continue
+			}
+
+			if n, err := strconv.Atoi(fields[1]); err == nil {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// oomKills returns the number of processes of the container killed by the
+// OOM killer, -1 if unknown.
+func (c *Container) oomKills() int {
+	if CgroupUnified() {
+		return parseOOMKills(c.CgroupItem("memory.events"))
+	}
+	return parseOOMKills(c.CgroupItem("memory.oom_control"))
+}
+
+// WatchState publishes the state changes and OOM kills of the container,
+// checked every interval, until the bus is closed.
+func (b *EventBus) WatchState(c *Container, interval time.Duration) {
+	name, lxcpath := c.Name(), c.ConfigPath()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		state := c.State()
+		kills := c.oomKills()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+			}
+
+			if s := c.State(); s != state {
+				state = s
+				b.Publish(Event{Type: EventState, Container: name, LXCPath: lxcpath, State: s.String()})
+			}
+
+			// the counter restarts with the cgroup
+			n := c.oomKills()
+			if n > kills && kills >= 0 {
+				b.Publish(Event{Type: EventOOM, Container: name, LXCPath: lxcpath, OOMKills: n - kills})
+			}
+			kills = n
+		}
+	}()
+}
+
+// Close stops watching the containers, it returns once the watchers
+// stopped. Publish keeps working.
+func (b *EventBus) Close() {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+	b.wg.Wait()
+}
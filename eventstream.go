@@ -0,0 +1,235 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is appended to the key of the handshake, see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// eventStreamBuffer is the number of events queued for a slow client
+// before dropping them.
+const eventStreamBuffer = 64
+
+// websocketAccept returns the Sec-WebSocket-Accept header of the key.
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// websocketFrame returns an unmasked final text frame of the payload, as
+// sent by servers.
+func websocketFrame(payload []byte) []byte {
+	frame := []byte{0x81}
+
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(n))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(n))
+	}
+	return append(frame, payload...)
+}
+
+// readWebsocketClose discards the frames of the client until it sends a
+// close frame or reading fails.
+func readWebsocketClose(r *bufio.Reader) {
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+
+		if header[0]&0x0f == 0x8 {
+			return
+		}
+
+		n := uint64(header[1] & 0x7f)
+		switch n {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(ext)
+		}
+
+		// masking key
+		if header[1]&0x80 != 0 {
+			n += 4
+		}
+
+		if _, err := io.CopyN(ioutil.Discard, r, int64(n)); err != nil {
+			return
+		}
+	}
+}
+
+// requestFilter narrows the filter to the container and type query
+// parameters of the request, e.g. ?container=web&type=state. Containers
+// outside of the filter can't be selected.
+func requestFilter(filter EventFilter, r *http.Request) EventFilter {
+	query := r.URL.Query()
+
+	if containers := query["container"]; len(containers) > 0 {
+		var selected []string
+		for _, name := range containers {
+			if (EventFilter{Containers: filter.Containers}).Match(Event{Container: name}) {
+				selected = append(selected, name)
+			}
+		}
+
+		// none allowed, match nothing
+		if len(selected) == 0 {
+			selected = []string{""}
+		}
+		filter.Containers = selected
+	}
+
+	if types := query["type"]; len(types) > 0 {
+		filter.Types = nil
+		for _, t := range types {
+			filter.Types = append(filter.Types, EventType(t))
+		}
+	}
+	return filter
+}
+
+type eventHandler struct {
+	bus    *EventBus
+	filter EventFilter
+}
+
+// EventHandler returns an http.Handler streaming the events of the bus
+// matching the filter as JSON, as server-sent events or, if the request
+// asks to upgrade, as websocket text messages. Clients narrow the stream
+// with the container and type query parameters, e.g.
+// /events?container=web&type=state&type=oom. Events are dropped while a
+// client falls behind.
+func EventHandler(bus *EventBus, filter EventFilter) http.Handler {
+	return &eventHandler{bus: bus, filter: filter}
+}
+
+func (h *eventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter := requestFilter(h.filter, r)
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.serveWebsocket(w, r, filter)
+		return
+	}
+	h.serveSSE(w, r, filter)
+}
+
+func (h *eventHandler) serveSSE(w http.ResponseWriter, r *http.Request, filter EventFilter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events := NewEventChannel(eventStreamBuffer)
+	unsubscribe := h.bus.Subscribe(filter, events)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events.Events():
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *eventHandler) serveWebsocket(w http.ResponseWriter, r *http.Request, filter EventFilter) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "unsupported websocket handshake", http.StatusBadRequest)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := NewEventChannel(eventStreamBuffer)
+	unsubscribe := h.bus.Subscribe(filter, events)
+	defer unsubscribe()
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	// the stream is one way, the client is gone once it sends a close
+	// frame or reading fails
+	gone := make(chan struct{})
+	go func() {
+		readWebsocketClose(rw.Reader)
+		close(gone)
+	}()
+
+	for {
+		select {
+		case <-gone:
+			// answer the close frame
+			conn.Write([]byte{0x88, 0})
+			return
+		case e := <-events.Events():
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+
+			if _, err := conn.Write(websocketFrame(data)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// freezeTimeout is how long the cgroup.freeze fallback waits for the
+// processes to be frozen.
+const freezeTimeout = 10 * time.Second
+
+// payloadCgroup returns the container's cgroup from the cgroup of its init
+// process, which may be a child cgroup created by the guest. dir is the
+// cgroup dir the container was placed in by liblxc, if empty the default
+// "lxc.payload.<name>" or, for liblxc before 4.0, "lxc/<name>" placement is
+// assumed.
+func payloadCgroup(path string, name string, dir string) (string, error) {
+	parts := strings.Split(path, "/")
+
+	match := func(expected []string) (string, bool) {
+		for i := 0; i+len(expected) <= len(parts); i++ {
+			if strings.Join(parts[i:i+len(expected)], "/") == strings.Join(expected, "/") {
+				return strings.Join(parts[:i+len(expected)], "/"), true
+			}
+		}
+		return "", false
+	}
+
+	if dir != "" {
+		if cgroup, ok := match(strings.Split(strings.Trim(dir, "/"), "/")); ok {
+			return cgroup, nil
+		}
+		return "", fmt.Errorf("%s: %q isn't below %q", ErrNotSupported, path, dir)
+	}
+
+	for _, expected := range [][]string{{"lxc.payload." + name}, {"lxc.payload", name}, {"lxc", name}} {
+		if cgroup, ok := match(expected); ok {
+			return cgroup, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unknown cgroup placement %q", ErrNotSupported, path)
+}
+
+// payloadCgroupDir returns the cgroup dir the running container was placed in
+// as reported by liblxc, empty for the default placement.
+//
+// Caller needs to hold the lock
+func (c *Container) payloadCgroupDir() string {
+	for _, key := range []string{"lxc.cgroup.dir.container", "lxc.cgroup.dir"} {
+		if values := c.runningConfigItem(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// parseProcCgroup returns the path of the cgroup v1 controller from
+// /proc/<pid>/cgroup, or the cgroup v2 path if controller is empty.
+func parseProcCgroup(content string, controller string) (string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if controller == "" && parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+
+		for _, c := range strings.Split(parts[1], ",") {
+			if controller != "" && c == controller {
+				return parts[2], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s: no %q cgroup", ErrFreezeFailed, controller)
+}
+
+// cgroupFrozen returns true if cgroup.events reports the cgroup as frozen.
+func cgroupFrozen(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "frozen 1" {
+			return true
+		}
+	}
+	return false
+}
+
+// unifiedCgroupPath returns the path of the container's cgroup v2.
+//
+// Caller needs to hold the lock
+func (c *Container) unifiedCgroupPath() (string, error) {
+	pid := c.initPid()
+	if pid <= 0 {
+		return "", ErrNotRunning
+	}
+
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+
+	path, err := parseProcCgroup(string(content), "")
+	if err != nil {
+		return "", err
+	}
+
+	path, err = payloadCgroup(path, c.name(), c.payloadCgroupDir())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join("/sys/fs/cgroup", path), nil
+}
+
+// setCgroupFrozen writes cgroup.freeze and waits until the state is reached.
+func setCgroupFrozen(path string, frozen bool) error {
+	value := "0"
+	if frozen {
+		value = "1"
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(path, "cgroup.freeze"), []byte(value), 0644); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(freezeTimeout)
+	for {
+		content, err := ioutil.ReadFile(filepath.Join(path, "cgroup.events"))
+		if err != nil {
+			return err
+		}
+
+		if cgroupFrozen(string(content)) == frozen {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s", filepath.Join(path, "cgroup.events"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// frozenByCgroup returns true if cgroup.events reports the container as
+// frozen on unified hosts, no matter which Container or process froze it.
+//
+// Caller needs to hold the lock
+func (c *Container) frozenByCgroup() bool {
+	if !CgroupUnified() {
+		return false
+	}
+
+	path, err := c.unifiedCgroupPath()
+	if err != nil {
+		return false
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(path, "cgroup.events"))
+	return err == nil && cgroupFrozen(string(content))
+}
+
+// freezeCgroup freezes the container by writing cgroup.freeze, for unified
+// hosts where liblxc fails to.
+//
+// Caller needs to hold the lock
+func (c *Container) freezeCgroup() error {
+	path, err := c.unifiedCgroupPath()
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrFreezeFailed, err)
+	}
+
+	if err := setCgroupFrozen(path, true); err != nil {
+		setCgroupFrozen(path, false)
+		return fmt.Errorf("%s: %v", ErrFreezeFailed, err)
+	}
+
+	c.freezeMethod = FreezeCgroup
+	return nil
+}
+
+// unfreezeCgroup thaws the container frozen by freezeCgroup.
+//
+// Caller needs to hold the lock
+func (c *Container) unfreezeCgroup() error {
+	path, err := c.unifiedCgroupPath()
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrUnfreezeFailed, err)
+	}
+
+	if err := setCgroupFrozen(path, false); err != nil {
+		return fmt.Errorf("%s: %v", ErrUnfreezeFailed, err)
+	}
+
+	c.freezeMethod = FreezeNone
+	return nil
+}
+
+// FreezeMethod returns how the container was frozen by Freeze, FreezeNone if
+// it isn't frozen through this Container.
+func (c *Container) FreezeMethod() FreezeMethod {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.freezeMethod
+}
@@ -1,5 +1,8 @@
 module github.com/lxc/go-lxc
 
-go 1.16
+go 1.18
 
-require golang.org/x/sys v0.0.0-20210603125802-9665404d3644
+require (
+	golang.org/x/sys v0.0.0-20210603125802-9665404d3644
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,199 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// nvidiaControlDevices are the device nodes needed besides the GPUs
+// themselves. The ones missing on the host are skipped.
+var nvidiaControlDevices = []string{
+	"/dev/nvidiactl",
+	"/dev/nvidia-uvm",
+	"/dev/nvidia-uvm-tools",
+	"/dev/nvidia-modeset",
+}
+
+// deviceRuleForNode returns a rule allowing full access to the given device
+// node.
+func deviceRuleForNode(path string) (DeviceRule, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return DeviceRule{}, err
+	}
+
+	rule := DeviceRule{
+		Major:  int(unix.Major(uint64(st.Rdev))),
+		Minor:  int(unix.Minor(uint64(st.Rdev))),
+		Access: "rwm",
+	}
+
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFCHR:
+		rule.Type = DeviceTypeChar
+	case unix.S_IFBLK:
+		rule.Type = DeviceTypeBlock
+	default:
+		return DeviceRule{}, fmt.Errorf("%s: %q", ErrInvalidDeviceRule, path)
+	}
+	return rule, nil
+}
+
+// passthroughDevice allows access to the given device node and bind mounts
+// it into the container.
+//
+// Caller needs to hold the lock
+func (c *Container) passthroughDevice(path string) error {
+	rule, err := deviceRuleForNode(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.setConfigItem(devicesConfigKey("allow"), rule.String()); err != nil {
+		return err
+	}
+
+	return c.setConfigItem("lxc.mount.entry", fmt.Sprintf("%s %s none bind,optional,create=file", path, strings.TrimPrefix(path, "/")))
+}
+
+// nvidiaDevices returns the NVIDIA GPU device nodes for the given indices,
+// or all of them if none are given.
+func nvidiaDevices(indices []int) ([]string, error) {
+	if len(indices) == 0 {
+		return filepath.Glob("/dev/nvidia[0-9]*")
+	}
+
+	var devices []string
+	for _, i := range indices {
+		path := "/dev/nvidia" + strconv.Itoa(i)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("%s: %q", ErrGPUNotFound, path)
+		}
+		devices = append(devices, path)
+	}
+	return devices, nil
+}
+
+// EnableNvidiaRuntime configures the container to use NVIDIA GPUs the same
+// way LXD's nvidia.runtime does. It allows and bind mounts the GPU device
+// nodes, exports the NVIDIA_* environment variables and registers the LXC
+// nvidia mount hook, which uses nvidia-container-cli to mount the driver
+// libraries into the container. The changes apply on the next start.
+func (c *Container) EnableNvidiaRuntime(opts NvidiaOptions) (err error) {
+	finish, err := c.operation("EnableNvidiaRuntime")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	devices, err := nvidiaDevices(opts.Devices)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		return fmt.Errorf("%s: %q", ErrGPUNotFound, "nvidia")
+	}
+
+	for _, path := range nvidiaControlDevices {
+		if _, err := os.Stat(path); err == nil {
+			devices = append(devices, path)
+		}
+	}
+
+	for _, path := range devices {
+		if err := c.passthroughDevice(path); err != nil {
+			return err
+		}
+	}
+
+	visible := "all"
+	if len(opts.Devices) > 0 {
+		indices := make([]string, len(opts.Devices))
+		for i, v := range opts.Devices {
+			indices[i] = strconv.Itoa(v)
+		}
+		visible = strings.Join(indices, ",")
+	}
+
+	env := []string{"NVIDIA_VISIBLE_DEVICES=" + visible}
+	if len(opts.DriverCapabilities) > 0 {
+		env = append(env, "NVIDIA_DRIVER_CAPABILITIES="+strings.Join(opts.DriverCapabilities, ","))
+	}
+	if opts.RequireCUDA != "" {
+		env = append(env, "NVIDIA_REQUIRE_CUDA="+opts.RequireCUDA)
+	}
+
+	for _, v := range env {
+		if err := c.setConfigItem("lxc.environment", v); err != nil {
+			return err
+		}
+	}
+
+	if opts.Hook != "" {
+		return c.setConfigItem("lxc.hook.mount", opts.Hook)
+	}
+	return nil
+}
+
+// EnableROCmRuntime configures the container to use AMD GPUs through ROCm.
+// It allows /dev/kfd and the /dev/dri nodes, bind mounts them and the ROCm
+// installation into the container. The changes apply on the next start.
+func (c *Container) EnableROCmRuntime(opts ROCmOptions) (err error) {
+	finish, err := c.operation("EnableROCmRuntime")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := os.Stat("/dev/kfd"); err != nil {
+		return fmt.Errorf("%s: %q", ErrGPUNotFound, "/dev/kfd")
+	}
+
+	if err := c.passthroughDevice("/dev/kfd"); err != nil {
+		return err
+	}
+
+	nodes, err := filepath.Glob("/dev/dri/*")
+	if err != nil {
+		return err
+	}
+
+	for _, path := range nodes {
+		rule, err := deviceRuleForNode(path)
+		if err != nil {
+			// skip the by-path symlink directory
+			continue
+		}
+
+		if err := c.setConfigItem(devicesConfigKey("allow"), rule.String()); err != nil {
+			return err
+		}
+	}
+
+	if err := c.setConfigItem("lxc.mount.entry", "/dev/dri dev/dri none bind,optional,create=dir"); err != nil {
+		return err
+	}
+
+	if opts.Path != "" {
+		return c.setConfigItem("lxc.mount.entry", fmt.Sprintf("%s %s none bind,ro,optional,create=dir", opts.Path, strings.TrimPrefix(opts.Path, "/")))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"os"
+)
+
+// helper is run by invoking the current executable with the argument it is
+// registered under, e.g. as a hook of liblxc.
+type helper struct {
+	// args is the minimum number of arguments following the marker.
+	args int
+	run  func(args []string) error
+}
+
+// helpers are keyed by the argument marking an invocation of the current
+// executable as the helper.
+var helpers = map[string]helper{
+	// lxc runs the hook as "<exe> __go_lxc_hook__ <socket> <name> <section> <type> [args...]".
+	// Errors are ignored, a missing listener must not prevent the container
+	// from starting.
+	hookHelperArg: {4, func(args []string) error {
+		runHookHelper(args[0], args[1:])
+		return nil
+	}},
+
+	// RunCommandAsync runs the helper as "<exe> __go_lxc_attach__ <name> <lxcpath> <args...>".
+	attachHelperArg: {3, func(args []string) error {
+		return runAttachHelper(args[0], args[1], args[2:])
+	}},
+
+	// StartWithFds runs the helper as "<exe> __go_lxc_start_fds__ <name> <lxcpath> [fdnames...]"
+	// with the fds from 3 on.
+	startFdsHelperArg: {2, func(args []string) error {
+		return runStartFdsHelper(args[0], args[1], args[2:])
+	}},
+
+	// lxc runs the hook as "<exe> __go_lxc_swap__ <name> <lxcpath> <name> lxc post-stop".
+	swapHelperArg: {2, func(args []string) error {
+		return releaseSwap(args[1], args[0])
+	}},
+}
+
+// init takes over the process if the current executable was invoked as one
+// of the helpers, before the program importing the package runs.
+func init() {
+	if len(os.Args) < 2 {
+		return
+	}
+
+	h, ok := helpers[os.Args[1]]
+	if !ok || len(os.Args)-2 < h.args {
+		return
+	}
+
+	if err := h.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,222 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hibernation records the limit squeezed by Hibernate, restored by Thaw.
+type hibernation struct {
+	// Key is the cgroup file lowered to reclaim memory, empty if
+	// memory.reclaim was used.
+	Key string `json:"key,omitempty"`
+	// Value is the previous value of Key.
+	Value string `json:"value,omitempty"`
+}
+
+// hibernationPath returns the file recording the hibernation.
+//
+// Caller needs to hold the lock
+func (c *Container) hibernationPath() string {
+	return filepath.Join(c.configPath(), c.name(), "hibernation.json")
+}
+
+// squeezeKey returns the cgroup file whose limit makes the kernel reclaim
+// memory down to it: memory.high doesn't invoke the OOM killer, cgroup1
+// only has the hard limit which is refused with EBUSY if the memory can't
+// be reclaimed.
+func squeezeKey(unified bool) string {
+	if unified {
+		return "memory.high"
+	}
+	return "memory.limit_in_bytes"
+}
+
+// reclaimAmount returns how much memory to reclaim to keep resident of the
+// current usage.
+func reclaimAmount(current ByteSize, resident ByteSize) ByteSize {
+	if current <= resident {
+		return 0
+	}
+	return current - resident
+}
+
+// reclaim pushes the memory of the frozen container exceeding resident to
+// swap, through memory.reclaim where available and by lowering the memory
+// limit otherwise.
+//
+// Caller needs to hold the lock
+func (c *Container) reclaim(resident ByteSize) (hibernation, error) {
+	var h hibernation
+
+	usageKey := "memory.usage_in_bytes"
+	if CgroupUnified() {
+		usageKey = "memory.current"
+	}
+
+	current, err := c.cgroupItemAsByteSize(usageKey, ErrMemLimit)
+	if err != nil {
+		return h, err
+	}
+
+	amount := reclaimAmount(current, resident)
+	if amount == 0 {
+		return h, nil
+	}
+
+	// memory.reclaim (5.19) fails with EAGAIN if it reclaimed less
+	if CgroupUnified() && c.setCgroupItem("memory.reclaim", fmt.Sprintf("%.f", amount)) == nil {
+		return h, nil
+	}
+
+	h.Key = squeezeKey(CgroupUnified())
+	h.Value = strings.TrimSpace(c.cgroupItem(h.Key)[0])
+	if h.Value == "" {
+		return hibernation{}, ErrMemLimit
+	}
+
+	if err := c.setCgroupItem(h.Key, fmt.Sprintf("%.f", resident)); err != nil {
+		if CgroupUnified() {
+			return hibernation{}, fmt.Errorf("%s: %s = %.f", ErrHibernateFailed, h.Key, resident)
+		}
+
+		// the cgroup1 limit is refused once reclaiming stalls, what was
+		// reclaimed until then stays in swap
+		if err := c.setCgroupItem(h.Key, h.Value); err != nil {
+			return hibernation{}, fmt.Errorf("%s: %s = %s", ErrHibernateFailed, h.Key, h.Value)
+		}
+		return hibernation{}, nil
+	}
+	return h, nil
+}
+
+// Hibernate parks the idle container cheaply on an overcommitted host: it
+// freezes the container and pushes its memory to swap until only
+// opts.Resident is left, so it needs swap to be effective. Thaw reverts it.
+// With opts.CheckpointDirectory set the container is checkpointed with CRIU
+// first, without stopping it, so it can be restored should the host need to
+// kill it.
+func (c *Container) Hibernate(opts HibernateOptions) (err error) {
+	finish, err := c.operation("Hibernate")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if opts.Resident < 0 {
+		return fmt.Errorf("%s: resident memory %s", ErrInvalidLimit, opts.Resident)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if opts.CheckpointDirectory != "" {
+		if err := c.checkpoint(CheckpointOptions{Directory: opts.CheckpointDirectory, Stop: false}); err != nil {
+			return err
+		}
+	}
+
+	// check the state using lockless version
+	if c.state() == FROZEN {
+		return ErrAlreadyFrozen
+	}
+
+	if err := c.freeze(); err != nil {
+		return err
+	}
+
+	h, err := c.reclaim(opts.Resident)
+	if err != nil {
+		c.unfreeze()
+		return err
+	}
+
+	content, err := json.Marshal(h)
+	if err == nil {
+		err = ioutil.WriteFile(c.hibernationPath(), content, 0644)
+	}
+
+	if err != nil {
+		if h.Key != "" {
+			c.setCgroupItem(h.Key, h.Value)
+		}
+		c.unfreeze()
+		return err
+	}
+	return nil
+}
+
+// Hibernated returns whether the container was hibernated and not thawed.
+func (c *Container) Hibernated() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return false
+	}
+
+	_, err := os.Stat(c.hibernationPath())
+	return err == nil && c.state() == FROZEN
+}
+
+// Thaw resumes the container parked by Hibernate, restoring its memory limit
+// before unfreezing it. Its memory is swapped back in as it is used.
+func (c *Container) Thaw() (err error) {
+	finish, err := c.operation("Thaw")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if err := c.makeSure(isRunning); err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadFile(c.hibernationPath())
+	if os.IsNotExist(err) {
+		return ErrNotHibernated
+	} else if err != nil {
+		return err
+	}
+
+	var h hibernation
+	if err := json.Unmarshal(content, &h); err != nil {
+		return err
+	}
+
+	if h.Key != "" {
+		if err := c.setCgroupItem(h.Key, h.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, h.Key, h.Value)
+		}
+	}
+
+	if err := os.Remove(c.hibernationPath()); err != nil {
+		return err
+	}
+
+	if err := c.unfreeze(); err != nil && err != ErrNotFrozen {
+		return err
+	}
+	return nil
+}
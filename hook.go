@@ -0,0 +1,209 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// hookHelperArg marks an invocation of the current executable as the hook
+// helper.
+const hookHelperArg = "__go_lxc_hook__"
+
+// maxAbstractSocketName is the longest name of an abstract unix socket,
+// sun_path less the leading NUL.
+const maxAbstractSocketName = 107
+
+// DefaultHookTypes are the hooks HookEvents registers the helper for by
+// default. The pre-mount, mount, autodev and start hooks are missing as they
+// run in the network namespace of the container where the socket isn't
+// reachable.
+var DefaultHookTypes = []string{
+	"pre-start",
+	"start-host",
+	"stop",
+	"post-stop",
+	"clone",
+	"destroy",
+}
+
+// HookEvent represents a single execution of a container hook.
+type HookEvent struct {
+	// Container is the name of the container.
+	Container string
+	// Section is the config section of the hook, usually "lxc".
+	Section string
+	// Type is the hook type (e.g. "pre-start").
+	Type string
+	// Args holds the additional arguments passed to the hook.
+	Args []string
+	// Env holds the environment of the hook (LXC_ROOTFS_MOUNT, ...).
+	Env map[string]string
+}
+
+// runHookHelper forwards the hook invocation to the listening process. Errors
+// are ignored, a missing listener must not prevent the container from
+// starting.
+func runHookHelper(socket string, args []string) {
+	event := HookEvent{
+		Container: args[0],
+		Section:   args[1],
+		Type:      args[2],
+		Args:      args[3:],
+		Env:       make(map[string]string),
+	}
+
+	for _, v := range os.Environ() {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 {
+			event.Env[parts[0]] = parts[1]
+		}
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	json.NewEncoder(conn).Encode(event)
+}
+
+// trustedUID returns whether a peer running as uid may send events: root
+// and the user of this process.
+func trustedUID(uid uint32) bool {
+	return uid == 0 || int(uid) == os.Geteuid()
+}
+
+// trustedPeer returns whether the process at the other end of the unix
+// socket connection runs as a trusted user, any local user can connect to
+// an abstract socket.
+func trustedPeer(conn net.Conn) bool {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return false
+	}
+
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return false
+	}
+	return trustedUID(cred.Uid)
+}
+
+// hookListener receives the events sent by the hook helper.
+type hookListener struct {
+	listener net.Listener
+	events   chan HookEvent
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (h *hookListener) run() {
+	defer close(h.events)
+
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if !trustedPeer(conn) {
+			conn.Close()
+			continue
+		}
+
+		var event HookEvent
+		err = json.NewDecoder(conn).Decode(&event)
+		conn.Close()
+		if err != nil {
+			continue
+		}
+
+		select {
+		case h.events <- event:
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (h *hookListener) close() {
+	h.once.Do(func() {
+		close(h.done)
+		h.listener.Close()
+	})
+}
+
+// HookEvents registers a helper for the given hook types (DefaultHookTypes
+// if none are given) and returns a channel receiving an event whenever one
+// of these hooks runs. The helper is the current executable, which forwards
+// the hook type and environment over an abstract unix socket, events sent by
+// other users than root and the user of this process are dropped. Hooks
+// running in the network namespace of the container can't reach the socket. The
+// channel is closed when the container is released. The hooks are added to
+// the in-memory configuration and apply on the next start.
+func (c *Container) HookEvents(types ...string) (<-chan HookEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if c.hooks != nil {
+		return c.hooks.events, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	socket := fmt.Sprintf("@go-lxc-hook/%d/%s/%s", os.Getpid(), c.configPath(), c.name())
+	if len(socket) > maxAbstractSocketName+1 {
+		return nil, fmt.Errorf("%s: %q", ErrSocketNameTooLong, socket)
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(types) == 0 {
+		types = DefaultHookTypes
+	}
+
+	hook := shellQuote([]string{exe, hookHelperArg, socket})
+	for _, t := range types {
+		if err := c.setConfigItem("lxc.hook."+t, hook); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	c.hooks = &hookListener{listener: listener, events: make(chan HookEvent, 16), done: make(chan struct{})}
+	go c.hooks.run()
+
+	return c.hooks.events, nil
+}
@@ -0,0 +1,198 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// sshKeygenUnit regenerates the SSH host keys removed by ResetIdentity on the
+// next boot of systemd guests.
+const sshKeygenUnit = `[Unit]
+Description=Regenerate SSH host keys
+Before=ssh.service sshd.service
+ConditionPathExistsGlob=!/etc/ssh/ssh_host_*_key
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/ssh-keygen -A
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// refuseSymlink fails if the last component of path in a rootfs is a
+// symlink, which the guest could point at a file of the host.
+func refuseSymlink(path string) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s: %q is a symlink", ErrUnsafeRootfsPath, path)
+	}
+	return nil
+}
+
+// writeRootfsFile writes a file of a rootfs whose parents were resolved by
+// resolvePath, without following a symlink in its last component.
+func writeRootfsFile(path string, content []byte, perm os.FileMode) error {
+	if err := refuseSymlink(path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readResolvedFile reads a file of a rootfs whose parents were resolved by
+// resolvePath, without following a symlink in its last component.
+func readResolvedFile(path string) ([]byte, error) {
+	if err := refuseSymlink(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// resetIdentity clears the machine-id, the SSH host keys and the journal of
+// the guest in rootfs.
+func resetIdentity(rootfs string) error {
+	resolve := func(path string) (string, error) {
+		return resolvePath(rootfs, path)
+	}
+
+	machineID, err := resolve("/etc/machine-id")
+	if err != nil {
+		return err
+	}
+
+	if err := refuseSymlink(machineID); err != nil {
+		return err
+	}
+
+	// an empty machine-id is regenerated by systemd on boot
+	if content, err := ioutil.ReadFile(machineID); err == nil {
+		if id := strings.TrimSpace(string(content)); id != "" && !strings.Contains(id, "/") {
+			journal, err := resolve(filepath.Join("/var/log/journal", id))
+			if err != nil {
+				return err
+			}
+
+			if err := os.RemoveAll(journal); err != nil {
+				return err
+			}
+		}
+
+		if err := writeRootfsFile(machineID, nil, 0444); err != nil {
+			return err
+		}
+	}
+
+	dbusID, err := resolve("/var/lib/dbus/machine-id")
+	if err != nil {
+		return err
+	}
+
+	// usually a symlink to /etc/machine-id
+	if info, err := os.Lstat(dbusID); err == nil && info.Mode().IsRegular() {
+		if err := os.Remove(dbusID); err != nil {
+			return err
+		}
+	}
+
+	sshDir, err := resolve("/etc/ssh")
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(sshDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	keys, err := filepath.Glob(filepath.Join(sshDir, "ssh_host_*"))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := os.Remove(key); err != nil {
+			return err
+		}
+	}
+
+	unit, err := resolve("/etc/systemd/system/go-lxc-ssh-keygen.service")
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(filepath.Dir(unit)); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	if err := writeRootfsFile(unit, []byte(sshKeygenUnit), 0644); err != nil {
+		return err
+	}
+
+	link, err := resolve("/etc/systemd/system/multi-user.target.wants/go-lxc-ssh-keygen.service")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return err
+	}
+
+	os.Remove(link)
+	return os.Symlink("/etc/systemd/system/go-lxc-ssh-keygen.service", link)
+}
+
+// ResetIdentity clears the identity a guest inherits when cloned: it empties
+// /etc/machine-id so a new one is generated on boot, removes the journal of
+// the old machine-id and removes the SSH host keys, which systemd guests
+// regenerate on the next boot. The container has to be stopped.
+func (c *Container) ResetIdentity() (err error) {
+	finish, err := c.operation("ResetIdentity")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
+		return err
+	}
+
+	rootfs, unmount, err := c.mountRootfs()
+	if err != nil {
+		return err
+	}
+	defer unmount()
+
+	return resetIdentity(rootfs)
+}
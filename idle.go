@@ -0,0 +1,158 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"sync"
+	"time"
+)
+
+// idleTracker tracks when a container was last active.
+type idleTracker struct {
+	last      time.Time
+	traffic   ByteSize
+	processes int
+}
+
+// observe records a sample of the activity counters and returns how long
+// the container has been idle. Any change of the traffic or more than one
+// process count as activity.
+func (t *idleTracker) observe(now time.Time, traffic ByteSize, processes int) time.Duration {
+	if t.last.IsZero() || traffic != t.traffic || processes > 1 {
+		t.last = now
+	}
+	t.traffic = traffic
+	t.processes = processes
+
+	return now.Sub(t.last)
+}
+
+// reset restarts the idle time, e.g. after the container (re)started.
+func (t *idleTracker) reset() {
+	*t = idleTracker{}
+}
+
+// IdleWatcher shuts a container down once it's idle, see WatchIdle.
+type IdleWatcher struct {
+	c      *Container
+	policy IdlePolicy
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// traffic returns the bytes sent and received by the container.
+func (w *IdleWatcher) traffic() ByteSize {
+	var total ByteSize
+
+	stats, err := w.c.InterfaceStats()
+	if err != nil {
+		return 0
+	}
+
+	for _, iface := range stats {
+		for _, v := range iface {
+			total += v
+		}
+	}
+	return total
+}
+
+// processes returns the number of processes of the container, 1 if it's
+// init only.
+func (w *IdleWatcher) processes() int {
+	pids, err := namespacePIDs(w.c.InitPid())
+	if err != nil {
+		// can't tell, count it as busy
+		return 2
+	}
+	return len(pids)
+}
+
+func (w *IdleWatcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.policy.Interval)
+	defer ticker.Stop()
+
+	var tracker idleTracker
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if w.c.State() != RUNNING {
+			tracker.reset()
+			continue
+		}
+
+		var traffic ByteSize
+		if w.policy.Traffic {
+			traffic = w.traffic()
+		}
+
+		processes := 1
+		if w.policy.Processes {
+			processes = w.processes()
+		}
+
+		idle := tracker.observe(time.Now(), traffic, processes)
+		if idle < w.policy.Timeout {
+			continue
+		}
+
+		if w.policy.Bus != nil {
+			w.policy.Bus.Publish(Event{Type: EventIdle, Container: w.c.Name(), LXCPath: w.c.ConfigPath(), Idle: idle})
+		}
+
+		w.c.Shutdown(w.policy.ShutdownTimeout)
+		tracker.reset()
+	}
+}
+
+// Stop stops watching the container and waits for the watcher to exit.
+func (w *IdleWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// WatchIdle shuts the container down once it was idle for the timeout of
+// the policy while RUNNING, i.e. it had no network traffic and, if the
+// policy counts them, no processes besides init. Frozen containers aren't
+// considered. Combined with an Activator it scales services to zero. Call
+// Stop on the returned IdleWatcher to stop watching.
+func (c *Container) WatchIdle(policy IdlePolicy) (*IdleWatcher, error) {
+	if !policy.Traffic && !policy.Processes {
+		return nil, ErrInsufficientNumberOfArguments
+	}
+
+	if policy.Interval <= 0 || policy.Timeout <= 0 {
+		return nil, ErrInsufficientNumberOfArguments
+	}
+
+	c.mu.RLock()
+	if err := c.makeSure(isDefined); err != nil {
+		c.mu.RUnlock()
+		return nil, err
+	}
+	c.mu.RUnlock()
+
+	w := &IdleWatcher{
+		c:      c,
+		policy: policy,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+
+	return w, nil
+}
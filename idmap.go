@@ -0,0 +1,485 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// IDType specifies whether an idmap entry maps user or group ids.
+type IDType int
+
+const (
+	// IDTypeUID maps user ids
+	IDTypeUID IDType = iota + 1
+	// IDTypeGID maps group ids
+	IDTypeGID
+)
+
+// IDType as string
+func (t IDType) String() string {
+	switch t {
+	case IDTypeUID:
+		return "u"
+	case IDTypeGID:
+		return "g"
+	}
+	return ""
+}
+
+// IDMapEntry maps a contiguous range of ids inside the container to a range
+// of ids on the host.
+type IDMapEntry struct {
+	Type   IDType
+	Nsid   int64
+	Hostid int64
+	Range  int64
+}
+
+// String returns the entry in lxc.idmap format, e.g. "u 0 100000 65536".
+func (e IDMapEntry) String() string {
+	return fmt.Sprintf("%s %d %d %d", e.Type, e.Nsid, e.Hostid, e.Range)
+}
+
+// IDMap is the set of uid and gid mappings of an unprivileged container.
+type IDMap []IDMapEntry
+
+// ParseIDMap parses lxc.idmap values such as "u 0 100000 65536".
+func ParseIDMap(values []string) (IDMap, error) {
+	var idmap IDMap
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		fields := strings.Fields(v)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidIDMap, v)
+		}
+
+		var entry IDMapEntry
+		switch fields[0] {
+		case "u":
+			entry.Type = IDTypeUID
+		case "g":
+			entry.Type = IDTypeGID
+		default:
+			return nil, fmt.Errorf("%s: %q", ErrInvalidIDMap, v)
+		}
+
+		nums := make([]int64, 3)
+		for i, f := range fields[1:] {
+			n, err := strconv.ParseInt(f, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s: %q", ErrInvalidIDMap, v)
+			}
+			nums[i] = n
+		}
+		entry.Nsid, entry.Hostid, entry.Range = nums[0], nums[1], nums[2]
+
+		idmap = append(idmap, entry)
+	}
+
+	return idmap, nil
+}
+
+// ToHost maps an id as seen inside the container to the corresponding host id.
+func (m IDMap) ToHost(t IDType, id int64) (int64, bool) {
+	for _, e := range m {
+		if e.Type == t && id >= e.Nsid && id < e.Nsid+e.Range {
+			return e.Hostid + id - e.Nsid, true
+		}
+	}
+	return -1, false
+}
+
+// ToContainer maps a host id to the corresponding id inside the container.
+func (m IDMap) ToContainer(t IDType, id int64) (int64, bool) {
+	for _, e := range m {
+		if e.Type == t && id >= e.Hostid && id < e.Hostid+e.Range {
+			return e.Nsid + id - e.Hostid, true
+		}
+	}
+	return -1, false
+}
+
+// IDMap returns the idmap of the container. The map is empty for privileged
+// containers.
+func (c *Container) IDMap() (IDMap, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	return c.idMap()
+}
+
+// Caller needs to hold the lock
+func (c *Container) idMap() (IDMap, error) {
+	if VersionAtLeast(2, 1, 0) {
+		return ParseIDMap(c.configItem("lxc.idmap"))
+	}
+
+	return ParseIDMap(c.configItem("lxc.id_map"))
+}
+
+// ShiftRootfs chowns every file below path from container ids to the host ids
+// given by idmap, so a rootfs prepared for a privileged container can be used
+// by an unprivileged one. POSIX ACLs and file capabilities are shifted as well.
+func ShiftRootfs(path string, idmap IDMap) error {
+	return shiftTree(path, idmap.ToHost, true)
+}
+
+// UnshiftRootfs reverts ShiftRootfs, chowning every file below path from host
+// ids back to container ids, e.g. to export the rootfs of an unprivileged
+// container.
+func UnshiftRootfs(path string, idmap IDMap) error {
+	return shiftTree(path, idmap.ToContainer, false)
+}
+
+// RootfsOptions type is used for defining how the rootfs of an unprivileged
+// container is prepared.
+type RootfsOptions struct {
+	// UseIdmappedMounts has liblxc mount the rootfs idmapped to the
+	// container's idmap instead of chowning every file. It falls back to
+	// chown-shifting if the kernel or liblxc lack idmapped mounts support.
+	UseIdmappedMounts bool
+}
+
+var (
+	idmappedMountsOnce      sync.Once
+	idmappedMountsSupported bool
+)
+
+// IdmappedMountsSupported returns true if both the kernel and liblxc support
+// idmapped mounts.
+func IdmappedMountsSupported() bool {
+	idmappedMountsOnce.Do(func() {
+		if !HasAPIExtension("idmapped_mounts_v2") {
+			return
+		}
+
+		// mount_setattr() and MOUNT_ATTR_IDMAP were added together, so
+		// anything but ENOSYS for a bogus call means support.
+		_, _, errno := unix.Syscall6(unix.SYS_MOUNT_SETATTR, ^uintptr(0), 0, 0, 0, 0, 0)
+		idmappedMountsSupported = errno != unix.ENOSYS
+	})
+
+	return idmappedMountsSupported
+}
+
+// PrepareRootfs makes the rootfs of the container usable with its idmap,
+// either by configuring an idmapped rootfs mount or by shifting the ownership
+// of the files on disk. It is a no-op for privileged containers.
+func (c *Container) PrepareRootfs(opts RootfsOptions) (err error) {
+	finish, err := c.operation("PrepareRootfs")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.prepareRootfs(opts)
+}
+
+// prepareRootfs makes the rootfs usable with the idmap of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) prepareRootfs(opts RootfsOptions) error {
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
+		return err
+	}
+
+	idmap, err := c.idMap()
+	if err != nil {
+		return err
+	}
+	if len(idmap) == 0 {
+		return nil
+	}
+
+	if opts.UseIdmappedMounts && IdmappedMountsSupported() {
+		options := c.configItem("lxc.rootfs.options")[0]
+		for _, o := range strings.Split(options, ",") {
+			if strings.HasPrefix(o, "idmap=") {
+				return nil
+			}
+		}
+
+		if options != "" {
+			options += ","
+		}
+		if err := c.setConfigItem("lxc.rootfs.options", options+"idmap=container"); err != nil {
+			return err
+		}
+		return c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config"))
+	}
+
+	rootfs := c.rootfsPath()
+	if rootfs == "" {
+		return ErrNotSupported
+	}
+	return ShiftRootfs(rootfs, idmap)
+}
+
+// rootfsPath returns the host path of a directory backed rootfs, or an empty
+// string for block device and layered backends.
+//
+// Caller needs to hold the lock
+func (c *Container) rootfsPath() string {
+	key := "lxc.rootfs.path"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.rootfs"
+	}
+
+	rootfs := c.configItem(key)[0]
+	if strings.HasPrefix(rootfs, "/") {
+		return rootfs
+	}
+
+	parts := strings.SplitN(rootfs, ":", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	switch parts[0] {
+	case "dir", "btrfs", "zfs":
+		if strings.HasPrefix(parts[1], "/") {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+type idMapper func(t IDType, id int64) (int64, bool)
+
+func shiftTree(root string, mapID idMapper, toHost bool) error {
+	// hard links share their inode, which must be shifted once only
+	seen := make(map[[2]uint64]bool)
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 && !info.IsDir() {
+			inode := [2]uint64{uint64(st.Dev), st.Ino}
+			if seen[inode] {
+				return nil
+			}
+			seen[inode] = true
+		}
+
+		if err := shiftFile(path, info, mapID, toHost); err != nil {
+			return fmt.Errorf("%s: %q: %v", ErrShiftFailed, path, err)
+		}
+		return nil
+	})
+}
+
+func shiftFile(path string, info os.FileInfo, mapID idMapper, toHost bool) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unsupported file info")
+	}
+
+	uid, ok := mapID(IDTypeUID, int64(st.Uid))
+	if !ok {
+		return fmt.Errorf("uid %d is not mapped", st.Uid)
+	}
+
+	gid, ok := mapID(IDTypeGID, int64(st.Gid))
+	if !ok {
+		return fmt.Errorf("gid %d is not mapped", st.Gid)
+	}
+
+	// Read the capabilities before chown() drops them.
+	isLink := info.Mode()&os.ModeSymlink != 0
+	var caps []byte
+	if !isLink && info.Mode().IsRegular() {
+		caps, _ = getxattr(path, "security.capability")
+	}
+
+	if err := unix.Lchown(path, int(uid), int(gid)); err != nil {
+		return err
+	}
+
+	if isLink {
+		return nil
+	}
+
+	// chown() clears the setuid and setgid bits.
+	if info.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0 {
+		if err := os.Chmod(path, info.Mode()); err != nil {
+			return err
+		}
+	}
+
+	if caps != nil {
+		caps, err := shiftCapabilities(caps, mapID, toHost)
+		if err != nil {
+			return err
+		}
+		if err := unix.Lsetxattr(path, "security.capability", caps, 0); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{"system.posix_acl_access", "system.posix_acl_default"} {
+		acl, err := getxattr(path, name)
+		if err != nil || acl == nil {
+			continue
+		}
+
+		acl, err = shiftACL(acl, mapID)
+		if err != nil {
+			return err
+		}
+		if err := unix.Lsetxattr(path, name, acl, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getxattr(path string, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, name, nil)
+	if err != nil || size <= 0 {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Lgetxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+const (
+	aclHeaderSize = 4
+	aclEntrySize  = 8
+	aclUser       = 0x02
+	aclGroup      = 0x08
+)
+
+// shiftACL shifts the ids of named user and group entries of a POSIX ACL in
+// its on-disk xattr representation.
+func shiftACL(acl []byte, mapID idMapper) ([]byte, error) {
+	if len(acl) < aclHeaderSize || (len(acl)-aclHeaderSize)%aclEntrySize != 0 {
+		return nil, fmt.Errorf("invalid POSIX ACL")
+	}
+
+	out := make([]byte, len(acl))
+	copy(out, acl)
+
+	for off := aclHeaderSize; off < len(out); off += aclEntrySize {
+		var t IDType
+		switch binary.LittleEndian.Uint16(out[off:]) {
+		case aclUser:
+			t = IDTypeUID
+		case aclGroup:
+			t = IDTypeGID
+		default:
+			continue
+		}
+
+		id := binary.LittleEndian.Uint32(out[off+4:])
+		mapped, ok := mapID(t, int64(id))
+		if !ok {
+			return nil, fmt.Errorf("ACL %s id %d is not mapped", t, id)
+		}
+		binary.LittleEndian.PutUint32(out[off+4:], uint32(mapped))
+	}
+
+	return out, nil
+}
+
+const (
+	vfsCapRevision2 = 0x02000000
+	vfsCapRevision3 = 0x03000000
+	vfsCapRevMask   = 0xff000000
+	vfsCapV2Size    = 20
+	vfsCapV3Size    = 24
+)
+
+// shiftCapabilities shifts the root uid of namespaced (revision 3) file
+// capabilities. Revision 2 capabilities belong to the root user of the
+// namespace the rootfs is used in: shifting them to the host converts them to
+// revision 3 owned by the root user of the container, unshifting converts
+// capabilities owned by the root user of the container back to revision 2.
+func shiftCapabilities(caps []byte, mapID idMapper, toHost bool) ([]byte, error) {
+	if len(caps) < 4 {
+		return nil, fmt.Errorf("invalid file capabilities")
+	}
+
+	magic := binary.LittleEndian.Uint32(caps)
+	switch magic & vfsCapRevMask {
+	case vfsCapRevision2:
+		if len(caps) != vfsCapV2Size {
+			return nil, fmt.Errorf("invalid file capabilities")
+		}
+
+		if !toHost {
+			return caps, nil
+		}
+
+		rootid, ok := mapID(IDTypeUID, 0)
+		if !ok {
+			return nil, fmt.Errorf("capability root id 0 is not mapped")
+		}
+
+		out := make([]byte, vfsCapV3Size)
+		copy(out, caps)
+		binary.LittleEndian.PutUint32(out, vfsCapRevision3|(magic&^vfsCapRevMask))
+		binary.LittleEndian.PutUint32(out[vfsCapV2Size:], uint32(rootid))
+		return out, nil
+	case vfsCapRevision3:
+		if len(caps) != vfsCapV3Size {
+			return nil, fmt.Errorf("invalid file capabilities")
+		}
+
+		rootid, ok := mapID(IDTypeUID, int64(binary.LittleEndian.Uint32(caps[vfsCapV2Size:])))
+		if !ok {
+			return nil, fmt.Errorf("capability root id is not mapped")
+		}
+
+		if !toHost && rootid == 0 {
+			out := make([]byte, vfsCapV2Size)
+			copy(out, caps)
+			binary.LittleEndian.PutUint32(out, vfsCapRevision2|(magic&^vfsCapRevMask))
+			return out, nil
+		}
+
+		out := make([]byte, vfsCapV3Size)
+		copy(out, caps)
+		binary.LittleEndian.PutUint32(out[vfsCapV2Size:], uint32(rootid))
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported file capabilities revision")
+}
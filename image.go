@@ -0,0 +1,268 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// quoteArgs joins args into a command line as parsed by liblxc.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// imageConfigItems translates the image configuration into config items.
+// Only numeric users and groups are supported.
+func imageConfigItems(config ociImageConfig) []KeyValue {
+	var items []KeyValue
+
+	if args := append(append([]string{}, config.Config.Entrypoint...), config.Config.Cmd...); len(args) > 0 {
+		items = append(items, KeyValue{"lxc.init.cmd", quoteArgs(args)})
+	}
+
+	if config.Config.WorkingDir != "" {
+		items = append(items, KeyValue{"lxc.init.cwd", config.Config.WorkingDir})
+	}
+
+	for _, env := range config.Config.Env {
+		items = append(items, KeyValue{"lxc.environment", env})
+	}
+
+	if config.Config.User != "" {
+		parts := strings.SplitN(config.Config.User, ":", 2)
+		if _, err := strconv.ParseUint(parts[0], 10, 32); err == nil {
+			items = append(items, KeyValue{"lxc.init.uid", parts[0]})
+		}
+		if len(parts) == 2 {
+			if _, err := strconv.ParseUint(parts[1], 10, 32); err == nil {
+				items = append(items, KeyValue{"lxc.init.gid", parts[1]})
+			}
+		}
+	}
+
+	return items
+}
+
+// contextReader fails reads once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// pullImage downloads the configuration and the layers of the image into
+// dir, returning the paths of the layers in order.
+func pullImage(ctx context.Context, ref string, opts ImageOptions, dir string) (ociImageConfig, []string, error) {
+	var config ociImageConfig
+
+	r, err := parseImageReference(ref)
+	if err != nil {
+		return config, nil, err
+	}
+
+	platform := opts.Platform
+	if platform == "" {
+		platform = hostPlatform()
+	}
+
+	client, err := newRegistryClient(ctx, r, opts)
+	if err != nil {
+		return config, nil, err
+	}
+
+	manifest, digest, err := client.manifest(platform)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if opts.Digest != "" && opts.Digest != digest {
+		return config, nil, fmt.Errorf("%s: expected digest %q, got %q", ErrVerificationFailed, opts.Digest, digest)
+	}
+
+	if opts.CosignPublicKey != nil {
+		key, err := parsePublicKey(opts.CosignPublicKey)
+		if err != nil {
+			return config, nil, err
+		}
+
+		if err := client.verifyCosign(key, digest, dir); err != nil {
+			return config, nil, err
+		}
+	}
+
+	path, err := client.blob(manifest.Config, dir)
+	if err != nil {
+		return config, nil, err
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, nil, fmt.Errorf("%s: %v", ErrPullFailed, err)
+	}
+
+	var layers []string
+	for _, layer := range manifest.Layers {
+		path, err := client.blob(layer, dir)
+		if err != nil {
+			return config, nil, err
+		}
+		layers = append(layers, path)
+	}
+
+	return config, layers, nil
+}
+
+// CreateFromImage creates the container from an OCI image pulled from a
+// registry, e.g. "docker.io/library/alpine:3.20". The layers are verified
+// against their digests and extracted into the rootfs of the container, the
+// entrypoint, environment and working directory of the image are set as its
+// init command. The image can be pinned to a digest or required to be signed
+// with cosign, see ImageOptions.
+func (c *Container) CreateFromImage(ref string, opts ImageOptions) error {
+	return c.CreateFromImageContext(context.Background(), ref, opts)
+}
+
+// CreateFromImageContext is like CreateFromImage but aborts the pull and the
+// extraction of the layers once ctx is done. Interrupted downloads are
+// resumed with range requests and verified again.
+func (c *Container) CreateFromImageContext(ctx context.Context, ref string, opts ImageOptions) (err error) {
+	finish, err := c.operation("CreateFromImageContext", ref)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	if opts.Owner != "" && !validOwner(opts.Owner) {
+		return fmt.Errorf("%s: %q", ErrInvalidOwner, opts.Owner)
+	}
+
+	c.mu.Lock()
+	if err := c.makeSure(isNotDefined); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	name, lxcpath := c.name(), c.configPath()
+	c.mu.Unlock()
+
+	if opts.Download == nil {
+		config, err := LoadDownloadConfig(lxcpath)
+		if err != nil {
+			return err
+		}
+		opts.Download = &config
+	}
+
+	cache := opts.CacheDir
+	if cache == "" {
+		tmp, err := ioutil.TempDir("", "go-lxc-image")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		cache = tmp
+	} else if err := os.MkdirAll(cache, 0700); err != nil {
+		return err
+	}
+
+	config, layers, err := pullImage(ctx, ref, opts, cache)
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrPullFailed, err)
+	}
+
+	dir := filepath.Join(lxcpath, name)
+	cleanup := func(err error) error {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	var readers []io.Reader
+	for _, layer := range layers {
+		f, err := os.Open(layer)
+		if err != nil {
+			return cleanup(err)
+		}
+		defer f.Close()
+		readers = append(readers, contextReader{ctx, f})
+	}
+
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := ApplyLayers(rootfs, readers); err != nil {
+		return cleanup(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	defaultConfig := defaultConfigFile()
+	if _, err := os.Stat(defaultConfig); err == nil {
+		if err := c.loadConfigFile(defaultConfig); err != nil {
+			return cleanup(err)
+		}
+	}
+
+	var items []KeyValue
+	if _, err := os.Stat("/usr/share/lxc/config/common.conf"); err == nil {
+		items = append(items, KeyValue{"lxc.include", "/usr/share/lxc/config/common.conf"})
+	}
+
+	items = append(items,
+		KeyValue{"lxc.rootfs.path", "dir:" + rootfs},
+		KeyValue{"lxc.uts.name", name},
+	)
+	items = append(items, imageConfigItems(config)...)
+
+	for _, kv := range items {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return cleanup(fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value))
+		}
+	}
+
+	if err := c.saveConfigFile(filepath.Join(dir, "config")); err != nil {
+		return cleanup(err)
+	}
+
+	if _, err := assignUUID(lxcpath, name); err != nil {
+		return cleanup(err)
+	}
+
+	if err := writeOwner(lxcpath, name, opts.Owner); err != nil {
+		return cleanup(err)
+	}
+
+	// layers hold the rootfs unshifted
+	if err := c.prepareRootfs(RootfsOptions{}); err != nil {
+		return cleanup(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,374 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigOrigin is a config item along with the file it was read from.
+type ConfigOrigin struct {
+	KeyValue
+	// File is the included file the item comes from, empty for items of
+	// the configuration of the container itself.
+	File string
+}
+
+// parseConfigLines parses the items of a config file, skipping comments
+// and blank lines.
+func parseConfigLines(content string) []KeyValue {
+	var items []KeyValue
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		items = append(items, KeyValue{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+	}
+	return items
+}
+
+// includeFiles returns the files included by path. Including a directory
+// includes the *.conf files in it, in lexical order.
+func includeFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ErrIncludeFailed, err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(path, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// expandIncludes returns the items of the file with its includes expanded
+// in place. visiting holds the files being expanded to detect cycles.
+func expandIncludes(path string, visiting map[string]bool) ([]ConfigOrigin, error) {
+	files, err := includeFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []ConfigOrigin
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if visiting[abs] {
+			return nil, fmt.Errorf("%s: %q", ErrIncludeCycle, file)
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrIncludeFailed, err)
+		}
+
+		visiting[abs] = true
+		expanded, err := expandItems(parseConfigLines(string(content)), file, visiting)
+		delete(visiting, abs)
+
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, expanded...)
+	}
+	return items, nil
+}
+
+// expandItems replaces the lxc.include items with the items of the included
+// files.
+func expandItems(items []KeyValue, file string, visiting map[string]bool) ([]ConfigOrigin, error) {
+	var expanded []ConfigOrigin
+
+	for _, kv := range items {
+		if kv.Key != "lxc.include" {
+			expanded = append(expanded, ConfigOrigin{kv, file})
+			continue
+		}
+
+		included, err := expandIncludes(kv.Value, visiting)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, included...)
+	}
+	return expanded, nil
+}
+
+// unexpandedConfig returns the items of the configuration as they would be
+// saved, with the lxc.include items instead of the included ones.
+//
+// Caller needs to hold the lock
+func (c *Container) unexpandedConfig() ([]KeyValue, error) {
+	f, err := ioutil.TempFile("", "go-lxc-config")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := c.saveConfigFile(f.Name()); err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigLines(string(content)), nil
+}
+
+// Includes returns the files included by the configuration of the
+// container, in order. Files included by these aren't reported.
+func (c *Container) Includes() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	config, err := c.unexpandedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var includes []string
+	for _, kv := range config {
+		if kv.Key == "lxc.include" {
+			includes = append(includes, kv.Value)
+		}
+	}
+	return includes, nil
+}
+
+// AddInclude includes the file or directory in the configuration of the
+// container, loading its items. Includes leading back to path or to an
+// include file already being expanded are rejected with ErrIncludeCycle.
+func (c *Container) AddInclude(path string) (err error) {
+	finish, err := c.operation("AddInclude", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if _, err := expandIncludes(path, make(map[string]bool)); err != nil {
+		return err
+	}
+
+	return c.setConfigItem("lxc.include", path)
+}
+
+// RemoveInclude removes the include of the file or directory from the
+// configuration of the container, along with the items it loaded. The
+// configuration is reloaded, so items set directly are kept.
+func (c *Container) RemoveInclude(path string) (err error) {
+	finish, err := c.operation("RemoveInclude", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	config, err := c.unexpandedConfig()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	found := false
+	for _, kv := range config {
+		if kv.Key == "lxc.include" && kv.Value == path && !found {
+			found = true
+			continue
+		}
+		lines = append(lines, kv.String())
+	}
+
+	if !found {
+		return fmt.Errorf("%s: %q", ErrIncludeNotFound, path)
+	}
+
+	f, err := ioutil.TempFile("", "go-lxc-config")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(lines, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.reloadConfig(f.Name())
+}
+
+// ExpandedConfig returns the configuration of the container with the
+// included files expanded in place, recording where each item comes from.
+// Unlike DumpConfig the items keep the order of the config files.
+func (c *Container) ExpandedConfig() ([]ConfigOrigin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	config, err := c.unexpandedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return expandItems(config, "", make(map[string]bool))
+}
+
+// multiValueKeys are the keys accumulating their values instead of
+// replacing them, an empty value clears them.
+var multiValueKeys = []string{
+	"lxc.apparmor.raw",
+	"lxc.cap.drop",
+	"lxc.cap.keep",
+	"lxc.cgroup.devices.allow",
+	"lxc.cgroup.devices.deny",
+	"lxc.cgroup2.devices.allow",
+	"lxc.cgroup2.devices.deny",
+	"lxc.environment",
+	"lxc.group",
+	"lxc.hook.",
+	"lxc.idmap",
+	"lxc.id_map",
+	"lxc.mount.auto",
+	"lxc.mount.entry",
+	"lxc.net.*.ipv4.address",
+	"lxc.net.*.ipv6.address",
+}
+
+// isMultiValueKey returns true if the key accumulates its values.
+func isMultiValueKey(key string) bool {
+	for _, k := range multiValueKeys {
+		if strings.HasSuffix(k, ".") && strings.HasPrefix(key, k) {
+			return true
+		}
+
+		if ok, _ := filepath.Match(k, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultConfigFile returns the config file new containers start from.
+func defaultConfigFile() string {
+	if path := GlobalConfigItem("lxc.default_config"); path != "" {
+		return path
+	}
+	return "/etc/lxc/default.conf"
+}
+
+// effectiveConfig collapses the expanded items into the ones in effect.
+// Later values of a key replace earlier ones, except for keys accumulating
+// their values which are only reset by an empty value.
+func effectiveConfig(items []ConfigOrigin) []ConfigOrigin {
+	var effective []ConfigOrigin
+
+	drop := func(key string) {
+		kept := effective[:0]
+		for _, item := range effective {
+			if item.Key != key {
+				kept = append(kept, item)
+			}
+		}
+		effective = kept
+	}
+
+	for _, item := range items {
+		if !isMultiValueKey(item.Key) || item.Value == "" {
+			drop(item.Key)
+		}
+
+		if item.Value != "" {
+			effective = append(effective, item)
+		}
+	}
+	return effective
+}
+
+// EffectiveConfig returns the items liblxc uses for the container, along
+// with the file each of them was set in. Includes are resolved, keys set
+// more than once are reported with the value in effect only. For containers
+// which aren't defined yet, the default configuration they are created with
+// (lxc.default_config) comes first. Keys never set and left at the liblxc
+// defaults aren't reported.
+func (c *Container) EffectiveConfig() ([]ConfigOrigin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	var items []ConfigOrigin
+	if !c.defined() {
+		if _, err := os.Stat(defaultConfigFile()); err == nil {
+			defaults, err := expandIncludes(defaultConfigFile(), make(map[string]bool))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, defaults...)
+		}
+	}
+
+	config, err := c.unexpandedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := expandItems(config, "", make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	return effectiveConfig(append(items, expanded...)), nil
+}
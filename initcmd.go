@@ -0,0 +1,172 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// splitArgs splits a command line the way liblxc splits lxc.init.cmd,
+// honoring single and double quotes and backslash escapes. It's the inverse
+// of quoteArgs.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote: %s", s)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// containerInitItems translates init into config items, the ones left at
+// their defaults have empty values.
+func containerInitItems(init ContainerInit) ([]KeyValue, error) {
+	if init.UID < 0 || init.GID < 0 {
+		return nil, fmt.Errorf("%s: %d:%d", ErrSettingConfigItemFailed, init.UID, init.GID)
+	}
+
+	items := []KeyValue{
+		{"lxc.init.cmd", quoteArgs(init.Cmd)},
+		{"lxc.init.uid", ""},
+		{"lxc.init.gid", ""},
+		{"lxc.init.cwd", init.Cwd},
+	}
+
+	if init.UID != 0 {
+		items[1].Value = strconv.Itoa(init.UID)
+	}
+	if init.GID != 0 {
+		items[2].Value = strconv.Itoa(init.GID)
+	}
+
+	for _, env := range init.Env {
+		if env == "" || strings.HasPrefix(env, "=") {
+			return nil, fmt.Errorf("%s: lxc.environment = %q", ErrSettingConfigItemFailed, env)
+		}
+		items = append(items, KeyValue{"lxc.environment", env})
+	}
+	return items, nil
+}
+
+// Init returns the init process of the container found in its configuration.
+func (c *Container) Init() (ContainerInit, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return ContainerInit{}, ErrNotDefined
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ContainerInit{}, ErrNotSupported
+	}
+
+	cmd, err := splitArgs(c.configItem("lxc.init.cmd")[0])
+	if err != nil {
+		return ContainerInit{}, err
+	}
+
+	uid, err := c.configInt("lxc.init.uid")
+	if err != nil {
+		return ContainerInit{}, err
+	}
+
+	gid, err := c.configInt("lxc.init.gid")
+	if err != nil {
+		return ContainerInit{}, err
+	}
+
+	return ContainerInit{
+		Cmd: cmd,
+		UID: uid,
+		GID: gid,
+		Cwd: c.configItem("lxc.init.cwd")[0],
+		Env: nonEmpty(c.configItem("lxc.environment")),
+	}, nil
+}
+
+// SetInit replaces the init process in the configuration of the container,
+// including its environment. It applies on the next start.
+func (c *Container) SetInit(init ContainerInit) (err error) {
+	finish, err := c.operation("SetInit")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ErrNotSupported
+	}
+
+	items, err := containerInitItems(init)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{"lxc.init.cmd", "lxc.init.uid", "lxc.init.gid", "lxc.init.cwd", "lxc.environment"} {
+		if err := c.clearConfigItem(key); err != nil {
+			return err
+		}
+	}
+
+	for _, kv := range items {
+		if kv.Value == "" {
+			continue
+		}
+
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+		}
+	}
+	return nil
+}
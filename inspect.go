@@ -0,0 +1,134 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"strings"
+	"time"
+)
+
+// InspectConfig summarizes the configuration of a container.
+type InspectConfig struct {
+	Hostname  string    `json:"hostname,omitempty"`
+	Arch      string    `json:"arch,omitempty"`
+	Autostart bool      `json:"autostart"`
+	Networks  []Network `json:"networks,omitempty"`
+	// Volatile holds the values generated for the container, see
+	// Container.Volatile.
+	Volatile map[string]string `json:"volatile,omitempty"`
+}
+
+// InspectUsage is the resource usage of a running container.
+type InspectUsage struct {
+	Memory  ByteSize      `json:"memory"`
+	CPUTime time.Duration `json:"cpu_time"`
+}
+
+// InspectInfo describes a container, see Container.Inspect.
+type InspectInfo struct {
+	Name         string `json:"name"`
+	LXCPath      string `json:"lxcpath"`
+	ConfigFile   string `json:"config_file"`
+	State        string `json:"state"`
+	PID          int    `json:"pid,omitempty"`
+	BackingStore string `json:"backing_store"`
+	Rootfs       string `json:"rootfs,omitempty"`
+
+	IPAddresses []string      `json:"ip_addresses,omitempty"`
+	Config      InspectConfig `json:"config"`
+	// Mounts holds the lxc.mount.entry items, in fstab format.
+	Mounts []string `json:"mounts,omitempty"`
+	// Limits holds the cgroup items of the config, e.g.
+	// lxc.cgroup2.memory.max.
+	Limits    map[string]string `json:"limits,omitempty"`
+	Usage     *InspectUsage     `json:"usage,omitempty"`
+	Snapshots []Snapshot        `json:"snapshots,omitempty"`
+}
+
+// inspectConfig fills the parts of the info read from the config.
+func inspectConfig(info *InspectInfo, config []KeyValue) {
+	for _, kv := range config {
+		switch {
+		case kv.Key == "lxc.uts.name" || kv.Key == "lxc.utsname":
+			info.Config.Hostname = kv.Value
+		case kv.Key == "lxc.arch":
+			info.Config.Arch = kv.Value
+		case kv.Key == "lxc.start.auto":
+			info.Config.Autostart = kv.Value == "1"
+		case kv.Key == "lxc.rootfs.path" || kv.Key == "lxc.rootfs":
+			info.Rootfs = kv.Value
+		case kv.Key == "lxc.mount.entry":
+			info.Mounts = append(info.Mounts, kv.Value)
+		case strings.HasPrefix(kv.Key, "lxc.cgroup.") || strings.HasPrefix(kv.Key, "lxc.cgroup2."):
+			if info.Limits == nil {
+				info.Limits = make(map[string]string)
+			}
+			info.Limits[kv.Key] = kv.Value
+		}
+	}
+}
+
+// Inspect returns a document describing the container: its state, config
+// summary, mounts, limits, snapshots and, if running, init pid, addresses
+// and resource usage. It's meant to be serialized to JSON, e.g. for a
+// CLI. The parts are read one after the other, a container changing state
+// meanwhile may be described inconsistently.
+func (c *Container) Inspect() (InspectInfo, error) {
+	if !c.Defined() {
+		return InspectInfo{}, ErrNotDefined
+	}
+
+	state := c.State()
+	info := InspectInfo{
+		Name:         c.Name(),
+		LXCPath:      c.ConfigPath(),
+		ConfigFile:   c.ConfigFileName(),
+		State:        state.String(),
+		BackingStore: c.BackendStore().String(),
+	}
+
+	config, err := c.DumpConfig()
+	if err != nil {
+		return InspectInfo{}, err
+	}
+	inspectConfig(&info, config)
+
+	if info.Config.Networks, err = c.Networks(); err != nil {
+		return InspectInfo{}, err
+	}
+
+	if info.Config.Volatile, err = c.Volatile(); err != nil {
+		return InspectInfo{}, err
+	}
+
+	// no snapshots is reported as ErrNoSnapshot
+	if snapshots, err := c.Snapshots(); err == nil {
+		info.Snapshots = snapshots
+	}
+
+	if state != RUNNING && state != FROZEN {
+		return info, nil
+	}
+
+	info.PID = c.InitPid()
+
+	// the addresses and usage are best effort, the container may stop
+	// while being inspected
+	if addresses, err := c.IPAddresses(); err == nil {
+		info.IPAddresses = addresses
+	}
+
+	var usage InspectUsage
+	memory, merr := c.MemoryUsage()
+	cpu, cerr := c.CPUTime()
+	if merr == nil && cerr == nil {
+		usage.Memory = memory
+		usage.CPUTime = cpu
+		info.Usage = &usage
+	}
+	return info, nil
+}
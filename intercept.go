@@ -0,0 +1,57 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Operation describes a mutating operation about to be executed.
+type Operation struct {
+	// Name is the name of the method, e.g. "Destroy" or "SetConfigItem"
+	Name      string
+	Container *Container
+	Args      []string
+}
+
+// Interceptor is called before an operation is executed, returning an error
+// vetoes it. It may inspect the container, e.g. through ConfigItem, but must
+// not call operations on it.
+type Interceptor func(op Operation) error
+
+var (
+	interceptorMu sync.RWMutex
+	interceptor   Interceptor
+)
+
+// SetInterceptor installs the interceptor consulted before every mutating
+// operation (the ones recorded by the audit log), e.g. to refuse destroying
+// production containers. A nil interceptor allows all operations, which is
+// the default. Vetoed operations fail with ErrOperationDenied.
+func SetInterceptor(i Interceptor) {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+
+	interceptor = i
+}
+
+// intercept asks the interceptor whether the operation may be executed.
+func (c *Container) intercept(name string, args []string) error {
+	interceptorMu.RLock()
+	i := interceptor
+	interceptorMu.RUnlock()
+
+	if i == nil {
+		return nil
+	}
+
+	if err := i(Operation{Name: name, Container: c, Args: args}); err != nil {
+		return fmt.Errorf("%w: %v", ErrOperationDenied, err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// whiteoutPrefix marks the deletion of a file from a lower layer.
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaque marks a directory whose lower layer content is hidden.
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// resolvePath joins path to root, resolving symlinks in the parent
+// directories relative to root so the result can't escape it. The last
+// component isn't resolved.
+func resolvePath(root string, path string) (string, error) {
+	parts := strings.Split(filepath.Clean("/"+path), "/")
+	if len(parts) < 2 || parts[len(parts)-1] == "" {
+		return root, nil
+	}
+
+	resolved := "/"
+	pending := parts[1 : len(parts)-1]
+	for links := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			// missing parents are created as directories later on
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("%s: too many levels of symbolic links: %q", ErrApplyLayerFailed, path)
+		}
+
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(strings.Trim(target, "/"), "/"), pending...)
+		resolved = filepath.Clean(resolved)
+	}
+
+	return filepath.Join(root, resolved, parts[len(parts)-1]), nil
+}
+
+// decompressLayer transparently decompresses gzip compressed layers.
+func decompressLayer(r io.Reader) (io.Reader, error) {
+	buf := bufio.NewReader(r)
+
+	magic, err := buf.Peek(2)
+	if err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return gzip.NewReader(buf)
+	}
+	return buf, nil
+}
+
+// applyLayer extracts a single layer into root.
+func applyLayer(root string, layer io.Reader) error {
+	r, err := decompressLayer(layer)
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrApplyLayerFailed, err)
+	}
+
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	// paths created by this layer survive opaque whiteouts
+	created := make(map[string]bool)
+	var dirs []*tar.Header
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", ErrApplyLayerFailed, err)
+		}
+
+		name := filepath.Clean("/" + hdr.Name)
+		path, err := resolvePath(root, name)
+		if err != nil {
+			return err
+		}
+
+		base := filepath.Base(path)
+		dir := filepath.Dir(path)
+
+		if base == whiteoutOpaque {
+			entries, err := os.ReadDir(dir)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			for _, e := range entries {
+				p := filepath.Join(dir, e.Name())
+				if created[p] {
+					continue
+				}
+
+				if err := os.RemoveAll(p); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			// ".wh.." would remove the directory holding it
+			target := strings.TrimPrefix(base, whiteoutPrefix)
+			if target == "" || target == "." || target == ".." || strings.Contains(target, "/") {
+				return fmt.Errorf("%s: invalid whiteout %q", ErrApplyLayerFailed, hdr.Name)
+			}
+
+			whiteout := filepath.Join(dir, target)
+			if !strings.HasPrefix(whiteout, root+string(filepath.Separator)) {
+				return fmt.Errorf("%s: invalid whiteout %q", ErrApplyLayerFailed, hdr.Name)
+			}
+
+			if err := os.RemoveAll(whiteout); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		// replace existing entries unless both are directories
+		if info, err := os.Lstat(path); err == nil {
+			if !(info.IsDir() && hdr.Typeflag == tar.TypeDir) {
+				if err := os.RemoveAll(path); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := extractEntry(root, path, hdr, tr); err != nil {
+			return err
+		}
+		created[path] = true
+
+		if hdr.Typeflag == tar.TypeDir {
+			// set the directory times once their content is extracted
+			h := *hdr
+			h.Name = path
+			dirs = append(dirs, &h)
+		}
+	}
+
+	for _, hdr := range dirs {
+		ts := []unix.Timespec{unix.NsecToTimespec(hdr.AccessTime.UnixNano()), unix.NsecToTimespec(hdr.ModTime.UnixNano())}
+		if hdr.AccessTime.IsZero() {
+			ts[0] = ts[1]
+		}
+		unix.UtimesNanoAt(unix.AT_FDCWD, hdr.Name, ts, unix.AT_SYMLINK_NOFOLLOW)
+	}
+
+	return nil
+}
+
+// extractEntry creates a single entry and applies its metadata.
+func extractEntry(root string, path string, hdr *tar.Header, r io.Reader) error {
+	mode := uint32(hdr.Mode & 07777)
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.Mkdir(path, os.FileMode(mode)); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(mode))
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, r)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", ErrApplyLayerFailed, err)
+		}
+	case tar.TypeSymlink:
+		if err := os.Symlink(hdr.Linkname, path); err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		target, err := resolvePath(root, hdr.Linkname)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Link(target, path); err != nil {
+			return err
+		}
+		return nil
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		kind := uint32(unix.S_IFIFO)
+		if hdr.Typeflag == tar.TypeChar {
+			kind = unix.S_IFCHR
+		} else if hdr.Typeflag == tar.TypeBlock {
+			kind = unix.S_IFBLK
+		}
+
+		err := unix.Mknod(path, kind|mode, int(unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor))))
+		if err == unix.EPERM && kind != unix.S_IFIFO {
+			// unprivileged, the device nodes are provided by liblxc
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		// skip unsupported entries (e.g. global PAX headers)
+		return nil
+	}
+
+	if err := os.Lchown(path, hdr.Uid, hdr.Gid); err != nil && os.Geteuid() == 0 {
+		return err
+	}
+
+	for key, value := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "SCHILY.xattr.") {
+			unix.Lsetxattr(path, strings.TrimPrefix(key, "SCHILY.xattr."), []byte(value), 0)
+		}
+	}
+
+	if hdr.Typeflag == tar.TypeSymlink {
+		ts := []unix.Timespec{unix.NsecToTimespec(hdr.ModTime.UnixNano()), unix.NsecToTimespec(hdr.ModTime.UnixNano())}
+		unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW)
+		return nil
+	}
+
+	// chown clears the setuid and setgid bits
+	if err := os.Chmod(path, os.FileMode(mode&0777)|modeBits(mode)); err != nil {
+		return err
+	}
+
+	if hdr.Typeflag != tar.TypeDir {
+		return os.Chtimes(path, hdr.ModTime, hdr.ModTime)
+	}
+	return nil
+}
+
+// modeBits converts the setuid, setgid and sticky bits of a unix mode into
+// their os.FileMode equivalents.
+func modeBits(mode uint32) os.FileMode {
+	var m os.FileMode
+	if mode&unix.S_ISUID != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&unix.S_ISGID != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&unix.S_ISVTX != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
+
+// ApplyLayers extracts the given image layers (tar archives, optionally gzip
+// compressed) into rootfs in order, honoring AUFS/OCI whiteouts: ".wh.<name>"
+// deletes <name> from the lower layers and ".wh..wh..opq" hides the lower
+// layer content of its directory. Paths can't escape rootfs, even through
+// symlinks created by the layers.
+func ApplyLayers(rootfs string, layers []io.Reader) error {
+	if err := os.MkdirAll(rootfs, 0755); err != nil {
+		return err
+	}
+
+	for i, layer := range layers {
+		if err := applyLayer(rootfs, layer); err != nil {
+			return fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -2,10 +2,13 @@
 // Use of this source code is governed by a LGPLv2.1
 // license that can be found in the LICENSE file.
 
-// +build linux,cgo,!static_build
+// +build linux,cgo,!static_build,!static_liblxc
 
 package lxc
 
 // #cgo CFLAGS: -std=gnu11 -Wvla -Werror
 // #cgo pkg-config: lxc
 import "C"
+
+// staticLiblxc is set when liblxc is linked into the binary.
+const staticLiblxc = false
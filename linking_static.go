@@ -2,11 +2,14 @@
 // Use of this source code is governed by a LGPLv2.1
 // license that can be found in the LICENSE file.
 
-// +build linux,cgo,static_build
+// +build linux,cgo,static_build linux,cgo,static_liblxc
 
 package lxc
 
 // #cgo CFLAGS: -std=gnu11 -Wvla -Werror
 // #cgo pkg-config: --static lxc libcrypto
-// #cgo LDFLAGS: -static
+// #cgo LDFLAGS: -static -lpthread
 import "C"
+
+// staticLiblxc is set when liblxc is linked into the binary.
+const staticLiblxc = true
@@ -0,0 +1,348 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// rotatedLog returns the path of the n-th rotated log.
+func rotatedLog(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// logAge returns the time since the previous rotation of the log, or since
+// its creation if it was never rotated. It's 0 if unknown.
+func logAge(path string, now time.Time) time.Duration {
+	for _, rotated := range []string{rotatedLog(path, 1), rotatedLog(path, 1) + ".gz"} {
+		if fi, err := os.Stat(rotated); err == nil {
+			return now.Sub(fi.ModTime())
+		}
+	}
+
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx); err != nil || stx.Mask&unix.STATX_BTIME == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)))
+}
+
+// logNeedsRotation returns whether the log is due for rotation.
+func logNeedsRotation(path string, size int64, opts LogRotateOptions, now time.Time) bool {
+	if size == 0 {
+		return false
+	}
+
+	if opts.MaxSize > 0 && size > int64(opts.MaxSize) {
+		return true
+	}
+	return opts.MaxAge > 0 && logAge(path, now) > opts.MaxAge
+}
+
+// shiftLogs renames path.1 to path.2 and so on, dropping the ones beyond
+// keep. Compressed logs are shifted alike.
+func shiftLogs(path string, keep int) error {
+	for _, ext := range []string{"", ".gz"} {
+		if err := os.Remove(rotatedLog(path, keep) + ext); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for n := keep - 1; n >= 1; n-- {
+			if err := os.Rename(rotatedLog(path, n)+ext, rotatedLog(path, n+1)+ext); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressLog replaces the log with a gzip compressed <path>.gz.
+func compressLog(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, src); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// copyTruncate copies the log to dst and truncates it. liblxc keeps the
+// log open with O_APPEND, so it continues writing at the start.
+func copyTruncate(path string, dst string) error {
+	src, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return src.Truncate(0)
+}
+
+// rotateLogFile rotates the log if it's due, it returns whether it did.
+func rotateLogFile(path string, opts LogRotateOptions, now time.Time) (bool, error) {
+	if opts.Keep < 1 {
+		return false, fmt.Errorf("%s: keep %d", ErrRotateLogFailed, opts.Keep)
+	}
+
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !logNeedsRotation(path, fi.Size(), opts, now) {
+		return false, nil
+	}
+
+	if err := shiftLogs(path, opts.Keep); err != nil {
+		return false, fmt.Errorf("%s: %v", ErrRotateLogFailed, err)
+	}
+
+	if err := copyTruncate(path, rotatedLog(path, 1)); err != nil {
+		return false, fmt.Errorf("%s: %v", ErrRotateLogFailed, err)
+	}
+
+	if opts.Compress {
+		if err := compressLog(rotatedLog(path, 1)); err != nil {
+			return true, fmt.Errorf("%s: %v", ErrRotateLogFailed, err)
+		}
+	}
+	return true, nil
+}
+
+// RotateLog rotates the log file of the container once it exceeds the size
+// or age of the options, it returns whether it did. The log is copied to
+// <log>.1 and truncated in place, so a running container keeps logging.
+func (c *Container) RotateLog(opts LogRotateOptions) (_ bool, err error) {
+	finish, err := c.operation("RotateLog")
+	if err != nil {
+		return false, err
+	}
+	defer finish(&err)
+
+	path := c.LogFile()
+	if path == "" {
+		return false, ErrNoLogFile
+	}
+
+	return rotateLogFile(path, opts, time.Now())
+}
+
+// logFiles returns the log file and console log file of the container,
+// the ones not set are left out.
+//
+// Caller needs to hold the lock
+func (c *Container) logFiles() []string {
+	key := "lxc.logfile"
+	if VersionAtLeast(2, 1, 0) {
+		key = "lxc.log.file"
+	}
+
+	var files []string
+	for _, k := range []string{key, "lxc.console.logfile"} {
+		if values := nonEmpty(c.configItem(k)); len(values) > 0 {
+			files = append(files, values[0])
+		}
+	}
+	return files
+}
+
+// LogRotator rotates the log files and console log files of containers,
+// and any other log file added to it, e.g. of containers managed by a
+// supervisor. It's safe for concurrent use.
+type LogRotator struct {
+	opts LogRotateOptions
+
+	mu       sync.Mutex
+	files    map[string]bool
+	lxcpaths map[string]bool
+
+	// OnError is called with the errors of rotating a log, which don't
+	// stop the rotator. Errors are dropped if nil.
+	OnError func(path string, err error)
+}
+
+// NewLogRotator returns a LogRotator rotating logs according to the
+// options. Set Compress to gzip the rotated logs.
+func NewLogRotator(opts LogRotateOptions) *LogRotator {
+	return &LogRotator{
+		opts:     opts,
+		files:    make(map[string]bool),
+		lxcpaths: make(map[string]bool),
+	}
+}
+
+// AddFile adds a log file to rotate.
+func (r *LogRotator) AddFile(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.files[path] = true
+}
+
+// RemoveFile stops rotating a log file.
+func (r *LogRotator) RemoveFile(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.files, path)
+}
+
+// AddContainer adds the log file and console log file of the container
+// as configured now.
+func (r *LogRotator) AddContainer(c *Container) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	for _, path := range c.logFiles() {
+		r.AddFile(path)
+	}
+	return nil
+}
+
+// AddLXCPath rotates the logs of all containers of the lxcpath, the
+// default one if not given. The containers are looked up on every pass,
+// so containers created later are picked up.
+func (r *LogRotator) AddLXCPath(lxcpath ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := DefaultConfigPath()
+	if len(lxcpath) > 0 {
+		path = lxcpath[0]
+	}
+	r.lxcpaths[path] = true
+}
+
+// paths returns the log files to rotate.
+func (r *LogRotator) paths() []string {
+	r.mu.Lock()
+	var paths []string
+	for path := range r.files {
+		paths = append(paths, path)
+	}
+
+	var lxcpaths []string
+	for lxcpath := range r.lxcpaths {
+		lxcpaths = append(lxcpaths, lxcpath)
+	}
+	r.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		seen[path] = true
+	}
+
+	for _, lxcpath := range lxcpaths {
+		for _, c := range DefinedContainers(lxcpath) {
+			c.mu.RLock()
+			for _, path := range c.logFiles() {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+			c.mu.RUnlock()
+			c.Release()
+		}
+	}
+	return paths
+}
+
+// Rotate rotates the logs which are due once, it returns the ones it
+// rotated.
+func (r *LogRotator) Rotate() []string {
+	var rotated []string
+
+	now := time.Now()
+	for _, path := range r.paths() {
+		ok, err := rotateLogFile(path, r.opts, now)
+		if err != nil && r.OnError != nil {
+			r.OnError(path, err)
+		}
+		if ok {
+			rotated = append(rotated, path)
+		}
+	}
+	return rotated
+}
+
+// Run rotates the logs which are due every interval until the context is
+// done.
+func (r *LogRotator) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Rotate()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
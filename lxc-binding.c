@@ -4,12 +4,21 @@
 
 // +build linux,cgo
 
+#ifndef _GNU_SOURCE
+#define _GNU_SOURCE
+#endif
 #include <errno.h>
+#include <fcntl.h>
+#include <signal.h>
 #include <stdbool.h>
 #include <string.h>
+#include <sys/prctl.h>
+#include <sys/resource.h>
+#include <sys/stat.h>
 #include <sys/types.h>
 #include <sys/wait.h>
 #include <errno.h>
+#include <unistd.h>
 
 #include <lxc/lxccontainer.h>
 #include <lxc/attach_options.h>
@@ -134,6 +143,11 @@ bool go_lxc_wait(struct lxc_container *c, const char *state, int timeout) {
 	return c->wait(c, state, timeout);
 }
 
+int go_lxc_get_config_item_len(struct lxc_container *c, const char *key)
+{
+	return c->get_config_item(c, key, NULL, 0);
+}
+
 char *go_lxc_get_config_item(struct lxc_container *c, const char *key)
 {
 	char *value = NULL;
@@ -213,6 +227,24 @@ again:
 	return value;
 }
 
+char **go_lxc_get_cgroup_items(struct lxc_container *c, char **keys)
+{
+	char **values;
+	size_t i, n = 0;
+
+	while (keys[n])
+		n++;
+
+	values = calloc(n + 1, sizeof(char *));
+	if (values == NULL)
+		return NULL;
+
+	for (i = 0; i < n; i++)
+		values[i] = go_lxc_get_cgroup_item(c, keys[i]);
+
+	return values;
+}
+
 bool go_lxc_set_cgroup_item(struct lxc_container *c, const char *key, const char *value) {
 	return c->set_cgroup_item(c, key, value);
 }
@@ -253,6 +285,10 @@ int go_lxc_console_getfd(struct lxc_container *c, int ttynum) {
 	return mainfd;
 }
 
+int go_lxc_console_getfds(struct lxc_container *c, int *ttynum, int *mainfd) {
+	return c->console_getfd(c, ttynum, mainfd);
+}
+
 bool go_lxc_console(struct lxc_container *c, int ttynum, int stdinfd, int stdoutfd, int stderrfd, int escape) {
 
 	if (c->console(c, ttynum, stdinfd, stdoutfd, stderrfd, escape) == 0) {
@@ -285,6 +321,118 @@ again:
         return status;
 }
 
+/* The payload of the exec functions below, the command to run along with
+ * the extra options to apply first. The attached process waits on sync_fds
+ * until the calling process applied the resource limits.
+ */
+struct go_lxc_attach_payload {
+	lxc_attach_command_t command;
+	struct extra_attach_opts *extras;
+	int sync_fds[2];
+};
+
+/* Whether the command has to be run through the exec functions below. */
+static bool go_lxc_needs_extra_attach_opts(struct extra_attach_opts *extras) {
+	if (!extras)
+		return false;
+
+	return extras->nr_rlimits > 0 || extras->umask >= 0 || extras->no_new_privs;
+}
+
+static int go_lxc_attach_prepare(struct go_lxc_attach_payload *p) {
+	p->sync_fds[0] = -1;
+	p->sync_fds[1] = -1;
+
+	if (!p->extras || p->extras->nr_rlimits == 0)
+		return 0;
+
+	return pipe2(p->sync_fds, O_CLOEXEC);
+}
+
+/* Applies the resource limits to the attached process from the calling
+ * process, before the attached process switched to its uid and gid, so hard
+ * limits can be raised. The attached process continues afterwards, or exits if
+ * the limits couldn't be applied.
+ */
+static int go_lxc_attach_release(struct go_lxc_attach_payload *p, pid_t pid, int ret) {
+	char ok = 1;
+	int i;
+
+	if (p->sync_fds[0] < 0)
+		return ret;
+
+	close(p->sync_fds[0]);
+	if (ret < 0) {
+		close(p->sync_fds[1]);
+		return ret;
+	}
+
+	for (i = 0; i < p->extras->nr_rlimits; i++) {
+		struct rlimit limit = {
+			.rlim_cur = p->extras->rlimit_soft[i],
+			.rlim_max = p->extras->rlimit_hard[i],
+		};
+
+		if (prlimit(pid, p->extras->rlimit_resources[i], &limit, NULL) < 0) {
+			ok = 0;
+			break;
+		}
+	}
+
+	if (write(p->sync_fds[1], &ok, 1) != 1)
+		ok = 0;
+	close(p->sync_fds[1]);
+
+	if (!ok) {
+		kill(pid, SIGKILL);
+		wait_for_pid_status(pid);
+		return GO_LXC_RLIMITS_FAILED;
+	}
+
+	return ret;
+}
+
+static int go_lxc_apply_extra_attach_opts(struct go_lxc_attach_payload *p) {
+	struct extra_attach_opts *extras = p->extras;
+	char ok = 0;
+
+	if (!extras)
+		return -1;
+
+	if (p->sync_fds[0] >= 0) {
+		close(p->sync_fds[1]);
+		if (read(p->sync_fds[0], &ok, 1) != 1 || !ok)
+			return -1;
+		close(p->sync_fds[0]);
+	}
+
+	if (extras->umask >= 0)
+		umask(extras->umask);
+
+	if (extras->no_new_privs && prctl(PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0) < 0)
+		return -1;
+
+	return 0;
+}
+
+static int go_lxc_attach_run_command(void *payload) {
+	struct go_lxc_attach_payload *p = payload;
+
+	if (go_lxc_apply_extra_attach_opts(p) < 0)
+		return -1;
+
+	return lxc_attach_run_command(&p->command);
+}
+
+static int go_lxc_attach_run_shell(void *payload) {
+	struct go_lxc_attach_payload *p = payload;
+
+	if (go_lxc_apply_extra_attach_opts(p) < 0)
+		return -1;
+
+	return lxc_attach_run_shell(NULL);
+}
+
 int go_lxc_attach_no_wait(struct lxc_container *c,
 		bool clear_env,
 		int namespaces,
@@ -296,13 +444,17 @@ int go_lxc_attach_no_wait(struct lxc_container *c,
 		char **extra_keep_env,
 		const char * const argv[],
 		pid_t *attached_pid,
-		int attach_flags) {
+		int attach_flags,
+		struct extra_attach_opts *extras) {
 	int ret;
 
 	lxc_attach_options_t attach_options = LXC_ATTACH_OPTIONS_DEFAULT;
 	attach_options.attach_flags = attach_flags;
 
-	lxc_attach_command_t command = (lxc_attach_command_t){.program = NULL};
+	struct go_lxc_attach_payload payload = {
+		.command = (lxc_attach_command_t){.program = NULL},
+		.extras = extras,
+	};
 
 	attach_options.env_policy = LXC_ATTACH_KEEP_ENV;
 	if (clear_env) {
@@ -329,10 +481,21 @@ int go_lxc_attach_no_wait(struct lxc_container *c,
 	attach_options.extra_env_vars = extra_env_vars;
 	attach_options.extra_keep_env = extra_keep_env;
 
-	command.program = (char *)argv[0];
-	command.argv = (char **)argv;
+#if VERSION_AT_LEAST(4, 0, 0)
+	if (extras && extras->lsm_label) {
+		attach_options.lsm_label = extras->lsm_label;
+		attach_options.attach_flags |= LXC_ATTACH_LSM_LABEL;
+	}
+#endif
+
+	payload.command.program = (char *)argv[0];
+	payload.command.argv = (char **)argv;
 
-	ret = c->attach(c, lxc_attach_run_command, &command, &attach_options, attached_pid);
+	if (go_lxc_attach_prepare(&payload) < 0)
+		return -1;
+
+	ret = c->attach(c, go_lxc_attach_run_command, &payload, &attach_options, attached_pid);
+	ret = go_lxc_attach_release(&payload, *attached_pid, ret);
 	if (ret < 0)
 		return ret;
 
@@ -348,9 +511,14 @@ int go_lxc_attach(struct lxc_container *c,
 		char *initial_cwd,
 		char **extra_env_vars,
 		char **extra_keep_env,
-		int attach_flags) {
+		int attach_flags,
+		struct extra_attach_opts *extras) {
 	int ret;
 	pid_t pid;
+	struct go_lxc_attach_payload payload = {
+		.command = (lxc_attach_command_t){.program = NULL},
+		.extras = extras,
+	};
 
 	lxc_attach_options_t attach_options = LXC_ATTACH_OPTIONS_DEFAULT;
 	attach_options.attach_flags = attach_flags;
@@ -380,7 +548,18 @@ int go_lxc_attach(struct lxc_container *c,
 	attach_options.extra_env_vars = extra_env_vars;
 	attach_options.extra_keep_env = extra_keep_env;
 
-	ret = c->attach(c, lxc_attach_run_shell, NULL, &attach_options, &pid);
+#if VERSION_AT_LEAST(4, 0, 0)
+	if (extras && extras->lsm_label) {
+		attach_options.lsm_label = extras->lsm_label;
+		attach_options.attach_flags |= LXC_ATTACH_LSM_LABEL;
+	}
+#endif
+
+	if (go_lxc_attach_prepare(&payload) < 0)
+		return -1;
+
+	ret = c->attach(c, go_lxc_attach_run_shell, &payload, &attach_options, &pid);
+	ret = go_lxc_attach_release(&payload, pid, ret);
 	if (ret < 0)
 		return ret;
 
@@ -404,8 +583,14 @@ int go_lxc_attach_run_wait(struct lxc_container *c,
 		char **extra_env_vars,
 		char **extra_keep_env,
 		const char * const argv[],
-		int attach_flags) {
+		int attach_flags,
+		struct extra_attach_opts *extras) {
 	int ret;
+	pid_t pid;
+	struct go_lxc_attach_payload payload = {
+		.command = (lxc_attach_command_t){.program = (char *)argv[0], .argv = (char **)argv},
+		.extras = extras,
+	};
 
 	lxc_attach_options_t attach_options = LXC_ATTACH_OPTIONS_DEFAULT;
 	attach_options.attach_flags = attach_flags;
@@ -435,7 +620,32 @@ int go_lxc_attach_run_wait(struct lxc_container *c,
 	attach_options.extra_env_vars = extra_env_vars;
 	attach_options.extra_keep_env = extra_keep_env;
 
-	ret = c->attach_run_wait(c, &attach_options, argv[0], argv);
+#if VERSION_AT_LEAST(4, 0, 0)
+	if (extras && extras->lsm_label) {
+		attach_options.lsm_label = extras->lsm_label;
+		attach_options.attach_flags |= LXC_ATTACH_LSM_LABEL;
+	}
+#endif
+
+	if (!go_lxc_needs_extra_attach_opts(extras)) {
+		ret = c->attach_run_wait(c, &attach_options, argv[0], argv);
+		if (WIFEXITED(ret) && WEXITSTATUS(ret) == 255)
+			return -1;
+		return ret;
+	}
+
+	if (go_lxc_attach_prepare(&payload) < 0)
+		return -1;
+
+	ret = c->attach(c, go_lxc_attach_run_command, &payload, &attach_options, &pid);
+	ret = go_lxc_attach_release(&payload, pid, ret);
+	if (ret < 0)
+		return ret;
+
+	ret = wait_for_pid_status(pid);
+	if (ret < 0)
+		return ret;
+
 	if (WIFEXITED(ret) && WEXITSTATUS(ret) == 255)
 		return -1;
 	return ret;
@@ -16,6 +16,8 @@ import "C"
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -81,38 +83,114 @@ func GlobalConfigItem(name string) string {
 
 // DefaultConfigPath returns default config path.
 func DefaultConfigPath() string {
-	return GlobalConfigItem("lxc.lxcpath")
+	return RuntimeInfo().LXCPath
 }
 
 // DefaultLvmVg returns the name of the default LVM volume group.
 func DefaultLvmVg() string {
-	return GlobalConfigItem("lxc.bdev.lvm.vg")
+	return RuntimeInfo().LVMVolumeGroup
 }
 
 // DefaultZfsRoot returns the name of the default ZFS root.
 func DefaultZfsRoot() string {
-	return GlobalConfigItem("lxc.bdev.zfs.root")
+	return RuntimeInfo().ZFSRoot
 }
 
-// ContainerNames returns the names of defined and active containers on the system.
-func ContainerNames(lxcpath ...string) []string {
-	var size int
-	var cnames **C.char
+const (
+	listAll = iota
+	listDefined
+	listActive
+)
 
+// listContainerNames lists the containers of the given kind. A missing lxcpath
+// isn't an error, it simply holds no containers.
+func listContainerNames(kind int, lxcpath ...string) ([]string, error) {
+	var clxcpath *C.char
+
+	path := DefaultConfigPath()
 	if lxcpath != nil && len(lxcpath) == 1 {
-		clxcpath := C.CString(lxcpath[0])
+		path = lxcpath[0]
+
+		clxcpath = C.CString(lxcpath[0])
 		defer C.free(unsafe.Pointer(clxcpath))
+	}
 
-		size = int(C.list_all_containers(clxcpath, &cnames, nil))
-	} else {
+	// liblxc skips over directories it can't read, so check for
+	// ourselves to tell "no containers" from "no access".
+	if kind != listActive {
+		dir, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%s: %v", ErrListFailed, err)
+		}
+
+		_, err = dir.Readdirnames(1)
+		dir.Close()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%s: %v", ErrListFailed, err)
+		}
+	}
 
-		size = int(C.list_all_containers(nil, &cnames, nil))
+	var size int
+	var cnames **C.char
+	var errno error
+
+	switch kind {
+	case listDefined:
+		ret, err := C.list_defined_containers(clxcpath, &cnames, nil)
+		size, errno = int(ret), err
+	case listActive:
+		ret, err := C.list_active_containers(clxcpath, &cnames, nil)
+		size, errno = int(ret), err
+	default:
+		ret, err := C.list_all_containers(clxcpath, &cnames, nil)
+		size, errno = int(ret), err
+	}
+
+	if size < 0 {
+		if errno != nil {
+			return nil, fmt.Errorf("%s: %v", ErrListFailed, errno)
+		}
+		return nil, ErrListFailed
 	}
 
 	if size < 1 {
-		return nil
+		return nil, nil
 	}
-	return convertNArgs(cnames, size)
+	return convertNArgs(cnames, size), nil
+}
+
+// listContainers returns the containers for the given names. On error, the
+// containers allocated so far are released.
+func listContainers(names []string, lxcpath ...string) ([]*Container, error) {
+	var containers []*Container
+
+	for _, v := range names {
+		container, err := NewContainer(v, lxcpath...)
+		if err != nil {
+			for _, c := range containers {
+				c.Release()
+			}
+			return nil, fmt.Errorf("%s: %q", err, v)
+		}
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// ContainerNames returns the names of defined and active containers on the system.
+func ContainerNames(lxcpath ...string) []string {
+	names, _ := listContainerNames(listAll, lxcpath...)
+	return names
+}
+
+// ContainerNamesE returns the names of defined and active containers on the
+// system. Unlike ContainerNames it reports errors, e.g. when lxcpath can't be read.
+func ContainerNamesE(lxcpath ...string) ([]string, error) {
+	return listContainerNames(listAll, lxcpath...)
 }
 
 // Containers returns the defined and active containers on the system. Only
@@ -130,25 +208,28 @@ func Containers(lxcpath ...string) []*Container {
 	return containers
 }
 
-// DefinedContainerNames returns the names of the defined containers on the system.
-func DefinedContainerNames(lxcpath ...string) []string {
-	var size int
-	var cnames **C.char
-
-	if lxcpath != nil && len(lxcpath) == 1 {
-		clxcpath := C.CString(lxcpath[0])
-		defer C.free(unsafe.Pointer(clxcpath))
+// ContainersE returns the defined and active containers on the system. Unlike
+// Containers it reports errors, including containers that couldn't be retrieved.
+// Caller needs to call Release() on the returned containers to release resources.
+func ContainersE(lxcpath ...string) ([]*Container, error) {
+	names, err := ContainerNamesE(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
 
-		size = int(C.list_defined_containers(clxcpath, &cnames, nil))
-	} else {
+	return listContainers(names, lxcpath...)
+}
 
-		size = int(C.list_defined_containers(nil, &cnames, nil))
-	}
+// DefinedContainerNames returns the names of the defined containers on the system.
+func DefinedContainerNames(lxcpath ...string) []string {
+	names, _ := listContainerNames(listDefined, lxcpath...)
+	return names
+}
 
-	if size < 1 {
-		return nil
-	}
-	return convertNArgs(cnames, size)
+// DefinedContainerNamesE returns the names of the defined containers on the
+// system. Unlike DefinedContainerNames it reports errors.
+func DefinedContainerNamesE(lxcpath ...string) ([]string, error) {
+	return listContainerNames(listDefined, lxcpath...)
 }
 
 // DefinedContainers returns the defined containers on the system.  Only
@@ -166,25 +247,28 @@ func DefinedContainers(lxcpath ...string) []*Container {
 	return containers
 }
 
-// ActiveContainerNames returns the names of the active containers on the system.
-func ActiveContainerNames(lxcpath ...string) []string {
-	var size int
-	var cnames **C.char
-
-	if lxcpath != nil && len(lxcpath) == 1 {
-		clxcpath := C.CString(lxcpath[0])
-		defer C.free(unsafe.Pointer(clxcpath))
+// DefinedContainersE returns the defined containers on the system. Unlike
+// DefinedContainers it reports errors.
+// Caller needs to call Release() on the returned containers to release resources.
+func DefinedContainersE(lxcpath ...string) ([]*Container, error) {
+	names, err := DefinedContainerNamesE(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
 
-		size = int(C.list_active_containers(clxcpath, &cnames, nil))
-	} else {
+	return listContainers(names, lxcpath...)
+}
 
-		size = int(C.list_active_containers(nil, &cnames, nil))
-	}
+// ActiveContainerNames returns the names of the active containers on the system.
+func ActiveContainerNames(lxcpath ...string) []string {
+	names, _ := listContainerNames(listActive, lxcpath...)
+	return names
+}
 
-	if size < 1 {
-		return nil
-	}
-	return convertNArgs(cnames, size)
+// ActiveContainerNamesE returns the names of the active containers on the
+// system. Unlike ActiveContainerNames it reports errors.
+func ActiveContainerNamesE(lxcpath ...string) ([]string, error) {
+	return listContainerNames(listActive, lxcpath...)
 }
 
 // ActiveContainers returns the active containers on the system. Only
@@ -202,6 +286,18 @@ func ActiveContainers(lxcpath ...string) []*Container {
 	return containers
 }
 
+// ActiveContainersE returns the active containers on the system. Unlike
+// ActiveContainers it reports errors.
+// Caller needs to call Release() on the returned containers to release resources.
+func ActiveContainersE(lxcpath ...string) ([]*Container, error) {
+	names, err := ActiveContainerNamesE(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
+
+	return listContainers(names, lxcpath...)
+}
+
 // VersionNumber returns the LXC version.
 func VersionNumber() (major int, minor int) {
 	major = C.LXC_VERSION_MAJOR
@@ -326,10 +422,12 @@ func HasApiExtension(extension string) bool {
 
 // HasAPIExtension returns true if the extension is supported.
 func HasAPIExtension(extension string) bool {
-	if RuntimeLiblxcVersionAtLeast(Version(), 3, 1, 0) {
-		apiExtension := C.CString(extension)
-		defer C.free(unsafe.Pointer(apiExtension))
-		return bool(C.go_lxc_has_api_extension(apiExtension))
-	}
-	return false
+	return RuntimeInfo().HasAPIExtension(extension)
+}
+
+// hasAPIExtension asks liblxc whether the extension is supported.
+func hasAPIExtension(extension string) bool {
+	apiExtension := C.CString(extension)
+	defer C.free(unsafe.Pointer(apiExtension))
+	return bool(C.go_lxc_has_api_extension(apiExtension))
 }
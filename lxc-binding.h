@@ -41,7 +41,9 @@ extern bool go_lxc_want_close_all_fds(struct lxc_container *c, bool state);
 extern bool go_lxc_want_daemonize(struct lxc_container *c, bool state);
 extern char* go_lxc_config_file_name(struct lxc_container *c);
 extern char* go_lxc_get_cgroup_item(struct lxc_container *c, const char *key);
+extern char** go_lxc_get_cgroup_items(struct lxc_container *c, char **keys);
 extern char* go_lxc_get_config_item(struct lxc_container *c, const char *key);
+extern int go_lxc_get_config_item_len(struct lxc_container *c, const char *key);
 extern char** go_lxc_get_interfaces(struct lxc_container *c);
 extern char** go_lxc_get_ips(struct lxc_container *c, const char *interface, const char *family, int scope);
 extern char* go_lxc_get_keys(struct lxc_container *c, const char *key);
@@ -56,6 +58,26 @@ typedef struct lxc_groups_t {
 } lxc_groups_t;
 # endif
 
+/* Returned by the attach functions if the resource limits of the command
+ * couldn't be applied.
+ */
+#define GO_LXC_RLIMITS_FAILED (-2)
+
+/* This is a struct that we can add "extra" attach options to which liblxc
+ * has no notion of. They are applied by the attached process right before it
+ * executes the command, except for the resource limits which the calling
+ * process applies before the attached process switches its credentials.
+ */
+struct extra_attach_opts {
+	int nr_rlimits;
+	int *rlimit_resources;
+	uint64_t *rlimit_soft;
+	uint64_t *rlimit_hard;
+	int umask; /* -1 keeps the umask */
+	bool no_new_privs;
+	char *lsm_label;
+};
+
 extern int go_lxc_attach_run_wait(struct lxc_container *c,
 		bool clear_env,
 		int namespaces,
@@ -66,7 +88,8 @@ extern int go_lxc_attach_run_wait(struct lxc_container *c,
 		char **extra_env_vars,
 		char **extra_keep_env,
 		const char * const argv[],
-		int attach_flags);
+		int attach_flags,
+		struct extra_attach_opts *extras);
 extern int go_lxc_attach(struct lxc_container *c,
 		bool clear_env,
 		int namespaces,
@@ -76,7 +99,8 @@ extern int go_lxc_attach(struct lxc_container *c,
 		char *initial_cwd,
 		char **extra_env_vars,
 		char **extra_keep_env,
-		int attach_flags);
+		int attach_flags,
+		struct extra_attach_opts *extras);
 extern int go_lxc_attach_no_wait(struct lxc_container *c,
 		bool clear_env,
 		int namespaces,
@@ -88,8 +112,10 @@ extern int go_lxc_attach_no_wait(struct lxc_container *c,
 		char **extra_keep_env,
 		const char * const argv[],
 		pid_t *attached_pid,
-		int attach_flags);
+		int attach_flags,
+		struct extra_attach_opts *extras);
 extern int go_lxc_console_getfd(struct lxc_container *c, int ttynum);
+extern int go_lxc_console_getfds(struct lxc_container *c, int *ttynum, int *mainfd);
 extern int go_lxc_snapshot_list(struct lxc_container *c, struct lxc_snapshot **ret);
 extern int go_lxc_snapshot(struct lxc_container *c);
 extern pid_t go_lxc_init_pid(struct lxc_container *c);
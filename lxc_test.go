@@ -2,16 +2,40 @@
 // Use of this source code is governed by a LGPLv2.1
 // license that can be found in the LICENSE file.
 
+//go:build linux && cgo
 // +build linux,cgo
 
 package lxc
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -19,6 +43,8 @@ import (
 	"syscall"
 	"testing"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -265,6 +291,164 @@ func TestCloneUsingOverlayfs(t *testing.T) {
 	}
 }
 
+func TestClonePostClone(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	name := ContainerCloneName() + "-postclone"
+	var rootfs string
+	err = c.Clone(name, CloneOptions{
+		PostClone: func(n *Container) error {
+			rootfs = n.MountedRootfs()
+			if rootfs == "" {
+				return fmt.Errorf("rootfs isn't mounted")
+			}
+			return ioutil.WriteFile(filepath.Join(rootfs, "postclone"), []byte(name), 0644)
+		},
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	n, err := NewContainer(name)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer n.Release()
+	defer n.Destroy()
+
+	if n.MountedRootfs() != "" {
+		t.Errorf("MountedRootfs returned %q outside of PostClone", n.MountedRootfs())
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(n.rootfsPath(), "postclone"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(content) != name {
+		t.Errorf("PostClone changes weren't kept")
+	}
+}
+
+func TestClonePostCloneFailure(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	name := ContainerCloneName() + "-postclone-failure"
+	err = c.Clone(name, CloneOptions{
+		PostClone: func(n *Container) error {
+			return fmt.Errorf("refusing the clone")
+		},
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrCloneFailed.Error()) {
+		t.Errorf("expected %q, got %v", ErrCloneFailed, err)
+	}
+
+	n, err := NewContainer(name)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer n.Release()
+
+	if n.Defined() {
+		n.Destroy()
+		t.Errorf("a failed clone wasn't destroyed")
+	}
+}
+
+func TestConvertStorage(t *testing.T) {
+	if !(supported("overlayfs") || supported("overlay")) {
+		t.Skip("skipping test as overlayfs support is missing.")
+	}
+
+	c, err := NewContainer(ContainerCloneOverlayName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	uuid, err := c.UUID()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := c.ConvertStorage(Directory, ConvertStorageOptions{}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if c.Name() != ContainerCloneOverlayName() || c.BackendStore() != Directory {
+		t.Errorf("expected %s on %s, got %s on %s", ContainerCloneOverlayName(), Directory, c.Name(), c.BackendStore())
+	}
+
+	if id, err := c.UUID(); err != nil || id != uuid {
+		t.Errorf("expected the UUID %q to be kept, got %q, %v", uuid, id, err)
+	}
+
+	for _, name := range []string{ContainerCloneOverlayName() + "-convert", ContainerCloneOverlayName() + "-orig"} {
+		for _, defined := range DefinedContainerNames() {
+			if defined == name {
+				t.Errorf("expected %s to be removed", name)
+			}
+		}
+	}
+
+	// the name is taken, the original is kept
+	if err := c.ConvertStorage(Overlayfs, ConvertStorageOptions{BackupName: ContainerName()}); err == nil {
+		t.Errorf("expected the conversion to fail")
+	}
+
+	if !c.Defined() || c.BackendStore() != Directory {
+		t.Errorf("expected the original to be kept")
+	}
+}
+
+func TestPrepareRootfsIdmappedMounts(t *testing.T) {
+	if !IdmappedMountsSupported() {
+		t.Skip("skipping test as idmapped mounts are not supported.")
+	}
+
+	c, err := NewContainer(ContainerCloneName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	options := c.ConfigItem("lxc.rootfs.options")[0]
+	defer func() {
+		c.ClearConfigItem("lxc.idmap")
+		c.SetConfigItem("lxc.rootfs.options", options)
+		c.SaveConfigFile(c.ConfigFileName())
+	}()
+
+	if err := c.SetConfigItem("lxc.idmap", "u 0 100000 65536"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := c.SetConfigItem("lxc.idmap", "g 0 100000 65536"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := c.PrepareRootfs(RootfsOptions{UseIdmappedMounts: true}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// the option has to survive loading the container again
+	n, err := NewContainer(ContainerCloneName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer n.Release()
+
+	if !strings.Contains(n.ConfigItem("lxc.rootfs.options")[0], "idmap=container") {
+		t.Errorf("PrepareRootfs didn't save the rootfs options")
+	}
+}
+
 func TestCreateSnapshot(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -308,6 +492,33 @@ func TestRestoreSnapshot(t *testing.T) {
 	}
 }
 
+func TestRollbackToSnapshot(t *testing.T) {
+	if os.Getenv("GITHUB_ACTION") != "" {
+		t.Skip("Test broken on Github")
+	}
+
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	mac := c.ConfigItem(networkKey(0, "hwaddr"))[0]
+
+	snapshot := Snapshot{Name: DefaultSnapshotName}
+	if err := c.RollbackToSnapshot(snapshot); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if c.Name() != ContainerName() {
+		t.Errorf("unexpected name: %s", c.Name())
+	}
+
+	if got := c.ConfigItem(networkKey(0, "hwaddr"))[0]; got != mac {
+		t.Errorf("expected MAC %s, got %s", mac, got)
+	}
+}
+
 func TestConcurrentCreate(t *testing.T) {
 	t.Skip("Skipping concurrent tests for now")
 
@@ -349,6 +560,24 @@ func TestSnapshots(t *testing.T) {
 	}
 }
 
+func TestAllSnapshots(t *testing.T) {
+	snapshots, err := AllSnapshots()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	found := false
+	for _, s := range snapshots {
+		if s.Container == ContainerName() {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("AllSnapshots is missing the snapshots of %s", ContainerName())
+	}
+}
+
 func TestConcurrentStart(t *testing.T) {
 	t.Skip("Skipping concurrent tests for now")
 
@@ -502,6 +731,30 @@ func TestContainerNames(t *testing.T) {
 	}
 }
 
+func TestContainerNamesE(t *testing.T) {
+	if _, err := ContainerNamesE(); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	names, err := ContainerNamesE("/nonexistent/lxcpath")
+	if err != nil || names != nil {
+		t.Errorf("ContainerNamesE failed for a missing lxcpath: %v %v", names, err)
+	}
+
+	if unprivileged() {
+		dir, err := ioutil.TempDir("", "lxcpath")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		defer os.RemoveAll(dir)
+
+		os.Chmod(dir, 0)
+		if _, err := ContainerNamesE(dir); err == nil {
+			t.Errorf("ContainerNamesE didn't report an unreadable lxcpath")
+		}
+	}
+}
+
 func TestDefinedContainerNames(t *testing.T) {
 	if DefinedContainerNames() == nil {
 		t.Errorf("DefinedContainerNames failed...")
@@ -686,6 +939,30 @@ func TestConfigItem(t *testing.T) {
 	}
 }
 
+func TestConfigItemE(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	if values, err := c.ConfigItemE("lxc.uts.name"); err != nil || len(values) != 1 || values[0] != ContainerName() {
+		t.Errorf("ConfigItemE failed: %v %v", values, err)
+	}
+
+	if _, err := c.ConfigItemE("lxc.nonexistent"); err == nil {
+		t.Errorf("expected an error for an unknown key")
+	}
+
+	if value, ok := c.LookupConfigItem("lxc.uts.name"); !ok || value != ContainerName() {
+		t.Errorf("LookupConfigItem failed: %q %v", value, ok)
+	}
+
+	if _, ok := c.LookupConfigItem("lxc.nonexistent"); ok {
+		t.Errorf("expected an unknown key to be unset")
+	}
+}
+
 func TestSetConfigItem(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -733,6 +1010,30 @@ func TestSetCgroupItem(t *testing.T) {
 	}
 }
 
+func TestReadCgroupItems(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	keys := []string{"memory.limit_in_bytes", "cpuacct.usage", "nonexistent.item"}
+	items, err := c.ReadCgroupItems(keys)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	for _, key := range keys[:2] {
+		if !reflect.DeepEqual(items[key], c.CgroupItem(key)) {
+			t.Errorf("unexpected %s: %q", key, items[key])
+		}
+	}
+
+	if _, ok := items["nonexistent.item"]; ok {
+		t.Errorf("expected the missing item to be left out")
+	}
+}
+
 func TestClearConfigItem(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -748,6 +1049,32 @@ func TestClearConfigItem(t *testing.T) {
 	}
 }
 
+func TestDevicePolicy(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	policy, err := c.DevicePolicy()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := c.SetDevicePolicy(policy); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	roundtrip, err := c.DevicePolicy()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(policy, roundtrip) {
+		t.Errorf("DevicePolicy failed to round trip: %v != %v", policy, roundtrip)
+	}
+}
+
 func TestConfigKeys(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -767,6 +1094,35 @@ func TestConfigKeys(t *testing.T) {
 	}
 }
 
+func TestDumpConfig(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	config, err := c.DumpConfig()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	key := "lxc.net.0.type"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.network.0.type"
+	}
+
+	found := false
+	for _, kv := range config {
+		if kv.Key == key {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("DumpConfig is missing %s", key)
+	}
+}
+
 func TestInterfaces(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -810,11 +1166,38 @@ func TestKernelMemoryUsage(t *testing.T) {
 	}
 	defer c.Release()
 
+	if CgroupUnified() {
+		if _, err := c.KernelMemoryUsage(); err != ErrNotSupported {
+			t.Errorf("KernelMemoryUsage should not be supported on cgroup v2")
+		}
+		return
+	}
+
 	if _, err := c.KernelMemoryUsage(); err != nil {
 		t.Errorf(err.Error())
 	}
 }
 
+func TestSwapUsage(t *testing.T) {
+	if !CgroupUnified() && !exists("/sys/fs/cgroup/memory/memory.memsw.usage_in_bytes") {
+		t.Skip("skipping the test as it requires memory.memsw.usage_in_bytes to be set")
+	}
+
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	if _, err := c.SwapUsage(); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if _, err := c.SwapLimit(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
 func TestMemorySwapUsage(t *testing.T) {
 	if !exists("/sys/fs/cgroup/memory/memory.memsw.limit_in_bytes") {
 		t.Skip("skipping the test as it requires memory.memsw.limit_in_bytes to be set")
@@ -1111,7 +1494,7 @@ func TestRunCommandNoWait(t *testing.T) {
 	}
 }
 
-func TestRunCommand(t *testing.T) {
+func TestRunCommandAsync(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
 		t.Errorf(err.Error())
@@ -1119,76 +1502,195 @@ func TestRunCommand(t *testing.T) {
 	defer c.Release()
 
 	argsThree := []string{"/bin/sh", "-c", "exit 0"}
-	ok, err := c.RunCommand(argsThree, DefaultAttachOptions)
+	proc, err := c.RunCommandAsync(argsThree, DefaultAttachOptions)
 	if err != nil {
 		t.Errorf(err.Error())
-	}
-	if !ok {
-		t.Errorf("Expected success")
+		t.FailNow()
 	}
 
-	argsThree = []string{"/bin/sh", "-c", "exit 1"}
-	ok, err = c.RunCommand(argsThree, DefaultAttachOptions)
+	status, err := proc.Wait()
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	if ok {
-		t.Errorf("Expected failure")
+	if status != 0 {
+		t.Errorf("Expected success")
 	}
-}
 
-func TestCommandWithEnv(t *testing.T) {
-	c, err := NewContainer(ContainerName())
+	argsThree = []string{"/bin/sh", "-c", "exit 3"}
+	proc, err = c.RunCommandAsync(argsThree, DefaultAttachOptions)
 	if err != nil {
 		t.Errorf(err.Error())
+		t.FailNow()
 	}
-	defer c.Release()
-
-	options := DefaultAttachOptions
-	options.Env = []string{"FOO=BAR"}
-	options.ClearEnv = true
 
-	args := []string{"/bin/sh", "-c", "test $FOO = 'BAR'"}
-	ok, err := c.RunCommand(args, options)
+	status, err = proc.Wait()
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	if !ok {
-		t.Errorf("Expected success")
+	if status != 3 {
+		t.Errorf("Expected exit status 3, got %d", status)
 	}
 }
 
-func TestCommandWithEnvToKeep(t *testing.T) {
+func TestRunCommand(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
 		t.Errorf(err.Error())
 	}
 	defer c.Release()
 
-	options := DefaultAttachOptions
-	options.ClearEnv = true
-	options.EnvToKeep = []string{"USER"}
-
-	args := []string{"/bin/sh", "-c", fmt.Sprintf("test $USER = '%s'", os.Getenv("USER"))}
-	ok, err := c.RunCommand(args, DefaultAttachOptions)
+	argsThree := []string{"/bin/sh", "-c", "exit 0"}
+	ok, err := c.RunCommand(argsThree, DefaultAttachOptions)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
 	if !ok {
 		t.Errorf("Expected success")
 	}
+
+	argsThree = []string{"/bin/sh", "-c", "exit 1"}
+	ok, err = c.RunCommand(argsThree, DefaultAttachOptions)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if ok {
+		t.Errorf("Expected failure")
+	}
 }
 
-func TestCommandWithCwd(t *testing.T) {
+func TestRunCommandExtras(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
 		t.Errorf(err.Error())
 	}
 	defer c.Release()
 
-	options := DefaultAttachOptions
-	options.Cwd = "/tmp"
-
+	for _, tc := range []struct {
+		script string
+		modify func(*AttachOptions)
+	}{
+		{`test "$(umask)" = 0000`, func(o *AttachOptions) { umask := 0; o.Umask = &umask }},
+		{`test "$(umask)" = 0027`, func(o *AttachOptions) { umask := 0027; o.Umask = &umask }},
+		{`grep -q "^NoNewPrivs:.*1$" /proc/self/status`, func(o *AttachOptions) { o.NoNewPrivs = true }},
+		{`grep -q "^NoNewPrivs:.*0$" /proc/self/status`, func(o *AttachOptions) {}},
+	} {
+		options := DefaultAttachOptions
+		tc.modify(&options)
+
+		ok, err := c.RunCommand([]string{"/bin/sh", "-c", tc.script}, options)
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+		if !ok {
+			t.Errorf("Expected %q to succeed", tc.script)
+		}
+	}
+}
+
+func TestRunCommandRlimits(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	// the limits are applied before switching to the uid of the command
+	options := DefaultAttachOptions
+	options.UID = 1000
+	options.GID = 1000
+	options.Rlimits = []Rlimit{{"nofile", 1024, 4096}}
+
+	ok, err := c.RunCommand([]string{"/bin/sh", "-c", `test "$(ulimit -Hn)" = 4096`}, options)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !ok {
+		t.Errorf("Expected the resource limits to be applied")
+	}
+
+	// more open files than fs.nr_open allows
+	options.Rlimits = []Rlimit{{"nofile", 1 << 40, 1 << 40}}
+	if _, err := c.RunCommandStatus([]string{"/bin/true"}, options); err != ErrSettingRlimitsFailed {
+		t.Errorf("expected %q, got %v", ErrSettingRlimitsFailed, err)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	status, err := c.RunScript("test -d /\nexit 4\n", DefaultAttachOptions)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if status != 4 {
+		t.Errorf("Expected exit status 4, got %d", status)
+	}
+
+	status, err = c.RunCommands([][]string{{"/bin/true"}, {"/bin/sh", "-c", "exit 2"}, {"/bin/true"}}, DefaultAttachOptions)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if status != 2 {
+		t.Errorf("Expected exit status 2, got %d", status)
+	}
+}
+
+func TestCommandWithEnv(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	options := DefaultAttachOptions
+	options.Env = []string{"FOO=BAR"}
+	options.ClearEnv = true
+
+	args := []string{"/bin/sh", "-c", "test $FOO = 'BAR'"}
+	ok, err := c.RunCommand(args, options)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !ok {
+		t.Errorf("Expected success")
+	}
+}
+
+func TestCommandWithEnvToKeep(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	options := DefaultAttachOptions
+	options.ClearEnv = true
+	options.EnvToKeep = []string{"USER"}
+
+	args := []string{"/bin/sh", "-c", fmt.Sprintf("test $USER = '%s'", os.Getenv("USER"))}
+	ok, err := c.RunCommand(args, DefaultAttachOptions)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !ok {
+		t.Errorf("Expected success")
+	}
+}
+
+func TestCommandWithCwd(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	options := DefaultAttachOptions
+	options.Cwd = "/tmp"
+
 	args := []string{"/bin/sh", "-c", "test `pwd` = /tmp"}
 	ok, err := c.RunCommand(args, options)
 	if err != nil {
@@ -1377,6 +1879,50 @@ func TestIPv6Addresses(t *testing.T) {
 	}
 }
 
+func TestIPv4Addrs(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	addrs, err := c.IPv4Addrs()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	for _, addr := range addrs {
+		if !addr.Is4() {
+			t.Errorf("IPv4Addrs returned %s", addr)
+		}
+	}
+}
+
+func TestIPAddressesWithOptions(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	defer c.Release()
+
+	if _, err := c.IPAddressesWithOptions(IPAddressOptions{Family: "ipx"}); err == nil {
+		t.Errorf("IPAddressesWithOptions accepted an unknown family")
+	}
+
+	addresses, err := c.WaitIPAddressesWithOptions(IPAddressOptions{Family: "inet", IncludeLinkLocal: true}, 30*time.Second)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	for iface, ips := range addresses {
+		for _, ip := range ips {
+			if ip.To4() == nil {
+				t.Errorf("IPAddressesWithOptions returned %s on %s for inet", ip, iface)
+			}
+		}
+	}
+}
+
 func TestReboot(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -1459,6 +2005,146 @@ func TestStop(t *testing.T) {
 	}
 }
 
+func TestStartAndFreeze(t *testing.T) {
+	c, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	if err := c.StartAndFreeze(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Stop()
+
+	if c.State() != FROZEN {
+		t.Errorf("expected the container to be frozen, got %s", c.State())
+	}
+
+	if c.FreezeMethod() == FreezeNone {
+		t.Errorf("expected the freeze method to be recorded")
+	}
+
+	if err := c.StartAndFreeze(); err == nil || !strings.HasPrefix(err.Error(), ErrAlreadyRunning.Error()) {
+		t.Errorf("expected ErrAlreadyRunning, got %v", err)
+	}
+
+	if err := c.Unfreeze(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	c.Wait(RUNNING, 30*time.Second)
+	if c.State() != RUNNING || c.FreezeMethod() != FreezeNone {
+		t.Errorf("Unfreezing the container failed...")
+	}
+
+	if err := c.Stop(); err != nil {
+		t.Errorf(err.Error())
+	}
+	c.Wait(STOPPED, 30*time.Second)
+}
+
+func TestWarmPool(t *testing.T) {
+	if !(supported("overlayfs") || supported("overlay")) {
+		t.Skip("skipping test as overlayfs support is missing.")
+	}
+
+	base, err := NewContainer(ContainerName())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer base.Release()
+
+	opts := DefaultWarmPoolOptions
+	opts.Size = 1
+
+	pool, err := NewWarmPool(base, opts)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	custom := WarmCustomization{Hostname: "warm", IPv4: "192.0.2.10/32"}
+	checks := [][]string{
+		{"/bin/sh", "-c", `test "$(hostname)" = warm`},
+		{"/bin/sh", "-c", "ip addr show dev eth0 | grep -q 192.0.2.10/32"},
+	}
+
+	c, err := pool.Get(ctx, custom)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, args := range checks {
+		if status, err := c.RunCommandStatus(args, DefaultAttachOptions); err != nil || status != 0 {
+			t.Errorf("expected %q to succeed, got %d, %v", args, status, err)
+		}
+	}
+
+	// the pool is refilled, without starting more containers than its size
+	for len(pool.ready) != opts.Size {
+		if ctx.Err() != nil {
+			t.Fatalf("the pool wasn't refilled: %v", pool.Err())
+		}
+		time.Sleep(time.Second)
+	}
+	time.Sleep(5 * time.Second)
+
+	var n int
+	for _, name := range DefinedContainerNames(pool.lxcpath) {
+		if strings.HasPrefix(name, pool.opts.Prefix) {
+			n++
+		}
+	}
+	if n != opts.Size+1 {
+		t.Errorf("expected %d clones, got %d", opts.Size+1, n)
+	}
+
+	// a full pool destroys what is given back
+	name := c.Name()
+	if err := pool.Put(c, true); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if gone, err := NewContainer(name, pool.lxcpath); err == nil {
+		if gone.Defined() {
+			t.Errorf("expected %s to be destroyed", name)
+		}
+		gone.Release()
+	}
+
+	// a pool with room undoes the customization and hands it out again
+	c, err = pool.Get(ctx, custom)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	spare := &WarmPool{
+		ready:  make(chan *Container, 1),
+		slots:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		custom: map[*Container]WarmCustomization{c: custom},
+	}
+	defer spare.Close()
+
+	if err := spare.Put(c, true); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(spare.ready) != 1 {
+		t.Fatalf("expected the container to be reused")
+	}
+
+	for _, args := range checks {
+		if status, err := c.RunCommandStatus(args, DefaultAttachOptions); err != nil || status == 0 {
+			t.Errorf("expected %q to fail after reuse, got %d, %v", args, status, err)
+		}
+	}
+}
+
 func TestDestroySnapshot(t *testing.T) {
 	c, err := NewContainer(ContainerName())
 	if err != nil {
@@ -1654,3 +2340,4430 @@ func TestRuntimeLiblxcVersionAtLeast(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIDMap(t *testing.T) {
+	idmap, err := ParseIDMap([]string{"u 0 100000 65536", "g 0 200000 1000"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(idmap) != 2 || idmap[0].String() != "u 0 100000 65536" || idmap[1].String() != "g 0 200000 1000" {
+		t.Errorf("ParseIDMap failed: %v", idmap)
+	}
+
+	if id, ok := idmap.ToHost(IDTypeUID, 1000); !ok || id != 101000 {
+		t.Errorf("ToHost failed: %d", id)
+	}
+
+	if id, ok := idmap.ToContainer(IDTypeGID, 200033); !ok || id != 33 {
+		t.Errorf("ToContainer failed: %d", id)
+	}
+
+	if _, ok := idmap.ToHost(IDTypeGID, 1000); ok {
+		t.Errorf("ToHost mapped an id outside of the range")
+	}
+
+	for _, v := range []string{"x 0 100000 65536", "u 0 100000", "u 0 -1 10"} {
+		if _, err := ParseIDMap([]string{v}); err == nil {
+			t.Errorf("ParseIDMap accepted %q", v)
+		}
+	}
+}
+
+func TestShiftACL(t *testing.T) {
+	idmap := IDMap{{Type: IDTypeUID, Nsid: 0, Hostid: 100000, Range: 65536}, {Type: IDTypeGID, Nsid: 0, Hostid: 100000, Range: 65536}}
+
+	// version 2, ACL_USER_OBJ, ACL_USER 1000, ACL_GROUP 33
+	acl := []byte{
+		2, 0, 0, 0,
+		1, 0, 7, 0, 0xff, 0xff, 0xff, 0xff,
+		2, 0, 7, 0, 0xe8, 0x03, 0, 0,
+		8, 0, 5, 0, 0x21, 0, 0, 0,
+	}
+
+	shifted, err := shiftACL(acl, idmap.ToHost)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	unshifted, err := shiftACL(shifted, idmap.ToContainer)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if string(unshifted) != string(acl) {
+		t.Errorf("shiftACL didn't round trip")
+	}
+}
+
+func TestShiftCapabilities(t *testing.T) {
+	idmap := IDMap{{Type: IDTypeUID, Nsid: 0, Hostid: 100000, Range: 65536}, {Type: IDTypeGID, Nsid: 0, Hostid: 100000, Range: 65536}}
+
+	// revision 2 with VFS_CAP_FLAGS_EFFECTIVE, CAP_NET_RAW permitted
+	caps := []byte{
+		1, 0, 0, 2,
+		0, 0x20, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	shifted, err := shiftCapabilities(caps, idmap.ToHost, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(shifted) != vfsCapV3Size || shifted[3] != 3 || binary.LittleEndian.Uint32(shifted[vfsCapV2Size:]) != 100000 {
+		t.Errorf("shiftCapabilities didn't convert to a namespaced capability: %v", shifted)
+	}
+
+	unshifted, err := shiftCapabilities(shifted, idmap.ToContainer, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if string(unshifted) != string(caps) {
+		t.Errorf("shiftCapabilities didn't round trip")
+	}
+
+	// revision 3 owned by uid 1000 of the container
+	caps = append(append([]byte{}, caps...), 0xe8, 0x03, 0, 0)
+	caps[3] = 3
+
+	shifted, err = shiftCapabilities(caps, idmap.ToHost, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if binary.LittleEndian.Uint32(shifted[vfsCapV2Size:]) != 101000 {
+		t.Errorf("shiftCapabilities didn't shift the root id: %v", shifted)
+	}
+
+	unshifted, err = shiftCapabilities(shifted, idmap.ToContainer, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if string(unshifted) != string(caps) {
+		t.Errorf("shiftCapabilities didn't round trip")
+	}
+
+	if _, err := shiftCapabilities(caps, IDMap{}.ToHost, true); err == nil {
+		t.Errorf("shiftCapabilities accepted an unmapped root id")
+	}
+}
+
+func TestShiftRootfsHardLinks(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("skipping test as it requires root privileges")
+	}
+
+	dir, err := ioutil.TempDir("", "shift")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "busybox"), []byte("busybox"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, name := range []string{"sh", "ls"} {
+		if err := os.Link(filepath.Join(dir, "busybox"), filepath.Join(dir, name)); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	idmap := IDMap{{Type: IDTypeUID, Nsid: 0, Hostid: 100000, Range: 65536}, {Type: IDTypeGID, Nsid: 0, Hostid: 100000, Range: 65536}}
+	if err := ShiftRootfs(dir, idmap); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Lstat(filepath.Join(dir, "sh"), &st); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if st.Uid != 100000 || st.Gid != 100000 {
+		t.Errorf("unexpected owner %d:%d", st.Uid, st.Gid)
+	}
+
+	if err := UnshiftRootfs(dir, idmap); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestParseIfInet6(t *testing.T) {
+	content := `00000000000000000000000000000001 01 80 10 80       lo
+fe80000000000000021625fffe1e2a9c 0b 40 20 80     eth0
+`
+	indices := parseIfInet6(content)
+	if indices["lo"] != 1 || indices["eth0"] != 11 {
+		t.Errorf("parseIfInet6 failed: %v", indices)
+	}
+}
+
+func TestHugePageSizeName(t *testing.T) {
+	for size, name := range map[ByteSize]string{64 * KB: "64KB", 2 * MB: "2MB", 1 * GB: "1GB"} {
+		if v := hugePageSizeName(size); v != name {
+			t.Errorf("hugePageSizeName(%v) = %q, expected %q", size, v, name)
+		}
+	}
+}
+
+func TestParseRDMAResources(t *testing.T) {
+	resources, err := parseRDMAResources([]string{"mlx4_0 hca_handle=2 hca_object=2000", "ocrdma1 hca_handle=3 hca_object=max"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(resources) != 2 || resources[0].String() != "mlx4_0 hca_handle=2 hca_object=2000" || resources[1].HCAObjects != math.MaxInt64 {
+		t.Errorf("parseRDMAResources failed: %v", resources)
+	}
+
+	if _, err := parseRDMAResources([]string{"mlx4_0 hca_handle=foo"}); err == nil {
+		t.Errorf("parseRDMAResources accepted an invalid value")
+	}
+}
+
+func TestParseDeviceRule(t *testing.T) {
+	for _, v := range []string{"a", "c 1:3 rwm", "b *:* m", "c 136:* rw"} {
+		rule, err := ParseDeviceRule(v)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+
+		if rule.String() != v {
+			t.Errorf("ParseDeviceRule(%q) = %q", v, rule.String())
+		}
+	}
+
+	for _, v := range []string{"", "x 1:3 rwm", "c 1 rwm", "c 1:3 rwx", "a 1:3 rwm", "c -1:3 r"} {
+		if _, err := ParseDeviceRule(v); err == nil {
+			t.Errorf("ParseDeviceRule accepted %q", v)
+		}
+	}
+}
+
+func TestDeviceRuleForNode(t *testing.T) {
+	rule, err := deviceRuleForNode("/dev/null")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if rule.String() != "c 1:3 rwm" {
+		t.Errorf("deviceRuleForNode failed: %s", rule)
+	}
+}
+
+func TestCompareConfigs(t *testing.T) {
+	a := []KeyValue{
+		{"lxc.uts.name", "lorem"},
+		{"lxc.mount.entry", "proc proc proc defaults 0 0"},
+		{"lxc.mount.entry", "sys sys sysfs defaults 0 0"},
+		{"lxc.cap.drop", "sys_time"},
+	}
+	b := []KeyValue{
+		{"lxc.uts.name", "ipsum"},
+		{"lxc.mount.entry", "sys sys sysfs defaults 0 0"},
+		{"lxc.mount.entry", "proc proc proc defaults 0 0"},
+		{"lxc.mount.entry", "tmpfs tmp tmpfs defaults 0 0"},
+		{"lxc.start.auto", "1"},
+	}
+
+	diff := compareConfigs(a, b)
+
+	expected := ConfigDiff{
+		Added:   []KeyValue{{"lxc.mount.entry", "tmpfs tmp tmpfs defaults 0 0"}, {"lxc.start.auto", "1"}},
+		Removed: []KeyValue{{"lxc.cap.drop", "sys_time"}},
+		Changed: []ConfigChange{{"lxc.uts.name", "lorem", "ipsum"}},
+	}
+
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("compareConfigs failed: %+v", diff)
+	}
+
+	if !compareConfigs(a, a).Empty() {
+		t.Errorf("compareConfigs found differences in identical configs")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "heartbeat")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "rootfs")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := checkHeartbeatFile(root, "/heartbeat", time.Now(), time.Minute); err != nil {
+		t.Errorf("checkHeartbeatFile failed during the grace period: %s", err)
+	}
+
+	if err := checkHeartbeatFile(root, "/heartbeat", time.Now().Add(-time.Hour), time.Minute); err == nil {
+		t.Errorf("checkHeartbeatFile didn't detect the missing heartbeat")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "heartbeat"), nil, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := checkHeartbeatFile(root, "/heartbeat", time.Now().Add(-time.Hour), time.Minute); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// symlinks of the guest don't reach the files of the host
+	host := filepath.Join(dir, "host")
+	if err := ioutil.WriteFile(host, nil, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := os.Symlink(host, filepath.Join(root, "link")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := checkHeartbeatFile(root, "/link", time.Now().Add(-time.Hour), time.Minute); err == nil {
+		t.Errorf("checkHeartbeatFile followed a symlink to the host")
+	}
+
+	if err := pingHeartbeatSocket(root, "/heartbeat.sock", time.Second); err == nil {
+		t.Errorf("pingHeartbeatSocket succeeded without a listener")
+	}
+
+	for _, socket := range []string{filepath.Join(root, "heartbeat.sock"), filepath.Join(dir, "host.sock")} {
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		defer l.Close()
+
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				conn.Write([]byte("pong\n"))
+				conn.Close()
+			}
+		}()
+	}
+
+	if err := pingHeartbeatSocket(root, "/heartbeat.sock", time.Second); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "host.sock"), filepath.Join(root, "host.sock")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := pingHeartbeatSocket(root, "/host.sock", time.Second); err == nil {
+		t.Errorf("pingHeartbeatSocket followed a symlink to the host")
+	}
+}
+
+func TestHookHelper(t *testing.T) {
+	socket := fmt.Sprintf("@go-lxc-hook-test/%d", os.Getpid())
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	h := &hookListener{listener: listener, events: make(chan HookEvent, 1), done: make(chan struct{})}
+	go h.run()
+	defer h.close()
+
+	os.Setenv("LXC_HOOK_TYPE", "pre-start")
+	defer os.Unsetenv("LXC_HOOK_TYPE")
+
+	runHookHelper(socket, []string{"lorem", "lxc", "pre-start"})
+
+	select {
+	case event := <-h.events:
+		if event.Container != "lorem" || event.Type != "pre-start" || event.Env["LXC_HOOK_TYPE"] != "pre-start" {
+			t.Errorf("unexpected hook event: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("hook event wasn't received")
+	}
+}
+
+func TestSlicePath(t *testing.T) {
+	for slice, expected := range map[string]string{
+		"-.slice":                "",
+		"machine.slice":          "machine.slice",
+		"foo-bar-baz.slice":      "foo.slice/foo-bar.slice/foo-bar-baz.slice",
+		"system-lxc\\x2dd.slice": "system.slice/system-lxc\\x2dd.slice",
+	} {
+		path, err := slicePath(slice)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+
+		if path != expected {
+			t.Errorf("slicePath(%q) = %q, expected %q", slice, path, expected)
+		}
+	}
+
+	for _, slice := range []string{"machine", "-foo.slice", "foo--bar.slice", "foo/bar.slice", ".slice"} {
+		if _, err := slicePath(slice); err == nil {
+			t.Errorf("slicePath accepted %q", slice)
+		}
+	}
+}
+
+func TestNotifySocket(t *testing.T) {
+	n, err := newNotifySocket(nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer n.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := n.wait(ctx); err == nil {
+		t.Errorf("wait returned before READY=1 was sent")
+	}
+
+	conn, err := net.Dial("unixgram", n.path())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("STATUS=Booted\nREADY=1\n")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := n.wait(ctx); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	n.mu.Lock()
+	if n.status != "Booted" {
+		t.Errorf("unexpected status %q", n.status)
+	}
+	n.mu.Unlock()
+
+	// a restarted container has to report READY=1 again
+	n.reset()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := n.wait(ctx); err == nil {
+		t.Errorf("wait returned for READY=1 of the previous boot")
+	}
+
+	if n.trustedSender(nil) {
+		t.Errorf("expected a message without credentials not to be trusted")
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskusage")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "data"), make([]byte, 64*1024), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := os.Link(filepath.Join(dir, "data"), filepath.Join(dir, "link")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	size, err := diskUsage(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if size < 64*KB || size >= 128*KB {
+		t.Errorf("unexpected disk usage %s", size)
+	}
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	rfer, excl, err := parseBtrfsQgroup("qgroupid         rfer         excl \n--------         ----         ---- \n0/257       1073741824     16384 \n")
+	if err != nil || rfer != 1*GB || excl != 16*KB {
+		t.Errorf("parseBtrfsQgroup failed: %v %v %v", rfer, excl, err)
+	}
+
+	usage, err := parseZfsUsage("2147483648\n1048576\n4096\n")
+	if err != nil || usage.Rootfs != 2*GB || usage.Delta != 1*MB || usage.Snapshots != 4*KB {
+		t.Errorf("parseZfsUsage failed: %+v %v", usage, err)
+	}
+
+	size, used, err := parseLvs("  10737418240  25.00\n")
+	if err != nil || size != 10*GB || used != 2.5*GB {
+		t.Errorf("parseLvs failed: %v %v %v", size, used, err)
+	}
+
+	size, used, err = parseLvs("  1073741824\n")
+	if err != nil || size != 1*GB || used != 1*GB {
+		t.Errorf("parseLvs failed for thick volume: %v %v %v", size, used, err)
+	}
+}
+
+func TestTranslateLXDConfig(t *testing.T) {
+	backup := `container:
+  name: web
+  architecture: x86_64
+  config:
+    boot.autostart: "true"
+    environment.FOO: bar
+    limits.memory: 512MiB
+    limits.cpu: "2"
+    security.nesting: "true"
+    volatile.eth0.hwaddr: 00:16:3e:aa:bb:cc
+    volatile.idmap.current: '[{"Isuid":true,"Isgid":false,"Hostid":1000000,"Nsid":0,"Maprange":1000000000},{"Isuid":false,"Isgid":true,"Hostid":1000000,"Nsid":0,"Maprange":1000000000}]'
+    raw.lxc: |
+      lxc.apparmor.profile = unconfined
+      lxc.hook.pre-start = /bin/true
+  devices:
+    eth0:
+      type: nic
+      nictype: bridged
+      parent: lxdbr0
+    root:
+      type: disk
+      path: /
+      pool: default
+    data:
+      type: disk
+      source: /srv/data
+      path: /data
+      readonly: "true"
+`
+
+	instance, err := parseLXDInstance([]byte(backup), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if instance.Name != "web" {
+		t.Errorf("unexpected instance name %q", instance.Name)
+	}
+
+	items, skipped := translateLXDConfig(instance, true, LXDImportOptions{})
+
+	expected := []KeyValue{
+		{"lxc.arch", "x86_64"},
+		{"lxc.start.auto", "1"},
+		{"lxc.environment", "FOO=bar"},
+		{"lxc.cgroup2.cpuset.cpus", "0-1"},
+		{"lxc.cgroup2.memory.max", "536870912"},
+		{"lxc.idmap", "u 0 1000000 1000000000"},
+		{"lxc.idmap", "g 0 1000000 1000000000"},
+		{"lxc.net.0.type", "veth"},
+		{"lxc.net.0.link", "lxdbr0"},
+		{"lxc.net.0.flags", "up"},
+		{"lxc.net.0.name", "eth0"},
+		{"lxc.net.0.hwaddr", "00:16:3e:aa:bb:cc"},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("translateLXDConfig failed: %v", items)
+	}
+
+	if !reflect.DeepEqual(skipped, []string{"raw.lxc", "security.nesting", "devices.data"}) {
+		t.Errorf("unexpected skipped keys: %v", skipped)
+	}
+
+	items, skipped = translateLXDConfig(instance, true, LXDImportOptions{RawLXC: true, DiskDevices: true})
+
+	expected = []KeyValue{
+		{"lxc.arch", "x86_64"},
+		{"lxc.start.auto", "1"},
+		{"lxc.environment", "FOO=bar"},
+		{"lxc.cgroup2.cpuset.cpus", "0-1"},
+		{"lxc.cgroup2.memory.max", "536870912"},
+		{"lxc.apparmor.profile", "unconfined"},
+		{"lxc.hook.pre-start", "/bin/true"},
+		{"lxc.idmap", "u 0 1000000 1000000000"},
+		{"lxc.idmap", "g 0 1000000 1000000000"},
+		{"lxc.mount.entry", "/srv/data data none bind,create=dir,ro 0 0"},
+		{"lxc.net.0.type", "veth"},
+		{"lxc.net.0.link", "lxdbr0"},
+		{"lxc.net.0.flags", "up"},
+		{"lxc.net.0.name", "eth0"},
+		{"lxc.net.0.hwaddr", "00:16:3e:aa:bb:cc"},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("translateLXDConfig failed: %v", items)
+	}
+
+	if !reflect.DeepEqual(skipped, []string{"security.nesting"}) {
+		t.Errorf("unexpected skipped keys: %v", skipped)
+	}
+}
+
+func TestImportFromLXDName(t *testing.T) {
+	if !VersionAtLeast(2, 1, 0) {
+		t.Skip("skipping test as lxc version is less than 2.1.0")
+	}
+
+	dir, err := ioutil.TempDir("", "lxd")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	source, lxcpath := filepath.Join(dir, "backup"), filepath.Join(dir, "lxc", "path")
+	for _, d := range []string{filepath.Join(source, "rootfs"), lxcpath, filepath.Join(dir, "victim")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(source, "backup.yaml"), []byte("container:\n  name: ../../victim\n"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, _, err := ImportFromLXD(source, LXDImportOptions{LXCPath: lxcpath}); err == nil {
+		t.Errorf("expected the name of the backup to be refused")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "victim")); err != nil {
+		t.Errorf("the import removed a directory outside the lxcpath")
+	}
+}
+
+func TestNewOCISpec(t *testing.T) {
+	config := map[string][]string{
+		"lxc.uts.name":              {"web"},
+		"lxc.init.cmd":              {"/usr/bin/nginx -g daemon off;"},
+		"lxc.environment":           {"PATH=/usr/bin:/bin"},
+		"lxc.cap.drop":              {"sys_module mac_admin"},
+		"lxc.mount.entry":           {"/srv/data data none bind,create=dir,ro 0 0"},
+		"lxc.cgroup2.memory.max":    {"536870912"},
+		"lxc.cgroup2.devices.deny":  {"a"},
+		"lxc.cgroup2.devices.allow": {"c 1:3 rwm"},
+	}
+
+	idmap := IDMap{{Type: IDTypeUID, Nsid: 0, Hostid: 100000, Range: 65536}, {Type: IDTypeGID, Nsid: 0, Hostid: 100000, Range: 65536}}
+
+	spec, err := newOCISpec(func(key string) []string { return config[key] }, idmap, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if spec.Hostname != "web" || !reflect.DeepEqual(spec.Process.Args, []string{"/usr/bin/nginx", "-g", "daemon", "off;"}) || spec.Process.Cwd != "/" {
+		t.Errorf("unexpected process: %+v", spec.Process)
+	}
+
+	for _, c := range spec.Process.Capabilities.Bounding {
+		if c == "CAP_SYS_MODULE" || c == "CAP_MAC_ADMIN" {
+			t.Errorf("dropped capability %s is in the bounding set", c)
+		}
+	}
+
+	if len(spec.Process.Capabilities.Inheritable) != 0 {
+		t.Errorf("unexpected inheritable capabilities: %v", spec.Process.Capabilities.Inheritable)
+	}
+
+	last := spec.Mounts[len(spec.Mounts)-1]
+	if last.Destination != "/data" || last.Source != "/srv/data" || last.Type != "bind" || !reflect.DeepEqual(last.Options, []string{"bind", "ro"}) {
+		t.Errorf("unexpected mount: %+v", last)
+	}
+
+	if len(spec.Linux.UIDMappings) != 1 || spec.Linux.UIDMappings[0].HostID != 100000 {
+		t.Errorf("unexpected uid mappings: %+v", spec.Linux.UIDMappings)
+	}
+
+	if spec.Linux.Resources == nil || spec.Linux.Resources.Memory.Limit != 512*1024*1024 || len(spec.Linux.Resources.Devices) != 2 || spec.Linux.Resources.Devices[0].Allow {
+		t.Errorf("unexpected resources: %+v", spec.Linux.Resources)
+	}
+}
+
+func TestApplyLayers(t *testing.T) {
+	layer := func(entries ...tar.Header) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range entries {
+			hdr := hdr
+			content := []byte(hdr.Linkname)
+			if hdr.Typeflag == tar.TypeReg {
+				hdr.Linkname = ""
+				hdr.Size = int64(len(content))
+			}
+			if err := tw.WriteHeader(&hdr); err != nil {
+				t.Fatalf(err.Error())
+			}
+			if hdr.Typeflag == tar.TypeReg {
+				tw.Write(content)
+			}
+		}
+		tw.Close()
+		return &buf
+	}
+
+	dir, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	lower := layer(
+		tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "etc/hostname", Typeflag: tar.TypeReg, Mode: 0644, Linkname: "lower"},
+		tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Linkname: "root"},
+		tar.Header{Name: "opt/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "opt/old", Typeflag: tar.TypeReg, Mode: 0644, Linkname: "old"},
+		tar.Header{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "/../../.."},
+	)
+
+	upper := layer(
+		tar.Header{Name: "etc/.wh.hostname", Typeflag: tar.TypeReg, Mode: 0644},
+		tar.Header{Name: "opt/new", Typeflag: tar.TypeReg, Mode: 0644, Linkname: "new"},
+		tar.Header{Name: "opt/.wh..wh..opq", Typeflag: tar.TypeReg, Mode: 0644},
+		tar.Header{Name: "escape/evil", Typeflag: tar.TypeReg, Mode: 0644, Linkname: "evil"},
+		tar.Header{Name: "etc/shadow", Typeflag: tar.TypeLink, Linkname: "etc/passwd"},
+	)
+
+	if err := ApplyLayers(filepath.Join(dir, "rootfs"), []io.Reader{lower, upper}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	rootfs := filepath.Join(dir, "rootfs")
+	for path, expected := range map[string]bool{
+		"etc/hostname": false,
+		"etc/passwd":   true,
+		"etc/shadow":   true,
+		"opt/old":      false,
+		"opt/new":      true,
+		"evil":         true,
+	} {
+		if _, err := os.Lstat(filepath.Join(rootfs, path)); (err == nil) != expected {
+			t.Errorf("unexpected state of %s: exists=%v", path, err == nil)
+		}
+	}
+
+	if _, err := os.Lstat(filepath.Join(dir, "evil")); err == nil {
+		t.Errorf("layer escaped the rootfs")
+	}
+
+	for _, name := range []string{".wh..", "etc/.wh..", "etc/.wh...", "escape/.wh.."} {
+		malicious := layer(tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644})
+		if err := ApplyLayers(rootfs, []io.Reader{malicious}); err == nil {
+			t.Errorf("expected the whiteout %q to be refused", name)
+		}
+
+		if _, err := os.Stat(filepath.Join(rootfs, "etc/passwd")); err != nil {
+			t.Fatalf("whiteout %q removed files outside its directory", name)
+		}
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	for ref, expected := range map[string]imageReference{
+		"alpine":                           {"registry-1.docker.io", "library/alpine", "latest"},
+		"docker.io/library/alpine:3.20":    {"registry-1.docker.io", "library/alpine", "3.20"},
+		"lxc/go-lxc:edge":                  {"registry-1.docker.io", "lxc/go-lxc", "edge"},
+		"ghcr.io/lxc/incus":                {"ghcr.io", "lxc/incus", "latest"},
+		"localhost:5000/busybox@sha256:ab": {"localhost:5000", "busybox", "sha256:ab"},
+	} {
+		r, err := parseImageReference(ref)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+
+		if r != expected {
+			t.Errorf("parseImageReference(%q) = %+v, expected %+v", ref, r, expected)
+		}
+	}
+
+	for _, ref := range []string{"", "Alpine", "alpine:"} {
+		if _, err := parseImageReference(ref); err == nil {
+			t.Errorf("expected an error for %q", ref)
+		}
+	}
+}
+
+func TestParseAuthenticate(t *testing.T) {
+	scheme, params := parseAuthenticate(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`)
+	if scheme != "bearer" {
+		t.Errorf("unexpected scheme %q", scheme)
+	}
+
+	if !reflect.DeepEqual(params, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/alpine:pull",
+	}) {
+		t.Errorf("unexpected parameters %v", params)
+	}
+}
+
+// testRegistry serves manifests by tag or digest and blobs by digest,
+// requiring bearer token authentication.
+func testRegistry(manifests map[string][]byte, blobs map[string][]byte) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var content []byte
+		var ok bool
+		if strings.HasPrefix(r.URL.Path, "/v2/library/alpine/manifests/") {
+			content, ok = manifests[strings.TrimPrefix(r.URL.Path, "/v2/library/alpine/manifests/")]
+		} else if strings.HasPrefix(r.URL.Path, "/v2/library/alpine/blobs/") {
+			content, ok = blobs[strings.TrimPrefix(r.URL.Path, "/v2/library/alpine/blobs/")]
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	}))
+	return server
+}
+
+func TestPullImage(t *testing.T) {
+	blobs := make(map[string][]byte)
+	add := func(content []byte) ociDescriptor {
+		digest := digestOf(content)
+		blobs[digest] = content
+		return ociDescriptor{Digest: digest, Size: int64(len(content))}
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "hello", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	tw.Write([]byte("world"))
+	tw.Close()
+
+	layer := add(buf.Bytes())
+	config := add([]byte(`{"architecture":"arm64","config":{"Env":["PATH=/bin"],"Cmd":["/bin/sh","-c","echo hi"]}}`))
+	manifest, _ := json.Marshal(ociManifest{MediaType: mediaTypeOCIManifest, Config: config, Layers: []ociDescriptor{layer}})
+
+	index := []byte(`{"mediaType":"` + mediaTypeOCIIndex + `","manifests":[` +
+		`{"digest":"sha256:0000","platform":{"os":"linux","architecture":"amd64"}},` +
+		`{"digest":"` + digestOf(manifest) + `","platform":{"os":"linux","architecture":"arm64","variant":"v8"}}]}`)
+
+	manifests := map[string][]byte{"3.20": index, digestOf(manifest): manifest}
+	server := testRegistry(manifests, blobs)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "pull")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0700); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ref := strings.TrimPrefix(server.URL, "http://") + "/library/alpine:3.20"
+	opts := ImageOptions{Platform: "linux/arm64", Insecure: true}
+
+	image, layers, err := pullImage(context.Background(), ref, opts, dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(layers) != 1 {
+		t.Fatalf("expected one layer, got %d", len(layers))
+	}
+
+	if !reflect.DeepEqual(imageConfigItems(image), []KeyValue{
+		{"lxc.init.cmd", "/bin/sh -c 'echo hi'"},
+		{"lxc.environment", "PATH=/bin"},
+	}) {
+		t.Errorf("unexpected config items %v", imageConfigItems(image))
+	}
+
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Platform: "linux/s390x", Insecure: true}, dir); err == nil {
+		t.Errorf("expected an error for a missing platform")
+	}
+
+	// verified blobs are reused
+	blobs[layer.Digest] = []byte("tampered")
+	if _, _, err := pullImage(context.Background(), ref, opts, dir); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if _, _, err := pullImage(context.Background(), ref, opts, filepath.Join(dir, "empty")); err == nil {
+		t.Errorf("expected a digest mismatch")
+	}
+
+	// manifests aren't read into memory without bounds
+	manifests["huge"] = bytes.Repeat([]byte(" "), maxManifestSize+1)
+	if _, _, err := pullImage(context.Background(), strings.TrimSuffix(ref, "3.20")+"huge", opts, dir); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("expected an oversized manifest to be refused")
+	}
+}
+
+func TestPullImageVerification(t *testing.T) {
+	blobs := make(map[string][]byte)
+	add := func(content []byte) ociDescriptor {
+		digest := digestOf(content)
+		blobs[digest] = content
+		return ociDescriptor{Digest: digest, Size: int64(len(content))}
+	}
+
+	config := add([]byte(`{"architecture":"amd64"}`))
+	manifest, _ := json.Marshal(ociManifest{MediaType: mediaTypeOCIManifest, Config: config})
+	digest := digestOf(manifest)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"alpine"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"}}`)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(crand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	signed := add(payload)
+	signed.MediaType = cosignPayloadMediaType
+	signed.Annotations = map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	signature, _ := json.Marshal(ociManifest{MediaType: mediaTypeOCIManifest, Config: config, Layers: []ociDescriptor{signed}})
+
+	manifests := map[string][]byte{"latest": manifest}
+	server := testRegistry(manifests, blobs)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "pull")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	ref := strings.TrimPrefix(server.URL, "http://") + "/library/alpine"
+
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Insecure: true, Digest: digest}, dir); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Insecure: true, Digest: "sha256:0000"}, dir); err == nil {
+		t.Errorf("expected a pinned digest mismatch")
+	}
+
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Insecure: true, CosignPublicKey: pub}, dir); err == nil {
+		t.Errorf("expected an error for a missing signature")
+	}
+
+	manifests["sha256-"+strings.TrimPrefix(digest, "sha256:")+".sig"] = signature
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Insecure: true, CosignPublicKey: pub}, dir); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	der, _ = x509.MarshalPKIXPublicKey(&other.PublicKey)
+	if _, _, err := pullImage(context.Background(), ref, ImageOptions{Insecure: true, CosignPublicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})}, dir); err == nil {
+		t.Errorf("expected an error for a signature of another key")
+	}
+}
+
+func TestBlobDigest(t *testing.T) {
+	if digest := digestOf([]byte("go-lxc")); !validDigest(digest) {
+		t.Errorf("expected %q to be valid", digest)
+	}
+
+	for _, invalid := range []string{"", "sha256:", "sha512:" + strings.Repeat("0", 64), "sha256:" + strings.Repeat("A", 64), "sha256:" + strings.Repeat("0", 63), "sha256:../../" + strings.Repeat("0", 58)} {
+		if validDigest(invalid) {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "blob")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	cache := filepath.Join(dir, "cache")
+	if err := os.Mkdir(cache, 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	r := &registryClient{ctx: context.Background()}
+	if _, err := r.blob(ociDescriptor{Digest: "sha256:../escaped"}, cache); err == nil {
+		t.Errorf("expected a digest escaping the cache to be refused")
+	}
+
+	if exists(filepath.Join(dir, "escaped.partial")) {
+		t.Errorf("the blob was written outside the cache")
+	}
+}
+
+func TestPullImageResume(t *testing.T) {
+	content := bytes.Repeat([]byte("go-lxc"), 1024)
+	layer := ociDescriptor{Digest: digestOf(content), Size: int64(len(content))}
+
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		var offset int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset)
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)-offset))
+		if offset > 0 {
+			w.WriteHeader(http.StatusPartialContent)
+		}
+
+		if first {
+			// drop the connection halfway through
+			w.Write(content[:len(content)/2])
+			return
+		}
+		w.Write(content[offset:])
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "pull")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := parseImageReference(strings.TrimPrefix(server.URL, "http://") + "/library/alpine")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	client, err := newRegistryClient(context.Background(), r, ImageOptions{Insecure: true, Retries: 1})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	path, err := client.blob(layer, dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(ranges, []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}) {
+		t.Errorf("unexpected range requests %v", ranges)
+	}
+
+	if err := verifyFile(path, layer.Digest); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := os.Mkdir(filepath.Join(dir, "canceled"), 0700); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	client, err = newRegistryClient(ctx, r, ImageOptions{Insecure: true, Retries: 1})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, err := client.blob(layer, filepath.Join(dir, "canceled")); err != context.Canceled {
+		t.Errorf("expected the download to be canceled, got %v", err)
+	}
+}
+
+func TestPullImageDownloadConfig(t *testing.T) {
+	content := make([]byte, 5*1024*1024)
+	rand.Read(content)
+	layer := ociDescriptor{Digest: digestOf(content), Size: int64(len(content))}
+
+	var mu sync.Mutex
+	var ranges []string
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, _, ok := r.BasicAuth(); !ok || username != "user" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		http.ServeContent(w, r, "blob", time.Time{}, bytes.NewReader(content))
+	}))
+	defer registry.Close()
+
+	var mirrorAuth string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		mirrorAuth += r.Header.Get("Authorization")
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer mirror.Close()
+
+	dir, err := ioutil.TempDir("", "pull")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := parseImageReference(strings.TrimPrefix(registry.URL, "http://") + "/library/alpine")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	config := DownloadConfig{Mirrors: []string{mirror.URL + "/"}, Segments: 4}
+	client, err := newRegistryClient(context.Background(), r, ImageOptions{Insecure: true, Download: &config, Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	path, err := client.blob(layer, dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := verifyFile(path, layer.Digest); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if len(ranges) != 4 {
+		t.Errorf("expected 4 segments, got %v", ranges)
+	}
+
+	if client.base != 1 {
+		t.Errorf("expected to fail over to the registry")
+	}
+
+	if mirrorAuth != "" {
+		t.Errorf("the credentials of the registry were sent to the mirror: %q", mirrorAuth)
+	}
+}
+
+func TestLxcpathDownloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if config, err := LoadDownloadConfig(dir); err != nil || !reflect.DeepEqual(config, GlobalDownloadConfig()) {
+		t.Errorf("expected the global configuration, got %+v, %v", config, err)
+	}
+
+	config := DownloadConfig{Proxy: "http://proxy.example.com:3128", Mirrors: []string{"https://mirror.gcr.io"}, Segments: 2}
+	if err := SaveDownloadConfig(config, dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if loaded, err := LoadDownloadConfig(dir); err != nil || !reflect.DeepEqual(loaded, config) {
+		t.Errorf("unexpected configuration %+v, %v", loaded, err)
+	}
+
+	if err := DeleteDownloadConfig(dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if config, err := LoadDownloadConfig(dir); err != nil || !reflect.DeepEqual(config, GlobalDownloadConfig()) {
+		t.Errorf("expected the global configuration again, got %+v, %v", config, err)
+	}
+
+	os.Setenv("https_proxy", "http://old.example.com")
+	defer os.Unsetenv("https_proxy")
+	os.Unsetenv("http_proxy")
+
+	withProxyEnv(config.Proxy, func() bool {
+		if os.Getenv("http_proxy") != config.Proxy || os.Getenv("https_proxy") != config.Proxy {
+			t.Errorf("the proxy isn't passed on through the environment")
+		}
+		return true
+	})
+
+	if _, ok := os.LookupEnv("http_proxy"); ok || os.Getenv("https_proxy") != "http://old.example.com" {
+		t.Errorf("the proxy environment wasn't restored")
+	}
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	order, err := topologicalOrder([]string{"app", "proxy", "db", "cache"}, map[string][]string{
+		"app":   {"db", "cache"},
+		"proxy": {"app"},
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(order, []string{"db", "cache", "app", "proxy"}) {
+		t.Errorf("unexpected order %v", order)
+	}
+
+	if _, err := topologicalOrder([]string{"a", "b", "c"}, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}); err == nil {
+		t.Errorf("expected a dependency cycle")
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	total, available, err := parseMeminfo("MemTotal:       16318412 kB\nMemFree:         1131016 kB\nMemAvailable:    9263136 kB\n")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if total != 16318412*KB || available != 9263136*KB {
+		t.Errorf("unexpected memory %s/%s", available, total)
+	}
+
+	for value, expected := range map[string]ByteSize{"max": 0, "": 0, "512M": 512 * MB, "1G": 1 * GB, "4096": 4096} {
+		if size, err := parseCgroupBytes(value); err != nil || size != expected {
+			t.Errorf("parseCgroupBytes(%q) = %s, %v", value, size, err)
+		}
+	}
+
+	if n, err := parseCPUList("0-3,8,10-11"); err != nil || n != 7 {
+		t.Errorf("parseCPUList = %d, %v", n, err)
+	}
+
+	if n, err := parseCPUQuota("150000", "100000"); err != nil || n != 1.5 {
+		t.Errorf("parseCPUQuota = %g, %v", n, err)
+	}
+
+	if err := admit("memory", 2, 6, 8); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := admit("memory", 3, 6, 8); err == nil {
+		t.Errorf("expected the reservation to be refused")
+	}
+}
+
+func TestCgroupFreezeHelpers(t *testing.T) {
+	path, err := parseProcCgroup("0::/lxc.payload.c1/init.scope\n", "")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if cgroup, err := payloadCgroup(path, "c1", ""); err != nil || cgroup != "/lxc.payload.c1" {
+		t.Errorf("unexpected payload cgroup %q, %v", cgroup, err)
+	}
+
+	path, err = parseProcCgroup("12:cpu,cpuacct:/lxc/c1\n5:freezer:/lxc/c2\n0::/\n", "freezer")
+	if err != nil || path != "/lxc/c2" {
+		t.Errorf("unexpected freezer cgroup %q", path)
+	}
+
+	for _, tt := range []struct {
+		path     string
+		dir      string
+		expected string
+	}{
+		{"/lxc/c1/sub", "", "/lxc/c1"},
+		{"/machine.slice/c1/init", "machine.slice/c1", "/machine.slice/c1"},
+		{"/user.slice/monitor/lxc.payload.c1/c1/init", "c1", "/user.slice/monitor/lxc.payload.c1/c1"},
+	} {
+		if cgroup, err := payloadCgroup(tt.path, "c1", tt.dir); err != nil || cgroup != tt.expected {
+			t.Errorf("unexpected payload cgroup of %q: %q, %v", tt.path, cgroup, err)
+		}
+	}
+
+	// the leaf cgroup of init isn't mistaken for the container's
+	for _, dir := range []string{"", "machine.slice/c2"} {
+		if _, err := payloadCgroup("/machine.slice/c1/init", "c1", dir); err == nil || !strings.HasPrefix(err.Error(), ErrNotSupported.Error()) {
+			t.Errorf("expected %q for dir %q, got %v", ErrNotSupported, dir, err)
+		}
+	}
+
+	if !cgroupFrozen("populated 1\nfrozen 1\n") || cgroupFrozen("populated 1\nfrozen 0\n") {
+		t.Errorf("unexpected cgroup.events parsing")
+	}
+
+	if FreezeCgroup.String() != "cgroup" {
+		t.Errorf("unexpected freeze method %s", FreezeCgroup)
+	}
+}
+
+func TestCloneIdentity(t *testing.T) {
+	if cloneMAC("web1", 0) != cloneMAC("web1", 0) || cloneMAC("web1", 0) == cloneMAC("web2", 0) || cloneMAC("web1", 0) == cloneMAC("web1", 1) {
+		t.Errorf("MAC addresses aren't derived from the name and index")
+	}
+
+	if !strings.HasPrefix(cloneMAC("web1", 0), "00:16:3e:") {
+		t.Errorf("unexpected MAC address %s", cloneMAC("web1", 0))
+	}
+
+	hosts := patchHosts("127.0.0.1 localhost\n127.0.1.1 base base.lan # base\n::1 basement\n", "base", "web1")
+	if hosts != "127.0.0.1 localhost\n127.0.1.1\tweb1\tweb1.lan # base\n::1 basement\n" {
+		t.Errorf("unexpected hosts %q", hosts)
+	}
+
+	dir, err := ioutil.TempDir("", "clone")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	host, rootfs := filepath.Join(dir, "host"), filepath.Join(dir, "rootfs")
+	for _, d := range []string{host, filepath.Join(rootfs, "etc")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	for _, path := range []string{filepath.Join(host, "hostname"), filepath.Join(host, "hosts"), filepath.Join(rootfs, "etc/hostname")} {
+		if err := ioutil.WriteFile(path, []byte("base\n"), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	if err := updateHostnameFiles(rootfs, "base", "web1"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if content, _ := ioutil.ReadFile(filepath.Join(rootfs, "etc/hostname")); string(content) != "web1\n" {
+		t.Errorf("unexpected hostname %q", content)
+	}
+
+	// a guest pointing its files at the host's must not get them written
+	if err := os.Symlink(filepath.Join(host, "hosts"), filepath.Join(rootfs, "etc/hosts")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := updateHostnameFiles(rootfs, "base", "web1"); err == nil {
+		t.Errorf("expected the symlinked /etc/hosts to be refused")
+	}
+
+	if err := os.RemoveAll(filepath.Join(rootfs, "etc")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := os.Symlink(host, filepath.Join(rootfs, "etc")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	updateHostnameFiles(rootfs, "base", "web1")
+
+	for _, name := range []string{"hostname", "hosts"} {
+		if content, _ := ioutil.ReadFile(filepath.Join(host, name)); string(content) != "base\n" {
+			t.Errorf("the host's %s was modified", name)
+		}
+	}
+}
+
+func TestResetIdentity(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(rootfs)
+
+	for path, content := range map[string]string{
+		"etc/machine-id":                     "0123456789abcdef\n",
+		"etc/ssh/ssh_host_ed25519_key":       "private",
+		"etc/ssh/ssh_host_ed25519_key.pub":   "public",
+		"etc/ssh/sshd_config":                "PermitRootLogin no\n",
+		"var/log/journal/0123456789abcdef/x": "journal",
+		"var/log/journal/other/x":            "journal",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(rootfs, path)), 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(rootfs, path), []byte(content), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc/systemd/system"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := resetIdentity(rootfs); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if content, err := ioutil.ReadFile(filepath.Join(rootfs, "etc/machine-id")); err != nil || len(content) != 0 {
+		t.Errorf("machine-id wasn't cleared")
+	}
+
+	for path, expected := range map[string]bool{
+		"etc/ssh/ssh_host_ed25519_key":     false,
+		"etc/ssh/ssh_host_ed25519_key.pub": false,
+		"etc/ssh/sshd_config":              true,
+		"var/log/journal/0123456789abcdef": false,
+		"var/log/journal/other":            true,
+		"etc/systemd/system/multi-user.target.wants/go-lxc-ssh-keygen.service": true,
+	} {
+		if _, err := os.Lstat(filepath.Join(rootfs, path)); (err == nil) != expected {
+			t.Errorf("unexpected state of %s: exists=%v", path, err == nil)
+		}
+	}
+
+	// a guest pointing its files at the host's must not get them written
+	host := filepath.Join(rootfs, "..", filepath.Base(rootfs)+"-host")
+	if err := ioutil.WriteFile(host, []byte("root:x:0:0\n"), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.Remove(host)
+
+	for _, path := range []string{"etc/machine-id", "etc/systemd/system/go-lxc-ssh-keygen.service"} {
+		os.Remove(filepath.Join(rootfs, "etc/machine-id"))
+		if err := ioutil.WriteFile(filepath.Join(rootfs, "etc/machine-id"), nil, 0444); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		os.Remove(filepath.Join(rootfs, path))
+		if err := os.Symlink(host, filepath.Join(rootfs, path)); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if err := resetIdentity(rootfs); err == nil {
+			t.Errorf("expected the symlinked %s to be refused", path)
+		}
+
+		if content, err := ioutil.ReadFile(host); err != nil || string(content) != "root:x:0:0\n" {
+			t.Fatalf("the file symlinked by %s was modified", path)
+		}
+	}
+}
+
+func TestParseUevent(t *testing.T) {
+	kernel := []byte("add@/devices/usb1/1-1/ttyUSB0\x00ACTION=add\x00SUBSYSTEM=tty\x00DEVNAME=ttyUSB0\x00MAJOR=188\x00MINOR=0\x00")
+
+	properties, err := parseUevent(kernel)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if properties["ACTION"] != "add" || properties["DEVNAME"] != "/dev/ttyUSB0" || properties["MAJOR"] != "188" {
+		t.Errorf("unexpected properties: %v", properties)
+	}
+
+	payload := []byte("ACTION=remove\x00SUBSYSTEM=tty\x00DEVNAME=/dev/ttyUSB1\x00ID_VENDOR_ID=0403\x00")
+	udev := make([]byte, 40)
+	copy(udev, "libudev\x00")
+	binary.LittleEndian.PutUint32(udev[16:20], 40)
+	binary.LittleEndian.PutUint32(udev[20:24], uint32(len(payload)))
+	udev = append(udev, payload...)
+
+	properties, err = parseUevent(udev)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if properties["ACTION"] != "remove" || properties["DEVNAME"] != "/dev/ttyUSB1" {
+		t.Errorf("unexpected properties: %v", properties)
+	}
+
+	if _, err := parseUevent([]byte("garbage")); err == nil {
+		t.Errorf("expected an error for an invalid message")
+	}
+
+	if !trustedUevent(unix.UnixCredentials(&unix.Ucred{Pid: 1, Uid: 0, Gid: 0})) {
+		t.Errorf("expected a message of root to be trusted")
+	}
+
+	if trustedUevent(unix.UnixCredentials(&unix.Ucred{Pid: 1000, Uid: 1000, Gid: 1000})) || trustedUevent(nil) {
+		t.Errorf("expected a message without root credentials to be refused")
+	}
+
+	// events are dropped rather than blocking the watcher
+	w := &DeviceWatcher{c: &Container{}, events: make(chan DeviceEvent, 1), stop: make(chan struct{})}
+	for i := 0; i < 2; i++ {
+		w.handle(map[string]string{"ACTION": "add", "DEVNAME": "/dev/ttyUSB0"})
+	}
+	if len(w.events) != 1 {
+		t.Errorf("expected the event to be buffered")
+	}
+
+	for _, tt := range []struct {
+		filter   DeviceFilter
+		expected bool
+	}{
+		{DeviceFilter{}, true},
+		{DeviceFilter{Subsystem: "tty", DevName: "ttyUSB*"}, true},
+		{DeviceFilter{Subsystem: "block"}, false},
+		{DeviceFilter{DevName: "ttyACM*"}, false},
+		{DeviceFilter{Properties: map[string]string{"ID_VENDOR_ID": "0403"}}, true},
+		{DeviceFilter{Properties: map[string]string{"ID_VENDOR_ID": "067b"}}, false},
+	} {
+		if tt.filter.Match(properties) != tt.expected {
+			t.Errorf("unexpected match of %+v", tt.filter)
+		}
+	}
+}
+
+func TestDeviceProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-lxc-profiles")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	profile := DeviceProfile{
+		Name:    "usb-serial",
+		Devices: []string{"/dev/null"},
+		Allow:   []string{"c 188:* rwm"},
+		Mounts:  []string{"/dev/serial dev/serial none bind,optional,create=dir"},
+	}
+
+	if err := SaveDeviceProfile(profile, dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := SaveDeviceProfile(DeviceProfile{Name: "../escape"}, dir); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+
+	if err := SaveDeviceProfile(DeviceProfile{Name: "broken", Allow: []string{"x"}}, dir); err == nil {
+		t.Errorf("expected an error for an invalid rule")
+	}
+
+	loaded, err := LoadDeviceProfile("usb-serial", dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(loaded, profile) {
+		t.Errorf("unexpected profile: %+v", loaded)
+	}
+
+	items, err := deviceProfileItems(loaded)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{devicesConfigKey("allow"), "c 1:3 rwm"},
+		{"lxc.mount.entry", "/dev/null dev/null none bind,optional,create=file"},
+		{devicesConfigKey("allow"), "c 188:* rwm"},
+		{"lxc.mount.entry", "/dev/serial dev/serial none bind,optional,create=dir"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	if names, err := DeviceProfiles(dir); err != nil || !reflect.DeepEqual(names, []string{"usb-serial"}) {
+		t.Errorf("unexpected profiles: %v %v", names, err)
+	}
+
+	if err := DeleteDeviceProfile("usb-serial", dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, err := LoadDeviceProfile("usb-serial", dir); err == nil {
+		t.Errorf("expected an error for a deleted profile")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-lxc-profiles")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	profile := Profile{
+		Name:    "base",
+		Config:  []KeyValue{{"lxc.apparmor.profile", "unconfined"}, {"lxc.environment", "TZ=UTC"}},
+		Devices: []string{"/dev/null"},
+	}
+
+	if err := SaveProfile(profile, dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := SaveProfile(Profile{Name: "broken", Config: []KeyValue{{"apparmor", "x"}}}, dir); err == nil {
+		t.Errorf("expected an error for an invalid key")
+	}
+
+	loaded, err := LoadProfile("base", dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(loaded, profile) {
+		t.Errorf("unexpected profile: %+v", loaded)
+	}
+
+	items, err := profileItems(loaded)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{"lxc.apparmor.profile", "unconfined"},
+		{"lxc.environment", "TZ=UTC"},
+		{devicesConfigKey("allow"), "c 1:3 rwm"},
+		{"lxc.mount.entry", "/dev/null dev/null none bind,optional,create=file"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	if names, err := Profiles(dir); err != nil || !reflect.DeepEqual(names, []string{"base"}) {
+		t.Errorf("unexpected profiles: %v %v", names, err)
+	}
+
+	if err := DeleteProfile("base", dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := DeleteProfile("base", dir); err == nil {
+		t.Errorf("expected an error for a deleted profile")
+	}
+}
+
+func TestExpandIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-lxc-includes")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"common.conf":           "# common\nlxc.cap.drop = sys_module\nlxc.include = " + filepath.Join(dir, "common.conf.d") + "\n",
+		"common.conf.d/b.conf":  "lxc.mount.auto = proc:rw\n",
+		"common.conf.d/a.conf":  "lxc.environment = A=1\n",
+		"common.conf.d/ignored": "lxc.environment = B=1\n",
+		"cycle.conf":            "lxc.include = " + filepath.Join(dir, "other.conf") + "\n",
+		"other.conf":            "lxc.include = " + filepath.Join(dir, "cycle.conf") + "\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	config := []KeyValue{
+		{"lxc.uts.name", "test"},
+		{"lxc.include", filepath.Join(dir, "common.conf")},
+	}
+
+	expanded, err := expandItems(config, "", make(map[string]bool))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []ConfigOrigin{
+		{KeyValue{"lxc.uts.name", "test"}, ""},
+		{KeyValue{"lxc.cap.drop", "sys_module"}, filepath.Join(dir, "common.conf")},
+		{KeyValue{"lxc.environment", "A=1"}, filepath.Join(dir, "common.conf.d/a.conf")},
+		{KeyValue{"lxc.mount.auto", "proc:rw"}, filepath.Join(dir, "common.conf.d/b.conf")},
+	}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("unexpected config: %v", expanded)
+	}
+
+	if _, err := expandIncludes(filepath.Join(dir, "cycle.conf"), make(map[string]bool)); err == nil || !strings.Contains(err.Error(), ErrIncludeCycle.Error()) {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+
+	if _, err := expandIncludes(filepath.Join(dir, "missing.conf"), make(map[string]bool)); err == nil {
+		t.Errorf("expected an error for a missing include")
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	items := []ConfigOrigin{
+		{KeyValue{"lxc.uts.name", "default"}, "/etc/lxc/default.conf"},
+		{KeyValue{"lxc.cap.drop", "mac_admin"}, "common.conf"},
+		{KeyValue{"lxc.mount.entry", "proc proc proc nodev 0 0"}, "common.conf"},
+		{KeyValue{"lxc.hook.mount", "/usr/share/lxcfs/lxc.mount.hook"}, "common.conf"},
+		{KeyValue{"lxc.uts.name", "test"}, ""},
+		{KeyValue{"lxc.cap.drop", ""}, ""},
+		{KeyValue{"lxc.cap.drop", "sys_time"}, ""},
+		{KeyValue{"lxc.mount.entry", "tmpfs tmp tmpfs defaults 0 0"}, ""},
+		{KeyValue{"lxc.net.0.ipv4.address", "10.0.3.2/24"}, ""},
+		{KeyValue{"lxc.net.0.ipv4.address", "10.0.3.3/24"}, ""},
+	}
+
+	expected := []ConfigOrigin{
+		{KeyValue{"lxc.mount.entry", "proc proc proc nodev 0 0"}, "common.conf"},
+		{KeyValue{"lxc.hook.mount", "/usr/share/lxcfs/lxc.mount.hook"}, "common.conf"},
+		{KeyValue{"lxc.uts.name", "test"}, ""},
+		{KeyValue{"lxc.cap.drop", "sys_time"}, ""},
+		{KeyValue{"lxc.mount.entry", "tmpfs tmp tmpfs defaults 0 0"}, ""},
+		{KeyValue{"lxc.net.0.ipv4.address", "10.0.3.2/24"}, ""},
+		{KeyValue{"lxc.net.0.ipv4.address", "10.0.3.3/24"}, ""},
+	}
+
+	if effective := effectiveConfig(items); !reflect.DeepEqual(effective, expected) {
+		t.Errorf("unexpected config: %v", effective)
+	}
+}
+
+func TestCgroupPlacementItems(t *testing.T) {
+	items, err := cgroupPlacementItems(CgroupPlacement{MonitorDir: "lxc.monitor/c1", ContainerDir: "lxc.payload/c1", ContainerInner: "init", Relative: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{"lxc.cgroup.dir", ""},
+		{"lxc.cgroup.dir.monitor", "lxc.monitor/c1"},
+		{"lxc.cgroup.dir.container", "lxc.payload/c1"},
+		{"lxc.cgroup.dir.container.inner", "init"},
+		{"lxc.cgroup.relative", "1"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	for _, p := range []CgroupPlacement{
+		{Dir: "/abs"},
+		{Dir: "../escape"},
+		{Dir: "a/../../b"},
+		{MonitorDir: "monitor"},
+		{Dir: "dir", MonitorDir: "monitor", ContainerDir: "payload"},
+		{MonitorDir: "same", ContainerDir: "same"},
+	} {
+		if _, err := cgroupPlacementItems(p); err == nil {
+			t.Errorf("expected an error for %+v", p)
+		}
+	}
+}
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure([]string{
+		"some avg10=1.50 avg60=0.75 avg300=0.10 total=123456",
+		"full avg10=0.50 avg60=0.25 avg300=0.00 total=1000",
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := Pressure{
+		Some: PressureValues{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 123456 * time.Microsecond},
+		Full: PressureValues{Avg10: 0.5, Avg60: 0.25, Avg300: 0, Total: time.Millisecond},
+	}
+	if p != expected {
+		t.Errorf("unexpected pressure: %+v", p)
+	}
+
+	for _, lines := range [][]string{{""}, {"none avg10=0"}, {"some avg10=x"}, {"some total"}} {
+		if _, err := parsePressure(lines); err == nil {
+			t.Errorf("expected an error for %q", lines)
+		}
+	}
+}
+
+func TestParseProcNet(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0035 00000000:0000 0A 00000000:00000000 00:00000000 00000000   101        0 1 1 0 100 0 0 10 0
+   1: 0201000A:B5A2 0101000A:01BB 01 00000000:00000000 00:00000000 00000000  1000        0 2 1 0 20 4 30 10 -1
+   2: 0201000A:B5A4 0101000A:01BB 06 00000000:00000000 00:00000000 00000000     0        0 0 3 0`
+	udp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  0: 00000000000000000000000000000000:0222 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 3 2 0 0`
+
+	stats := NetNSStats{TCPStates: make(map[string]int)}
+	if err := parseProcNet(tcp, "tcp", &stats); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := parseProcNet(udp6, "udp6", &stats); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if stats.TCP != 3 || stats.UDP != 1 {
+		t.Errorf("unexpected socket counts: %d %d", stats.TCP, stats.UDP)
+	}
+
+	if stats.TCPStates["LISTEN"] != 1 || stats.TCPStates["ESTABLISHED"] != 1 || stats.TCPStates["TIME_WAIT"] != 1 {
+		t.Errorf("unexpected states: %v", stats.TCPStates)
+	}
+
+	expected := []ListeningPort{
+		{"tcp", netip.MustParseAddrPort("127.0.0.1:53")},
+		{"udp6", netip.MustParseAddrPort("[::]:546")},
+	}
+	if !reflect.DeepEqual(stats.Listening, expected) {
+		t.Errorf("unexpected listening ports: %v", stats.Listening)
+	}
+
+	if _, err := parseProcNetAddr("0100007F"); err == nil {
+		t.Errorf("expected an error for an address without port")
+	}
+}
+
+func TestAuditConfig(t *testing.T) {
+	hardened := map[string][]string{
+		"lxc.apparmor.profile": {"generated"},
+		"lxc.seccomp.profile":  {"/usr/share/lxc/config/common.seccomp"},
+		"lxc.mount.auto":       {"proc:mixed sys:mixed cgroup:mixed"},
+	}
+	idmap := IDMap{{IDTypeUID, 0, 100000, 65536}, {IDTypeGID, 0, 100000, 65536}}
+
+	report := auditConfig(hardened, idmap)
+	if report.Grade != "A" || len(report.Findings) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	weak := map[string][]string{
+		"lxc.apparmor.profile": {"unconfined"},
+		"lxc.cap.drop":         {"sys_module mac_admin"},
+		"lxc.mount.auto":       {"proc:rw sys:rw"},
+		"lxc.mount.entry":      {"/sys sys none bind,ro 0 0"},
+	}
+
+	report = auditConfig(weak, nil)
+	if report.Grade != "F" {
+		t.Errorf("unexpected grade: %s", report.Grade)
+	}
+
+	checks := make(map[string]Severity)
+	for _, finding := range report.Findings {
+		if finding.Severity > checks[finding.Check] {
+			checks[finding.Check] = finding.Severity
+		}
+	}
+
+	expected := map[string]Severity{
+		"privileged":   SeverityCritical,
+		"capabilities": SeverityWarning,
+		"apparmor":     SeverityCritical,
+		"seccomp":      SeverityWarning,
+		"mounts":       SeverityWarning,
+	}
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("unexpected findings: %+v", report.Findings)
+	}
+
+	report = auditConfig(map[string][]string{"lxc.apparmor.profile": {"generated"}}, idmap)
+	if report.Grade != "B" {
+		t.Errorf("unexpected grade: %s", report.Grade)
+	}
+}
+
+func TestCompileOCISeccomp(t *testing.T) {
+	profile := `{
+		"defaultAction": "SCMP_ACT_ERRNO",
+		"defaultErrnoRet": 38,
+		"syscalls": [
+			{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"},
+			{"names": ["personality"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 8, "op": "SCMP_CMP_EQ"}]},
+			{"names": ["clone"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 2114060288, "valueTwo": 0, "op": "SCMP_CMP_MASKED_EQ"}]},
+			{"names": ["mount"], "action": "SCMP_ACT_ALLOW", "includes": {"caps": ["CAP_SYS_ADMIN"]}},
+			{"names": ["ptrace"], "action": "SCMP_ACT_ERRNO", "errnoRet": 1}
+		]
+	}`
+
+	policy, err := CompileOCISeccomp([]byte(profile))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `2
+allowlist errno 38
+[all]
+read allow
+write allow
+personality allow [0,8,SCMP_CMP_EQ]
+clone allow [0,0,SCMP_CMP_MASKED_EQ,2114060288]
+ptrace errno 1
+`
+	if policy != expected {
+		t.Errorf("unexpected policy:\n%s", policy)
+	}
+
+	policy, err = CompileOCISeccomp([]byte(`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["kexec_load"], "action": "SCMP_ACT_KILL"}]}`))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if policy != "2\ndenylist\n[all]\nkexec_load kill\n" {
+		t.Errorf("unexpected policy:\n%s", policy)
+	}
+
+	for _, profile := range []string{
+		`{"defaultAction": "SCMP_ACT_TRACE"}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_KILL", "args": [{"index": 0, "op": "SCMP_CMP_XX"}]}]}`,
+		`not json`,
+	} {
+		if _, err := CompileOCISeccomp([]byte(profile)); err == nil {
+			t.Errorf("expected an error for %s", profile)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, preset := range []Preset{Presets.Hardened, Presets.Compatibility, Presets.Nested} {
+		if !validProfileName(preset.Name) || len(preset.Config) == 0 {
+			t.Errorf("invalid preset %q", preset.Name)
+		}
+
+		for _, kv := range preset.Config {
+			if !strings.HasPrefix(kv.Key, "lxc.") {
+				t.Errorf("invalid key %q in preset %q", kv.Key, preset.Name)
+			}
+		}
+	}
+
+	config := map[string][]string{}
+	for _, kv := range Presets.Hardened.Config {
+		config[kv.Key] = append(config[kv.Key], kv.Value)
+	}
+
+	if report := auditConfig(config, IDMap{{IDTypeUID, 0, 100000, 65536}}); report.Grade != "A" {
+		t.Errorf("hardened preset isn't graded A: %+v", report.Findings)
+	}
+}
+
+func TestParsePasswdGroup(t *testing.T) {
+	users := parsePasswd("root:x:0:0:root:/root:/bin/bash\n# comment\nwww-data:x:33:33:www-data:/var/www:/usr/sbin/nologin\nbroken:x:a:0::/:/bin/sh\n")
+
+	expected := []User{
+		{Name: "root", UID: 0, GID: 0, Home: "/root", Shell: "/bin/bash"},
+		{Name: "www-data", UID: 33, GID: 33, Home: "/var/www", Shell: "/usr/sbin/nologin"},
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("unexpected users: %+v", users)
+	}
+
+	groups := parseGroup("root:x:0:\nwww-data:x:33:\nadm:x:4:syslog,www-data\n")
+
+	group, err := findGroup(groups, "adm")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if group.GID != 4 || !reflect.DeepEqual(group.Members, []string{"syslog", "www-data"}) {
+		t.Errorf("unexpected group: %+v", group)
+	}
+
+	if group, err := findGroup(groups, "33"); err != nil || group.Name != "www-data" {
+		t.Errorf("unexpected group: %+v %v", group, err)
+	}
+
+	if _, err := findGroup(groups, "missing"); err == nil {
+		t.Errorf("expected an error for a missing group")
+	}
+}
+
+func TestReadResolvedFile(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "rootfs")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(rootfs)
+
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "group"), []byte("root:x:0:\n"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// a guest controlled symlink pointing at a file of the host
+	if err := os.Symlink("/etc/hostname", filepath.Join(rootfs, "etc", "passwd")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	path, err := resolvePath(rootfs, "/etc/passwd")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := readResolvedFile(path); err == nil {
+		t.Errorf("expected the symlink to be refused")
+	}
+
+	path, err = resolvePath(rootfs, "/etc/group")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if content, err := readResolvedFile(path); err != nil || string(content) != "root:x:0:\n" {
+		t.Errorf("unexpected content: %q, %v", content, err)
+	}
+}
+
+func TestAttachEnv(t *testing.T) {
+	environ := []string{"HOME=/root", "LC_ALL=C", "LC_TIME=de_CH", "LANG=C", "TERM=xterm"}
+
+	options := attachEnv(AttachOptions{
+		EnvToKeep:     []string{"TERM"},
+		EnvToKeepGlob: []string{"LC_*", "LANG"},
+		Path:          DefaultAttachPath,
+	}, environ)
+
+	if !reflect.DeepEqual(options.EnvToKeep, []string{"TERM", "LC_ALL", "LC_TIME", "LANG"}) {
+		t.Errorf("unexpected env to keep: %v", options.EnvToKeep)
+	}
+
+	if !reflect.DeepEqual(options.Env, []string{"PATH=" + DefaultAttachPath}) {
+		t.Errorf("unexpected env: %v", options.Env)
+	}
+
+	options = attachEnv(AttachOptions{Env: []string{"PATH=/opt/bin"}, Path: DefaultAttachPath}, environ)
+	if !reflect.DeepEqual(options.Env, []string{"PATH=/opt/bin"}) {
+		t.Errorf("PATH of Env was overridden: %v", options.Env)
+	}
+
+	// the PATH of the calling process is kept by default
+	options = attachEnv(DefaultAttachOptions, environ)
+	if len(options.Env) != 0 {
+		t.Errorf("DefaultAttachOptions set the env: %v", options.Env)
+	}
+}
+
+func TestParseRlimit(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		expected Rlimit
+	}{
+		{"1024:4096", Rlimit{"nofile", 1024, 4096}},
+		{"1024", Rlimit{"nofile", 1024, 1024}},
+		{"0:unlimited", Rlimit{"nofile", 0, RlimitInfinity}},
+		{"unlimited", Rlimit{"nofile", RlimitInfinity, RlimitInfinity}},
+	} {
+		r, err := parseRlimit("nofile", tt.value)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+
+		if r != tt.expected {
+			t.Errorf("unexpected limit for %q: %+v", tt.value, r)
+		}
+	}
+
+	if s := (Rlimit{"core", 0, RlimitInfinity}).String(); s != "0:unlimited" {
+		t.Errorf("unexpected string: %s", s)
+	}
+
+	for _, value := range []string{"4096:1024", "x", "1:y"} {
+		if _, err := parseRlimit("nofile", value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+
+	if _, err := parseRlimit("files", "1"); err == nil {
+		t.Errorf("expected an error for an unknown resource")
+	}
+}
+
+func TestAttachExtras(t *testing.T) {
+	for _, umask := range []int{-1, 0, 0027} {
+		options := DefaultAttachOptions
+		if umask >= 0 {
+			options.Umask = &umask
+		}
+		options.NoNewPrivs = umask == 0
+
+		extras, free, err := makeExtraAttachOpts(options)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if int(extras.umask) != umask || bool(extras.no_new_privs) != options.NoNewPrivs {
+			t.Errorf("unexpected extra attach options for umask %o: %d, %v", umask, extras.umask, extras.no_new_privs)
+		}
+		free()
+	}
+
+	// options not built from DefaultAttachOptions keep the umask as well
+	extras, free, err := makeExtraAttachOpts(AttachOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if extras.umask != -1 {
+		t.Errorf("expected the zero value to keep the umask, got %o", extras.umask)
+	}
+	free()
+
+	keepCaps := DefaultAttachOptions
+	keepCaps.KeepCaps = true
+
+	elevated := DefaultAttachOptions
+	elevated.ElevatedPrivileges = true
+
+	if attachFlags(keepCaps) == attachFlags(DefaultAttachOptions) {
+		t.Errorf("expected KeepCaps not to drop the capabilities")
+	}
+
+	if attachFlags(keepCaps)&attachFlags(elevated) != attachFlags(elevated) {
+		t.Errorf("expected KeepCaps to only keep the capabilities")
+	}
+}
+
+func TestAttachLSMLabel(t *testing.T) {
+	for _, tt := range []struct {
+		apparmor string
+		selinux  string
+		enabled  bool
+		expected string
+	}{
+		{"", "", true, ""},
+		{"unconfined", "", false, "unconfined"},
+		{"", "unconfined_t", true, "unconfined_t"},
+		{"unconfined", "unconfined_t", true, "unconfined"},
+		{"unconfined", "unconfined_t", false, "unconfined_t"},
+	} {
+		label := attachLSMLabel(AttachOptions{AppArmorProfile: tt.apparmor, SELinuxLabel: tt.selinux}, tt.enabled)
+		if label != tt.expected {
+			t.Errorf("unexpected label %q for %+v", label, tt)
+		}
+	}
+}
+
+func TestProcessStartTime(t *testing.T) {
+	ticks, err := parseStartTime("1234 (my (odd) cmd) S 1 1234 1234 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 98765 1000 10 18446744073709551615")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if ticks != 98765 {
+		t.Errorf("unexpected start time: %d", ticks)
+	}
+
+	boot, err := parseBootTime("cpu  1 2 3 4\nintr 1\nbtime 1700000000\nprocesses 10\n")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !boot.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected boot time: %v", boot)
+	}
+
+	started, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if since := time.Since(started); since < -time.Minute || since > time.Hour {
+		t.Errorf("unexpected start time of the test: %v", started)
+	}
+}
+
+func TestAttachedProcessStatus(t *testing.T) {
+	options := DefaultAttachOptions
+	options.Rlimits = []Rlimit{{"nofile", 1024, 4096}}
+	options.Env = []string{"FOO=bar"}
+	umask := 0027
+	options.Umask = &umask
+
+	payload, err := json.Marshal(options)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var decoded AttachOptions
+	if err := json.Unmarshal(payload, &decoded); err != nil || !reflect.DeepEqual(decoded, options) {
+		t.Errorf("the options don't survive being passed to the helper: %+v, %v", decoded, err)
+	}
+
+	helper := exec.Command("/bin/true")
+	if err := helper.Start(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	writeInt(w, 4242)
+	writeInt(w, 3<<8)
+	w.Close()
+
+	p, err := newAttachedProcess(helper.Process.Pid, r)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if p.Pid != 4242 {
+		t.Errorf("expected pid 4242, got %d", p.Pid)
+	}
+
+	if status, err := p.Wait(); err != nil || status != 3 {
+		t.Errorf("expected exit status 3, got %d, %v", status, err)
+	}
+
+	if _, err := unix.Wait4(helper.Process.Pid, nil, unix.WNOHANG, nil); err != unix.ECHILD {
+		t.Errorf("expected the helper to be reaped, got %v", err)
+	}
+
+	// a helper failing to attach closes the pipe without a pid
+	helper = exec.Command("/bin/false")
+	if err := helper.Start(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	r, w, err = os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	w.Close()
+
+	if _, err := newAttachedProcess(helper.Process.Pid, r); err != ErrAttachFailed {
+		t.Errorf("expected ErrAttachFailed, got %v", err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	var records []AuditRecord
+	SetAuditSink(AuditFunc(func(record AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+	defer SetAuditSink(nil)
+
+	c := &Container{}
+	if err := c.SetConfigItem("lxc.uts.name", "audited"); err != ErrNotDefined {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+
+	record := records[0]
+	if record.Operation != "SetConfigItem" || !reflect.DeepEqual(record.Args, []string{"lxc.uts.name", "audited"}) {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	if record.Error != ErrNotDefined.Error() || record.PID != os.Getpid() || !strings.Contains(record.Caller, "lxc_test.go") {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	var buf bytes.Buffer
+	if err := NewAuditWriter(&buf).Record(record); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var decoded AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if decoded.Operation != record.Operation || decoded.Caller != record.Caller || !decoded.Time.Equal(record.Time) {
+		t.Errorf("unexpected decoded record: %+v", decoded)
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	var records []AuditRecord
+	SetAuditSink(AuditFunc(func(record AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+	defer SetAuditSink(nil)
+
+	SetInterceptor(func(op Operation) error {
+		if op.Name == "Destroy" {
+			return fmt.Errorf("containers may not be destroyed")
+		}
+		return nil
+	})
+	defer SetInterceptor(nil)
+
+	c := &Container{}
+	if err := c.Destroy(); !errors.Is(err, ErrOperationDenied) {
+		t.Errorf("expected the operation to be denied: %v", err)
+	}
+
+	if err := c.Stop(); err != ErrNotDefined {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(records) != 2 || records[0].Operation != "Destroy" || !strings.Contains(records[0].Error, ErrOperationDenied.Error()) {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+func TestInterceptedOperations(t *testing.T) {
+	var records []AuditRecord
+	SetAuditSink(AuditFunc(func(record AuditRecord) error {
+		records = append(records, record)
+		return nil
+	}))
+	defer SetAuditSink(nil)
+
+	SetInterceptor(func(op Operation) error {
+		if op.Name != "Hibernate" {
+			return fmt.Errorf("denied")
+		}
+		return nil
+	})
+	defer SetInterceptor(nil)
+
+	c := &Container{}
+	args := []string{"/bin/true"}
+	for name, call := range map[string]func() error{
+		"RunCommand": func() error {
+			_, err := c.RunCommand(args, DefaultAttachOptions)
+			return err
+		},
+		"RunCommandStatus": func() error {
+			_, err := c.RunCommandStatus(args, DefaultAttachOptions)
+			return err
+		},
+		"RunCommandNoWait": func() error {
+			_, err := c.RunCommandNoWait(args, DefaultAttachOptions)
+			return err
+		},
+		"RunCommandAsync": func() error {
+			_, err := c.RunCommandAsync(args, DefaultAttachOptions)
+			return err
+		},
+		"AttachShell":    func() error { return c.AttachShell(DefaultAttachOptions) },
+		"Console":        func() error { return c.Console(DefaultConsoleOptions) },
+		"LoadConfigFile": func() error { return c.LoadConfigFile("/dev/null") },
+		"ClearConfig":    c.ClearConfigE,
+	} {
+		records = nil
+		if err := call(); !errors.Is(err, ErrOperationDenied) {
+			t.Errorf("expected %s to be denied: %v", name, err)
+		}
+
+		if len(records) != 1 || records[0].Operation != name {
+			t.Errorf("unexpected records of %s: %+v", name, records)
+		}
+	}
+
+	// internal steps aren't recorded on their own
+	records = nil
+	if err := c.Hibernate(HibernateOptions{CheckpointDirectory: "/tmp"}); err != ErrNotDefined {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(records) != 1 || records[0].Operation != "Hibernate" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+func TestUnsupportedUpToDate(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "go-lxc-unsupported")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "unsupported.go")
+	if out, err := exec.Command(gobin, "run", "mkunsupported.go", "-o", path).CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+
+	generated, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	committed, err := ioutil.ReadFile("unsupported.go")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !bytes.Equal(generated, committed) {
+		t.Errorf("unsupported.go is out of date, run go generate")
+	}
+}
+
+func TestParseNSSwitch(t *testing.T) {
+	data := []byte(`# /etc/nsswitch.conf
+passwd:         files systemd
+group:          compat [NOTFOUND=return] sss
+shadow:         files ldap
+hosts:          files dns
+`)
+
+	modules := parseNSSwitch(data)
+	if !reflect.DeepEqual(modules, []string{"passwd: systemd", "group: sss"}) {
+		t.Errorf("unexpected modules: %v", modules)
+	}
+
+	if modules := parseNSSwitch([]byte("passwd: files\ngroup: files\n")); len(modules) != 0 {
+		t.Errorf("unexpected modules: %v", modules)
+	}
+}
+
+func TestTTYHelpers(t *testing.T) {
+	if path := ttyPath("", 1); path != "/dev/tty1" {
+		t.Errorf("unexpected path: %s", path)
+	}
+
+	if path := ttyPath("lxc", 4); path != "/dev/lxc/tty4" {
+		t.Errorf("unexpected path: %s", path)
+	}
+
+	pids, err := namespacePIDs(os.Getpid())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	found := false
+	for _, pid := range pids {
+		found = found || pid == os.Getpid()
+	}
+	if !found {
+		t.Errorf("own pid missing: %v", pids)
+	}
+
+	f, err := ioutil.TempFile("", "go-lxc-tty")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	found = false
+	for _, file := range openFiles(os.Getpid()) {
+		found = found || file == f.Name()
+	}
+	if !found {
+		t.Errorf("open file missing")
+	}
+}
+
+func TestConsoleTTYResize(t *testing.T) {
+	master, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip("no pty available")
+	}
+
+	ttyFd, err := unix.Dup(master)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tty := &ConsoleTTY{Num: 1, Fd: master, ttyFd: ttyFd}
+	if err := tty.Resize(50, 132); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ws, err := unix.IoctlGetWinsize(tty.Fd, unix.TIOCGWINSZ)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if ws.Row != 50 || ws.Col != 132 {
+		t.Errorf("unexpected size: %dx%d", ws.Row, ws.Col)
+	}
+
+	if err := tty.Resize(0, 80); err == nil {
+		t.Errorf("expected an error for an invalid size")
+	}
+
+	if err := tty.Close(); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func TestQuirks(t *testing.T) {
+	ids, releases := parseOSRelease([]byte(`NAME="openSUSE Leap"
+VERSION="15.5"
+ID="opensuse-leap"
+ID_LIKE="suse opensuse"
+VERSION_ID='15.5'
+`))
+	if !reflect.DeepEqual(ids, []string{"opensuse-leap", "suse", "opensuse"}) || !reflect.DeepEqual(releases, []string{"15.5"}) {
+		t.Errorf("unexpected os-release: %v %v", ids, releases)
+	}
+
+	if items := quirkItems("opensuse", releases...); len(items) != 1 || items[0].Key != "lxc.init.cmd" {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	SetQuirk(Quirk{Distribution: "debian", Release: "bookworm", Config: []KeyValue{{"lxc.apparmor.profile", "unconfined"}, mqueueEntry}})
+	defer DeleteQuirk("debian", "bookworm")
+
+	if q := LookupQuirks("Debian", "bookworm"); len(q) != 2 || q[1].Release != "bookworm" {
+		t.Errorf("unexpected quirks: %v", q)
+	}
+
+	if items := quirkItems("debian", "12", "bookworm"); !reflect.DeepEqual(items, []KeyValue{mqueueEntry, {"lxc.apparmor.profile", "unconfined"}}) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	if !quirkPresent("lxc.mount.auto", "cgroup:mixed", []string{"proc:mixed sys:mixed cgroup:rw:force"}) {
+		t.Errorf("expected the cgroup mount to be present")
+	}
+
+	if quirkPresent("lxc.mount.auto", "cgroup:mixed", []string{"proc:mixed sys:mixed"}) {
+		t.Errorf("expected the cgroup mount to be missing")
+	}
+}
+
+func TestContainerInitItems(t *testing.T) {
+	init := ContainerInit{
+		Cmd: []string{"/bin/sh", "-c", "echo 'hello world'"},
+		UID: 1000,
+		Cwd: "/srv",
+		Env: []string{"PATH=/bin", "TERM"},
+	}
+
+	items, err := containerInitItems(init)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{"lxc.init.cmd", `/bin/sh -c 'echo '\''hello world'\'''`},
+		{"lxc.init.uid", "1000"},
+		{"lxc.init.gid", ""},
+		{"lxc.init.cwd", "/srv"},
+		{"lxc.environment", "PATH=/bin"},
+		{"lxc.environment", "TERM"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	args, err := splitArgs(items[0].Value)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(args, init.Cmd) {
+		t.Errorf("unexpected args: %q", args)
+	}
+
+	if args, err := splitArgs(`a "b c" d\ e ''`); err != nil || !reflect.DeepEqual(args, []string{"a", "b c", "d e", ""}) {
+		t.Errorf("unexpected args: %q %v", args, err)
+	}
+
+	if _, err := splitArgs(`"unterminated`); err == nil {
+		t.Errorf("expected an error for an unterminated quote")
+	}
+
+	if _, err := containerInitItems(ContainerInit{Env: []string{"=x"}}); err == nil {
+		t.Errorf("expected an error for an invalid variable")
+	}
+}
+
+func TestApplicationConfigItems(t *testing.T) {
+	items, err := applicationConfigItems("app", "/srv/app", []string{"/bin/app", "--serve"}, ApplicationOptions{
+		UID:      1000,
+		Env:      []string{"PORT=8080"},
+		Bridge:   "lxcbr0",
+		ReadOnly: true,
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	config := make(map[string][]string)
+	for _, kv := range items {
+		config[kv.Key] = append(config[kv.Key], kv.Value)
+	}
+
+	for key, expected := range map[string][]string{
+		"lxc.rootfs.path":    {"dir:/srv/app"},
+		"lxc.rootfs.options": {"ro"},
+		"lxc.console.path":   {"none"},
+		"lxc.init.cmd":       {"/bin/app --serve"},
+		"lxc.init.uid":       {"1000"},
+		"lxc.environment":    {"PORT=8080"},
+		"lxc.net.0.link":     {"lxcbr0"},
+	} {
+		if !reflect.DeepEqual(config[key], expected) {
+			t.Errorf("unexpected %s: %v", key, config[key])
+		}
+	}
+
+	if _, ok := config["lxc.init.gid"]; ok {
+		t.Errorf("unexpected lxc.init.gid")
+	}
+
+	if _, err := applicationConfigItems("app", "/srv/app", nil, ApplicationOptions{}); err == nil {
+		t.Errorf("expected an error for an empty command")
+	}
+}
+
+func TestNewApplicationContainerIdentity(t *testing.T) {
+	if !VersionAtLeast(2, 1, 0) {
+		t.Skip("skipping test as lxc version is less than 2.1.0")
+	}
+
+	dir, err := ioutil.TempDir("", "app")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	rootfs, lxcpath := filepath.Join(dir, "rootfs"), filepath.Join(dir, "lxc")
+	for _, d := range []string{rootfs, lxcpath} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	if _, err := NewApplicationContainer("app", rootfs, []string{"/bin/app"}, ApplicationOptions{LXCPath: lxcpath, Owner: "/billing"}); err == nil {
+		t.Errorf("expected an error for an invalid owner")
+	}
+
+	c, err := NewApplicationContainer("app", rootfs, []string{"/bin/app"}, ApplicationOptions{LXCPath: lxcpath, Owner: "billing"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	if uuid, err := readUUID(lxcpath, "app"); err != nil || uuid == "" {
+		t.Errorf("expected a UUID to be assigned, got %q, %v", uuid, err)
+	}
+
+	if owner, err := readOwner(lxcpath, "app"); err != nil || owner != "billing" {
+		t.Errorf("expected the owner billing, got %q, %v", owner, err)
+	}
+}
+
+func TestStateTransitions(t *testing.T) {
+	for _, tc := range []struct {
+		from, to State
+		ok       bool
+	}{
+		{STOPPED, STARTING, true},
+		{STOPPED, RUNNING, false},
+		{STARTING, RUNNING, true},
+		{RUNNING, FREEZING, true},
+		{FROZEN, THAWED, true},
+		{FROZEN, RUNNING, false},
+		{STOPPING, STOPPED, true},
+	} {
+		if ok := tc.from.CanTransitionTo(tc.to); ok != tc.ok {
+			t.Errorf("%s -> %s: expected %v", tc.from, tc.to, tc.ok)
+		}
+	}
+
+	for state := range StateMap {
+		stable := StateMap[state].Stable()
+		if stable != (state == "STOPPED" || state == "RUNNING" || state == "FROZEN") {
+			t.Errorf("unexpected stability of %s", state)
+		}
+	}
+
+	c := &Container{}
+	if err := c.EnsureState(context.Background(), STARTING); err == nil {
+		t.Errorf("expected an error for a transitional state")
+	}
+
+	if err := c.EnsureState(context.Background(), STOPPED); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := c.EnsureState(context.Background(), RUNNING); err != ErrNotDefined {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFollowConsole(t *testing.T) {
+	for _, tc := range []struct {
+		values  []string
+		enabled bool
+	}{
+		{nil, false},
+		{[]string{""}, false},
+		{[]string{"0"}, false},
+		{[]string{"auto"}, true},
+		{[]string{"128k"}, true},
+	} {
+		if enabled := consoleBufferEnabled(tc.values); enabled != tc.enabled {
+			t.Errorf("%q: expected %v", tc.values, tc.enabled)
+		}
+	}
+
+	c := &Container{}
+	if err := c.FollowConsole(context.Background(), ioutil.Discard); err != ErrNotDefined {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHostVFs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{
+		"pci/0000:03:00.0",
+		"pci/0000:03:10.0/net/enp3s0f0v0",
+		"pci/0000:03:10.2",
+		"net/eth0/device",
+		"net/enp3s0f0",
+		"net/enp3s0f0v0",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	for link, target := range map[string]string{
+		"net/enp3s0f0/device":         "pci/0000:03:00.0",
+		"pci/0000:03:00.0/virtfn0":    "pci/0000:03:10.0",
+		"pci/0000:03:00.0/virtfn1":    "pci/0000:03:10.2",
+		"net/enp3s0f0v0/device":       "pci/0000:03:10.0",
+		"pci/0000:03:00.0/virtfn_bad": "pci/0000:03:10.2",
+	} {
+		if err := os.Symlink(filepath.Join(dir, target), filepath.Join(dir, link)); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "pci/0000:03:00.0/sriov_numvfs"), []byte("2\n"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	vfs, err := hostVFs(filepath.Join(dir, "net"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []VF{
+		{PF: "enp3s0f0", Index: 0, PCIAddress: "0000:03:10.0", Device: "enp3s0f0v0"},
+		{PF: "enp3s0f0", Index: 1, PCIAddress: "0000:03:10.2"},
+	}
+	if !reflect.DeepEqual(vfs, expected) {
+		t.Fatalf("unexpected vfs: %+v", vfs)
+	}
+
+	if !vfs[0].Free() || vfs[1].Free() {
+		t.Errorf("unexpected free vfs: %+v", vfs)
+	}
+
+	if args := vfArgs(vfs[0], VFOptions{Name: "eth1"}); args != nil {
+		t.Errorf("unexpected args: %q", args)
+	}
+
+	args := strings.Join(vfArgs(vfs[0], VFOptions{HWAddr: "00:16:3e:00:00:01", VLAN: 100}), " ")
+	if args != "ip link set dev enp3s0f0 vf 0 mac 00:16:3e:00:00:01 vlan 100" {
+		t.Errorf("unexpected args: %q", args)
+	}
+
+	items, err := Network{Type: NetworkPhys, Link: "enp3s0f0v0", Name: "eth1", MTU: 9000, Up: true}.configItems(1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expectedItems := []KeyValue{
+		{networkKey(1, "type"), "phys"},
+		{networkKey(1, "link"), "enp3s0f0v0"},
+		{networkKey(1, "name"), "eth1"},
+		{networkKey(1, "mtu"), "9000"},
+		{networkKey(1, "flags"), "up"},
+	}
+	if !reflect.DeepEqual(items, expectedItems) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	if _, err := (Network{Type: NetworkPhys}).configItems(0); err == nil {
+		t.Errorf("expected an error for a phys device without link")
+	}
+}
+
+func TestNetworkModes(t *testing.T) {
+	items, err := Network{Type: NetworkMacvlan, Link: "eth0", MacvlanMode: MacvlanBridge}.configItems(0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{networkKey(0, "type"), "macvlan"},
+		{networkKey(0, "link"), "eth0"},
+		{networkKey(0, "macvlan.mode"), "bridge"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	items, err = Network{Type: NetworkIPvlan, Link: "eth0", IPvlanMode: IPvlanL3S, IPvlanIsolation: IPvlanPrivate}.configItems(2)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected = []KeyValue{
+		{networkKey(2, "type"), "ipvlan"},
+		{networkKey(2, "link"), "eth0"},
+		{networkKey(2, "ipvlan.mode"), "l3s"},
+		{networkKey(2, "ipvlan.isolation"), "private"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	for _, n := range []Network{
+		{Type: NetworkMacvlan, MacvlanMode: MacvlanBridge},
+		{Type: NetworkMacvlan, Link: "eth0", MacvlanMode: "l2"},
+		{Type: NetworkVeth, Link: "lxcbr0", MacvlanMode: MacvlanBridge},
+		{Type: NetworkMacvlan, Link: "eth0", IPvlanMode: IPvlanL2},
+		{Type: NetworkIPvlan, Link: "eth0", IPvlanMode: "bridge"},
+		{Type: NetworkIPvlan, Link: "eth0", IPvlanIsolation: "passthru"},
+		{Type: NetworkIPvlan, Link: "eth0", HWAddr: "00:16:3e:00:00:01"},
+	} {
+		if err := n.validate(); err == nil {
+			t.Errorf("expected an error for %+v", n)
+		}
+	}
+}
+
+func TestVXLAN(t *testing.T) {
+	for _, opts := range []VXLANOptions{
+		{},
+		{VNI: 1 << 24},
+		{VNI: 42, Local: "host"},
+		{VNI: 42, Peers: []string{"10.0.0.2", "peer"}},
+	} {
+		if _, err := NewVXLAN(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+
+	v, err := NewVXLAN(VXLANOptions{VNI: 42, Local: "10.0.0.1", Device: "eth0", Peers: []string{"10.0.0.2"}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if v.Name() != "vxlan42" || v.Bridge() != "vxbr42" {
+		t.Errorf("unexpected names: %s %s", v.Name(), v.Bridge())
+	}
+
+	var commands []string
+	for _, args := range v.upCommands(false) {
+		commands = append(commands, strings.Join(args, " "))
+	}
+
+	expected := []string{
+		"ip link add vxbr42 type bridge",
+		"ip link add vxlan42 type vxlan id 42 dstport 4789 local 10.0.0.1 dev eth0",
+		"ip link set vxlan42 mtu 1450 master vxbr42 up",
+		"ip link set vxbr42 up",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+
+	if len(v.upCommands(true)) != 3 {
+		t.Errorf("expected the existing bridge to be kept")
+	}
+
+	commands = nil
+	for _, args := range v.peerCommands([]string{"10.0.0.2", "10.0.0.3"}, []string{"10.0.0.3", "10.0.0.4", "10.0.0.4"}) {
+		commands = append(commands, strings.Join(args, " "))
+	}
+
+	expected = []string{
+		"bridge fdb del 00:00:00:00:00:00 dev vxlan42 dst 10.0.0.2",
+		"bridge fdb append 00:00:00:00:00:00 dev vxlan42 dst 10.0.0.4",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+
+	var _ Overlay = v
+}
+
+func TestWireGuardMesh(t *testing.T) {
+	network := netip.MustParsePrefix("10.128.0.0/16")
+
+	subnet, err := meshSubnet(network, 24, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if subnet.String() != "10.128.3.0/24" {
+		t.Errorf("unexpected subnet: %s", subnet)
+	}
+
+	if _, err := meshSubnet(network, 24, 256); err == nil {
+		t.Errorf("expected an error for a subnet out of range")
+	}
+
+	if _, err := NewWireGuardMesh(WireGuardOptions{PrivateKeyFile: "key", Subnet: network, HostIndex: 1, Peers: []WireGuardPeer{{PublicKey: "a", HostIndex: 1}}}); err == nil {
+		t.Errorf("expected an error for a peer using the subnet of the host")
+	}
+
+	m, err := NewWireGuardMesh(WireGuardOptions{PrivateKeyFile: "key", Subnet: network, HostIndex: 1, HostBits: 30})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if m.Subnet().String() != "10.128.0.4/30" || m.Gateway().String() != "10.128.0.5" {
+		t.Errorf("unexpected subnet: %s via %s", m.Subnet(), m.Gateway())
+	}
+
+	addr, err := m.lease("c1")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if addr.String() != "10.128.0.6" {
+		t.Errorf("unexpected address: %s", addr)
+	}
+
+	if again, _ := m.lease("c1"); again != addr {
+		t.Errorf("unexpected address: %s", again)
+	}
+
+	if _, err := m.lease("c2"); err == nil {
+		t.Errorf("expected an error for a full subnet")
+	}
+
+	var commands []string
+	for _, args := range m.upCommands(true) {
+		commands = append(commands, strings.Join(args, " "))
+	}
+
+	expected := []string{
+		"ip link add wg-lxc type wireguard",
+		"wg set wg-lxc listen-port 51820 private-key key",
+		"ip link set wg-lxc up",
+		"ip addr replace 10.128.0.5/30 dev wgbr0",
+		"ip link set wgbr0 up",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+
+	old := []WireGuardPeer{{PublicKey: "a", HostIndex: 2}, {PublicKey: "b", HostIndex: 3}}
+	new := []WireGuardPeer{{PublicKey: "b", HostIndex: 3}, {PublicKey: "c", Endpoint: "192.0.2.4:51820", HostIndex: 4}}
+
+	commands = nil
+	for _, args := range m.peerCommands(old, new) {
+		commands = append(commands, strings.Join(args, " "))
+	}
+
+	expected = []string{
+		"ip route del 10.128.0.8/30 dev wg-lxc",
+		"wg set wg-lxc peer a remove",
+		"wg set wg-lxc peer c allowed-ips 10.128.0.16/30 endpoint 192.0.2.4:51820",
+		"ip route replace 10.128.0.16/30 dev wg-lxc",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+
+	var _ Overlay = m
+}
+
+func TestPortConflicts(t *testing.T) {
+	web := PortForward{Protocol: "tcp", Listen: netip.MustParseAddrPort("0.0.0.0:80"), Port: 8080}
+	dns := PortForward{Protocol: "udp", Listen: netip.MustParseAddrPort("192.0.2.1:53"), Port: 53}
+
+	if web.String() != "tcp 0.0.0.0:80 -> 8080" {
+		t.Errorf("unexpected string: %s", web)
+	}
+
+	for _, tc := range []struct {
+		protocol string
+		addr     string
+		overlaps bool
+	}{
+		{"tcp", "127.0.0.1:80", true},
+		{"tcp6", "[::]:80", true},
+		{"udp", "0.0.0.0:80", false},
+		{"tcp", "0.0.0.0:81", false},
+	} {
+		if overlaps := web.overlaps(tc.protocol, netip.MustParseAddrPort(tc.addr)); overlaps != tc.overlaps {
+			t.Errorf("%s %s: expected %v", tc.protocol, tc.addr, tc.overlaps)
+		}
+	}
+
+	if dns.overlaps("udp", netip.MustParseAddrPort("192.0.2.2:53")) {
+		t.Errorf("expected distinct addresses not to overlap")
+	}
+
+	if !dns.overlaps("udp6", netip.MustParseAddrPort("[::ffff:192.0.2.1]:53")) {
+		t.Errorf("expected mapped addresses to overlap")
+	}
+
+	listening := []ListeningPort{
+		{"tcp", netip.MustParseAddrPort("127.0.0.1:80")},
+		{"udp", netip.MustParseAddrPort("0.0.0.0:68")},
+	}
+	others := map[string][]PortForward{
+		"db":  {{Protocol: "tcp", Listen: netip.MustParseAddrPort("0.0.0.0:5432"), Port: 5432}},
+		"dns": {dns},
+	}
+
+	conflicts := portConflicts([]PortForward{web, dns}, listening, others)
+	expected := []string{
+		"tcp 0.0.0.0:80 is used by a host listener on 127.0.0.1:80",
+		`udp 192.0.2.1:53 is forwarded to container "dns" as well`,
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("unexpected conflicts: %q", conflicts)
+	}
+}
+
+func TestTop(t *testing.T) {
+	p, err := parseProcessStat("42 (my (odd) cmd) S 1 42 42 0 -1 4194560 100 0 0 0 150 50 0 0 20 0 1 0 1234 10000000 256 18446744073709551615")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if p.PID != 42 || p.PPID != 1 || p.Command != "my (odd) cmd" || p.State != "S" || p.startTime != 1234 {
+		t.Errorf("unexpected process: %+v", p)
+	}
+
+	if p.CPUTime != 2*time.Second || p.Memory != ByteSize(256*os.Getpagesize()) {
+		t.Errorf("unexpected usage: %v %v", p.CPUTime, p.Memory)
+	}
+
+	if _, err := parseProcessStat("42 (cmd) S 1"); err == nil {
+		t.Errorf("expected an error for a truncated stat")
+	}
+
+	now := time.Now()
+	sampler := &processSampler{}
+	sampler.update([]ProcessInfo{{PID: 1, CPUTime: time.Second}, {PID: 2, CPUTime: time.Second, startTime: 1}}, now)
+
+	processes := []ProcessInfo{
+		{PID: 1, CPUTime: 1500 * time.Millisecond, Memory: 10},
+		{PID: 2, CPUTime: 3 * time.Second, startTime: 2, Memory: 30},
+		{PID: 3, CPUTime: time.Second, Memory: 20},
+	}
+	sampler.update(processes, now.Add(time.Second))
+
+	if processes[0].CPUPercent != 50 || processes[1].CPUPercent != 0 || processes[2].CPUPercent != 0 {
+		t.Errorf("unexpected usage: %+v", processes)
+	}
+
+	var pids []int
+	for _, p := range sortProcesses(processes, TopSortMemory, 2) {
+		pids = append(pids, p.PID)
+	}
+
+	if !reflect.DeepEqual(pids, []int{2, 3}) {
+		t.Errorf("unexpected order: %v", pids)
+	}
+
+	pids = nil
+	for _, p := range sortProcesses(processes, TopSortCPU, 0) {
+		pids = append(pids, p.PID)
+	}
+
+	if !reflect.DeepEqual(pids, []int{1, 2, 3}) {
+		t.Errorf("unexpected order: %v", pids)
+	}
+}
+
+func TestWatchContainers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxcpath")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "existing"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "existing", "config"), nil, 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	w, err := WatchContainers(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer w.Close()
+
+	expect := func(expected ContainerEvent) {
+		select {
+		case event := <-w.Events():
+			if event != expected {
+				t.Fatalf("unexpected event: %+v, expected %+v", event, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %+v", expected)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "new"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// give the watcher a chance to watch the directory before the config
+	// appears, either way it has to be reported once
+	time.Sleep(100 * time.Millisecond)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "new", "config"), []byte("lxc.uts.name = new\n"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	expect(ContainerEvent{ContainerCreated, "new"})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "existing", "config"), []byte("lxc.uts.name = existing\n"), 0644); err != nil {
+		t.Fatalf(err.Error())
+	}
+	expect(ContainerEvent{ContainerConfigChanged, "existing"})
+
+	if err := os.RemoveAll(filepath.Join(dir, "existing")); err != nil {
+		t.Fatalf(err.Error())
+	}
+	expect(ContainerEvent{ContainerDestroyed, "existing"})
+
+	w.Close()
+	if _, ok := <-w.Events(); ok {
+		t.Errorf("expected the events to be closed")
+	}
+}
+
+func TestRuntimeInfo(t *testing.T) {
+	for _, tc := range []struct {
+		version             string
+		major, minor, micro int
+		devel               bool
+	}{
+		{"4.0.12", 4, 0, 12, false},
+		{"5.0.0~git2209-g5a7b9ce67", 5, 0, 0, false},
+		{"6.0.0 (devel)", 6, 0, 0, true},
+		{"3.1", 3, 1, 0, false},
+	} {
+		major, minor, micro, devel := parseVersion(tc.version)
+		if major != tc.major || minor != tc.minor || micro != tc.micro || devel != tc.devel {
+			t.Errorf("%s: unexpected %d.%d.%d %v", tc.version, major, minor, micro, devel)
+		}
+	}
+
+	r := &Runtime{Major: 4, Minor: 0, Micro: 12}
+	for _, tc := range []struct {
+		major, minor, micro int
+		ok                  bool
+	}{
+		{4, 0, 12, true},
+		{4, 0, 13, false},
+		{3, 9, 99, true},
+		{4, 1, 0, false},
+		{5, 0, 0, false},
+	} {
+		if ok := r.AtLeast(tc.major, tc.minor, tc.micro); ok != tc.ok {
+			t.Errorf("%d.%d.%d: expected %v", tc.major, tc.minor, tc.micro, tc.ok)
+		}
+	}
+
+	info := RuntimeInfo()
+	if RuntimeInfo() != info {
+		t.Errorf("expected the runtime information to be cached")
+	}
+
+	if info.Version != Version() || !RuntimeLiblxcVersionAtLeast(info.Version, info.Major, info.Minor, info.Micro) {
+		t.Errorf("unexpected version: %+v", info)
+	}
+
+	if refreshed := RefreshRuntimeInfo(); refreshed == info || RuntimeInfo() != refreshed {
+		t.Errorf("expected the runtime information to be refreshed")
+	}
+}
+
+func TestStatsReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"memory.current": "104857600\n",
+		"memory.max":     "max\n",
+		"memory.stat":    "anon 52428800\nfile 41943040\nkernel_stack 0\n",
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\nnr_periods 0\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	r := newStatsReader(true, dir, dir)
+	defer r.Close()
+
+	var mem MemStats
+	if err := r.ReadMemoryStatsInto(&mem); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := MemStats{Usage: 100 * MB, Limit: ByteSize(math.MaxInt64), Anon: 50 * MB, File: 40 * MB}
+	if mem != expected {
+		t.Errorf("unexpected memory stats: %+v", mem)
+	}
+
+	var cpu CPUStats
+	if err := r.ReadCPUStatsInto(&cpu); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if cpu != (CPUStats{Usage: 1500 * time.Millisecond, User: time.Second, System: 500 * time.Millisecond}) {
+		t.Errorf("unexpected cpu stats: %+v", cpu)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.ReadMemoryStatsInto(&mem)
+		r.ReadCPUStatsInto(&cpu)
+	})
+	if allocs != 0 {
+		t.Errorf("unexpected allocations: %v", allocs)
+	}
+
+	v1, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(v1)
+
+	for name, content := range map[string]string{
+		"memory.usage_in_bytes":       "1048576\n",
+		"memory.limit_in_bytes":       "9223372036854771712\n",
+		"memory.memsw.usage_in_bytes": "3145728\n",
+		"memory.stat":                 "cache 1\nrss 2\ntotal_cache 4096\ntotal_rss 8192\n",
+		"cpuacct.usage":               "2000000000\n",
+		"cpuacct.stat":                "user 150\nsystem 50\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(v1, name), []byte(content), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	r = newStatsReader(false, v1, v1)
+	defer r.Close()
+
+	if err := r.ReadMemoryStatsInto(&mem); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected = MemStats{Usage: MB, Limit: ByteSize(math.MaxInt64), Swap: 2 * MB, Anon: 8 * KB, File: 4 * KB}
+	if mem != expected {
+		t.Errorf("unexpected memory stats: %+v", mem)
+	}
+
+	if err := r.ReadCPUStatsInto(&cpu); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if cpu != (CPUStats{Usage: 2 * time.Second, User: 1500 * time.Millisecond, System: 500 * time.Millisecond}) {
+		t.Errorf("unexpected cpu stats: %+v", cpu)
+	}
+}
+
+func TestOperationPolicy(t *testing.T) {
+	defer SetOperationPolicy(OperationPolicy{})
+
+	if !IsTransient(fmt.Errorf("%w: console", unix.EAGAIN)) || !IsTransient(unix.EBUSY) || IsTransient(ErrStartFailed) {
+		t.Errorf("unexpected transient errors")
+	}
+
+	c := &Container{}
+	if c.Retryable(ErrNotDefined) || c.Retryable(fmt.Errorf("%w: no", ErrOperationDenied)) || !c.Retryable(unix.EAGAIN) {
+		t.Errorf("unexpected retryable errors")
+	}
+
+	// the errors returned by the checks of the operations
+	if err := c.makeSure(isDefined); !errors.Is(err, ErrNotDefined) || c.Retryable(err) {
+		t.Errorf("expected %v not to be retryable", err)
+	}
+
+	attempts := 0
+	fail := func() error {
+		attempts++
+		return unix.EBUSY
+	}
+
+	if err := c.retry(fail); err != unix.EBUSY || attempts != 1 {
+		t.Errorf("expected no retries by default, got %d attempts", attempts)
+	}
+
+	SetOperationPolicy(OperationPolicy{Attempts: 3, Backoff: time.Millisecond})
+
+	attempts = 0
+	if err := c.retry(fail); err != unix.EBUSY || attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	err := c.retry(func() error {
+		attempts++
+		if attempts < 2 {
+			return unix.EAGAIN
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("expected success on the second attempt, got %v after %d", err, attempts)
+	}
+
+	SetOperationPolicy(OperationPolicy{Attempts: 3, Backoff: time.Millisecond, Retryable: func(c *Container, err error) bool { return false }})
+
+	attempts = 0
+	if err := c.retry(fail); err != unix.EBUSY || attempts != 1 {
+		t.Errorf("expected the custom check to stop retrying, got %d attempts", attempts)
+	}
+}
+
+func TestInheritableFds(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer f.Close()
+
+	fd := int(f.Fd())
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_SETFD, 0); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	fds, err := InheritableFds()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	found := false
+	for _, n := range fds {
+		found = found || n == fd
+	}
+	if !found {
+		t.Fatalf("expected fd %d in %v", fd, fds)
+	}
+
+	if err := closeInheritedFds(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	fds, err = InheritableFds()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, n := range fds {
+		if n == fd {
+			t.Errorf("expected fd %d to be close-on-exec", fd)
+		}
+	}
+}
+
+func TestRotateLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lxc.log")
+	opts := LogRotateOptions{MaxSize: 4, Keep: 2}
+	now := time.Now()
+
+	if rotated, err := rotateLogFile(path, opts, now); err != nil || rotated {
+		t.Fatalf("expected a missing log not to rotate, got %v, %v", rotated, err)
+	}
+
+	for i, content := range []string{"first", "second", "third"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		rotated, err := rotateLogFile(path, opts, now)
+		if err != nil || !rotated {
+			t.Fatalf("expected rotation %d, got %v, %v", i, rotated, err)
+		}
+	}
+
+	for name, expected := range map[string]string{path: "", path + ".1": "third", path + ".2": "second"} {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if string(data) != expected {
+			t.Errorf("expected %q in %s, got %q", expected, name, data)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated logs")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("abc"), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if rotated, _ := rotateLogFile(path, opts, now); rotated {
+		t.Errorf("expected a small log not to rotate")
+	}
+
+	opts.MaxAge = time.Hour
+	if rotated, _ := rotateLogFile(path, opts, now.Add(2*time.Hour)); !rotated {
+		t.Errorf("expected an old log to rotate")
+	}
+
+	if _, err := rotateLogFile(path, LogRotateOptions{}, now); err == nil {
+		t.Errorf("expected an error without rotated logs to keep")
+	}
+}
+
+func TestLogRotator(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "console.log")
+
+	r := NewLogRotator(LogRotateOptions{MaxSize: 4, Keep: 2, Compress: true})
+	r.AddFile(path)
+	r.AddFile(filepath.Join(dir, "missing.log"))
+
+	var errs []error
+	r.OnError = func(path string, err error) {
+		errs = append(errs, err)
+	}
+
+	for _, content := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if rotated := r.Rotate(); len(rotated) != 1 || rotated[0] != path {
+			t.Fatalf("expected %s to rotate, got %v", path, rotated)
+		}
+	}
+
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	for name, expected := range map[string]string{path + ".1.gz": "second", path + ".2.gz": "first"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		data, err := ioutil.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if string(data) != expected {
+			t.Errorf("expected %q in %s, got %q", expected, name, data)
+		}
+	}
+
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected the uncompressed log to be removed")
+	}
+
+	r.RemoveFile(path)
+	if err := ioutil.WriteFile(path, []byte("third"), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if rotated := r.Rotate(); len(rotated) != 0 {
+		t.Errorf("expected no rotation after removing the file, got %v", rotated)
+	}
+}
+
+func TestVolatileConfigKeys(t *testing.T) {
+	golden := parseConfigLines(`
+lxc.uts.name = lorem
+lxc.net.0.type = veth
+lxc.net.0.hwaddr = 00:16:3e:aa:bb:cc
+`)
+	current := []KeyValue{
+		{"lxc.uts.name", "lorem"},
+		{"lxc.net.0.type", "veth"},
+		{"lxc.net.0.hwaddr", "00:16:3e:11:22:33"},
+		{"lxc.start.auto", "1"},
+	}
+
+	diff := compareConfigs(withoutVolatile(golden), withoutVolatile(current))
+
+	expected := ConfigDiff{Added: []KeyValue{{"lxc.start.auto", "1"}}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected only lxc.start.auto to drift, got %+v", diff)
+	}
+
+	if !isVolatileConfigKey("lxc.network.1.hwaddr") || isVolatileConfigKey("lxc.net.0.link") {
+		t.Errorf("isVolatileConfigKey failed")
+	}
+}
+
+func TestRandomMAC(t *testing.T) {
+	a, err := randomMAC()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	b, err := randomMAC()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, mac := range []string{a, b} {
+		if _, err := net.ParseMAC(mac); err != nil || !strings.HasPrefix(mac, "00:16:3e:") {
+			t.Errorf("unexpected MAC %q", mac)
+		}
+	}
+
+	if volatileHWAddrKey(1) != "volatile.net.1.hwaddr" {
+		t.Errorf("unexpected volatile key %q", volatileHWAddrKey(1))
+	}
+}
+
+func TestInspectConfig(t *testing.T) {
+	var info InspectInfo
+	inspectConfig(&info, []KeyValue{
+		{"lxc.uts.name", "lorem"},
+		{"lxc.arch", "linux64"},
+		{"lxc.start.auto", "1"},
+		{"lxc.rootfs.path", "dir:/var/lib/lxc/lorem/rootfs"},
+		{"lxc.mount.entry", "proc proc proc defaults 0 0"},
+		{"lxc.cgroup2.memory.max", "1G"},
+	})
+
+	expected := InspectInfo{
+		Rootfs: "dir:/var/lib/lxc/lorem/rootfs",
+		Config: InspectConfig{Hostname: "lorem", Arch: "linux64", Autostart: true},
+		Mounts: []string{"proc proc proc defaults 0 0"},
+		Limits: map[string]string{"lxc.cgroup2.memory.max": "1G"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("inspectConfig failed: %+v", info)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.Contains(string(data), `"hostname":"lorem"`) || strings.Contains(string(data), "usage") {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	all := NewEventChannel(8)
+	bus.Subscribe(EventFilter{}, all)
+
+	var buf bytes.Buffer
+	unsubscribe := bus.Subscribe(EventFilter{Types: []EventType{EventAudit}, Containers: []string{"lorem"}}, NewEventWriter(&buf))
+
+	var calls []EventType
+	bus.Subscribe(EventFilter{Types: []EventType{EventOOM, EventState}}, EventFunc(func(e Event) error {
+		calls = append(calls, e.Type)
+		return nil
+	}))
+
+	if err := bus.Record(AuditRecord{Operation: "Start", Container: "lorem"}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	bus.Record(AuditRecord{Operation: "Start", Container: "ipsum"})
+	bus.Publish(Event{Type: EventState, Container: "lorem", State: "RUNNING"})
+
+	unsubscribe()
+	bus.Record(AuditRecord{Operation: "Stop", Container: "lorem"})
+
+	if len(all.Events()) != 4 {
+		t.Errorf("expected 4 events on the channel, got %d", len(all.Events()))
+	}
+
+	e := <-all.Events()
+	if e.Type != EventAudit || e.Audit == nil || e.Audit.Operation != "Start" || e.Time.IsZero() {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"operation":"Start"`) {
+		t.Errorf("unexpected written events %q", buf.String())
+	}
+
+	if !reflect.DeepEqual(calls, []EventType{EventState}) {
+		t.Errorf("unexpected callback events %v", calls)
+	}
+}
+
+func TestParseOOMKills(t *testing.T) {
+	if n := parseOOMKills([]string{"low 0", "high 0", "max 3", "oom 2", "oom_kill 2"}); n != 2 {
+		t.Errorf("expected 2 OOM kills, got %d", n)
+	}
+
+	if n := parseOOMKills([]string{"oom_kill_disable 0\nunder_oom 0\noom_kill 5"}); n != 5 {
+		t.Errorf("expected 5 OOM kills, got %d", n)
+	}
+
+	if n := parseOOMKills(nil); n != -1 {
+		t.Errorf("expected -1 without counter, got %d", n)
+	}
+}
+
+func TestEventHandler(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	server := httptest.NewServer(EventHandler(bus, EventFilter{Containers: []string{"lorem", "ipsum"}}))
+	defer server.Close()
+
+	// publish until the client subscribed, events before are dropped
+	publish := func(stop chan struct{}) {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			bus.Publish(Event{Type: EventAudit, Container: "lorem"})
+			bus.Publish(Event{Type: EventState, Container: "dolor", State: "RUNNING"})
+			bus.Publish(Event{Type: EventState, Container: "lorem", State: "RUNNING"})
+		}
+	}
+
+	stop := make(chan struct{})
+	go publish(stop)
+
+	resp, err := http.Get(server.URL + "?type=state&container=lorem&container=dolor")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	resp.Body.Close()
+	close(stop)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if line != "event: state\n" || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected SSE stream %q", line)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "GET /?type=state HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+	reader = bufio.NewReader(conn)
+	wsResp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if wsResp.StatusCode != http.StatusSwitchingProtocols || wsResp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %d %v", wsResp.StatusCode, wsResp.Header)
+	}
+
+	stop = make(chan struct{})
+	go publish(stop)
+	defer close(stop)
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if header[0] != 0x81 || e.Type != EventState || e.Container != "lorem" {
+		t.Errorf("unexpected websocket message %x %s", header, payload)
+	}
+}
+
+func TestSessionRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewSessionRecorder(&buf, 80, 24)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	r.RecordInput = true
+
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer stdin.Close()
+	defer input.Close()
+
+	output, stdout, err := os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer output.Close()
+	defer stdout.Close()
+
+	fd, restore, err := r.interpose(stdin.Fd(), stdout.Fd())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// the session reads the input from its pty and writes to it, the
+	// euro sign split across writes
+	if _, err := input.Write([]byte("ls\r")); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	line := make([]byte, 16)
+	n, err := unix.Read(int(fd), line)
+	if err != nil || !strings.HasPrefix(string(line[:n]), "ls") {
+		t.Fatalf("expected the input on the pty, got %q, %v", line[:n], err)
+	}
+
+	euro := []byte("€")
+	unix.Write(int(fd), append([]byte("hi "), euro[:1]...))
+	unix.Write(int(fd), euro[1:])
+
+	received := ""
+	for !strings.Contains(received, "hi €") {
+		n, err := output.Read(line)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		received += string(line[:n])
+	}
+	restore()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	var header asciicastHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil || header.Version != 2 || header.Width != 80 {
+		t.Fatalf("unexpected header %q", lines[0])
+	}
+
+	var in, out string
+	for _, l := range lines[1:] {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(l), &event); err != nil || len(event) != 3 {
+			t.Fatalf("unexpected event %q", l)
+		}
+
+		switch event[1] {
+		case "i":
+			in += event[2].(string)
+		case "o":
+			out += event[2].(string)
+		}
+	}
+
+	if in != "ls\r" || !strings.Contains(out, "hi €") {
+		t.Errorf("unexpected recording: input %q, output %q", in, out)
+	}
+}
+
+func TestCommandsScript(t *testing.T) {
+	script := commandsScript([][]string{
+		{"apt-get", "install", "-y", "curl"},
+		{"sh", "-c", "echo $HOME; exit 3"},
+		{"echo", "it's", ""},
+		{"false"},
+	})
+
+	expected := `apt-get install -y curl </dev/null || exit $?
+sh -c 'echo $HOME; exit 3' </dev/null || exit $?
+echo 'it'\''s' '' </dev/null || exit $?
+false </dev/null || exit $?
+`
+	if script != expected {
+		t.Fatalf("unexpected script:\n%s", script)
+	}
+
+	cmd := exec.Command("/bin/sh", "-s")
+	cmd.Stdin = strings.NewReader(commandsScript([][]string{{"true"}, {"sh", "-c", "exit 3"}, {"sh", "-c", "exit 4"}}))
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("expected exit status 3 from the second command, got %v", err)
+	}
+}
+
+func TestListenEnv(t *testing.T) {
+	expected := []KeyValue{
+		{"lxc.environment", "LISTEN_PID=1"},
+		{"lxc.environment", "LISTEN_FDS=2"},
+		{"lxc.environment", "LISTEN_FDNAMES=http:https"},
+	}
+	if env := listenEnv([]string{"http", "https"}); !reflect.DeepEqual(env, expected) {
+		t.Errorf("unexpected environment %v", env)
+	}
+
+	for name, valid := range map[string]bool{"http": true, "web.socket": true, "": false, "a:b": false, "tab\t": false} {
+		if validFdName(name) != valid {
+			t.Errorf("expected validFdName(%q) to be %v", name, valid)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "fds")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewContainer("fds", dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	if err := c.StartWithFds(map[string]*os.File{"a:b": os.Stdin}); err == nil {
+		t.Errorf("expected an error for an invalid fd name")
+	}
+
+	// the monitor fails to start the undefined container
+	if err := c.StartWithFds(map[string]*os.File{"stdin": os.Stdin}); err == nil || !strings.HasPrefix(err.Error(), ErrStartFailed.Error()) {
+		t.Errorf("expected ErrStartFailed, got %v", err)
+	}
+}
+
+func TestActivatorTarget(t *testing.T) {
+	addrs := []netip.Addr{netip.MustParseAddr("fd42::5"), netip.MustParseAddr("10.0.3.5")}
+	if target, err := targetAddr(addrs, 80); err != nil || target != "10.0.3.5:80" {
+		t.Errorf("expected the IPv4 address, got %q, %v", target, err)
+	}
+
+	if target, err := targetAddr(addrs[:1], 80); err != nil || target != "[fd42::5]:80" {
+		t.Errorf("expected the IPv6 address, got %q, %v", target, err)
+	}
+
+	if _, err := targetAddr(nil, 80); err != ErrIPAddresses {
+		t.Errorf("expected ErrIPAddresses without addresses, got %v", err)
+	}
+
+	opts := DefaultActivatorOptions
+	opts.Address = "127.0.0.1:0"
+	if _, err := NewActivator(&Container{}, opts); err == nil {
+		t.Errorf("expected an error without target")
+	}
+}
+
+func TestIdleTracker(t *testing.T) {
+	var tracker idleTracker
+	now := time.Now()
+
+	if idle := tracker.observe(now, 100, 1); idle != 0 {
+		t.Errorf("expected the first sample to be active, got %s", idle)
+	}
+
+	if idle := tracker.observe(now.Add(time.Minute), 100, 1); idle != time.Minute {
+		t.Errorf("expected a minute idle, got %s", idle)
+	}
+
+	if idle := tracker.observe(now.Add(2*time.Minute), 200, 1); idle != 0 {
+		t.Errorf("expected traffic to count as activity, got %s", idle)
+	}
+
+	if idle := tracker.observe(now.Add(3*time.Minute), 200, 3); idle != 0 {
+		t.Errorf("expected processes to count as activity, got %s", idle)
+	}
+
+	if idle := tracker.observe(now.Add(5*time.Minute), 200, 1); idle != 2*time.Minute {
+		t.Errorf("expected two minutes idle, got %s", idle)
+	}
+
+	tracker.reset()
+	if idle := tracker.observe(now.Add(6*time.Minute), 200, 1); idle != 0 {
+		t.Errorf("expected a reset tracker to start over, got %s", idle)
+	}
+
+	if _, err := (&Container{}).WatchIdle(IdlePolicy{Timeout: time.Minute, Interval: time.Second}); err != ErrInsufficientNumberOfArguments {
+		t.Errorf("expected an error without activity to watch, got %v", err)
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-lxc-volumes")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	volume, err := CreateVolume("data", DefaultVolumeOptions, dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if info, err := os.Stat(volume.Path); err != nil || !info.IsDir() {
+		t.Errorf("expected the volume directory to be created: %v", err)
+	}
+
+	if _, err := CreateVolume("data", DefaultVolumeOptions, dir); err == nil {
+		t.Errorf("expected an error for an existing volume")
+	}
+
+	if _, err := CreateVolume("../escape", DefaultVolumeOptions, dir); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+
+	external := filepath.Join(dir, "external")
+	if err := os.Mkdir(external, 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	opts := DefaultVolumeOptions
+	opts.Path = external
+	if _, err := CreateVolume("shared", opts, dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	volumes, err := Volumes(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(volumes) != 2 || volumes[0].Name != "data" || volumes[1].Name != "shared" || !volumes[1].External || volumes[1].Path != external {
+		t.Errorf("unexpected volumes: %+v", volumes)
+	}
+
+	if _, err := LoadVolume("missing", dir); err == nil {
+		t.Errorf("expected an error for a missing volume")
+	}
+
+	volume.Attachments = []VolumeAttachment{{Container: "web", Target: "/srv"}, {Container: "gone", Target: "/srv"}}
+	if volumeOrphaned(volume, map[string]string{"web": "", "db": ""}) {
+		t.Errorf("expected a volume attached to a defined container not to be orphaned")
+	}
+	if !volumeOrphaned(volume, map[string]string{"db": ""}) {
+		t.Errorf("expected a volume of destroyed containers to be orphaned")
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	volume.Attachments = []VolumeAttachment{{Container: "web", UUID: uuid, Target: "/srv"}}
+	if volumeOrphaned(volume, map[string]string{"frontend": uuid}) {
+		t.Errorf("expected a volume attached to a renamed container not to be orphaned")
+	}
+	if !volumeOrphaned(volume, map[string]string{"web": ""}) {
+		t.Errorf("expected a volume of a destroyed container to be orphaned by a new one of the same name")
+	}
+
+	entry := volumeMountEntry(volume, VolumeAttachment{Target: "/srv/data", ReadOnly: true, Idmapped: true})
+	if entry != volume.Path+" srv/data none bind,create=dir,ro,idmap=container 0 0" {
+		t.Errorf("unexpected mount entry: %q", entry)
+	}
+
+	if err := removeVolumeData(volumes[1]); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := os.Stat(external); err != nil {
+		t.Errorf("expected an external directory to be kept: %v", err)
+	}
+}
+
+func TestTmpfsMountEntry(t *testing.T) {
+	entry, err := tmpfsMountEntry("/dev/shm", DefaultShmOptions)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if entry != "tmpfs dev/shm tmpfs rw,nosuid,nodev,size=67108864,mode=1777,create=dir 0 0" {
+		t.Errorf("unexpected mount entry: %q", entry)
+	}
+
+	path, opts, ok := parseTmpfsMountEntry(entry)
+	if !ok || path != "/dev/shm" || !reflect.DeepEqual(opts, DefaultShmOptions) {
+		t.Errorf("unexpected parsed mount entry: %q %+v", path, opts)
+	}
+
+	_, opts, ok = parseTmpfsMountEntry("tmpfs tmp tmpfs rw,noexec,size=512m,nr_inodes=1000 0 0")
+	if !ok || opts.Size != 512*MB || opts.Inodes != 1000 || !opts.NoExec {
+		t.Errorf("unexpected parsed mount entry: %+v", opts)
+	}
+
+	if _, _, ok := parseTmpfsMountEntry("/srv srv none bind 0 0"); ok {
+		t.Errorf("expected a bind mount not to be parsed as tmpfs")
+	}
+
+	for _, path := range []string{"tmp", "/", "/tmp/", "/my tmp"} {
+		if _, err := tmpfsMountEntry(path, DefaultTmpOptions); err == nil {
+			t.Errorf("expected an error for path %q", path)
+		}
+	}
+
+	if _, err := tmpfsMountEntry("/tmp", TmpfsOptions{Size: -1}); err == nil {
+		t.Errorf("expected an error for a negative size")
+	}
+
+	if _, err := tmpfsMountEntry("/tmp", TmpfsOptions{Mode: os.ModeDir | 0755}); err == nil {
+		t.Errorf("expected an error for an invalid mode")
+	}
+}
+
+func TestSwapLimitItem(t *testing.T) {
+	if kv, err := swapLimitItem(true, 0, 512*MB); err != nil || kv != (KeyValue{"lxc.cgroup2.memory.swap.max", "536870912"}) {
+		t.Errorf("unexpected cgroup2 limit: %v %v", kv, err)
+	}
+
+	if kv, err := swapLimitItem(false, GB, 512*MB); err != nil || kv != (KeyValue{"lxc.cgroup.memory.memsw.limit_in_bytes", "1610612736"}) {
+		t.Errorf("unexpected cgroup1 limit: %v %v", kv, err)
+	}
+
+	if _, err := swapLimitItem(false, 0, 512*MB); err == nil {
+		t.Errorf("expected an error for cgroup1 without a memory limit")
+	}
+
+	dir, err := ioutil.TempDir("", "go-lxc-swap")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "web"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, ok, err := loadSwapState(dir, "web"); ok || err != nil {
+		t.Errorf("expected no swap state: %v", err)
+	}
+
+	state := swapState{Options: DefaultSwapOptions, Device: "/dev/zram3"}
+	if err := saveSwapState(dir, "web", state); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	loaded, ok, err := loadSwapState(dir, "web")
+	if !ok || err != nil || loaded != state {
+		t.Errorf("unexpected swap state: %+v %v", loaded, err)
+	}
+}
+
+func TestTopology(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-lxc-topology")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"devices/system/cpu/online":          "0-2\n",
+		"devices/system/node/node0/cpulist":  "0-1\n",
+		"devices/system/node/node0/meminfo":  "Node 0 MemTotal:        1024 kB\nNode 0 MemFree:          512 kB\n",
+		"devices/system/node/node0/distance": "10 21\n",
+		"devices/system/node/node1/cpulist":  "2-3\n",
+		"devices/system/node/node1/distance": "21 10\n",
+	}
+	for cpu, siblings := range []string{"0-1", "0-1", "2"} {
+		prefix := fmt.Sprintf("devices/system/cpu/cpu%d/topology/", cpu)
+		files[prefix+"physical_package_id"] = strconv.Itoa(cpu / 2)
+		files[prefix+"core_id"] = "0"
+		files[prefix+"thread_siblings_list"] = siblings
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	info, err := topology(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(info.Nodes) != 2 || info.Nodes[0].Memory != 1024*KB || !reflect.DeepEqual(info.Nodes[1].Distances, []int{21, 10}) {
+		t.Errorf("unexpected nodes: %+v", info.Nodes)
+	}
+
+	if len(info.CPUs) != 3 || info.CPUs[2].Node != 1 || info.CPUs[2].Package != 1 || !reflect.DeepEqual(info.CPUs[0].Siblings, []int{0, 1}) {
+		t.Errorf("unexpected CPUs: %+v", info.CPUs)
+	}
+
+	// cpu3 is offline
+	cpus, mems, err := nodeCPUSet(info, 1)
+	if err != nil || cpus != "2" || mems != "1" {
+		t.Errorf("unexpected cpuset of node 1: %q %q %v", cpus, mems, err)
+	}
+
+	if _, _, err := nodeCPUSet(info, 2); err == nil {
+		t.Errorf("expected an error for a missing node")
+	}
+
+	if list := formatCPUList([]int{8, 0, 1, 2, 3, 5}); list != "0-3,5,8" {
+		t.Errorf("unexpected CPU list: %q", list)
+	}
+
+	if n, err := parseCPUList("0-3,8"); err != nil || n != 5 {
+		t.Errorf("unexpected CPU count: %d %v", n, err)
+	}
+}
+
+func TestRealtimeItems(t *testing.T) {
+	opts := DefaultRealtimeOptions
+	opts.CPUTime = 200 * time.Millisecond
+
+	items, err := realtimeItems(opts, 950*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []KeyValue{
+		{"lxc.cgroup.cpu.rt_period_us", "1000000"},
+		{"lxc.cgroup.cpu.rt_runtime_us", "100000"},
+		{"lxc.prlimit.rtprio", "50:50"},
+		{"lxc.prlimit.rttime", "200000:200000"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	values := make([]string, len(items))
+	for i, kv := range items {
+		values[i] = kv.Value
+	}
+	if parsed, ok, err := parseRealtime(values); !ok || err != nil || parsed != opts {
+		t.Errorf("unexpected parsed options: %+v %v", parsed, err)
+	}
+
+	if _, ok, err := parseRealtime([]string{"", "", "", ""}); ok || err != nil {
+		t.Errorf("expected no options: %v", err)
+	}
+
+	for _, invalid := range []RealtimeOptions{
+		{Runtime: 960 * time.Millisecond, Period: time.Second, Priority: 1},
+		{Runtime: 2 * time.Second, Period: time.Second},
+		{Runtime: time.Millisecond, Period: 2 * time.Second},
+		{Runtime: time.Millisecond, Period: time.Second, Priority: 100},
+		{Runtime: 0, Period: time.Second, Priority: 1},
+	} {
+		if _, err := realtimeItems(invalid, 950*time.Millisecond, time.Second); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+
+	// unlimited on the host
+	if _, err := realtimeItems(RealtimeOptions{Runtime: time.Second, Period: time.Second, Priority: 1}, -1, time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReclaimAmount(t *testing.T) {
+	if amount := reclaimAmount(GB, 256*MB); amount != 768*MB {
+		t.Errorf("unexpected amount: %s", amount)
+	}
+
+	if amount := reclaimAmount(128*MB, 256*MB); amount != 0 {
+		t.Errorf("expected nothing to reclaim below the resident memory, got %s", amount)
+	}
+
+	if squeezeKey(true) != "memory.high" || squeezeKey(false) != "memory.limit_in_bytes" {
+		t.Errorf("unexpected squeeze keys")
+	}
+
+	if err := (&Container{}).Thaw(); err != ErrNotDefined {
+		t.Errorf("expected ErrNotDefined, got %v", err)
+	}
+}
+
+func TestConfigTransaction(t *testing.T) {
+	c := &Container{}
+
+	tx := c.Begin()
+	if err := tx.Set("lxc.uts.name", "rubik"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := tx.Commit(); err != ErrNotDefined {
+		t.Errorf("expected ErrNotDefined, got %v", err)
+	}
+
+	if err := tx.Commit(); err != ErrTransactionDone {
+		t.Errorf("expected ErrTransactionDone, got %v", err)
+	}
+
+	tx = c.Begin()
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := tx.Clear("lxc.uts.name"); err != ErrTransactionDone {
+		t.Errorf("expected ErrTransactionDone, got %v", err)
+	}
+}
+
+func TestSetConfigItemsNotDefined(t *testing.T) {
+	c := &Container{}
+
+	if err := c.SetConfigItems(map[string]string{"lxc.uts.name": "rubik"}); err != ErrNotDefined {
+		t.Errorf("expected ErrNotDefined, got %v", err)
+	}
+}
+
+func TestWarmCustomizationCommands(t *testing.T) {
+	custom := WarmCustomization{Hostname: "warm", IPv4: "192.0.2.10/24"}
+
+	expected := [][]string{
+		{"/bin/sh", "-c", `hostname "$1" && echo "$1" > /etc/hostname`, "sh", "warm"},
+		{"ip", "addr", "add", "192.0.2.10/24", "dev", "eth0"},
+	}
+	if commands := customizeCommands(custom); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+
+	expected = [][]string{
+		{"ip", "addr", "del", "192.0.2.10/24", "dev", "eth0"},
+		{"/bin/sh", "-c", `hostname "$1" && echo "$1" > /etc/hostname`, "sh", "lorem-warm-1"},
+	}
+	if commands := revertCommands(custom, "lorem-warm-1"); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+
+	custom = WarmCustomization{IPv4: "192.0.2.10/24", Interface: "net1"}
+	if commands := revertCommands(custom, "lorem-warm-1"); len(commands) != 1 || commands[0][5] != "net1" {
+		t.Errorf("expected only the address of net1 to be removed, got %q", commands)
+	}
+
+	if commands := customizeCommands(WarmCustomization{}); len(commands) != 0 {
+		t.Errorf("expected no commands, got %q", commands)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	uuid, err := newUUID()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !validUUID(uuid) || uuid[14] != '4' {
+		t.Errorf("unexpected UUID: %q", uuid)
+	}
+
+	for _, invalid := range []string{"", "not-a-uuid", "1B4E28BA-2FA1-11D2-883F-B9A761BDE3FB", "1b4e28ba2fa111d2883fb9a761bde3fb0000"} {
+		if validUUID(invalid) {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "uuid")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "rubik"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if id, err := readUUID(dir, "rubik"); err != nil || id != "" {
+		t.Errorf("expected no UUID, got %q, %v", id, err)
+	}
+
+	if err := writeUUID(dir, "rubik", uuid); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if id, err := readUUID(dir, "rubik"); err != nil || id != uuid {
+		t.Errorf("expected %q, got %q, %v", uuid, id, err)
+	}
+
+	if _, err := LookupByUUID(dir, "rubik"); err == nil {
+		t.Errorf("expected an error for an invalid UUID")
+	}
+
+	// renaming and restoring snapshots keep the identity
+	for _, file := range []string{"volatile.json", "swap.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "rubik", file), []byte("{}\n"), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	c, err := NewContainer("rubik", dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Release()
+
+	restoreIdentity := c.keepIdentity()
+
+	if err := os.Mkdir(filepath.Join(dir, "cube"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := restoreIdentity("cube"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, file := range []string{"uuid", "volatile.json", "swap.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "cube", file)); err != nil {
+			t.Errorf("expected %s to be kept: %v", file, err)
+		}
+	}
+}
+
+func TestOwner(t *testing.T) {
+	for _, valid := range []string{"billing", "billing/api", "team-1/svc_a.v2"} {
+		if !validOwner(valid) {
+			t.Errorf("expected %q to be valid", valid)
+		}
+	}
+
+	for _, invalid := range []string{"", "/billing", "billing/", "billing//api", "billing/..", "bill ing"} {
+		if validOwner(invalid) {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+
+	if !ownedBy("billing/api", "billing") || !ownedBy("billing", "billing") || ownedBy("billing-eu", "billing") {
+		t.Errorf("unexpected ownership")
+	}
+
+	dir, err := ioutil.TempDir("", "owner")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "rubik"), 0755); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := writeOwner(dir, "rubik", "billing/api"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if owner, err := readOwner(dir, "rubik"); err != nil || owner != "billing/api" {
+		t.Errorf("expected billing/api, got %q, %v", owner, err)
+	}
+
+	if err := writeOwner(dir, "rubik", ""); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if owner, err := readOwner(dir, "rubik"); err != nil || owner != "" {
+		t.Errorf("expected no owner, got %q, %v", owner, err)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "projects")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	project := Project{Name: "billing/api", Memory: 4 * GB, CPUs: 2}
+	if err := SaveProject(project, dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := SaveProject(Project{Name: "billing/../x"}, dir); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+
+	loaded, err := LoadProject("billing/api", dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(loaded, project) {
+		t.Errorf("expected %+v, got %+v", project, loaded)
+	}
+
+	if names, err := Projects(dir); err != nil || !reflect.DeepEqual(names, []string{"billing/api"}) {
+		t.Errorf("unexpected projects: %v, %v", names, err)
+	}
+
+	projects, err := ownerProjects("billing/api/worker", dir)
+	if err != nil || len(projects) != 1 || projects[0].Name != "billing/api" {
+		t.Errorf("unexpected projects of the owner: %+v, %v", projects, err)
+	}
+
+	if err := projectQuota("billing", "memory", float64(GB), float64(3*GB), float64(4*GB)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := projectQuota("billing", "memory", float64(2*GB), float64(3*GB), float64(4*GB)); err == nil {
+		t.Errorf("expected the memory quota to be exceeded")
+	}
+
+	if err := projectQuota("billing", "cpu", 0, 0, 2); err == nil {
+		t.Errorf("expected an unlimited container to exceed the quota")
+	}
+
+	if err := projectQuota("billing", "cpu", 0, 0, 0); err != nil {
+		t.Errorf("unexpected error without quota: %v", err)
+	}
+
+	if err := DeleteProject("billing/api", dir); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, err := LoadProject("billing/api", dir); err == nil {
+		t.Errorf("expected the project to be deleted")
+	}
+}
+
+func TestHookPeer(t *testing.T) {
+	for _, hook := range DefaultHookTypes {
+		if hook == "pre-mount" || hook == "mount" || hook == "autodev" || hook == "start" {
+			t.Errorf("%s hooks can't reach the socket", hook)
+		}
+	}
+
+	socket := fmt.Sprintf("@go-lxc-test-hook/%d", os.Getpid())
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conn.Close()
+
+	if !trustedPeer(conn) {
+		t.Errorf("expected a connection of this process to be trusted")
+	}
+
+	if trustedUID(uint32(os.Geteuid())+1) && os.Geteuid() != -1 {
+		t.Errorf("expected another user not to be trusted")
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	for _, arg := range []string{hookHelperArg, attachHelperArg, startFdsHelperArg, swapHelperArg} {
+		if _, ok := helpers[arg]; !ok {
+			t.Errorf("%s isn't dispatched", arg)
+		}
+	}
+
+	// liblxc runs hooks through the shell
+	socket := fmt.Sprintf("@go-lxc-hook/%d/%s/%s", os.Getpid(), "/var/lib/it's lxc", "web$(id)")
+	output, err := exec.Command("/bin/sh", "-c", shellQuote([]string{"printf", "%s", socket})).Output()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if string(output) != socket {
+		t.Errorf("expected %q, got %q", socket, output)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// lxcpathWatchMask reports container directories appearing and going.
+	lxcpathWatchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+	// containerWatchMask reports the config file being written or removed.
+	containerWatchMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_DELETE | unix.IN_MOVED_FROM
+)
+
+// ContainerEventType is the kind of change a ContainerWatcher reports.
+type ContainerEventType string
+
+const (
+	// ContainerCreated reports a container whose config appeared.
+	ContainerCreated ContainerEventType = "created"
+	// ContainerDestroyed reports a container whose config or directory was
+	// removed.
+	ContainerDestroyed ContainerEventType = "destroyed"
+	// ContainerConfigChanged reports the config of a container was written.
+	ContainerConfigChanged ContainerEventType = "config-changed"
+)
+
+// ContainerEvent reports a container of an lxcpath changing.
+type ContainerEvent struct {
+	Type ContainerEventType
+	// Name is the name of the container.
+	Name string
+}
+
+// ContainerWatcher reports containers created, destroyed or reconfigured in
+// an lxcpath, by liblxc, the lxc tools or anything else.
+type ContainerWatcher struct {
+	lxcpath string
+	fd      int
+	events  chan ContainerEvent
+
+	// names maps the watch descriptors of the container directories to
+	// the container names, defined tracks the containers with a config.
+	names   map[int]string
+	defined map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// parseInotifyEvents calls fn for every event in the buffer read from an
+// inotify fd.
+func parseInotifyEvents(buf []byte, fn func(wd int, mask uint32, name string)) {
+	for len(buf) >= unix.SizeofInotifyEvent {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[0]))
+
+		end := unix.SizeofInotifyEvent + int(event.Len)
+		if end > len(buf) {
+			return
+		}
+
+		name := buf[unix.SizeofInotifyEvent:end]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+
+		fn(int(event.Wd), event.Mask, string(name))
+		buf = buf[end:]
+	}
+}
+
+// send reports the event unless the watcher is being closed.
+func (w *ContainerWatcher) send(t ContainerEventType, name string) {
+	switch t {
+	case ContainerCreated:
+		w.defined[name] = true
+	case ContainerDestroyed:
+		delete(w.defined, name)
+	}
+
+	select {
+	case w.events <- ContainerEvent{Type: t, Name: name}:
+	case <-w.stop:
+	}
+}
+
+// watchContainer watches the directory of the container, reporting it as
+// created if its config was written before the watch was added.
+func (w *ContainerWatcher) watchContainer(name string, report bool) {
+	wd, err := unix.InotifyAddWatch(w.fd, filepath.Join(w.lxcpath, name), containerWatchMask)
+	if err != nil {
+		return
+	}
+	w.names[wd] = name
+
+	if _, err := os.Stat(filepath.Join(w.lxcpath, name, "config")); err == nil {
+		if report {
+			w.send(ContainerCreated, name)
+		} else {
+			w.defined[name] = true
+		}
+	}
+}
+
+// handle turns an inotify event into container events.
+func (w *ContainerWatcher) handle(wd int, mask uint32, name string) {
+	// the directory of the container is gone
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.names, wd)
+		return
+	}
+
+	container, ok := w.names[wd]
+	if !ok {
+		// the lxcpath itself
+		switch {
+		case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			w.watchContainer(name, true)
+		case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+			if w.defined[name] {
+				w.send(ContainerDestroyed, name)
+			}
+		}
+		return
+	}
+
+	if name != "config" {
+		return
+	}
+
+	switch {
+	case mask&(unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) != 0:
+		if w.defined[container] {
+			w.send(ContainerConfigChanged, container)
+		} else {
+			w.send(ContainerCreated, container)
+		}
+	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+		if w.defined[container] {
+			w.send(ContainerDestroyed, container)
+		}
+	}
+}
+
+func (w *ContainerWatcher) run() {
+	defer close(w.done)
+	defer close(w.events)
+	defer unix.Close(w.fd)
+
+	buf := make([]byte, 64*1024)
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, 500)
+		if err != nil && err != unix.EINTR {
+			return
+		}
+		if n <= 0 {
+			continue
+		}
+
+		size, err := unix.Read(w.fd, buf)
+		if err != nil {
+			continue
+		}
+		parseInotifyEvents(buf[:size], w.handle)
+	}
+}
+
+// Events returns the channel receiving the container events. It is closed
+// once the watcher is closed. The watcher blocks until the events are read.
+func (w *ContainerWatcher) Events() <-chan ContainerEvent {
+	return w.events
+}
+
+// Close stops watching the lxcpath.
+func (w *ContainerWatcher) Close() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+// WatchContainers starts watching the lxcpath for containers being created,
+// destroyed or reconfigured, using inotify instead of rescanning it. The
+// containers existing already aren't reported.
+func WatchContainers(lxcpath ...string) (*ContainerWatcher, error) {
+	path := DefaultConfigPath()
+	if len(lxcpath) == 1 {
+		path = lxcpath[0]
+	}
+
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unix.InotifyAddWatch(fd, path, lxcpathWatchMask); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	w := &ContainerWatcher{
+		lxcpath: path,
+		fd:      fd,
+		events:  make(chan ContainerEvent, 16),
+		names:   make(map[int]string),
+		defined: make(map[string]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			w.watchContainer(entry.Name(), false)
+		}
+	}
+	go w.run()
+
+	return w, nil
+}
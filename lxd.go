@@ -0,0 +1,459 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// lxdInstance is the part of an LXD instance definition (backup.yaml,
+// index.yaml) needed to build an LXC configuration.
+type lxdInstance struct {
+	Name            string                       `yaml:"name"`
+	Architecture    string                       `yaml:"architecture"`
+	Config          map[string]string            `yaml:"config"`
+	Devices         map[string]map[string]string `yaml:"devices"`
+	ExpandedConfig  map[string]string            `yaml:"expanded_config"`
+	ExpandedDevices map[string]map[string]string `yaml:"expanded_devices"`
+}
+
+// lxdBackupFile is the layout of backup.yaml.
+type lxdBackupFile struct {
+	Container *lxdInstance `yaml:"container"`
+}
+
+// lxdIndexFile is the layout of index.yaml of an instance backup.
+type lxdIndexFile struct {
+	Name   string `yaml:"name"`
+	Config struct {
+		Container *lxdInstance `yaml:"container"`
+	} `yaml:"config"`
+}
+
+// lxdIDMapEntry is the JSON encoding of volatile.idmap.current.
+type lxdIDMapEntry struct {
+	Isuid    bool  `json:"Isuid"`
+	Isgid    bool  `json:"Isgid"`
+	Hostid   int64 `json:"Hostid"`
+	Nsid     int64 `json:"Nsid"`
+	Maprange int64 `json:"Maprange"`
+}
+
+// parseLXDInstance parses backup.yaml, falling back to index.yaml.
+func parseLXDInstance(backup []byte, index []byte) (*lxdInstance, error) {
+	if backup != nil {
+		var b lxdBackupFile
+		if err := yaml.Unmarshal(backup, &b); err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrImportFailed, err)
+		}
+
+		if b.Container != nil {
+			return b.Container, nil
+		}
+	}
+
+	if index != nil {
+		var i lxdIndexFile
+		if err := yaml.Unmarshal(index, &i); err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrImportFailed, err)
+		}
+
+		if i.Config.Container != nil {
+			return i.Config.Container, nil
+		}
+
+		if i.Name != "" {
+			return &lxdInstance{Name: i.Name}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %s", ErrImportFailed, "no instance definition found")
+}
+
+// parseLXDBytes parses LXD sizes, which use binary units (MiB, GiB, ...).
+func parseLXDBytes(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "iB") {
+		s = strings.TrimSuffix(s, "iB") + "B"
+	}
+	return ParseBytes(s)
+}
+
+// lxdCPUSet translates limits.cpu into a cpuset. A plain number of CPUs is
+// pinned to the first CPUs.
+func lxdCPUSet(value string) string {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n == 1 {
+			return "0"
+		}
+		return fmt.Sprintf("0-%d", n-1)
+	}
+	return value
+}
+
+// translateLXDConfig converts an LXD instance definition into LXC config
+// items. It returns the items and the LXD keys and devices which couldn't
+// be translated or weren't allowed by opts.
+func translateLXDConfig(instance *lxdInstance, unified bool, opts LXDImportOptions) ([]KeyValue, []string) {
+	config := instance.ExpandedConfig
+	if len(config) == 0 {
+		config = instance.Config
+	}
+
+	devices := instance.ExpandedDevices
+	if len(devices) == 0 {
+		devices = instance.Devices
+	}
+
+	cgroup := func(v1, v2 string) string {
+		if unified {
+			return "lxc.cgroup2." + v2
+		}
+		return "lxc.cgroup." + v1
+	}
+
+	var items []KeyValue
+	var skipped []string
+
+	if instance.Architecture != "" {
+		items = append(items, KeyValue{"lxc.arch", instance.Architecture})
+	}
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	privileged := config["security.privileged"] == "true"
+
+	for _, k := range keys {
+		v := config[k]
+
+		switch {
+		case strings.HasPrefix(k, "volatile.") || strings.HasPrefix(k, "image.") || strings.HasPrefix(k, "user."):
+			// runtime state and metadata without LXC equivalent
+		case k == "security.privileged":
+		case k == "boot.autostart":
+			if v == "true" {
+				items = append(items, KeyValue{"lxc.start.auto", "1"})
+			}
+		case k == "boot.autostart.delay":
+			items = append(items, KeyValue{"lxc.start.delay", v})
+		case k == "boot.autostart.priority":
+			items = append(items, KeyValue{"lxc.start.order", v})
+		case strings.HasPrefix(k, "environment."):
+			items = append(items, KeyValue{"lxc.environment", strings.TrimPrefix(k, "environment.") + "=" + v})
+		case k == "limits.memory":
+			size, err := parseLXDBytes(v)
+			if err != nil {
+				skipped = append(skipped, k)
+				continue
+			}
+			items = append(items, KeyValue{cgroup("memory.limit_in_bytes", "memory.max"), fmt.Sprintf("%.f", size)})
+		case k == "limits.cpu":
+			items = append(items, KeyValue{cgroup("cpuset.cpus", "cpuset.cpus"), lxdCPUSet(v)})
+		case k == "limits.processes":
+			items = append(items, KeyValue{cgroup("pids.max", "pids.max"), v})
+		case k == "raw.lxc":
+			if !opts.RawLXC {
+				skipped = append(skipped, k)
+				continue
+			}
+
+			for _, line := range strings.Split(v, "\n") {
+				parts := strings.SplitN(line, "=", 2)
+				if len(parts) != 2 || strings.HasPrefix(strings.TrimSpace(line), "#") {
+					continue
+				}
+				items = append(items, KeyValue{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+			}
+		default:
+			skipped = append(skipped, k)
+		}
+	}
+
+	if !privileged {
+		var idmap []lxdIDMapEntry
+		if err := json.Unmarshal([]byte(config["volatile.idmap.current"]), &idmap); err == nil {
+			for _, e := range idmap {
+				if e.Isuid {
+					items = append(items, KeyValue{"lxc.idmap", fmt.Sprintf("u %d %d %d", e.Nsid, e.Hostid, e.Maprange)})
+				}
+				if e.Isgid {
+					items = append(items, KeyValue{"lxc.idmap", fmt.Sprintf("g %d %d %d", e.Nsid, e.Hostid, e.Maprange)})
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nic := 0
+	for _, name := range names {
+		device := devices[name]
+
+		switch device["type"] {
+		case "nic":
+			link := device["parent"]
+			if link == "" {
+				link = device["network"]
+			}
+
+			if link == "" || (device["nictype"] != "" && device["nictype"] != "bridged") {
+				skipped = append(skipped, "devices."+name)
+				continue
+			}
+
+			prefix := fmt.Sprintf("lxc.net.%d", nic)
+			items = append(items,
+				KeyValue{prefix + ".type", "veth"},
+				KeyValue{prefix + ".link", link},
+				KeyValue{prefix + ".flags", "up"},
+			)
+
+			iface := device["name"]
+			if iface == "" {
+				iface = name
+			}
+			items = append(items, KeyValue{prefix + ".name", iface})
+
+			hwaddr := device["hwaddr"]
+			if hwaddr == "" {
+				hwaddr = config["volatile."+name+".hwaddr"]
+			}
+			if hwaddr != "" {
+				items = append(items, KeyValue{prefix + ".hwaddr", hwaddr})
+			}
+
+			if mtu := device["mtu"]; mtu != "" {
+				items = append(items, KeyValue{prefix + ".mtu", mtu})
+			}
+			nic++
+		case "disk":
+			if device["path"] == "/" {
+				// the root disk is the rootfs itself
+				continue
+			}
+
+			if !opts.DiskDevices || device["source"] == "" || !strings.HasPrefix(device["source"], "/") {
+				skipped = append(skipped, "devices."+name)
+				continue
+			}
+
+			options := "bind,create=dir"
+			if device["readonly"] == "true" {
+				options += ",ro"
+			}
+			if device["optional"] == "true" {
+				options += ",optional"
+			}
+			items = append(items, KeyValue{"lxc.mount.entry", fmt.Sprintf("%s %s none %s 0 0", device["source"], strings.TrimPrefix(device["path"], "/"), options)})
+		default:
+			skipped = append(skipped, "devices."+name)
+		}
+	}
+
+	return items, skipped
+}
+
+// readLXDSource reads the instance definition from an extracted backup or
+// storage volume and returns it along with the path of its rootfs.
+func readLXDSource(dir string) (*lxdInstance, string, error) {
+	read := func(path string) []byte {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		return content
+	}
+
+	// instance backups keep the volume in container/ (virtual-machine/ for VMs)
+	volume := dir
+	if _, err := os.Stat(filepath.Join(dir, "container", "rootfs")); err == nil {
+		volume = filepath.Join(dir, "container")
+	}
+
+	instance, err := parseLXDInstance(read(filepath.Join(volume, "backup.yaml")), read(filepath.Join(dir, "index.yaml")))
+	if err != nil {
+		return nil, "", err
+	}
+
+	rootfs := filepath.Join(volume, "rootfs")
+	if _, err := os.Stat(rootfs); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", ErrImportFailed, err)
+	}
+
+	return instance, rootfs, nil
+}
+
+// ImportFromLXD creates a plain LXC container from an LXD instance backup
+// (a tarball as created by "lxc export" or its extracted content with
+// index.yaml) or from an LXD storage volume holding backup.yaml and rootfs.
+// The LXD config keys and devices are translated where LXC has an
+// equivalent; the ones which couldn't be translated are returned so the
+// caller can report them. raw.lxc and disk devices are only imported when
+// opts allows them.
+// Caller needs to call Release() on the returned container.
+func ImportFromLXD(source string, opts LXDImportOptions) (_ *Container, _ []string, err error) {
+	if !VersionAtLeast(2, 1, 0) {
+		return nil, nil, ErrNotSupported
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", ErrImportFailed, err)
+	}
+
+	dir := source
+	if !info.IsDir() {
+		// only unpack the metadata, the rootfs is extracted in place later
+		dir, err = ioutil.TempDir("", "go-lxc-lxd")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		output, err := exec.Command("tar", "-xf", source, "-C", dir, "--strip-components=1", "--wildcards", "--exclude=*/rootfs/*", "backup/index.yaml", "backup/container/backup.yaml", "backup/container/rootfs").CombinedOutput()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", ErrImportFailed, strings.TrimSpace(string(output)))
+		}
+	}
+
+	instance, rootfs, err := readLXDSource(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = instance.Name
+	}
+
+	// the name of the backup is untrusted and becomes a path below lxcpath
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return nil, nil, fmt.Errorf("%s: invalid name %q", ErrImportFailed, name)
+	}
+
+	if opts.Owner != "" && !validOwner(opts.Owner) {
+		return nil, nil, fmt.Errorf("%s: %q", ErrInvalidOwner, opts.Owner)
+	}
+
+	lxcpath := opts.LXCPath
+	if lxcpath == "" {
+		lxcpath = DefaultConfigPath()
+	}
+
+	c, err := NewContainer(name, lxcpath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.Defined() {
+		c.Release()
+		return nil, nil, fmt.Errorf("%s: %q", ErrAlreadyDefined, name)
+	}
+
+	finish, err := c.operation("ImportFromLXD", source)
+	if err != nil {
+		c.Release()
+		return nil, nil, err
+	}
+	defer finish(&err)
+
+	target := filepath.Join(lxcpath, name, "rootfs")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		c.Release()
+		return nil, nil, err
+	}
+
+	cleanup := func(err error) (*Container, []string, error) {
+		os.RemoveAll(filepath.Join(lxcpath, name))
+		c.Release()
+		return nil, nil, err
+	}
+
+	var cmd *exec.Cmd
+	if info.IsDir() {
+		cmd = exec.Command("cp", "-a", rootfs+"/.", target)
+	} else {
+		cmd = exec.Command("tar", "-xf", source, "-C", target, "--numeric-owner", "--xattrs", "--xattrs-include=*", "--strip-components=3", "backup/container/rootfs")
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return cleanup(fmt.Errorf("%s: %s", ErrImportFailed, strings.TrimSpace(string(output))))
+	}
+
+	items, skipped := translateLXDConfig(instance, CgroupUnified(), opts)
+
+	privileged := instance.Config["security.privileged"] == "true" || instance.ExpandedConfig["security.privileged"] == "true"
+	includes := []string{"/usr/share/lxc/config/common.conf"}
+	if !privileged {
+		includes = append(includes, "/usr/share/lxc/config/userns.conf")
+	}
+
+	var config []KeyValue
+	for _, include := range includes {
+		if _, err := os.Stat(include); err == nil {
+			config = append(config, KeyValue{"lxc.include", include})
+		}
+	}
+
+	config = append(config,
+		KeyValue{"lxc.rootfs.path", "dir:" + target},
+		KeyValue{"lxc.uts.name", name},
+	)
+	config = append(config, items...)
+
+	err = func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, kv := range config {
+			if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+				return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+			}
+		}
+
+		if err := c.saveConfigFile(filepath.Join(lxcpath, name, "config")); err != nil {
+			return err
+		}
+
+		if _, err := assignUUID(lxcpath, name); err != nil {
+			return err
+		}
+
+		if err := writeOwner(lxcpath, name, opts.Owner); err != nil {
+			return err
+		}
+
+		// backups hold the rootfs unshifted, storage volumes are shifted already
+		if !info.IsDir() {
+			return c.prepareRootfs(RootfsOptions{})
+		}
+		return nil
+	}()
+	if err != nil {
+		return cleanup(err)
+	}
+
+	return c, skipped, nil
+}
@@ -0,0 +1,466 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build ignore
+
+// mkunsupported generates unsupported.go, which provides the API of the
+// package on platforms without liblxc (!linux or !cgo). The exported types,
+// constants and variables are copied, all exported functions and methods are
+// stubbed to return ErrNotSupported.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const output = "unsupported.go"
+
+var outputFlag = flag.String("o", output, "file to write")
+
+// skipped files don't declare API
+var skipped = map[string]bool{
+	"cgo.go":             true,
+	"doc.go":             true,
+	"linking_dynamic.go": true,
+	"linking_static.go":  true,
+	"mkunsupported.go":   true,
+	output:               true,
+}
+
+// portable replaces the linux only types by the types they alias
+var portable = map[string][2]string{
+	"unix.Signal": {"syscall", "Signal"},
+}
+
+// decl is a top level declaration (or a spec of a var block) and the file it
+// is in.
+type decl struct {
+	file *ast.File
+	src  []byte
+	node ast.Node
+	doc  *ast.CommentGroup
+
+	// for funcs only
+	fn *ast.FuncDecl
+}
+
+type generator struct {
+	fset *token.FileSet
+
+	// declarations by name, methods by "Type.Method"
+	decls   map[string]*decl
+	methods map[string][]*decl
+
+	emitted map[ast.Node]bool
+	order   []*decl
+	pending []string
+	imports map[string]string
+}
+
+func (g *generator) parse() error {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		if skipped[name] || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		f, err := parser.ParseFile(g.fset, name, src, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				fd := &decl{file: f, src: src, node: d, doc: d.Doc, fn: d}
+				if d.Recv == nil {
+					if d.Name.Name == "init" {
+						continue
+					}
+					g.decls[d.Name.Name] = fd
+				} else {
+					recv := receiverType(d)
+					g.methods[recv] = append(g.methods[recv], fd)
+				}
+			case *ast.GenDecl:
+				if d.Tok == token.IMPORT {
+					continue
+				}
+
+				// const blocks are kept together for iota
+				if d.Tok == token.CONST {
+					cd := &decl{file: f, src: src, node: d, doc: d.Doc}
+					for _, spec := range d.Specs {
+						for _, n := range spec.(*ast.ValueSpec).Names {
+							g.decls[n.Name] = cd
+						}
+					}
+					continue
+				}
+
+				for _, spec := range d.Specs {
+					doc := d.Doc
+					if d.Lparen.IsValid() {
+						doc = specDoc(spec)
+					}
+
+					sd := &decl{file: f, src: src, node: spec, doc: doc}
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						g.decls[spec.Name.Name] = sd
+					case *ast.ValueSpec:
+						for _, n := range spec.Names {
+							g.decls[n.Name] = sd
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Doc
+	case *ast.ValueSpec:
+		return spec.Doc
+	}
+	return nil
+}
+
+func receiverType(fn *ast.FuncDecl) string {
+	t := fn.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	return t.(*ast.Ident).Name
+}
+
+// require queues the declaration of name.
+func (g *generator) require(name string) {
+	g.pending = append(g.pending, name)
+}
+
+// references queues the package level names and records the imports used by
+// node.
+func (g *generator) references(d *decl, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && x.Obj == nil {
+				if p, ok := portable[x.Name+"."+n.Sel.Name]; ok {
+					x.Name, n.Sel.Name = p[0], p[1]
+					g.imports[p[0]] = p[0]
+					return false
+				}
+				if path, ok := importPath(d.file, x.Name); ok {
+					g.imports[x.Name] = path
+					return false
+				}
+			}
+		case *ast.Ident:
+			// parameters shadow the package level names
+			if n.Obj != nil {
+				if _, ok := n.Obj.Decl.(*ast.Field); ok {
+					return true
+				}
+			}
+
+			if _, ok := g.decls[n.Name]; ok {
+				g.require(n.Name)
+			}
+		}
+		return true
+	})
+}
+
+func importPath(f *ast.File, name string) (string, bool) {
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if path == "C" {
+			continue
+		}
+
+		local := filepath.Base(path)
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		if local == name {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func (g *generator) resolve() {
+	for len(g.pending) > 0 {
+		name := g.pending[0]
+		g.pending = g.pending[1:]
+
+		d := g.decls[name]
+		if g.emitted[d.node] {
+			continue
+		}
+		g.emitted[d.node] = true
+		g.order = append(g.order, d)
+
+		if d.fn != nil {
+			if !ast.IsExported(name) {
+				log.Fatalf("%s references the unexported function %s", g.fset.Position(d.node.Pos()), name)
+			}
+			g.references(d, d.fn.Type)
+			continue
+		}
+
+		if spec, ok := d.node.(*ast.TypeSpec); ok {
+			g.references(d, exportedType(spec))
+
+			// unexported types are copied with their methods, exported
+			// types get the exported methods as stubs
+			for _, m := range g.methods[name] {
+				if ast.IsExported(name) && !ast.IsExported(m.fn.Name.Name) {
+					continue
+				}
+				if !g.emitted[m.node] {
+					g.emitted[m.node] = true
+					g.order = append(g.order, m)
+					if ast.IsExported(name) {
+						g.references(m, m.fn.Type)
+					} else {
+						g.references(m, m.fn)
+					}
+				}
+			}
+			continue
+		}
+
+		g.references(d, d.node)
+	}
+}
+
+// exportedType returns the type spec without the unexported struct fields.
+func exportedType(spec *ast.TypeSpec) *ast.TypeSpec {
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return spec
+	}
+
+	fields := &ast.FieldList{Opening: st.Fields.Opening, Closing: st.Fields.Closing}
+	for _, field := range st.Fields.List {
+		if exportedField(field) {
+			fields.List = append(fields.List, field)
+		}
+	}
+
+	copied := *spec
+	copied.Type = &ast.StructType{Struct: st.Struct, Fields: fields}
+	return &copied
+}
+
+func exportedField(field *ast.Field) bool {
+	if len(field.Names) == 0 {
+		t := field.Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		switch t := t.(type) {
+		case *ast.Ident:
+			return ast.IsExported(t.Name)
+		case *ast.SelectorExpr:
+			return true
+		}
+		return false
+	}
+
+	for _, n := range field.Names {
+		if !ast.IsExported(n.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *generator) text(d *decl, from token.Pos, to token.Pos) string {
+	file := g.fset.File(from)
+	return string(d.src[file.Offset(from):file.Offset(to)])
+}
+
+func (g *generator) node(node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, g.fset, node); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
+
+func (g *generator) write(buf *bytes.Buffer, d *decl) {
+	if d.doc != nil {
+		buf.WriteString(g.text(d, d.doc.Pos(), d.doc.End()))
+		buf.WriteString("\n")
+	}
+
+	switch node := d.node.(type) {
+	case *ast.FuncDecl:
+		name := node.Name.Name
+		if node.Recv != nil && !ast.IsExported(receiverType(node)) {
+			buf.WriteString(g.text(d, node.Pos(), node.End()))
+			break
+		}
+		if !ast.IsExported(name) {
+			log.Fatalf("%s: unexported function %s", g.fset.Position(node.Pos()), name)
+		}
+		buf.WriteString(g.stub(node))
+	case *ast.GenDecl:
+		buf.WriteString(g.text(d, node.Pos(), node.End()))
+	case *ast.TypeSpec:
+		spec := exportedType(node)
+		if spec == node {
+			fmt.Fprintf(buf, "type %s", g.text(d, node.Pos(), node.End()))
+			break
+		}
+
+		fmt.Fprintf(buf, "type %s struct {\n", node.Name.Name)
+		for _, field := range spec.Type.(*ast.StructType).Fields.List {
+			from, to := field.Pos(), field.End()
+			if field.Doc != nil {
+				from = field.Doc.Pos()
+			}
+			if field.Comment != nil {
+				to = field.Comment.End()
+			}
+			buf.WriteString(g.text(d, from, to))
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}")
+	case *ast.ValueSpec:
+		fmt.Fprintf(buf, "var %s", g.text(d, node.Pos(), node.End()))
+	}
+	buf.WriteString("\n\n")
+}
+
+// stub returns fn returning the zero values and ErrNotSupported as error.
+func (g *generator) stub(fn *ast.FuncDecl) string {
+	typ := *fn.Type
+	body := "return"
+
+	if typ.Results != nil {
+		results := &ast.FieldList{Opening: 1, Closing: 1}
+		for i, field := range typ.Results.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for j := 0; j < n; j++ {
+				name := "_"
+				last := i == len(typ.Results.List)-1 && j == n-1
+				if ident, ok := field.Type.(*ast.Ident); ok && last && ident.Name == "error" {
+					name = "err"
+					body = "err = ErrNotSupported\nreturn"
+				}
+				results.List = append(results.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(name)}, Type: field.Type})
+			}
+		}
+		typ.Results = results
+	}
+
+	stub := &ast.FuncDecl{Recv: fn.Recv, Name: fn.Name, Type: &typ}
+	return fmt.Sprintf("%s {\n%s\n}", g.node(stub), body)
+}
+
+func main() {
+	flag.Parse()
+
+	g := &generator{
+		fset:    token.NewFileSet(),
+		decls:   make(map[string]*decl),
+		methods: make(map[string][]*decl),
+		emitted: make(map[ast.Node]bool),
+		imports: make(map[string]string),
+	}
+
+	if err := g.parse(); err != nil {
+		log.Fatal(err)
+	}
+
+	var names []string
+	for name := range g.decls {
+		if ast.IsExported(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.require(name)
+	}
+	g.resolve()
+
+	var body bytes.Buffer
+	for _, d := range g.order {
+		g.write(&body, d)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// Code generated by mkunsupported.go; DO NOT EDIT.
+
+// +build !linux !cgo
+
+package lxc
+
+`)
+
+	var paths []string
+	for name, path := range g.imports {
+		if filepath.Base(path) != name {
+			path = name + " " + strconv.Quote(path)
+		} else {
+			path = strconv.Quote(path)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(paths) > 0 {
+		fmt.Fprintf(&buf, "import (\n%s\n)\n\n", strings.Join(paths, "\n"))
+	}
+
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		ioutil.WriteFile(*outputFlag, buf.Bytes(), 0644)
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(*outputFlag, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+func parseAddrs(values []string) []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(values))
+	for _, v := range values {
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+func (c *Container) addrs(interfaceName string, family string, missing error) ([]netip.Addr, error) {
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	result, ok := c.getIPs(interfaceName, family, 0)
+	if !ok {
+		return nil, missing
+	}
+	return parseAddrs(result), nil
+}
+
+// IPAddrs returns all IP addresses.
+func (c *Container) IPAddrs() ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs("", "", ErrIPAddresses)
+}
+
+// IPv4Addrs returns all IPv4 addresses.
+func (c *Container) IPv4Addrs() ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs("", "inet", ErrIPv4Addresses)
+}
+
+// IPv6Addrs returns all IPv6 addresses.
+func (c *Container) IPv6Addrs() ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs("", "inet6", ErrIPv6Addresses)
+}
+
+// InterfaceAddrs returns the IP addresses of the given network interface.
+func (c *Container) InterfaceAddrs(interfaceName string) ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs(interfaceName, "", ErrIPAddress)
+}
+
+// InterfaceIPv4Addrs returns the IPv4 addresses of the given network interface.
+func (c *Container) InterfaceIPv4Addrs(interfaceName string) ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs(interfaceName, "inet", ErrIPv4Addresses)
+}
+
+// InterfaceIPv6Addrs returns the IPv6 addresses of the given network interface.
+func (c *Container) InterfaceIPv6Addrs(interfaceName string) ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.addrs(interfaceName, "inet6", ErrIPv6Addresses)
+}
+
+// WaitIPAddrs waits until IPAddrs call returns something or time outs
+func (c *Container) WaitIPAddrs(timeout time.Duration) ([]netip.Addr, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	for {
+		if result, err := c.addrs("", "", ErrIPAddresses); err == nil && len(result) > 0 {
+			return result, nil
+		}
+		time.Sleep(1 * time.Second)
+
+		if time.Since(now) >= timeout {
+			return nil, ErrIPAddresses
+		}
+	}
+}
+
+// ConfiguredIPPrefixes returns the static addresses of the container's
+// network devices (lxc.net.N.ipv4.address and lxc.net.N.ipv6.address).
+func (c *Container) ConfiguredIPPrefixes() ([]netip.Prefix, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	netPrefix := "lxc.net"
+	v4Key, v6Key := "ipv4.address", "ipv6.address"
+	if !VersionAtLeast(2, 1, 0) {
+		netPrefix = "lxc.network"
+		v4Key, v6Key = "ipv4", "ipv6"
+	}
+
+	var prefixes []netip.Prefix
+	for i := 0; i < len(c.configItem(netPrefix)); i++ {
+		for _, key := range []string{v4Key, v6Key} {
+			for _, v := range c.configItem(fmt.Sprintf("%s.%d.%s", netPrefix, i, key)) {
+				// IPv4 addresses may carry a broadcast address.
+				fields := strings.Fields(v)
+				if len(fields) == 0 {
+					continue
+				}
+				v = fields[0]
+
+				prefix, err := netip.ParsePrefix(v)
+				if err != nil {
+					addr, err := netip.ParseAddr(v)
+					if err != nil {
+						return nil, err
+					}
+					prefix = netip.PrefixFrom(addr, addr.BitLen())
+				}
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+
+	return prefixes, nil
+}
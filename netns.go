@@ -0,0 +1,218 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// tcpListen and tcpClose are the socket states in /proc/net/{tcp,udp}.
+	tcpListen = 0x0a
+	tcpClose  = 0x07
+)
+
+// ListeningPort represents a socket accepting connections or datagrams.
+type ListeningPort struct {
+	// Protocol is "tcp", "tcp6", "udp" or "udp6".
+	Protocol string
+	Address  netip.AddrPort
+}
+
+// NetNSStats represents the socket statistics of a network namespace.
+type NetNSStats struct {
+	// TCP and UDP are the numbers of open sockets, over IPv4 and IPv6.
+	TCP int
+	UDP int
+	// TCPStates are the numbers of TCP sockets per state, e.g.
+	// "ESTABLISHED" or "TIME_WAIT".
+	TCPStates map[string]int
+	// Conntrack is the number of conntrack entries, -1 if conntrack isn't
+	// loaded.
+	Conntrack int
+	// Listening are the TCP sockets listening and the bound UDP sockets.
+	Listening []ListeningPort
+}
+
+// tcpStates are the names of the TCP states as numbered by the kernel.
+var tcpStates = []string{
+	"", "ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2",
+	"TIME_WAIT", "CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+	"NEW_SYN_RECV",
+}
+
+// parseProcNetAddr parses an address of /proc/net/{tcp,udp}{,6} such as
+// "0100007F:0035". The address is printed as native 32 bit words, little
+// endian on all hosts supported by liblxc.
+func parseProcNetAddr(s string) (netip.AddrPort, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return netip.AddrPort{}, fmt.Errorf("invalid address %q", s)
+	}
+
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return netip.AddrPort{}, fmt.Errorf("invalid address %q", s)
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid port %q", s)
+	}
+
+	ip := make([]byte, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr, uint16(port)), nil
+}
+
+// parseProcNet adds the sockets of a /proc/net/{tcp,udp}{,6} file to stats.
+func parseProcNet(content string, protocol string, stats *NetNSStats) error {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+
+	// skip the header
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		local, err := parseProcNetAddr(fields[1])
+		if err != nil {
+			return err
+		}
+
+		remote, err := parseProcNetAddr(fields[2])
+		if err != nil {
+			return err
+		}
+
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid state %q", fields[3])
+		}
+
+		if strings.HasPrefix(protocol, "tcp") {
+			stats.TCP++
+			if int(state) < len(tcpStates) {
+				stats.TCPStates[tcpStates[state]]++
+			}
+
+			if state == tcpListen {
+				stats.Listening = append(stats.Listening, ListeningPort{protocol, local})
+			}
+			continue
+		}
+
+		stats.UDP++
+		if state == tcpClose && remote.Port() == 0 {
+			stats.Listening = append(stats.Listening, ListeningPort{protocol, local})
+		}
+	}
+	return nil
+}
+
+// inNetNS runs fn in the network namespace of the process. The calling
+// goroutine is locked to its thread, which is discarded if it can't be
+// moved back into the original namespace.
+func inNetNS(pid int, fn func() error) error {
+	target, err := unix.Open(fmt.Sprintf("/proc/%d/ns/net", pid), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(target)
+
+	runtime.LockOSThread()
+
+	self, err := unix.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer unix.Close(self)
+
+	if err := unix.Setns(target, unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+
+	ferr := fn()
+
+	if err := unix.Setns(self, unix.CLONE_NEWNET); err != nil {
+		// leave the thread locked, it exits with the goroutine
+		return err
+	}
+	runtime.UnlockOSThread()
+
+	return ferr
+}
+
+// netNSStats collects the socket statistics of the process' network
+// namespace. Sockets are read through /proc/<pid>/net, conntrack needs to
+// enter the namespace.
+func netNSStats(pid int) (NetNSStats, error) {
+	stats := NetNSStats{TCPStates: make(map[string]int), Conntrack: -1}
+
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		content, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "net", protocol))
+		if err != nil {
+			// no IPv6 in the namespace
+			continue
+		}
+
+		if err := parseProcNet(string(content), protocol, &stats); err != nil {
+			return stats, fmt.Errorf("%s: %v", ErrNetNSStats, err)
+		}
+	}
+
+	err := inNetNS(pid, func() error {
+		content, err := ioutil.ReadFile("/proc/sys/net/netfilter/nf_conntrack_count")
+		if err != nil {
+			return nil
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return err
+		}
+		stats.Conntrack = count
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("%s: %v", ErrNetNSStats, err)
+	}
+
+	return stats, nil
+}
+
+// NetNSStats returns the socket statistics of the network namespace of the
+// container: open TCP and UDP sockets, listening ports and conntrack
+// entries. Useful to spot socket leaks or unexpected listeners. Entering the
+// namespace for the conntrack entries needs CAP_SYS_ADMIN.
+func (c *Container) NetNSStats() (NetNSStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning | isPrivileged); err != nil {
+		return NetNSStats{}, err
+	}
+
+	return netNSStats(c.initPid())
+}
@@ -0,0 +1,412 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// NetworkType is the type of a network device (lxc.net.N.type).
+type NetworkType string
+
+const (
+	// NetworkEmpty only creates the loopback interface.
+	NetworkEmpty NetworkType = "empty"
+	// NetworkNone shares the network namespace of the host.
+	NetworkNone NetworkType = "none"
+	// NetworkVeth creates a veth pair, the host side attached to Link.
+	NetworkVeth NetworkType = "veth"
+	// NetworkPhys moves the host interface Link into the container.
+	NetworkPhys NetworkType = "phys"
+	// NetworkMacvlan creates a macvlan interface on top of Link.
+	NetworkMacvlan NetworkType = "macvlan"
+	// NetworkIPvlan creates an ipvlan interface on top of Link.
+	NetworkIPvlan NetworkType = "ipvlan"
+	// NetworkVlan creates a vlan interface on top of Link.
+	NetworkVlan NetworkType = "vlan"
+)
+
+// MacvlanMode is how macvlan interfaces on the same link communicate
+// (lxc.net.N.macvlan.mode).
+type MacvlanMode string
+
+const (
+	// MacvlanPrivate isolates the interface from the other ones.
+	MacvlanPrivate MacvlanMode = "private"
+	// MacvlanVEPA sends all traffic to the switch, which may reflect it.
+	MacvlanVEPA MacvlanMode = "vepa"
+	// MacvlanBridge switches the traffic between the interfaces directly.
+	MacvlanBridge MacvlanMode = "bridge"
+	// MacvlanPassthru hands the link over to a single interface.
+	MacvlanPassthru MacvlanMode = "passthru"
+)
+
+// IPvlanMode is the layer ipvlan interfaces switch traffic on
+// (lxc.net.N.ipvlan.mode).
+type IPvlanMode string
+
+const (
+	// IPvlanL2 switches on layer 2, the interfaces share the MAC address
+	// of the link.
+	IPvlanL2 IPvlanMode = "l2"
+	// IPvlanL3 routes on layer 3, the interfaces need static addresses.
+	IPvlanL3 IPvlanMode = "l3"
+	// IPvlanL3S routes on layer 3 through the netfilter hooks of the host.
+	IPvlanL3S IPvlanMode = "l3s"
+)
+
+// IPvlanIsolation is how ipvlan interfaces on the same link communicate
+// (lxc.net.N.ipvlan.isolation).
+type IPvlanIsolation string
+
+const (
+	// IPvlanBridge switches the traffic between the interfaces directly.
+	IPvlanBridge IPvlanIsolation = "bridge"
+	// IPvlanPrivate isolates the interface from the other ones.
+	IPvlanPrivate IPvlanIsolation = "private"
+	// IPvlanVEPA sends all traffic to the switch, which may reflect it.
+	IPvlanVEPA IPvlanIsolation = "vepa"
+)
+
+// Network is a network device of a container.
+type Network struct {
+	// Index is the N of lxc.net.N, set by Networks.
+	Index int
+	Type  NetworkType
+	// Link is the host interface the device is created on or moved from.
+	Link string
+	// Name is the name of the interface in the container.
+	Name   string
+	HWAddr string
+	// MTU of the interface, 0 for the default.
+	MTU int
+	// Up brings the interface up when the container starts.
+	Up bool
+
+	// MacvlanMode of a macvlan device, liblxc defaults to private.
+	MacvlanMode MacvlanMode
+	// IPvlanMode of an ipvlan device, liblxc defaults to l3.
+	IPvlanMode IPvlanMode
+	// IPvlanIsolation of an ipvlan device, liblxc defaults to bridge.
+	IPvlanIsolation IPvlanIsolation
+}
+
+// validate checks the modes of the network device match its type.
+func (n Network) validate() error {
+	if n.Type == "" {
+		return fmt.Errorf("%s: no type", ErrInvalidNetwork)
+	}
+
+	if n.Link == "" && (n.Type == NetworkPhys || n.Type == NetworkMacvlan || n.Type == NetworkIPvlan || n.Type == NetworkVlan) {
+		return fmt.Errorf("%s: %s needs a link", ErrInvalidNetwork, n.Type)
+	}
+
+	if n.MacvlanMode != "" {
+		if n.Type != NetworkMacvlan {
+			return fmt.Errorf("%s: macvlan mode on a %s device", ErrInvalidNetwork, n.Type)
+		}
+
+		switch n.MacvlanMode {
+		case MacvlanPrivate, MacvlanVEPA, MacvlanBridge, MacvlanPassthru:
+		default:
+			return fmt.Errorf("%s: macvlan mode %q", ErrInvalidNetwork, n.MacvlanMode)
+		}
+	}
+
+	if n.IPvlanMode != "" || n.IPvlanIsolation != "" {
+		if n.Type != NetworkIPvlan {
+			return fmt.Errorf("%s: ipvlan mode on a %s device", ErrInvalidNetwork, n.Type)
+		}
+
+		switch n.IPvlanMode {
+		case "", IPvlanL2, IPvlanL3, IPvlanL3S:
+		default:
+			return fmt.Errorf("%s: ipvlan mode %q", ErrInvalidNetwork, n.IPvlanMode)
+		}
+
+		switch n.IPvlanIsolation {
+		case "", IPvlanBridge, IPvlanPrivate, IPvlanVEPA:
+		default:
+			return fmt.Errorf("%s: ipvlan isolation %q", ErrInvalidNetwork, n.IPvlanIsolation)
+		}
+	}
+
+	// ipvlan interfaces always use the MAC address of the link
+	if n.Type == NetworkIPvlan && n.HWAddr != "" {
+		return fmt.Errorf("%s: ipvlan devices can't set hwaddr", ErrInvalidNetwork)
+	}
+	return nil
+}
+
+// kernelModuleAvailable returns whether the kernel module is loaded, built
+// in or can be loaded on demand.
+func kernelModuleAvailable(name string) bool {
+	if _, err := os.Stat(filepath.Join("/sys/module", name)); err == nil {
+		return true
+	}
+
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return false
+	}
+
+	dir := filepath.Join("/lib/modules", unix.ByteSliceToString(uname.Release[:]))
+	for _, index := range []string{"modules.builtin", "modules.dep"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, index))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			path := strings.SplitN(line, ":", 2)[0]
+			if strings.HasPrefix(filepath.Base(path), name+".ko") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runCommands runs the host networking commands, e.g. ip link, in order.
+func runCommands(commands [][]string) error {
+	for _, args := range commands {
+		if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %q: %s", err, args, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// networkSupported checks liblxc and the kernel support the type of the
+// network device.
+func networkSupported(t NetworkType) error {
+	switch t {
+	case NetworkIPvlan:
+		if !HasAPIExtension("network_ipvlan") {
+			return fmt.Errorf("%s: liblxc lacks ipvlan", ErrNotSupported)
+		}
+	case NetworkMacvlan, NetworkVlan:
+	default:
+		return nil
+	}
+
+	module := string(t)
+	if t == NetworkVlan {
+		module = "8021q"
+	}
+
+	if !kernelModuleAvailable(module) {
+		return fmt.Errorf("%s: kernel lacks %s", ErrNotSupported, module)
+	}
+	return nil
+}
+
+// configItems returns the config of the network device as lxc.net.index.
+func (n Network) configItems(index int) ([]KeyValue, error) {
+	if err := n.validate(); err != nil {
+		return nil, err
+	}
+
+	items := []KeyValue{{networkKey(index, "type"), string(n.Type)}}
+	if n.Link != "" {
+		items = append(items, KeyValue{networkKey(index, "link"), n.Link})
+	}
+	if n.Name != "" {
+		items = append(items, KeyValue{networkKey(index, "name"), n.Name})
+	}
+	if n.HWAddr != "" {
+		items = append(items, KeyValue{networkKey(index, "hwaddr"), n.HWAddr})
+	}
+	if n.MTU > 0 {
+		items = append(items, KeyValue{networkKey(index, "mtu"), strconv.Itoa(n.MTU)})
+	}
+	if n.Up {
+		items = append(items, KeyValue{networkKey(index, "flags"), "up"})
+	}
+	if n.MacvlanMode != "" {
+		items = append(items, KeyValue{networkKey(index, "macvlan.mode"), string(n.MacvlanMode)})
+	}
+	if n.IPvlanMode != "" {
+		items = append(items, KeyValue{networkKey(index, "ipvlan.mode"), string(n.IPvlanMode)})
+	}
+	if n.IPvlanIsolation != "" {
+		items = append(items, KeyValue{networkKey(index, "ipvlan.isolation"), string(n.IPvlanIsolation)})
+	}
+	return items, nil
+}
+
+// networkIndices returns the indices of the configured network devices.
+// liblxc lists them as the value of lxc.net, older versions only list the
+// types of the devices.
+//
+// Caller needs to hold the lock
+func (c *Container) networkIndices() []int {
+	key := strings.TrimSuffix(networkKey(0, ""), ".0.")
+
+	values := nonEmpty(c.configItem(key))
+	indices := make([]int, len(values))
+	for i, v := range values {
+		index, err := strconv.Atoi(v)
+		if err != nil {
+			index = i
+		}
+		indices[i] = index
+	}
+	return indices
+}
+
+// network returns the network device lxc.net.index.
+//
+// Caller needs to hold the lock
+func (c *Container) network(index int) Network {
+	item := func(name string) string {
+		if values := c.configItem(networkKey(index, name)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	mtu, _ := strconv.Atoi(item("mtu"))
+	n := Network{
+		Index:  index,
+		Type:   NetworkType(item("type")),
+		Link:   item("link"),
+		Name:   item("name"),
+		HWAddr: item("hwaddr"),
+		MTU:    mtu,
+		Up:     item("flags") == "up",
+	}
+
+	switch n.Type {
+	case NetworkMacvlan:
+		n.MacvlanMode = MacvlanMode(item("macvlan.mode"))
+	case NetworkIPvlan:
+		n.IPvlanMode = IPvlanMode(item("ipvlan.mode"))
+		n.IPvlanIsolation = IPvlanIsolation(item("ipvlan.isolation"))
+	}
+	return n
+}
+
+// Networks returns the configured network devices of the container.
+func (c *Container) Networks() ([]Network, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	var networks []Network
+	for _, index := range c.networkIndices() {
+		networks = append(networks, c.network(index))
+	}
+	return networks, nil
+}
+
+// addNetwork adds the network device after the configured ones.
+//
+// Caller needs to hold the lock
+func (c *Container) addNetwork(n Network) (int, error) {
+	if c.container == nil {
+		return -1, ErrNotDefined
+	}
+
+	index := 0
+	for _, i := range c.networkIndices() {
+		if i >= index {
+			index = i + 1
+		}
+	}
+
+	items, err := n.configItems(index)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, kv := range items {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return -1, err
+		}
+	}
+	return index, nil
+}
+
+// AddNetwork adds the network device to the container and returns its
+// index. Index is ignored. It fails with ErrNotSupported if liblxc or the
+// kernel lack the type of the device. The device is created on the next
+// start.
+func (c *Container) AddNetwork(n Network) (_ int, err error) {
+	finish, err := c.operation("AddNetwork", string(n.Type))
+	if err != nil {
+		return 0, err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := n.validate(); err != nil {
+		return -1, err
+	}
+
+	if err := networkSupported(n.Type); err != nil {
+		return -1, err
+	}
+
+	return c.addNetwork(n)
+}
+
+// removeNetwork removes the network device lxc.net.index.
+//
+// Caller needs to hold the lock
+func (c *Container) removeNetwork(index int) error {
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	for _, i := range c.networkIndices() {
+		if i == index {
+			return c.clearConfigItem(strings.TrimSuffix(networkKey(index, ""), "."))
+		}
+	}
+	return fmt.Errorf("%s: no device %d", ErrInvalidNetwork, index)
+}
+
+// RemoveNetwork removes the network device lxc.net.index from the
+// container. The indices of the other devices are kept.
+func (c *Container) RemoveNetwork(index int) (err error) {
+	finish, err := c.operation("RemoveNetwork", strconv.Itoa(index))
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.removeNetwork(index)
+}
+
+// Overlay is a network spanning several hosts which containers are attached
+// to, e.g. a VXLAN.
+type Overlay interface {
+	// Up creates the interfaces of the overlay on the host.
+	Up() error
+	// Down removes the interfaces of the overlay from the host.
+	Down() error
+	// Attach adds a network device connected to the overlay to the
+	// container and returns its index.
+	Attach(c *Container) (int, error)
+}
@@ -0,0 +1,240 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// notifySocketPath is where the notify socket is mounted inside the
+// container. /run isn't used as the guest may mount a tmpfs over it.
+const notifySocketPath = "/dev/.go-lxc-notify"
+
+// notifySocket receives sd_notify messages from the guest.
+type notifySocket struct {
+	dir  string
+	conn *net.UnixConn
+	// idmap of the container, whose users may send messages besides root
+	// and the user of this process.
+	idmap IDMap
+
+	mu     sync.Mutex
+	status string
+	ready  chan struct{}
+}
+
+func newNotifySocket(idmap IDMap) (*notifySocket, error) {
+	dir, err := ioutil.TempDir("", "go-lxc-notify")
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	// root of an unprivileged container is mapped to another uid, the
+	// messages of other users are dropped by their credentials
+	for _, p := range []string{dir, path} {
+		if err := os.Chmod(p, 0777); err != nil {
+			conn.Close()
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+
+	raw, err := conn.SyscallConn()
+	if err == nil {
+		err = raw.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PASSCRED, 1)
+		})
+	}
+	if err != nil {
+		conn.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	n := &notifySocket{dir: dir, conn: conn, idmap: idmap, ready: make(chan struct{})}
+	go n.run()
+
+	return n, nil
+}
+
+func (n *notifySocket) path() string {
+	return filepath.Join(n.dir, "notify")
+}
+
+// trustedSender returns whether the credentials passed along a message are
+// of root, the user of this process or a user of the container.
+func (n *notifySocket) trustedSender(oob []byte) bool {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return false
+	}
+
+	for _, msg := range msgs {
+		cred, err := unix.ParseUnixCredentials(&msg)
+		if err != nil {
+			continue
+		}
+
+		if trustedUID(cred.Uid) {
+			return true
+		}
+
+		_, ok := n.idmap.ToContainer(IDTypeUID, int64(cred.Uid))
+		return ok
+	}
+	return false
+}
+
+func (n *notifySocket) run() {
+	buf := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(unix.SizeofUcred))
+	for {
+		size, oobn, _, _, err := n.conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return
+		}
+
+		if !n.trustedSender(oob[:oobn]) {
+			continue
+		}
+
+		n.mu.Lock()
+		for _, line := range strings.Split(string(buf[:size]), "\n") {
+			switch {
+			case line == "READY=1":
+				select {
+				case <-n.ready:
+				default:
+					close(n.ready)
+				}
+			case strings.HasPrefix(line, "STATUS="):
+				n.status = strings.TrimPrefix(line, "STATUS=")
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+// reset forgets the messages of a previous boot of the container.
+func (n *notifySocket) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.ready = make(chan struct{})
+	n.status = ""
+}
+
+func (n *notifySocket) wait(ctx context.Context) error {
+	n.mu.Lock()
+	ready := n.ready
+	n.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *notifySocket) close() {
+	n.conn.Close()
+	os.RemoveAll(n.dir)
+}
+
+// NotifySocket wires a sd_notify compatible socket into the container. The
+// socket is bind mounted into the container and NOTIFY_SOCKET is set in the
+// environment of init, so a systemd guest reports READY=1 once it finished
+// booting. The changes apply on the next start. It returns the path of the
+// socket on the host.
+func (c *Container) NotifySocket() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return "", ErrNotDefined
+	}
+
+	if c.notify != nil {
+		return c.notify.path(), nil
+	}
+
+	if err := c.makeSure(isNotRunning); err != nil {
+		return "", err
+	}
+
+	idmap, err := c.idMap()
+	if err != nil {
+		return "", err
+	}
+
+	n, err := newNotifySocket(idmap)
+	if err != nil {
+		return "", err
+	}
+
+	entries := []KeyValue{
+		{Key: "lxc.mount.entry", Value: n.path() + " " + strings.TrimPrefix(notifySocketPath, "/") + " none bind,create=file 0 0"},
+		{Key: "lxc.environment", Value: "NOTIFY_SOCKET=" + notifySocketPath},
+	}
+
+	for _, kv := range entries {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			n.close()
+			return "", err
+		}
+	}
+
+	c.notify = n
+	return n.path(), nil
+}
+
+// WaitReady waits until the guest sent READY=1 over the socket set up by
+// NotifySocket since the container was last started, or until ctx is done.
+func (c *Container) WaitReady(ctx context.Context) error {
+	c.mu.RLock()
+	n := c.notify
+	c.mu.RUnlock()
+
+	if n == nil {
+		return ErrNoNotifySocket
+	}
+
+	return n.wait(ctx)
+}
+
+// NotifyStatus returns the last STATUS= message sent by the guest over the
+// socket set up by NotifySocket.
+func (c *Container) NotifyStatus() string {
+	c.mu.RLock()
+	n := c.notify
+	c.mu.RUnlock()
+
+	if n == nil {
+		return ""
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.status
+}
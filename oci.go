@@ -0,0 +1,388 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ociVersion is the version of the OCI runtime specification of generated
+// bundles.
+const ociVersion = "1.0.2"
+
+// The subset of the OCI runtime specification go-lxc can map an LXC
+// configuration to.
+type ociSpec struct {
+	Version  string     `json:"ociVersion"`
+	Process  ociProcess `json:"process"`
+	Root     ociRoot    `json:"root"`
+	Hostname string     `json:"hostname,omitempty"`
+	Mounts   []ociMount `json:"mounts"`
+	Linux    ociLinux   `json:"linux"`
+}
+
+type ociProcess struct {
+	Terminal     bool             `json:"terminal"`
+	User         ociUser          `json:"user"`
+	Args         []string         `json:"args"`
+	Env          []string         `json:"env,omitempty"`
+	Cwd          string           `json:"cwd"`
+	Capabilities *ociCapabilities `json:"capabilities,omitempty"`
+}
+
+type ociUser struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+type ociCapabilities struct {
+	Bounding    []string `json:"bounding"`
+	Effective   []string `json:"effective"`
+	Permitted   []string `json:"permitted"`
+	Inheritable []string `json:"inheritable"`
+}
+
+type ociRoot struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+type ociMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type ociIDMapping struct {
+	ContainerID uint32 `json:"containerID"`
+	HostID      uint32 `json:"hostID"`
+	Size        uint32 `json:"size"`
+}
+
+type ociNamespace struct {
+	Type string `json:"type"`
+}
+
+type ociDeviceCgroup struct {
+	Allow  bool   `json:"allow"`
+	Type   string `json:"type,omitempty"`
+	Major  *int64 `json:"major,omitempty"`
+	Minor  *int64 `json:"minor,omitempty"`
+	Access string `json:"access,omitempty"`
+}
+
+type ociResources struct {
+	Devices []ociDeviceCgroup `json:"devices,omitempty"`
+	Memory  *struct {
+		Limit int64 `json:"limit"`
+	} `json:"memory,omitempty"`
+	CPU *struct {
+		Cpus string `json:"cpus"`
+	} `json:"cpu,omitempty"`
+	Pids *struct {
+		Limit int64 `json:"limit"`
+	} `json:"pids,omitempty"`
+}
+
+type ociLinux struct {
+	UIDMappings []ociIDMapping `json:"uidMappings,omitempty"`
+	GIDMappings []ociIDMapping `json:"gidMappings,omitempty"`
+	Namespaces  []ociNamespace `json:"namespaces"`
+	Resources   *ociResources  `json:"resources,omitempty"`
+}
+
+// capabilities lists the capabilities known to lxc.cap.drop and lxc.cap.keep.
+var capabilities = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill",
+	"setgid", "setuid", "setpcap", "linux_immutable", "net_bind_service",
+	"net_broadcast", "net_admin", "net_raw", "ipc_lock", "ipc_owner",
+	"sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time",
+	"sys_tty_config", "mknod", "lease", "audit_write", "audit_control",
+	"setfcap", "mac_override", "mac_admin", "syslog", "wake_alarm",
+	"block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+// ociDefaultMounts are the mounts every OCI bundle needs, matching what
+// lxc.autodev and lxc.mount.auto set up.
+var ociDefaultMounts = []ociMount{
+	{Destination: "/proc", Type: "proc", Source: "proc"},
+	{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+	{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620"}},
+	{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+	{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+	{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+}
+
+// ociMountEntry translates an lxc.mount.entry (fstab format) into an OCI
+// mount. Mounts relative to the rootfs are made absolute and the LXC only
+// options are dropped.
+func ociMountEntry(entry string) (ociMount, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 4 {
+		return ociMount{}, false
+	}
+
+	m := ociMount{Source: fields[0], Destination: "/" + strings.TrimPrefix(fields[1], "/"), Type: fields[2]}
+	for _, o := range strings.Split(fields[3], ",") {
+		if o == "optional" || o == "defaults" || strings.HasPrefix(o, "create=") {
+			continue
+		}
+		m.Options = append(m.Options, o)
+	}
+
+	if m.Type == "none" {
+		m.Type = "bind"
+	}
+	return m, true
+}
+
+// newOCISpec builds an OCI runtime spec from the config items returned by
+// config.
+func newOCISpec(config func(key string) []string, idmap IDMap, unified bool) (*ociSpec, error) {
+	first := func(key string) string {
+		values := config(key)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	nonEmpty := func(key string) []string {
+		var ret []string
+		for _, v := range config(key) {
+			if v != "" {
+				ret = append(ret, v)
+			}
+		}
+		return ret
+	}
+
+	args, err := splitArgs(first("lxc.init.cmd"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ErrExportFailed, err)
+	}
+
+	spec := &ociSpec{
+		Version:  ociVersion,
+		Root:     ociRoot{Path: "rootfs"},
+		Hostname: first("lxc.uts.name"),
+		Process: ociProcess{
+			Args: args,
+			Env:  nonEmpty("lxc.environment"),
+			Cwd:  first("lxc.init.cwd"),
+		},
+	}
+
+	if len(spec.Process.Args) == 0 {
+		spec.Process.Args = []string{"/sbin/init"}
+	}
+	if spec.Process.Cwd == "" {
+		spec.Process.Cwd = "/"
+	}
+
+	for _, v := range []struct {
+		key string
+		id  *uint32
+	}{{"lxc.init.uid", &spec.Process.User.UID}, {"lxc.init.gid", &spec.Process.User.GID}} {
+		if s := first(v.key); s != "" {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s = %s", ErrExportFailed, v.key, s)
+			}
+			*v.id = uint32(id)
+		}
+	}
+
+	// lxc.cap.keep wins over lxc.cap.drop
+	caps := nonEmpty("lxc.cap.keep")
+	if len(caps) == 0 {
+		drop := make(map[string]bool)
+		for _, v := range nonEmpty("lxc.cap.drop") {
+			for _, c := range strings.Fields(v) {
+				drop[c] = true
+			}
+		}
+
+		for _, c := range capabilities {
+			if !drop[c] {
+				caps = append(caps, c)
+			}
+		}
+	}
+
+	var ociCaps []string
+	for _, v := range caps {
+		for _, c := range strings.Fields(v) {
+			if c != "none" {
+				ociCaps = append(ociCaps, "CAP_"+strings.ToUpper(c))
+			}
+		}
+	}
+	// the inheritable set stays empty, it would pass the capabilities on
+	// across the exec of unprivileged programs (CVE-2022-29162)
+	spec.Process.Capabilities = &ociCapabilities{Bounding: ociCaps, Effective: ociCaps, Permitted: ociCaps}
+
+	spec.Mounts = append(spec.Mounts, ociDefaultMounts...)
+	for _, v := range nonEmpty("lxc.mount.entry") {
+		if m, ok := ociMountEntry(v); ok {
+			spec.Mounts = append(spec.Mounts, m)
+		}
+	}
+
+	for _, ns := range []string{"pid", "network", "ipc", "uts", "mount", "cgroup"} {
+		spec.Linux.Namespaces = append(spec.Linux.Namespaces, ociNamespace{Type: ns})
+	}
+
+	if len(idmap) > 0 {
+		spec.Linux.Namespaces = append(spec.Linux.Namespaces, ociNamespace{Type: "user"})
+		for _, e := range idmap {
+			m := ociIDMapping{ContainerID: uint32(e.Nsid), HostID: uint32(e.Hostid), Size: uint32(e.Range)}
+			if e.Type == IDTypeUID {
+				spec.Linux.UIDMappings = append(spec.Linux.UIDMappings, m)
+			} else {
+				spec.Linux.GIDMappings = append(spec.Linux.GIDMappings, m)
+			}
+		}
+	}
+
+	cgroup := func(v1, v2 string) string {
+		if unified {
+			return first("lxc.cgroup2." + v2)
+		}
+		return first("lxc.cgroup." + v1)
+	}
+
+	resources := &ociResources{}
+	if v := cgroup("memory.limit_in_bytes", "memory.max"); v != "" && v != "max" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			size, err := ParseBytes(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: memory limit %q", ErrExportFailed, v)
+			}
+			limit = int64(size)
+		}
+		resources.Memory = &struct {
+			Limit int64 `json:"limit"`
+		}{limit}
+	}
+
+	if v := cgroup("cpuset.cpus", "cpuset.cpus"); v != "" {
+		resources.CPU = &struct {
+			Cpus string `json:"cpus"`
+		}{v}
+	}
+
+	if v := cgroup("pids.max", "pids.max"); v != "" && v != "max" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: pids limit %q", ErrExportFailed, v)
+		}
+		resources.Pids = &struct {
+			Limit int64 `json:"limit"`
+		}{limit}
+	}
+
+	for _, rule := range []struct {
+		file  string
+		allow bool
+	}{{"deny", false}, {"allow", true}} {
+		prefix := "lxc.cgroup.devices."
+		if unified {
+			prefix = "lxc.cgroup2.devices."
+		}
+
+		for _, v := range nonEmpty(prefix + rule.file) {
+			r, err := ParseDeviceRule(v)
+			if err != nil {
+				return nil, err
+			}
+
+			d := ociDeviceCgroup{Allow: rule.allow, Type: string(r.Type), Access: r.Access}
+			if r.Major != DeviceWildcard {
+				major := int64(r.Major)
+				d.Major = &major
+			}
+			if r.Minor != DeviceWildcard {
+				minor := int64(r.Minor)
+				d.Minor = &minor
+			}
+			if r.Type == DeviceTypeAll {
+				d.Access = "rwm"
+			}
+			resources.Devices = append(resources.Devices, d)
+		}
+	}
+
+	if resources.Memory != nil || resources.CPU != nil || resources.Pids != nil || len(resources.Devices) > 0 {
+		spec.Linux.Resources = resources
+	}
+
+	return spec, nil
+}
+
+// ExportOCI writes an OCI runtime bundle of the stopped container to dir:
+// a config.json generated from the LXC configuration where it can be mapped
+// (init command, environment, idmap, mounts, capabilities, cgroup limits and
+// device rules) and a copy of the rootfs. The bundle can be run with runc or
+// crun. Only directory backed root filesystems are supported.
+func (c *Container) ExportOCI(dir string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
+		return err
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ErrNotSupported
+	}
+
+	rootfs := c.rootfsPath()
+	if rootfs == "" {
+		return ErrNotSupported
+	}
+
+	idmap, err := c.idMap()
+	if err != nil {
+		return err
+	}
+
+	spec, err := newOCISpec(c.configItem, idmap, CgroupUnified())
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(spec, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(dir, "rootfs")
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	if output, err := exec.Command("cp", "-a", rootfs+"/.", target).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", ErrExportFailed, strings.TrimSpace(string(output)))
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "config.json"), content, 0644)
+}
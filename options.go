@@ -7,7 +7,10 @@
 package lxc
 
 import (
+	"net/http"
+	"net/netip"
 	"os"
+	"time"
 )
 
 // AttachOptions type is used for defining various attach options.
@@ -28,6 +31,12 @@ type AttachOptions struct {
 	// GID specifies the group id to run as.
 	GID int
 
+	// User specifies the user to run as by name, e.g. "www-data", or as
+	// "user:group". It is looked up in the container and overrides UID and
+	// GID. Groups defaults to the groups the user is a member of, Cwd to
+	// the home directory of the user if empty.
+	User string
+
 	// Groups specifies the list of additional group ids to run with.
 	Groups []int
 
@@ -40,6 +49,15 @@ type AttachOptions struct {
 	// EnvToKeep specifies the environment of the process when ClearEnv is true.
 	EnvToKeep []string
 
+	// EnvToKeepGlob adds the variables of the calling process matching any
+	// of the glob patterns, e.g. "LC_*", to EnvToKeep.
+	EnvToKeepGlob []string
+
+	// Path specifies the PATH of the process unless Env sets one, e.g.
+	// DefaultAttachPath. The PATH of the calling process is kept if empty,
+	// which often doesn't match the layout inside the container.
+	Path string
+
 	// StdinFd specifies the fd to read input from.
 	StdinFd uintptr
 
@@ -54,6 +72,46 @@ type AttachOptions struct {
 	// if the command does not attach to the container's mount namespace.
 	RemountSysProc bool
 
+	// Rlimits are the resource limits of the command. They are applied by
+	// the calling process before the command switches to UID and GID, so
+	// hard limits can be raised as far as the calling process may raise
+	// them.
+	Rlimits []Rlimit
+
+	// Umask specifies the umask of the command, e.g. 0027. The umask of the
+	// calling process is kept if nil.
+	Umask *int
+
+	// KeepCaps keeps the capabilities dropped through lxc.cap.drop in the
+	// bounding set of the command, without the other effects of
+	// ElevatedPrivileges.
+	KeepCaps bool
+
+	// NoNewPrivs sets no_new_privs for the command, so it can't gain
+	// privileges through setuid binaries or file capabilities. It is
+	// always set if the container sets lxc.no_new_privs.
+	NoNewPrivs bool
+
+	// AppArmorProfile runs the command under the given AppArmor profile
+	// instead of the one of the container, e.g. "unconfined". Needs liblxc
+	// 4.0.
+	AppArmorProfile string
+
+	// SELinuxLabel runs the command under the given SELinux context instead
+	// of the one of the container. Needs liblxc 4.0.
+	SELinuxLabel string
+
+	// Recorder records the session of AttachShell, which then runs on a
+	// pty of its own whose input and output are passed on to StdinFd and
+	// StdoutFd.
+	Recorder *SessionRecorder
+
+	// CloseInheritedFds marks the fds of the calling process which would be
+	// inherited by the command close-on-exec before attaching, e.g. the
+	// log file liblxc opened. Stdin, stdout and stderr are kept. The flag
+	// stays set in the calling process, see InheritableFds.
+	CloseInheritedFds bool
+
 	// ElevatedPrivileges runs the command with elevated privileges.
 	// The capabilities, cgroup and security module restrictions of the container are not applied.
 	// WARNING: This may leak privileges into the container.
@@ -67,17 +125,49 @@ var DefaultAttachOptions = AttachOptions{
 	Cwd:                "/",
 	UID:                -1,
 	GID:                -1,
+	User:               "",
 	Groups:             nil,
 	ClearEnv:           false,
 	Env:                nil,
 	EnvToKeep:          nil,
+	EnvToKeepGlob:      nil,
+	Path:               "",
 	StdinFd:            os.Stdin.Fd(),
 	StdoutFd:           os.Stdout.Fd(),
 	StderrFd:           os.Stderr.Fd(),
 	RemountSysProc:     false,
+	Rlimits:            nil,
+	Umask:              nil,
+	KeepCaps:           false,
+	NoNewPrivs:         false,
+	AppArmorProfile:    "",
+	SELinuxLabel:       "",
+	Recorder:           nil,
+	CloseInheritedFds:  false,
 	ElevatedPrivileges: false,
 }
 
+// IPAddressOptions type is used for filtering the IP addresses of a container.
+type IPAddressOptions struct {
+
+	// Interface limits the addresses to the given interface. All interfaces
+	// but the loopback are used if not set.
+	Interface string
+
+	// Family limits the addresses to either "inet" or "inet6". Both are used if not set.
+	Family string
+
+	// Scope is the IPv6 scope id the addresses have to match (0 is global scope).
+	Scope int
+
+	// IncludeLinkLocal includes the IPv4 and IPv6 link-local addresses.
+	IncludeLinkLocal bool
+
+	// WaitForGlobal makes WaitIPAddressesWithOptions wait until a global
+	// unicast address shows up rather than returning on the first address.
+	WaitForGlobal bool
+}
+
 // TemplateOptions type is used for defining various template options.
 type TemplateOptions struct {
 
@@ -121,6 +211,9 @@ type TemplateOptions struct {
 
 	// ExtraArgs provides a way to specify template specific args.
 	ExtraArgs []string
+
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
 }
 
 // BackendStoreSpecs represents a LXC storage backend.
@@ -174,6 +267,10 @@ type ConsoleOptions struct {
 
 	// EscapeCharacter (a means <Ctrl a>, b maens <Ctrl b>).
 	EscapeCharacter rune
+
+	// Recorder records the session, which then runs on a pty of its own
+	// whose input and output are passed on to StdinFd and StdoutFd.
+	Recorder *SessionRecorder
 }
 
 // DefaultConsoleOptions is a convenient set of options to be used.
@@ -183,6 +280,7 @@ var DefaultConsoleOptions = ConsoleOptions{
 	StdoutFd:        os.Stdout.Fd(),
 	StderrFd:        os.Stderr.Fd(),
 	EscapeCharacter: 'a',
+	Recorder:        nil,
 }
 
 // CloneOptions type is used for defining various clone options.
@@ -202,6 +300,20 @@ type CloneOptions struct {
 
 	// Create a snapshot rather than copy.
 	Snapshot bool
+
+	// Derive the MAC addresses from the name of the new container instead of
+	// generating random ones. Ignored if KeepMAC is set.
+	RegenerateMAC bool
+
+	// Set lxc.uts.name and the hostname in /etc/hostname and /etc/hosts of the
+	// new root filesystem to the name of the new container. Ignored if
+	// KeepName is set.
+	UpdateHostname bool
+
+	// PostClone is called with the new container before it is first started,
+	// its root filesystem is accessible at MountedRootfs. Changes to the
+	// configuration are saved. The clone is destroyed if it returns an error.
+	PostClone func(c *Container) error
 }
 
 // DefaultCloneOptions is a convenient set of options to be used.
@@ -209,6 +321,23 @@ var DefaultCloneOptions = CloneOptions{
 	Backend: Directory,
 }
 
+// ConvertStorageOptions type is used for defining storage conversion options.
+type ConvertStorageOptions struct {
+
+	// TemporaryName is the name the converted copy is created under before it
+	// replaces the original container (default: "<name>-convert").
+	TemporaryName string
+
+	// BackupName is the name the original container is kept under until the
+	// converted copy replaced it (default: "<name>-orig").
+	BackupName string
+
+	// DestroySnapshots allows converting a container which has snapshots.
+	// The snapshots are destroyed before the original is renamed, liblxc
+	// can't rename them along.
+	DestroySnapshots bool
+}
+
 // CheckpointOptions type is used for defining checkpoint options for CRIU.
 type CheckpointOptions struct {
 	Directory string
@@ -241,3 +370,676 @@ type ConsoleLogOptions struct {
 	ReadMax        uint64
 	WriteToLogFile bool
 }
+
+// NvidiaOptions type is used for defining the NVIDIA runtime options.
+type NvidiaOptions struct {
+	// Devices is the list of GPU indices exposed to the container (NVIDIA_VISIBLE_DEVICES).
+	// All GPUs are exposed if empty.
+	Devices []int
+
+	// DriverCapabilities is the list of driver features exposed to the container (NVIDIA_DRIVER_CAPABILITIES).
+	DriverCapabilities []string
+
+	// RequireCUDA is a constraint on the CUDA version required by the container (NVIDIA_REQUIRE_CUDA).
+	RequireCUDA string
+
+	// Hook is the path of the LXC nvidia mount hook which mounts the driver libraries into the container.
+	Hook string
+}
+
+// DefaultNvidiaOptions is a convenient set of options to be used.
+var DefaultNvidiaOptions = NvidiaOptions{
+	Devices:            nil,
+	DriverCapabilities: []string{"compute", "utility"},
+	RequireCUDA:        "",
+	Hook:               "/usr/share/lxc/hooks/nvidia",
+}
+
+// ROCmOptions type is used for defining the AMD ROCm runtime options.
+type ROCmOptions struct {
+	// Path is the ROCm installation on the host, bind mounted read-only into the container.
+	// Nothing is mounted if empty.
+	Path string
+}
+
+// DefaultROCmOptions is a convenient set of options to be used.
+var DefaultROCmOptions = ROCmOptions{
+	Path: "/opt/rocm",
+}
+
+// WatchdogAction type specifies what the watchdog does when the container stops responding.
+type WatchdogAction int
+
+const (
+	// WatchdogAlert only reports the failure.
+	WatchdogAlert WatchdogAction = iota
+	// WatchdogRestart reports the failure and restarts the container.
+	WatchdogRestart
+)
+
+// WatchdogOptions type is used for defining the watchdog options.
+type WatchdogOptions struct {
+	// HeartbeatFile is a path inside the container which the guest touches periodically.
+	HeartbeatFile string
+
+	// HeartbeatSocket is the path of a unix socket inside the container.
+	// The guest is expected to answer every connection with at least one byte.
+	HeartbeatSocket string
+
+	// Interval specifies how often the heartbeat is checked.
+	Interval time.Duration
+
+	// Timeout specifies how long the guest may stay silent before it is considered hung.
+	Timeout time.Duration
+
+	// Action specifies what to do when the guest is hung.
+	Action WatchdogAction
+
+	// OnFailure is called with the reason whenever the guest is considered hung.
+	OnFailure func(c *Container, err error)
+}
+
+// DefaultWatchdogOptions is a convenient set of options to be used.
+var DefaultWatchdogOptions = WatchdogOptions{
+	HeartbeatFile:   "/run/heartbeat",
+	HeartbeatSocket: "",
+	Interval:        10 * time.Second,
+	Timeout:         30 * time.Second,
+	Action:          WatchdogAlert,
+	OnFailure:       nil,
+}
+
+// CgroupScopeOptions type is used for defining the systemd unit the container's cgroup is placed in.
+type CgroupScopeOptions struct {
+	// Slice is the systemd slice the scope is created in (e.g. "machine.slice").
+	Slice string
+
+	// Scope is the name of the scope unit (default: "lxc-<name>.scope").
+	Scope string
+}
+
+// DefaultCgroupScopeOptions is a convenient set of options to be used.
+var DefaultCgroupScopeOptions = CgroupScopeOptions{
+	Slice: "machine.slice",
+	Scope: "",
+}
+
+// ContainerInit type is used for defining the process started as init of an
+// application container (lxc.init.*).
+type ContainerInit struct {
+	// Cmd is the command line of init (lxc.init.cmd), /sbin/init if empty.
+	Cmd []string
+
+	// UID is the user id init runs as in the container (lxc.init.uid).
+	UID int
+
+	// GID is the group id init runs as in the container (lxc.init.gid).
+	GID int
+
+	// Cwd is the working directory of init (lxc.init.cwd), / if empty.
+	Cwd string
+
+	// Env are the environment variables of init (lxc.environment) as
+	// KEY=VALUE, a bare KEY passes the variable of the caller.
+	Env []string
+}
+
+// ApplicationOptions type is used for defining the optional settings of
+// NewApplicationContainer.
+type ApplicationOptions struct {
+	// LXCPath is the directory the container is created in, the default
+	// lxcpath if empty.
+	LXCPath string
+
+	// UID and GID are the user and group ids the command runs as.
+	UID int
+	GID int
+
+	// Cwd is the working directory of the command, / if empty.
+	Cwd string
+
+	// Env are the environment variables of the command as KEY=VALUE.
+	Env []string
+
+	// Bridge connects the container to the bridge through a veth pair, the
+	// container only has a loopback interface if empty.
+	Bridge string
+
+	// ReadOnly mounts the rootfs read-only.
+	ReadOnly bool
+
+	// Mounts are additional fstab-like mount entries (lxc.mount.entry),
+	// with the target relative to the rootfs.
+	Mounts []string
+
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// CgroupPlacement type is used for defining where the cgroups of the
+// container are created, relative to the cgroup liblxc is configured to use
+// (lxc.cgroup.pattern) or, with Relative, to the cgroup of the caller.
+type CgroupPlacement struct {
+	// Dir is the cgroup of the container and its monitor (lxc.cgroup.dir).
+	Dir string
+
+	// MonitorDir is the cgroup of the monitor (lxc.cgroup.dir.monitor),
+	// needs ContainerDir and liblxc 4.0.
+	MonitorDir string
+
+	// ContainerDir is the cgroup of the container (lxc.cgroup.dir.container),
+	// needs MonitorDir and liblxc 4.0.
+	ContainerDir string
+
+	// ContainerInner is the cgroup inside ContainerDir the payload is moved
+	// into (lxc.cgroup.dir.container.inner), e.g. so it can delegate the
+	// controllers of ContainerDir. Needs liblxc 4.0.
+	ContainerInner string
+
+	// Relative places the cgroups relative to the cgroup of the process
+	// starting the container (lxc.cgroup.relative), e.g. inside the
+	// delegated cgroup of a systemd user session. Needs liblxc 4.0.
+	Relative bool
+}
+
+// LXDImportOptions type is used for defining the options of an import from LXD.
+type LXDImportOptions struct {
+	// Name of the new container (default: the name of the LXD instance).
+	Name string
+
+	// LXCPath the container is created in (default: DefaultConfigPath()).
+	LXCPath string
+
+	// RawLXC imports the raw.lxc key of the instance. It is skipped by
+	// default, the backup is untrusted and its hooks run as root on the host.
+	RawLXC bool
+
+	// DiskDevices imports the disk devices of the instance as bind mounts.
+	// They are skipped by default, they may bind any path of the host.
+	DiskDevices bool
+
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// ImageOptions type is used for defining the options of CreateFromImage.
+type ImageOptions struct {
+	// Platform of the image to pull, "<os>/<arch>[/<variant>]" (default: the host platform).
+	Platform string
+
+	// Username and Password used to authenticate against the registry.
+	Username string
+	Password string
+
+	// Insecure uses plain HTTP to talk to the registry.
+	Insecure bool
+
+	// HTTPClient used for the registry requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+
+	// Digest pins the manifest the reference resolves to ("sha256:<hex>").
+	Digest string
+
+	// CosignPublicKey is a PEM encoded public key the image must be signed
+	// with by cosign. GPG validation of linuxcontainers images is handled by
+	// the download template, see TemplateOptions.
+	CosignPublicKey []byte
+
+	// Retries is the number of times an interrupted blob download is resumed.
+	Retries int
+
+	// Download configures proxies, mirrors and throttling of the pull
+	// (default: LoadDownloadConfig() of the lxcpath of the container).
+	Download *DownloadConfig
+
+	// CacheDir keeps downloaded blobs, so an interrupted pull is resumed by
+	// the next one (default: a temporary directory removed afterwards).
+	CacheDir string
+
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// DefaultImageOptions is a convenient set of options to be used.
+var DefaultImageOptions = ImageOptions{
+	Platform:        "",
+	Username:        "",
+	Password:        "",
+	Insecure:        false,
+	HTTPClient:      nil,
+	Digest:          "",
+	CosignPublicKey: nil,
+	Retries:         3,
+	Download:        nil,
+	CacheDir:        "",
+}
+
+// DownloadConfig type is used for defining how images are downloaded, see
+// SetGlobalDownloadConfig and SaveDownloadConfig.
+type DownloadConfig struct {
+	// Proxy is the URL of the HTTP proxy (default: the HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY environment variables). It is used by image pulls and
+	// passed to the download template through http_proxy and https_proxy.
+	Proxy string `json:"proxy,omitempty"`
+
+	// Mirrors are registry URLs (e.g. "https://mirror.gcr.io") tried in order
+	// before the registry of the image, failing over to the next one. They
+	// only apply to image pulls, see TemplateOptions.Server for the download
+	// template.
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	// RateLimit is the maximum download rate of image pulls in bytes per
+	// second (0: unlimited).
+	RateLimit ByteSize `json:"rate_limit,omitempty"`
+
+	// Segments is the number of parallel range requests large blobs of
+	// image pulls are downloaded with (0 or 1: sequential).
+	Segments int `json:"segments,omitempty"`
+}
+
+// DefaultDownloadConfig is a convenient set of options to be used.
+var DefaultDownloadConfig = DownloadConfig{
+	Proxy:     "",
+	Mirrors:   nil,
+	RateLimit: 0,
+	Segments:  1,
+}
+
+// AdmissionOptions type is used for defining the capacity of the host containers are admitted to.
+type AdmissionOptions struct {
+	// MemoryOvercommit scales the memory of the host available to memory limits.
+	MemoryOvercommit float64
+
+	// CPUOvercommit scales the CPUs of the host available to CPU limits.
+	CPUOvercommit float64
+
+	// MinFreeDisk is the disk space which has to be left in the lxcpath.
+	MinFreeDisk ByteSize
+}
+
+// DefaultAdmissionOptions is a convenient set of options to be used.
+var DefaultAdmissionOptions = AdmissionOptions{
+	MemoryOvercommit: 1.0,
+	CPUOvercommit:    1.0,
+	MinFreeDisk:      0,
+}
+
+// WarmPoolOptions type is used for defining the containers kept by a WarmPool.
+type WarmPoolOptions struct {
+	// Size is the number of containers kept ready.
+	Size int
+
+	// Prefix of the names of the clones (default: "<base>-warm-").
+	Prefix string
+
+	// Frozen freezes the containers right after they are started, see
+	// StartAndFreeze.
+	Frozen bool
+
+	// Clone specifies how the base container is cloned.
+	Clone CloneOptions
+
+	// RetryInterval is the time to wait after a failed clone or start
+	// (minimum: 1s).
+	RetryInterval time.Duration
+}
+
+// DefaultWarmPoolOptions is a convenient set of options to be used.
+var DefaultWarmPoolOptions = WarmPoolOptions{
+	Size:          2,
+	Prefix:        "",
+	Frozen:        false,
+	Clone:         CloneOptions{Backend: Overlayfs, Snapshot: true},
+	RetryInterval: 5 * time.Second,
+}
+
+// VFOptions type is used for defining the options of a SR-IOV virtual
+// function assigned to a container.
+type VFOptions struct {
+	// Name is the name of the interface in the container.
+	Name string
+
+	// HWAddr is the MAC address the physical function assigns to the VF.
+	HWAddr string
+
+	// VLAN tags the traffic of the VF on the physical function, 0 for
+	// untagged.
+	VLAN int
+
+	// MTU of the interface, 0 for the default.
+	MTU int
+}
+
+// VXLANOptions type is used for defining a VXLAN overlay between hosts.
+type VXLANOptions struct {
+	// VNI is the VXLAN network identifier, the same on all hosts.
+	VNI int
+
+	// Bridge is the bridge the VXLAN interface and the containers are
+	// attached to, vxbr<VNI> if empty.
+	Bridge string
+
+	// Device is the host interface the VXLAN traffic is sent over, chosen
+	// by the routing table if empty.
+	Device string
+
+	// Local is the address of this host the VXLAN traffic is sent from.
+	Local string
+
+	// Peers are the addresses of the other hosts.
+	Peers []string
+
+	// Port is the UDP port of the VXLAN traffic, 4789 if 0.
+	Port int
+
+	// MTU of the container interfaces, 1450 if 0 to leave room for the
+	// VXLAN header on a 1500 bytes link.
+	MTU int
+}
+
+// WireGuardOptions type is used for defining a WireGuard mesh between hosts.
+type WireGuardOptions struct {
+	// Interface is the WireGuard interface of the host, wg-lxc if empty.
+	Interface string
+
+	// ListenPort is the UDP port of the WireGuard traffic, 51820 if 0.
+	ListenPort int
+
+	// PrivateKeyFile is the file holding the private key of the host, as
+	// generated by wg genkey.
+	PrivateKeyFile string
+
+	// Subnet is the network the container subnets of all hosts are
+	// allocated from, e.g. 10.128.0.0/16.
+	Subnet netip.Prefix
+
+	// HostBits is the prefix length of the container subnet of a host, 24
+	// if 0.
+	HostBits int
+
+	// HostIndex is the number of the container subnet of this host in
+	// Subnet, unique across the mesh.
+	HostIndex int
+
+	// Bridge is the bridge the containers of the host are attached to,
+	// wgbr0 if empty.
+	Bridge string
+
+	// Peers are the other hosts of the mesh.
+	Peers []WireGuardPeer
+
+	// MTU of the container interfaces, 1420 if 0 to leave room for the
+	// WireGuard header on a 1500 bytes link.
+	MTU int
+}
+
+// TopSort type specifies the order of the processes reported by Top.
+type TopSort int
+
+const (
+	// TopSortCPU sorts by CPU usage, highest first.
+	TopSortCPU TopSort = iota
+	// TopSortMemory sorts by resident memory, highest first.
+	TopSortMemory
+	// TopSortPID sorts by process id.
+	TopSortPID
+)
+
+// TopOptions type is used for defining the options of Top.
+type TopOptions struct {
+	// Interval specifies how often the processes are sampled.
+	Interval time.Duration
+
+	// Sort specifies the order of the processes.
+	Sort TopSort
+
+	// Limit specifies the maximum number of processes reported, all if 0.
+	Limit int
+}
+
+// DefaultTopOptions is a convenient set of options to be used.
+var DefaultTopOptions = TopOptions{
+	Interval: 2 * time.Second,
+	Sort:     TopSortCPU,
+	Limit:    0,
+}
+
+// LogRotateOptions type is used for defining when and how log files are
+// rotated.
+type LogRotateOptions struct {
+	// MaxSize rotates the log once it grows beyond the size, never if 0.
+	MaxSize ByteSize
+
+	// MaxAge rotates the log once the previous rotation, or the creation
+	// of the log if it was never rotated, is older, never if 0.
+	MaxAge time.Duration
+
+	// Keep specifies the number of rotated logs kept, e.g. lxc.log.1 to
+	// lxc.log.5 for 5.
+	Keep int
+
+	// Compress gzips the rotated logs, e.g. lxc.log.1.gz.
+	Compress bool
+}
+
+// DefaultLogRotateOptions is a convenient set of options to be used.
+var DefaultLogRotateOptions = LogRotateOptions{
+	MaxSize:  10 * MB,
+	MaxAge:   0,
+	Keep:     5,
+	Compress: false,
+}
+
+// ActivatorOptions type is used for defining the socket of an Activator and
+// the service it activates.
+type ActivatorOptions struct {
+	// Network and Address specify the host socket, as passed to
+	// net.Listen, e.g. "tcp" and ":8080".
+	Network string
+	Address string
+
+	// Target specifies the address of the service, e.g. "10.0.3.5:80".
+	// The first address of the container with TargetPort is used if
+	// empty.
+	Target     string
+	TargetPort uint16
+
+	// Timeout specifies how long a connection waits for the container to
+	// start and the service to accept connections.
+	Timeout time.Duration
+}
+
+// DefaultActivatorOptions is a convenient set of options to be used.
+var DefaultActivatorOptions = ActivatorOptions{
+	Network:    "tcp",
+	Address:    "",
+	Target:     "",
+	TargetPort: 0,
+	Timeout:    30 * time.Second,
+}
+
+// IdlePolicy type is used for defining when an idle container is shut down
+// by WatchIdle.
+type IdlePolicy struct {
+	// Timeout specifies how long the container has to be idle.
+	Timeout time.Duration
+
+	// Interval specifies how often the activity is checked.
+	Interval time.Duration
+
+	// Traffic counts network traffic of the container as activity.
+	Traffic bool
+
+	// Processes counts processes besides init as activity.
+	Processes bool
+
+	// ShutdownTimeout specifies how long Shutdown waits before the
+	// container is stopped.
+	ShutdownTimeout time.Duration
+
+	// Bus receives an EventIdle event before an idle container is shut
+	// down, if set.
+	Bus *EventBus
+}
+
+// DefaultIdlePolicy is a convenient set of options to be used.
+var DefaultIdlePolicy = IdlePolicy{
+	Timeout:         15 * time.Minute,
+	Interval:        30 * time.Second,
+	Traffic:         true,
+	Processes:       false,
+	ShutdownTimeout: 30 * time.Second,
+	Bus:             nil,
+}
+
+// VolumeOptions type is used for defining how a volume is created.
+type VolumeOptions struct {
+	// Backend specifies the type of the volume, Directory, Btrfs or ZFS.
+	Backend BackendStore
+
+	// Path registers an existing host directory as Directory volume
+	// instead of creating one.
+	Path string
+
+	// ZFSRoot specifies the dataset ZFS volumes are created below
+	// (default: DefaultZfsRoot()).
+	ZFSRoot string
+}
+
+// DefaultVolumeOptions is a convenient set of options to be used.
+var DefaultVolumeOptions = VolumeOptions{
+	Backend: Directory,
+	Path:    "",
+	ZFSRoot: "",
+}
+
+// VolumeAttachOptions type is used for defining how a volume is mounted into
+// a container.
+type VolumeAttachOptions struct {
+	// Target specifies the absolute path of the volume in the container.
+	Target string
+
+	// ReadOnly mounts the volume read-only.
+	ReadOnly bool
+
+	// UseIdmappedMounts mounts the volume idmapped to the container's
+	// idmap instead of shifting its files on disk, if supported.
+	UseIdmappedMounts bool
+}
+
+// DefaultVolumeAttachOptions is a convenient set of options to be used.
+var DefaultVolumeAttachOptions = VolumeAttachOptions{
+	Target:            "",
+	ReadOnly:          false,
+	UseIdmappedMounts: true,
+}
+
+// TmpfsOptions type is used for defining a tmpfs mounted into a container.
+type TmpfsOptions struct {
+	// Size limits the size of the tmpfs, 0 for the kernel default of half
+	// the RAM.
+	Size ByteSize
+
+	// Inodes limits the number of inodes, 0 for the kernel default.
+	Inodes int64
+
+	// Mode specifies the permissions of the root directory, 0 for the
+	// kernel default 1777. Only permission bits and os.ModeSticky are
+	// allowed.
+	Mode os.FileMode
+
+	// NoSuid ignores setuid and setgid bits.
+	NoSuid bool
+
+	// NoDev disallows access to device nodes.
+	NoDev bool
+
+	// NoExec disallows executing files.
+	NoExec bool
+}
+
+// DefaultShmOptions is a convenient set of options to be used for /dev/shm.
+var DefaultShmOptions = TmpfsOptions{
+	Size:   64 * MB,
+	Inodes: 0,
+	Mode:   os.ModeSticky | 0777,
+	NoSuid: true,
+	NoDev:  true,
+	NoExec: false,
+}
+
+// DefaultTmpOptions is a convenient set of options to be used for /tmp.
+var DefaultTmpOptions = TmpfsOptions{
+	Size:   256 * MB,
+	Inodes: 0,
+	Mode:   os.ModeSticky | 0777,
+	NoSuid: true,
+	NoDev:  true,
+	NoExec: false,
+}
+
+// SwapOptions type is used for defining the swap provisioned for a
+// container.
+type SwapOptions struct {
+	// Backend specifies how the swap is provided.
+	Backend SwapBackend
+
+	// Size specifies the size of the swap.
+	Size ByteSize
+
+	// Priority specifies the priority of the swap file on the host, -1 for
+	// the kernel default. Ignored for zram.
+	Priority int
+}
+
+// DefaultSwapOptions is a convenient set of options to be used.
+var DefaultSwapOptions = SwapOptions{
+	Backend:  SwapFile,
+	Size:     1 * GB,
+	Priority: -1,
+}
+
+// RealtimeOptions type is used for defining the realtime scheduling of a
+// container.
+type RealtimeOptions struct {
+	// Runtime specifies the CPU time realtime tasks of the container get
+	// per Period (cpu.rt_runtime_us).
+	Runtime time.Duration
+
+	// Period specifies the period of the realtime bandwidth
+	// (cpu.rt_period_us).
+	Period time.Duration
+
+	// Priority specifies the highest SCHED_FIFO and SCHED_RR priority
+	// processes of the container can set (prlimit rtprio).
+	Priority int
+
+	// CPUTime limits the CPU time a realtime task may use without blocking
+	// (prlimit rttime), 0 for unlimited.
+	CPUTime time.Duration
+}
+
+// DefaultRealtimeOptions is a convenient set of options to be used.
+var DefaultRealtimeOptions = RealtimeOptions{
+	Runtime:  100 * time.Millisecond,
+	Period:   time.Second,
+	Priority: 50,
+	CPUTime:  0,
+}
+
+// HibernateOptions type is used for defining how a container is hibernated.
+type HibernateOptions struct {
+	// Resident specifies how much memory is left resident, 0 to reclaim
+	// as much as possible.
+	Resident ByteSize
+
+	// CheckpointDirectory specifies where the container is checkpointed
+	// to before hibernating it, if set.
+	CheckpointDirectory string
+}
+
+// DefaultHibernateOptions is a convenient set of options to be used.
+var DefaultHibernateOptions = HibernateOptions{
+	Resident:            0,
+	CheckpointDirectory: "",
+}
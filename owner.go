@@ -0,0 +1,127 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validOwner returns whether owner is a namespaced owner, names separated by
+// slashes (e.g. "billing/api"), each consisting of letters, digits, dots,
+// dashes and underscores.
+func validOwner(owner string) bool {
+	if owner == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(owner, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-", r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ownedBy returns whether owner is the namespace or belongs to it.
+func ownedBy(owner string, namespace string) bool {
+	return owner == namespace || strings.HasPrefix(owner, namespace+"/")
+}
+
+// ownerPath returns the file holding the owner of the container.
+func ownerPath(lxcpath string, name string) string {
+	return filepath.Join(lxcpath, name, "owner")
+}
+
+// readOwner returns the owner of the container, empty if it has none.
+func readOwner(lxcpath string, name string) (string, error) {
+	content, err := ioutil.ReadFile(ownerPath(lxcpath, name))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// writeOwner sets the owner of the container, removing it if empty.
+func writeOwner(lxcpath string, name string, owner string) error {
+	if owner == "" {
+		if err := os.Remove(ownerPath(lxcpath, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if !validOwner(owner) {
+		return fmt.Errorf("%s: %q", ErrInvalidOwner, owner)
+	}
+	return ioutil.WriteFile(ownerPath(lxcpath, name), []byte(owner+"\n"), 0644)
+}
+
+// Owner returns the owner of the container, empty if it has none.
+func (c *Container) Owner() (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return "", err
+	}
+
+	return readOwner(c.configPath(), c.name())
+}
+
+// SetOwner tags the container with the owner, names separated by slashes
+// from the tenant down (e.g. "billing/api"). An empty owner removes the tag.
+func (c *Container) SetOwner(owner string) (err error) {
+	finish, err := c.operation("SetOwner", owner)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	return writeOwner(c.configPath(), c.name(), owner)
+}
+
+// ListByOwner returns the names of the defined containers owned by owner or
+// by owners in its namespace, e.g. "billing/api" for "billing".
+func ListByOwner(owner string, lxcpath ...string) ([]string, error) {
+	if !validOwner(owner) {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidOwner, owner)
+	}
+
+	path := profileConfigPath(lxcpath)
+
+	names, err := DefinedContainerNamesE(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []string
+	for _, name := range names {
+		if o, err := readOwner(path, name); err == nil && o != "" && ownedBy(o, owner) {
+			owned = append(owned, name)
+		}
+	}
+	return owned, nil
+}
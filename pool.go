@@ -0,0 +1,347 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WarmPool maintains a number of pre-started clones of a base container and
+// hands them out on demand.
+type WarmPool struct {
+	base    *Container
+	opts    WarmPoolOptions
+	lxcpath string
+
+	// a slot is taken by every container ready or being added, so
+	// there are at most Size of them
+	ready chan *Container
+	slots chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	mu     sync.Mutex
+	seq    int
+	err    error
+	closed bool
+	custom map[*Container]WarmCustomization
+}
+
+// WarmCustomization is applied to a container handed out by WarmPool.Get.
+type WarmCustomization struct {
+	// Hostname of the container.
+	Hostname string
+
+	// IPv4 address in CIDR notation added to Interface.
+	IPv4 string
+
+	// Interface the address is added to (default: "eth0").
+	Interface string
+}
+
+// NewWarmPool starts filling a pool of clones of base, which must be
+// stopped. The clones are named "<prefix><n>".
+func NewWarmPool(base *Container, opts WarmPoolOptions) (*WarmPool, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("%s: %d", ErrInvalidPoolSize, opts.Size)
+	}
+
+	if !base.Defined() {
+		return nil, ErrNotDefined
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = base.Name() + "-warm-"
+	}
+
+	if opts.RetryInterval < time.Second {
+		opts.RetryInterval = time.Second
+	}
+
+	lxcpath := opts.Clone.ConfigPath
+	if lxcpath == "" {
+		lxcpath = base.ConfigPath()
+	}
+
+	p := &WarmPool{
+		base:    base,
+		opts:    opts,
+		lxcpath: lxcpath,
+		ready:   make(chan *Container, opts.Size),
+		slots:   make(chan struct{}, opts.Size),
+		done:    make(chan struct{}),
+		custom:  make(map[*Container]WarmCustomization),
+	}
+
+	p.wg.Add(1)
+	go p.fill()
+
+	return p, nil
+}
+
+// spawn clones and starts a new container.
+func (p *WarmPool) spawn() (*Container, error) {
+	var name string
+	for {
+		p.mu.Lock()
+		p.seq++
+		name = fmt.Sprintf("%s%d", p.opts.Prefix, p.seq)
+		p.mu.Unlock()
+
+		c, err := NewContainer(name, p.lxcpath)
+		if err != nil {
+			return nil, err
+		}
+		defined := c.Defined()
+		c.Release()
+
+		if !defined {
+			break
+		}
+	}
+
+	if err := p.base.Clone(name, p.opts.Clone); err != nil {
+		return nil, err
+	}
+
+	c, err := NewContainer(name, p.lxcpath)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.opts.Frozen {
+		err = c.StartAndFreeze()
+	} else {
+		err = c.Start()
+	}
+
+	if err != nil {
+		c.Destroy()
+		c.Release()
+		return nil, err
+	}
+	return c, nil
+}
+
+// fill keeps the pool at its size until it is closed.
+func (p *WarmPool) fill() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		select {
+		case p.slots <- struct{}{}:
+		case <-p.done:
+			return
+		}
+
+		c, err := p.spawn()
+
+		p.mu.Lock()
+		p.err = err
+		p.mu.Unlock()
+
+		if err != nil {
+			<-p.slots
+
+			select {
+			case <-p.done:
+				return
+			case <-time.After(p.opts.RetryInterval):
+			}
+			continue
+		}
+
+		// there is room for it, the slot was taken above
+		p.ready <- c
+	}
+}
+
+// discard stops, destroys and releases the container.
+func discard(c *Container) {
+	if c.State() == FROZEN {
+		c.Unfreeze()
+	}
+
+	if c.Running() {
+		c.Stop()
+	}
+
+	c.Destroy()
+	c.Release()
+}
+
+// customizeCommands returns the commands applying the customization.
+func customizeCommands(custom WarmCustomization) [][]string {
+	var commands [][]string
+
+	if custom.Hostname != "" {
+		commands = append(commands, []string{"/bin/sh", "-c", `hostname "$1" && echo "$1" > /etc/hostname`, "sh", custom.Hostname})
+	}
+
+	if custom.IPv4 != "" {
+		commands = append(commands, []string{"ip", "addr", "add", custom.IPv4, "dev", custom.iface()})
+	}
+	return commands
+}
+
+// revertCommands returns the commands undoing the customization, hostname
+// is the one the container had before.
+func revertCommands(custom WarmCustomization, hostname string) [][]string {
+	var commands [][]string
+
+	if custom.IPv4 != "" {
+		commands = append(commands, []string{"ip", "addr", "del", custom.IPv4, "dev", custom.iface()})
+	}
+
+	if custom.Hostname != "" {
+		commands = append(commands, []string{"/bin/sh", "-c", `hostname "$1" && echo "$1" > /etc/hostname`, "sh", hostname})
+	}
+	return commands
+}
+
+// iface returns the interface the address is added to.
+func (custom WarmCustomization) iface() string {
+	if custom.Interface == "" {
+		return "eth0"
+	}
+	return custom.Interface
+}
+
+// attachCommands runs the commands in the running container, stopping at the
+// first which fails.
+func attachCommands(c *Container, commands [][]string) error {
+	for _, args := range commands {
+		status, err := c.RunCommandStatus(args, DefaultAttachOptions)
+		if err != nil {
+			return err
+		}
+
+		if status != 0 {
+			return fmt.Errorf("%s: %q exited with %d", ErrAttachFailed, args, status)
+		}
+	}
+	return nil
+}
+
+// Get hands out a container of the pool, thawed and customized, waiting for
+// one to become ready until ctx is done. The container is owned by the caller
+// until it is given back with Put.
+func (p *WarmPool) Get(ctx context.Context, custom WarmCustomization) (*Container, error) {
+	var c *Container
+	select {
+	case c = <-p.ready:
+		<-p.slots
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// attaching doesn't need init to have booted
+	if c.State() == FROZEN {
+		if err := c.Unfreeze(); err != nil {
+			discard(c)
+			return nil, err
+		}
+	}
+
+	if err := attachCommands(c, customizeCommands(custom)); err != nil {
+		discard(c)
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.custom[c] = custom
+	p.mu.Unlock()
+
+	return c, nil
+}
+
+// Put gives a container back. If reuse is set and the pool isn't full, its
+// customization is undone and it is handed out again (frozen again for
+// frozen pools), otherwise it is destroyed and replaced by a fresh clone.
+func (p *WarmPool) Put(c *Container, reuse bool) error {
+	p.mu.Lock()
+	custom, ok := p.custom[c]
+	delete(p.custom, c)
+	p.mu.Unlock()
+
+	if reuse && ok {
+		hostname := c.Name()
+		if uts := c.ConfigItem("lxc.uts.name"); uts[0] != "" {
+			hostname = uts[0]
+		}
+
+		if err := attachCommands(c, revertCommands(custom, hostname)); err != nil {
+			discard(c)
+			return err
+		}
+
+		if p.opts.Frozen {
+			if err := c.Freeze(); err != nil {
+				discard(c)
+				return err
+			}
+		}
+
+		p.mu.Lock()
+		if !p.closed {
+			select {
+			case p.slots <- struct{}{}:
+				p.ready <- c
+				p.mu.Unlock()
+				return nil
+			default:
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	discard(c)
+	return nil
+}
+
+// Err returns the error of the last attempt to add a container to the pool.
+func (p *WarmPool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// Close stops filling the pool and destroys the containers which weren't
+// handed out.
+func (p *WarmPool) Close() {
+	p.once.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		close(p.done)
+		p.wg.Wait()
+
+		for {
+			select {
+			case c := <-p.ready:
+				<-p.slots
+				discard(c)
+			default:
+				return
+			}
+		}
+	})
+}
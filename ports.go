@@ -0,0 +1,265 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PortForward is a host port forwarded to the container, e.g. by an
+// iptables DNAT rule or a proxy of the caller.
+type PortForward struct {
+	// Protocol is "tcp" or "udp".
+	Protocol string
+	// Listen is the address and port on the host, an unspecified address
+	// for all addresses of the host.
+	Listen netip.AddrPort
+	// Port is the port in the container.
+	Port uint16
+}
+
+// String returns the forward in the form "tcp 0.0.0.0:80 -> 8080".
+func (p PortForward) String() string {
+	return fmt.Sprintf("%s %s -> %d", p.Protocol, p.Listen, p.Port)
+}
+
+// overlaps returns whether a socket of the protocol, e.g. "tcp6", bound to
+// addr uses the host port of the forward. Unspecified addresses overlap
+// with all addresses of both families.
+func (p PortForward) overlaps(protocol string, addr netip.AddrPort) bool {
+	if strings.TrimSuffix(protocol, "6") != p.Protocol || addr.Port() != p.Listen.Port() {
+		return false
+	}
+
+	a, b := p.Listen.Addr().Unmap(), addr.Addr().Unmap()
+	return a.IsUnspecified() || b.IsUnspecified() || a == b
+}
+
+// portConflicts returns the conflicts of the forwards with the host
+// listeners and the forwards of other containers.
+func portConflicts(forwards []PortForward, listening []ListeningPort, others map[string][]PortForward) []string {
+	names := make([]string, 0, len(others))
+	for name := range others {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var conflicts []string
+	for _, p := range forwards {
+		for _, l := range listening {
+			if p.overlaps(l.Protocol, l.Address) {
+				conflicts = append(conflicts, fmt.Sprintf("%s %s is used by a host listener on %s", p.Protocol, p.Listen, l.Address))
+			}
+		}
+
+		for _, name := range names {
+			for _, o := range others[name] {
+				if p.overlaps(o.Protocol, o.Listen) {
+					conflicts = append(conflicts, fmt.Sprintf("%s %s is forwarded to container %q as well", p.Protocol, p.Listen, name))
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// hostListeningPorts returns the listening sockets of the network namespace
+// of the calling process.
+func hostListeningPorts() ([]ListeningPort, error) {
+	stats := NetNSStats{TCPStates: make(map[string]int)}
+
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		content, err := ioutil.ReadFile(filepath.Join("/proc/net", protocol))
+		if err != nil {
+			// no IPv6 on the host
+			continue
+		}
+
+		if err := parseProcNet(string(content), protocol, &stats); err != nil {
+			return nil, err
+		}
+	}
+	return stats.Listening, nil
+}
+
+// portForwardsPath returns the file recording the port forwards of the
+// container.
+func portForwardsPath(lxcpath string, name string) string {
+	return filepath.Join(lxcpath, name, "ports.json")
+}
+
+// readPortForwards returns the port forwards of the container.
+func readPortForwards(lxcpath string, name string) ([]PortForward, error) {
+	var forwards []PortForward
+
+	content, err := ioutil.ReadFile(portForwardsPath(lxcpath, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &forwards); err != nil {
+		return nil, err
+	}
+	return forwards, nil
+}
+
+// savePortForwards records the port forwards of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) savePortForwards(forwards []PortForward) error {
+	path := portForwardsPath(c.configPath(), c.name())
+
+	if len(forwards) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	content, err := json.MarshalIndent(forwards, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// PortForwards returns the host ports forwarded to the container.
+func (c *Container) PortForwards() ([]PortForward, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	return readPortForwards(c.configPath(), c.name())
+}
+
+// AddPortForward records a host port forwarded to the container, so that
+// conflicts are detected before it starts. Setting up the forward itself
+// is up to the caller.
+func (c *Container) AddPortForward(p PortForward) (err error) {
+	finish, err := c.operation("AddPortForward", p.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	if (p.Protocol != "tcp" && p.Protocol != "udp") || !p.Listen.IsValid() || p.Listen.Port() == 0 || p.Port == 0 {
+		return fmt.Errorf("%s: invalid port forward %q", ErrInvalidNetwork, p)
+	}
+
+	forwards, err := readPortForwards(c.configPath(), c.name())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range forwards {
+		if f.overlaps(p.Protocol, p.Listen) {
+			return fmt.Errorf("%s: %s overlaps with %s", ErrPortConflict, p, f)
+		}
+	}
+
+	return c.savePortForwards(append(forwards, p))
+}
+
+// RemovePortForward removes a host port forwarded to the container.
+func (c *Container) RemovePortForward(p PortForward) (err error) {
+	finish, err := c.operation("RemovePortForward", p.String())
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	forwards, err := readPortForwards(c.configPath(), c.name())
+	if err != nil {
+		return err
+	}
+
+	for i, f := range forwards {
+		if f == p {
+			return c.savePortForwards(append(forwards[:i], forwards[i+1:]...))
+		}
+	}
+	return nil
+}
+
+// checkPortConflicts checks the host ports forwarded to the container are
+// neither used by host listeners nor forwarded to another container of the
+// lxcpath.
+//
+// Caller needs to hold the lock
+func (c *Container) checkPortConflicts() error {
+	forwards, err := readPortForwards(c.configPath(), c.name())
+	if err != nil || len(forwards) == 0 {
+		return err
+	}
+
+	listening, err := hostListeningPorts()
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(portForwardsPath(c.configPath(), "*"))
+	if err != nil {
+		return err
+	}
+
+	others := make(map[string][]PortForward)
+	for _, path := range paths {
+		name := filepath.Base(filepath.Dir(path))
+		if name == c.name() {
+			continue
+		}
+
+		if others[name], err = readPortForwards(c.configPath(), name); err != nil {
+			return err
+		}
+	}
+
+	if conflicts := portConflicts(forwards, listening, others); len(conflicts) > 0 {
+		return fmt.Errorf("%s: %s", ErrPortConflict, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// CheckPortConflicts checks the host ports forwarded to the container are
+// neither used by host listeners nor forwarded to another container of the
+// lxcpath. Start runs the check as well.
+func (c *Container) CheckPortConflicts() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	return c.checkPortConflicts()
+}
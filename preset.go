@@ -0,0 +1,239 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Preset is a bundle of config items applied to a container as a unit.
+type Preset struct {
+	Name   string
+	Config []KeyValue
+}
+
+// Presets are the built-in presets. Their config items are the documented
+// deltas applied to the configuration of the container, keys accumulating
+// their values (e.g. lxc.mount.auto) are extended, an empty value clears a
+// key.
+var Presets = struct {
+	// Hardened drops dangerous capabilities, confines the container with
+	// the generated apparmor profile and the LXC default seccomp policy,
+	// mounts /proc, /sys and the cgroups mixed (read-only where possible)
+	// and sets no_new_privs for init:
+	//
+	//	lxc.cap.drop = mac_admin mac_override sys_module sys_rawio sys_time
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_nesting = 0
+	//	lxc.seccomp.profile = /usr/share/lxc/config/common.seccomp
+	//	lxc.mount.auto = proc:mixed sys:mixed cgroup:mixed
+	//	lxc.no_new_privs = 1
+	Hardened Preset
+
+	// Compatibility relaxes the confinement for old distributions and
+	// payloads expecting writable /proc and /sys:
+	//
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_incomplete = 1
+	//	lxc.mount.auto = proc:rw sys:rw cgroup:rw
+	//	lxc.no_new_privs = 0
+	Compatibility Preset
+
+	// Nested allows running containers inside the container:
+	//
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_nesting = 1
+	//	lxc.mount.auto = proc:rw sys:rw cgroup:rw:force
+	Nested Preset
+}{
+	Hardened: Preset{
+		Name: "hardened",
+		Config: []KeyValue{
+			{"lxc.cap.drop", "mac_admin mac_override sys_module sys_rawio sys_time"},
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_nesting", "0"},
+			{"lxc.seccomp.profile", "/usr/share/lxc/config/common.seccomp"},
+			{"lxc.mount.auto", "proc:mixed sys:mixed cgroup:mixed"},
+			{"lxc.no_new_privs", "1"},
+		},
+	},
+	Compatibility: Preset{
+		Name: "compatibility",
+		Config: []KeyValue{
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_incomplete", "1"},
+			{"lxc.mount.auto", "proc:rw sys:rw cgroup:rw"},
+			{"lxc.no_new_privs", "0"},
+		},
+	},
+	Nested: Preset{
+		Name: "nested",
+		Config: []KeyValue{
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_nesting", "1"},
+			{"lxc.mount.auto", "proc:rw sys:rw cgroup:rw:force"},
+		},
+	},
+}
+
+// appliedPresetsPath returns the file recording the applied presets.
+//
+// Caller needs to hold the lock
+func (c *Container) appliedPresetsPath() string {
+	return filepath.Join(c.configPath(), c.name(), "presets.json")
+}
+
+// appliedPresets returns the applied presets in the order they were applied.
+//
+// Caller needs to hold the lock
+func (c *Container) appliedPresets() ([]attachedProfile, error) {
+	var applied []attachedProfile
+
+	content, err := ioutil.ReadFile(c.appliedPresetsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// saveAppliedPresets records the applied presets and saves the
+// configuration of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) saveAppliedPresets(applied []attachedProfile) error {
+	path := c.appliedPresetsPath()
+
+	if len(applied) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		content, err := json.MarshalIndent(applied, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config"))
+}
+
+// AppliedPresets returns the names of the presets applied to the container.
+func (c *Container) AppliedPresets() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	applied, err := c.appliedPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, preset := range applied {
+		names = append(names, preset.Name)
+	}
+	return names, nil
+}
+
+// ApplyPreset applies the preset, e.g. Presets.Hardened, to the
+// configuration of the container and saves it. The values it replaces are
+// recorded for RevertPreset. Applying an applied preset again is a no-op.
+func (c *Container) ApplyPreset(preset Preset) (err error) {
+	finish, err := c.operation("ApplyPreset", preset.Name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		return ErrNotSupported
+	}
+
+	if !validProfileName(preset.Name) {
+		return fmt.Errorf("%s: %q", ErrInvalidProfile, preset.Name)
+	}
+
+	applied, err := c.appliedPresets()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range applied {
+		if p.Name == preset.Name {
+			return nil
+		}
+	}
+
+	items, err := c.applyProfileItems(preset.Config)
+	if err != nil {
+		c.unwindProfileItems(items)
+		return err
+	}
+
+	return c.saveAppliedPresets(append(applied, attachedProfile{Name: preset.Name, Items: items}))
+}
+
+// RevertPreset reverts the named preset, restoring the values it replaced
+// and removing the values it added, and saves the configuration of the
+// container. Presets applied later which set the same keys should be
+// reverted first.
+func (c *Container) RevertPreset(name string) (err error) {
+	finish, err := c.operation("RevertPreset", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	applied, err := c.appliedPresets()
+	if err != nil {
+		return err
+	}
+
+	for i, p := range applied {
+		if p.Name != name {
+			continue
+		}
+
+		if err := c.unwindProfileItems(p.Items); err != nil {
+			return err
+		}
+		return c.saveAppliedPresets(append(applied[:i], applied[i+1:]...))
+	}
+
+	return fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+}
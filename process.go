@@ -0,0 +1,151 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// attachHelperArg marks an invocation of the current executable as the
+// parent of a command started by RunCommandAsync.
+const attachHelperArg = "__go_lxc_attach__"
+
+// AttachedProcess is a command started by RunCommandAsync.
+type AttachedProcess struct {
+	// Pid is the process ID of the attached command seen from outside the container.
+	Pid int
+
+	helper int
+	status *os.File
+
+	once       sync.Once
+	exitStatus int
+	err        error
+}
+
+func newAttachedProcess(helper int, status *os.File) (*AttachedProcess, error) {
+	p := &AttachedProcess{helper: helper, status: status}
+
+	pid, err := p.readInt()
+	if err != nil {
+		p.status.Close()
+		p.reapHelper()
+		return nil, ErrAttachFailed
+	}
+	p.Pid = pid
+
+	return p, nil
+}
+
+// readInt reads a single C int written by the helper process.
+func (p *AttachedProcess) readInt() (int, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(p.status, buf[:]); err != nil {
+		return -1, err
+	}
+	return int(*(*int32)(unsafe.Pointer(&buf[0]))), nil
+}
+
+// writeInt writes a single C int as read by AttachedProcess.
+func writeInt(w io.Writer, v int) error {
+	var buf [4]byte
+	*(*int32)(unsafe.Pointer(&buf[0])) = int32(v)
+
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// runAttachHelper runs the command in the container as a child of the
+// helper, which is a fresh process: liblxc forks while attaching, which
+// isn't safe in the multithreaded caller. The resolved attach options are
+// read from fd 4, the pid and then the wait status of the command are
+// written to fd 3. Stdin, stdout and stderr are passed on to the command.
+func runAttachHelper(name string, lxcpath string, args []string) error {
+	// the command must not inherit them
+	unix.CloseOnExec(3)
+	unix.CloseOnExec(4)
+
+	status := os.NewFile(3, "attach status")
+	defer status.Close()
+
+	input := os.NewFile(4, "attach options")
+	var options AttachOptions
+	err := json.NewDecoder(input).Decode(&options)
+	input.Close()
+	if err != nil {
+		return err
+	}
+	options.StdinFd, options.StdoutFd, options.StderrFd = 0, 1, 2
+
+	c, err := NewContainer(name, lxcpath)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	pid, err := c.RunCommandNoWait(args, options)
+	if err != nil {
+		return err
+	}
+
+	if err := writeInt(status, pid); err != nil {
+		return err
+	}
+
+	var ws unix.WaitStatus
+	for {
+		_, err = unix.Wait4(pid, &ws, 0, nil)
+		if err != unix.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return writeInt(status, int(ws))
+}
+
+// reapHelper collects the helper process. Someone else may have reaped it
+// already, which is harmless as the status is delivered over the pipe.
+func (p *AttachedProcess) reapHelper() {
+	for {
+		_, err := unix.Wait4(p.helper, nil, 0, nil)
+		if err != unix.EINTR {
+			return
+		}
+	}
+}
+
+// Wait waits for the command to exit and returns its exit status. A command
+// terminated by a signal reports an exit status of -1. Wait may be called
+// several times and from several goroutines.
+func (p *AttachedProcess) Wait() (int, error) {
+	p.once.Do(func() {
+		defer p.reapHelper()
+		defer p.status.Close()
+
+		status, err := p.readInt()
+		if err != nil {
+			p.exitStatus, p.err = -1, ErrAttachFailed
+			return
+		}
+		p.exitStatus = unix.WaitStatus(status).ExitStatus()
+	})
+
+	return p.exitStatus, p.err
+}
+
+// Signal sends a signal to the command.
+func (p *AttachedProcess) Signal(sig unix.Signal) error {
+	return unix.Kill(p.Pid, sig)
+}
@@ -0,0 +1,398 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of config items and devices shared by containers,
+// similar to LXD profiles.
+type Profile struct {
+	Name string `json:"name"`
+	// Config are the config items set by the profile, in order.
+	Config []KeyValue `json:"config,omitempty"`
+	// Devices are host device nodes allowed and bind mounted into the
+	// container at the same path.
+	Devices []string `json:"devices,omitempty"`
+}
+
+// profileDir returns the directory the profiles of lxcpath are stored in.
+func profileDir(lxcpath string) string {
+	return filepath.Join(lxcpath, "profiles")
+}
+
+// SaveProfile stores the profile under lxcpath (default:
+// DefaultConfigPath()), replacing a profile of the same name. Containers it
+// is attached to pick the changes up when a profile is attached to or
+// detached from them next.
+func SaveProfile(profile Profile, lxcpath ...string) error {
+	if !validProfileName(profile.Name) {
+		return fmt.Errorf("%s: %q", ErrInvalidProfile, profile.Name)
+	}
+
+	for _, kv := range profile.Config {
+		if !strings.HasPrefix(kv.Key, "lxc.") {
+			return fmt.Errorf("%s: %q", ErrInvalidProfile, kv.Key)
+		}
+	}
+
+	dir := profileDir(profileConfigPath(lxcpath))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(profile, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, profile.Name+".json"), content, 0644)
+}
+
+// LoadProfile returns the named profile stored under lxcpath.
+func LoadProfile(name string, lxcpath ...string) (Profile, error) {
+	var profile Profile
+
+	if !validProfileName(name) {
+		return profile, fmt.Errorf("%s: %q", ErrInvalidProfile, name)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(profileDir(profileConfigPath(lxcpath)), name+".json"))
+	if os.IsNotExist(err) {
+		return profile, fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	} else if err != nil {
+		return profile, err
+	}
+
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return profile, fmt.Errorf("%s: %v", ErrInvalidProfile, err)
+	}
+	profile.Name = name
+	return profile, nil
+}
+
+// DeleteProfile removes the named profile stored under lxcpath. Containers
+// it is attached to keep its settings until it is detached.
+func DeleteProfile(name string, lxcpath ...string) error {
+	if !validProfileName(name) {
+		return fmt.Errorf("%s: %q", ErrInvalidProfile, name)
+	}
+
+	err := os.Remove(filepath.Join(profileDir(profileConfigPath(lxcpath)), name+".json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	}
+	return err
+}
+
+// Profiles returns the names of the profiles stored under lxcpath.
+func Profiles(lxcpath ...string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(profileDir(profileConfigPath(lxcpath)), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(match), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// profileItem records a config item set by a profile. Items of keys taking
+// a single value replaced the previous values, which are restored when the
+// profile is detached.
+type profileItem struct {
+	Key      string   `json:"key"`
+	Value    string   `json:"value"`
+	Replaced bool     `json:"replaced,omitempty"`
+	Previous []string `json:"previous,omitempty"`
+}
+
+// attachedProfile records the items set by an attached profile.
+type attachedProfile struct {
+	Name  string        `json:"name"`
+	Items []profileItem `json:"items"`
+}
+
+// profileItems returns the config items the profile sets.
+func profileItems(profile Profile) ([]KeyValue, error) {
+	devices, err := deviceProfileItems(DeviceProfile{Devices: profile.Devices})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]KeyValue{}, profile.Config...), devices...), nil
+}
+
+// nonEmpty drops the empty values returned by liblxc for unset keys.
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// applyProfileItems sets the items, recording what they replaced.
+//
+// Caller needs to hold the lock
+func (c *Container) applyProfileItems(items []KeyValue) ([]profileItem, error) {
+	var applied []profileItem
+
+	for _, kv := range items {
+		previous := nonEmpty(c.configItem(kv.Key))
+
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return applied, fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+		}
+
+		item := profileItem{Key: kv.Key, Value: kv.Value}
+		if len(nonEmpty(c.configItem(kv.Key))) <= len(previous) {
+			item.Replaced = true
+			item.Previous = previous
+		}
+		applied = append(applied, item)
+	}
+	return applied, nil
+}
+
+// unwindProfileItems undoes applyProfileItems, in reverse order.
+//
+// Caller needs to hold the lock
+func (c *Container) unwindProfileItems(items []profileItem) error {
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+
+		if !item.Replaced {
+			if err := c.removeConfigValue(item.Key, item.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.clearConfigItem(item.Key); err != nil {
+			return err
+		}
+
+		for _, v := range item.Previous {
+			if err := c.setConfigItem(item.Key, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attachedProfilesPath returns the file recording the attached profiles.
+//
+// Caller needs to hold the lock
+func (c *Container) attachedProfilesPath() string {
+	return filepath.Join(c.configPath(), c.name(), "profiles.json")
+}
+
+// attachedProfiles returns the attached profiles in the order they apply.
+//
+// Caller needs to hold the lock
+func (c *Container) attachedProfiles() ([]attachedProfile, error) {
+	var attached []attachedProfile
+
+	content, err := ioutil.ReadFile(c.attachedProfilesPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &attached); err != nil {
+		return nil, err
+	}
+	return attached, nil
+}
+
+// setAttachedProfiles unwinds the attached profiles, applies the named ones
+// in order from their current definitions and saves the configuration. Later
+// profiles override the keys taking a single value set by earlier ones,
+// which override the configuration of the container itself. Attached
+// profiles deleted in the meantime apply the items they were attached with.
+//
+// Caller needs to hold the lock
+func (c *Container) setAttachedProfiles(names []string) error {
+	attached, err := c.attachedProfiles()
+	if err != nil {
+		return err
+	}
+
+	recorded := make(map[string][]KeyValue)
+	for _, profile := range attached {
+		var items []KeyValue
+		for _, item := range profile.Items {
+			items = append(items, KeyValue{item.Key, item.Value})
+		}
+		recorded[profile.Name] = items
+	}
+
+	itemsOf := make([][]KeyValue, len(names))
+	for i, name := range names {
+		profile, err := LoadProfile(name, c.configPath())
+		if err == nil {
+			itemsOf[i], err = profileItems(profile)
+		}
+
+		if items, ok := recorded[name]; ok && err != nil {
+			itemsOf[i], err = items, nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := len(attached) - 1; i >= 0; i-- {
+		if err := c.unwindProfileItems(attached[i].Items); err != nil {
+			return err
+		}
+	}
+
+	previous := attached
+	attached = nil
+	for i, name := range names {
+		applied, err := c.applyProfileItems(itemsOf[i])
+		attached = append(attached, attachedProfile{Name: name, Items: applied})
+
+		if err != nil {
+			// restore the profiles attached before
+			for j := len(attached) - 1; j >= 0; j-- {
+				c.unwindProfileItems(attached[j].Items)
+			}
+			for _, profile := range previous {
+				c.applyProfileItems(recorded[profile.Name])
+			}
+			return err
+		}
+	}
+
+	path := c.attachedProfilesPath()
+	if len(attached) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		content, err := json.MarshalIndent(attached, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config"))
+}
+
+// AttachedProfiles returns the names of the profiles attached to the
+// container, in the order they apply.
+func (c *Container) AttachedProfiles() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	return c.attachedProfileNames()
+}
+
+// AttachProfile attaches the named profile stored under the lxcpath of the
+// container and saves its configuration. Profiles apply in the order they
+// were attached, every attached profile is applied again from its current
+// definition. Attaching an attached profile refreshes it in place.
+func (c *Container) AttachProfile(name string) (err error) {
+	finish, err := c.operation("AttachProfile", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	names, err := c.attachedProfileNames()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range names {
+		if n == name {
+			return c.setAttachedProfiles(names)
+		}
+	}
+	return c.setAttachedProfiles(append(names, name))
+}
+
+// DetachProfile detaches the named profile, restoring the values it replaced,
+// and saves the configuration of the container.
+func (c *Container) DetachProfile(name string) (err error) {
+	finish, err := c.operation("DetachProfile", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	names, err := c.attachedProfileNames()
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	for _, n := range names {
+		if n != name {
+			remaining = append(remaining, n)
+		}
+	}
+
+	if len(remaining) == len(names) {
+		return fmt.Errorf("%s: %q", ErrProfileNotFound, name)
+	}
+	return c.setAttachedProfiles(remaining)
+}
+
+// attachedProfileNames returns the names of the attached profiles.
+//
+// Caller needs to hold the lock
+func (c *Container) attachedProfileNames() ([]string, error) {
+	attached, err := c.attachedProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(attached))
+	for i, profile := range attached {
+		names[i] = profile.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,276 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Project puts aggregate quotas on the containers of an owner namespace, the
+// containers owned by its name or by owners below it (see
+// Container.SetOwner). A quota of 0 is unlimited.
+type Project struct {
+	// Name is the owner namespace, e.g. "billing".
+	Name string `json:"name"`
+	// Memory is the total of the memory limits of the running containers.
+	Memory ByteSize `json:"memory,omitempty"`
+	// CPUs is the total of the CPU limits of the running containers.
+	CPUs float64 `json:"cpus,omitempty"`
+	// Disk is the total disk space used by the containers and their
+	// snapshots, see Container.DiskUsage.
+	Disk ByteSize `json:"disk,omitempty"`
+}
+
+// ProjectUsageInfo describes what the containers of a project use of its
+// quotas.
+type ProjectUsageInfo struct {
+	Containers []string
+	Running    []string
+	Memory     ByteSize
+	CPUs       float64
+	Disk       ByteSize
+}
+
+// projectDir returns the directory the projects of lxcpath are stored in.
+func projectDir(lxcpath string) string {
+	return filepath.Join(lxcpath, "projects")
+}
+
+// projectFile returns the file the named project is stored in, the slashes
+// of the namespace are escaped.
+func projectFile(lxcpath string, name string) string {
+	return filepath.Join(projectDir(lxcpath), strings.Replace(name, "/", "%", -1)+".json")
+}
+
+// SaveProject stores the project under lxcpath (default:
+// DefaultConfigPath()), replacing a project of the same name. The quotas
+// apply from the next start of its containers on.
+func SaveProject(project Project, lxcpath ...string) error {
+	if !validOwner(project.Name) {
+		return fmt.Errorf("%s: %q", ErrInvalidOwner, project.Name)
+	}
+
+	if project.Memory < 0 || project.CPUs < 0 || project.Disk < 0 {
+		return fmt.Errorf("%s: negative quota", ErrInvalidLimit)
+	}
+
+	path := profileConfigPath(lxcpath)
+	if err := os.MkdirAll(projectDir(path), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(project, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(projectFile(path, project.Name), content, 0644)
+}
+
+// LoadProject returns the named project stored under lxcpath.
+func LoadProject(name string, lxcpath ...string) (Project, error) {
+	var project Project
+
+	if !validOwner(name) {
+		return project, fmt.Errorf("%s: %q", ErrInvalidOwner, name)
+	}
+
+	content, err := ioutil.ReadFile(projectFile(profileConfigPath(lxcpath), name))
+	if os.IsNotExist(err) {
+		return project, fmt.Errorf("%s: %q", ErrProjectNotFound, name)
+	} else if err != nil {
+		return project, err
+	}
+
+	if err := json.Unmarshal(content, &project); err != nil {
+		return project, err
+	}
+	project.Name = name
+	return project, nil
+}
+
+// DeleteProject removes the named project stored under lxcpath, its
+// containers are no longer limited by its quotas.
+func DeleteProject(name string, lxcpath ...string) error {
+	if !validOwner(name) {
+		return fmt.Errorf("%s: %q", ErrInvalidOwner, name)
+	}
+
+	err := os.Remove(projectFile(profileConfigPath(lxcpath), name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %q", ErrProjectNotFound, name)
+	}
+	return err
+}
+
+// Projects returns the names of the projects stored under lxcpath.
+func Projects(lxcpath ...string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(projectDir(profileConfigPath(lxcpath)), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = strings.Replace(strings.TrimSuffix(filepath.Base(match), ".json"), "%", "/", -1)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ownerProjects returns the projects of lxcpath the owner belongs to, those
+// of its namespace and of the namespaces above.
+func ownerProjects(owner string, lxcpath string) ([]Project, error) {
+	var projects []Project
+
+	parts := strings.Split(owner, "/")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], "/")
+		if _, err := os.Stat(projectFile(lxcpath, name)); os.IsNotExist(err) {
+			continue
+		}
+
+		project, err := LoadProject(name, lxcpath)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// projectUsage returns the usage of the project by the containers of
+// lxcpath, ignoring the reservation of the container named exclude. Walking
+// the filesystems for the disk usage is skipped unless disk is set.
+func projectUsage(project Project, lxcpath string, exclude string, disk bool) (ProjectUsageInfo, error) {
+	var usage ProjectUsageInfo
+
+	names, err := DefinedContainerNamesE(lxcpath)
+	if err != nil {
+		return usage, err
+	}
+
+	for _, name := range names {
+		owner, err := readOwner(lxcpath, name)
+		if err != nil || owner == "" || !ownedBy(owner, project.Name) {
+			continue
+		}
+		usage.Containers = append(usage.Containers, name)
+
+		c, err := NewContainer(name, lxcpath)
+		if err != nil {
+			return usage, err
+		}
+
+		err = func() error {
+			defer c.Release()
+
+			if c.Running() {
+				usage.Running = append(usage.Running, name)
+
+				if name != exclude {
+					c.mu.RLock()
+					memory, cpus, err := c.reservation()
+					c.mu.RUnlock()
+
+					if err != nil {
+						return err
+					}
+					usage.Memory += memory
+					usage.CPUs += cpus
+				}
+			}
+
+			if disk {
+				du, err := c.DiskUsage()
+				if err != nil {
+					return err
+				}
+				usage.Disk += du.Total()
+			}
+			return nil
+		}()
+		if err != nil {
+			return usage, err
+		}
+	}
+	return usage, nil
+}
+
+// ProjectUsage returns what the containers of the named project stored
+// under lxcpath use of its quotas.
+func ProjectUsage(name string, lxcpath ...string) (ProjectUsageInfo, error) {
+	project, err := LoadProject(name, lxcpath...)
+	if err != nil {
+		return ProjectUsageInfo{}, err
+	}
+	return projectUsage(project, profileConfigPath(lxcpath), "", true)
+}
+
+// projectQuota checks that a reservation fits into the quota of the project
+// left by the reservations in use. Unlimited reservations never fit.
+func projectQuota(project string, resource string, need float64, used float64, quota float64) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	if need <= 0 {
+		return fmt.Errorf("%s: %q: %s: container has no limit", ErrProjectQuota, project, resource)
+	}
+
+	if used+need > quota {
+		return fmt.Errorf("%s: %q: %s: need %g, %g of %g reserved", ErrProjectQuota, project, resource, need, used, quota)
+	}
+	return nil
+}
+
+// checkProjectQuotas checks that the configured memory and CPU limits of the
+// container fit into the quotas of its projects, and that they haven't used
+// up their disk quotas.
+//
+// Caller needs to hold the lock
+func (c *Container) checkProjectQuotas() error {
+	owner, err := readOwner(c.configPath(), c.name())
+	if err != nil || owner == "" {
+		return err
+	}
+
+	projects, err := ownerProjects(owner, c.configPath())
+	if err != nil || len(projects) == 0 {
+		return err
+	}
+
+	memory, cpus, err := c.reservation()
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		usage, err := projectUsage(project, c.configPath(), c.name(), project.Disk > 0)
+		if err != nil {
+			return err
+		}
+
+		if err := projectQuota(project.Name, "memory", float64(memory), float64(usage.Memory), float64(project.Memory)); err != nil {
+			return err
+		}
+
+		if err := projectQuota(project.Name, "cpu", cpus, usage.CPUs, project.CPUs); err != nil {
+			return err
+		}
+
+		if project.Disk > 0 && usage.Disk >= project.Disk {
+			return fmt.Errorf("%s: %q: disk: %s of %s used", ErrProjectQuota, project.Name, usage.Disk, project.Disk)
+		}
+	}
+	return nil
+}
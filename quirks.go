@@ -0,0 +1,284 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Quirk is configuration a distribution needs to run in a container.
+type Quirk struct {
+	// Distribution is the ID of the distribution, e.g. "ubuntu".
+	Distribution string
+	// Release is the version or code name of the release, e.g. "7" or
+	// "jammy", empty for all releases.
+	Release string
+	Config  []KeyValue
+	// Reason explains why the configuration is needed.
+	Reason string
+}
+
+// mqueueEntry mounts the POSIX message queue systemd expects.
+var mqueueEntry = KeyValue{"lxc.mount.entry", "mqueue dev/mqueue mqueue rw,relatime,create=dir,optional 0 0"}
+
+var (
+	quirksMu sync.RWMutex
+	quirks   = map[string]Quirk{}
+)
+
+func init() {
+	for _, q := range []Quirk{
+		{
+			Distribution: "centos",
+			Release:      "7",
+			Config:       []KeyValue{{"lxc.mount.auto", "cgroup:mixed"}},
+			Reason:       "systemd 219 can't set up its cgroups without the hierarchy mounted",
+		},
+		{
+			Distribution: "debian",
+			Config:       []KeyValue{mqueueEntry},
+			Reason:       "systemd mounts the POSIX message queue",
+		},
+		{
+			Distribution: "gentoo",
+			Config:       []KeyValue{{"lxc.tty.max", "6"}},
+			Reason:       "the default inittab spawns agetty on tty1 to tty6",
+		},
+		{
+			Distribution: "opensuse",
+			Config:       []KeyValue{{"lxc.init.cmd", "/usr/lib/systemd/systemd"}},
+			Reason:       "minimal images don't ship the /sbin/init link",
+		},
+		{
+			Distribution: "ubuntu",
+			Config:       []KeyValue{mqueueEntry},
+			Reason:       "systemd mounts the POSIX message queue",
+		},
+	} {
+		quirks[quirkKey(q.Distribution, q.Release)] = q
+	}
+}
+
+func quirkKey(distribution string, release string) string {
+	return strings.ToLower(distribution) + "/" + strings.ToLower(release)
+}
+
+// Quirks returns the entries of the quirks database, sorted by distribution
+// and release.
+func Quirks() []Quirk {
+	quirksMu.RLock()
+	defer quirksMu.RUnlock()
+
+	var ret []Quirk
+	for _, q := range quirks {
+		ret = append(ret, q)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return quirkKey(ret[i].Distribution, ret[i].Release) < quirkKey(ret[j].Distribution, ret[j].Release)
+	})
+	return ret
+}
+
+// LookupQuirks returns the entries applying to the release of the
+// distribution, the one for all its releases first.
+func LookupQuirks(distribution string, release string) []Quirk {
+	quirksMu.RLock()
+	defer quirksMu.RUnlock()
+
+	var ret []Quirk
+	if q, ok := quirks[quirkKey(distribution, "")]; ok {
+		ret = append(ret, q)
+	}
+
+	if release == "" {
+		return ret
+	}
+
+	if q, ok := quirks[quirkKey(distribution, release)]; ok {
+		ret = append(ret, q)
+	}
+	return ret
+}
+
+// SetQuirk adds the entry to the quirks database, replacing the one of the
+// same distribution and release. An entry without config disables the
+// default one.
+func SetQuirk(q Quirk) {
+	quirksMu.Lock()
+	defer quirksMu.Unlock()
+
+	quirks[quirkKey(q.Distribution, q.Release)] = q
+}
+
+// DeleteQuirk removes the entry of the distribution and release from the
+// quirks database.
+func DeleteQuirk(distribution string, release string) {
+	quirksMu.Lock()
+	defer quirksMu.Unlock()
+
+	delete(quirks, quirkKey(distribution, release))
+}
+
+// quirkItems returns the config of the quirks of the distribution matching
+// any of the releases.
+func quirkItems(distribution string, releases ...string) []KeyValue {
+	var items []KeyValue
+	seen := make(map[KeyValue]bool)
+
+	for _, release := range append([]string{""}, releases...) {
+		for _, q := range LookupQuirks(distribution, release) {
+			for _, kv := range q.Config {
+				if !seen[kv] {
+					seen[kv] = true
+					items = append(items, kv)
+				}
+			}
+		}
+	}
+	return items
+}
+
+// quirkPresent returns whether the value of a quirk is already configured.
+// The lxc.mount.auto flags are present if the filesystem is mounted in any
+// mode.
+func quirkPresent(key string, value string, values []string) bool {
+	if key == "lxc.mount.auto" {
+		fs := strings.SplitN(value, ":", 2)[0]
+		for _, v := range values {
+			for _, flag := range strings.Fields(v) {
+				if strings.SplitN(flag, ":", 2)[0] == fs {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOSRelease returns the distributions (ID followed by ID_LIKE) and the
+// releases (VERSION_ID and VERSION_CODENAME) of an os-release file.
+func parseOSRelease(data []byte) ([]string, []string) {
+	var ids []string
+	var like []string
+	var releases []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, "'")
+		}
+
+		switch key {
+		case "ID":
+			ids = []string{value}
+		case "ID_LIKE":
+			like = strings.Fields(value)
+		case "VERSION_ID", "VERSION_CODENAME":
+			if value != "" {
+				releases = append(releases, value)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return append(ids, like...), releases
+}
+
+// applyQuirks adds the config of the quirks which isn't set yet.
+//
+// Caller needs to hold the lock
+func (c *Container) applyQuirks(items []KeyValue) (bool, error) {
+	changed := false
+
+	for _, kv := range items {
+		if !IsSupportedConfigItem(kv.Key) {
+			continue
+		}
+
+		values := nonEmpty(c.configItem(kv.Key))
+		if len(values) > 0 && !isMultiValueKey(kv.Key) {
+			continue
+		}
+
+		if quirkPresent(kv.Key, kv.Value, values) {
+			continue
+		}
+
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// createQuirks saves the quirks of the created distribution to the config.
+//
+// Caller needs to hold the lock
+func (c *Container) createQuirks(distribution string, release string) error {
+	changed, err := c.applyQuirks(quirkItems(distribution, release))
+	if err != nil || !changed {
+		return err
+	}
+
+	return c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config"))
+}
+
+// startQuirks adds the quirks of the distribution found in a directory
+// backed rootfs to the in-memory config.
+//
+// Caller needs to hold the lock
+func (c *Container) startQuirks() error {
+	rootfs := c.rootfsPath()
+	if rootfs == "" {
+		return nil
+	}
+
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		resolved, err := resolvePath(rootfs, path)
+		if err != nil {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(resolved)
+		if err != nil {
+			continue
+		}
+
+		ids, releases := parseOSRelease(data)
+		for _, id := range ids {
+			if _, err := c.applyQuirks(quirkItems(id, releases...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRTPriority is the highest SCHED_FIFO and SCHED_RR priority.
+const maxRTPriority = 99
+
+// realtimeKeys are the config items set by SetRealtime, in the order they
+// are applied.
+var realtimeKeys = []string{
+	"lxc.cgroup.cpu.rt_period_us",
+	"lxc.cgroup.cpu.rt_runtime_us",
+	"lxc.prlimit.rtprio",
+	"lxc.prlimit.rttime",
+}
+
+// hostRTBandwidth returns the realtime runtime and period of the host from
+// /proc/sys/kernel, a negative runtime means unlimited.
+func hostRTBandwidth() (time.Duration, time.Duration, error) {
+	var values [2]int64
+	for i, name := range []string{"sched_rt_runtime_us", "sched_rt_period_us"} {
+		content, err := ioutil.ReadFile(filepath.Join("/proc/sys/kernel", name))
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if values[i], err = strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	return time.Duration(values[0]) * time.Microsecond, time.Duration(values[1]) * time.Microsecond, nil
+}
+
+// realtimeSupported returns whether the kernel was built with realtime group
+// scheduling, which is only available with cgroup1.
+func realtimeSupported() bool {
+	if CgroupUnified() {
+		return false
+	}
+
+	matches, _ := filepath.Glob("/sys/fs/cgroup/cpu*/cpu.rt_runtime_us")
+	return len(matches) > 0
+}
+
+// realtimeItems returns the config items of the options after checking that
+// the container can't starve the host, its share of the CPU time for
+// realtime tasks must not exceed the share of the host.
+func realtimeItems(opts RealtimeOptions, hostRuntime time.Duration, hostPeriod time.Duration) ([]KeyValue, error) {
+	if opts.Period < time.Microsecond || opts.Period > time.Second {
+		return nil, fmt.Errorf("%s: realtime period %s not within 1µs and 1s", ErrInvalidLimit, opts.Period)
+	}
+
+	if opts.Runtime < 0 || opts.Runtime > opts.Period {
+		return nil, fmt.Errorf("%s: realtime runtime %s exceeds the period %s", ErrInvalidLimit, opts.Runtime, opts.Period)
+	}
+
+	if hostRuntime >= 0 && float64(opts.Runtime)/float64(opts.Period) > float64(hostRuntime)/float64(hostPeriod) {
+		return nil, fmt.Errorf("%s: realtime runtime %s per %s exceeds the host's %s per %s", ErrInvalidLimit, opts.Runtime, opts.Period, hostRuntime, hostPeriod)
+	}
+
+	if opts.Priority < 0 || opts.Priority > maxRTPriority {
+		return nil, fmt.Errorf("%s: realtime priority %d not within 0 and %d", ErrInvalidLimit, opts.Priority, maxRTPriority)
+	}
+
+	// the kernel refuses realtime policies in groups without runtime
+	if opts.Priority > 0 && opts.Runtime == 0 {
+		return nil, fmt.Errorf("%s: realtime priority %d without runtime", ErrInvalidLimit, opts.Priority)
+	}
+
+	if opts.CPUTime < 0 {
+		return nil, fmt.Errorf("%s: realtime CPU time %s", ErrInvalidLimit, opts.CPUTime)
+	}
+
+	items := []KeyValue{
+		{realtimeKeys[0], strconv.FormatInt(int64(opts.Period/time.Microsecond), 10)},
+		{realtimeKeys[1], strconv.FormatInt(int64(opts.Runtime/time.Microsecond), 10)},
+		{realtimeKeys[2], Rlimit{Resource: "rtprio", Soft: uint64(opts.Priority), Hard: uint64(opts.Priority)}.String()},
+	}
+
+	if opts.CPUTime > 0 {
+		us := uint64(opts.CPUTime / time.Microsecond)
+		items = append(items, KeyValue{realtimeKeys[3], Rlimit{Resource: "rttime", Soft: us, Hard: us}.String()})
+	}
+	return items, nil
+}
+
+// SetRealtime allows the processes of the container to use SCHED_FIFO and
+// SCHED_RR up to the priority, with the CPU time of realtime tasks limited
+// to the runtime per period by cpu.rt_runtime_us and cpu.rt_period_us. It
+// refuses options giving the container a larger share of the CPU than the
+// host allows realtime tasks (kernel.sched_rt_runtime_us). The cgroup is
+// updated if the container is running, the prlimits apply on the next
+// start. Realtime group scheduling is only available with cgroup1.
+func (c *Container) SetRealtime(opts RealtimeOptions) (err error) {
+	finish, err := c.operation("SetRealtime")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	if !realtimeSupported() {
+		return ErrRealtimeLimit
+	}
+
+	hostRuntime, hostPeriod, err := hostRTBandwidth()
+	if err != nil {
+		return fmt.Errorf("%s: %v", ErrRealtimeLimit, err)
+	}
+
+	items, err := realtimeItems(opts, hostRuntime, hostPeriod)
+	if err != nil {
+		return err
+	}
+
+	if c.running() {
+		// a period shorter than the current runtime is refused, which
+		// then has to be lowered first
+		period := KeyValue{"cpu.rt_period_us", items[0].Value}
+		runtime := KeyValue{"cpu.rt_runtime_us", items[1].Value}
+
+		order := []KeyValue{period, runtime}
+		if current, err := strconv.ParseInt(c.cgroupItem(runtime.Key)[0], 10, 64); err == nil && time.Duration(current)*time.Microsecond > opts.Period {
+			order = []KeyValue{runtime, period}
+		}
+
+		for _, kv := range order {
+			if err := c.setCgroupItem(kv.Key, kv.Value); err != nil {
+				return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+			}
+		}
+	}
+
+	for _, key := range realtimeKeys {
+		if err := c.clearConfigItem(key); err != nil {
+			return err
+		}
+	}
+
+	for _, kv := range items {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, kv.Key, kv.Value)
+		}
+	}
+	return nil
+}
+
+// Realtime returns the realtime options of the container, false if
+// SetRealtime wasn't used.
+func (c *Container) Realtime() (RealtimeOptions, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return RealtimeOptions{}, false, err
+	}
+
+	values := make([]string, len(realtimeKeys))
+	for i, key := range realtimeKeys {
+		values[i] = c.configItem(key)[0]
+	}
+	return parseRealtime(values)
+}
+
+// parseRealtime returns the options of the values of the realtimeKeys.
+func parseRealtime(values []string) (RealtimeOptions, bool, error) {
+	var opts RealtimeOptions
+
+	if values[0] == "" || values[1] == "" {
+		return opts, false, nil
+	}
+
+	period, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return opts, false, fmt.Errorf("%s: %q", ErrInvalidLimit, values[0])
+	}
+
+	runtime, err := strconv.ParseInt(values[1], 10, 64)
+	if err != nil {
+		return opts, false, fmt.Errorf("%s: %q", ErrInvalidLimit, values[1])
+	}
+	opts.Period, opts.Runtime = time.Duration(period)*time.Microsecond, time.Duration(runtime)*time.Microsecond
+
+	if values[2] != "" {
+		r, err := parseRlimit("rtprio", values[2])
+		if err != nil {
+			return opts, false, err
+		}
+		opts.Priority = int(r.Hard)
+	}
+
+	if values[3] != "" {
+		r, err := parseRlimit("rttime", values[3])
+		if err != nil {
+			return opts, false, err
+		}
+		if r.Hard != RlimitInfinity {
+			opts.CPUTime = time.Duration(r.Hard) * time.Microsecond
+		}
+	}
+	return opts, true, nil
+}
+
+// ClearRealtime removes the realtime settings of the container, it can't
+// use realtime policies from the next start on.
+func (c *Container) ClearRealtime() (err error) {
+	finish, err := c.operation("ClearRealtime")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	for _, key := range realtimeKeys {
+		if err := c.clearConfigItem(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// SessionRecorder records the input and output of console and attach
+// sessions with timestamps, in the asciicast v2 format of asciinema. Set it
+// as Recorder of ConsoleOptions or AttachOptions.
+type SessionRecorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+
+	// RecordInput records the keystrokes too, e.g. for compliance. Note
+	// they include passwords typed without echo.
+	RecordInput bool
+
+	// partial UTF-8 sequences of the last write, per event type
+	carry map[string][]byte
+}
+
+// asciicastHeader is the first line of an asciicast v2 recording.
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// NewSessionRecorder writes the header of a recording of a terminal of the
+// given size to w and returns the recorder writing the events to it.
+func NewSessionRecorder(w io.Writer, width int, height int) (*SessionRecorder, error) {
+	r := &SessionRecorder{w: w, start: time.Now(), carry: make(map[string][]byte)}
+
+	header, err := json.Marshal(asciicastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Env:       map[string]string{"TERM": "xterm"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append(header, '\n')); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// record writes an event, keeping an incomplete UTF-8 sequence at the end
+// of p for the next one.
+func (r *SessionRecorder) record(code string, p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.carry[code], p...)
+
+	// up to utf8.UTFMax-1 trailing bytes may start a rune
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	r.carry[code] = append([]byte(nil), data[end:]...)
+
+	if end == 0 {
+		return nil
+	}
+
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, string(data[:end])})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.w.Write(append(event, '\n'))
+	return err
+}
+
+// Output records output of the session.
+func (r *SessionRecorder) Output(p []byte) error {
+	return r.record("o", p)
+}
+
+// Input records input of the session, if RecordInput is set.
+func (r *SessionRecorder) Input(p []byte) error {
+	if !r.RecordInput {
+		return nil
+	}
+	return r.record("i", p)
+}
+
+// openPTY returns the master and slave of a new pty.
+func openPTY() (int, int, error) {
+	master, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	if err := unix.IoctlSetPointerInt(master, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(master)
+		return -1, -1, err
+	}
+
+	n, err := unix.IoctlGetInt(master, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(master)
+		return -1, -1, err
+	}
+
+	slave, err := unix.Open(fmt.Sprintf("/dev/pts/%d", n), unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		unix.Close(master)
+		return -1, -1, err
+	}
+	return master, slave, nil
+}
+
+// copyRecorded copies from the fd to w until reading fails or stop is
+// closed, passing the data to record. It polls so that it can be stopped
+// while the fd has nothing to read.
+func copyRecorded(fd int, w int, record func([]byte) error, stop <-chan struct{}) {
+	buf := make([]byte, 4096)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n, err := unix.Poll(fds, 100)
+		if err == unix.EINTR || n == 0 {
+			continue
+		} else if err != nil {
+			return
+		}
+
+		n, err = unix.Read(fd, buf)
+		if n <= 0 || err != nil {
+			return
+		}
+
+		record(buf[:n])
+		if _, err := unix.Write(w, buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+// interpose puts a pty between the session and the stdin and stdout of the
+// caller, recording what passes through. It returns the slave to use as
+// stdin, stdout and stderr of the session and a function restoring the
+// terminal once it ended.
+func (r *SessionRecorder) interpose(stdin uintptr, stdout uintptr) (uintptr, func(), error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if ws, err := unix.IoctlGetWinsize(int(stdout), unix.TIOCGWINSZ); err == nil {
+		unix.IoctlSetWinsize(slave, unix.TIOCSWINSZ, ws)
+	}
+
+	// the session sets its pty raw, the terminal of the caller has to
+	// pass the keystrokes on as they are too
+	termios, err := unix.IoctlGetTermios(int(stdin), unix.TCGETS)
+	if err != nil {
+		termios = nil
+	} else {
+		raw := *termios
+		raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+		raw.Oflag &^= unix.OPOST
+		raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		raw.Cflag &^= unix.CSIZE | unix.PARENB
+		raw.Cflag |= unix.CS8
+		unix.IoctlSetTermios(int(stdin), unix.TCSETS, &raw)
+	}
+
+	stopInput := make(chan struct{})
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		copyRecorded(int(stdin), master, r.Input, stopInput)
+	}()
+
+	stopOutput := make(chan struct{})
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		// reading fails with EIO once the slave is closed everywhere
+		copyRecorded(master, int(stdout), r.Output, stopOutput)
+	}()
+
+	restore := func() {
+		close(stopInput)
+		<-inputDone
+
+		// drain the output, processes left running in the background may
+		// keep the slave open though
+		unix.Close(slave)
+		select {
+		case <-outputDone:
+		case <-time.After(time.Second):
+			close(stopOutput)
+			<-outputDone
+		}
+		unix.Close(master)
+
+		if termios != nil {
+			unix.IoctlSetTermios(int(stdin), unix.TCSETS, termios)
+		}
+	}
+	return uintptr(slave), restore, nil
+}
@@ -0,0 +1,632 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// maxManifestSize bounds the manifests, indexes and token responses
+	// read into memory.
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+// imageReference represents a parsed image reference such as
+// "docker.io/library/alpine:3.20".
+type imageReference struct {
+	Registry   string
+	Repository string
+	// Reference is the tag or digest of the image.
+	Reference string
+}
+
+// parseImageReference parses an image reference. The registry defaults to
+// Docker Hub and the tag to "latest", like the docker CLI does.
+func parseImageReference(ref string) (imageReference, error) {
+	r := imageReference{Registry: dockerHubRegistry, Reference: "latest"}
+
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		r.Reference = name[i+1:]
+		name = name[:i]
+	} else if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		r.Reference = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		r.Registry = parts[0]
+		name = parts[1]
+	}
+
+	if r.Registry == "docker.io" || r.Registry == "index.docker.io" {
+		r.Registry = dockerHubRegistry
+	}
+
+	if name == "" || r.Reference == "" || strings.ToLower(name) != name {
+		return imageReference{}, fmt.Errorf("%s: %q", ErrInvalidImageReference, ref)
+	}
+
+	if r.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	r.Repository = name
+	return r, nil
+}
+
+// ociDescriptor references a blob or manifest.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	// Annotations carry e.g. the cosign signature of a layer.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant,omitempty"`
+	} `json:"platform,omitempty"`
+}
+
+// ociManifest is an image manifest or an image index (manifest list).
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociImageConfig is the part of the image configuration used to set up the
+// container.
+type ociImageConfig struct {
+	Architecture string `json:"architecture"`
+	Config       struct {
+		User       string   `json:"User"`
+		Env        []string `json:"Env"`
+		Entrypoint []string `json:"Entrypoint"`
+		Cmd        []string `json:"Cmd"`
+		WorkingDir string   `json:"WorkingDir"`
+	} `json:"config"`
+}
+
+// hostPlatform returns the OCI platform of the host, e.g. "linux/arm64/v8".
+func hostPlatform() string {
+	switch runtime.GOARCH {
+	case "arm":
+		return "linux/arm/v7"
+	case "arm64":
+		return "linux/arm64/v8"
+	}
+	return "linux/" + runtime.GOARCH
+}
+
+// matchPlatform returns true if the descriptor is for the given platform.
+// The variant is only compared if both sides specify one.
+func matchPlatform(d ociDescriptor, platform string) bool {
+	if d.Platform == nil {
+		return false
+	}
+
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || d.Platform.OS != parts[0] || d.Platform.Architecture != parts[1] {
+		return false
+	}
+
+	if len(parts) > 2 && d.Platform.Variant != "" && d.Platform.Variant != parts[2] {
+		return false
+	}
+	return true
+}
+
+// registryClient is a minimal OCI distribution (Docker registry v2) client.
+type registryClient struct {
+	ctx      context.Context
+	client   *http.Client
+	retries  int
+	ref      imageReference
+	username string
+	password string
+
+	// bases are the mirrors and the registry, base is the one in use
+	bases    []string
+	base     int
+	limiter  *rateLimiter
+	segments int
+
+	// tokens are the bearer tokens by base
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func newRegistryClient(ctx context.Context, ref imageReference, opts ImageOptions) (*registryClient, error) {
+	config := GlobalDownloadConfig()
+	if opts.Download != nil {
+		config = *opts.Download
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		var err error
+		if client, err = config.httpClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	scheme := "https"
+	if opts.Insecure {
+		scheme = "http"
+	}
+
+	return &registryClient{
+		ctx:      ctx,
+		client:   client,
+		retries:  opts.Retries,
+		ref:      ref,
+		username: opts.Username,
+		password: opts.Password,
+		bases:    config.baseURLs(scheme, ref.Registry),
+		limiter:  &rateLimiter{rate: config.RateLimit},
+		segments: config.Segments,
+	}, nil
+}
+
+// parseAuthenticate parses a WWW-Authenticate header into its scheme and
+// parameters.
+func parseAuthenticate(header string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return parts[0], params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.TrimSpace(strings.TrimLeft(rest[:i], ", "))
+		rest = rest[i+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[strings.ToLower(key)] = value
+	}
+
+	return strings.ToLower(parts[0]), params
+}
+
+// credentials returns the username and password to send to base, the
+// credentials belong to the registry and are never sent to its mirrors.
+func (r *registryClient) credentials(base string) (string, string) {
+	if len(r.bases) == 0 || base != r.bases[len(r.bases)-1] {
+		return "", ""
+	}
+	return r.username, r.password
+}
+
+// authenticate fetches a bearer token for base as requested by it.
+func (r *registryClient) authenticate(ctx context.Context, base string, header string) error {
+	username, password := r.credentials(base)
+
+	scheme, params := parseAuthenticate(header)
+	if scheme == "basic" {
+		if username == "" {
+			return fmt.Errorf("%s: %s", ErrRegistryFailed, "registry requires credentials")
+		}
+		return nil
+	}
+
+	if scheme != "bearer" || params["realm"] == "" {
+		return fmt.Errorf("%s: unsupported authentication %q", ErrRegistryFailed, header)
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil {
+		return err
+	}
+
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", r.ref.Repository)
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: token request failed: %s", ErrRegistryFailed, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&token); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens == nil {
+		r.tokens = make(map[string]string)
+	}
+
+	r.tokens[base] = token.Token
+	if token.Token == "" {
+		r.tokens[base] = token.AccessToken
+	}
+	return nil
+}
+
+// do requests the given registry path, failing over to the next mirror on
+// errors. Both complete and partial content are considered successful.
+func (r *registryClient) do(path string, header http.Header) (*http.Response, error) {
+	return r.doContext(r.ctx, path, header)
+}
+
+func (r *registryClient) doContext(ctx context.Context, path string, header http.Header) (*http.Response, error) {
+	r.mu.Lock()
+	first := r.base
+	r.mu.Unlock()
+
+	var err error
+	for i := first; i < len(r.bases); i++ {
+		var resp *http.Response
+		resp, err = r.request(ctx, r.bases[i], path, header)
+		if err == nil {
+			r.mu.Lock()
+			if i > r.base {
+				r.base = i
+			}
+			r.mu.Unlock()
+			return resp, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// request requests the given path from a registry, authenticating on demand.
+func (r *registryClient) request(ctx context.Context, base string, path string, header http.Header) (*http.Response, error) {
+	u := fmt.Sprintf("%s/v2/%s/%s", base, r.ref.Repository, path)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		r.mu.Lock()
+		token := r.tokens[base]
+		r.mu.Unlock()
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if username, password := r.credentials(base); username != "" {
+			req.SetBasicAuth(username, password)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			header := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+
+			if err := r.authenticate(ctx, base, header); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return nil, &registryError{path: path, status: resp.Status, code: resp.StatusCode}
+		}
+		return resp, nil
+	}
+}
+
+// registryError is returned for unexpected registry responses.
+type registryError struct {
+	path   string
+	status string
+	code   int
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrRegistryFailed, e.path, e.status)
+}
+
+// get requests the given registry path with the given accepted media types.
+func (r *registryClient) get(path string, accept ...string) (*http.Response, error) {
+	return r.do(path, http.Header{"Accept": accept})
+}
+
+// digestOf returns the sha256 digest of content.
+func digestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// validDigest returns whether digest is a sha256 digest with its hex encoded
+// sum in lower case, it is safe to be used in paths.
+func validDigest(digest string) bool {
+	hash := strings.TrimPrefix(digest, "sha256:")
+	if hash == digest || len(hash) != sha256.Size*2 {
+		return false
+	}
+
+	for _, r := range hash {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// rawManifest fetches a manifest and returns its content along with its
+// digest.
+func (r *registryClient) rawManifest(reference string) ([]byte, string, error) {
+	resp, err := r.get("manifests/"+reference, mediaTypeOCIIndex, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeDockerManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(content) > maxManifestSize {
+		return nil, "", fmt.Errorf("%s: manifest %q exceeds %d bytes", ErrRegistryFailed, reference, maxManifestSize)
+	}
+
+	digest := digestOf(content)
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		return nil, "", fmt.Errorf("%s: %q", ErrDigestMismatch, reference)
+	}
+	return content, digest, nil
+}
+
+// manifest fetches the image manifest for the given platform, resolving
+// image indexes and manifest lists. It also returns the digest of the
+// manifest the reference points to.
+func (r *registryClient) manifest(platform string) (*ociManifest, string, error) {
+	reference := r.ref.Reference
+	var top string
+
+	for depth := 0; depth < 2; depth++ {
+		content, digest, err := r.rawManifest(reference)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if top == "" {
+			top = digest
+		}
+
+		var m ociManifest
+		if err := json.Unmarshal(content, &m); err != nil {
+			return nil, "", fmt.Errorf("%s: %v", ErrRegistryFailed, err)
+		}
+
+		if m.MediaType != mediaTypeOCIIndex && m.MediaType != mediaTypeDockerManifestList && len(m.Manifests) == 0 {
+			return &m, top, nil
+		}
+
+		reference = ""
+		for _, d := range m.Manifests {
+			if matchPlatform(d, platform) {
+				reference = d.Digest
+				break
+			}
+		}
+
+		if reference == "" {
+			return nil, "", fmt.Errorf("%s: %q", ErrPlatformNotFound, platform)
+		}
+	}
+
+	return nil, "", fmt.Errorf("%s: %s", ErrRegistryFailed, "nested image index")
+}
+
+// verifyFile checks the sha256 digest of the file at path.
+func verifyFile(path string, digest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if "sha256:"+hex.EncodeToString(h.Sum(nil)) != digest {
+		return fmt.Errorf("%s: %q", ErrDigestMismatch, digest)
+	}
+	return nil
+}
+
+// download fetches the blob into path, resuming from the content already
+// present. It returns whether the download was resumed.
+func (r *registryClient) download(d ociDescriptor, path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := r.do("blobs/"+d.Digest, header)
+	if e, ok := err.(*registryError); ok && offset > 0 && e.code == http.StatusRequestedRangeNotSatisfiable {
+		// the content is complete already
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+		// the registry doesn't support ranges, start over
+		if err := f.Truncate(0); err != nil {
+			return false, err
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		offset = 0
+	}
+
+	_, err = io.Copy(f, limitedReader{r.ctx, resp.Body, r.limiter})
+	return offset > 0, err
+}
+
+// blob downloads the given blob into dir, named after its digest, and
+// verifies it. Interrupted downloads are resumed, up to the configured
+// number of retries, and complete blobs already present in dir are reused.
+func (r *registryClient) blob(d ociDescriptor, dir string) (string, error) {
+	// the digest comes from the untrusted manifest and becomes a path
+	if !validDigest(d.Digest) {
+		return "", fmt.Errorf("%s: unsupported digest %q", ErrDigestMismatch, d.Digest)
+	}
+
+	path := filepath.Join(dir, strings.TrimPrefix(d.Digest, "sha256:"))
+	if err := verifyFile(path, d.Digest); err == nil {
+		return path, nil
+	}
+
+	partial := path + ".partial"
+	for attempt := 0; ; attempt++ {
+		var resumed bool
+		var err error
+
+		segmented := false
+		if _, serr := os.Stat(partial); os.IsNotExist(serr) && r.segments > 1 && d.Size >= segmentMinSize {
+			segmented, err = r.downloadSegments(d, partial, r.segments)
+			if err != nil {
+				// segments can't be resumed
+				os.Remove(partial)
+			}
+		}
+
+		if !segmented {
+			resumed, err = r.download(d, partial)
+		}
+		if err == nil {
+			err = verifyFile(partial, d.Digest)
+			if err == nil {
+				return path, os.Rename(partial, path)
+			}
+
+			// the partial content may be stale, start over
+			os.Remove(partial)
+			if !resumed {
+				return "", err
+			}
+		}
+
+		if r.ctx.Err() != nil {
+			return "", r.ctx.Err()
+		}
+
+		if e, ok := err.(*registryError); ok && e.code < http.StatusInternalServerError {
+			return "", err
+		}
+
+		if attempt >= r.retries {
+			return "", err
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return "", r.ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 500 * time.Millisecond):
+		}
+	}
+}
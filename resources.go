@@ -0,0 +1,314 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// DiskSpace represents the size of a filesystem.
+type DiskSpace struct {
+	Total ByteSize
+	Free  ByteSize
+}
+
+// HostResourcesInfo represents the resources of the host.
+type HostResourcesInfo struct {
+	MemoryTotal ByteSize
+	// MemoryFree is the memory available without swapping.
+	MemoryFree ByteSize
+	CPUs       int
+	// Disk is the space of the filesystem of each lxcpath.
+	Disk map[string]DiskSpace
+}
+
+// parseMeminfo returns the total and available memory from /proc/meminfo.
+func parseMeminfo(content string) (ByteSize, ByteSize, error) {
+	values := make(map[string]ByteSize)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if len(fields) == 3 && fields[2] == "kB" {
+			n *= 1024
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = ByteSize(n)
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return -1, -1, fmt.Errorf("%s: %q", ErrHostResources, "/proc/meminfo")
+	}
+
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return total, available, nil
+}
+
+// diskSpace returns the size of the filesystem path is on.
+func diskSpace(path string) (DiskSpace, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskSpace{}, fmt.Errorf("%s: %v", ErrHostResources, err)
+	}
+
+	return DiskSpace{
+		Total: ByteSize(st.Blocks * uint64(st.Bsize)),
+		Free:  ByteSize(st.Bavail * uint64(st.Bsize)),
+	}, nil
+}
+
+// HostResources returns a snapshot of the memory and CPUs of the host and of
+// the disk space of the given lxcpaths (default: DefaultConfigPath()).
+func HostResources(lxcpath ...string) (HostResourcesInfo, error) {
+	info := HostResourcesInfo{CPUs: runtime.NumCPU(), Disk: make(map[string]DiskSpace)}
+
+	content, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return info, fmt.Errorf("%s: %v", ErrHostResources, err)
+	}
+
+	if info.MemoryTotal, info.MemoryFree, err = parseMeminfo(string(content)); err != nil {
+		return info, err
+	}
+
+	if len(lxcpath) == 0 {
+		lxcpath = []string{DefaultConfigPath()}
+	}
+
+	for _, path := range lxcpath {
+		if info.Disk[path], err = diskSpace(path); err != nil {
+			return info, err
+		}
+	}
+
+	return info, nil
+}
+
+// parseCgroupBytes parses a memory limit as written to the cgroup files, e.g.
+// "512M" or "1073741824". It returns 0 for unlimited.
+func parseCgroupBytes(value string) (ByteSize, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "max" || value == "-1" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	switch strings.ToUpper(value[len(value)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	}
+
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: %q", ErrInvalidLimit, value)
+	}
+	return ByteSize(n * multiplier), nil
+}
+
+// expandCPUList returns the CPUs of a list such as "0-3,8".
+func expandCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, r := range strings.Split(strings.TrimSpace(list), ",") {
+		if r == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidLimit, list)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("%s: %q", ErrInvalidLimit, list)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// parseCPUList returns the number of CPUs in a list such as "0-3,8".
+func parseCPUList(list string) (int, error) {
+	cpus, err := expandCPUList(list)
+	return len(cpus), err
+}
+
+// parseCPUQuota returns the number of CPUs a quota and a period in
+// microseconds amount to, 0 for unlimited.
+func parseCPUQuota(quota string, period string) (float64, error) {
+	quota, period = strings.TrimSpace(quota), strings.TrimSpace(period)
+	if quota == "" || quota == "max" || quota == "-1" {
+		return 0, nil
+	}
+
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q", ErrInvalidLimit, quota)
+	}
+
+	p := 100000.0
+	if period != "" {
+		if p, err = strconv.ParseFloat(period, 64); err != nil || p <= 0 {
+			return 0, fmt.Errorf("%s: %q", ErrInvalidLimit, period)
+		}
+	}
+	return q / p, nil
+}
+
+// reservation returns the memory and the CPUs reserved by the configured
+// limits of the container, 0 for unlimited.
+//
+// Caller needs to hold the lock
+func (c *Container) reservation() (ByteSize, float64, error) {
+	var memory, quota, period, cpuset string
+	if CgroupUnified() {
+		memory = c.configItem("lxc.cgroup2.memory.max")[0]
+		if max := strings.Fields(c.configItem("lxc.cgroup2.cpu.max")[0]); len(max) > 0 {
+			quota = max[0]
+			if len(max) > 1 {
+				period = max[1]
+			}
+		}
+		cpuset = c.configItem("lxc.cgroup2.cpuset.cpus")[0]
+	} else {
+		memory = c.configItem("lxc.cgroup.memory.limit_in_bytes")[0]
+		quota = c.configItem("lxc.cgroup.cpu.cfs_quota_us")[0]
+		period = c.configItem("lxc.cgroup.cpu.cfs_period_us")[0]
+		cpuset = c.configItem("lxc.cgroup.cpuset.cpus")[0]
+	}
+
+	mem, err := parseCgroupBytes(memory)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cpus, err := parseCPUQuota(quota, period)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if cpuset != "" {
+		n, err := parseCPUList(cpuset)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if cpus == 0 || float64(n) < cpus {
+			cpus = float64(n)
+		}
+	}
+
+	return mem, cpus, nil
+}
+
+// admit checks that a reservation fits into the capacity left by the
+// reservations in use.
+func admit(resource string, need float64, used float64, capacity float64) error {
+	if need > 0 && used+need > capacity {
+		return fmt.Errorf("%s: %s: need %g, %g of %g reserved", ErrInsufficientResources, resource, need, used, capacity)
+	}
+	return nil
+}
+
+// Admit checks that the configured memory and CPU limits of the container fit
+// into the capacity of the host left by the limits of the running containers
+// in the same lxcpath, scaled by the overcommit ratios of opts. Containers
+// without limits are always admitted, unless a project they belong to has
+// quotas (see Project).
+func (c *Container) Admit(opts AdmissionOptions) error {
+	c.mu.RLock()
+	memory, cpus, err := c.reservation()
+	name, lxcpath := c.name(), c.configPath()
+	c.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	host, err := HostResources(lxcpath)
+	if err != nil {
+		return err
+	}
+
+	running, err := ActiveContainersE(lxcpath)
+	if err != nil {
+		return err
+	}
+
+	var usedMemory ByteSize
+	var usedCPUs float64
+	for _, r := range running {
+		if r.Name() != name {
+			r.mu.RLock()
+			m, n, err := r.reservation()
+			r.mu.RUnlock()
+
+			if err == nil {
+				usedMemory += m
+				usedCPUs += n
+			}
+		}
+		r.Release()
+	}
+
+	if err := admit("memory", float64(memory), float64(usedMemory), float64(host.MemoryTotal)*opts.MemoryOvercommit); err != nil {
+		return err
+	}
+
+	if err := admit("cpu", cpus, usedCPUs, float64(host.CPUs)*opts.CPUOvercommit); err != nil {
+		return err
+	}
+
+	if free := host.Disk[lxcpath].Free; opts.MinFreeDisk > 0 && free < opts.MinFreeDisk {
+		return fmt.Errorf("%s: disk: %s free, %s required", ErrInsufficientResources, free, opts.MinFreeDisk)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.checkProjectQuotas()
+}
+
+// StartAdmitted starts the container if Admit admits it.
+func (c *Container) StartAdmitted(opts AdmissionOptions) error {
+	if err := c.Admit(opts); err != nil {
+		return err
+	}
+	return c.Start()
+}
@@ -0,0 +1,100 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// OperationPolicy configures retrying lifecycle operations (Start,
+// StartWithArgs, Stop, Shutdown, Reboot, Freeze and Unfreeze) which failed
+// for a transient reason, e.g. because the container was still stopping.
+type OperationPolicy struct {
+	// Attempts is the maximum number of attempts, 0 or 1 disables
+	// retrying.
+	Attempts int
+	// Backoff is the delay before the second attempt, doubled after each
+	// attempt, 100ms if 0.
+	Backoff time.Duration
+	// MaxBackoff caps the delay, unlimited if 0.
+	MaxBackoff time.Duration
+	// Retryable decides whether a failed attempt is retried,
+	// Container.Retryable if nil.
+	Retryable func(c *Container, err error) bool
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   OperationPolicy
+)
+
+// SetOperationPolicy installs the policy used by all containers. The zero
+// policy, which is the default, doesn't retry.
+func SetOperationPolicy(p OperationPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+
+	policy = p
+}
+
+// IsTransient returns true if the error is caused by a condition which
+// clears by itself, such as EAGAIN, EBUSY or EINTR.
+func IsTransient(err error) bool {
+	return errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EBUSY) || errors.Is(err, unix.EINTR)
+}
+
+// Busy returns true if the container is in a transitional state, e.g.
+// STARTING or STOPPING, in which liblxc refuses most operations.
+func (c *Container) Busy() bool {
+	return !c.State().Stable()
+}
+
+// Retryable returns true if the operation which failed with err may succeed
+// when retried: the error is transient or the container is busy. It lets
+// callers without an OperationPolicy retry themselves.
+func (c *Container) Retryable(err error) bool {
+	if err == nil || errors.Is(err, ErrOperationDenied) || errors.Is(err, ErrNotDefined) {
+		return false
+	}
+	return IsTransient(err) || c.Busy()
+}
+
+// retry runs the operation as allowed by the operation policy. It must be
+// called without holding the lock so that the container can settle.
+func (c *Container) retry(fn func() error) error {
+	policyMu.RLock()
+	p := policy
+	policyMu.RUnlock()
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = (*Container).Retryable
+	}
+
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || !retryable(c, err) {
+			return err
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
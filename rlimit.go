@@ -0,0 +1,186 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// RlimitInfinity is the value of an unlimited resource.
+const RlimitInfinity = ^uint64(0)
+
+// rlimitResources maps the names used by lxc.prlimit to the resources.
+var rlimitResources = map[string]int{
+	"as":         unix.RLIMIT_AS,
+	"core":       unix.RLIMIT_CORE,
+	"cpu":        unix.RLIMIT_CPU,
+	"data":       unix.RLIMIT_DATA,
+	"fsize":      unix.RLIMIT_FSIZE,
+	"locks":      unix.RLIMIT_LOCKS,
+	"memlock":    unix.RLIMIT_MEMLOCK,
+	"msgqueue":   unix.RLIMIT_MSGQUEUE,
+	"nice":       unix.RLIMIT_NICE,
+	"nofile":     unix.RLIMIT_NOFILE,
+	"nproc":      unix.RLIMIT_NPROC,
+	"rss":        unix.RLIMIT_RSS,
+	"rtprio":     unix.RLIMIT_RTPRIO,
+	"rttime":     unix.RLIMIT_RTTIME,
+	"sigpending": unix.RLIMIT_SIGPENDING,
+	"stack":      unix.RLIMIT_STACK,
+}
+
+// Rlimit represents a resource limit such as the maximum number of open
+// files.
+type Rlimit struct {
+	// Resource is the name of the resource as used by lxc.prlimit, e.g.
+	// "nofile" or "core".
+	Resource string
+	Soft     uint64
+	Hard     uint64
+}
+
+// String returns the limit in the format of lxc.prlimit values.
+func (r Rlimit) String() string {
+	format := func(v uint64) string {
+		if v == RlimitInfinity {
+			return "unlimited"
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	return format(r.Soft) + ":" + format(r.Hard)
+}
+
+// validate checks the resource name and that soft doesn't exceed hard.
+func (r Rlimit) validate() error {
+	if _, ok := rlimitResources[r.Resource]; !ok {
+		return fmt.Errorf("%s: %q", ErrInvalidLimit, r.Resource)
+	}
+
+	if r.Soft > r.Hard {
+		return fmt.Errorf("%s: %s %s", ErrInvalidLimit, r.Resource, r)
+	}
+	return nil
+}
+
+// parseRlimit parses a lxc.prlimit value, "soft:hard" or a single value for
+// both.
+func parseRlimit(resource string, value string) (Rlimit, error) {
+	parse := func(v string) (uint64, error) {
+		if v == "unlimited" || v == "infinity" {
+			return RlimitInfinity, nil
+		}
+		return strconv.ParseUint(v, 10, 64)
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+
+	soft, err := parse(parts[0])
+	if err != nil {
+		return Rlimit{}, fmt.Errorf("%s: %q", ErrInvalidLimit, value)
+	}
+
+	hard := soft
+	if len(parts) == 2 {
+		if hard, err = parse(parts[1]); err != nil {
+			return Rlimit{}, fmt.Errorf("%s: %q", ErrInvalidLimit, value)
+		}
+	}
+
+	r := Rlimit{Resource: resource, Soft: soft, Hard: hard}
+	return r, r.validate()
+}
+
+// Limits returns the resource limits of the container init as set through
+// lxc.prlimit, sorted by resource.
+func (c *Container) Limits() ([]Rlimit, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	var limits []Rlimit
+	for _, kv := range c.configValues("lxc.prlimit") {
+		r, err := parseRlimit(strings.TrimPrefix(kv.Key, "lxc.prlimit."), kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, r)
+	}
+
+	sort.Slice(limits, func(i, j int) bool { return limits[i].Resource < limits[j].Resource })
+	return limits, nil
+}
+
+// Limit returns the limit of the resource set through lxc.prlimit. The
+// second return value is false if the limit isn't set.
+func (c *Container) Limit(resource string) (Rlimit, bool, error) {
+	limits, err := c.Limits()
+	if err != nil {
+		return Rlimit{}, false, err
+	}
+
+	for _, r := range limits {
+		if r.Resource == resource {
+			return r, true, nil
+		}
+	}
+	return Rlimit{}, false, nil
+}
+
+// SetLimit sets the limit of the resource for the container init through
+// lxc.prlimit, e.g. SetLimit("nofile", 1024, 4096). Use RlimitInfinity for
+// an unlimited resource. The limit applies on the next start.
+func (c *Container) SetLimit(resource string, soft uint64, hard uint64) (err error) {
+	finish, err := c.operation("SetLimit", resource, strconv.FormatUint(soft, 10), strconv.FormatUint(hard, 10))
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	r := Rlimit{Resource: resource, Soft: soft, Hard: hard}
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	return c.setConfigItem("lxc.prlimit."+resource, r.String())
+}
+
+// ClearLimit removes the limit of the resource set through lxc.prlimit.
+func (c *Container) ClearLimit(resource string) (err error) {
+	finish, err := c.operation("ClearLimit", resource)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if _, ok := rlimitResources[resource]; !ok {
+		return fmt.Errorf("%s: %q", ErrInvalidLimit, resource)
+	}
+
+	return c.clearConfigItem("lxc.prlimit." + resource)
+}
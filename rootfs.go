@@ -0,0 +1,88 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// mountRootfs makes the root filesystem of the stopped container accessible
+// on the host. Directory backed root filesystems are used in place, overlay
+// and block device backed ones are mounted on a temporary directory. The
+// returned function undoes the mount.
+//
+// Caller needs to hold the lock
+func (c *Container) mountRootfs() (string, func(), error) {
+	if path := c.rootfsPath(); path != "" {
+		return path, func() {}, nil
+	}
+
+	key := "lxc.rootfs.path"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.rootfs"
+	}
+	rootfs := c.configItem(key)[0]
+
+	parts := strings.SplitN(rootfs, ":", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("%s: %q", ErrMountRootfsFailed, rootfs)
+	}
+
+	target, err := ioutil.TempDir("", "go-lxc-rootfs")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		unix.Unmount(target, unix.MNT_DETACH)
+		os.Remove(target)
+	}
+
+	switch parts[0] {
+	case "overlay", "overlayfs":
+		// overlay:<lower>:<upper>, liblxc keeps the workdir next to upper
+		dirs := strings.SplitN(parts[1], ":", 2)
+		if len(dirs) != 2 {
+			os.Remove(target)
+			return "", nil, fmt.Errorf("%s: %q", ErrMountRootfsFailed, rootfs)
+		}
+
+		work := filepath.Join(filepath.Dir(dirs[1]), "olwork")
+		if err := os.MkdirAll(work, 0755); err != nil {
+			os.Remove(target)
+			return "", nil, err
+		}
+
+		options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", dirs[0], dirs[1], work)
+		if err := unix.Mount("overlay", target, "overlay", 0, options); err != nil {
+			os.Remove(target)
+			return "", nil, fmt.Errorf("%s: %v", ErrMountRootfsFailed, err)
+		}
+	case "lvm", "loop", "nbd", "rbd":
+		args := []string{parts[1], target}
+		if parts[0] == "loop" {
+			args = append([]string{"-o", "loop"}, args...)
+		}
+
+		if output, err := exec.Command("mount", args...).CombinedOutput(); err != nil {
+			os.Remove(target)
+			return "", nil, fmt.Errorf("%s: %s", ErrMountRootfsFailed, strings.TrimSpace(string(output)))
+		}
+	default:
+		os.Remove(target)
+		return "", nil, fmt.Errorf("%s: %q", ErrMountRootfsFailed, rootfs)
+	}
+
+	return target, cleanup, nil
+}
@@ -0,0 +1,137 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Runtime describes the liblxc the process runs with and the host setup it
+// detected. It's collected once by RuntimeInfo and can be shared freely.
+type Runtime struct {
+	// Version is the liblxc version as returned by Version.
+	Version string
+	// Major, Minor and Micro are the parts of the version.
+	Major int
+	Minor int
+	Micro int
+	// Devel is set for development snapshots of liblxc.
+	Devel bool
+
+	// CgroupUnified is set if the host runs the pure cgroup v2 hierarchy.
+	CgroupUnified bool
+
+	// LXCPath, LVMVolumeGroup, ZFSRoot and RootfsMount are the global
+	// lxc.lxcpath, lxc.bdev.lvm.vg, lxc.bdev.zfs.root and
+	// lxc.rootfs.mount settings.
+	LXCPath        string
+	LVMVolumeGroup string
+	ZFSRoot        string
+	RootfsMount    string
+
+	mu         sync.Mutex
+	extensions map[string]bool
+}
+
+var (
+	runtimeMu      sync.Mutex
+	currentRuntime *Runtime
+)
+
+// parseVersion splits a liblxc version such as "4.0.12", "5.0.0~git2209" or
+// "6.0.0 (devel)".
+func parseVersion(version string) (major int, minor int, micro int, devel bool) {
+	version = strings.Split(version, "~")[0]
+
+	if strings.HasSuffix(version, " (devel)") || strings.HasSuffix(version, "-devel") {
+		devel = true
+		version = strings.TrimSuffix(strings.TrimSuffix(version, " (devel)"), "-devel")
+	}
+
+	parts := strings.SplitN(version, ".", 3)
+	numbers := []*int{&major, &minor, &micro}
+	for i, part := range parts {
+		*numbers[i], _ = strconv.Atoi(part)
+	}
+	return
+}
+
+func newRuntime() *Runtime {
+	r := &Runtime{
+		Version:        Version(),
+		CgroupUnified:  detectCgroupUnified(),
+		LXCPath:        GlobalConfigItem("lxc.lxcpath"),
+		LVMVolumeGroup: GlobalConfigItem("lxc.bdev.lvm.vg"),
+		ZFSRoot:        GlobalConfigItem("lxc.bdev.zfs.root"),
+		RootfsMount:    GlobalConfigItem("lxc.rootfs.mount"),
+		extensions:     make(map[string]bool),
+	}
+	r.Major, r.Minor, r.Micro, r.Devel = parseVersion(r.Version)
+	return r
+}
+
+// RuntimeInfo returns the runtime information, collecting it on the first
+// call.
+func RuntimeInfo() *Runtime {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	if currentRuntime == nil {
+		currentRuntime = newRuntime()
+	}
+	return currentRuntime
+}
+
+// RefreshRuntimeInfo collects the runtime information again, e.g. after the
+// global LXC config was edited, and returns it. Runtime values returned
+// earlier keep the old information.
+func RefreshRuntimeInfo() *Runtime {
+	r := newRuntime()
+
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	currentRuntime = r
+	return r
+}
+
+// AtLeast returns true if the liblxc version is at least the given one.
+// Development snapshots support everything.
+func (r *Runtime) AtLeast(major int, minor int, micro int) bool {
+	if r.Devel {
+		return true
+	}
+
+	if r.Major != major {
+		return r.Major > major
+	}
+
+	if r.Minor != minor {
+		return r.Minor > minor
+	}
+	return r.Micro >= micro
+}
+
+// HasAPIExtension returns true if liblxc supports the extension. The answers
+// of liblxc are cached.
+func (r *Runtime) HasAPIExtension(extension string) bool {
+	if !r.AtLeast(3, 1, 0) {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	supported, ok := r.extensions[extension]
+	if !ok {
+		supported = hasAPIExtension(extension)
+		r.extensions[extension] = supported
+	}
+	return supported
+}
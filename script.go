@@ -0,0 +1,101 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// shellQuote joins args into a command line as parsed by sh.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		safe := arg != ""
+		for _, r := range arg {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@%+=:,./-", r)) {
+				safe = false
+				break
+			}
+		}
+
+		if safe {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// commandsScript returns a script running the commands in order, exiting
+// with the status of the first failing one. The commands read from
+// /dev/null so they can't consume the rest of the script.
+func commandsScript(commands [][]string) string {
+	var b strings.Builder
+	for _, args := range commands {
+		b.WriteString(shellQuote(args))
+		b.WriteString(" </dev/null || exit $?\n")
+	}
+	return b.String()
+}
+
+// runScript feeds the script to /bin/sh in the container through stdin.
+//
+// Caller needs to hold the lock
+func (c *Container) runScript(script string, options AttachOptions) (int, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return -1, err
+	}
+	defer r.Close()
+
+	// fails with EPIPE once the shell exits without reading everything
+	go func() {
+		io.WriteString(w, script)
+		w.Close()
+	}()
+
+	options.StdinFd = r.Fd()
+	ret, err := c.runCommandStatus([]string{"/bin/sh", "-s"}, options)
+	if err != nil {
+		return -1, err
+	}
+	if ret < 0 {
+		return ret, ErrAttachFailed
+	}
+	return ret, nil
+}
+
+// RunScript runs the shell script in the container with /bin/sh, in a
+// single attach instead of one per command, and returns its exit status.
+// The script is passed on stdin, commands of the script reading stdin
+// consume the rest of it.
+func (c *Container) RunScript(script string, options AttachOptions) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.runScript(script, options)
+}
+
+// RunCommands runs the commands in the container in order, in a single
+// attach instead of one per command. It stops at the first command which
+// fails and returns its exit status, 0 if all succeed. The commands run
+// through /bin/sh with their stdin on /dev/null.
+func (c *Container) RunCommands(commands [][]string, options AttachOptions) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, args := range commands {
+		if len(args) == 0 {
+			return -1, ErrInsufficientNumberOfArguments
+		}
+	}
+
+	return c.runScript(commandsScript(commands), options)
+}
@@ -0,0 +1,248 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ociSeccomp is the seccomp section of an OCI runtime spec, as also used by
+// the Docker and Kubernetes profiles.
+type ociSeccomp struct {
+	DefaultAction   string       `json:"defaultAction"`
+	DefaultErrnoRet *uint        `json:"defaultErrnoRet,omitempty"`
+	Architectures   []string     `json:"architectures,omitempty"`
+	Syscalls        []ociSyscall `json:"syscalls,omitempty"`
+}
+
+// ociSyscall is a rule of an OCI seccomp profile.
+type ociSyscall struct {
+	Names    []string         `json:"names"`
+	Name     string           `json:"name,omitempty"`
+	Action   string           `json:"action"`
+	ErrnoRet *uint            `json:"errnoRet,omitempty"`
+	Args     []ociSeccompArg  `json:"args,omitempty"`
+	Includes ociSeccompFilter `json:"includes,omitempty"`
+	Excludes ociSeccompFilter `json:"excludes,omitempty"`
+}
+
+// ociSeccompArg is a condition on a syscall argument.
+type ociSeccompArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+// ociSeccompFilter restricts a rule of a Docker profile to architectures
+// or capabilities.
+type ociSeccompFilter struct {
+	Arches []string `json:"arches,omitempty"`
+	Caps   []string `json:"caps,omitempty"`
+}
+
+// seccompOperators are the comparison operators understood by liblxc.
+var seccompOperators = map[string]bool{
+	"SCMP_CMP_NE":        true,
+	"SCMP_CMP_LT":        true,
+	"SCMP_CMP_LE":        true,
+	"SCMP_CMP_EQ":        true,
+	"SCMP_CMP_GE":        true,
+	"SCMP_CMP_GT":        true,
+	"SCMP_CMP_MASKED_EQ": true,
+}
+
+// seccompAction translates an OCI action into a liblxc one.
+func seccompAction(action string, errnoRet *uint) (string, error) {
+	switch action {
+	case "SCMP_ACT_ALLOW":
+		return "allow", nil
+	case "SCMP_ACT_KILL", "SCMP_ACT_KILL_THREAD", "SCMP_ACT_KILL_PROCESS":
+		return "kill", nil
+	case "SCMP_ACT_TRAP":
+		return "trap", nil
+	case "SCMP_ACT_LOG":
+		return "log", nil
+	case "SCMP_ACT_NOTIFY":
+		return "notify", nil
+	case "SCMP_ACT_ERRNO":
+		// EPERM unless given
+		errno := uint(1)
+		if errnoRet != nil {
+			errno = *errnoRet
+		}
+		return fmt.Sprintf("errno %d", errno), nil
+	}
+	return "", fmt.Errorf("%s: unsupported action %q", ErrInvalidSeccompProfile, action)
+}
+
+// seccompArg translates an argument condition into the liblxc format.
+// For SCMP_CMP_MASKED_EQ OCI gives the mask first, liblxc the value.
+func seccompArg(arg ociSeccompArg) (string, error) {
+	if !seccompOperators[arg.Op] || arg.Index > 5 {
+		return "", fmt.Errorf("%s: invalid argument condition %+v", ErrInvalidSeccompProfile, arg)
+	}
+
+	if arg.Op == "SCMP_CMP_MASKED_EQ" {
+		return fmt.Sprintf("[%d,%d,%s,%d]", arg.Index, arg.ValueTwo, arg.Op, arg.Value), nil
+	}
+	return fmt.Sprintf("[%d,%d,%s]", arg.Index, arg.Value, arg.Op), nil
+}
+
+// ruleApplies returns true if a rule restricted by includes and excludes
+// applies on this host. Rules requiring capabilities are left out, the
+// container is assumed to run without them.
+func (s ociSyscall) ruleApplies() bool {
+	if len(s.Includes.Caps) > 0 {
+		return false
+	}
+
+	if len(s.Includes.Arches) > 0 {
+		found := false
+		for _, arch := range s.Includes.Arches {
+			if arch == runtime.GOARCH {
+				found = true
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for _, arch := range s.Excludes.Arches {
+		if arch == runtime.GOARCH {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileOCISeccomp translates an OCI seccomp profile, either the seccomp
+// section of a runtime spec or a Docker profile, into a liblxc seccomp v2
+// policy. Rules of Docker profiles conditional on capabilities are left
+// out, the ones conditional on architectures only apply on matching hosts.
+func CompileOCISeccomp(profile []byte) (string, error) {
+	var spec ociSeccomp
+	if err := json.Unmarshal(profile, &spec); err != nil {
+		return "", fmt.Errorf("%s: %v", ErrInvalidSeccompProfile, err)
+	}
+
+	defaultAction, err := seccompAction(spec.DefaultAction, spec.DefaultErrnoRet)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{"2"}
+	if defaultAction == "allow" {
+		lines = append(lines, "denylist")
+	} else {
+		lines = append(lines, "allowlist "+defaultAction)
+	}
+	lines = append(lines, "[all]")
+
+	for _, syscall := range spec.Syscalls {
+		if !syscall.ruleApplies() {
+			continue
+		}
+
+		action, err := seccompAction(syscall.Action, syscall.ErrnoRet)
+		if err != nil {
+			return "", err
+		}
+
+		if action == defaultAction {
+			continue
+		}
+
+		var args []string
+		for _, arg := range syscall.Args {
+			v, err := seccompArg(arg)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, v)
+		}
+
+		names := syscall.Names
+		if syscall.Name != "" {
+			names = append(names, syscall.Name)
+		}
+
+		for _, name := range names {
+			if name == "" || strings.ContainsAny(name, " \t\n[]") {
+				return "", fmt.Errorf("%s: invalid syscall %q", ErrInvalidSeccompProfile, name)
+			}
+			lines = append(lines, strings.Join(append([]string{name, action}, args...), " "))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// SetOCISeccompProfile compiles the OCI seccomp profile (see
+// CompileOCISeccomp) and sets it as the seccomp profile of the container.
+// The policy is stored in the directory of a defined container, in a
+// temporary file otherwise.
+func (c *Container) SetOCISeccompProfile(profile []byte) (err error) {
+	finish, err := c.operation("SetOCISeccompProfile")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	policy, err := CompileOCISeccomp(profile)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	var path string
+	if dir := filepath.Join(c.configPath(), c.name()); c.defined() {
+		path = filepath.Join(dir, "seccomp.policy")
+		if err := ioutil.WriteFile(path, []byte(policy), 0644); err != nil {
+			return err
+		}
+	} else {
+		f, err := ioutil.TempFile("", "go-lxc-seccomp")
+		if err != nil {
+			return err
+		}
+		path = f.Name()
+
+		_, err = f.WriteString(policy)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+	}
+
+	key := "lxc.seccomp.profile"
+	if !VersionAtLeast(2, 1, 0) {
+		key = "lxc.seccomp"
+	}
+
+	if err := c.clearConfigItem(key); err != nil {
+		return err
+	}
+	return c.setConfigItem(key, path)
+}
@@ -0,0 +1,197 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hardeningCapabilities are the capabilities a privileged container should
+// drop, as done by the LXC common configuration.
+var hardeningCapabilities = []string{"mac_admin", "mac_override", "sys_module", "sys_rawio", "sys_time"}
+
+// SecurityFinding represents a single result of a security audit.
+type SecurityFinding struct {
+	// Check is the name of the check, e.g. "privileged" or "seccomp".
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+// SecurityReport represents the result of a security audit.
+type SecurityReport struct {
+	Findings []SecurityFinding
+	// Grade is "A" without warnings, "B" with up to two, "C" with more and
+	// "F" with any critical finding.
+	Grade string
+}
+
+// auditConfig checks the configuration, given by key, against the
+// hardening checklist.
+func auditConfig(config map[string][]string, idmap IDMap) SecurityReport {
+	var report SecurityReport
+	add := func(check string, severity Severity, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, SecurityFinding{check, severity, fmt.Sprintf(format, args...)})
+	}
+
+	values := func(key string) []string {
+		var result []string
+		for _, v := range config[key] {
+			result = append(result, strings.Fields(v)...)
+		}
+		return result
+	}
+
+	privileged := len(idmap) == 0
+	if privileged {
+		add("privileged", SeverityCritical, "container is privileged, root in the container is root on the host")
+	} else if host, ok := idmap.ToHost(IDTypeUID, 0); ok && host == 0 {
+		add("privileged", SeverityCritical, "uid 0 in the container is mapped to uid 0 on the host")
+	} else if _, ok := idmap.ToHost(IDTypeUID, 0); !ok {
+		add("privileged", SeverityInfo, "uid 0 in the container isn't mapped")
+	}
+
+	if keep := values("lxc.cap.keep"); len(keep) > 0 {
+		add("capabilities", SeverityInfo, "capabilities are restricted to %s", strings.Join(keep, ", "))
+	} else if privileged {
+		dropped := make(map[string]bool)
+		for _, v := range values("lxc.cap.drop") {
+			dropped[strings.ToLower(v)] = true
+		}
+
+		var missing []string
+		for _, capability := range hardeningCapabilities {
+			if !dropped[capability] {
+				missing = append(missing, capability)
+			}
+		}
+
+		if len(missing) > 0 {
+			add("capabilities", SeverityWarning, "capabilities not dropped: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	switch profile := strings.Join(config["lxc.apparmor.profile"], ""); profile {
+	case "unconfined":
+		if privileged {
+			add("apparmor", SeverityCritical, "apparmor profile is unconfined")
+		} else {
+			add("apparmor", SeverityWarning, "apparmor profile is unconfined")
+		}
+	case "":
+		add("apparmor", SeverityInfo, "apparmor profile is chosen by liblxc")
+	}
+
+	if strings.Join(config["lxc.apparmor.allow_nesting"], "") == "1" {
+		add("apparmor", SeverityWarning, "apparmor allows nesting, which gives access to /proc and /sys of the container")
+	}
+
+	if strings.Join(config["lxc.seccomp.profile"], "") == "" {
+		add("seccomp", SeverityWarning, "no seccomp profile is set")
+	}
+
+	for _, v := range values("lxc.mount.auto") {
+		switch v {
+		case "proc:rw", "sys:rw", "cgroup:rw", "cgroup-full:rw":
+			add("mounts", SeverityWarning, "%s is mounted read-write", strings.TrimSuffix(v, ":rw"))
+		}
+	}
+
+	for _, entry := range config["lxc.mount.entry"] {
+		fields := strings.Fields(entry)
+		if len(fields) < 4 {
+			continue
+		}
+
+		readonly := false
+		for _, option := range strings.Split(fields[3], ",") {
+			if option == "ro" {
+				readonly = true
+			}
+		}
+
+		switch fields[0] {
+		case "/", "/proc", "/sys", "/dev", "/run", "/var/run":
+			if !readonly {
+				add("mounts", SeverityCritical, "host %s is mounted read-write", fields[0])
+			}
+		}
+	}
+
+	for _, key := range []string{"lxc.cgroup.devices.allow", "lxc.cgroup2.devices.allow"} {
+		for _, v := range config[key] {
+			if strings.TrimSpace(v) == "a" {
+				add("devices", SeverityCritical, "access to all devices is allowed")
+			}
+		}
+	}
+
+	warnings := 0
+	report.Grade = "A"
+	for _, finding := range report.Findings {
+		switch finding.Severity {
+		case SeverityCritical:
+			report.Grade = "F"
+			return report
+		case SeverityWarning:
+			warnings++
+		}
+	}
+
+	if warnings > 2 {
+		report.Grade = "C"
+	} else if warnings > 0 {
+		report.Grade = "B"
+	}
+	return report
+}
+
+// SecurityAudit checks the configuration of the container against a
+// hardening checklist: whether it is privileged or maps root to the host,
+// the dropped capabilities, the apparmor and seccomp profiles, mounts of
+// /proc and /sys and the allowed devices. The findings are graded, see
+// SecurityReport.
+func (c *Container) SecurityAudit() (SecurityReport, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return SecurityReport{}, ErrNotDefined
+	}
+
+	idmap, err := c.idMap()
+	if err != nil {
+		return SecurityReport{}, err
+	}
+
+	keys := map[string]string{
+		"lxc.apparmor.profile":       "lxc.apparmor.profile",
+		"lxc.apparmor.allow_nesting": "lxc.apparmor.allow_nesting",
+		"lxc.seccomp.profile":        "lxc.seccomp.profile",
+		"lxc.cap.drop":               "lxc.cap.drop",
+		"lxc.cap.keep":               "lxc.cap.keep",
+		"lxc.mount.auto":             "lxc.mount.auto",
+		"lxc.mount.entry":            "lxc.mount.entry",
+		"lxc.cgroup.devices.allow":   "lxc.cgroup.devices.allow",
+		"lxc.cgroup2.devices.allow":  "lxc.cgroup2.devices.allow",
+	}
+
+	if !VersionAtLeast(2, 1, 0) {
+		keys["lxc.apparmor.profile"] = "lxc.aa_profile"
+		keys["lxc.seccomp.profile"] = "lxc.seccomp"
+		delete(keys, "lxc.apparmor.allow_nesting")
+		delete(keys, "lxc.cgroup2.devices.allow")
+	}
+
+	config := make(map[string][]string)
+	for key, configKey := range keys {
+		config[key] = nonEmpty(c.configItem(configKey))
+	}
+
+	return auditConfig(config, idmap), nil
+}
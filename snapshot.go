@@ -0,0 +1,109 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// snapshotTimestampLayout is the layout liblxc uses for snapshot timestamps.
+const snapshotTimestampLayout = "2006:01:02 15:04:05"
+
+// ContainerSnapshot represents a snapshot together with the container it
+// belongs to.
+type ContainerSnapshot struct {
+	Container string
+	Snapshot  Snapshot
+	// Time is the parsed snapshot timestamp.
+	Time time.Time
+	// Size is the disk usage of the snapshot directory. Snapshots stored
+	// outside the filesystem (e.g. zfs or lvm) only account for their config.
+	Size ByteSize
+}
+
+// diskUsage returns the disk usage of the given path, counting hard linked
+// files once.
+func diskUsage(path string) (ByteSize, error) {
+	var size int64
+	seen := make(map[uint64]bool)
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files may disappear or be unreadable, skip them
+			if p == path {
+				return err
+			}
+			return nil
+		}
+
+		st, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			size += info.Size()
+			return nil
+		}
+
+		if st.Nlink > 1 && !info.IsDir() {
+			if seen[st.Ino] {
+				return nil
+			}
+			seen[st.Ino] = true
+		}
+
+		size += st.Blocks * 512
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return ByteSize(size), nil
+}
+
+// AllSnapshots returns the snapshots of all containers in the given lxcpath,
+// so backup tools can inventory an entire host in one call.
+func AllSnapshots(lxcpath ...string) ([]ContainerSnapshot, error) {
+	containers, err := ContainersE(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		for _, c := range containers {
+			c.Release()
+		}
+	}()
+
+	var snapshots []ContainerSnapshot
+	for _, c := range containers {
+		list, err := c.Snapshots()
+		if err == ErrNoSnapshot || err == ErrNotDefined {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range list {
+			snapshot := ContainerSnapshot{Container: c.Name(), Snapshot: s}
+
+			if t, err := time.ParseInLocation(snapshotTimestampLayout, s.Timestamp, time.Local); err == nil {
+				snapshot.Time = t
+			}
+
+			if size, err := diskUsage(filepath.Join(s.Path, s.Name)); err == nil {
+				snapshot.Size = size
+			}
+
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
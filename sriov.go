@@ -0,0 +1,157 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sysClassNet lists the network interfaces of the host.
+const sysClassNet = "/sys/class/net"
+
+// VF is a SR-IOV virtual function of a host network card.
+type VF struct {
+	// PF is the interface of the physical function, e.g. enp3s0f0.
+	PF string
+	// Index is the number of the VF on the physical function.
+	Index int
+	// PCIAddress is the address of the VF, e.g. 0000:03:10.0.
+	PCIAddress string
+	// Device is the interface of the VF on the host, empty if it's moved
+	// into a container or not bound to a network driver.
+	Device string
+}
+
+// Free returns whether the VF can be assigned to a container.
+func (vf VF) Free() bool {
+	return vf.Device != ""
+}
+
+// hostVFs returns the VFs of the physical functions in the sysfs net class
+// directory.
+func hostVFs(dir string) ([]VF, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var vfs []VF
+	for _, entry := range entries {
+		device := filepath.Join(dir, entry.Name(), "device")
+
+		// only physical functions have sriov_numvfs
+		if _, err := os.Stat(filepath.Join(device, "sriov_numvfs")); err != nil {
+			continue
+		}
+
+		links, err := filepath.Glob(filepath.Join(device, "virtfn*"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+			if err != nil {
+				continue
+			}
+
+			target, err := os.Readlink(link)
+			if err != nil {
+				continue
+			}
+
+			vf := VF{PF: entry.Name(), Index: index, PCIAddress: filepath.Base(target)}
+			if names, err := ioutil.ReadDir(filepath.Join(link, "net")); err == nil && len(names) > 0 {
+				vf.Device = names[0].Name()
+			}
+			vfs = append(vfs, vf)
+		}
+	}
+
+	sort.Slice(vfs, func(i, j int) bool {
+		if vfs[i].PF != vfs[j].PF {
+			return vfs[i].PF < vfs[j].PF
+		}
+		return vfs[i].Index < vfs[j].Index
+	})
+	return vfs, nil
+}
+
+// HostVFs returns the SR-IOV virtual functions of the network cards of the
+// host.
+func HostVFs() ([]VF, error) {
+	return hostVFs(sysClassNet)
+}
+
+// vfArgs returns the arguments of ip link configuring the VF on its
+// physical function, nil if there's nothing to configure.
+func vfArgs(vf VF, opts VFOptions) []string {
+	var args []string
+	if opts.HWAddr != "" {
+		args = append(args, "mac", opts.HWAddr)
+	}
+	if opts.VLAN > 0 {
+		args = append(args, "vlan", strconv.Itoa(opts.VLAN))
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]string{"ip", "link", "set", "dev", vf.PF, "vf", strconv.Itoa(vf.Index)}, args...)
+}
+
+// AssignVF assigns the free SR-IOV virtual function to the container as a
+// phys network device and returns its index. The MAC address and VLAN of
+// opts are set on the physical function right away, the interface is moved
+// into the container on the next start. liblxc moves it back to the host
+// when the container stops, RemoveNetwork then releases it from the
+// config.
+func (c *Container) AssignVF(vf VF, opts VFOptions) (_ int, err error) {
+	finish, err := c.operation("AssignVF", vf.PF, strconv.Itoa(vf.Index))
+	if err != nil {
+		return 0, err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return -1, ErrNotDefined
+	}
+
+	if !vf.Free() {
+		return -1, fmt.Errorf("%s: %s vf %d", ErrVFInUse, vf.PF, vf.Index)
+	}
+
+	for _, index := range c.networkIndices() {
+		if n := c.network(index); n.Type == NetworkPhys && n.Link == vf.Device {
+			return -1, fmt.Errorf("%s: %s", ErrVFInUse, vf.Device)
+		}
+	}
+
+	if args := vfArgs(vf, opts); args != nil {
+		if err := runCommands([][]string{args}); err != nil {
+			return -1, err
+		}
+	}
+
+	return c.addNetwork(Network{
+		Type:   NetworkPhys,
+		Link:   vf.Device,
+		Name:   opts.Name,
+		HWAddr: opts.HWAddr,
+		MTU:    opts.MTU,
+		Up:     true,
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// startFdsHelperArg marks an invocation of the current executable as the
+// monitor of a container started with StartWithFds.
+const startFdsHelperArg = "__go_lxc_start_fds__"
+
+// startFdsTimeout is how long StartWithFds waits for the container to run.
+const startFdsTimeout = 60 * time.Second
+
+// listenEnv returns the environment of init announcing the fds, see
+// sd_listen_fds(3). Init is pid 1 in the container.
+func listenEnv(names []string) []KeyValue {
+	return []KeyValue{
+		{"lxc.environment", "LISTEN_PID=1"},
+		{"lxc.environment", "LISTEN_FDS=" + strconv.Itoa(len(names))},
+		{"lxc.environment", "LISTEN_FDNAMES=" + strings.Join(names, ":")},
+	}
+}
+
+// validFdName returns whether the name is accepted by sd_listen_fds, which
+// splits LISTEN_FDNAMES at colons.
+func validFdName(name string) bool {
+	if name == "" || len(name) > 255 {
+		return false
+	}
+
+	for _, r := range name {
+		if r < ' ' || r > '~' || r == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// runStartFdsHelper starts the container in the foreground, liblxc closes
+// the inherited fds when it daemonizes. It returns once the container
+// stopped.
+func runStartFdsHelper(name string, lxcpath string, names []string) error {
+	c, err := NewContainer(name, lxcpath)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	for _, kv := range listenEnv(names) {
+		if err := c.SetConfigItem(kv.Key, kv.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := c.WantDaemonize(false); err != nil {
+		return err
+	}
+
+	if err := c.WantCloseAllFds(false); err != nil {
+		return err
+	}
+
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("%s: init exited with %d", err, c.ErrorNum())
+	}
+	return nil
+}
+
+// StartWithFds starts the container passing the files to its init from fd
+// 3 on, in the order of their names, with LISTEN_FDS, LISTEN_FDNAMES and
+// LISTEN_PID set for socket activation, e.g. of systemd units inside the
+// container. liblxc only keeps inherited fds when starting in the
+// foreground, so the current executable is run as monitor of the container
+// with the saved configuration. It returns once the container is running,
+// or with ErrStartFailed and the error of the monitor if it exits first or
+// the container isn't running within startFdsTimeout, in which case the
+// monitor is killed.
+func (c *Container) StartWithFds(fds map[string]*os.File) (err error) {
+	finish, err := c.operation("StartWithFds")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	// the monitor outlives the caller, a pipe would break once it exits
+	stderr, err := ioutil.TempFile("", "go-lxc-start")
+	if err != nil {
+		return err
+	}
+	os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	c.mu.Lock()
+	cmd, err := c.startFdsMonitor(fds, stderr)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(startFdsTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				// it ran and stopped already
+				return nil
+			}
+			msg, _ := ioutil.ReadFile(fmt.Sprintf("/proc/self/fd/%d", stderr.Fd()))
+			return fmt.Errorf("%s: %v: %s", ErrStartFailed, err, strings.TrimSpace(string(msg)))
+		case <-ticker.C:
+			if c.Running() {
+				return nil
+			}
+		case <-timeout.C:
+			cmd.Process.Kill()
+			<-exited
+			return fmt.Errorf("%s: not running after %s", ErrStartFailed, startFdsTimeout)
+		}
+	}
+}
+
+// startFdsMonitor runs the current executable as monitor of the container
+// with the files from fd 3 on.
+//
+// Caller needs to hold the lock
+func (c *Container) startFdsMonitor(fds map[string]*os.File, stderr *os.File) (*exec.Cmd, error) {
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	if err := c.makeSure(isNotRunning); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range fds {
+		if !validFdName(name) {
+			return nil, fmt.Errorf("%s: %q", ErrInvalidFdName, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(exe, append([]string{startFdsHelperArg, c.name(), c.configPath()}, names...)...)
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	for _, name := range names {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, fds[name])
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
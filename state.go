@@ -0,0 +1,76 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// stablePollInterval is how often EnsureState checks whether a container
+// left a transitional state.
+const stablePollInterval = 100 * time.Millisecond
+
+// waitStable waits for the container to reach a stable state.
+func (c *Container) waitStable(ctx context.Context) (State, error) {
+	ticker := time.NewTicker(stablePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if state := c.State(); state.Stable() {
+			return state, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnsureState brings the container into the target state, which is STOPPED,
+// RUNNING or FROZEN, by starting, stopping, freezing or unfreezing it. It
+// waits for containers in transitional states (e.g. STARTING) to settle
+// first and is a no-op if the container already is in the target state,
+// which makes it suitable for reconciliation loops.
+func (c *Container) EnsureState(ctx context.Context, target State) error {
+	if !target.Stable() {
+		return fmt.Errorf("%s: %s", ErrUnstableState, target)
+	}
+
+	for {
+		state, err := c.waitStable(ctx)
+		if err != nil {
+			return err
+		}
+
+		if state == target {
+			return nil
+		}
+
+		switch {
+		case state == FROZEN:
+			// thaw first, a frozen container can't be stopped cleanly
+			err = c.Unfreeze()
+		case state == STOPPED:
+			err = c.Start()
+		case target == STOPPED:
+			err = c.Stop()
+		case target == FROZEN:
+			err = c.Freeze()
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
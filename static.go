@@ -0,0 +1,111 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nssSources are the sources of /etc/nsswitch.conf available to static
+// binaries, all others are NSS modules loaded with dlopen.
+var nssSources = map[string]bool{
+	"files":  true,
+	"compat": true,
+}
+
+// nssDatabases are the databases liblxc looks up.
+var nssDatabases = map[string]bool{
+	"passwd": true,
+	"group":  true,
+}
+
+// StaticallyLinked returns whether liblxc is linked into the binary, i.e. it
+// was built with the static_liblxc (or static_build) tag.
+func StaticallyLinked() bool {
+	return staticLiblxc
+}
+
+// parseNSSwitch returns the databases liblxc needs which are looked up
+// through NSS modules.
+func parseNSSwitch(data []byte) []string {
+	var modules []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		database, sources, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		database = strings.TrimSpace(database)
+		if !nssDatabases[database] {
+			continue
+		}
+
+		for _, source := range strings.Fields(sources) {
+			// skip actions, e.g. [NOTFOUND=return]
+			if strings.HasPrefix(source, "[") {
+				continue
+			}
+			if !nssSources[source] {
+				modules = append(modules, fmt.Sprintf("%s: %s", database, source))
+			}
+		}
+	}
+	return modules
+}
+
+// CheckRuntime verifies the host provides what liblxc needs at runtime: the
+// lxcpath and rootfs mount point, a cgroup hierarchy and, if liblxc is
+// statically linked, users and groups which can be looked up without NSS
+// modules. It's meant for single binary deployments to minimal hosts.
+func CheckRuntime() error {
+	var problems []string
+
+	if lxcpath := DefaultConfigPath(); lxcpath == "" {
+		problems = append(problems, "lxc.lxcpath is not set")
+	} else if _, err := os.Stat(filepath.Dir(lxcpath)); err != nil {
+		problems = append(problems, fmt.Sprintf("lxcpath %q can't be created", lxcpath))
+	}
+
+	if mount := RuntimeInfo().RootfsMount; mount != "" {
+		if _, err := os.Stat(mount); err != nil {
+			problems = append(problems, fmt.Sprintf("rootfs mount point %q is missing", mount))
+		}
+	}
+
+	if _, err := os.Stat("/sys/fs/cgroup"); err != nil {
+		problems = append(problems, "no cgroup hierarchy is mounted")
+	}
+
+	if staticLiblxc {
+		data, err := ioutil.ReadFile("/etc/nsswitch.conf")
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for _, module := range parseNSSwitch(data) {
+			problems = append(problems, fmt.Sprintf("%q needs NSS modules unavailable to static binaries", module))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %s", ErrRuntimeCheckFailed, strings.Join(problems, ", "))
+	}
+	return nil
+}
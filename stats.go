@@ -0,0 +1,307 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// MemStats is the memory usage of a container.
+type MemStats struct {
+	// Usage is the memory used, including the page cache.
+	Usage ByteSize
+	// Limit is the memory limit, math.MaxInt64 if unlimited.
+	Limit ByteSize
+	// Swap is the swap used, 0 without swap accounting.
+	Swap ByteSize
+	// Anon is the anonymous memory, e.g. the heaps of the processes.
+	Anon ByteSize
+	// File is the page cache.
+	File ByteSize
+}
+
+// CPUStats is the CPU usage of a container.
+type CPUStats struct {
+	// Usage is the CPU time used.
+	Usage time.Duration
+	// User and System are the CPU time used in user and kernel mode.
+	User   time.Duration
+	System time.Duration
+}
+
+// statsFile is a cgroup file kept open to be read again and again.
+type statsFile struct {
+	fd int
+}
+
+// StatsReader reads the memory and CPU usage of a running container
+// without allocating, for monitoring agents sampling many containers at a
+// high frequency. It keeps the cgroup files open, re-reading them with
+// pread, and parses them into structs provided by the caller. It isn't safe
+// for concurrent use and needs to be closed.
+type StatsReader struct {
+	unified bool
+	buf     []byte
+
+	memCurrent, memMax, memSwap, memStat statsFile
+	cpuUsage, cpuStat                    statsFile
+}
+
+// openStatsFile opens the cgroup file, which may be missing, e.g. without
+// swap accounting.
+func openStatsFile(dir string, name string) statsFile {
+	fd, err := unix.Open(filepath.Join(dir, name), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return statsFile{fd: -1}
+	}
+	return statsFile{fd: fd}
+}
+
+// newStatsReader opens the cgroup files of the memory and cpu (cpuacct on
+// cgroup v1) directories, the same directory on cgroup v2.
+func newStatsReader(unified bool, memDir string, cpuDir string) *StatsReader {
+	r := &StatsReader{unified: unified, buf: make([]byte, 16*1024)}
+
+	if unified {
+		r.memCurrent = openStatsFile(memDir, "memory.current")
+		r.memMax = openStatsFile(memDir, "memory.max")
+		r.memSwap = openStatsFile(memDir, "memory.swap.current")
+		r.memStat = openStatsFile(memDir, "memory.stat")
+		r.cpuUsage = statsFile{fd: -1}
+		r.cpuStat = openStatsFile(cpuDir, "cpu.stat")
+		return r
+	}
+
+	r.memCurrent = openStatsFile(memDir, "memory.usage_in_bytes")
+	r.memMax = openStatsFile(memDir, "memory.limit_in_bytes")
+	r.memSwap = openStatsFile(memDir, "memory.memsw.usage_in_bytes")
+	r.memStat = openStatsFile(memDir, "memory.stat")
+	r.cpuUsage = openStatsFile(cpuDir, "cpuacct.usage")
+	r.cpuStat = openStatsFile(cpuDir, "cpuacct.stat")
+	return r
+}
+
+// NewStatsReader returns a StatsReader for the running container. It reads
+// the cgroups the container had when it was created, so it needs to be
+// replaced when the container restarts.
+func (c *Container) NewStatsReader() (*StatsReader, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	if CgroupUnified() {
+		dir, err := c.unifiedCgroupPath()
+		if err != nil {
+			return nil, err
+		}
+		return newStatsReader(true, dir, dir), nil
+	}
+
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", c.initPid()))
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := parseProcCgroup(string(content), "memory")
+	if err != nil {
+		return nil, err
+	}
+
+	cpuacct, err := parseProcCgroup(string(content), "cpuacct")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := c.payloadCgroupDir()
+
+	memory, err = payloadCgroup(memory, c.name(), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cpuacct, err = payloadCgroup(cpuacct, c.name(), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatsReader(false,
+		filepath.Join("/sys/fs/cgroup/memory", memory),
+		filepath.Join("/sys/fs/cgroup/cpuacct", cpuacct)), nil
+}
+
+// read returns the current content of the file, nil if it's missing. The
+// content is valid until the next read.
+func (r *StatsReader) read(f statsFile) ([]byte, error) {
+	if f.fd < 0 {
+		return nil, nil
+	}
+
+	n, err := unix.Pread(f.fd, r.buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	return r.buf[:n], nil
+}
+
+// parseStatsUint parses a decimal number at the start of b, skipping
+// leading white space, without allocating.
+func parseStatsUint(b []byte) (uint64, []byte, bool) {
+	for len(b) > 0 && (b[0] == ' ' || b[0] == '\n') {
+		b = b[1:]
+	}
+
+	var n uint64
+	i := 0
+	for ; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		n = n*10 + uint64(b[i]-'0')
+	}
+	return n, b[i:], i > 0
+}
+
+// readUint reads a file holding a single number, "max" is returned as
+// math.MaxInt64.
+func (r *StatsReader) readUint(f statsFile) (uint64, error) {
+	content, err := r.read(f)
+	if err != nil || content == nil {
+		return 0, err
+	}
+
+	if len(content) >= 3 && string(content[:3]) == "max" {
+		return math.MaxInt64, nil
+	}
+
+	n, _, ok := parseStatsUint(content)
+	if !ok {
+		return 0, unix.EINVAL
+	}
+	return n, nil
+}
+
+// parseStatsKeys calls fn for every "key value" line of a stat file.
+func parseStatsKeys(content []byte, fn func(key []byte, value uint64)) {
+	for len(content) > 0 {
+		i := 0
+		for i < len(content) && content[i] != ' ' && content[i] != '\n' {
+			i++
+		}
+		key := content[:i]
+
+		value, rest, ok := parseStatsUint(content[i:])
+		if ok {
+			fn(key, value)
+		}
+
+		// skip to the next line
+		for len(rest) > 0 && rest[0] != '\n' {
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+		content = rest
+	}
+}
+
+// ReadMemoryStatsInto reads the memory usage of the container into s.
+func (r *StatsReader) ReadMemoryStatsInto(s *MemStats) error {
+	usage, err := r.readUint(r.memCurrent)
+	if err != nil {
+		return err
+	}
+
+	limit, err := r.readUint(r.memMax)
+	if err != nil {
+		return err
+	}
+
+	swap, err := r.readUint(r.memSwap)
+	if err != nil {
+		return err
+	}
+
+	content, err := r.read(r.memStat)
+	if err != nil {
+		return err
+	}
+
+	*s = MemStats{Usage: ByteSize(usage), Limit: ByteSize(limit)}
+	if r.unified {
+		s.Swap = ByteSize(swap)
+	} else if swap > usage {
+		// memsw includes the memory
+		s.Swap = ByteSize(swap - usage)
+	}
+
+	parseStatsKeys(content, func(key []byte, value uint64) {
+		switch string(key) {
+		case "anon", "total_rss":
+			s.Anon = ByteSize(value)
+		case "file", "total_cache":
+			s.File = ByteSize(value)
+		}
+	})
+
+	// cgroup v1 reports unlimited as the largest page aligned number
+	if s.Limit >= ByteSize(math.MaxInt64&^0xfff) {
+		s.Limit = ByteSize(math.MaxInt64)
+	}
+	return nil
+}
+
+// ReadCPUStatsInto reads the CPU usage of the container into s.
+func (r *StatsReader) ReadCPUStatsInto(s *CPUStats) error {
+	*s = CPUStats{}
+
+	if !r.unified {
+		usage, err := r.readUint(r.cpuUsage)
+		if err != nil {
+			return err
+		}
+		s.Usage = time.Duration(usage)
+	}
+
+	content, err := r.read(r.cpuStat)
+	if err != nil {
+		return err
+	}
+
+	parseStatsKeys(content, func(key []byte, value uint64) {
+		switch string(key) {
+		case "usage_usec":
+			s.Usage = time.Duration(value) * time.Microsecond
+		case "user_usec":
+			s.User = time.Duration(value) * time.Microsecond
+		case "system_usec":
+			s.System = time.Duration(value) * time.Microsecond
+		case "user":
+			s.User = time.Duration(value) * time.Second / userHZ
+		case "system":
+			s.System = time.Duration(value) * time.Second / userHZ
+		}
+	})
+	return nil
+}
+
+// Close closes the cgroup files.
+func (r *StatsReader) Close() error {
+	for _, f := range []*statsFile{&r.memCurrent, &r.memMax, &r.memSwap, &r.memStat, &r.cpuUsage, &r.cpuStat} {
+		if f.fd >= 0 {
+			unix.Close(f.fd)
+			f.fd = -1
+		}
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// swapHelperArg marks an invocation of the current executable as the
+// post-stop hook releasing the swap of a container.
+const swapHelperArg = "__go_lxc_swap__"
+
+// swapFlagPrefer is SWAP_FLAG_PREFER of swapon(2), the priority goes into
+// the lower bits.
+const swapFlagPrefer = 0x8000
+
+// SwapBackend specifies how the swap of a container is provided.
+type SwapBackend int
+
+const (
+	// SwapFile swaps to a file in the directory of the container. Swap isn't
+	// namespaced, the file is activated on the host and adds to the swap
+	// every process of the host may use, the memory cgroup only limits the
+	// swap use of the container to its size.
+	SwapFile SwapBackend = iota + 1
+	// SwapZram passes a zram device to the container, which activates it
+	// itself. This needs a privileged container.
+	SwapZram
+)
+
+// SwapBackend as string
+func (b SwapBackend) String() string {
+	switch b {
+	case SwapFile:
+		return "file"
+	case SwapZram:
+		return "zram"
+	}
+	return ""
+}
+
+// swapState is the swap configuration of a container and the zram device
+// passed to it while running.
+type swapState struct {
+	Options SwapOptions `json:"options"`
+	Device  string      `json:"device,omitempty"`
+}
+
+// swapStatePath returns the file holding the swap configuration.
+func swapStatePath(lxcpath string, name string) string {
+	return filepath.Join(lxcpath, name, "swap.json")
+}
+
+// swapFilePath returns the swap file of a container.
+func swapFilePath(lxcpath string, name string) string {
+	return filepath.Join(lxcpath, name, "swap.img")
+}
+
+// loadSwapState returns the swap configuration, false if the container has
+// none.
+func loadSwapState(lxcpath string, name string) (swapState, bool, error) {
+	var state swapState
+
+	content, err := ioutil.ReadFile(swapStatePath(lxcpath, name))
+	if os.IsNotExist(err) {
+		return state, false, nil
+	} else if err != nil {
+		return state, false, err
+	}
+
+	if err := json.Unmarshal(content, &state); err != nil {
+		return state, false, err
+	}
+	return state, true, nil
+}
+
+// saveSwapState records the swap configuration.
+func saveSwapState(lxcpath string, name string, state swapState) error {
+	content, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(swapStatePath(lxcpath, name), content, 0644)
+}
+
+// swapon activates the swap area at path, an active one isn't an error.
+func swapon(path string, priority int) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	flags := 0
+	if priority >= 0 {
+		flags = swapFlagPrefer | priority&0x7fff
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_SWAPON, uintptr(unsafe.Pointer(p)), uintptr(flags), 0)
+	if errno != 0 && errno != unix.EBUSY {
+		return fmt.Errorf("%s: swapon %s: %v", ErrSwapFailed, path, errno)
+	}
+	return nil
+}
+
+// swapoff deactivates the swap area at path, an inactive one isn't an
+// error.
+func swapoff(path string) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_SWAPOFF, uintptr(unsafe.Pointer(p)), 0, 0)
+	if errno != 0 && errno != unix.EINVAL && errno != unix.ENOENT {
+		return fmt.Errorf("%s: swapoff %s: %v", ErrSwapFailed, path, errno)
+	}
+	return nil
+}
+
+// mkswap sets up a swap area at path.
+func mkswap(path string) error {
+	if output, err := exec.Command("mkswap", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", ErrSwapFailed, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// createSwapFile creates a swap file of the size at path, reusing an
+// existing one of the same size. Swap files must not have holes, so the
+// space is allocated.
+func createSwapFile(path string, size ByteSize) error {
+	if info, err := os.Stat(path); err == nil && info.Size() == int64(size) {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Fallocate(int(f.Fd()), 0, 0, int64(size))
+	if err == unix.EOPNOTSUPP {
+		// write zeros instead
+		err = nil
+		buf := make([]byte, 1<<20)
+		for left := int64(size); left > 0 && err == nil; left -= int64(len(buf)) {
+			if left < int64(len(buf)) {
+				buf = buf[:left]
+			}
+			_, err = f.Write(buf)
+		}
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("%s: %v", ErrSwapFailed, err)
+	}
+	return mkswap(path)
+}
+
+// addZram sets up a new zram device of the size as swap area.
+func addZram(size ByteSize) (string, error) {
+	content, err := ioutil.ReadFile("/sys/class/zram-control/hot_add")
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", ErrSwapFailed, err)
+	}
+
+	id := strings.TrimSpace(string(content))
+	if err := ioutil.WriteFile(fmt.Sprintf("/sys/block/zram%s/disksize", id), []byte(strconv.FormatInt(int64(size), 10)), 0644); err != nil {
+		removeZram("/dev/zram" + id)
+		return "", fmt.Errorf("%s: %v", ErrSwapFailed, err)
+	}
+
+	device := "/dev/zram" + id
+	if err := mkswap(device); err != nil {
+		removeZram(device)
+		return "", err
+	}
+	return device, nil
+}
+
+// removeZram deactivates the zram device, swap isn't namespaced so the
+// device stays active after the container stopped, and removes it.
+func removeZram(device string) error {
+	if err := swapoff(device); err != nil {
+		return err
+	}
+
+	id := strings.TrimPrefix(device, "/dev/zram")
+	ioutil.WriteFile(fmt.Sprintf("/sys/block/zram%s/reset", id), []byte("1"), 0644)
+	if err := ioutil.WriteFile("/sys/class/zram-control/hot_remove", []byte(id), 0644); err != nil {
+		return fmt.Errorf("%s: %v", ErrSwapFailed, err)
+	}
+	return nil
+}
+
+// swapLimitItem returns the config item limiting the swap of a container
+// with the memory limit to size. cgroup1 limits memory plus swap, which
+// can't be derived without a memory limit.
+func swapLimitItem(unified bool, memory ByteSize, size ByteSize) (KeyValue, error) {
+	if unified {
+		return KeyValue{"lxc.cgroup2.memory.swap.max", strconv.FormatInt(int64(size), 10)}, nil
+	}
+
+	if memory == 0 {
+		return KeyValue{}, fmt.Errorf("%s: cgroup1 can't limit the swap without a memory limit", ErrSwapFailed)
+	}
+	return KeyValue{"lxc.cgroup.memory.memsw.limit_in_bytes", strconv.FormatInt(int64(memory+size), 10)}, nil
+}
+
+// swapLimit returns the config item limiting the swap of the container to
+// size with its configured memory limit.
+//
+// Caller needs to hold the lock
+func (c *Container) swapLimit(size ByteSize) (KeyValue, error) {
+	memoryKey := "lxc.cgroup.memory.limit_in_bytes"
+	if CgroupUnified() {
+		memoryKey = "lxc.cgroup2.memory.max"
+	}
+
+	memory, err := parseCgroupBytes(c.configItem(memoryKey)[0])
+	if err != nil {
+		return KeyValue{}, err
+	}
+	return swapLimitItem(CgroupUnified(), memory, size)
+}
+
+// releaseSwap deactivates the swap of the stopped container and removes
+// its zram device.
+func releaseSwap(lxcpath string, name string) error {
+	state, ok, err := loadSwapState(lxcpath, name)
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := swapoff(swapFilePath(lxcpath, name)); err != nil {
+		return err
+	}
+
+	if state.Device == "" {
+		return nil
+	}
+
+	if err := removeZram(state.Device); err != nil {
+		return err
+	}
+
+	state.Device = ""
+	return saveSwapState(lxcpath, name, state)
+}
+
+// provisionSwap activates the swap of the container for the next start and
+// sets up the config items limiting it to the swap and releasing it once
+// the container stopped. The returned function drops the items again once
+// liblxc read them.
+//
+// Caller needs to hold the lock
+func (c *Container) provisionSwap() (func(), error) {
+	state, ok, err := loadSwapState(c.configPath(), c.name())
+	if err != nil || !ok {
+		return func() {}, err
+	}
+
+	// left over by a start which failed
+	if err := releaseSwap(c.configPath(), c.name()); err != nil {
+		return func() {}, err
+	}
+
+	// the swap isn't activated unless it can be limited
+	limit, err := c.swapLimit(state.Options.Size)
+	if err != nil {
+		return func() {}, err
+	}
+
+	items := []KeyValue{limit}
+	restore := func() {
+		for _, kv := range items {
+			c.removeConfigValue(kv.Key, kv.Value)
+		}
+	}
+
+	switch state.Options.Backend {
+	case SwapFile:
+		path := swapFilePath(c.configPath(), c.name())
+		if err := createSwapFile(path, state.Options.Size); err != nil {
+			return restore, err
+		}
+
+		if err := swapon(path, state.Options.Priority); err != nil {
+			return restore, err
+		}
+	case SwapZram:
+		device, err := addZram(state.Options.Size)
+		if err != nil {
+			return restore, err
+		}
+
+		state.Device = device
+		if err := saveSwapState(c.configPath(), c.name(), state); err != nil {
+			removeZram(device)
+			return restore, err
+		}
+
+		rule, err := deviceRuleForNode(device)
+		if err != nil {
+			return restore, err
+		}
+
+		items = append(items,
+			KeyValue{devicesConfigKey("allow"), rule.String()},
+			KeyValue{"lxc.mount.entry", fmt.Sprintf("%s %s none bind,create=file 0 0", device, strings.TrimPrefix(device, "/"))},
+		)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return restore, err
+	}
+	items = append(items, KeyValue{"lxc.hook.post-stop", shellQuote([]string{exe, swapHelperArg, c.name(), c.configPath()})})
+
+	for _, kv := range items {
+		if err := c.setConfigItem(kv.Key, kv.Value); err != nil {
+			return restore, err
+		}
+	}
+	return restore, nil
+}
+
+// SetSwap provisions swap for the container from its next start on. The
+// swap is set up before the container starts and released after it
+// stopped, by the current executable running as post-stop hook. On cgroup1
+// hosts the container needs a memory limit, the swap can't be limited
+// otherwise.
+func (c *Container) SetSwap(opts SwapOptions) (err error) {
+	finish, err := c.operation("SetSwap")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	if opts.Backend.String() == "" {
+		return fmt.Errorf("%s: swap backend %d", ErrInvalidLimit, opts.Backend)
+	}
+
+	// a page for the header and at least one for swapping
+	if opts.Size < 2*ByteSize(os.Getpagesize()) {
+		return fmt.Errorf("%s: swap size %s", ErrInvalidLimit, opts.Size)
+	}
+
+	if opts.Priority > 0x7fff {
+		return fmt.Errorf("%s: swap priority %d", ErrInvalidLimit, opts.Priority)
+	}
+
+	if _, err := c.swapLimit(opts.Size); err != nil {
+		return err
+	}
+
+	state, _, err := loadSwapState(c.configPath(), c.name())
+	if err != nil {
+		return err
+	}
+
+	state.Options = opts
+	return saveSwapState(c.configPath(), c.name(), state)
+}
+
+// Swap returns the swap options of the container, ErrNoSwap if it has no
+// swap provisioned.
+func (c *Container) Swap() (SwapOptions, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return SwapOptions{}, err
+	}
+
+	state, ok, err := loadSwapState(c.configPath(), c.name())
+	if err != nil {
+		return SwapOptions{}, err
+	}
+	if !ok {
+		return SwapOptions{}, ErrNoSwap
+	}
+	return state.Options, nil
+}
+
+// RemoveSwap stops provisioning swap for the stopped container and removes
+// its swap file.
+func (c *Container) RemoveSwap() (err error) {
+	finish, err := c.operation("RemoveSwap")
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined | isNotRunning); err != nil {
+		return err
+	}
+
+	if err := releaseSwap(c.configPath(), c.name()); err != nil {
+		return err
+	}
+
+	for _, path := range []string{swapFilePath(c.configPath(), c.name()), swapStatePath(c.configPath(), c.name())} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
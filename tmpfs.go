@@ -0,0 +1,197 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tmpfsMountEntry returns the lxc.mount.entry mounting a tmpfs at the
+// absolute path in the container.
+func tmpfsMountEntry(path string, opts TmpfsOptions) (string, error) {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path || path == "/" || strings.ContainsAny(path, " \t\n\\") {
+		return "", fmt.Errorf("%s: path %q", ErrInvalidTmpfs, path)
+	}
+
+	if opts.Size < 0 || opts.Inodes < 0 {
+		return "", fmt.Errorf("%s: negative limit", ErrInvalidTmpfs)
+	}
+
+	if opts.Mode&^(os.ModePerm|os.ModeSticky) != 0 {
+		return "", fmt.Errorf("%s: mode %s", ErrInvalidTmpfs, opts.Mode)
+	}
+
+	options := []string{"rw"}
+	if opts.NoSuid {
+		options = append(options, "nosuid")
+	}
+	if opts.NoDev {
+		options = append(options, "nodev")
+	}
+	if opts.NoExec {
+		options = append(options, "noexec")
+	}
+	if opts.Size > 0 {
+		options = append(options, fmt.Sprintf("size=%d", int64(opts.Size)))
+	}
+	if opts.Inodes > 0 {
+		options = append(options, fmt.Sprintf("nr_inodes=%d", opts.Inodes))
+	}
+	if opts.Mode != 0 {
+		mode := opts.Mode.Perm()
+		if opts.Mode&os.ModeSticky != 0 {
+			mode |= 01000
+		}
+		options = append(options, fmt.Sprintf("mode=%o", mode))
+	}
+	options = append(options, "create=dir")
+
+	return fmt.Sprintf("tmpfs %s tmpfs %s 0 0", strings.TrimPrefix(path, "/"), strings.Join(options, ",")), nil
+}
+
+// parseTmpfsMountEntry returns the path and options of an lxc.mount.entry
+// mounting a tmpfs, false for other mounts.
+func parseTmpfsMountEntry(entry string) (string, TmpfsOptions, bool) {
+	var opts TmpfsOptions
+
+	fields := strings.Fields(entry)
+	if len(fields) < 4 || fields[2] != "tmpfs" {
+		return "", opts, false
+	}
+
+	for _, o := range strings.Split(fields[3], ",") {
+		parts := strings.SplitN(o, "=", 2)
+		switch parts[0] {
+		case "nosuid":
+			opts.NoSuid = true
+		case "nodev":
+			opts.NoDev = true
+		case "noexec":
+			opts.NoExec = true
+		}
+
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "size":
+			// sizes relative to the RAM are left at 0
+			opts.Size, _ = parseCgroupBytes(parts[1])
+		case "nr_inodes":
+			opts.Inodes, _ = strconv.ParseInt(parts[1], 10, 64)
+		case "mode":
+			if mode, err := strconv.ParseUint(parts[1], 8, 32); err == nil {
+				opts.Mode = os.FileMode(mode) & os.ModePerm
+				if mode&01000 != 0 {
+					opts.Mode |= os.ModeSticky
+				}
+			}
+		}
+	}
+	return "/" + strings.TrimPrefix(fields[1], "/"), opts, true
+}
+
+// removeTmpfs removes the tmpfs mounts at path.
+//
+// Caller needs to hold the lock
+func (c *Container) removeTmpfs(path string) error {
+	for _, entry := range c.configItem("lxc.mount.entry") {
+		if target, _, ok := parseTmpfsMountEntry(entry); ok && target == path {
+			if err := c.removeConfigValue("lxc.mount.entry", entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetTmpfs mounts a tmpfs with the options at the absolute path in the
+// container, replacing a tmpfs mounted there before.
+func (c *Container) SetTmpfs(path string, opts TmpfsOptions) (err error) {
+	finish, err := c.operation("SetTmpfs", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	entry, err := tmpfsMountEntry(path, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := c.removeTmpfs(path); err != nil {
+		return err
+	}
+	return c.setConfigItem("lxc.mount.entry", entry)
+}
+
+// RemoveTmpfs removes the tmpfs mounted at path in the container.
+func (c *Container) RemoveTmpfs(path string) (err error) {
+	finish, err := c.operation("RemoveTmpfs", path)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	return c.removeTmpfs(filepath.Clean(path))
+}
+
+// TmpfsMounts returns the options of the tmpfs mounts of the container by
+// path.
+func (c *Container) TmpfsMounts() (map[string]TmpfsOptions, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	mounts := make(map[string]TmpfsOptions)
+	for _, entry := range c.configItem("lxc.mount.entry") {
+		if path, opts, ok := parseTmpfsMountEntry(entry); ok {
+			mounts[path] = opts
+		}
+	}
+	return mounts, nil
+}
+
+// SetShmSize mounts a tmpfs of the size at /dev/shm, with the
+// DefaultShmOptions otherwise.
+func (c *Container) SetShmSize(size ByteSize) error {
+	opts := DefaultShmOptions
+	opts.Size = size
+
+	return c.SetTmpfs("/dev/shm", opts)
+}
+
+// SetTmpSize mounts a tmpfs of the size at /tmp, with the DefaultTmpOptions
+// otherwise.
+func (c *Container) SetTmpSize(size ByteSize) error {
+	opts := DefaultTmpOptions
+	opts.Size = size
+
+	return c.SetTmpfs("/tmp", opts)
+}
@@ -0,0 +1,221 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProcessInfo describes a process of a running container.
+type ProcessInfo struct {
+	// PID and PPID are the host process ids.
+	PID  int
+	PPID int
+	// Command is the name of the executable, truncated by the kernel to 15
+	// characters.
+	Command string
+	// State is the state as reported by ps, e.g. "R" or "S".
+	State string
+	// CPUTime is the CPU time used by the process.
+	CPUTime time.Duration
+	// CPUPercent is the usage of one CPU since the previous sample.
+	CPUPercent float64
+	// Memory is the resident memory of the process.
+	Memory ByteSize
+
+	// startTime tells apart processes reusing a pid
+	startTime uint64
+}
+
+// parseProcessStat parses the content of /proc/<pid>/stat.
+func parseProcessStat(stat string) (ProcessInfo, error) {
+	// the command may contain spaces and parentheses
+	lparen, rparen := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if lparen < 0 || rparen < lparen {
+		return ProcessInfo{}, fmt.Errorf("invalid stat %q", stat)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:lparen]))
+	if err != nil {
+		return ProcessInfo{}, fmt.Errorf("invalid stat %q", stat)
+	}
+
+	// the fields after the command start at the state, the third field
+	fields := strings.Fields(stat[rparen+1:])
+	if len(fields) < 22 {
+		return ProcessInfo{}, fmt.Errorf("invalid stat %q", stat)
+	}
+
+	var values [5]uint64
+	for i, field := range []int{1, 11, 12, 19, 21} {
+		if values[i], err = strconv.ParseUint(fields[field], 10, 64); err != nil {
+			return ProcessInfo{}, fmt.Errorf("invalid stat %q", stat)
+		}
+	}
+
+	return ProcessInfo{
+		PID:       pid,
+		PPID:      int(values[0]),
+		Command:   stat[lparen+1 : rparen],
+		State:     fields[0],
+		CPUTime:   time.Duration(values[1]+values[2]) * time.Second / userHZ,
+		Memory:    ByteSize(values[4] * uint64(unix.Getpagesize())),
+		startTime: values[3],
+	}, nil
+}
+
+// sortProcesses sorts the processes and drops the ones beyond the limit.
+func sortProcesses(processes []ProcessInfo, by TopSort, limit int) []ProcessInfo {
+	sort.SliceStable(processes, func(i, j int) bool {
+		a, b := processes[i], processes[j]
+		switch {
+		case by == TopSortCPU && a.CPUPercent != b.CPUPercent:
+			return a.CPUPercent > b.CPUPercent
+		case by == TopSortMemory && a.Memory != b.Memory:
+			return a.Memory > b.Memory
+		}
+		return a.PID < b.PID
+	})
+
+	if limit > 0 && len(processes) > limit {
+		processes = processes[:limit]
+	}
+	return processes
+}
+
+// processSampler computes the CPU usage of processes between samples.
+type processSampler struct {
+	last     map[int]ProcessInfo
+	lastTime time.Time
+}
+
+// update sets the CPU usage of the processes since the previous sample.
+func (s *processSampler) update(processes []ProcessInfo, now time.Time) {
+	elapsed := now.Sub(s.lastTime)
+
+	for i, p := range processes {
+		prev, ok := s.last[p.PID]
+		if ok && prev.startTime == p.startTime && elapsed > 0 {
+			processes[i].CPUPercent = float64(p.CPUTime-prev.CPUTime) / float64(elapsed) * 100
+		}
+	}
+
+	s.last = make(map[int]ProcessInfo, len(processes))
+	for _, p := range processes {
+		s.last[p.PID] = p
+	}
+	s.lastTime = now
+}
+
+// processes returns the processes in the pid namespace of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) processes() ([]ProcessInfo, error) {
+	pids, err := namespacePIDs(c.initPid())
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			// exited while scanning
+			continue
+		}
+
+		p, err := parseProcessStat(string(stat))
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+// Processes returns the processes of the running container sorted by pid.
+// CPUPercent isn't set, Top samples it.
+func (c *Container) Processes() ([]ProcessInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	processes, err := c.processes()
+	if err != nil {
+		return nil, err
+	}
+	return sortProcesses(processes, TopSortPID, 0), nil
+}
+
+// sample returns the processes of the container, nil once it stopped.
+func (c *Container) sample() ([]ProcessInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.running() {
+		return nil, nil
+	}
+	return c.processes()
+}
+
+// Top samples the processes of the running container every interval and
+// sends them, sorted and limited as requested, on the returned channel,
+// like top does. The channel is closed when ctx is done or the container
+// stops.
+func (c *Container) Top(ctx context.Context, opts TopOptions) (<-chan []ProcessInfo, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultTopOptions.Interval
+	}
+
+	processes, err := c.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := &processSampler{}
+	sampler.update(processes, time.Now())
+
+	ch := make(chan []ProcessInfo)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			processes, err := c.sample()
+			if err != nil || processes == nil {
+				return
+			}
+			sampler.update(processes, time.Now())
+
+			select {
+			case ch <- sortProcesses(processes, opts.Sort, opts.Limit):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,272 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NUMANode describes a NUMA node of the host.
+type NUMANode struct {
+	ID   int
+	CPUs []int
+	// Memory is the memory attached to the node.
+	Memory ByteSize
+	// Distances are the relative access costs from this node to each node,
+	// by node id.
+	Distances []int
+}
+
+// TopologyCPU describes an online CPU of the host.
+type TopologyCPU struct {
+	ID      int
+	Node    int
+	Package int
+	Core    int
+	// Siblings are the hardware threads sharing the core, including this
+	// CPU.
+	Siblings []int
+}
+
+// TopologyInfo describes the NUMA nodes and CPUs of the host.
+type TopologyInfo struct {
+	Nodes []NUMANode
+	CPUs  []TopologyCPU
+}
+
+// Node returns the NUMA node with the id.
+func (t TopologyInfo) Node(id int) (NUMANode, bool) {
+	for _, node := range t.Nodes {
+		if node.ID == id {
+			return node, true
+		}
+	}
+	return NUMANode{}, false
+}
+
+// formatCPUList returns the sorted CPUs as list of ranges, e.g. "0-3,8".
+func formatCPUList(cpus []int) string {
+	sorted := append([]int(nil), cpus...)
+	sort.Ints(sorted)
+
+	var ranges []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+
+		if sorted[i] == sorted[j] {
+			ranges = append(ranges, strconv.Itoa(sorted[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// readSysInt reads a file holding a single integer.
+func readSysInt(path string) (int, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
+
+// readSysCPUList reads a file holding a CPU list.
+func readSysCPUList(path string) ([]int, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return expandCPUList(string(content))
+}
+
+// parseNodeMeminfo returns the MemTotal of a node meminfo file, e.g.
+// "Node 0 MemTotal:        6158152 kB".
+func parseNodeMeminfo(content string) ByteSize {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "MemTotal:" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		if len(fields) == 5 && fields[4] == "kB" {
+			n *= 1024
+		}
+		return ByteSize(n)
+	}
+	return 0
+}
+
+// topology reads the topology below the sysfs mounted at sys.
+func topology(sys string) (TopologyInfo, error) {
+	var info TopologyInfo
+
+	online, err := readSysCPUList(filepath.Join(sys, "devices/system/cpu/online"))
+	if err != nil {
+		return info, fmt.Errorf("%s: %v", ErrHostResources, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(sys, "devices/system/node/node[0-9]*"))
+	if err != nil {
+		return info, err
+	}
+
+	nodeOf := make(map[int]int)
+	for _, match := range matches {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(match), "node"))
+		if err != nil {
+			continue
+		}
+
+		node := NUMANode{ID: id}
+		if node.CPUs, err = readSysCPUList(filepath.Join(match, "cpulist")); err != nil {
+			return info, fmt.Errorf("%s: %v", ErrHostResources, err)
+		}
+
+		if content, err := ioutil.ReadFile(filepath.Join(match, "meminfo")); err == nil {
+			node.Memory = parseNodeMeminfo(string(content))
+		}
+
+		if content, err := ioutil.ReadFile(filepath.Join(match, "distance")); err == nil {
+			for _, field := range strings.Fields(string(content)) {
+				if d, err := strconv.Atoi(field); err == nil {
+					node.Distances = append(node.Distances, d)
+				}
+			}
+		}
+
+		for _, cpu := range node.CPUs {
+			nodeOf[cpu] = id
+		}
+		info.Nodes = append(info.Nodes, node)
+	}
+	sort.Slice(info.Nodes, func(i, j int) bool { return info.Nodes[i].ID < info.Nodes[j].ID })
+
+	// kernels without NUMA support have a single implicit node
+	if len(info.Nodes) == 0 {
+		info.Nodes = []NUMANode{{ID: 0, CPUs: online, Distances: []int{10}}}
+	}
+
+	for _, id := range online {
+		cpu := TopologyCPU{ID: id, Node: nodeOf[id], Siblings: []int{id}}
+
+		dir := filepath.Join(sys, fmt.Sprintf("devices/system/cpu/cpu%d/topology", id))
+		if _, err := os.Stat(dir); err == nil {
+			if cpu.Package, err = readSysInt(filepath.Join(dir, "physical_package_id")); err != nil {
+				return info, fmt.Errorf("%s: %v", ErrHostResources, err)
+			}
+
+			if cpu.Core, err = readSysInt(filepath.Join(dir, "core_id")); err != nil {
+				return info, fmt.Errorf("%s: %v", ErrHostResources, err)
+			}
+
+			if siblings, err := readSysCPUList(filepath.Join(dir, "thread_siblings_list")); err == nil {
+				cpu.Siblings = siblings
+			}
+		}
+		info.CPUs = append(info.CPUs, cpu)
+	}
+	return info, nil
+}
+
+// Topology returns the NUMA nodes of the host and its online CPUs with
+// their hardware thread siblings.
+func Topology() (TopologyInfo, error) {
+	return topology("/sys")
+}
+
+// nodeCPUSet returns the cpuset.cpus and cpuset.mems confining a container
+// to the node, only its online CPUs are used.
+func nodeCPUSet(info TopologyInfo, id int) (string, string, error) {
+	node, ok := info.Node(id)
+	if !ok {
+		return "", "", fmt.Errorf("%s: %d", ErrNUMANodeNotFound, id)
+	}
+
+	var cpus []int
+	for _, cpu := range info.CPUs {
+		if cpu.Node == node.ID {
+			cpus = append(cpus, cpu.ID)
+		}
+	}
+
+	if len(cpus) == 0 {
+		return "", "", fmt.Errorf("%s: node %d has no online CPUs", ErrNUMANodeNotFound, id)
+	}
+	return formatCPUList(cpus), strconv.Itoa(node.ID), nil
+}
+
+// PinToNode confines the container to the CPUs and the memory of the NUMA
+// node, setting cpuset.cpus and cpuset.mems together so that it never runs
+// on CPUs of one node with memory of another. The config is updated, and
+// the cgroup too if the container is running.
+func (c *Container) PinToNode(node int) (err error) {
+	finish, err := c.operation("PinToNode", strconv.Itoa(node))
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	info, err := Topology()
+	if err != nil {
+		return err
+	}
+
+	cpus, mems, err := nodeCPUSet(info, node)
+	if err != nil {
+		return err
+	}
+
+	prefix := "lxc.cgroup."
+	if CgroupUnified() {
+		prefix = "lxc.cgroup2."
+	}
+
+	items := []KeyValue{{"cpuset.cpus", cpus}, {"cpuset.mems", mems}}
+	for _, kv := range items {
+		if err := c.clearConfigItem(prefix + kv.Key); err != nil {
+			return err
+		}
+
+		if err := c.setConfigItem(prefix+kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s = %s", err, prefix+kv.Key, kv.Value)
+		}
+	}
+
+	if !c.running() {
+		return nil
+	}
+
+	for _, kv := range items {
+		if err := c.setCgroupItem(kv.Key, kv.Value); err != nil {
+			return fmt.Errorf("%s: %s", err, kv.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignPayloadMediaType    = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+// cosignPayload is the simple signing payload signed by cosign.
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// parsePublicKey parses a PEM encoded public key.
+func parsePublicKey(key []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, fmt.Errorf("%s: %s", ErrVerificationFailed, "invalid public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ErrVerificationFailed, err)
+	}
+	return pub, nil
+}
+
+// verifySignature verifies the signature of payload with the given key.
+func verifySignature(key crypto.PublicKey, payload []byte, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	}
+	return false
+}
+
+// verifyCosignPayload checks that a signed payload refers to digest.
+func verifyCosignPayload(key crypto.PublicKey, payload []byte, signature string, digest string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !verifySignature(key, payload, sig) {
+		return false
+	}
+
+	var p cosignPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return false
+	}
+	return p.Critical.Image.DockerManifestDigest == digest
+}
+
+// verifyCosign verifies that the manifest with the given digest was signed
+// with key, looking the signatures up under the "sha256-<hex>.sig" tag as
+// cosign stores them.
+func (r *registryClient) verifyCosign(key crypto.PublicKey, digest string, dir string) error {
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+
+	content, _, err := r.rawManifest(tag)
+	if err != nil {
+		return fmt.Errorf("%s: no signature for %q: %v", ErrVerificationFailed, digest, err)
+	}
+
+	var m ociManifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return fmt.Errorf("%s: %v", ErrVerificationFailed, err)
+	}
+
+	for _, layer := range m.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok || layer.MediaType != cosignPayloadMediaType {
+			continue
+		}
+
+		path, err := r.blob(layer, dir)
+		if err != nil {
+			return err
+		}
+
+		payload, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if verifyCosignPayload(key, payload, signature, digest) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: no valid signature for %q", ErrVerificationFailed, digest)
+}
@@ -0,0 +1,275 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// TTY describes a tty of a running container.
+type TTY struct {
+	// Num is the number of the tty, starting at 1, as used by ConsoleFd.
+	Num int
+	// Path is the device node in the container, e.g. /dev/tty1.
+	Path string
+	// PIDs are the host pids of the container processes which have the
+	// tty open, e.g. a getty.
+	PIDs []int
+}
+
+// InUse returns whether a process of the container has the tty open.
+func (t TTY) InUse() bool {
+	return len(t.PIDs) > 0
+}
+
+// ConsoleTTY is a console tty of a running container allocated by
+// AllocateConsole.
+type ConsoleTTY struct {
+	// Num is the number of the allocated tty, 0 for the console.
+	Num int
+	// Fd is the master side of the tty, reading and writing it interacts
+	// with the console.
+	Fd int
+
+	// ttyFd keeps the tty allocated
+	ttyFd int
+}
+
+// Resize sets the window size of the tty, the processes running on it get
+// a SIGWINCH.
+func (t *ConsoleTTY) Resize(rows int, cols int) error {
+	if rows <= 0 || cols <= 0 || rows > 0xffff || cols > 0xffff {
+		return fmt.Errorf("%s: %dx%d", unix.EINVAL, rows, cols)
+	}
+
+	return unix.IoctlSetWinsize(t.Fd, unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(rows), Col: uint16(cols)})
+}
+
+// Close closes the tty so that it can be allocated by another caller.
+func (t *ConsoleTTY) Close() error {
+	err := unix.Close(t.Fd)
+	if cerr := unix.Close(t.ttyFd); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ttyConfigKeys returns the keys of the tty and pty counts.
+func ttyConfigKeys() (string, string) {
+	if !VersionAtLeast(2, 1, 0) {
+		return "lxc.tty", "lxc.pts"
+	}
+	return "lxc.tty.max", "lxc.pty.max"
+}
+
+// ttyPath returns the device node of tty num in the container, liblxc
+// creates it in lxc.tty.dir if set.
+func ttyPath(dir string, num int) string {
+	return path.Join("/dev", dir, fmt.Sprintf("tty%d", num))
+}
+
+// Caller needs to hold the lock
+func (c *Container) configInt(key string) (int, error) {
+	values := c.configItem(key)
+	if len(values) == 0 || values[0] == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(values[0])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s = %q", err, key, values[0])
+	}
+	return n, nil
+}
+
+// Caller needs to hold the lock
+func (c *Container) setConfigCount(key string, n int) error {
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if n < 0 {
+		return fmt.Errorf("%s: %s = %d", ErrSettingConfigItemFailed, key, n)
+	}
+
+	if err := c.clearConfigItem(key); err != nil {
+		return err
+	}
+	return c.setConfigItem(key, strconv.Itoa(n))
+}
+
+// TTYMax returns the number of ttys allocated for the container
+// (lxc.tty.max), 0 for headless containers.
+func (c *Container) TTYMax() (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return 0, ErrNotDefined
+	}
+
+	key, _ := ttyConfigKeys()
+	return c.configInt(key)
+}
+
+// SetTTYMax sets the number of ttys allocated for the container
+// (lxc.tty.max), 0 makes it headless. It applies on the next start.
+func (c *Container) SetTTYMax(n int) (err error) {
+	finish, err := c.operation("SetTTYMax", strconv.Itoa(n))
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, _ := ttyConfigKeys()
+	return c.setConfigCount(key, n)
+}
+
+// PTYMax returns the maximum number of ptys the container may allocate
+// (lxc.pty.max), 0 if unlimited.
+func (c *Container) PTYMax() (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return 0, ErrNotDefined
+	}
+
+	_, key := ttyConfigKeys()
+	return c.configInt(key)
+}
+
+// SetPTYMax sets the maximum number of ptys the container may allocate
+// (lxc.pty.max), 0 for unlimited. It applies on the next start.
+func (c *Container) SetPTYMax(n int) (err error) {
+	finish, err := c.operation("SetPTYMax", strconv.Itoa(n))
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, key := ttyConfigKeys()
+	return c.setConfigCount(key, n)
+}
+
+// namespacePIDs returns the pids of the processes in the pid namespace of
+// pid, including pid itself.
+func namespacePIDs(pid int) ([]int, error) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		// processes exit while scanning
+		if link, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", p)); err == nil && link == ns {
+			pids = append(pids, p)
+		}
+	}
+	return pids, nil
+}
+
+// openFiles returns the paths of the files pid has open, relative to its
+// root.
+func openFiles(pid int) []string {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if link, err := os.Readlink(filepath.Join(dir, entry.Name())); err == nil {
+			files = append(files, link)
+		}
+	}
+	return files
+}
+
+// TTYs reports the ttys of the running container and the processes using
+// them, e.g. to pick a free tty for ConsoleFd.
+func (c *Container) TTYs() ([]TTY, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return nil, err
+	}
+
+	key, _ := ttyConfigKeys()
+	max, err := c.configInt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := ""
+	if values := c.configItem("lxc.tty.dir"); len(values) > 0 {
+		dir = values[0]
+	}
+
+	ttys := make([]TTY, max)
+	byPath := make(map[string]*TTY)
+	for i := range ttys {
+		ttys[i] = TTY{Num: i + 1, Path: ttyPath(dir, i+1)}
+		byPath[ttys[i].Path] = &ttys[i]
+
+		// the node in lxc.tty.dir is linked to from /dev
+		if dir != "" {
+			byPath[ttyPath("", i+1)] = &ttys[i]
+		}
+	}
+
+	if max == 0 {
+		return ttys, nil
+	}
+
+	pids, err := namespacePIDs(c.initPid())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pid := range pids {
+		for _, file := range openFiles(pid) {
+			tty, ok := byPath[strings.TrimSuffix(file, " (deleted)")]
+			if !ok {
+				continue
+			}
+
+			if n := len(tty.PIDs); n == 0 || tty.PIDs[n-1] != pid {
+				tty.PIDs = append(tty.PIDs, pid)
+			}
+		}
+	}
+	return ttys, nil
+}
@@ -150,6 +150,35 @@ func (t State) String() string {
 	return ""
 }
 
+// stateTransitions are the states liblxc moves a container to from a state.
+var stateTransitions = map[State][]State{
+	STOPPED:  {STARTING},
+	STARTING: {RUNNING, STOPPING, ABORTING},
+	RUNNING:  {STOPPING, FREEZING},
+	STOPPING: {STOPPED},
+	ABORTING: {STOPPING},
+	FREEZING: {FROZEN, RUNNING},
+	FROZEN:   {THAWED, STOPPING},
+	THAWED:   {RUNNING},
+}
+
+// CanTransitionTo returns true if a container can move from the state to the
+// given state in a single step.
+func (t State) CanTransitionTo(state State) bool {
+	for _, s := range stateTransitions[t] {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Stable returns true if the container stays in the state until an
+// operation changes it, i.e. for STOPPED, RUNNING and FROZEN.
+func (t State) Stable() bool {
+	return t == STOPPED || t == RUNNING || t == FROZEN
+}
+
 // Taken from http://golang.org/doc/effective_go.html#constants
 
 // ByteSize type
@@ -373,3 +402,53 @@ const (
 	// FEATURE_LAZY_PAGES - lazy pages support
 	FEATURE_LAZY_PAGES
 )
+
+// FreezeMethod type specifies how a container was frozen.
+type FreezeMethod int
+
+const (
+	// FreezeNone means the container wasn't frozen through this Container
+	FreezeNone FreezeMethod = iota
+	// FreezeLiblxc means the container was frozen by liblxc
+	FreezeLiblxc
+	// FreezeCgroup means the container was frozen by writing cgroup.freeze
+	FreezeCgroup
+)
+
+// FreezeMethod as string
+func (m FreezeMethod) String() string {
+	switch m {
+	case FreezeNone:
+		return "none"
+	case FreezeLiblxc:
+		return "liblxc"
+	case FreezeCgroup:
+		return "cgroup"
+	}
+	return ""
+}
+
+// Severity type specifies how severe a finding of a security audit is.
+type Severity int
+
+const (
+	// SeverityInfo is a hardening hint
+	SeverityInfo Severity = iota
+	// SeverityWarning weakens the isolation of the container
+	SeverityWarning
+	// SeverityCritical lets the container escape or take over the host
+	SeverityCritical
+)
+
+// Severity as string
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return ""
+}
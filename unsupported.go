@@ -0,0 +1,5384 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// Code generated by mkunsupported.go; DO NOT EDIT.
+
+//go:build !linux || !cgo
+// +build !linux !cgo
+
+package lxc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// STOPPED means container is not running
+	STOPPED State = iota + 1
+	// STARTING means container is starting
+	STARTING
+	// RUNNING means container is running
+	RUNNING
+	// STOPPING means container is stopping
+	STOPPING
+	// ABORTING means container is aborting
+	ABORTING
+	// FREEZING means container is freezing
+	FREEZING
+	// FROZEN means containe is frozen
+	FROZEN
+	// THAWED means container is thawed
+	THAWED
+)
+
+const (
+	// TRACE priority
+	TRACE LogLevel = iota
+	// DEBUG priority
+	DEBUG
+	// INFO priority
+	INFO
+	// NOTICE priority
+	NOTICE
+	// WARN priority
+	WARN
+	// ERROR priority
+	ERROR
+	// CRIT priority
+	CRIT
+	// ALERT priority
+	ALERT
+	// FATAL priority
+	FATAL
+)
+
+// Acquire increments the reference counter of the container object.
+func Acquire(c *Container) (_ bool) {
+	return
+}
+
+// Activator listens on a host socket for a container and starts it on the
+// first connection, proxying the connections to the service inside once it
+// accepts them. Together with WatchIdle shutting idle containers down it
+// scales LXC services to zero.
+type Activator struct {
+}
+
+// Addr returns the address of the host socket.
+func (a *Activator) Addr() (_ net.Addr) {
+	return
+}
+
+// Serve accepts connections until the activator is closed, starting the
+// container on the first one. Connections waiting longer than the timeout
+// of the options for the service are closed.
+func (a *Activator) Serve() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Close stops listening, the connections being proxied keep going until
+// they are closed. Wait waits for them.
+func (a *Activator) Close() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Wait waits until the connections being proxied are closed.
+func (a *Activator) Wait() {
+	return
+}
+
+// ActivatorOptions type is used for defining the socket of an Activator and
+// the service it activates.
+type ActivatorOptions struct {
+	// Network and Address specify the host socket, as passed to
+	// net.Listen, e.g. "tcp" and ":8080".
+	Network string
+	Address string
+	// Target specifies the address of the service, e.g. "10.0.3.5:80".
+	// The first address of the container with TargetPort is used if
+	// empty.
+	Target     string
+	TargetPort uint16
+	// Timeout specifies how long a connection waits for the container to
+	// start and the service to accept connections.
+	Timeout time.Duration
+}
+
+// ActiveContainerNames returns the names of the active containers on the system.
+func ActiveContainerNames(lxcpath ...string) (_ []string) {
+	return
+}
+
+// ActiveContainerNamesE returns the names of the active containers on the
+// system. Unlike ActiveContainerNames it reports errors.
+func ActiveContainerNamesE(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ActiveContainers returns the active containers on the system. Only
+// containers that could retrieved successfully are returned.
+// Caller needs to call Release() on the returned containers to release resources.
+func ActiveContainers(lxcpath ...string) (_ []*Container) {
+	return
+}
+
+// ActiveContainersE returns the active containers on the system. Unlike
+// ActiveContainers it reports errors.
+// Caller needs to call Release() on the returned containers to release resources.
+func ActiveContainersE(lxcpath ...string) (_ []*Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AdmissionOptions type is used for defining the capacity of the host containers are admitted to.
+type AdmissionOptions struct {
+	// MemoryOvercommit scales the memory of the host available to memory limits.
+	MemoryOvercommit float64
+	// CPUOvercommit scales the CPUs of the host available to CPU limits.
+	CPUOvercommit float64
+	// MinFreeDisk is the disk space which has to be left in the lxcpath.
+	MinFreeDisk ByteSize
+}
+
+// AllSnapshots returns the snapshots of all containers in the given lxcpath,
+// so backup tools can inventory an entire host in one call.
+func AllSnapshots(lxcpath ...string) (_ []ContainerSnapshot, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ApplicationOptions type is used for defining the optional settings of
+// NewApplicationContainer.
+type ApplicationOptions struct {
+	// LXCPath is the directory the container is created in, the default
+	// lxcpath if empty.
+	LXCPath string
+	// UID and GID are the user and group ids the command runs as.
+	UID int
+	GID int
+	// Cwd is the working directory of the command, / if empty.
+	Cwd string
+	// Env are the environment variables of the command as KEY=VALUE.
+	Env []string
+	// Bridge connects the container to the bridge through a veth pair, the
+	// container only has a loopback interface if empty.
+	Bridge string
+	// ReadOnly mounts the rootfs read-only.
+	ReadOnly bool
+	// Mounts are additional fstab-like mount entries (lxc.mount.entry),
+	// with the target relative to the rootfs.
+	Mounts []string
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// ApplyLayers extracts the given image layers (tar archives, optionally gzip
+// compressed) into rootfs in order, honoring AUFS/OCI whiteouts: ".wh.<name>"
+// deletes <name> from the lower layers and ".wh..wh..opq" hides the lower
+// layer content of its directory. Paths can't escape rootfs, even through
+// symlinks created by the layers.
+func ApplyLayers(rootfs string, layers []io.Reader) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachOptions type is used for defining various attach options.
+type AttachOptions struct {
+	// Specify the namespaces to attach to, as OR'ed list of clone flags (syscall.CLONE_NEWNS | syscall.CLONE_NEWUTS ...).
+	Namespaces int
+	// Specify the architecture which the kernel should appear to be running as to the command executed.
+	Arch Personality
+	// Cwd specifies the working directory of the command.
+	Cwd string
+	// UID specifies the user id to run as.
+	UID int
+	// GID specifies the group id to run as.
+	GID int
+	// User specifies the user to run as by name, e.g. "www-data", or as
+	// "user:group". It is looked up in the container and overrides UID and
+	// GID. Groups defaults to the groups the user is a member of, Cwd to
+	// the home directory of the user if empty.
+	User string
+	// Groups specifies the list of additional group ids to run with.
+	Groups []int
+	// If ClearEnv is true the environment is cleared before running the command.
+	ClearEnv bool
+	// Env specifies the environment of the process.
+	Env []string
+	// EnvToKeep specifies the environment of the process when ClearEnv is true.
+	EnvToKeep []string
+	// EnvToKeepGlob adds the variables of the calling process matching any
+	// of the glob patterns, e.g. "LC_*", to EnvToKeep.
+	EnvToKeepGlob []string
+	// Path specifies the PATH of the process unless Env sets one, e.g.
+	// DefaultAttachPath. The PATH of the calling process is kept if empty,
+	// which often doesn't match the layout inside the container.
+	Path string
+	// StdinFd specifies the fd to read input from.
+	StdinFd uintptr
+	// StdoutFd specifies the fd to write output to.
+	StdoutFd uintptr
+	// StderrFd specifies the fd to write error output to.
+	StderrFd uintptr
+	// RemountSysProc remounts /sys and /proc for the executed command.
+	// This is required to reflect the container (PID) namespace context
+	// if the command does not attach to the container's mount namespace.
+	RemountSysProc bool
+	// Rlimits are the resource limits of the command. They are applied by
+	// the calling process before the command switches to UID and GID, so
+	// hard limits can be raised as far as the calling process may raise
+	// them.
+	Rlimits []Rlimit
+	// Umask specifies the umask of the command, e.g. 0027. The umask of the
+	// calling process is kept if nil.
+	Umask *int
+	// KeepCaps keeps the capabilities dropped through lxc.cap.drop in the
+	// bounding set of the command, without the other effects of
+	// ElevatedPrivileges.
+	KeepCaps bool
+	// NoNewPrivs sets no_new_privs for the command, so it can't gain
+	// privileges through setuid binaries or file capabilities. It is
+	// always set if the container sets lxc.no_new_privs.
+	NoNewPrivs bool
+	// AppArmorProfile runs the command under the given AppArmor profile
+	// instead of the one of the container, e.g. "unconfined". Needs liblxc
+	// 4.0.
+	AppArmorProfile string
+	// SELinuxLabel runs the command under the given SELinux context instead
+	// of the one of the container. Needs liblxc 4.0.
+	SELinuxLabel string
+	// Recorder records the session of AttachShell, which then runs on a
+	// pty of its own whose input and output are passed on to StdinFd and
+	// StdoutFd.
+	Recorder *SessionRecorder
+	// CloseInheritedFds marks the fds of the calling process which would be
+	// inherited by the command close-on-exec before attaching, e.g. the
+	// log file liblxc opened. Stdin, stdout and stderr are kept. The flag
+	// stays set in the calling process, see InheritableFds.
+	CloseInheritedFds bool
+	// ElevatedPrivileges runs the command with elevated privileges.
+	// The capabilities, cgroup and security module restrictions of the container are not applied.
+	// WARNING: This may leak privileges into the container.
+	ElevatedPrivileges bool
+}
+
+// AttachedProcess is a command started by RunCommandAsync.
+type AttachedProcess struct {
+	// Pid is the process ID of the attached command seen from outside the container.
+	Pid int
+}
+
+// Wait waits for the command to exit and returns its exit status. A command
+// terminated by a signal reports an exit status of -1. Wait may be called
+// several times and from several goroutines.
+func (p *AttachedProcess) Wait() (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Signal sends a signal to the command.
+func (p *AttachedProcess) Signal(sig syscall.Signal) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AuditFile is an AuditSink appending JSON lines to a file.
+type AuditFile struct {
+}
+
+// Record appends the record and syncs it to disk.
+func (a *AuditFile) Record(record AuditRecord) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Close closes the file.
+func (a *AuditFile) Close() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AuditFunc is an AuditSink calling the function for each record.
+type AuditFunc func(record AuditRecord) error
+
+// Record calls f(record).
+func (f AuditFunc) Record(record AuditRecord) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AuditRecord describes a mutating operation on a container.
+type AuditRecord struct {
+	Time      time.Time     `json:"time"`
+	Operation string        `json:"operation"`
+	Container string        `json:"container"`
+	LXCPath   string        `json:"lxcpath"`
+	Args      []string      `json:"args,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	// Caller metadata: the calling process and the file:line the
+	// operation was invoked from.
+	UID    int    `json:"uid"`
+	PID    int    `json:"pid"`
+	Caller string `json:"caller,omitempty"`
+}
+
+// AuditSink receives the records of the audit log.
+type AuditSink interface {
+	Record(record AuditRecord) error
+}
+
+const (
+	// Btrfs backendstore type
+	Btrfs BackendStore = iota + 1
+	// Directory backendstore type
+	Directory
+	// LVM backendstore type
+	LVM
+	// ZFS backendstore type
+	ZFS
+	// Aufs backendstore type
+	Aufs
+	// Overlayfs backendstore type
+	Overlayfs
+	// Loopback backendstore type
+	Loopback
+	// Best backendstore type
+	Best
+)
+
+const (
+	// B - byte
+	B = iota
+
+	// KB - kilobyte
+	KB ByteSize = 1 << (10 * iota)
+
+	// MB - megabyte
+	MB
+
+	// GB - gigabyte
+	GB
+
+	// TB - terabyte
+	TB
+
+	// PB - petabyte
+	PB
+
+	// EB - exabyte
+	EB
+
+	// ZB - zettabyte
+	ZB
+
+	// YB - yottabyte
+	YB
+)
+
+// BackendStore type specifies possible backend types.
+type BackendStore int
+
+// BackendStore as string
+func (t BackendStore) String() (_ string) {
+	return
+}
+
+// Set is the method to set the flag value, part of the flag.Value interface.
+func (t *BackendStore) Set(value string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// BackendStoreSpecs represents a LXC storage backend.
+type BackendStoreSpecs struct {
+	FSType string
+	FSSize uint64
+	Dir    *string
+	ZFS    struct {
+		Root string
+	}
+	LVM struct {
+		VG, LV, Thinpool string
+	}
+	RBD struct {
+		Name, Pool string
+	}
+}
+
+// BusyboxTemplateOptions is a convenient set of options for "busybox" template.
+var BusyboxTemplateOptions = TemplateOptions{
+	Template: "busybox",
+}
+
+// ByteSize type
+type ByteSize float64
+
+func (b ByteSize) String() (_ string) {
+	return
+}
+
+// CPUStats is the CPU usage of a container.
+type CPUStats struct {
+	// Usage is the CPU time used.
+	Usage time.Duration
+	// User and System are the CPU time used in user and kernel mode.
+	User   time.Duration
+	System time.Duration
+}
+
+// CgroupPlacement type is used for defining where the cgroups of the
+// container are created, relative to the cgroup liblxc is configured to use
+// (lxc.cgroup.pattern) or, with Relative, to the cgroup of the caller.
+type CgroupPlacement struct {
+	// Dir is the cgroup of the container and its monitor (lxc.cgroup.dir).
+	Dir string
+	// MonitorDir is the cgroup of the monitor (lxc.cgroup.dir.monitor),
+	// needs ContainerDir and liblxc 4.0.
+	MonitorDir string
+	// ContainerDir is the cgroup of the container (lxc.cgroup.dir.container),
+	// needs MonitorDir and liblxc 4.0.
+	ContainerDir string
+	// ContainerInner is the cgroup inside ContainerDir the payload is moved
+	// into (lxc.cgroup.dir.container.inner), e.g. so it can delegate the
+	// controllers of ContainerDir. Needs liblxc 4.0.
+	ContainerInner string
+	// Relative places the cgroups relative to the cgroup of the process
+	// starting the container (lxc.cgroup.relative), e.g. inside the
+	// delegated cgroup of a systemd user session. Needs liblxc 4.0.
+	Relative bool
+}
+
+// CgroupScopeOptions type is used for defining the systemd unit the container's cgroup is placed in.
+type CgroupScopeOptions struct {
+	// Slice is the systemd slice the scope is created in (e.g. "machine.slice").
+	Slice string
+	// Scope is the name of the scope unit (default: "lxc-<name>.scope").
+	Scope string
+}
+
+// CgroupUnified returns true if the host runs the pure cgroup v2 (unified)
+// hierarchy.
+func CgroupUnified() (_ bool) {
+	return
+}
+
+// CheckRuntime verifies the host provides what liblxc needs at runtime: the
+// lxcpath and rootfs mount point, a cgroup hierarchy and, if liblxc is
+// statically linked, users and groups which can be looked up without NSS
+// modules. It's meant for single binary deployments to minimal hosts.
+func CheckRuntime() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CheckpointOptions type is used for defining checkpoint options for CRIU.
+type CheckpointOptions struct {
+	Directory string
+	Stop      bool
+	Verbose   bool
+}
+
+// CloneOptions type is used for defining various clone options.
+type CloneOptions struct {
+	// Backend specifies the type of the backend.
+	Backend BackendStore
+	// lxcpath in which to create the new container. If not set the original container's lxcpath will be used.
+	ConfigPath string
+	// Do not change the hostname of the container (in the root filesystem).
+	KeepName bool
+	// Use the same MAC address as the original container, rather than generating a new random one.
+	KeepMAC bool
+	// Create a snapshot rather than copy.
+	Snapshot bool
+	// Derive the MAC addresses from the name of the new container instead of
+	// generating random ones. Ignored if KeepMAC is set.
+	RegenerateMAC bool
+	// Set lxc.uts.name and the hostname in /etc/hostname and /etc/hosts of the
+	// new root filesystem to the name of the new container. Ignored if
+	// KeepName is set.
+	UpdateHostname bool
+	// PostClone is called with the new container before it is first started,
+	// its root filesystem is accessible at MountedRootfs. Changes to the
+	// configuration are saved. The clone is destroyed if it returns an error.
+	PostClone func(c *Container) error
+}
+
+// CompareConfigs returns the differences between the configurations of the
+// given containers, as if a was changed into b.
+func CompareConfigs(a, b *Container) (_ ConfigDiff, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CompileOCISeccomp translates an OCI seccomp profile, either the seccomp
+// section of a runtime spec or a Docker profile, into a liblxc seccomp v2
+// policy. Rules of Docker profiles conditional on capabilities are left
+// out, the ones conditional on architectures only apply on matching hosts.
+func CompileOCISeccomp(profile []byte) (_ string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConfigChange represents a config item whose value differs between two
+// containers.
+type ConfigChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// ConfigDiff represents the differences between two container configurations.
+type ConfigDiff struct {
+	// Added holds the items only found in the second configuration.
+	Added []KeyValue
+	// Removed holds the items only found in the first configuration.
+	Removed []KeyValue
+	// Changed holds the single valued items found in both configurations
+	// with different values.
+	Changed []ConfigChange
+}
+
+// Empty returns true if the configurations are identical.
+func (d ConfigDiff) Empty() (_ bool) {
+	return
+}
+
+// ConfigOrigin is a config item along with the file it was read from.
+type ConfigOrigin struct {
+	KeyValue
+	// File is the included file the item comes from, empty for items of
+	// the configuration of the container itself.
+	File string
+}
+
+// ConfigTransaction collects config changes of a container to apply them
+// together, see Container.Begin.
+type ConfigTransaction struct {
+}
+
+// Set sets the value of the given config item on Commit.
+func (tx *ConfigTransaction) Set(key string, value string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Clear clears the value of the given config item on Commit.
+func (tx *ConfigTransaction) Clear(key string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Rollback discards the changes of the transaction.
+func (tx *ConfigTransaction) Rollback() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Commit applies the changes of the transaction in order. Keys unknown to
+// liblxc are refused before changing anything, and if a change fails the
+// configuration of the container is restored as it was before, so that it
+// is left untouched.
+func (tx *ConfigTransaction) Commit() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConsoleLogOptions type is used for defining console log options.
+type ConsoleLogOptions struct {
+	ClearLog       bool
+	ReadLog        bool
+	ReadMax        uint64
+	WriteToLogFile bool
+}
+
+// ConsoleOptions type is used for defining various console options.
+type ConsoleOptions struct {
+	// Tty number to attempt to allocate, -1 to allocate the first available tty, or 0 to allocate the console.
+	Tty int
+	// StdinFd specifies the fd to read input from.
+	StdinFd uintptr
+	// StdoutFd specifies the fd to write output to.
+	StdoutFd uintptr
+	// StderrFd specifies the fd to write error output to.
+	StderrFd uintptr
+	// EscapeCharacter (a means <Ctrl a>, b maens <Ctrl b>).
+	EscapeCharacter rune
+	// Recorder records the session, which then runs on a pty of its own
+	// whose input and output are passed on to StdinFd and StdoutFd.
+	Recorder *SessionRecorder
+}
+
+// ConsoleTTY is a console tty of a running container allocated by
+// AllocateConsole.
+type ConsoleTTY struct {
+	// Num is the number of the allocated tty, 0 for the console.
+	Num int
+	// Fd is the master side of the tty, reading and writing it interacts
+	// with the console.
+	Fd int
+}
+
+// Resize sets the window size of the tty, the processes running on it get
+// a SIGWINCH.
+func (t *ConsoleTTY) Resize(rows int, cols int) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Close closes the tty so that it can be allocated by another caller.
+func (t *ConsoleTTY) Close() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Container struct
+type Container struct {
+}
+
+// SwapUsage returns the swap usage of the container in bytes.
+func (c *Container) SwapUsage() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SwapLimit returns the swap limit of the container in bytes.
+func (c *Container) SwapLimit() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetSwapLimit sets the swap limit of the container in bytes. On cgroup v1
+// hosts it sets memory.memsw.limit_in_bytes to the memory limit plus limit.
+func (c *Container) SetSwapLimit(limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// HugeTLBUsage returns the huge page usage of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) HugeTLBUsage(pageSize ByteSize) (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// HugeTLBLimit returns the huge page limit of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) HugeTLBLimit(pageSize ByteSize) (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetHugeTLBLimit sets the huge page limit of the container in bytes for the
+// given huge page size (e.g. 2 * MB).
+func (c *Container) SetHugeTLBLimit(pageSize ByteSize, limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RDMAUsage returns the RDMA resources currently used by the container, per
+// device.
+func (c *Container) RDMAUsage() (_ []RDMAResources, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RDMALimits returns the RDMA resource limits of the container, per device.
+func (c *Container) RDMALimits() (_ []RDMAResources, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetRDMALimit sets the RDMA resource limits of the container for a single
+// device.
+func (c *Container) SetRDMALimit(limit RDMAResources) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// PressureStats returns the pressure stall information (PSI) of the CPU,
+// memory and IO of the container, as found in its cgroup. Needs cgroup v2
+// and a kernel with PSI enabled.
+func (c *Container) PressureStats() (_ PressureStats, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetCgroupScope places the cgroup of the container inside the given systemd
+// slice (through lxc.cgroup.dir), so containers started by a daemon don't end
+// up in the daemon's own cgroup. The cgroup is created directly on the
+// cgroupfs by liblxc when the container starts.
+func (c *Container) SetCgroupScope(opts CgroupScopeOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartInScope starts the container with its cgroup placed in the given
+// systemd slice and scope. See SetCgroupScope.
+func (c *Container) StartInScope(opts CgroupScopeOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CgroupPlacement returns the cgroup placement found in the configuration of
+// the container.
+func (c *Container) CgroupPlacement() (_ CgroupPlacement, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetCgroupPlacement replaces the cgroup placement in the configuration of
+// the container, it applies on the next start. The dirs are relative paths,
+// the monitor and container dirs are set together or not at all.
+func (c *Container) SetCgroupPlacement(p CgroupPlacement) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// MountedRootfs returns the path the root filesystem is accessible at on the
+// host while a CloneOptions.PostClone callback runs, an empty string
+// otherwise.
+func (c *Container) MountedRootfs() (_ string) {
+	return
+}
+
+// DumpConfig returns the complete configuration of the container, in the
+// order of the keys reported by liblxc. Network devices are expanded into
+// their indexed subkeys (lxc.net.0.type, lxc.net.0.link, ...).
+func (c *Container) DumpConfig() (_ []KeyValue, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DetectDrift returns the differences between the golden config file,
+// saved with SaveConfigFile when the container was provisioned, and the
+// current configuration, as if the golden one was changed into it.
+// Volatile keys like generated MACs are ignored, includes are compared as
+// lxc.include items.
+func (c *Container) DetectDrift(goldenPath string) (_ ConfigDiff, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Begin starts a config transaction. Its changes are only applied to the
+// container by Commit, either all of them or none.
+func (c *Container) Begin() (_ *ConfigTransaction) {
+	return
+}
+
+// SetConfigItems sets the values of the given config items under a single
+// lock, so that concurrent callers never observe or cause a partially
+// applied set. The keys are checked up front and applied in sorted order;
+// if one fails the configuration is left untouched.
+func (c *Container) SetConfigItems(items map[string]string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// FollowConsole streams the console output of the container to w until ctx
+// is done, like lxc-console --show-log with follow. It keeps following
+// across restarts of the container and waits for it to start if it's
+// stopped.
+//
+// The output is read from the console ring buffer, which needs
+// lxc.console.buffer.size to be set, and is cleared from it once written
+// to w. Output written right before the container stops may be lost with
+// the buffer.
+func (c *Container) FollowConsole(ctx context.Context, w io.Writer) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Release decrements the reference counter of the container object.
+// nil on success or if reference was successfully dropped and container has been freed, and ErrReleaseFailed on error.
+func (c *Container) Release() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Name returns the name of the container.
+func (c *Container) Name() (_ string) {
+	return
+}
+
+// String returns the string representation of container.
+func (c *Container) String() (_ string) {
+	return
+}
+
+// Defined returns true if the container is already defined.
+func (c *Container) Defined() (_ bool) {
+	return
+}
+
+// Running returns true if the container is already running.
+func (c *Container) Running() (_ bool) {
+	return
+}
+
+// Controllable returns true if the caller can control the container.
+func (c *Container) Controllable() (_ bool) {
+	return
+}
+
+// CreateSnapshot creates a new snapshot.
+func (c *Container) CreateSnapshot() (_ *Snapshot, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RestoreSnapshot creates a new container based on a snapshot.
+func (c *Container) RestoreSnapshot(snapshot Snapshot, name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DestroySnapshot destroys the specified snapshot.
+func (c *Container) DestroySnapshot(snapshot Snapshot) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DestroyAllSnapshots destroys all the snapshot.
+func (c *Container) DestroyAllSnapshots() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RollbackToSnapshot restores the container in place from the snapshot,
+// like lxc-snapshot -r. A running container is stopped first and started
+// again afterwards. The name and the MAC addresses of the container are
+// kept.
+func (c *Container) RollbackToSnapshot(snapshot Snapshot) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Snapshots returns the list of container snapshots.
+func (c *Container) Snapshots() (_ []Snapshot, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// State returns the state of the container.
+func (c *Container) State() (_ State) {
+	return
+}
+
+// InitPid returns the process ID of the container's init process
+// seen from outside the container.
+func (c *Container) InitPid() (_ int) {
+	return
+}
+
+// InitPidFd returns the pidfd of the container's init process.
+func (c *Container) InitPidFd() (_ *os.File, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DevptsFd returns the pidfd of the container's init process.
+func (c *Container) DevptsFd() (_ *os.File, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SeccompNotifyFd returns the seccomp notify fd of the container.
+func (c *Container) SeccompNotifyFd() (_ *os.File, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SeccompNotifyFdActive returns the seccomp notify fd of the running container.
+func (c *Container) SeccompNotifyFdActive() (_ *os.File, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Daemonize returns true if the container wished to be daemonized.
+func (c *Container) Daemonize() (_ bool) {
+	return
+}
+
+// WantDaemonize determines if the container wants to run daemonized.
+func (c *Container) WantDaemonize(state bool) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WantCloseAllFds determines whether container wishes all file descriptors
+// to be closed on startup.
+func (c *Container) WantCloseAllFds(state bool) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetVerbosity sets the verbosity level of some API calls
+func (c *Container) SetVerbosity(verbosity Verbosity) {
+	return
+}
+
+// Freeze freezes the running container. On unified cgroup hosts where liblxc
+// fails to, e.g. older liblxc versions, it falls back to writing cgroup.freeze,
+// see FreezeMethod.
+func (c *Container) Freeze() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Unfreeze thaws the frozen container.
+func (c *Container) Unfreeze() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Create creates the container using given TemplateOptions
+func (c *Container) Create(options TemplateOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Start starts the container.
+func (c *Container) Start() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartWithArgs starts the container using given arguments.
+func (c *Container) StartWithArgs(args []string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartExecute starts a container. It runs a minimal init as PID 1 and the
+// requested program as the second process.
+func (c *Container) StartExecute(args []string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartAndFreeze starts the container and freezes it as soon as liblxc
+// reports it running, before the lock is released, which allows keeping
+// pre-started containers around to be thawed on demand by Unfreeze. The
+// container isn't frozen before its init runs: init and whatever it started
+// meanwhile are frozen wherever they got to. The container is stopped again
+// if it can't be frozen.
+func (c *Container) StartAndFreeze() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Execute executes the given command in a temporary container.
+func (c *Container) Execute(args ...string) (_ []byte, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Stop stops the container.
+func (c *Container) Stop() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Reboot reboots the container.
+func (c *Container) Reboot() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Shutdown shuts down the container.
+func (c *Container) Shutdown(timeout time.Duration) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Destroy destroys the container.
+func (c *Container) Destroy() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DestroyWithAllSnapshots destroys the container and its snapshots
+func (c *Container) DestroyWithAllSnapshots() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Clone clones the container using given arguments with specified backend.
+func (c *Container) Clone(name string, options CloneOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConvertStorage moves the rootfs of the container to another backend store.
+// The container is cloned into the target backend under a temporary name,
+// the original is renamed out of the way and the clone takes over its name,
+// keeping the hostname and MAC addresses. The original is only destroyed once
+// the clone replaced it, if the swap fails it gets its name back.
+func (c *Container) ConvertStorage(target BackendStore, opts ConvertStorageOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// BackendStore returns the backend store of the container's rootfs.
+func (c *Container) BackendStore() (_ BackendStore) {
+	return
+}
+
+// Rename renames the container.
+func (c *Container) Rename(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Wait waits for container to reach a particular state.
+func (c *Container) Wait(state State, timeout time.Duration) (_ bool) {
+	return
+}
+
+// ConfigFileName returns the container's configuration file's name.
+func (c *Container) ConfigFileName() (_ string) {
+	return
+}
+
+// ConfigItem returns the value of the given config item.
+func (c *Container) ConfigItem(key string) (_ []string) {
+	return
+}
+
+// ConfigItemE returns the values of the given config item. Unlike ConfigItem
+// it fails for keys unknown to liblxc and returns no values for unset keys.
+// liblxc doesn't tell unset keys apart from keys set to an empty value.
+func (c *Container) ConfigItemE(key string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LookupConfigItem returns the value of the given config item and whether
+// it's set, values of keys accumulating several values are separated by
+// newlines. Unknown keys are reported as unset.
+func (c *Container) LookupConfigItem(key string) (_ string, _ bool) {
+	return
+}
+
+// SetConfigItem sets the value of the given config item.
+func (c *Container) SetConfigItem(key string, value string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunningConfigItem returns the value of the given config item.
+func (c *Container) RunningConfigItem(key string) (_ []string) {
+	return
+}
+
+// CgroupItem returns the value of the given cgroup subsystem value.
+func (c *Container) CgroupItem(key string) (_ []string) {
+	return
+}
+
+// ReadCgroupItems returns the values of the given cgroup items, reading all
+// of them in a single call into liblxc, which is cheaper than calling
+// CgroupItem for each of them in stats collection loops. Items which can't be
+// read are left out.
+func (c *Container) ReadCgroupItems(keys []string) (_ map[string][]string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetCgroupItem sets the value of given cgroup subsystem value.
+func (c *Container) SetCgroupItem(key string, value string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ClearConfig completely clears the containers in-memory configuration.
+// See ClearConfigE to learn whether it was cleared.
+func (c *Container) ClearConfig() {
+	return
+}
+
+// ClearConfigE is like ClearConfig but returns an error if the configuration
+// wasn't cleared, e.g. as the interceptor denied it.
+func (c *Container) ClearConfigE() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ClearConfigItem clears the value of given config item.
+func (c *Container) ClearConfigItem(key string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConfigKeys returns the names of the config items.
+func (c *Container) ConfigKeys(key ...string) (_ []string) {
+	return
+}
+
+// LoadConfigFile loads the configuration file from given path.
+func (c *Container) LoadConfigFile(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SaveConfigFile saves the configuration file to given path.
+func (c *Container) SaveConfigFile(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConfigPath returns the configuration file's path.
+func (c *Container) ConfigPath() (_ string) {
+	return
+}
+
+// SetConfigPath sets the configuration file's path.
+func (c *Container) SetConfigPath(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// MemoryUsage returns memory usage of the container in bytes.
+func (c *Container) MemoryUsage() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// MemoryLimit returns memory limit of the container in bytes.
+func (c *Container) MemoryLimit() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetMemoryLimit sets memory limit of the container in bytes.
+func (c *Container) SetMemoryLimit(limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SoftMemoryLimit returns soft memory limit of the container in bytes.
+func (c *Container) SoftMemoryLimit() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetSoftMemoryLimit sets soft  memory limit of the container in bytes.
+func (c *Container) SetSoftMemoryLimit(limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// KernelMemoryUsage returns current kernel memory allocation of the container in bytes.
+//
+// Deprecated: Kernel memory accounting only exists on cgroup v1 and was
+// removed from recent kernels. ErrNotSupported is returned on cgroup v2 hosts.
+func (c *Container) KernelMemoryUsage() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// KernelMemoryLimit returns kernel memory limit of the container in bytes.
+//
+// Deprecated: See KernelMemoryUsage.
+func (c *Container) KernelMemoryLimit() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetKernelMemoryLimit sets kernel memory limit of the container in bytes.
+//
+// Deprecated: See KernelMemoryUsage.
+func (c *Container) SetKernelMemoryLimit(limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// MemorySwapUsage returns memory+swap usage of the container in bytes.
+func (c *Container) MemorySwapUsage() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// MemorySwapLimit returns the memory+swap limit of the container in bytes.
+func (c *Container) MemorySwapLimit() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetMemorySwapLimit sets memory+swap limit of the container in bytes.
+func (c *Container) SetMemorySwapLimit(limit ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// BlkioUsage returns number of bytes transferred to/from the disk by the container.
+func (c *Container) BlkioUsage() (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CPUTime returns the total CPU time (in nanoseconds) consumed by all tasks
+// in this cgroup (including tasks lower in the hierarchy).
+func (c *Container) CPUTime() (_ time.Duration, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CPUTimePerCPU returns the CPU time (in nanoseconds) consumed on each CPU by
+// all tasks in this cgroup (including tasks lower in the hierarchy).
+func (c *Container) CPUTimePerCPU() (_ map[int]time.Duration, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CPUStats returns the number of CPU cycles (in the units defined by USER_HZ on the system)
+// consumed by tasks in this cgroup and its children in both user mode and system (kernel) mode.
+func (c *Container) CPUStats() (_ map[string]int64, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConsoleFd allocates a console tty from container
+// ttynum: tty number to attempt to allocate or -1 to allocate the first available tty
+//
+// Returns "ttyfd" on success, -1 on failure. The returned "ttyfd" is
+// used to keep the tty allocated. The caller should close "ttyfd" to
+// indicate that it is done with the allocated console so that it can
+// be allocated by another caller. See AllocateConsole for the number of the
+// allocated tty and resizing it.
+func (c *Container) ConsoleFd(ttynum int) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AllocateConsole allocates the console tty ttynum of the container, -1 for
+// the first available one and 0 for the console itself. Unlike ConsoleFd it
+// reports the allocated tty and keeps it until the returned ConsoleTTY is
+// closed.
+func (c *Container) AllocateConsole(ttynum int) (_ *ConsoleTTY, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Console allocates and runs a console tty from container
+//
+// This function will not return until the console has been exited by the user.
+func (c *Container) Console(options ConsoleOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachShell attaches a shell to the container.
+// It clears all environment variables before attaching.
+func (c *Container) AttachShell(options AttachOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunCommandStatus attachs a shell and runs the command within the container.
+// The process will wait for the command to finish and return the result of
+// waitpid(), i.e. the process' exit status. An error is returned only when
+// invocation of the command completely fails.
+func (c *Container) RunCommandStatus(args []string, options AttachOptions) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunCommandNoWait runs the given command and returns without waiting it to finish.
+func (c *Container) RunCommandNoWait(args []string, options AttachOptions) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunCommandAsync runs the given command and returns without waiting it to finish.
+//
+// Unlike RunCommandNoWait, the command is not a child of the calling process.
+// The current executable is run as helper which attaches and reaps the
+// command, reporting its exit status back over a pipe, so the result can't
+// be lost to a SIGCHLD handler or a waitpid(-1) loop elsewhere in the host
+// process. Use the returned AttachedProcess to wait for the command.
+func (c *Container) RunCommandAsync(args []string, options AttachOptions) (_ *AttachedProcess, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunCommand attachs a shell and runs the command within the container.
+// The process will wait for the command to finish and return a success status. An error
+// is returned only when invocation of the command completely fails.
+func (c *Container) RunCommand(args []string, options AttachOptions) (_ bool, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Interfaces returns the names of the network interfaces.
+func (c *Container) Interfaces() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InterfaceStats returns the stats about container's network interfaces
+func (c *Container) InterfaceStats() (_ map[string]map[string]ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPAddress returns the IP address of the given network interface.
+func (c *Container) IPAddress(interfaceName string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv4Address returns the IPv4 address of the given network interface.
+func (c *Container) IPv4Address(interfaceName string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv6Address returns the IPv6 address of the given network interface.
+func (c *Container) IPv6Address(interfaceName string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitIPAddresses waits until IPAddresses call returns something or time outs
+func (c *Container) WaitIPAddresses(timeout time.Duration) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPAddresses returns all IP addresses.
+func (c *Container) IPAddresses() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv4Addresses returns all IPv4 addresses.
+func (c *Container) IPv4Addresses() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv6Addresses returns all IPv6 addresses.
+func (c *Container) IPv6Addresses() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPAddressesWithOptions returns the IP addresses matching the given options,
+// indexed by interface name.
+func (c *Container) IPAddressesWithOptions(opts IPAddressOptions) (_ map[string][]net.IP, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitIPAddressesWithOptions waits until IPAddressesWithOptions returns
+// something (or a global unicast address if WaitForGlobal is set) or time outs.
+func (c *Container) WaitIPAddressesWithOptions(opts IPAddressOptions, timeout time.Duration) (_ map[string][]net.IP, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LogFile returns the name of the logfile.
+func (c *Container) LogFile() (_ string) {
+	return
+}
+
+// SetLogFile sets the name of the logfile.
+func (c *Container) SetLogFile(filename string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LogLevel returns the level of the logfile.
+func (c *Container) LogLevel() (_ LogLevel) {
+	return
+}
+
+// SetLogLevel sets the level of the logfile.
+func (c *Container) SetLogLevel(level LogLevel) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AddDeviceNode adds specified device to the container.
+func (c *Container) AddDeviceNode(source string, destination ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveDeviceNode removes the specified device from the container.
+func (c *Container) RemoveDeviceNode(source string, destination ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Checkpoint checkpoints the container.
+func (c *Container) Checkpoint(opts CheckpointOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Restore restores the container from a checkpoint.
+func (c *Container) Restore(opts RestoreOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Migrate migrates the container.
+func (c *Container) Migrate(cmd uint, opts MigrateOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachInterface attaches specified netdev to the container.
+func (c *Container) AttachInterface(source, destination string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DetachInterface detaches specified netdev from the container.
+func (c *Container) DetachInterface(source string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DetachInterfaceRename detaches specified netdev from the container and renames it.
+func (c *Container) DetachInterfaceRename(source, target string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConsoleLog allows to perform operations on the container's in-memory console
+// buffer.
+func (c *Container) ConsoleLog(opt ConsoleLogOptions) (_ []byte, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ErrorNum returns the error_num field of the container.
+func (c *Container) ErrorNum() (_ int) {
+	return
+}
+
+// AppliedDeviceProfiles returns the names of the device profiles applied to
+// the container.
+func (c *Container) AppliedDeviceProfiles() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ApplyProfile adds the devices of the named profile stored under the
+// lxcpath of the container to its configuration and saves it. The devices
+// are available on the next start. Applying a profile again replaces the
+// devices it added before.
+func (c *Container) ApplyProfile(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveProfile removes the devices added by the named profile from the
+// configuration of the container and saves it. The items recorded when the
+// profile was applied are removed, so this works even if the profile was
+// changed or deleted in the meantime.
+func (c *Container) RemoveProfile(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DevicePolicy returns the device policy of the container as found in its
+// configuration.
+func (c *Container) DevicePolicy() (_ DevicePolicy, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetDevicePolicy replaces the device policy in the configuration of the
+// container. The policy is applied on the next start of the container.
+func (c *Container) SetDevicePolicy(policy DevicePolicy) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WatchDevices starts passing host devices matching filter through to the
+// running container, following the udev events of the host.
+func (c *Container) WatchDevices(filter DeviceFilter) (_ *DeviceWatcher, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DiskUsage returns the disk usage of the root filesystem and the snapshots
+// of the container, using the tools of its backend (btrfs qgroups, zfs, lvs)
+// or walking the filesystem.
+func (c *Container) DiskUsage() (_ DiskUsageInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// FreezeMethod returns how the container was frozen by Freeze, FreezeNone if
+// it isn't frozen through this Container.
+func (c *Container) FreezeMethod() (_ FreezeMethod) {
+	return
+}
+
+// EnableNvidiaRuntime configures the container to use NVIDIA GPUs the same
+// way LXD's nvidia.runtime does. It allows and bind mounts the GPU device
+// nodes, exports the NVIDIA_* environment variables and registers the LXC
+// nvidia mount hook, which uses nvidia-container-cli to mount the driver
+// libraries into the container. The changes apply on the next start.
+func (c *Container) EnableNvidiaRuntime(opts NvidiaOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// EnableROCmRuntime configures the container to use AMD GPUs through ROCm.
+// It allows /dev/kfd and the /dev/dri nodes, bind mounts them and the ROCm
+// installation into the container. The changes apply on the next start.
+func (c *Container) EnableROCmRuntime(opts ROCmOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Hibernate parks the idle container cheaply on an overcommitted host: it
+// freezes the container and pushes its memory to swap until only
+// opts.Resident is left, so it needs swap to be effective. Thaw reverts it.
+// With opts.CheckpointDirectory set the container is checkpointed with CRIU
+// first, without stopping it, so it can be restored should the host need to
+// kill it.
+func (c *Container) Hibernate(opts HibernateOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Hibernated returns whether the container was hibernated and not thawed.
+func (c *Container) Hibernated() (_ bool) {
+	return
+}
+
+// Thaw resumes the container parked by Hibernate, restoring its memory limit
+// before unfreezing it. Its memory is swapped back in as it is used.
+func (c *Container) Thaw() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// HookEvents registers a helper for the given hook types (DefaultHookTypes
+// if none are given) and returns a channel receiving an event whenever one
+// of these hooks runs. The helper is the current executable, which forwards
+// the hook type and environment over an abstract unix socket, events sent by
+// other users than root and the user of this process are dropped. Hooks
+// running in the network namespace of the container can't reach the socket. The
+// channel is closed when the container is released. The hooks are added to
+// the in-memory configuration and apply on the next start.
+func (c *Container) HookEvents(types ...string) (_ <-chan HookEvent, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ResetIdentity clears the identity a guest inherits when cloned: it empties
+// /etc/machine-id so a new one is generated on boot, removes the journal of
+// the old machine-id and removes the SSH host keys, which systemd guests
+// regenerate on the next boot. The container has to be stopped.
+func (c *Container) ResetIdentity() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WatchIdle shuts the container down once it was idle for the timeout of
+// the policy while RUNNING, i.e. it had no network traffic and, if the
+// policy counts them, no processes besides init. Frozen containers aren't
+// considered. Combined with an Activator it scales services to zero. Call
+// Stop on the returned IdleWatcher to stop watching.
+func (c *Container) WatchIdle(policy IdlePolicy) (_ *IdleWatcher, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IDMap returns the idmap of the container. The map is empty for privileged
+// containers.
+func (c *Container) IDMap() (_ IDMap, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// PrepareRootfs makes the rootfs of the container usable with its idmap,
+// either by configuring an idmapped rootfs mount or by shifting the ownership
+// of the files on disk. It is a no-op for privileged containers.
+func (c *Container) PrepareRootfs(opts RootfsOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CreateFromImage creates the container from an OCI image pulled from a
+// registry, e.g. "docker.io/library/alpine:3.20". The layers are verified
+// against their digests and extracted into the rootfs of the container, the
+// entrypoint, environment and working directory of the image are set as its
+// init command. The image can be pinned to a digest or required to be signed
+// with cosign, see ImageOptions.
+func (c *Container) CreateFromImage(ref string, opts ImageOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CreateFromImageContext is like CreateFromImage but aborts the pull and the
+// extraction of the layers once ctx is done. Interrupted downloads are
+// resumed with range requests and verified again.
+func (c *Container) CreateFromImageContext(ctx context.Context, ref string, opts ImageOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Includes returns the files included by the configuration of the
+// container, in order. Files included by these aren't reported.
+func (c *Container) Includes() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AddInclude includes the file or directory in the configuration of the
+// container, loading its items. Includes leading back to path or to an
+// include file already being expanded are rejected with ErrIncludeCycle.
+func (c *Container) AddInclude(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveInclude removes the include of the file or directory from the
+// configuration of the container, along with the items it loaded. The
+// configuration is reloaded, so items set directly are kept.
+func (c *Container) RemoveInclude(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ExpandedConfig returns the configuration of the container with the
+// included files expanded in place, recording where each item comes from.
+// Unlike DumpConfig the items keep the order of the config files.
+func (c *Container) ExpandedConfig() (_ []ConfigOrigin, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// EffectiveConfig returns the items liblxc uses for the container, along
+// with the file each of them was set in. Includes are resolved, keys set
+// more than once are reported with the value in effect only. For containers
+// which aren't defined yet, the default configuration they are created with
+// (lxc.default_config) comes first. Keys never set and left at the liblxc
+// defaults aren't reported.
+func (c *Container) EffectiveConfig() (_ []ConfigOrigin, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Init returns the init process of the container found in its configuration.
+func (c *Container) Init() (_ ContainerInit, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetInit replaces the init process in the configuration of the container,
+// including its environment. It applies on the next start.
+func (c *Container) SetInit(init ContainerInit) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Inspect returns a document describing the container: its state, config
+// summary, mounts, limits, snapshots and, if running, init pid, addresses
+// and resource usage. It's meant to be serialized to JSON, e.g. for a
+// CLI. The parts are read one after the other, a container changing state
+// meanwhile may be described inconsistently.
+func (c *Container) Inspect() (_ InspectInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RotateLog rotates the log file of the container once it exceeds the size
+// or age of the options, it returns whether it did. The log is copied to
+// <log>.1 and truncated in place, so a running container keeps logging.
+func (c *Container) RotateLog(opts LogRotateOptions) (_ bool, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPAddrs returns all IP addresses.
+func (c *Container) IPAddrs() (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv4Addrs returns all IPv4 addresses.
+func (c *Container) IPv4Addrs() (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IPv6Addrs returns all IPv6 addresses.
+func (c *Container) IPv6Addrs() (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InterfaceAddrs returns the IP addresses of the given network interface.
+func (c *Container) InterfaceAddrs(interfaceName string) (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InterfaceIPv4Addrs returns the IPv4 addresses of the given network interface.
+func (c *Container) InterfaceIPv4Addrs(interfaceName string) (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InterfaceIPv6Addrs returns the IPv6 addresses of the given network interface.
+func (c *Container) InterfaceIPv6Addrs(interfaceName string) (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitIPAddrs waits until IPAddrs call returns something or time outs
+func (c *Container) WaitIPAddrs(timeout time.Duration) (_ []netip.Addr, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConfiguredIPPrefixes returns the static addresses of the container's
+// network devices (lxc.net.N.ipv4.address and lxc.net.N.ipv6.address).
+func (c *Container) ConfiguredIPPrefixes() (_ []netip.Prefix, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NetNSStats returns the socket statistics of the network namespace of the
+// container: open TCP and UDP sockets, listening ports and conntrack
+// entries. Useful to spot socket leaks or unexpected listeners. Entering the
+// namespace for the conntrack entries needs CAP_SYS_ADMIN.
+func (c *Container) NetNSStats() (_ NetNSStats, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Networks returns the configured network devices of the container.
+func (c *Container) Networks() (_ []Network, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AddNetwork adds the network device to the container and returns its
+// index. Index is ignored. It fails with ErrNotSupported if liblxc or the
+// kernel lack the type of the device. The device is created on the next
+// start.
+func (c *Container) AddNetwork(n Network) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveNetwork removes the network device lxc.net.index from the
+// container. The indices of the other devices are kept.
+func (c *Container) RemoveNetwork(index int) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NotifySocket wires a sd_notify compatible socket into the container. The
+// socket is bind mounted into the container and NOTIFY_SOCKET is set in the
+// environment of init, so a systemd guest reports READY=1 once it finished
+// booting. The changes apply on the next start. It returns the path of the
+// socket on the host.
+func (c *Container) NotifySocket() (_ string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitReady waits until the guest sent READY=1 over the socket set up by
+// NotifySocket since the container was last started, or until ctx is done.
+func (c *Container) WaitReady(ctx context.Context) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NotifyStatus returns the last STATUS= message sent by the guest over the
+// socket set up by NotifySocket.
+func (c *Container) NotifyStatus() (_ string) {
+	return
+}
+
+// ExportOCI writes an OCI runtime bundle of the stopped container to dir:
+// a config.json generated from the LXC configuration where it can be mapped
+// (init command, environment, idmap, mounts, capabilities, cgroup limits and
+// device rules) and a copy of the rootfs. The bundle can be run with runc or
+// crun. Only directory backed root filesystems are supported.
+func (c *Container) ExportOCI(dir string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Owner returns the owner of the container, empty if it has none.
+func (c *Container) Owner() (_ string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetOwner tags the container with the owner, names separated by slashes
+// from the tenant down (e.g. "billing/api"). An empty owner removes the tag.
+func (c *Container) SetOwner(owner string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// PortForwards returns the host ports forwarded to the container.
+func (c *Container) PortForwards() (_ []PortForward, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AddPortForward records a host port forwarded to the container, so that
+// conflicts are detected before it starts. Setting up the forward itself
+// is up to the caller.
+func (c *Container) AddPortForward(p PortForward) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemovePortForward removes a host port forwarded to the container.
+func (c *Container) RemovePortForward(p PortForward) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CheckPortConflicts checks the host ports forwarded to the container are
+// neither used by host listeners nor forwarded to another container of the
+// lxcpath. Start runs the check as well.
+func (c *Container) CheckPortConflicts() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AppliedPresets returns the names of the presets applied to the container.
+func (c *Container) AppliedPresets() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ApplyPreset applies the preset, e.g. Presets.Hardened, to the
+// configuration of the container and saves it. The values it replaces are
+// recorded for RevertPreset. Applying an applied preset again is a no-op.
+func (c *Container) ApplyPreset(preset Preset) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RevertPreset reverts the named preset, restoring the values it replaced
+// and removing the values it added, and saves the configuration of the
+// container. Presets applied later which set the same keys should be
+// reverted first.
+func (c *Container) RevertPreset(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachedProfiles returns the names of the profiles attached to the
+// container, in the order they apply.
+func (c *Container) AttachedProfiles() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachProfile attaches the named profile stored under the lxcpath of the
+// container and saves its configuration. Profiles apply in the order they
+// were attached, every attached profile is applied again from its current
+// definition. Attaching an attached profile refreshes it in place.
+func (c *Container) AttachProfile(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DetachProfile detaches the named profile, restoring the values it replaced,
+// and saves the configuration of the container.
+func (c *Container) DetachProfile(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetRealtime allows the processes of the container to use SCHED_FIFO and
+// SCHED_RR up to the priority, with the CPU time of realtime tasks limited
+// to the runtime per period by cpu.rt_runtime_us and cpu.rt_period_us. It
+// refuses options giving the container a larger share of the CPU than the
+// host allows realtime tasks (kernel.sched_rt_runtime_us). The cgroup is
+// updated if the container is running, the prlimits apply on the next
+// start. Realtime group scheduling is only available with cgroup1.
+func (c *Container) SetRealtime(opts RealtimeOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Realtime returns the realtime options of the container, false if
+// SetRealtime wasn't used.
+func (c *Container) Realtime() (_ RealtimeOptions, _ bool, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ClearRealtime removes the realtime settings of the container, it can't
+// use realtime policies from the next start on.
+func (c *Container) ClearRealtime() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Admit checks that the configured memory and CPU limits of the container fit
+// into the capacity of the host left by the limits of the running containers
+// in the same lxcpath, scaled by the overcommit ratios of opts. Containers
+// without limits are always admitted, unless a project they belong to has
+// quotas (see Project).
+func (c *Container) Admit(opts AdmissionOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartAdmitted starts the container if Admit admits it.
+func (c *Container) StartAdmitted(opts AdmissionOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Busy returns true if the container is in a transitional state, e.g.
+// STARTING or STOPPING, in which liblxc refuses most operations.
+func (c *Container) Busy() (_ bool) {
+	return
+}
+
+// Retryable returns true if the operation which failed with err may succeed
+// when retried: the error is transient or the container is busy. It lets
+// callers without an OperationPolicy retry themselves.
+func (c *Container) Retryable(err error) (_ bool) {
+	return
+}
+
+// Limits returns the resource limits of the container init as set through
+// lxc.prlimit, sorted by resource.
+func (c *Container) Limits() (_ []Rlimit, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Limit returns the limit of the resource set through lxc.prlimit. The
+// second return value is false if the limit isn't set.
+func (c *Container) Limit(resource string) (_ Rlimit, _ bool, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetLimit sets the limit of the resource for the container init through
+// lxc.prlimit, e.g. SetLimit("nofile", 1024, 4096). Use RlimitInfinity for
+// an unlimited resource. The limit applies on the next start.
+func (c *Container) SetLimit(resource string, soft uint64, hard uint64) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ClearLimit removes the limit of the resource set through lxc.prlimit.
+func (c *Container) ClearLimit(resource string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunScript runs the shell script in the container with /bin/sh, in a
+// single attach instead of one per command, and returns its exit status.
+// The script is passed on stdin, commands of the script reading stdin
+// consume the rest of it.
+func (c *Container) RunScript(script string, options AttachOptions) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RunCommands runs the commands in the container in order, in a single
+// attach instead of one per command. It stops at the first command which
+// fails and returns its exit status, 0 if all succeed. The commands run
+// through /bin/sh with their stdin on /dev/null.
+func (c *Container) RunCommands(commands [][]string, options AttachOptions) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetOCISeccompProfile compiles the OCI seccomp profile (see
+// CompileOCISeccomp) and sets it as the seccomp profile of the container.
+// The policy is stored in the directory of a defined container, in a
+// temporary file otherwise.
+func (c *Container) SetOCISeccompProfile(profile []byte) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SecurityAudit checks the configuration of the container against a
+// hardening checklist: whether it is privileged or maps root to the host,
+// the dropped capabilities, the apparmor and seccomp profiles, mounts of
+// /proc and /sys and the allowed devices. The findings are graded, see
+// SecurityReport.
+func (c *Container) SecurityAudit() (_ SecurityReport, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AssignVF assigns the free SR-IOV virtual function to the container as a
+// phys network device and returns its index. The MAC address and VLAN of
+// opts are set on the physical function right away, the interface is moved
+// into the container on the next start. liblxc moves it back to the host
+// when the container stops, RemoveNetwork then releases it from the
+// config.
+func (c *Container) AssignVF(vf VF, opts VFOptions) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartWithFds starts the container passing the files to its init from fd
+// 3 on, in the order of their names, with LISTEN_FDS, LISTEN_FDNAMES and
+// LISTEN_PID set for socket activation, e.g. of systemd units inside the
+// container. liblxc only keeps inherited fds when starting in the
+// foreground, so the current executable is run as monitor of the container
+// with the saved configuration. It returns once the container is running,
+// or with ErrStartFailed and the error of the monitor if it exits first or
+// the container isn't running within startFdsTimeout, in which case the
+// monitor is killed.
+func (c *Container) StartWithFds(fds map[string]*os.File) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// EnsureState brings the container into the target state, which is STOPPED,
+// RUNNING or FROZEN, by starting, stopping, freezing or unfreezing it. It
+// waits for containers in transitional states (e.g. STARTING) to settle
+// first and is a no-op if the container already is in the target state,
+// which makes it suitable for reconciliation loops.
+func (c *Container) EnsureState(ctx context.Context, target State) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewStatsReader returns a StatsReader for the running container. It reads
+// the cgroups the container had when it was created, so it needs to be
+// replaced when the container restarts.
+func (c *Container) NewStatsReader() (_ *StatsReader, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetSwap provisions swap for the container from its next start on. The
+// swap is set up before the container starts and released after it
+// stopped, by the current executable running as post-stop hook. On cgroup1
+// hosts the container needs a memory limit, the swap can't be limited
+// otherwise.
+func (c *Container) SetSwap(opts SwapOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Swap returns the swap options of the container, ErrNoSwap if it has no
+// swap provisioned.
+func (c *Container) Swap() (_ SwapOptions, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveSwap stops provisioning swap for the stopped container and removes
+// its swap file.
+func (c *Container) RemoveSwap() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetTmpfs mounts a tmpfs with the options at the absolute path in the
+// container, replacing a tmpfs mounted there before.
+func (c *Container) SetTmpfs(path string, opts TmpfsOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// RemoveTmpfs removes the tmpfs mounted at path in the container.
+func (c *Container) RemoveTmpfs(path string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// TmpfsMounts returns the options of the tmpfs mounts of the container by
+// path.
+func (c *Container) TmpfsMounts() (_ map[string]TmpfsOptions, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetShmSize mounts a tmpfs of the size at /dev/shm, with the
+// DefaultShmOptions otherwise.
+func (c *Container) SetShmSize(size ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetTmpSize mounts a tmpfs of the size at /tmp, with the DefaultTmpOptions
+// otherwise.
+func (c *Container) SetTmpSize(size ByteSize) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Processes returns the processes of the running container sorted by pid.
+// CPUPercent isn't set, Top samples it.
+func (c *Container) Processes() (_ []ProcessInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Top samples the processes of the running container every interval and
+// sends them, sorted and limited as requested, on the returned channel,
+// like top does. The channel is closed when ctx is done or the container
+// stops.
+func (c *Container) Top(ctx context.Context, opts TopOptions) (_ <-chan []ProcessInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// PinToNode confines the container to the CPUs and the memory of the NUMA
+// node, setting cpuset.cpus and cpuset.mems together so that it never runs
+// on CPUs of one node with memory of another. The config is updated, and
+// the cgroup too if the container is running.
+func (c *Container) PinToNode(node int) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// TTYMax returns the number of ttys allocated for the container
+// (lxc.tty.max), 0 for headless containers.
+func (c *Container) TTYMax() (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetTTYMax sets the number of ttys allocated for the container
+// (lxc.tty.max), 0 makes it headless. It applies on the next start.
+func (c *Container) SetTTYMax(n int) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// PTYMax returns the maximum number of ptys the container may allocate
+// (lxc.pty.max), 0 if unlimited.
+func (c *Container) PTYMax() (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetPTYMax sets the maximum number of ptys the container may allocate
+// (lxc.pty.max), 0 for unlimited. It applies on the next start.
+func (c *Container) SetPTYMax(n int) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// TTYs reports the ttys of the running container and the processes using
+// them, e.g. to pick a free tty for ConsoleFd.
+func (c *Container) TTYs() (_ []TTY, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartedAt returns the time the container was started at, i.e. the start
+// time of its init process. The resolution is 10ms.
+func (c *Container) StartedAt() (_ time.Time, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Uptime returns how long the container has been running.
+func (c *Container) Uptime() (_ time.Duration, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LookupUser returns the user of the given name, or numeric uid, from
+// /etc/passwd of the container. The root filesystem of a stopped container
+// is mounted for the lookup.
+func (c *Container) LookupUser(name string) (_ User, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LookupGroup returns the group of the given name, or numeric gid, from
+// /etc/group of the container.
+func (c *Container) LookupGroup(name string) (_ Group, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// UUID returns the UUID of the container, which stays the same when it is
+// renamed. It is generated by Create, Clone, CreateFromImage, ImportFromLXD
+// and NewApplicationContainer, containers created otherwise get one on first
+// use. liblxc refuses unknown config keys, so it is kept in a file next to
+// the config instead.
+func (c *Container) UUID() (_ string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Volatile returns the volatile config of the container, the values
+// generated for it (e.g. volatile.net.0.hwaddr for the MAC of a network
+// without a configured one) or recorded by the caller (e.g. the last
+// state), kept next to its config instead of in it.
+func (c *Container) Volatile() (_ map[string]string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetVolatile records a volatile value, e.g. volatile.last_state, the
+// key must start with VolatilePrefix.
+func (c *Container) SetVolatile(key string, value string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ResetVolatile removes the given volatile keys, all of them if none are
+// given. Generated values are generated again on the next start, e.g. a
+// network gets a new MAC.
+func (c *Container) ResetVolatile(keys ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachVolume bind mounts the named volume of the lxcpath of the container
+// into it, from the next start on. For unprivileged containers the volume
+// is mounted idmapped if requested and supported, otherwise its files are
+// shifted on disk to the idmap of the container. External volumes are never
+// shifted, they require idmapped mounts. All containers using a
+// shifted volume need the same idmap, ErrVolumeIDMapMismatch is returned
+// otherwise.
+func (c *Container) AttachVolume(name string, opts VolumeAttachOptions) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DetachVolume removes the mount of the named volume from the container.
+// The files of the volume are shifted back once no container relies on
+// them being shifted.
+func (c *Container) DetachVolume(name string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AttachedVolumes returns the volumes of the lxcpath of the container
+// attached to it.
+func (c *Container) AttachedVolumes() (_ []Volume, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Watchdog starts monitoring the heartbeat of the container in the
+// background. The guest is considered hung if the heartbeat file wasn't
+// touched or the heartbeat socket didn't answer within the timeout while the
+// container is RUNNING. Call Stop on the returned Watchdog to stop monitoring.
+func (c *Container) Watchdog(opts WatchdogOptions) (_ *Watchdog, err error) {
+	err = ErrNotSupported
+	return
+}
+
+const (
+	// ContainerCreated reports a container whose config appeared.
+	ContainerCreated ContainerEventType = "created"
+	// ContainerDestroyed reports a container whose config or directory was
+	// removed.
+	ContainerDestroyed ContainerEventType = "destroyed"
+	// ContainerConfigChanged reports the config of a container was written.
+	ContainerConfigChanged ContainerEventType = "config-changed"
+)
+
+// ContainerEvent reports a container of an lxcpath changing.
+type ContainerEvent struct {
+	Type ContainerEventType
+	// Name is the name of the container.
+	Name string
+}
+
+// ContainerEventType is the kind of change a ContainerWatcher reports.
+type ContainerEventType string
+
+// ContainerInit type is used for defining the process started as init of an
+// application container (lxc.init.*).
+type ContainerInit struct {
+	// Cmd is the command line of init (lxc.init.cmd), /sbin/init if empty.
+	Cmd []string
+	// UID is the user id init runs as in the container (lxc.init.uid).
+	UID int
+	// GID is the group id init runs as in the container (lxc.init.gid).
+	GID int
+	// Cwd is the working directory of init (lxc.init.cwd), / if empty.
+	Cwd string
+	// Env are the environment variables of init (lxc.environment) as
+	// KEY=VALUE, a bare KEY passes the variable of the caller.
+	Env []string
+}
+
+// ContainerNames returns the names of defined and active containers on the system.
+func ContainerNames(lxcpath ...string) (_ []string) {
+	return
+}
+
+// ContainerNamesE returns the names of defined and active containers on the
+// system. Unlike ContainerNames it reports errors, e.g. when lxcpath can't be read.
+func ContainerNamesE(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ContainerSnapshot represents a snapshot together with the container it
+// belongs to.
+type ContainerSnapshot struct {
+	Container string
+	Snapshot  Snapshot
+	// Time is the parsed snapshot timestamp.
+	Time time.Time
+	// Size is the disk usage of the snapshot directory. Snapshots stored
+	// outside the filesystem (e.g. zfs or lvm) only account for their config.
+	Size ByteSize
+}
+
+// ContainerWatcher reports containers created, destroyed or reconfigured in
+// an lxcpath, by liblxc, the lxc tools or anything else.
+type ContainerWatcher struct {
+}
+
+// Events returns the channel receiving the container events. It is closed
+// once the watcher is closed. The watcher blocks until the events are read.
+func (w *ContainerWatcher) Events() (_ <-chan ContainerEvent) {
+	return
+}
+
+// Close stops watching the lxcpath.
+func (w *ContainerWatcher) Close() {
+	return
+}
+
+// Containers returns the defined and active containers on the system. Only
+// containers that could retrieved successfully are returned.
+// Caller needs to call Release() on the returned containers to release resources.
+func Containers(lxcpath ...string) (_ []*Container) {
+	return
+}
+
+// ContainersE returns the defined and active containers on the system. Unlike
+// Containers it reports errors, including containers that couldn't be retrieved.
+// Caller needs to call Release() on the returned containers to release resources.
+func ContainersE(lxcpath ...string) (_ []*Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ConvertStorageOptions type is used for defining storage conversion options.
+type ConvertStorageOptions struct {
+	// TemporaryName is the name the converted copy is created under before it
+	// replaces the original container (default: "<name>-convert").
+	TemporaryName string
+	// BackupName is the name the original container is kept under until the
+	// converted copy replaced it (default: "<name>-orig").
+	BackupName string
+	// DestroySnapshots allows converting a container which has snapshots.
+	// The snapshots are destroyed before the original is renamed, liblxc
+	// can't rename them along.
+	DestroySnapshots bool
+}
+
+// CreateVolume creates the named volume under lxcpath (default:
+// DefaultConfigPath()). Directory and btrfs volumes are created below the
+// volumes directory of lxcpath, zfs volumes as dataset below
+// VolumeOptions.ZFSRoot mounted there. Directory volumes may register an
+// existing host directory instead.
+func CreateVolume(name string, opts VolumeOptions, lxcpath ...string) (_ Volume, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// CriuFeatures represents a set of CRIU features
+type CriuFeatures uint64
+
+// DefaultActivatorOptions is a convenient set of options to be used.
+var DefaultActivatorOptions = ActivatorOptions{
+	Network:    "tcp",
+	Address:    "",
+	Target:     "",
+	TargetPort: 0,
+	Timeout:    30 * time.Second,
+}
+
+// DefaultAdmissionOptions is a convenient set of options to be used.
+var DefaultAdmissionOptions = AdmissionOptions{
+	MemoryOvercommit: 1.0,
+	CPUOvercommit:    1.0,
+	MinFreeDisk:      0,
+}
+
+// DefaultAttachOptions is a convenient set of options to be used.
+var DefaultAttachOptions = AttachOptions{
+	Namespaces:         -1,
+	Arch:               -1,
+	Cwd:                "/",
+	UID:                -1,
+	GID:                -1,
+	User:               "",
+	Groups:             nil,
+	ClearEnv:           false,
+	Env:                nil,
+	EnvToKeep:          nil,
+	EnvToKeepGlob:      nil,
+	Path:               "",
+	StdinFd:            os.Stdin.Fd(),
+	StdoutFd:           os.Stdout.Fd(),
+	StderrFd:           os.Stderr.Fd(),
+	RemountSysProc:     false,
+	Rlimits:            nil,
+	Umask:              nil,
+	KeepCaps:           false,
+	NoNewPrivs:         false,
+	AppArmorProfile:    "",
+	SELinuxLabel:       "",
+	Recorder:           nil,
+	CloseInheritedFds:  false,
+	ElevatedPrivileges: false,
+}
+
+// DefaultAttachPath is a PATH matching the layout of common distributions, to
+// be used as AttachOptions.Path.
+const DefaultAttachPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// DefaultCgroupScopeOptions is a convenient set of options to be used.
+var DefaultCgroupScopeOptions = CgroupScopeOptions{
+	Slice: "machine.slice",
+	Scope: "",
+}
+
+// DefaultCloneOptions is a convenient set of options to be used.
+var DefaultCloneOptions = CloneOptions{
+	Backend: Directory,
+}
+
+// DefaultConfigPath returns default config path.
+func DefaultConfigPath() (_ string) {
+	return
+}
+
+// DefaultConsoleOptions is a convenient set of options to be used.
+var DefaultConsoleOptions = ConsoleOptions{
+	Tty:             -1,
+	StdinFd:         os.Stdin.Fd(),
+	StdoutFd:        os.Stdout.Fd(),
+	StderrFd:        os.Stderr.Fd(),
+	EscapeCharacter: 'a',
+	Recorder:        nil,
+}
+
+// DefaultDownloadConfig is a convenient set of options to be used.
+var DefaultDownloadConfig = DownloadConfig{
+	Proxy:     "",
+	Mirrors:   nil,
+	RateLimit: 0,
+	Segments:  1,
+}
+
+// DefaultHibernateOptions is a convenient set of options to be used.
+var DefaultHibernateOptions = HibernateOptions{
+	Resident:            0,
+	CheckpointDirectory: "",
+}
+
+// DefaultHookTypes are the hooks HookEvents registers the helper for by
+// default. The pre-mount, mount, autodev and start hooks are missing as they
+// run in the network namespace of the container where the socket isn't
+// reachable.
+var DefaultHookTypes = []string{
+	"pre-start",
+	"start-host",
+	"stop",
+	"post-stop",
+	"clone",
+	"destroy",
+}
+
+// DefaultIdlePolicy is a convenient set of options to be used.
+var DefaultIdlePolicy = IdlePolicy{
+	Timeout:         15 * time.Minute,
+	Interval:        30 * time.Second,
+	Traffic:         true,
+	Processes:       false,
+	ShutdownTimeout: 30 * time.Second,
+	Bus:             nil,
+}
+
+// DefaultImageOptions is a convenient set of options to be used.
+var DefaultImageOptions = ImageOptions{
+	Platform:        "",
+	Username:        "",
+	Password:        "",
+	Insecure:        false,
+	HTTPClient:      nil,
+	Digest:          "",
+	CosignPublicKey: nil,
+	Retries:         3,
+	Download:        nil,
+	CacheDir:        "",
+}
+
+// DefaultLogRotateOptions is a convenient set of options to be used.
+var DefaultLogRotateOptions = LogRotateOptions{
+	MaxSize:  10 * MB,
+	MaxAge:   0,
+	Keep:     5,
+	Compress: false,
+}
+
+// DefaultLvmVg returns the name of the default LVM volume group.
+func DefaultLvmVg() (_ string) {
+	return
+}
+
+// DefaultNvidiaOptions is a convenient set of options to be used.
+var DefaultNvidiaOptions = NvidiaOptions{
+	Devices:            nil,
+	DriverCapabilities: []string{"compute", "utility"},
+	RequireCUDA:        "",
+	Hook:               "/usr/share/lxc/hooks/nvidia",
+}
+
+// DefaultROCmOptions is a convenient set of options to be used.
+var DefaultROCmOptions = ROCmOptions{
+	Path: "/opt/rocm",
+}
+
+// DefaultRealtimeOptions is a convenient set of options to be used.
+var DefaultRealtimeOptions = RealtimeOptions{
+	Runtime:  100 * time.Millisecond,
+	Period:   time.Second,
+	Priority: 50,
+	CPUTime:  0,
+}
+
+// DefaultShmOptions is a convenient set of options to be used for /dev/shm.
+var DefaultShmOptions = TmpfsOptions{
+	Size:   64 * MB,
+	Inodes: 0,
+	Mode:   os.ModeSticky | 0777,
+	NoSuid: true,
+	NoDev:  true,
+	NoExec: false,
+}
+
+// DefaultSwapOptions is a convenient set of options to be used.
+var DefaultSwapOptions = SwapOptions{
+	Backend:  SwapFile,
+	Size:     1 * GB,
+	Priority: -1,
+}
+
+// DefaultTmpOptions is a convenient set of options to be used for /tmp.
+var DefaultTmpOptions = TmpfsOptions{
+	Size:   256 * MB,
+	Inodes: 0,
+	Mode:   os.ModeSticky | 0777,
+	NoSuid: true,
+	NoDev:  true,
+	NoExec: false,
+}
+
+// DefaultTopOptions is a convenient set of options to be used.
+var DefaultTopOptions = TopOptions{
+	Interval: 2 * time.Second,
+	Sort:     TopSortCPU,
+	Limit:    0,
+}
+
+// DefaultVolumeAttachOptions is a convenient set of options to be used.
+var DefaultVolumeAttachOptions = VolumeAttachOptions{
+	Target:            "",
+	ReadOnly:          false,
+	UseIdmappedMounts: true,
+}
+
+// DefaultVolumeOptions is a convenient set of options to be used.
+var DefaultVolumeOptions = VolumeOptions{
+	Backend: Directory,
+	Path:    "",
+	ZFSRoot: "",
+}
+
+// DefaultWarmPoolOptions is a convenient set of options to be used.
+var DefaultWarmPoolOptions = WarmPoolOptions{
+	Size:          2,
+	Prefix:        "",
+	Frozen:        false,
+	Clone:         CloneOptions{Backend: Overlayfs, Snapshot: true},
+	RetryInterval: 5 * time.Second,
+}
+
+// DefaultWatchdogOptions is a convenient set of options to be used.
+var DefaultWatchdogOptions = WatchdogOptions{
+	HeartbeatFile:   "/run/heartbeat",
+	HeartbeatSocket: "",
+	Interval:        10 * time.Second,
+	Timeout:         30 * time.Second,
+	Action:          WatchdogAlert,
+	OnFailure:       nil,
+}
+
+// DefaultZfsRoot returns the name of the default ZFS root.
+func DefaultZfsRoot() (_ string) {
+	return
+}
+
+// DefinedContainerNames returns the names of the defined containers on the system.
+func DefinedContainerNames(lxcpath ...string) (_ []string) {
+	return
+}
+
+// DefinedContainerNamesE returns the names of the defined containers on the
+// system. Unlike DefinedContainerNames it reports errors.
+func DefinedContainerNamesE(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DefinedContainers returns the defined containers on the system.  Only
+// containers that could retrieved successfully are returned.
+// Caller needs to call Release() on the returned containers to release resources.
+func DefinedContainers(lxcpath ...string) (_ []*Container) {
+	return
+}
+
+// DefinedContainersE returns the defined containers on the system. Unlike
+// DefinedContainers it reports errors.
+// Caller needs to call Release() on the returned containers to release resources.
+func DefinedContainersE(lxcpath ...string) (_ []*Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeleteDeviceProfile removes the named profile stored under lxcpath.
+// Containers it was applied to keep their devices.
+func DeleteDeviceProfile(name string, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeleteDownloadConfig removes the download configuration stored under
+// lxcpath, the global one applies to its containers again.
+func DeleteDownloadConfig(lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeleteProfile removes the named profile stored under lxcpath. Containers
+// it is attached to keep its settings until it is detached.
+func DeleteProfile(name string, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeleteProject removes the named project stored under lxcpath, its
+// containers are no longer limited by its quotas.
+func DeleteProject(name string, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeleteQuirk removes the entry of the distribution and release from the
+// quirks database.
+func DeleteQuirk(distribution string, release string) {
+	return
+}
+
+// DeleteVolume removes the named volume of lxcpath and its data. It fails
+// with ErrVolumeInUse while the volume is attached to a defined container,
+// attachments of containers destroyed since are dropped.
+func DeleteVolume(name string, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DependencyManager starts and stops a set of containers honoring the
+// dependencies among them.
+type DependencyManager struct {
+}
+
+// Add adds the container, its dependents are started once health returns
+// (default: WaitRunning).
+func (m *DependencyManager) Add(c *Container, health HealthCheck) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Require declares that the named container needs the given dependencies,
+// e.g. Require("app", "db") starts "db" before "app" and stops it after.
+func (m *DependencyManager) Require(name string, dependencies ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Order returns the names of the containers in the order they are started.
+func (m *DependencyManager) Order() (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StartAll starts the containers which aren't running yet. Each container is
+// started once the health checks of all its dependencies passed, independent
+// containers are started concurrently. The first failure aborts the
+// containers which didn't start yet.
+func (m *DependencyManager) StartAll(ctx context.Context) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// StopAll stops the running containers in the reverse order of StartAll,
+// shutting each down cleanly within timeout before stopping it.
+func (m *DependencyManager) StopAll(timeout time.Duration) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeviceEvent reports a device passed to or removed from the container.
+type DeviceEvent struct {
+	// Action is the udev action, "add" or "remove".
+	Action string
+	// Path is the device node, e.g. "/dev/ttyUSB0".
+	Path string
+	// Properties are the udev properties of the device.
+	Properties map[string]string
+	// Err is set if the device couldn't be added or removed.
+	Err error
+}
+
+// DeviceFilter selects the host devices a DeviceWatcher passes through.
+type DeviceFilter struct {
+	// Subsystem of the device, e.g. "tty" or "usb".
+	Subsystem string
+	// DevName is a glob matched against the device node name relative to
+	// /dev, e.g. "ttyUSB*".
+	DevName string
+	// Properties are udev properties the device needs to have, e.g.
+	// {"ID_VENDOR_ID": "0403"}.
+	Properties map[string]string
+}
+
+// Match returns true if the udev properties of a device match the filter.
+func (f DeviceFilter) Match(properties map[string]string) (_ bool) {
+	return
+}
+
+// DevicePolicy represents the device access policy of a container. Deny
+// rules are applied before allow rules, so the usual policy is to deny
+// DeviceTypeAll and to allow the required devices.
+type DevicePolicy struct {
+	Allow []DeviceRule
+	Deny  []DeviceRule
+}
+
+// DeviceProfile is a named set of devices applied to containers as a unit,
+// e.g. all USB serial adapters of a host.
+type DeviceProfile struct {
+	Name string `json:"name"`
+	// Devices are host device nodes allowed and bind mounted into the
+	// container at the same path.
+	Devices []string `json:"devices,omitempty"`
+	// Allow are additional device cgroup rules, e.g. "c 188:* rwm".
+	Allow []string `json:"allow,omitempty"`
+	// Mounts are additional lxc.mount.entry values.
+	Mounts []string `json:"mounts,omitempty"`
+}
+
+// DeviceProfiles returns the names of the profiles stored under lxcpath.
+func DeviceProfiles(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DeviceRule represents a single device cgroup rule such as "c 1:3 rwm".
+type DeviceRule struct {
+	Type  DeviceType
+	Major int
+	Minor int
+	// Access is any combination of "r" (read), "w" (write) and "m" (mknod).
+	Access string
+}
+
+// String returns the rule in the format used by devices.allow and devices.deny.
+func (r DeviceRule) String() (_ string) {
+	return
+}
+
+// DeviceType represents the type of a device in a device cgroup rule.
+type DeviceType string
+
+const (
+	// DeviceTypeAll matches all devices
+	DeviceTypeAll DeviceType = "a"
+	// DeviceTypeChar matches character devices
+	DeviceTypeChar DeviceType = "c"
+	// DeviceTypeBlock matches block devices
+	DeviceTypeBlock DeviceType = "b"
+)
+
+// DeviceWatcher passes host devices matching a filter through to a running
+// container as they appear and removes them once they are gone.
+type DeviceWatcher struct {
+}
+
+// Events returns the channel receiving an event for every device added or
+// removed. It is closed once the watcher is closed. Events are dropped
+// while the buffer of the channel is full, so it should be drained.
+func (w *DeviceWatcher) Events() (_ <-chan DeviceEvent) {
+	return
+}
+
+// Close stops watching the devices. Devices already passed through are left
+// in place.
+func (w *DeviceWatcher) Close() {
+	return
+}
+
+// DeviceWildcard matches any major or minor number.
+const DeviceWildcard = -1
+
+// DiskSpace represents the size of a filesystem.
+type DiskSpace struct {
+	Total ByteSize
+	Free  ByteSize
+}
+
+// DiskUsage returns the disk usage of all defined containers in the given
+// lxcpath, keyed by container name.
+func DiskUsage(lxcpath ...string) (_ map[string]DiskUsageInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// DiskUsageInfo represents the disk usage of a container.
+type DiskUsageInfo struct {
+	Backend BackendStore
+	// Rootfs is the size of the root filesystem as seen by the container.
+	Rootfs ByteSize
+	// Delta is the space used exclusively by the container, e.g. the upper
+	// directory of an overlay clone or the exclusive data of a btrfs
+	// subvolume. It equals Rootfs for backends without sharing.
+	Delta ByteSize
+	// Snapshots is the space used by the snapshots of the container.
+	Snapshots ByteSize
+}
+
+// Total returns the space used exclusively by the container and its snapshots.
+func (d DiskUsageInfo) Total() (_ ByteSize) {
+	return
+}
+
+// DownloadConfig type is used for defining how images are downloaded, see
+// SetGlobalDownloadConfig and SaveDownloadConfig.
+type DownloadConfig struct {
+	// Proxy is the URL of the HTTP proxy (default: the HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY environment variables). It is used by image pulls and
+	// passed to the download template through http_proxy and https_proxy.
+	Proxy string `json:"proxy,omitempty"`
+	// Mirrors are registry URLs (e.g. "https://mirror.gcr.io") tried in order
+	// before the registry of the image, failing over to the next one. They
+	// only apply to image pulls, see TemplateOptions.Server for the download
+	// template.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// RateLimit is the maximum download rate of image pulls in bytes per
+	// second (0: unlimited).
+	RateLimit ByteSize `json:"rate_limit,omitempty"`
+	// Segments is the number of parallel range requests large blobs of
+	// image pulls are downloaded with (0 or 1: sequential).
+	Segments int `json:"segments,omitempty"`
+}
+
+// DownloadTemplateOptions is a convenient set of options for "download" template.
+var DownloadTemplateOptions = TemplateOptions{
+	Template: "download",
+	Distro:   "ubuntu",
+	Release:  "trusty",
+	Arch:     "amd64",
+}
+
+const (
+	// ErrActivationFailed - activating the container failed
+	ErrActivationFailed = lxcError("activating the container failed")
+
+	// ErrAddDeviceNodeFailed - adding device to container failed
+	ErrAddDeviceNodeFailed = lxcError("adding device to container failed")
+
+	// ErrAllocationFailed - allocating memory failed
+	ErrAllocationFailed = lxcError("allocating memory failed")
+
+	// ErrAlreadyDefined - container already defined
+	ErrAlreadyDefined = lxcError("container already defined")
+
+	// ErrAlreadyFrozen - container is already frozen
+	ErrAlreadyFrozen = lxcError("container is already frozen")
+
+	// ErrAlreadyRunning - container is already running
+	ErrAlreadyRunning = lxcError("container is already running")
+
+	// ErrApplyLayerFailed - applying the image layer failed
+	ErrApplyLayerFailed = lxcError("applying the image layer failed")
+
+	// ErrAttachFailed - attaching to the container failed
+	ErrAttachFailed = lxcError("attaching to the container failed")
+
+	// ErrAttachInterfaceFailed - attaching specified netdev to the container failed
+	ErrAttachInterfaceFailed = lxcError("attaching specified netdev to the container failed")
+
+	// ErrBlkioUsage - BlkioUsage for the container failed
+	ErrBlkioUsage = lxcError("BlkioUsage for the container failed")
+
+	// ErrCheckpointFailed - checkpoint failed
+	ErrCheckpointFailed = lxcError("checkpoint failed")
+
+	// ErrClearingConfigItemFailed - clearing config item for the container failed
+	ErrClearingConfigItemFailed = lxcError("clearing config item for the container failed")
+
+	// ErrClearingCgroupItemFailed - clearing cgroup item for the container failed
+	ErrClearingCgroupItemFailed = lxcError("clearing cgroup item for the container failed")
+
+	// ErrCloneFailed - cloning the container failed
+	ErrCloneFailed = lxcError("cloning the container failed")
+
+	// ErrCloseAllFdsFailed - setting close_all_fds flag for container failed
+	ErrCloseAllFdsFailed = lxcError("setting close_all_fds flag for container failed")
+
+	// ErrConvertStorageFailed - converting the storage backend of the container failed
+	ErrConvertStorageFailed = lxcError("converting the storage backend of the container failed")
+
+	// ErrCreateFailed - creating the container failed
+	ErrCreateFailed = lxcError("creating the container failed")
+
+	// ErrCreateSnapshotFailed - snapshotting the container failed
+	ErrCreateSnapshotFailed = lxcError("snapshotting the container failed")
+
+	// ErrCreateVolumeFailed - creating the volume failed
+	ErrCreateVolumeFailed = lxcError("creating the volume failed")
+
+	// ErrDaemonizeFailed - setting daemonize flag for container failed
+	ErrDaemonizeFailed = lxcError("setting daemonize flag for container failed")
+
+	// ErrDeleteVolumeFailed - deleting the volume failed
+	ErrDeleteVolumeFailed = lxcError("deleting the volume failed")
+
+	// ErrDependencyCycle - dependencies of the containers form a cycle
+	ErrDependencyCycle = lxcError("dependencies of the containers form a cycle")
+
+	// ErrDestroyAllSnapshotsFailed - destroying all snapshots failed
+	ErrDestroyAllSnapshotsFailed = lxcError("destroying all snapshots failed")
+
+	// ErrDestroyFailed - destroying the container failed
+	ErrDestroyFailed = lxcError("destroying the container failed")
+
+	// ErrDestroySnapshotFailed - destroying the snapshot failed
+	ErrDestroySnapshotFailed = lxcError("destroying the snapshot failed")
+
+	// ErrDestroyWithAllSnapshotsFailed - destroying the container with all snapshots failed
+	ErrDestroyWithAllSnapshotsFailed = lxcError("destroying the container with all snapshots failed")
+
+	// ErrDetachInterfaceFailed - detaching specified netdev to the container failed
+	ErrDetachInterfaceFailed = lxcError("detaching specified netdev to the container failed")
+
+	// ErrDigestMismatch - content does not match its digest
+	ErrDigestMismatch = lxcError("content does not match its digest")
+
+	// ErrDiskUsage - getting the disk usage of the container failed
+	ErrDiskUsage = lxcError("getting the disk usage of the container failed")
+
+	// ErrExecuteFailed - executing the command in a temporary container failed
+	ErrExecuteFailed = lxcError("executing the command in a temporary container failed")
+
+	// ErrExportFailed - exporting the container failed
+	ErrExportFailed = lxcError("exporting the container failed")
+
+	// ErrFreezeFailed - freezing the container failed
+	ErrFreezeFailed = lxcError("freezing the container failed")
+
+	// ErrGPUNotFound - no matching GPU found on the host
+	ErrGPUNotFound = lxcError("no matching GPU found on the host")
+
+	// ErrGroupNotFound - group not found in the container
+	ErrGroupNotFound = lxcError("group not found in the container")
+
+	// ErrHasSnapshots - container has snapshots
+	ErrHasSnapshots = lxcError("container has snapshots")
+
+	// ErrHibernateFailed - hibernating the container failed
+	ErrHibernateFailed = lxcError("hibernating the container failed")
+
+	// ErrHostResources - getting the resources of the host failed
+	ErrHostResources = lxcError("getting the resources of the host failed")
+
+	// ErrHugeTLBLimit - your kernel does not support cgroup hugetlb controller
+	ErrHugeTLBLimit = lxcError("your kernel does not support cgroup hugetlb controller")
+
+	// ErrImportFailed - importing the container failed
+	ErrImportFailed = lxcError("importing the container failed")
+
+	// ErrIncludeCycle - config includes form a cycle
+	ErrIncludeCycle = lxcError("config includes form a cycle")
+
+	// ErrIncludeFailed - reading the included config failed
+	ErrIncludeFailed = lxcError("reading the included config failed")
+
+	// ErrIncludeNotFound - config is not included
+	ErrIncludeNotFound = lxcError("config is not included")
+
+	// ErrInsufficientNumberOfArguments - insufficient number of arguments were supplied
+	ErrInsufficientNumberOfArguments = lxcError("insufficient number of arguments were supplied")
+
+	// ErrInsufficientResources - host has insufficient resources left for the container
+	ErrInsufficientResources = lxcError("host has insufficient resources left for the container")
+
+	// ErrInterfaces - getting interface names for the container failed
+	ErrInterfaces = lxcError("getting interface names for the container failed")
+
+	// ErrInvalidCgroupPlacement - invalid cgroup placement
+	ErrInvalidCgroupPlacement = lxcError("invalid cgroup placement")
+
+	// ErrInvalidCgroupScope - invalid systemd slice or scope name
+	ErrInvalidCgroupScope = lxcError("invalid systemd slice or scope name")
+
+	// ErrInvalidDeviceRule - invalid device cgroup rule
+	ErrInvalidDeviceRule = lxcError("invalid device cgroup rule")
+
+	// ErrInvalidFdName - invalid file descriptor name
+	ErrInvalidFdName = lxcError("invalid file descriptor name")
+
+	// ErrInvalidIDMap - invalid idmap entry
+	ErrInvalidIDMap = lxcError("invalid idmap entry")
+
+	// ErrInvalidImageReference - invalid image reference
+	ErrInvalidImageReference = lxcError("invalid image reference")
+
+	// ErrInvalidLimit - invalid resource limit
+	ErrInvalidLimit = lxcError("invalid resource limit")
+
+	// ErrInvalidNetwork - invalid network device
+	ErrInvalidNetwork = lxcError("invalid network device")
+
+	// ErrInvalidOwner - invalid owner
+	ErrInvalidOwner = lxcError("invalid owner")
+
+	// ErrInvalidPoolSize - invalid pool size
+	ErrInvalidPoolSize = lxcError("invalid pool size")
+
+	// ErrInvalidProfile - invalid profile
+	ErrInvalidProfile = lxcError("invalid profile")
+
+	// ErrInvalidSeccompProfile - invalid seccomp profile
+	ErrInvalidSeccompProfile = lxcError("invalid seccomp profile")
+
+	// ErrInvalidTmpfs - invalid tmpfs mount
+	ErrInvalidTmpfs = lxcError("invalid tmpfs mount")
+
+	// ErrInvalidUUID - invalid UUID
+	ErrInvalidUUID = lxcError("invalid UUID")
+
+	// ErrInvalidVolatileKey - invalid volatile key
+	ErrInvalidVolatileKey = lxcError("invalid volatile key")
+
+	// ErrInvalidVolume - invalid volume
+	ErrInvalidVolume = lxcError("invalid volume")
+
+	// ErrIPAddresses - getting IP addresses of the container failed
+	ErrIPAddresses = lxcError("getting IP addresses of the container failed")
+
+	// ErrIPAddress - getting IP address on the interface of the container failed
+	ErrIPAddress = lxcError("getting IP address on the interface of the container failed")
+
+	// ErrIPv4Addresses - getting IPv4 addresses of the container failed
+	ErrIPv4Addresses = lxcError("getting IPv4 addresses of the container failed")
+
+	// ErrIPv6Addresses - getting IPv6 addresses of the container failed
+	ErrIPv6Addresses = lxcError("getting IPv6 addresses of the container failed")
+
+	// ErrKMemLimit - your kernel does not support cgroup kernel memory controller
+	ErrKMemLimit = lxcError("your kernel does not support cgroup kernel memory controller")
+
+	// ErrListFailed - listing the containers failed
+	ErrListFailed = lxcError("listing the containers failed")
+
+	// ErrLoadConfigFailed - loading config file for the container failed
+	ErrLoadConfigFailed = lxcError("loading config file for the container failed")
+
+	// ErrMemLimit - your kernel does not support cgroup memory controller
+	ErrMemLimit = lxcError("your kernel does not support cgroup memory controller")
+
+	// ErrMemorySwapLimit - your kernel does not support cgroup swap controller
+	ErrMemorySwapLimit = lxcError("your kernel does not support cgroup swap controller")
+
+	// ErrMethodNotAllowed - the requested method is not currently supported with unprivileged containers
+	ErrMethodNotAllowed = lxcError("the requested method is not currently supported with unprivileged containers")
+
+	// ErrMountRootfsFailed - mounting the root filesystem of the container failed
+	ErrMountRootfsFailed = lxcError("mounting the root filesystem of the container failed")
+
+	// ErrNUMANodeNotFound - NUMA node not found on the host
+	ErrNUMANodeNotFound = lxcError("NUMA node not found on the host")
+
+	// ErrNetNSStats - getting the network namespace statistics failed
+	ErrNetNSStats = lxcError("getting the network namespace statistics failed")
+
+	// ErrNewFailed - allocating the container failed
+	ErrNewFailed = lxcError("allocating the container failed")
+
+	// ErrNoConsoleBuffer - container has no console ring buffer
+	ErrNoConsoleBuffer = lxcError("container has no console ring buffer (lxc.console.buffer.size)")
+
+	// ErrNoLogFile - container has no log file
+	ErrNoLogFile = lxcError("container has no log file (lxc.log.file)")
+
+	// ErrNoNotifySocket - container has no notify socket
+	ErrNoNotifySocket = lxcError("container has no notify socket")
+
+	// ErrNoSnapshot - container has no snapshot
+	ErrNoSnapshot = lxcError("container has no snapshot")
+
+	// ErrNoSwap - container has no swap
+	ErrNoSwap = lxcError("container has no swap")
+
+	// ErrNotDefined - container is not defined
+	ErrNotDefined = lxcError("container is not defined")
+
+	// ErrNotFrozen - container is not frozen
+	ErrNotFrozen = lxcError("container is not frozen")
+
+	// ErrNotHibernated - container is not hibernated
+	ErrNotHibernated = lxcError("container is not hibernated")
+
+	// ErrNotRunning - container is not running
+	ErrNotRunning = lxcError("container is not running")
+
+	// ErrNotSupported - method is not supported by this LXC version
+	ErrNotSupported = lxcError("method is not supported by this LXC version")
+
+	// ErrOperationDenied - operation was denied by the interceptor
+	ErrOperationDenied = lxcError("operation was denied by the interceptor")
+
+	// ErrPlatformNotFound - image is not available for the platform
+	ErrPlatformNotFound = lxcError("image is not available for the platform")
+
+	// ErrPoolClosed - pool is closed
+	ErrPoolClosed = lxcError("pool is closed")
+
+	// ErrPortConflict - host port is in use
+	ErrPortConflict = lxcError("host port is in use")
+
+	// ErrPressureStats - your kernel does not support pressure stall information
+	ErrPressureStats = lxcError("your kernel does not support pressure stall information")
+
+	// ErrProfileNotFound - profile not found
+	ErrProfileNotFound = lxcError("profile not found")
+
+	// ErrProjectNotFound - project not found
+	ErrProjectNotFound = lxcError("project not found")
+
+	// ErrProjectQuota - project quota exceeded
+	ErrProjectQuota = lxcError("project quota exceeded")
+
+	// ErrPullFailed - pulling the image failed
+	ErrPullFailed = lxcError("pulling the image failed")
+
+	// ErrRDMALimit - your kernel does not support cgroup rdma controller
+	ErrRDMALimit = lxcError("your kernel does not support cgroup rdma controller")
+
+	// ErrRealtimeLimit - your kernel does not support realtime group scheduling
+	ErrRealtimeLimit = lxcError("your kernel does not support realtime group scheduling")
+
+	// ErrRegistryFailed - registry request failed
+	ErrRegistryFailed = lxcError("registry request failed")
+
+	// ErrRebootFailed - rebooting the container failed
+	ErrRebootFailed = lxcError("rebooting the container failed")
+
+	// ErrRemoveDeviceNodeFailed - removing device from container failed
+	ErrRemoveDeviceNodeFailed = lxcError("removing device from container failed")
+
+	// ErrRenameFailed - renaming the container failed
+	ErrRenameFailed = lxcError("renaming the container failed")
+
+	// ErrRestoreFailed - restore failed
+	ErrRestoreFailed = lxcError("restore failed")
+
+	// ErrRestoreSnapshotFailed - restoring the container failed
+	ErrRestoreSnapshotFailed = lxcError("restoring the container failed")
+
+	// ErrRotateLogFailed - rotating the log failed
+	ErrRotateLogFailed = lxcError("rotating the log failed")
+
+	// ErrRuntimeCheckFailed - host doesn't provide what liblxc needs at runtime
+	ErrRuntimeCheckFailed = lxcError("host doesn't provide what liblxc needs at runtime")
+
+	// ErrSaveConfigFailed - saving config file for the container failed
+	ErrSaveConfigFailed = lxcError("saving config file for the container failed")
+
+	// ErrSettingCgroupItemFailed - setting cgroup item for the container failed
+	ErrSettingCgroupItemFailed = lxcError("setting cgroup item for the container failed")
+
+	// ErrSettingConfigItemFailed - setting config item for the container failed
+	ErrSettingConfigItemFailed = lxcError("setting config item for the container failed")
+
+	// ErrSettingConfigPathFailed - setting config file for the container failed
+	ErrSettingConfigPathFailed = lxcError("setting config file for the container failed")
+
+	// ErrSettingHugeTLBLimitFailed - setting hugetlb limit for the container failed
+	ErrSettingHugeTLBLimitFailed = lxcError("setting hugetlb limit for the container failed")
+
+	// ErrSettingKMemoryLimitFailed - setting kernel memory limit for the container failed
+	ErrSettingKMemoryLimitFailed = lxcError("setting kernel memory limit for the container failed")
+
+	// ErrSettingMemoryLimitFailed - setting memory limit for the container failed
+	ErrSettingMemoryLimitFailed = lxcError("setting memory limit for the container failed")
+
+	// ErrSettingMemorySwapLimitFailed - setting memory+swap limit for the container failed
+	ErrSettingMemorySwapLimitFailed = lxcError("setting memory+swap limit for the container failed")
+
+	// ErrSettingRDMALimitFailed - setting rdma limit for the container failed
+	ErrSettingRDMALimitFailed = lxcError("setting rdma limit for the container failed")
+
+	// ErrSettingRlimitsFailed - setting the resource limits of the command failed
+	ErrSettingRlimitsFailed = lxcError("setting the resource limits of the command failed")
+
+	// ErrSettingSoftMemoryLimitFailed - setting soft memory limit for the container failed
+	ErrSettingSoftMemoryLimitFailed = lxcError("setting soft memory limit for the container failed")
+
+	// ErrShiftFailed - shifting the ownership of the file failed
+	ErrShiftFailed = lxcError("shifting the ownership of the file failed")
+
+	// ErrShutdownFailed - shutting down the container failed
+	ErrShutdownFailed = lxcError("shutting down the container failed")
+
+	// ErrSocketNameTooLong - the name of the unix socket is too long
+	ErrSocketNameTooLong = lxcError("the name of the unix socket is too long")
+
+	// ErrSoftMemLimit - your kernel does not support cgroup memory controller
+	ErrSoftMemLimit = lxcError("your kernel does not support cgroup memory controller")
+
+	// ErrStartFailed - starting the container failed
+	ErrStartFailed = lxcError("starting the container failed")
+
+	// ErrStopFailed - stopping the container failed
+	ErrStopFailed = lxcError("stopping the container failed")
+
+	// ErrSwapFailed - setting up the swap of the container failed
+	ErrSwapFailed = lxcError("setting up the swap of the container failed")
+
+	// ErrTemplateNotAllowed - unprivileged users only allowed to use "download" template
+	ErrTemplateNotAllowed = lxcError("unprivileged users only allowed to use \"download\" template")
+
+	// ErrTransactionDone - the config transaction was already committed or rolled back
+	ErrTransactionDone = lxcError("the config transaction was already committed or rolled back")
+
+	// ErrUUIDNotFound - no container with the UUID
+	ErrUUIDNotFound = lxcError("no container with the UUID")
+
+	// ErrUnfreezeFailed - unfreezing the container failed
+	ErrUnfreezeFailed = lxcError("unfreezing the container failed")
+
+	// ErrUnknownDependency - unknown container in dependencies
+	ErrUnknownDependency = lxcError("unknown container in dependencies")
+
+	// ErrUnknownAddressFamily - unknown address family
+	ErrUnknownAddressFamily = lxcError("unknown address family")
+
+	// ErrUnknownBackendStore - unknown backend type
+	ErrUnknownBackendStore = lxcError("unknown backend type")
+
+	// ErrUnknownConfigItem - unknown config item
+	ErrUnknownConfigItem = lxcError("unknown config item")
+
+	// ErrUnsafeRootfsPath - refusing to follow a symlink in the rootfs
+	ErrUnsafeRootfsPath = lxcError("refusing to follow a symlink in the rootfs")
+
+	// ErrUnstableState - only STOPPED, RUNNING and FROZEN can be ensured
+	ErrUnstableState = lxcError("only STOPPED, RUNNING and FROZEN can be ensured")
+
+	// ErrUserNotFound - user not found in the container
+	ErrUserNotFound = lxcError("user not found in the container")
+
+	// ErrVFInUse - virtual function is in use
+	ErrVFInUse = lxcError("virtual function is in use")
+
+	// ErrVerificationFailed - verifying the image failed
+	ErrVerificationFailed = lxcError("verifying the image failed")
+
+	// ErrVolumeExists - volume already exists
+	ErrVolumeExists = lxcError("volume already exists")
+
+	// ErrVolumeIDMapMismatch - volume is shifted to a different idmap
+	ErrVolumeIDMapMismatch = lxcError("volume is shifted to a different idmap")
+
+	// ErrVolumeInUse - volume is in use
+	ErrVolumeInUse = lxcError("volume is in use")
+
+	// ErrVolumeNotFound - volume not found
+	ErrVolumeNotFound = lxcError("volume not found")
+
+	// ErrWatchdogTimeout - container heartbeat timed out
+	ErrWatchdogTimeout = lxcError("container heartbeat timed out")
+
+	// ErrReleaseFailed - releasing the container failed
+	ErrReleaseFailed = lxcError("releasing the container failed")
+)
+
+// Event is a single event published on an EventBus. Only the field of its
+// type is set among State, OOMKills, Hook, Audit and Idle.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      EventType `json:"type"`
+	Container string    `json:"container"`
+	LXCPath   string    `json:"lxcpath,omitempty"`
+	// State is the new state of the container.
+	State string `json:"state,omitempty"`
+	// OOMKills is the number of processes killed since the previous
+	// event.
+	OOMKills int          `json:"oom_kills,omitempty"`
+	Hook     *HookEvent   `json:"hook,omitempty"`
+	Audit    *AuditRecord `json:"audit,omitempty"`
+	// Idle is how long the container was idle.
+	Idle time.Duration `json:"idle,omitempty"`
+}
+
+const (
+	// EventState reports a container changing state.
+	EventState EventType = "state"
+	// EventOOM reports processes of a container killed by the OOM killer.
+	EventOOM EventType = "oom"
+	// EventHook reports a hook of a container running.
+	EventHook EventType = "hook"
+	// EventAudit reports a mutating operation on a container.
+	EventAudit EventType = "audit"
+	// EventIdle reports an idle container being shut down, see WatchIdle.
+	EventIdle EventType = "idle"
+)
+
+// EventBus delivers the state, OOM, hook and audit events of containers to
+// the subscribers whose filter matches, so they consume one stream. Sources
+// are added with WatchState, WatchHooks and by setting the bus as audit
+// sink, Publish adds events of other sources. Errors of sinks are dropped.
+type EventBus struct {
+}
+
+// Subscribe delivers the events matching the filter to the sink until the
+// returned function is called.
+func (b *EventBus) Subscribe(filter EventFilter, sink EventSink) (_ func()) {
+	return
+}
+
+// Publish delivers the event to the matching subscribers, in the calling
+// goroutine. The time is set if zero.
+func (b *EventBus) Publish(e Event) {
+	return
+}
+
+// Record publishes the audit record, which makes the bus an AuditSink:
+//
+//	SetAuditSink(bus)
+func (b *EventBus) Record(record AuditRecord) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WatchHooks publishes the events of the hooks of the container, see
+// HookEvents, until the container is released or the bus closed.
+func (b *EventBus) WatchHooks(c *Container, types ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WatchState publishes the state changes and OOM kills of the container,
+// checked every interval, until the bus is closed.
+func (b *EventBus) WatchState(c *Container, interval time.Duration) {
+	return
+}
+
+// Close stops watching the containers, it returns once the watchers
+// stopped. Publish keeps working.
+func (b *EventBus) Close() {
+	return
+}
+
+// EventChannel is an EventSink delivering the events on a channel. Events
+// are dropped while the channel is full, so a slow reader doesn't hold up
+// the bus.
+type EventChannel struct {
+}
+
+// Send queues the event, dropping it if the channel is full.
+func (ch *EventChannel) Send(e Event) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Events returns the channel receiving the events.
+func (ch *EventChannel) Events() (_ <-chan Event) {
+	return
+}
+
+// EventFile is an EventSink appending JSON lines to a file.
+type EventFile struct {
+}
+
+// Close closes the file.
+func (ef *EventFile) Close() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// EventFilter selects the events delivered to a subscriber, an empty
+// field matches all events.
+type EventFilter struct {
+	Types      []EventType
+	Containers []string
+}
+
+// Match returns whether the event passes the filter.
+func (f EventFilter) Match(e Event) (_ bool) {
+	return
+}
+
+// EventFunc is an EventSink calling the function for each event.
+type EventFunc func(e Event) error
+
+// Send calls f(e).
+func (f EventFunc) Send(e Event) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// EventHandler returns an http.Handler streaming the events of the bus
+// matching the filter as JSON, as server-sent events or, if the request
+// asks to upgrade, as websocket text messages. Clients narrow the stream
+// with the container and type query parameters, e.g.
+// /events?container=web&type=state&type=oom. Events are dropped while a
+// client falls behind.
+func EventHandler(bus *EventBus, filter EventFilter) (_ http.Handler) {
+	return
+}
+
+// EventSink receives the events of a subscription.
+type EventSink interface {
+	Send(e Event) error
+}
+
+// EventType is the kind of event published on an EventBus.
+type EventType string
+
+const (
+	// FEATURE_MEM_TRACK - memory tracking support
+	FEATURE_MEM_TRACK CriuFeatures = 1 << iota
+
+	// FEATURE_LAZY_PAGES - lazy pages support
+	FEATURE_LAZY_PAGES
+)
+
+const (
+	// FreezeNone means the container wasn't frozen through this Container
+	FreezeNone FreezeMethod = iota
+	// FreezeLiblxc means the container was frozen by liblxc
+	FreezeLiblxc
+	// FreezeCgroup means the container was frozen by writing cgroup.freeze
+	FreezeCgroup
+)
+
+// FreezeMethod type specifies how a container was frozen.
+type FreezeMethod int
+
+// FreezeMethod as string
+func (m FreezeMethod) String() (_ string) {
+	return
+}
+
+// GlobalConfigItem returns the value of the given global config key.
+func GlobalConfigItem(name string) (_ string) {
+	return
+}
+
+// GlobalDownloadConfig returns the download configuration used by image pulls
+// that don't specify one.
+func GlobalDownloadConfig() (_ DownloadConfig) {
+	return
+}
+
+// Group represents an entry of /etc/group inside a container.
+type Group struct {
+	Name    string
+	GID     int
+	Members []string
+}
+
+// HasAPIExtension returns true if the extension is supported.
+func HasAPIExtension(extension string) (_ bool) {
+	return
+}
+
+// HasApiExtension returns true if the extension is supported.
+// Deprecated: Please use HasAPIExtension instead.
+func HasApiExtension(extension string) (_ bool) {
+	return
+}
+
+// HealthCheck returns nil once a started container is ready to be depended
+// on, or an error if it won't become ready before ctx is done.
+type HealthCheck func(ctx context.Context, c *Container) error
+
+// HibernateOptions type is used for defining how a container is hibernated.
+type HibernateOptions struct {
+	// Resident specifies how much memory is left resident, 0 to reclaim
+	// as much as possible.
+	Resident ByteSize
+	// CheckpointDirectory specifies where the container is checkpointed
+	// to before hibernating it, if set.
+	CheckpointDirectory string
+}
+
+// HookEvent represents a single execution of a container hook.
+type HookEvent struct {
+	// Container is the name of the container.
+	Container string
+	// Section is the config section of the hook, usually "lxc".
+	Section string
+	// Type is the hook type (e.g. "pre-start").
+	Type string
+	// Args holds the additional arguments passed to the hook.
+	Args []string
+	// Env holds the environment of the hook (LXC_ROOTFS_MOUNT, ...).
+	Env map[string]string
+}
+
+// HostResources returns a snapshot of the memory and CPUs of the host and of
+// the disk space of the given lxcpaths (default: DefaultConfigPath()).
+func HostResources(lxcpath ...string) (_ HostResourcesInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// HostResourcesInfo represents the resources of the host.
+type HostResourcesInfo struct {
+	MemoryTotal ByteSize
+	// MemoryFree is the memory available without swapping.
+	MemoryFree ByteSize
+	CPUs       int
+	// Disk is the space of the filesystem of each lxcpath.
+	Disk map[string]DiskSpace
+}
+
+// HostVFs returns the SR-IOV virtual functions of the network cards of the
+// host.
+func HostVFs() (_ []VF, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// IDMap is the set of uid and gid mappings of an unprivileged container.
+type IDMap []IDMapEntry
+
+// ToHost maps an id as seen inside the container to the corresponding host id.
+func (m IDMap) ToHost(t IDType, id int64) (_ int64, _ bool) {
+	return
+}
+
+// ToContainer maps a host id to the corresponding id inside the container.
+func (m IDMap) ToContainer(t IDType, id int64) (_ int64, _ bool) {
+	return
+}
+
+// IDMapEntry maps a contiguous range of ids inside the container to a range
+// of ids on the host.
+type IDMapEntry struct {
+	Type   IDType
+	Nsid   int64
+	Hostid int64
+	Range  int64
+}
+
+// String returns the entry in lxc.idmap format, e.g. "u 0 100000 65536".
+func (e IDMapEntry) String() (_ string) {
+	return
+}
+
+// IDType specifies whether an idmap entry maps user or group ids.
+type IDType int
+
+// IDType as string
+func (t IDType) String() (_ string) {
+	return
+}
+
+const (
+	// IDTypeUID maps user ids
+	IDTypeUID IDType = iota + 1
+	// IDTypeGID maps group ids
+	IDTypeGID
+)
+
+// IPAddressOptions type is used for filtering the IP addresses of a container.
+type IPAddressOptions struct {
+	// Interface limits the addresses to the given interface. All interfaces
+	// but the loopback are used if not set.
+	Interface string
+	// Family limits the addresses to either "inet" or "inet6". Both are used if not set.
+	Family string
+	// Scope is the IPv6 scope id the addresses have to match (0 is global scope).
+	Scope int
+	// IncludeLinkLocal includes the IPv4 and IPv6 link-local addresses.
+	IncludeLinkLocal bool
+	// WaitForGlobal makes WaitIPAddressesWithOptions wait until a global
+	// unicast address shows up rather than returning on the first address.
+	WaitForGlobal bool
+}
+
+const (
+	// IPvlanBridge switches the traffic between the interfaces directly.
+	IPvlanBridge IPvlanIsolation = "bridge"
+	// IPvlanPrivate isolates the interface from the other ones.
+	IPvlanPrivate IPvlanIsolation = "private"
+	// IPvlanVEPA sends all traffic to the switch, which may reflect it.
+	IPvlanVEPA IPvlanIsolation = "vepa"
+)
+
+// IPvlanIsolation is how ipvlan interfaces on the same link communicate
+// (lxc.net.N.ipvlan.isolation).
+type IPvlanIsolation string
+
+const (
+	// IPvlanL2 switches on layer 2, the interfaces share the MAC address
+	// of the link.
+	IPvlanL2 IPvlanMode = "l2"
+	// IPvlanL3 routes on layer 3, the interfaces need static addresses.
+	IPvlanL3 IPvlanMode = "l3"
+	// IPvlanL3S routes on layer 3 through the netfilter hooks of the host.
+	IPvlanL3S IPvlanMode = "l3s"
+)
+
+// IPvlanMode is the layer ipvlan interfaces switch traffic on
+// (lxc.net.N.ipvlan.mode).
+type IPvlanMode string
+
+// IdlePolicy type is used for defining when an idle container is shut down
+// by WatchIdle.
+type IdlePolicy struct {
+	// Timeout specifies how long the container has to be idle.
+	Timeout time.Duration
+	// Interval specifies how often the activity is checked.
+	Interval time.Duration
+	// Traffic counts network traffic of the container as activity.
+	Traffic bool
+	// Processes counts processes besides init as activity.
+	Processes bool
+	// ShutdownTimeout specifies how long Shutdown waits before the
+	// container is stopped.
+	ShutdownTimeout time.Duration
+	// Bus receives an EventIdle event before an idle container is shut
+	// down, if set.
+	Bus *EventBus
+}
+
+// IdleWatcher shuts a container down once it's idle, see WatchIdle.
+type IdleWatcher struct {
+}
+
+// Stop stops watching the container and waits for the watcher to exit.
+func (w *IdleWatcher) Stop() {
+	return
+}
+
+// IdmappedMountsSupported returns true if both the kernel and liblxc support
+// idmapped mounts.
+func IdmappedMountsSupported() (_ bool) {
+	return
+}
+
+// ImageOptions type is used for defining the options of CreateFromImage.
+type ImageOptions struct {
+	// Platform of the image to pull, "<os>/<arch>[/<variant>]" (default: the host platform).
+	Platform string
+	// Username and Password used to authenticate against the registry.
+	Username string
+	Password string
+	// Insecure uses plain HTTP to talk to the registry.
+	Insecure bool
+	// HTTPClient used for the registry requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+	// Digest pins the manifest the reference resolves to ("sha256:<hex>").
+	Digest string
+	// CosignPublicKey is a PEM encoded public key the image must be signed
+	// with by cosign. GPG validation of linuxcontainers images is handled by
+	// the download template, see TemplateOptions.
+	CosignPublicKey []byte
+	// Retries is the number of times an interrupted blob download is resumed.
+	Retries int
+	// Download configures proxies, mirrors and throttling of the pull
+	// (default: LoadDownloadConfig() of the lxcpath of the container).
+	Download *DownloadConfig
+	// CacheDir keeps downloaded blobs, so an interrupted pull is resumed by
+	// the next one (default: a temporary directory removed afterwards).
+	CacheDir string
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// ImportFromLXD creates a plain LXC container from an LXD instance backup
+// (a tarball as created by "lxc export" or its extracted content with
+// index.yaml) or from an LXD storage volume holding backup.yaml and rootfs.
+// The LXD config keys and devices are translated where LXC has an
+// equivalent; the ones which couldn't be translated are returned so the
+// caller can report them. raw.lxc and disk devices are only imported when
+// opts allows them.
+// Caller needs to call Release() on the returned container.
+func ImportFromLXD(source string, opts LXDImportOptions) (_ *Container, _ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InheritableFds returns the fds of the calling process which processes
+// started through Attach, RunCommand and friends inherit, e.g. the log file
+// liblxc opened. Stdin, stdout and stderr aren't reported.
+func InheritableFds() (_ []int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// InspectConfig summarizes the configuration of a container.
+type InspectConfig struct {
+	Hostname  string    `json:"hostname,omitempty"`
+	Arch      string    `json:"arch,omitempty"`
+	Autostart bool      `json:"autostart"`
+	Networks  []Network `json:"networks,omitempty"`
+	// Volatile holds the values generated for the container, see
+	// Container.Volatile.
+	Volatile map[string]string `json:"volatile,omitempty"`
+}
+
+// InspectInfo describes a container, see Container.Inspect.
+type InspectInfo struct {
+	Name         string        `json:"name"`
+	LXCPath      string        `json:"lxcpath"`
+	ConfigFile   string        `json:"config_file"`
+	State        string        `json:"state"`
+	PID          int           `json:"pid,omitempty"`
+	BackingStore string        `json:"backing_store"`
+	Rootfs       string        `json:"rootfs,omitempty"`
+	IPAddresses  []string      `json:"ip_addresses,omitempty"`
+	Config       InspectConfig `json:"config"`
+	// Mounts holds the lxc.mount.entry items, in fstab format.
+	Mounts []string `json:"mounts,omitempty"`
+	// Limits holds the cgroup items of the config, e.g.
+	// lxc.cgroup2.memory.max.
+	Limits    map[string]string `json:"limits,omitempty"`
+	Usage     *InspectUsage     `json:"usage,omitempty"`
+	Snapshots []Snapshot        `json:"snapshots,omitempty"`
+}
+
+// InspectUsage is the resource usage of a running container.
+type InspectUsage struct {
+	Memory  ByteSize      `json:"memory"`
+	CPUTime time.Duration `json:"cpu_time"`
+}
+
+// Interceptor is called before an operation is executed, returning an error
+// vetoes it. It may inspect the container, e.g. through ConfigItem, but must
+// not call operations on it.
+type Interceptor func(op Operation) error
+
+// IsSupportedConfigItem returns true if the key belongs to a supported config item.
+func IsSupportedConfigItem(key string) (_ bool) {
+	return
+}
+
+// IsTransient returns true if the error is caused by a condition which
+// clears by itself, such as EAGAIN, EBUSY or EINTR.
+func IsTransient(err error) (_ bool) {
+	return
+}
+
+// KeyValue represents a single config item. Keys which can be set multiple
+// times (e.g. lxc.mount.entry) show up once per value.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// String returns the item in the config file format.
+func (kv KeyValue) String() (_ string) {
+	return
+}
+
+// LXDImportOptions type is used for defining the options of an import from LXD.
+type LXDImportOptions struct {
+	// Name of the new container (default: the name of the LXD instance).
+	Name string
+	// LXCPath the container is created in (default: DefaultConfigPath()).
+	LXCPath string
+	// RawLXC imports the raw.lxc key of the instance. It is skipped by
+	// default, the backup is untrusted and its hooks run as root on the host.
+	RawLXC bool
+	// DiskDevices imports the disk devices of the instance as bind mounts.
+	// They are skipped by default, they may bind any path of the host.
+	DiskDevices bool
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// ListByOwner returns the names of the defined containers owned by owner or
+// by owners in its namespace, e.g. "billing/api" for "billing".
+func ListByOwner(owner string, lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ListeningPort represents a socket accepting connections or datagrams.
+type ListeningPort struct {
+	// Protocol is "tcp", "tcp6", "udp" or "udp6".
+	Protocol string
+	Address  netip.AddrPort
+}
+
+// LoadDeviceProfile returns the named profile stored under lxcpath.
+func LoadDeviceProfile(name string, lxcpath ...string) (_ DeviceProfile, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LoadDownloadConfig returns the download configuration used for the
+// containers under lxcpath, GlobalDownloadConfig() if none is stored.
+func LoadDownloadConfig(lxcpath ...string) (_ DownloadConfig, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LoadProfile returns the named profile stored under lxcpath.
+func LoadProfile(name string, lxcpath ...string) (_ Profile, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LoadProject returns the named project stored under lxcpath.
+func LoadProject(name string, lxcpath ...string) (_ Project, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LoadVolume returns the named volume of lxcpath.
+func LoadVolume(name string, lxcpath ...string) (_ Volume, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LogLevel type specifies possible log levels.
+type LogLevel int
+
+func (l LogLevel) String() (_ string) {
+	return
+}
+
+// LogRotateOptions type is used for defining when and how log files are
+// rotated.
+type LogRotateOptions struct {
+	// MaxSize rotates the log once it grows beyond the size, never if 0.
+	MaxSize ByteSize
+	// MaxAge rotates the log once the previous rotation, or the creation
+	// of the log if it was never rotated, is older, never if 0.
+	MaxAge time.Duration
+	// Keep specifies the number of rotated logs kept, e.g. lxc.log.1 to
+	// lxc.log.5 for 5.
+	Keep int
+	// Compress gzips the rotated logs, e.g. lxc.log.1.gz.
+	Compress bool
+}
+
+// LogRotator rotates the log files and console log files of containers,
+// and any other log file added to it, e.g. of containers managed by a
+// supervisor. It's safe for concurrent use.
+type LogRotator struct {
+	// OnError is called with the errors of rotating a log, which don't
+	// stop the rotator. Errors are dropped if nil.
+	OnError func(path string, err error)
+}
+
+// AddFile adds a log file to rotate.
+func (r *LogRotator) AddFile(path string) {
+	return
+}
+
+// RemoveFile stops rotating a log file.
+func (r *LogRotator) RemoveFile(path string) {
+	return
+}
+
+// AddContainer adds the log file and console log file of the container
+// as configured now.
+func (r *LogRotator) AddContainer(c *Container) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// AddLXCPath rotates the logs of all containers of the lxcpath, the
+// default one if not given. The containers are looked up on every pass,
+// so containers created later are picked up.
+func (r *LogRotator) AddLXCPath(lxcpath ...string) {
+	return
+}
+
+// Rotate rotates the logs which are due once, it returns the ones it
+// rotated.
+func (r *LogRotator) Rotate() (_ []string) {
+	return
+}
+
+// Run rotates the logs which are due every interval until the context is
+// done.
+func (r *LogRotator) Run(ctx context.Context, interval time.Duration) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LookupByUUID returns the container with the UUID in lxcpath, the default
+// one if empty. Containers which have no UUID yet aren't found.
+// Caller needs to call Release() on the returned container to release resources.
+func LookupByUUID(lxcpath string, uuid string) (_ *Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// LookupQuirks returns the entries applying to the release of the
+// distribution, the one for all its releases first.
+func LookupQuirks(distribution string, release string) (_ []Quirk) {
+	return
+}
+
+const (
+	// MIGRATE_PRE_DUMP - pre-dump live migration phase
+	MIGRATE_PRE_DUMP = 0
+
+	// MIGRATE_DUMP - main live migration phase
+	MIGRATE_DUMP = 1
+
+	// MIGRATE_RESTORE - post migration phase
+	MIGRATE_RESTORE = 2
+
+	// MIGRATE_FEATURE_CHECK - migration feature check
+	MIGRATE_FEATURE_CHECK = 3
+)
+
+const (
+	// MacvlanPrivate isolates the interface from the other ones.
+	MacvlanPrivate MacvlanMode = "private"
+	// MacvlanVEPA sends all traffic to the switch, which may reflect it.
+	MacvlanVEPA MacvlanMode = "vepa"
+	// MacvlanBridge switches the traffic between the interfaces directly.
+	MacvlanBridge MacvlanMode = "bridge"
+	// MacvlanPassthru hands the link over to a single interface.
+	MacvlanPassthru MacvlanMode = "passthru"
+)
+
+// MacvlanMode is how macvlan interfaces on the same link communicate
+// (lxc.net.N.macvlan.mode).
+type MacvlanMode string
+
+// MemStats is the memory usage of a container.
+type MemStats struct {
+	// Usage is the memory used, including the page cache.
+	Usage ByteSize
+	// Limit is the memory limit, math.MaxInt64 if unlimited.
+	Limit ByteSize
+	// Swap is the swap used, 0 without swap accounting.
+	Swap ByteSize
+	// Anon is the anonymous memory, e.g. the heaps of the processes.
+	Anon ByteSize
+	// File is the page cache.
+	File ByteSize
+}
+
+// MigrateOptions type is used for defining migrate options.
+type MigrateOptions struct {
+	Directory       string
+	PredumpDir      string
+	ActionScript    string
+	Verbose         bool
+	Stop            bool
+	PreservesInodes bool
+	GhostLimit      uint64
+	FeaturesToCheck CriuFeatures
+}
+
+// NUMANode describes a NUMA node of the host.
+type NUMANode struct {
+	ID   int
+	CPUs []int
+	// Memory is the memory attached to the node.
+	Memory ByteSize
+	// Distances are the relative access costs from this node to each node,
+	// by node id.
+	Distances []int
+}
+
+// NetNSStats represents the socket statistics of a network namespace.
+type NetNSStats struct {
+	// TCP and UDP are the numbers of open sockets, over IPv4 and IPv6.
+	TCP int
+	UDP int
+	// TCPStates are the numbers of TCP sockets per state, e.g.
+	// "ESTABLISHED" or "TIME_WAIT".
+	TCPStates map[string]int
+	// Conntrack is the number of conntrack entries, -1 if conntrack isn't
+	// loaded.
+	Conntrack int
+	// Listening are the TCP sockets listening and the bound UDP sockets.
+	Listening []ListeningPort
+}
+
+// Network is a network device of a container.
+type Network struct {
+	// Index is the N of lxc.net.N, set by Networks.
+	Index int
+	Type  NetworkType
+	// Link is the host interface the device is created on or moved from.
+	Link string
+	// Name is the name of the interface in the container.
+	Name   string
+	HWAddr string
+	// MTU of the interface, 0 for the default.
+	MTU int
+	// Up brings the interface up when the container starts.
+	Up bool
+	// MacvlanMode of a macvlan device, liblxc defaults to private.
+	MacvlanMode MacvlanMode
+	// IPvlanMode of an ipvlan device, liblxc defaults to l3.
+	IPvlanMode IPvlanMode
+	// IPvlanIsolation of an ipvlan device, liblxc defaults to bridge.
+	IPvlanIsolation IPvlanIsolation
+}
+
+const (
+	// NetworkEmpty only creates the loopback interface.
+	NetworkEmpty NetworkType = "empty"
+	// NetworkNone shares the network namespace of the host.
+	NetworkNone NetworkType = "none"
+	// NetworkVeth creates a veth pair, the host side attached to Link.
+	NetworkVeth NetworkType = "veth"
+	// NetworkPhys moves the host interface Link into the container.
+	NetworkPhys NetworkType = "phys"
+	// NetworkMacvlan creates a macvlan interface on top of Link.
+	NetworkMacvlan NetworkType = "macvlan"
+	// NetworkIPvlan creates an ipvlan interface on top of Link.
+	NetworkIPvlan NetworkType = "ipvlan"
+	// NetworkVlan creates a vlan interface on top of Link.
+	NetworkVlan NetworkType = "vlan"
+)
+
+// NetworkType is the type of a network device (lxc.net.N.type).
+type NetworkType string
+
+// NewActivator listens on the socket of the options for the container.
+// Serve accepts the connections.
+func NewActivator(c *Container, opts ActivatorOptions) (_ *Activator, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewApplicationContainer defines a container running cmd from the
+// directory rootfs instead of an init system, e.g. to run a single service
+// like runc would. It writes a minimal configuration, see
+// ApplicationOptions for the optional settings. The container is started
+// with Start and stops once cmd exits.
+// Caller needs to call Release() on the returned container to release resources.
+func NewApplicationContainer(name string, rootfs string, cmd []string, opts ...ApplicationOptions) (_ *Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewAuditWriter returns an AuditSink writing one JSON object per record to w.
+func NewAuditWriter(w io.Writer) (_ AuditSink) {
+	return
+}
+
+// NewContainer returns a new container struct.
+// Caller needs to call Release() on the returned container to release its resources.
+func NewContainer(name string, lxcpath ...string) (_ *Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewDependencyManager returns an empty DependencyManager.
+func NewDependencyManager() (_ *DependencyManager) {
+	return
+}
+
+// NewEventBus returns an EventBus without subscribers.
+func NewEventBus() (_ *EventBus) {
+	return
+}
+
+// NewEventChannel returns an EventChannel buffering size events.
+func NewEventChannel(size int) (_ *EventChannel) {
+	return
+}
+
+// NewEventWriter returns an EventSink writing one JSON object per event to
+// w.
+func NewEventWriter(w io.Writer) (_ EventSink) {
+	return
+}
+
+// NewLogRotator returns a LogRotator rotating logs according to the
+// options. Set Compress to gzip the rotated logs.
+func NewLogRotator(opts LogRotateOptions) (_ *LogRotator) {
+	return
+}
+
+// NewSessionRecorder writes the header of a recording of a terminal of the
+// given size to w and returns the recorder writing the events to it.
+func NewSessionRecorder(w io.Writer, width int, height int) (_ *SessionRecorder, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewVXLAN returns the VXLAN overlay described by opts. It doesn't touch the
+// host until Up is called.
+func NewVXLAN(opts VXLANOptions) (_ *VXLAN, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewWarmPool starts filling a pool of clones of base, which must be
+// stopped. The clones are named "<prefix><n>".
+func NewWarmPool(base *Container, opts WarmPoolOptions) (_ *WarmPool, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NewWireGuardMesh returns the mesh described by opts. It doesn't touch the
+// host until Up is called.
+func NewWireGuardMesh(opts WireGuardOptions) (_ *WireGuardMesh, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// NvidiaOptions type is used for defining the NVIDIA runtime options.
+type NvidiaOptions struct {
+	// Devices is the list of GPU indices exposed to the container (NVIDIA_VISIBLE_DEVICES).
+	// All GPUs are exposed if empty.
+	Devices []int
+	// DriverCapabilities is the list of driver features exposed to the container (NVIDIA_DRIVER_CAPABILITIES).
+	DriverCapabilities []string
+	// RequireCUDA is a constraint on the CUDA version required by the container (NVIDIA_REQUIRE_CUDA).
+	RequireCUDA string
+	// Hook is the path of the LXC nvidia mount hook which mounts the driver libraries into the container.
+	Hook string
+}
+
+// OpenAuditFile opens (or creates) the file at path for appending records.
+func OpenAuditFile(path string) (_ *AuditFile, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// OpenEventFile opens (or creates) the file at path for appending events.
+func OpenEventFile(path string) (_ *EventFile, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Operation describes a mutating operation about to be executed.
+type Operation struct {
+	// Name is the name of the method, e.g. "Destroy" or "SetConfigItem"
+	Name      string
+	Container *Container
+	Args      []string
+}
+
+// OperationPolicy configures retrying lifecycle operations (Start,
+// StartWithArgs, Stop, Shutdown, Reboot, Freeze and Unfreeze) which failed
+// for a transient reason, e.g. because the container was still stopping.
+type OperationPolicy struct {
+	// Attempts is the maximum number of attempts, 0 or 1 disables
+	// retrying.
+	Attempts int
+	// Backoff is the delay before the second attempt, doubled after each
+	// attempt, 100ms if 0.
+	Backoff time.Duration
+	// MaxBackoff caps the delay, unlimited if 0.
+	MaxBackoff time.Duration
+	// Retryable decides whether a failed attempt is retried,
+	// Container.Retryable if nil.
+	Retryable func(c *Container, err error) bool
+}
+
+// OrphanVolumes returns the volumes of lxcpath which aren't attached to any
+// defined container, either never attached, detached from all of them or
+// left behind by destroyed containers.
+func OrphanVolumes(lxcpath ...string) (_ []Volume, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Overlay is a network spanning several hosts which containers are attached
+// to, e.g. a VXLAN.
+type Overlay interface {
+	// Up creates the interfaces of the overlay on the host.
+	Up() error
+	// Down removes the interfaces of the overlay from the host.
+	Down() error
+	// Attach adds a network device connected to the overlay to the
+	// container and returns its index.
+	Attach(c *Container) (int, error)
+}
+
+// ParseBytes parses a byte size string. A byte size string is a number followed by
+// a unit suffix, such as "1024B" or "1 MB". Valid byte units are "B", "KB",
+// "MB", "GB", "TB", "PB" and "EB". You can also use the long
+// format of units, such as "kilobyte" or "kilobytes".
+func ParseBytes(s string) (_ ByteSize, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ParseDeviceRule parses a device cgroup rule such as "c 1:3 rwm" or "a".
+func ParseDeviceRule(s string) (_ DeviceRule, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ParseIDMap parses lxc.idmap values such as "u 0 100000 65536".
+func ParseIDMap(values []string) (_ IDMap, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Personality allows to set the architecture for the container.
+type Personality int64
+
+// PortForward is a host port forwarded to the container, e.g. by an
+// iptables DNAT rule or a proxy of the caller.
+type PortForward struct {
+	// Protocol is "tcp" or "udp".
+	Protocol string
+	// Listen is the address and port on the host, an unspecified address
+	// for all addresses of the host.
+	Listen netip.AddrPort
+	// Port is the port in the container.
+	Port uint16
+}
+
+// String returns the forward in the form "tcp 0.0.0.0:80 -> 8080".
+func (p PortForward) String() (_ string) {
+	return
+}
+
+// Preset is a bundle of config items applied to a container as a unit.
+type Preset struct {
+	Name   string
+	Config []KeyValue
+}
+
+// Presets are the built-in presets. Their config items are the documented
+// deltas applied to the configuration of the container, keys accumulating
+// their values (e.g. lxc.mount.auto) are extended, an empty value clears a
+// key.
+var Presets = struct {
+	// Hardened drops dangerous capabilities, confines the container with
+	// the generated apparmor profile and the LXC default seccomp policy,
+	// mounts /proc, /sys and the cgroups mixed (read-only where possible)
+	// and sets no_new_privs for init:
+	//
+	//	lxc.cap.drop = mac_admin mac_override sys_module sys_rawio sys_time
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_nesting = 0
+	//	lxc.seccomp.profile = /usr/share/lxc/config/common.seccomp
+	//	lxc.mount.auto = proc:mixed sys:mixed cgroup:mixed
+	//	lxc.no_new_privs = 1
+	Hardened Preset
+
+	// Compatibility relaxes the confinement for old distributions and
+	// payloads expecting writable /proc and /sys:
+	//
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_incomplete = 1
+	//	lxc.mount.auto = proc:rw sys:rw cgroup:rw
+	//	lxc.no_new_privs = 0
+	Compatibility Preset
+
+	// Nested allows running containers inside the container:
+	//
+	//	lxc.apparmor.profile = generated
+	//	lxc.apparmor.allow_nesting = 1
+	//	lxc.mount.auto = proc:rw sys:rw cgroup:rw:force
+	Nested Preset
+}{
+	Hardened: Preset{
+		Name: "hardened",
+		Config: []KeyValue{
+			{"lxc.cap.drop", "mac_admin mac_override sys_module sys_rawio sys_time"},
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_nesting", "0"},
+			{"lxc.seccomp.profile", "/usr/share/lxc/config/common.seccomp"},
+			{"lxc.mount.auto", "proc:mixed sys:mixed cgroup:mixed"},
+			{"lxc.no_new_privs", "1"},
+		},
+	},
+	Compatibility: Preset{
+		Name: "compatibility",
+		Config: []KeyValue{
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_incomplete", "1"},
+			{"lxc.mount.auto", "proc:rw sys:rw cgroup:rw"},
+			{"lxc.no_new_privs", "0"},
+		},
+	},
+	Nested: Preset{
+		Name: "nested",
+		Config: []KeyValue{
+			{"lxc.apparmor.profile", "generated"},
+			{"lxc.apparmor.allow_nesting", "1"},
+			{"lxc.mount.auto", "proc:rw sys:rw cgroup:rw:force"},
+		},
+	},
+}
+
+// Pressure represents the pressure stall information of a single resource.
+// Full is always zero for the CPU on kernels before 5.13.
+type Pressure struct {
+	Some PressureValues
+	Full PressureValues
+}
+
+// PressureStats represents the pressure stall information of a container.
+type PressureStats struct {
+	CPU    Pressure
+	Memory Pressure
+	IO     Pressure
+}
+
+// PressureValues represents one line of a pressure stall information file:
+// the share of wall time some (or all) tasks were stalled, averaged over 10,
+// 60 and 300 seconds, in percent, and the total stall time.
+type PressureValues struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  time.Duration
+}
+
+// ProcessInfo describes a process of a running container.
+type ProcessInfo struct {
+	// PID and PPID are the host process ids.
+	PID  int
+	PPID int
+	// Command is the name of the executable, truncated by the kernel to 15
+	// characters.
+	Command string
+	// State is the state as reported by ps, e.g. "R" or "S".
+	State string
+	// CPUTime is the CPU time used by the process.
+	CPUTime time.Duration
+	// CPUPercent is the usage of one CPU since the previous sample.
+	CPUPercent float64
+	// Memory is the resident memory of the process.
+	Memory ByteSize
+}
+
+// Profile is a named set of config items and devices shared by containers,
+// similar to LXD profiles.
+type Profile struct {
+	Name string `json:"name"`
+	// Config are the config items set by the profile, in order.
+	Config []KeyValue `json:"config,omitempty"`
+	// Devices are host device nodes allowed and bind mounted into the
+	// container at the same path.
+	Devices []string `json:"devices,omitempty"`
+}
+
+// Profiles returns the names of the profiles stored under lxcpath.
+func Profiles(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Project puts aggregate quotas on the containers of an owner namespace, the
+// containers owned by its name or by owners below it (see
+// Container.SetOwner). A quota of 0 is unlimited.
+type Project struct {
+	// Name is the owner namespace, e.g. "billing".
+	Name string `json:"name"`
+	// Memory is the total of the memory limits of the running containers.
+	Memory ByteSize `json:"memory,omitempty"`
+	// CPUs is the total of the CPU limits of the running containers.
+	CPUs float64 `json:"cpus,omitempty"`
+	// Disk is the total disk space used by the containers and their
+	// snapshots, see Container.DiskUsage.
+	Disk ByteSize `json:"disk,omitempty"`
+}
+
+// ProjectUsage returns what the containers of the named project stored
+// under lxcpath use of its quotas.
+func ProjectUsage(name string, lxcpath ...string) (_ ProjectUsageInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ProjectUsageInfo describes what the containers of a project use of its
+// quotas.
+type ProjectUsageInfo struct {
+	Containers []string
+	Running    []string
+	Memory     ByteSize
+	CPUs       float64
+	Disk       ByteSize
+}
+
+// Projects returns the names of the projects stored under lxcpath.
+func Projects(lxcpath ...string) (_ []string, err error) {
+	err = ErrNotSupported
+	return
+}
+
+const (
+	// Quiet makes some API calls not to write anything to stdout
+	Quiet Verbosity = 1 << iota
+	// Verbose makes some API calls write to stdout
+	Verbose
+)
+
+// Quirk is configuration a distribution needs to run in a container.
+type Quirk struct {
+	// Distribution is the ID of the distribution, e.g. "ubuntu".
+	Distribution string
+	// Release is the version or code name of the release, e.g. "7" or
+	// "jammy", empty for all releases.
+	Release string
+	Config  []KeyValue
+	// Reason explains why the configuration is needed.
+	Reason string
+}
+
+// Quirks returns the entries of the quirks database, sorted by distribution
+// and release.
+func Quirks() (_ []Quirk) {
+	return
+}
+
+// RDMAResources represents the RDMA resources of a single device as found in
+// the rdma.max and rdma.current cgroup files. A value of math.MaxInt64 means
+// unlimited.
+type RDMAResources struct {
+	Device     string
+	HCAHandles int64
+	HCAObjects int64
+}
+
+// String returns the resources in the format expected by rdma.max.
+func (r RDMAResources) String() (_ string) {
+	return
+}
+
+// ROCmOptions type is used for defining the AMD ROCm runtime options.
+type ROCmOptions struct {
+	// Path is the ROCm installation on the host, bind mounted read-only into the container.
+	// Nothing is mounted if empty.
+	Path string
+}
+
+// RealtimeOptions type is used for defining the realtime scheduling of a
+// container.
+type RealtimeOptions struct {
+	// Runtime specifies the CPU time realtime tasks of the container get
+	// per Period (cpu.rt_runtime_us).
+	Runtime time.Duration
+	// Period specifies the period of the realtime bandwidth
+	// (cpu.rt_period_us).
+	Period time.Duration
+	// Priority specifies the highest SCHED_FIFO and SCHED_RR priority
+	// processes of the container can set (prlimit rtprio).
+	Priority int
+	// CPUTime limits the CPU time a realtime task may use without blocking
+	// (prlimit rttime), 0 for unlimited.
+	CPUTime time.Duration
+}
+
+// RefreshRuntimeInfo collects the runtime information again, e.g. after the
+// global LXC config was edited, and returns it. Runtime values returned
+// earlier keep the old information.
+func RefreshRuntimeInfo() (_ *Runtime) {
+	return
+}
+
+// Release decrements the reference counter of the container object.
+func Release(c *Container) (_ bool) {
+	return
+}
+
+// RestoreOptions type is used for defining restore options for CRIU.
+type RestoreOptions struct {
+	Directory string
+	Verbose   bool
+}
+
+// Rlimit represents a resource limit such as the maximum number of open
+// files.
+type Rlimit struct {
+	// Resource is the name of the resource as used by lxc.prlimit, e.g.
+	// "nofile" or "core".
+	Resource string
+	Soft     uint64
+	Hard     uint64
+}
+
+// String returns the limit in the format of lxc.prlimit values.
+func (r Rlimit) String() (_ string) {
+	return
+}
+
+// RlimitInfinity is the value of an unlimited resource.
+const RlimitInfinity = ^uint64(0)
+
+// RootfsOptions type is used for defining how the rootfs of an unprivileged
+// container is prepared.
+type RootfsOptions struct {
+	// UseIdmappedMounts has liblxc mount the rootfs idmapped to the
+	// container's idmap instead of chowning every file. It falls back to
+	// chown-shifting if the kernel or liblxc lack idmapped mounts support.
+	UseIdmappedMounts bool
+}
+
+// Runtime describes the liblxc the process runs with and the host setup it
+// detected. It's collected once by RuntimeInfo and can be shared freely.
+type Runtime struct {
+	// Version is the liblxc version as returned by Version.
+	Version string
+	// Major, Minor and Micro are the parts of the version.
+	Major int
+	Minor int
+	Micro int
+	// Devel is set for development snapshots of liblxc.
+	Devel bool
+	// CgroupUnified is set if the host runs the pure cgroup v2 hierarchy.
+	CgroupUnified bool
+	// LXCPath, LVMVolumeGroup, ZFSRoot and RootfsMount are the global
+	// lxc.lxcpath, lxc.bdev.lvm.vg, lxc.bdev.zfs.root and
+	// lxc.rootfs.mount settings.
+	LXCPath        string
+	LVMVolumeGroup string
+	ZFSRoot        string
+	RootfsMount    string
+}
+
+// AtLeast returns true if the liblxc version is at least the given one.
+// Development snapshots support everything.
+func (r *Runtime) AtLeast(major int, minor int, micro int) (_ bool) {
+	return
+}
+
+// HasAPIExtension returns true if liblxc supports the extension. The answers
+// of liblxc are cached.
+func (r *Runtime) HasAPIExtension(extension string) (_ bool) {
+	return
+}
+
+// RuntimeInfo returns the runtime information, collecting it on the first
+// call.
+func RuntimeInfo() (_ *Runtime) {
+	return
+}
+
+// RuntimeLiblxcVersionAtLeast checks if the system's liblxc matches the
+// provided version requirement
+func RuntimeLiblxcVersionAtLeast(version string, major int, minor int, micro int) (_ bool) {
+	return
+}
+
+// SaveDeviceProfile stores the profile under lxcpath (default:
+// DefaultConfigPath()), replacing a profile of the same name.
+func SaveDeviceProfile(profile DeviceProfile, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SaveDownloadConfig stores the download configuration of the containers
+// under lxcpath (default: DefaultConfigPath()), which then takes precedence
+// over the global one for them.
+func SaveDownloadConfig(config DownloadConfig, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SaveProfile stores the profile under lxcpath (default:
+// DefaultConfigPath()), replacing a profile of the same name. Containers it
+// is attached to pick the changes up when a profile is attached to or
+// detached from them next.
+func SaveProfile(profile Profile, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SaveProject stores the project under lxcpath (default:
+// DefaultConfigPath()), replacing a project of the same name. The quotas
+// apply from the next start of its containers on.
+func SaveProject(project Project, lxcpath ...string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SecurityFinding represents a single result of a security audit.
+type SecurityFinding struct {
+	// Check is the name of the check, e.g. "privileged" or "seccomp".
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+// SecurityReport represents the result of a security audit.
+type SecurityReport struct {
+	Findings []SecurityFinding
+	// Grade is "A" without warnings, "B" with up to two, "C" with more and
+	// "F" with any critical finding.
+	Grade string
+}
+
+// SessionRecorder records the input and output of console and attach
+// sessions with timestamps, in the asciicast v2 format of asciinema. Set it
+// as Recorder of ConsoleOptions or AttachOptions.
+type SessionRecorder struct {
+	// RecordInput records the keystrokes too, e.g. for compliance. Note
+	// they include passwords typed without echo.
+	RecordInput bool
+}
+
+// Output records output of the session.
+func (r *SessionRecorder) Output(p []byte) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Input records input of the session, if RecordInput is set.
+func (r *SessionRecorder) Input(p []byte) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetAuditSink enables the audit log, every mutating operation (Create,
+// Start, Stop, SetConfigItem, ...) is recorded into sink once it finished.
+// A nil sink disables it again, which is the default. Failing to record
+// doesn't change the result of the operation.
+func SetAuditSink(sink AuditSink) {
+	return
+}
+
+// SetGlobalDownloadConfig sets the download configuration used by image
+// pulls that don't specify one, unless the lxcpath of the container has one
+// of its own, see SaveDownloadConfig.
+func SetGlobalDownloadConfig(config DownloadConfig) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetInterceptor installs the interceptor consulted before every mutating
+// operation (the ones recorded by the audit log), e.g. to refuse destroying
+// production containers. A nil interceptor allows all operations, which is
+// the default. Vetoed operations fail with ErrOperationDenied.
+func SetInterceptor(i Interceptor) {
+	return
+}
+
+// SetOperationPolicy installs the policy used by all containers. The zero
+// policy, which is the default, doesn't retry.
+func SetOperationPolicy(p OperationPolicy) {
+	return
+}
+
+// SetQuirk adds the entry to the quirks database, replacing the one of the
+// same distribution and release. An entry without config disables the
+// default one.
+func SetQuirk(q Quirk) {
+	return
+}
+
+// Severity type specifies how severe a finding of a security audit is.
+type Severity int
+
+// Severity as string
+func (s Severity) String() (_ string) {
+	return
+}
+
+const (
+	// SeverityInfo is a hardening hint
+	SeverityInfo Severity = iota
+	// SeverityWarning weakens the isolation of the container
+	SeverityWarning
+	// SeverityCritical lets the container escape or take over the host
+	SeverityCritical
+)
+
+// ShiftRootfs chowns every file below path from container ids to the host ids
+// given by idmap, so a rootfs prepared for a privileged container can be used
+// by an unprivileged one. POSIX ACLs and file capabilities are shifted as well.
+func ShiftRootfs(path string, idmap IDMap) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Snapshot struct
+type Snapshot struct {
+	Name        string
+	CommentPath string
+	Timestamp   string
+	Path        string
+}
+
+// State type specifies possible container states.
+type State int
+
+// State as string
+func (t State) String() (_ string) {
+	return
+}
+
+// CanTransitionTo returns true if a container can move from the state to the
+// given state in a single step.
+func (t State) CanTransitionTo(state State) (_ bool) {
+	return
+}
+
+// Stable returns true if the container stays in the state until an
+// operation changes it, i.e. for STOPPED, RUNNING and FROZEN.
+func (t State) Stable() (_ bool) {
+	return
+}
+
+// StateMap provides the mapping betweens the state names and states
+var StateMap = map[string]State{
+	"STOPPED":  STOPPED,
+	"STARTING": STARTING,
+	"RUNNING":  RUNNING,
+	"STOPPING": STOPPING,
+	"ABORTING": ABORTING,
+	"FREEZING": FREEZING,
+	"FROZEN":   FROZEN,
+	"THAWED":   THAWED,
+}
+
+// StaticallyLinked returns whether liblxc is linked into the binary, i.e. it
+// was built with the static_liblxc (or static_build) tag.
+func StaticallyLinked() (_ bool) {
+	return
+}
+
+// StatsReader reads the memory and CPU usage of a running container
+// without allocating, for monitoring agents sampling many containers at a
+// high frequency. It keeps the cgroup files open, re-reading them with
+// pread, and parses them into structs provided by the caller. It isn't safe
+// for concurrent use and needs to be closed.
+type StatsReader struct {
+}
+
+// ReadMemoryStatsInto reads the memory usage of the container into s.
+func (r *StatsReader) ReadMemoryStatsInto(s *MemStats) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// ReadCPUStatsInto reads the CPU usage of the container into s.
+func (r *StatsReader) ReadCPUStatsInto(s *CPUStats) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Close closes the cgroup files.
+func (r *StatsReader) Close() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SwapBackend specifies how the swap of a container is provided.
+type SwapBackend int
+
+// SwapBackend as string
+func (b SwapBackend) String() (_ string) {
+	return
+}
+
+const (
+	// SwapFile swaps to a file in the directory of the container. Swap isn't
+	// namespaced, the file is activated on the host and adds to the swap
+	// every process of the host may use, the memory cgroup only limits the
+	// swap use of the container to its size.
+	SwapFile SwapBackend = iota + 1
+	// SwapZram passes a zram device to the container, which activates it
+	// itself. This needs a privileged container.
+	SwapZram
+)
+
+// SwapOptions type is used for defining the swap provisioned for a
+// container.
+type SwapOptions struct {
+	// Backend specifies how the swap is provided.
+	Backend SwapBackend
+	// Size specifies the size of the swap.
+	Size ByteSize
+	// Priority specifies the priority of the swap file on the host, -1 for
+	// the kernel default. Ignored for zram.
+	Priority int
+}
+
+// TTY describes a tty of a running container.
+type TTY struct {
+	// Num is the number of the tty, starting at 1, as used by ConsoleFd.
+	Num int
+	// Path is the device node in the container, e.g. /dev/tty1.
+	Path string
+	// PIDs are the host pids of the container processes which have the
+	// tty open, e.g. a getty.
+	PIDs []int
+}
+
+// InUse returns whether a process of the container has the tty open.
+func (t TTY) InUse() (_ bool) {
+	return
+}
+
+// TemplateOptions type is used for defining various template options.
+type TemplateOptions struct {
+	// Template specifies the name of the template.
+	Template string
+	// Backend specifies the type of the backend.
+	Backend      BackendStore
+	BackendSpecs *BackendStoreSpecs
+	// Distro specifies the name of the distribution.
+	Distro string
+	// Release specifies the name/version of the distribution.
+	Release string
+	// Arch specified the architecture of the container.
+	Arch string
+	// Variant specifies the variant of the image (default: "default").
+	Variant string
+	// Image server (default: "images.linuxcontainers.org").
+	Server string
+	// GPG keyid (default: 0x...).
+	KeyID string
+	// GPG keyserver to use.
+	KeyServer string
+	// Disable GPG validation (not recommended).
+	DisableGPGValidation bool
+	// Flush the local copy (if present).
+	FlushCache bool
+	// Force the use of the local copy even if expired.
+	ForceCache bool
+	// ExtraArgs provides a way to specify template specific args.
+	ExtraArgs []string
+	// Owner tags the container with its owner, see Container.SetOwner.
+	Owner string
+}
+
+// TmpfsOptions type is used for defining a tmpfs mounted into a container.
+type TmpfsOptions struct {
+	// Size limits the size of the tmpfs, 0 for the kernel default of half
+	// the RAM.
+	Size ByteSize
+	// Inodes limits the number of inodes, 0 for the kernel default.
+	Inodes int64
+	// Mode specifies the permissions of the root directory, 0 for the
+	// kernel default 1777. Only permission bits and os.ModeSticky are
+	// allowed.
+	Mode os.FileMode
+	// NoSuid ignores setuid and setgid bits.
+	NoSuid bool
+	// NoDev disallows access to device nodes.
+	NoDev bool
+	// NoExec disallows executing files.
+	NoExec bool
+}
+
+// TopOptions type is used for defining the options of Top.
+type TopOptions struct {
+	// Interval specifies how often the processes are sampled.
+	Interval time.Duration
+	// Sort specifies the order of the processes.
+	Sort TopSort
+	// Limit specifies the maximum number of processes reported, all if 0.
+	Limit int
+}
+
+// TopSort type specifies the order of the processes reported by Top.
+type TopSort int
+
+const (
+	// TopSortCPU sorts by CPU usage, highest first.
+	TopSortCPU TopSort = iota
+	// TopSortMemory sorts by resident memory, highest first.
+	TopSortMemory
+	// TopSortPID sorts by process id.
+	TopSortPID
+)
+
+// Topology returns the NUMA nodes of the host and its online CPUs with
+// their hardware thread siblings.
+func Topology() (_ TopologyInfo, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// TopologyCPU describes an online CPU of the host.
+type TopologyCPU struct {
+	ID      int
+	Node    int
+	Package int
+	Core    int
+	// Siblings are the hardware threads sharing the core, including this
+	// CPU.
+	Siblings []int
+}
+
+// TopologyInfo describes the NUMA nodes and CPUs of the host.
+type TopologyInfo struct {
+	Nodes []NUMANode
+	CPUs  []TopologyCPU
+}
+
+// Node returns the NUMA node with the id.
+func (t TopologyInfo) Node(id int) (_ NUMANode, _ bool) {
+	return
+}
+
+// UbuntuTemplateOptions is a convenient set of options for "ubuntu" template.
+var UbuntuTemplateOptions = TemplateOptions{
+	Template: "ubuntu",
+}
+
+// UnshiftRootfs reverts ShiftRootfs, chowning every file below path from host
+// ids back to container ids, e.g. to export the rootfs of an unprivileged
+// container.
+func UnshiftRootfs(path string, idmap IDMap) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// User represents an entry of /etc/passwd inside a container.
+type User struct {
+	Name  string
+	UID   int
+	GID   int
+	Home  string
+	Shell string
+}
+
+// VF is a SR-IOV virtual function of a host network card.
+type VF struct {
+	// PF is the interface of the physical function, e.g. enp3s0f0.
+	PF string
+	// Index is the number of the VF on the physical function.
+	Index int
+	// PCIAddress is the address of the VF, e.g. 0000:03:10.0.
+	PCIAddress string
+	// Device is the interface of the VF on the host, empty if it's moved
+	// into a container or not bound to a network driver.
+	Device string
+}
+
+// Free returns whether the VF can be assigned to a container.
+func (vf VF) Free() (_ bool) {
+	return
+}
+
+// VFOptions type is used for defining the options of a SR-IOV virtual
+// function assigned to a container.
+type VFOptions struct {
+	// Name is the name of the interface in the container.
+	Name string
+	// HWAddr is the MAC address the physical function assigns to the VF.
+	HWAddr string
+	// VLAN tags the traffic of the VF on the physical function, 0 for
+	// untagged.
+	VLAN int
+	// MTU of the interface, 0 for the default.
+	MTU int
+}
+
+// VXLAN is an L2 overlay connecting the containers of several hosts. Each
+// host bridges a VXLAN interface with the veths of its containers and
+// floods broadcast traffic to the list of peers, so no multicast is needed
+// in the underlay.
+type VXLAN struct {
+}
+
+// Name returns the name of the VXLAN interface.
+func (v *VXLAN) Name() (_ string) {
+	return
+}
+
+// Bridge returns the name of the bridge the containers are attached to.
+func (v *VXLAN) Bridge() (_ string) {
+	return
+}
+
+// Up creates the bridge, unless it exists, and the VXLAN interface and adds
+// the peers.
+func (v *VXLAN) Up() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Down removes the VXLAN interface and the bridge.
+func (v *VXLAN) Down() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetPeers replaces the peers of the overlay, e.g. when hosts join or leave.
+func (v *VXLAN) SetPeers(peers []string) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Attach adds a veth connected to the bridge of the overlay to the
+// container and returns its index. The device is created on the next start.
+func (v *VXLAN) Attach(c *Container) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// VXLANOptions type is used for defining a VXLAN overlay between hosts.
+type VXLANOptions struct {
+	// VNI is the VXLAN network identifier, the same on all hosts.
+	VNI int
+	// Bridge is the bridge the VXLAN interface and the containers are
+	// attached to, vxbr<VNI> if empty.
+	Bridge string
+	// Device is the host interface the VXLAN traffic is sent over, chosen
+	// by the routing table if empty.
+	Device string
+	// Local is the address of this host the VXLAN traffic is sent from.
+	Local string
+	// Peers are the addresses of the other hosts.
+	Peers []string
+	// Port is the UDP port of the VXLAN traffic, 4789 if 0.
+	Port int
+	// MTU of the container interfaces, 1450 if 0 to leave room for the
+	// VXLAN header on a 1500 bytes link.
+	MTU int
+}
+
+// Verbosity type
+type Verbosity int
+
+// Version returns the LXC version.
+func Version() (_ string) {
+	return
+}
+
+// VersionAtLeast returns true when the tested version >= current version.
+func VersionAtLeast(major int, minor int, micro int) (_ bool) {
+	return
+}
+
+// VersionNumber returns the LXC version.
+func VersionNumber() (_ int, _ int) {
+	return
+}
+
+// VolatilePrefix is the prefix of the keys of the volatile config.
+const VolatilePrefix = "volatile."
+
+// Volume is a named host directory, btrfs subvolume or zfs dataset which can
+// be bind mounted into several containers of an lxcpath.
+type Volume struct {
+	Name    string       `json:"name"`
+	Backend BackendStore `json:"backend"`
+	// Path is the directory on the host holding the data.
+	Path string `json:"path"`
+	// Dataset is the zfs dataset of the volume.
+	Dataset string    `json:"dataset,omitempty"`
+	Created time.Time `json:"created"`
+	// External is set for host directories registered with
+	// VolumeOptions.Path, their data is kept on DeleteVolume.
+	External bool `json:"external,omitempty"`
+	// IDMap is the idmap the files were shifted to on disk for the
+	// containers attaching the volume without idmapped mounts, empty if
+	// they are unshifted.
+	IDMap       IDMap              `json:"idmap,omitempty"`
+	Attachments []VolumeAttachment `json:"attachments,omitempty"`
+}
+
+// VolumeAttachOptions type is used for defining how a volume is mounted into
+// a container.
+type VolumeAttachOptions struct {
+	// Target specifies the absolute path of the volume in the container.
+	Target string
+	// ReadOnly mounts the volume read-only.
+	ReadOnly bool
+	// UseIdmappedMounts mounts the volume idmapped to the container's
+	// idmap instead of shifting its files on disk, if supported.
+	UseIdmappedMounts bool
+}
+
+// VolumeAttachment records a container the volume is attached to.
+type VolumeAttachment struct {
+	Container string `json:"container"`
+	// UUID is the UUID of the container, which identifies it after it was
+	// renamed.
+	UUID string `json:"uuid,omitempty"`
+	// Target is the path of the volume in the container.
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readonly,omitempty"`
+	// Idmapped is set if the volume is mounted idmapped instead of shifted
+	// on disk.
+	Idmapped bool `json:"idmapped,omitempty"`
+	// Shifted is set if the container relies on the files being shifted
+	// to Volume.IDMap.
+	Shifted bool `json:"shifted,omitempty"`
+}
+
+// VolumeOptions type is used for defining how a volume is created.
+type VolumeOptions struct {
+	// Backend specifies the type of the volume, Directory, Btrfs or ZFS.
+	Backend BackendStore
+	// Path registers an existing host directory as Directory volume
+	// instead of creating one.
+	Path string
+	// ZFSRoot specifies the dataset ZFS volumes are created below
+	// (default: DefaultZfsRoot()).
+	ZFSRoot string
+}
+
+// Volumes returns the volumes of lxcpath, sorted by name.
+func Volumes(lxcpath ...string) (_ []Volume, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitNotifyReady is a HealthCheck waiting for the guest to send READY=1
+// over the socket set up by NotifySocket.
+func WaitNotifyReady(ctx context.Context, c *Container) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WaitRunning is a HealthCheck waiting for the container to be RUNNING.
+func WaitRunning(ctx context.Context, c *Container) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// WarmCustomization is applied to a container handed out by WarmPool.Get.
+type WarmCustomization struct {
+	// Hostname of the container.
+	Hostname string
+	// IPv4 address in CIDR notation added to Interface.
+	IPv4 string
+	// Interface the address is added to (default: "eth0").
+	Interface string
+}
+
+// WarmPool maintains a number of pre-started clones of a base container and
+// hands them out on demand.
+type WarmPool struct {
+}
+
+// Get hands out a container of the pool, thawed and customized, waiting for
+// one to become ready until ctx is done. The container is owned by the caller
+// until it is given back with Put.
+func (p *WarmPool) Get(ctx context.Context, custom WarmCustomization) (_ *Container, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Put gives a container back. If reuse is set and the pool isn't full, its
+// customization is undone and it is handed out again (frozen again for
+// frozen pools), otherwise it is destroyed and replaced by a fresh clone.
+func (p *WarmPool) Put(c *Container, reuse bool) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Err returns the error of the last attempt to add a container to the pool.
+func (p *WarmPool) Err() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Close stops filling the pool and destroys the containers which weren't
+// handed out.
+func (p *WarmPool) Close() {
+	return
+}
+
+// WarmPoolOptions type is used for defining the containers kept by a WarmPool.
+type WarmPoolOptions struct {
+	// Size is the number of containers kept ready.
+	Size int
+	// Prefix of the names of the clones (default: "<base>-warm-").
+	Prefix string
+	// Frozen freezes the containers right after they are started, see
+	// StartAndFreeze.
+	Frozen bool
+	// Clone specifies how the base container is cloned.
+	Clone CloneOptions
+	// RetryInterval is the time to wait after a failed clone or start
+	// (minimum: 1s).
+	RetryInterval time.Duration
+}
+
+// WatchContainers starts watching the lxcpath for containers being created,
+// destroyed or reconfigured, using inotify instead of rescanning it. The
+// containers existing already aren't reported.
+func WatchContainers(lxcpath ...string) (_ *ContainerWatcher, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Watchdog monitors the heartbeat of a running container.
+type Watchdog struct {
+}
+
+// Stop stops monitoring the container and waits for the watchdog to exit.
+func (w *Watchdog) Stop() {
+	return
+}
+
+// WatchdogAction type specifies what the watchdog does when the container stops responding.
+type WatchdogAction int
+
+const (
+	// WatchdogAlert only reports the failure.
+	WatchdogAlert WatchdogAction = iota
+	// WatchdogRestart reports the failure and restarts the container.
+	WatchdogRestart
+)
+
+// WatchdogOptions type is used for defining the watchdog options.
+type WatchdogOptions struct {
+	// HeartbeatFile is a path inside the container which the guest touches periodically.
+	HeartbeatFile string
+	// HeartbeatSocket is the path of a unix socket inside the container.
+	// The guest is expected to answer every connection with at least one byte.
+	HeartbeatSocket string
+	// Interval specifies how often the heartbeat is checked.
+	Interval time.Duration
+	// Timeout specifies how long the guest may stay silent before it is considered hung.
+	Timeout time.Duration
+	// Action specifies what to do when the guest is hung.
+	Action WatchdogAction
+	// OnFailure is called with the reason whenever the guest is considered hung.
+	OnFailure func(c *Container, err error)
+}
+
+// WireGuardMesh connects the containers of several hosts over encrypted
+// WireGuard tunnels. Every host gets its own container subnet, routed
+// through the tunnel to the host owning it, and hands out the addresses of
+// the subnet to the containers attached to its bridge.
+type WireGuardMesh struct {
+}
+
+// Subnet returns the container subnet of the host.
+func (m *WireGuardMesh) Subnet() (_ netip.Prefix) {
+	return
+}
+
+// Gateway returns the address of the bridge, the default gateway of the
+// containers.
+func (m *WireGuardMesh) Gateway() (_ netip.Addr) {
+	return
+}
+
+// Up creates the WireGuard interface and the bridge, unless it exists, sets
+// up the tunnels and routes to the peers and enables IPv4 forwarding.
+func (m *WireGuardMesh) Up() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Down removes the WireGuard interface, with it the routes to the peers, and
+// the bridge.
+func (m *WireGuardMesh) Down() (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// SetPeers replaces the peers of the mesh, e.g. when hosts join or leave.
+func (m *WireGuardMesh) SetPeers(peers []WireGuardPeer) (err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Attach adds a veth connected to the bridge of the host to the container,
+// with an address of the container subnet of the host and the bridge as
+// gateway, and returns its index. The device is created on the next start.
+func (m *WireGuardMesh) Attach(c *Container) (_ int, err error) {
+	err = ErrNotSupported
+	return
+}
+
+// Release frees the address leased to the container by Attach.
+func (m *WireGuardMesh) Release(c *Container) {
+	return
+}
+
+// WireGuardOptions type is used for defining a WireGuard mesh between hosts.
+type WireGuardOptions struct {
+	// Interface is the WireGuard interface of the host, wg-lxc if empty.
+	Interface string
+	// ListenPort is the UDP port of the WireGuard traffic, 51820 if 0.
+	ListenPort int
+	// PrivateKeyFile is the file holding the private key of the host, as
+	// generated by wg genkey.
+	PrivateKeyFile string
+	// Subnet is the network the container subnets of all hosts are
+	// allocated from, e.g. 10.128.0.0/16.
+	Subnet netip.Prefix
+	// HostBits is the prefix length of the container subnet of a host, 24
+	// if 0.
+	HostBits int
+	// HostIndex is the number of the container subnet of this host in
+	// Subnet, unique across the mesh.
+	HostIndex int
+	// Bridge is the bridge the containers of the host are attached to,
+	// wgbr0 if empty.
+	Bridge string
+	// Peers are the other hosts of the mesh.
+	Peers []WireGuardPeer
+	// MTU of the container interfaces, 1420 if 0 to leave room for the
+	// WireGuard header on a 1500 bytes link.
+	MTU int
+}
+
+// WireGuardPeer is another host of a WireGuard mesh.
+type WireGuardPeer struct {
+	// PublicKey is the public key of the host, as printed by wg pubkey.
+	PublicKey string
+	// Endpoint is the address and port the host is reached at, e.g.
+	// 192.0.2.2:51820.
+	Endpoint string
+	// HostIndex is the number of the container subnet of the host.
+	HostIndex int
+}
+
+const (
+	// X86 - Intel 32bit
+	X86 Personality = 0x0008
+
+	// X86_64 - Intel 64bit
+	X86_64 = 0x0000
+)
+
+type lxcError string
+
+func (e lxcError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,102 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the unit of the times in /proc/<pid>/stat, fixed by the kernel
+// ABI independent of the configured HZ.
+const userHZ = 100
+
+// parseStartTime returns the start time of a process in clock ticks after
+// boot from the content of /proc/<pid>/stat.
+func parseStartTime(stat string) (uint64, error) {
+	// the command may contain spaces and parentheses
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid stat %q", stat)
+	}
+
+	// the fields after the command start at the state, the third field,
+	// the start time is the 22nd field
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat %q", stat)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// parseBootTime returns the boot time of the host from the content of
+// /proc/stat.
+func parseBootTime(stat string) (time.Time, error) {
+	scanner := bufio.NewScanner(strings.NewReader(stat))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
+
+// processStartTime returns the time the process was started at.
+func processStartTime(pid int) (time.Time, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ticks, err := parseStartTime(string(stat))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	content, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	boot, err := parseBootTime(string(content))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return boot.Add(time.Duration(ticks) * time.Second / userHZ), nil
+}
+
+// StartedAt returns the time the container was started at, i.e. the start
+// time of its init process. The resolution is 10ms.
+func (c *Container) StartedAt() (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isRunning); err != nil {
+		return time.Time{}, err
+	}
+
+	return processStartTime(c.initPid())
+}
+
+// Uptime returns how long the container has been running.
+func (c *Container) Uptime() (time.Duration, error) {
+	started, err := c.StartedAt()
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(started), nil
+}
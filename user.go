@@ -0,0 +1,235 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// User represents an entry of /etc/passwd inside a container.
+type User struct {
+	Name  string
+	UID   int
+	GID   int
+	Home  string
+	Shell string
+}
+
+// Group represents an entry of /etc/group inside a container.
+type Group struct {
+	Name    string
+	GID     int
+	Members []string
+}
+
+// parsePasswd parses the content of /etc/passwd.
+func parsePasswd(content string) []User {
+	var users []User
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) != 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		uid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		gid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+
+		users = append(users, User{Name: fields[0], UID: uid, GID: gid, Home: fields[5], Shell: fields[6]})
+	}
+	return users
+}
+
+// parseGroup parses the content of /etc/group.
+func parseGroup(content string) []Group {
+	var groups []Group
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) != 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		group := Group{Name: fields[0], GID: gid}
+		if fields[3] != "" {
+			group.Members = strings.Split(fields[3], ",")
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// readRootfsFile reads a file of the root filesystem of the container,
+// through the init process if it is running. Symlinks are resolved inside
+// the rootfs and the file itself mustn't be one.
+//
+// Caller needs to hold the lock
+func (c *Container) readRootfsFile(path string) ([]byte, error) {
+	if c.running() {
+		root := fmt.Sprintf("/proc/%d/root", c.initPid())
+
+		resolved, err := resolvePath(root, path)
+		if err != nil {
+			return nil, err
+		}
+		return readResolvedFile(resolved)
+	}
+
+	rootfs, cleanup, err := c.mountRootfs()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	resolved, err := resolvePath(rootfs, path)
+	if err != nil {
+		return nil, err
+	}
+	return readResolvedFile(resolved)
+}
+
+// lookupUser finds a user by name or numeric uid in the container.
+//
+// Caller needs to hold the lock
+func (c *Container) lookupUser(name string) (User, error) {
+	content, err := c.readRootfsFile("/etc/passwd")
+	if err != nil {
+		return User{}, fmt.Errorf("%s: %v", ErrUserNotFound, err)
+	}
+
+	uid, err := strconv.Atoi(name)
+	numeric := err == nil
+
+	for _, user := range parsePasswd(string(content)) {
+		if user.Name == name || (numeric && user.UID == uid) {
+			return user, nil
+		}
+	}
+	return User{}, fmt.Errorf("%s: %q", ErrUserNotFound, name)
+}
+
+// lookupGroups returns the groups of the container.
+//
+// Caller needs to hold the lock
+func (c *Container) lookupGroups() ([]Group, error) {
+	content, err := c.readRootfsFile("/etc/group")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ErrGroupNotFound, err)
+	}
+	return parseGroup(string(content)), nil
+}
+
+// findGroup finds a group by name or numeric gid.
+func findGroup(groups []Group, name string) (Group, error) {
+	gid, err := strconv.Atoi(name)
+	numeric := err == nil
+
+	for _, group := range groups {
+		if group.Name == name || (numeric && group.GID == gid) {
+			return group, nil
+		}
+	}
+	return Group{}, fmt.Errorf("%s: %q", ErrGroupNotFound, name)
+}
+
+// LookupUser returns the user of the given name, or numeric uid, from
+// /etc/passwd of the container. The root filesystem of a stopped container
+// is mounted for the lookup.
+func (c *Container) LookupUser(name string) (User, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return User{}, err
+	}
+
+	return c.lookupUser(name)
+}
+
+// LookupGroup returns the group of the given name, or numeric gid, from
+// /etc/group of the container.
+func (c *Container) LookupGroup(name string) (Group, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return Group{}, err
+	}
+
+	groups, err := c.lookupGroups()
+	if err != nil {
+		return Group{}, err
+	}
+	return findGroup(groups, name)
+}
+
+// resolveAttachUser resolves options.User ("user" or "user:group") into the
+// uid, primary gid and, unless set, supplementary groups of the user. Other
+// options are returned as given.
+//
+// Caller needs to hold the lock
+func (c *Container) resolveAttachUser(options AttachOptions) (AttachOptions, error) {
+	if options.User == "" {
+		return options, nil
+	}
+
+	parts := strings.SplitN(options.User, ":", 2)
+
+	user, err := c.lookupUser(parts[0])
+	if err != nil {
+		return options, err
+	}
+
+	options.UID = user.UID
+	options.GID = user.GID
+
+	groups, err := c.lookupGroups()
+	if err != nil {
+		return options, err
+	}
+
+	if len(parts) == 2 {
+		group, err := findGroup(groups, parts[1])
+		if err != nil {
+			return options, err
+		}
+		options.GID = group.GID
+	}
+
+	if options.Groups == nil {
+		for _, group := range groups {
+			for _, member := range group.Members {
+				if member == user.Name && group.GID != options.GID {
+					options.Groups = append(options.Groups, group.GID)
+				}
+			}
+		}
+	}
+
+	if options.Cwd == "" {
+		options.Cwd = filepath.Clean(user.Home)
+	}
+	return options, nil
+}
@@ -0,0 +1,171 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// validUUID returns whether s is a UUID in its canonical lower case form.
+func validUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdef", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// uuidPath returns the file holding the UUID of the container. liblxc
+// refuses config keys it doesn't know, so it is kept next to the config.
+func uuidPath(lxcpath string, name string) string {
+	return filepath.Join(lxcpath, name, "uuid")
+}
+
+// readUUID returns the UUID of the container, empty if it has none.
+func readUUID(lxcpath string, name string) (string, error) {
+	content, err := ioutil.ReadFile(uuidPath(lxcpath, name))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	uuid := strings.TrimSpace(string(content))
+	if !validUUID(uuid) {
+		return "", fmt.Errorf("%s: %q", ErrInvalidUUID, uuid)
+	}
+	return uuid, nil
+}
+
+// writeUUID sets the UUID of the container.
+func writeUUID(lxcpath string, name string, uuid string) error {
+	return ioutil.WriteFile(uuidPath(lxcpath, name), []byte(uuid+"\n"), 0644)
+}
+
+// assignUUID gives the container a new UUID.
+func assignUUID(lxcpath string, name string) (string, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	return uuid, writeUUID(lxcpath, name, uuid)
+}
+
+// identityFiles are the files next to the config identifying the container
+// whatever its name, including the MAC addresses it was given and its swap.
+var identityFiles = []string{"uuid", "owner", "volatile.json", "swap.json"}
+
+// keepIdentity returns a function restoring the identity files of the
+// container under name after liblxc replaced it, as renaming and restoring
+// snapshots clone the container without the files besides the config.
+//
+// Caller needs to hold the lock
+func (c *Container) keepIdentity() func(name string) error {
+	lxcpath := c.configPath()
+
+	contents := make(map[string][]byte)
+	for _, file := range identityFiles {
+		if content, err := ioutil.ReadFile(filepath.Join(lxcpath, c.name(), file)); err == nil {
+			contents[file] = content
+		}
+	}
+
+	return func(name string) error {
+		for file, content := range contents {
+			if err := ioutil.WriteFile(filepath.Join(lxcpath, name, file), content, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// UUID returns the UUID of the container, which stays the same when it is
+// renamed. It is generated by Create, Clone, CreateFromImage, ImportFromLXD
+// and NewApplicationContainer, containers created otherwise get one on first
+// use. liblxc refuses unknown config keys, so it is kept in a file next to
+// the config instead.
+func (c *Container) UUID() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return "", ErrNotDefined
+	}
+
+	if err := c.makeSure(isDefined); err != nil {
+		return "", err
+	}
+
+	return c.uuid()
+}
+
+// uuid returns the UUID of the container, assigning one if it has none.
+//
+// Caller needs to hold the lock
+func (c *Container) uuid() (string, error) {
+	uuid, err := readUUID(c.configPath(), c.name())
+	if err != nil || uuid != "" {
+		return uuid, err
+	}
+	return assignUUID(c.configPath(), c.name())
+}
+
+// LookupByUUID returns the container with the UUID in lxcpath, the default
+// one if empty. Containers which have no UUID yet aren't found.
+// Caller needs to call Release() on the returned container to release resources.
+func LookupByUUID(lxcpath string, uuid string) (*Container, error) {
+	if lxcpath == "" {
+		lxcpath = DefaultConfigPath()
+	}
+
+	uuid = strings.ToLower(uuid)
+	if !validUUID(uuid) {
+		return nil, fmt.Errorf("%s: %q", ErrInvalidUUID, uuid)
+	}
+
+	names, err := DefinedContainerNamesE(lxcpath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if id, err := readUUID(lxcpath, name); err == nil && id == uuid {
+			return NewContainer(name, lxcpath)
+		}
+	}
+	return nil, fmt.Errorf("%s: %s", ErrUUIDNotFound, uuid)
+}
@@ -0,0 +1,517 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	v2 "github.com/lxc/go-lxc"
+)
+
+// Container struct
+type Container struct {
+	mu     sync.Mutex
+	c      *v2.Container
+	calls  int
+	closed bool
+}
+
+// New returns a new container struct. The underlying liblxc handle is
+// released once the container is garbage collected or Close is called.
+func New(name string, opts Options) (*Container, error) {
+	c, err := v2.NewContainer(name, opts.lxcpath()...)
+	if err != nil {
+		return nil, err
+	}
+	return newContainer(c), nil
+}
+
+func newContainer(c *v2.Container) *Container {
+	container := &Container{c: c}
+	runtime.SetFinalizer(container, (*Container).Close)
+	return container
+}
+
+// Containers returns the defined and active containers in the lxcpath.
+func Containers(ctx context.Context, opts Options) ([]*Container, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	list, err := v2.ContainersE(opts.lxcpath()...)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*Container, len(list))
+	for i, c := range list {
+		containers[i] = newContainer(c)
+	}
+	return containers, nil
+}
+
+// Close releases the container early. Using the container afterwards returns
+// ErrClosed. If calls are still running in the background the liblxc handle
+// is released once the last of them returns.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	runtime.SetFinalizer(c, nil)
+	c.closed = true
+	if c.calls > 0 {
+		return nil
+	}
+	return c.release()
+}
+
+// release drops the liblxc handle.
+//
+// Caller needs to hold the lock
+func (c *Container) release() error {
+	err := c.c.Release()
+	c.c = nil
+	return err
+}
+
+// do runs fn on the v2 container unless the context is done first. liblxc
+// calls can't be interrupted, so if the context is done while fn runs do
+// returns ctx.Err() right away and fn keeps running in the background: the
+// operation may still take effect. The call is counted rather than holding
+// the lock, so Close doesn't block on it and the handle is only released
+// once it returns.
+func (c *Container) do(ctx context.Context, fn func(c *v2.Container) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.calls++
+	container := c.c
+	c.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		defer c.finish()
+		done <- fn(container)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish ends a call started by do, releasing the handle if the container
+// was closed in the meantime.
+func (c *Container) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls--
+	if c.closed && c.calls == 0 {
+		c.release()
+	}
+}
+
+// timeout returns the time left until the context deadline, or -1 (no
+// timeout) if the context has none.
+func timeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return -1 * time.Second
+	}
+
+	left := time.Until(deadline)
+	if left < time.Second {
+		return time.Second
+	}
+	return left
+}
+
+// Name returns the name of the container.
+func (c *Container) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ""
+	}
+	return c.c.Name()
+}
+
+// Defined returns true if the container is already defined.
+func (c *Container) Defined(ctx context.Context) (bool, error) {
+	var defined bool
+	err := c.do(ctx, func(c *v2.Container) error {
+		defined = c.Defined()
+		return nil
+	})
+	return defined, err
+}
+
+// State returns the state of the container.
+func (c *Container) State(ctx context.Context) (State, error) {
+	var state State
+	err := c.do(ctx, func(c *v2.Container) error {
+		state = c.State()
+		return nil
+	})
+	return state, err
+}
+
+// Create creates the container using given CreateOptions.
+func (c *Container) Create(ctx context.Context, opts CreateOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Create(opts)
+	})
+}
+
+// Start starts the container.
+func (c *Container) Start(ctx context.Context, opts StartOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		if opts.UseInit {
+			return c.StartExecute(opts.Args)
+		}
+
+		if opts.Args != nil {
+			return c.StartWithArgs(opts.Args)
+		}
+
+		return c.Start()
+	})
+}
+
+// Stop kills the container.
+func (c *Container) Stop(ctx context.Context) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Stop()
+	})
+}
+
+// Shutdown cleanly shuts down the container, waiting until the context
+// deadline for it to stop.
+func (c *Container) Shutdown(ctx context.Context) error {
+	t := timeout(ctx)
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Shutdown(t)
+	})
+}
+
+// Reboot reboots the container.
+func (c *Container) Reboot(ctx context.Context) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Reboot()
+	})
+}
+
+// Freeze freezes the running container.
+func (c *Container) Freeze(ctx context.Context) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Freeze()
+	})
+}
+
+// Unfreeze thaws the frozen container.
+func (c *Container) Unfreeze(ctx context.Context) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Unfreeze()
+	})
+}
+
+// Destroy destroys the container.
+func (c *Container) Destroy(ctx context.Context, opts DestroyOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		if opts.WithSnapshots {
+			return c.DestroyWithAllSnapshots()
+		}
+		return c.Destroy()
+	})
+}
+
+// Clone clones the container and returns the clone.
+func (c *Container) Clone(ctx context.Context, name string, opts CloneOptions) (*Container, error) {
+	var clone *Container
+	err := c.do(ctx, func(c *v2.Container) error {
+		if err := c.Clone(name, opts); err != nil {
+			return err
+		}
+
+		lxcpath := opts.ConfigPath
+		if lxcpath == "" {
+			lxcpath = c.ConfigPath()
+		}
+
+		container, err := v2.NewContainer(name, lxcpath)
+		if err != nil {
+			return err
+		}
+		clone = newContainer(container)
+		return nil
+	})
+	return clone, err
+}
+
+// Rename renames the container.
+func (c *Container) Rename(ctx context.Context, name string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Rename(name)
+	})
+}
+
+// Wait waits for the container to reach the given state or the context to be
+// done.
+func (c *Container) Wait(ctx context.Context, state State) error {
+	for {
+		var reached bool
+		err := c.do(ctx, func(c *v2.Container) error {
+			reached = c.Wait(state, time.Second)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if reached {
+			return nil
+		}
+	}
+}
+
+// ConfigItem returns the value of the given config item.
+func (c *Container) ConfigItem(ctx context.Context, key string) ([]string, error) {
+	var value []string
+	err := c.do(ctx, func(c *v2.Container) error {
+		value = c.ConfigItem(key)
+		return nil
+	})
+	return value, err
+}
+
+// SetConfigItem sets the value of the given config item.
+func (c *Container) SetConfigItem(ctx context.Context, key string, value string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.SetConfigItem(key, value)
+	})
+}
+
+// ClearConfigItem clears the value of given config item.
+func (c *Container) ClearConfigItem(ctx context.Context, key string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.ClearConfigItem(key)
+	})
+}
+
+// ConfigKeys returns the names of the config items below prefix, or the top
+// level keys if prefix is empty.
+func (c *Container) ConfigKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := c.do(ctx, func(c *v2.Container) error {
+		if prefix == "" {
+			keys = c.ConfigKeys()
+		} else {
+			keys = c.ConfigKeys(prefix)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// SaveConfig saves the configuration of the container to its config file, or
+// to path if given.
+func (c *Container) SaveConfig(ctx context.Context, path string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		if path == "" {
+			path = c.ConfigFileName()
+		}
+		return c.SaveConfigFile(path)
+	})
+}
+
+// LoadConfig loads the configuration file from given path.
+func (c *Container) LoadConfig(ctx context.Context, path string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.LoadConfigFile(path)
+	})
+}
+
+// CreateSnapshot creates a new snapshot.
+func (c *Container) CreateSnapshot(ctx context.Context) (*Snapshot, error) {
+	var snapshot *Snapshot
+	err := c.do(ctx, func(c *v2.Container) error {
+		var err error
+		snapshot, err = c.CreateSnapshot()
+		return err
+	})
+	return snapshot, err
+}
+
+// Snapshots returns the list of container snapshots.
+func (c *Container) Snapshots(ctx context.Context) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	err := c.do(ctx, func(c *v2.Container) error {
+		var err error
+		snapshots, err = c.Snapshots()
+		if err == v2.ErrNoSnapshot {
+			return nil
+		}
+		return err
+	})
+	return snapshots, err
+}
+
+// RestoreSnapshot creates a new container based on a snapshot.
+func (c *Container) RestoreSnapshot(ctx context.Context, snapshot Snapshot, name string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.RestoreSnapshot(snapshot, name)
+	})
+}
+
+// DestroySnapshot destroys the specified snapshot.
+func (c *Container) DestroySnapshot(ctx context.Context, snapshot Snapshot) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.DestroySnapshot(snapshot)
+	})
+}
+
+// RunCommand runs the command within the container and returns its exit
+// status. If the context is done first, the command is killed.
+func (c *Container) RunCommand(ctx context.Context, args []string, opts AttachOptions) (int, error) {
+	var proc *v2.AttachedProcess
+	err := c.do(ctx, func(c *v2.Container) error {
+		var err error
+		proc, err = c.RunCommandAsync(args, opts)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	type result struct {
+		status int
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		status, err := proc.Wait()
+		done <- result{status, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.status, r.err
+	case <-ctx.Done():
+		proc.Signal(syscall.SIGKILL)
+		return -1, ctx.Err()
+	}
+}
+
+// AttachShell attaches a shell to the container. It returns once the shell
+// exits, or with the error of the context while the shell keeps running.
+func (c *Container) AttachShell(ctx context.Context, opts AttachOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.AttachShell(opts)
+	})
+}
+
+// Console allocates and runs a console tty of the container. It returns once
+// the console is detached, or with the error of the context while the
+// console keeps running.
+func (c *Container) Console(ctx context.Context, opts ConsoleOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Console(opts)
+	})
+}
+
+// CgroupItem returns the value of the given cgroup item of the running
+// container.
+func (c *Container) CgroupItem(ctx context.Context, key string) ([]string, error) {
+	var value []string
+	err := c.do(ctx, func(c *v2.Container) error {
+		items, err := c.ReadCgroupItems([]string{key})
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		if value, ok = items[key]; !ok {
+			return fmt.Errorf("%s: %q", ErrReadingCgroupItemFailed, key)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// SetCgroupItem sets the value of the given cgroup item of the running
+// container.
+func (c *Container) SetCgroupItem(ctx context.Context, key string, value string) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.SetCgroupItem(key, value)
+	})
+}
+
+// Checkpoint checkpoints the container with CRIU.
+func (c *Container) Checkpoint(ctx context.Context, opts CheckpointOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Checkpoint(opts)
+	})
+}
+
+// Restore restores the container from a CRIU checkpoint.
+func (c *Container) Restore(ctx context.Context, opts RestoreOptions) error {
+	return c.do(ctx, func(c *v2.Container) error {
+		return c.Restore(opts)
+	})
+}
+
+// IPAddresses returns the IP addresses matching the given options, indexed by
+// interface name. If WaitForGlobal is set, it waits for a global address until
+// the context is done.
+func (c *Container) IPAddresses(ctx context.Context, opts IPAddressOptions) (map[string][]net.IP, error) {
+	for {
+		var addresses map[string][]net.IP
+		err := c.do(ctx, func(c *v2.Container) error {
+			var err error
+			addresses, err = c.IPAddressesWithOptions(opts)
+			return err
+		})
+		if err != nil || !opts.WaitForGlobal {
+			return addresses, err
+		}
+
+		for _, ips := range addresses {
+			for _, ip := range ips {
+				if ip.IsGlobalUnicast() {
+					return addresses, nil
+				}
+			}
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
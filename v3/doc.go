@@ -0,0 +1,12 @@
+/*
+Package lxc provides the v3 Go Bindings for LXC (Linux Containers) C API.
+
+It is a redesign of the v2 API layered on top of it: all operations take a
+context.Context and return errors, option structs replace positional
+arguments and containers are released by the garbage collector.
+
+liblxc calls can't be interrupted. When the context of an operation is done
+before liblxc returns, the operation returns the error of the context while
+liblxc finishes it in the background, so it may still take effect.
+*/
+package lxc
@@ -0,0 +1,21 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+const (
+	// ErrClosed - the container has been closed
+	ErrClosed = lxcError("the container has been closed")
+
+	// ErrReadingCgroupItemFailed - reading cgroup item for the container failed
+	ErrReadingCgroupItemFailed = lxcError("reading cgroup item for the container failed")
+)
+
+type lxcError string
+
+func (e lxcError) Error() string {
+	return string(e)
+}
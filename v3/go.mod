@@ -0,0 +1,10 @@
+module github.com/lxc/go-lxc/v3
+
+go 1.18
+
+require github.com/lxc/go-lxc v0.0.0-20261017184416-2bf9bba8f182
+
+require (
+	golang.org/x/sys v0.0.0-20210603125802-9665404d3644 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
@@ -0,0 +1,208 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	v2 "github.com/lxc/go-lxc"
+)
+
+func name(name string) string {
+	if os.Geteuid() != 0 {
+		return fmt.Sprintf("%s-unprivileged", name)
+	}
+	return name
+}
+
+func TestCanceledContext(t *testing.T) {
+	c, err := New("v3-canceled", Options{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.Start(ctx, StartOptions{}); err != context.Canceled {
+		t.Errorf("Start ignored the canceled context: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c, err := New("v3-closed", Options{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if _, err := c.Defined(context.Background()); err != ErrClosed {
+		t.Errorf("Defined on a closed container returned %v", err)
+	}
+
+	if _, err := c.CgroupItem(context.Background(), "memory.max"); err != ErrClosed {
+		t.Errorf("CgroupItem on a closed container returned %v", err)
+	}
+
+	if err := c.Checkpoint(context.Background(), CheckpointOptions{}); err != ErrClosed {
+		t.Errorf("Checkpoint on a closed container returned %v", err)
+	}
+}
+
+func TestCloseDuringCall(t *testing.T) {
+	c, err := New("v3-close-during-call", Options{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	err = c.do(ctx, func(c *v2.Container) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("do ignored the canceled context: %v", err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- c.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close blocked on the call running in the background")
+	}
+
+	if _, err := c.Defined(context.Background()); err != ErrClosed {
+		t.Errorf("Defined on a closed container returned %v", err)
+	}
+
+	c.mu.Lock()
+	released := c.c == nil
+	c.mu.Unlock()
+	if released {
+		t.Errorf("Close released the container under the running call")
+	}
+
+	close(unblock)
+	for i := 0; ; i++ {
+		c.mu.Lock()
+		released = c.c == nil
+		c.mu.Unlock()
+		if released {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("the container wasn't released once the call returned")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	c, err := New(name("v3-lifecycle"), Options{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Close()
+
+	if err := c.Create(ctx, CreateOptions{
+		Template: "download",
+		Distro:   "alpine",
+		Release:  "edge",
+		Arch:     "amd64",
+	}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer c.Destroy(context.Background(), DestroyOptions{WithSnapshots: true})
+
+	if defined, err := c.Defined(ctx); err != nil || !defined {
+		t.Errorf("Defined returned %v, %v after Create", defined, err)
+	}
+
+	if err := c.SetConfigItem(ctx, "lxc.uts.name", "v3"); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if value, err := c.ConfigItem(ctx, "lxc.uts.name"); err != nil || len(value) != 1 || value[0] != "v3" {
+		t.Errorf("ConfigItem returned %v, %v", value, err)
+	}
+
+	if err := c.Start(ctx, StartOptions{}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err := c.Wait(ctx, RUNNING); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if state, err := c.State(ctx); err != nil || state != RUNNING {
+		t.Errorf("State returned %v, %v after Start", state, err)
+	}
+
+	if status, err := c.RunCommand(ctx, []string{"/bin/true"}, DefaultAttachOptions); err != nil || status != 0 {
+		t.Errorf("RunCommand returned %d, %v", status, err)
+	}
+
+	if err := c.Freeze(ctx); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if state, err := c.State(ctx); err != nil || state != FROZEN {
+		t.Errorf("State returned %v, %v after Freeze", state, err)
+	}
+
+	if err := c.Unfreeze(ctx); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := c.Stop(ctx); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := c.Wait(ctx, STOPPED); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	clone, err := c.Clone(ctx, name("v3-lifecycle-clone"), CloneOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer clone.Close()
+
+	if defined, err := clone.Defined(ctx); err != nil || !defined {
+		t.Errorf("Defined returned %v, %v for the clone", defined, err)
+	}
+
+	if err := clone.Destroy(ctx, DestroyOptions{}); err != nil {
+		t.Errorf(err.Error())
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	v2 "github.com/lxc/go-lxc"
+)
+
+// Options type is used for defining where containers are looked up.
+type Options struct {
+
+	// LXCPath is the directory holding the containers (default: lxc.lxcpath).
+	LXCPath string
+}
+
+func (o Options) lxcpath() []string {
+	if o.LXCPath == "" {
+		return nil
+	}
+	return []string{o.LXCPath}
+}
+
+// CreateOptions type is used for defining various create options.
+type CreateOptions = v2.TemplateOptions
+
+// StartOptions type is used for defining various start options.
+type StartOptions struct {
+
+	// Args overrides the init command of the container.
+	Args []string
+
+	// UseInit runs a minimal init as PID 1 and Args as the second process.
+	UseInit bool
+}
+
+// DestroyOptions type is used for defining various destroy options.
+type DestroyOptions struct {
+
+	// WithSnapshots destroys the snapshots of the container as well.
+	WithSnapshots bool
+}
+
+// CloneOptions type is used for defining various clone options.
+type CloneOptions = v2.CloneOptions
+
+// AttachOptions type is used for defining various attach options.
+type AttachOptions = v2.AttachOptions
+
+// DefaultAttachOptions is a convenient set of options to be used.
+var DefaultAttachOptions = v2.DefaultAttachOptions
+
+// IPAddressOptions type is used for filtering the IP addresses of a container.
+type IPAddressOptions = v2.IPAddressOptions
+
+// ConsoleOptions type is used for defining various console options.
+type ConsoleOptions = v2.ConsoleOptions
+
+// DefaultConsoleOptions is a convenient set of options to be used.
+var DefaultConsoleOptions = v2.DefaultConsoleOptions
+
+// CheckpointOptions type is used for defining checkpoint options for CRIU.
+type CheckpointOptions = v2.CheckpointOptions
+
+// RestoreOptions type is used for defining restore options for CRIU.
+type RestoreOptions = v2.RestoreOptions
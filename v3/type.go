@@ -0,0 +1,86 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	v2 "github.com/lxc/go-lxc"
+)
+
+// State type specifies possible container states.
+type State = v2.State
+
+const (
+	// STOPPED means container is not running
+	STOPPED = v2.STOPPED
+	// STARTING means container is starting
+	STARTING = v2.STARTING
+	// RUNNING means container is running
+	RUNNING = v2.RUNNING
+	// STOPPING means container is stopping
+	STOPPING = v2.STOPPING
+	// ABORTING means container is aborting
+	ABORTING = v2.ABORTING
+	// FREEZING means container is freezing
+	FREEZING = v2.FREEZING
+	// FROZEN means containe is frozen
+	FROZEN = v2.FROZEN
+	// THAWED means container is thawed
+	THAWED = v2.THAWED
+)
+
+// BackendStore type specifies possible backend types.
+type BackendStore = v2.BackendStore
+
+const (
+	// Btrfs backendstore type
+	Btrfs = v2.Btrfs
+	// Directory backendstore type
+	Directory = v2.Directory
+	// LVM backendstore type
+	LVM = v2.LVM
+	// ZFS backendstore type
+	ZFS = v2.ZFS
+	// Aufs backendstore type
+	Aufs = v2.Aufs
+	// Overlayfs backendstore type
+	Overlayfs = v2.Overlayfs
+	// Loopback backendstore type
+	Loopback = v2.Loopback
+	// Best backendstore type
+	Best = v2.Best
+)
+
+// ByteSize type
+type ByteSize = v2.ByteSize
+
+// LogLevel type specifies possible log levels.
+type LogLevel = v2.LogLevel
+
+// Snapshot struct
+type Snapshot = v2.Snapshot
+
+// BackendStoreSpecs represents a LXC storage backend.
+type BackendStoreSpecs = v2.BackendStoreSpecs
+
+// Personality allows to set the architecture for the container.
+type Personality = v2.Personality
+
+var (
+	// ErrNotDefined - container is not defined
+	ErrNotDefined error = v2.ErrNotDefined
+
+	// ErrNotRunning - container is not running
+	ErrNotRunning error = v2.ErrNotRunning
+
+	// ErrNotSupported - method is not supported by this LXC version
+	ErrNotSupported error = v2.ErrNotSupported
+)
+
+// Version returns the LXC version.
+func Version() string {
+	return v2.Version()
+}
@@ -0,0 +1,212 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VolatilePrefix is the prefix of the keys of the volatile config.
+const VolatilePrefix = "volatile."
+
+// volatileHWAddrKey returns the volatile key of the MAC of a network.
+func volatileHWAddrKey(index int) string {
+	return fmt.Sprintf("%snet.%d.hwaddr", VolatilePrefix, index)
+}
+
+// randomMAC returns a random MAC in the range liblxc generates them.
+func randomMAC() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("00:16:3e:%02x:%02x:%02x", b[0], b[1], b[2]), nil
+}
+
+// volatilePath returns the file holding the volatile config.
+//
+// Caller needs to hold the lock
+func (c *Container) volatilePath() string {
+	return filepath.Join(c.configPath(), c.name(), "volatile.json")
+}
+
+// volatile returns the volatile config.
+//
+// Caller needs to hold the lock
+func (c *Container) volatile() (map[string]string, error) {
+	values := make(map[string]string)
+
+	content, err := ioutil.ReadFile(c.volatilePath())
+	if os.IsNotExist(err) {
+		return values, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// saveVolatile records the volatile config, removing the file if empty.
+//
+// Caller needs to hold the lock
+func (c *Container) saveVolatile(values map[string]string) error {
+	path := c.volatilePath()
+
+	if len(values) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	content, err := json.MarshalIndent(values, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// applyVolatile sets the volatile MACs of the networks without a
+// configured one, generating the missing ones, so they are kept across
+// restarts without being written to the config. The returned function
+// clears them again once liblxc started the container.
+//
+// Caller needs to hold the lock
+func (c *Container) applyVolatile() (func(), error) {
+	values, err := c.volatile()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	restore := func() {
+		for _, key := range keys {
+			c.clearConfigItem(key)
+		}
+	}
+
+	changed := false
+	for i := 0; c.configItem(networkKey(i, "type"))[0] != ""; i++ {
+		switch NetworkType(c.configItem(networkKey(i, "type"))[0]) {
+		case NetworkVeth, NetworkMacvlan, NetworkVlan:
+		default:
+			continue
+		}
+
+		if c.configItem(networkKey(i, "hwaddr"))[0] != "" {
+			continue
+		}
+
+		mac, ok := values[volatileHWAddrKey(i)]
+		if !ok {
+			if mac, err = randomMAC(); err != nil {
+				return nil, err
+			}
+			values[volatileHWAddrKey(i)] = mac
+			changed = true
+		}
+
+		if err := c.setConfigItem(networkKey(i, "hwaddr"), mac); err != nil {
+			restore()
+			return nil, err
+		}
+		keys = append(keys, networkKey(i, "hwaddr"))
+	}
+
+	if changed {
+		if err := c.saveVolatile(values); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return restore, nil
+}
+
+// Volatile returns the volatile config of the container, the values
+// generated for it (e.g. volatile.net.0.hwaddr for the MAC of a network
+// without a configured one) or recorded by the caller (e.g. the last
+// state), kept next to its config instead of in it.
+func (c *Container) Volatile() (map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.container == nil {
+		return nil, ErrNotDefined
+	}
+
+	return c.volatile()
+}
+
+// SetVolatile records a volatile value, e.g. volatile.last_state, the
+// key must start with VolatilePrefix.
+func (c *Container) SetVolatile(key string, value string) (err error) {
+	finish, err := c.operation("SetVolatile", key, value)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if !strings.HasPrefix(key, VolatilePrefix) || key == VolatilePrefix {
+		return fmt.Errorf("%s: %q", ErrInvalidVolatileKey, key)
+	}
+
+	values, err := c.volatile()
+	if err != nil {
+		return err
+	}
+
+	values[key] = value
+	return c.saveVolatile(values)
+}
+
+// ResetVolatile removes the given volatile keys, all of them if none are
+// given. Generated values are generated again on the next start, e.g. a
+// network gets a new MAC.
+func (c *Container) ResetVolatile(keys ...string) (err error) {
+	finish, err := c.operation("ResetVolatile", keys...)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.container == nil {
+		return ErrNotDefined
+	}
+
+	if len(keys) == 0 {
+		return c.saveVolatile(nil)
+	}
+
+	values, err := c.volatile()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(values, key)
+	}
+	return c.saveVolatile(values)
+}
@@ -0,0 +1,506 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Volume is a named host directory, btrfs subvolume or zfs dataset which can
+// be bind mounted into several containers of an lxcpath.
+type Volume struct {
+	Name    string       `json:"name"`
+	Backend BackendStore `json:"backend"`
+	// Path is the directory on the host holding the data.
+	Path string `json:"path"`
+	// Dataset is the zfs dataset of the volume.
+	Dataset string    `json:"dataset,omitempty"`
+	Created time.Time `json:"created"`
+	// External is set for host directories registered with
+	// VolumeOptions.Path, their data is kept on DeleteVolume.
+	External bool `json:"external,omitempty"`
+	// IDMap is the idmap the files were shifted to on disk for the
+	// containers attaching the volume without idmapped mounts, empty if
+	// they are unshifted.
+	IDMap       IDMap              `json:"idmap,omitempty"`
+	Attachments []VolumeAttachment `json:"attachments,omitempty"`
+}
+
+// VolumeAttachment records a container the volume is attached to.
+type VolumeAttachment struct {
+	Container string `json:"container"`
+	// UUID is the UUID of the container, which identifies it after it was
+	// renamed.
+	UUID string `json:"uuid,omitempty"`
+	// Target is the path of the volume in the container.
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readonly,omitempty"`
+	// Idmapped is set if the volume is mounted idmapped instead of shifted
+	// on disk.
+	Idmapped bool `json:"idmapped,omitempty"`
+	// Shifted is set if the container relies on the files being shifted
+	// to Volume.IDMap.
+	Shifted bool `json:"shifted,omitempty"`
+}
+
+// attachedTo returns whether the attachment is of the container with the
+// name and the UUID, attachments recorded without UUID go by the name.
+func (a VolumeAttachment) attachedTo(name string, uuid string) bool {
+	if a.UUID != "" {
+		return a.UUID == uuid
+	}
+	return a.Container == name
+}
+
+// volumeDir returns the directory the volumes of lxcpath are stored in.
+func volumeDir(lxcpath string) string {
+	return filepath.Join(lxcpath, "volumes")
+}
+
+// loadVolume reads the metadata of the named volume of lxcpath.
+func loadVolume(lxcpath string, name string) (Volume, error) {
+	var volume Volume
+
+	if !validProfileName(name) {
+		return volume, fmt.Errorf("%s: %q", ErrInvalidVolume, name)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(volumeDir(lxcpath), name+".json"))
+	if os.IsNotExist(err) {
+		return volume, fmt.Errorf("%s: %q", ErrVolumeNotFound, name)
+	} else if err != nil {
+		return volume, err
+	}
+
+	if err := json.Unmarshal(content, &volume); err != nil {
+		return volume, fmt.Errorf("%s: %v", ErrInvalidVolume, err)
+	}
+	volume.Name = name
+	return volume, nil
+}
+
+// saveVolume writes the metadata of the volume.
+func saveVolume(lxcpath string, volume Volume) error {
+	content, err := json.MarshalIndent(volume, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(volumeDir(lxcpath), volume.Name+".json"), content, 0644)
+}
+
+// CreateVolume creates the named volume under lxcpath (default:
+// DefaultConfigPath()). Directory and btrfs volumes are created below the
+// volumes directory of lxcpath, zfs volumes as dataset below
+// VolumeOptions.ZFSRoot mounted there. Directory volumes may register an
+// existing host directory instead.
+func CreateVolume(name string, opts VolumeOptions, lxcpath ...string) (Volume, error) {
+	volume := Volume{Name: name, Backend: opts.Backend, Created: time.Now()}
+
+	if !validProfileName(name) {
+		return volume, fmt.Errorf("%s: %q", ErrInvalidVolume, name)
+	}
+
+	path := profileConfigPath(lxcpath)
+	dir := volumeDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return volume, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, name+".json")); err == nil {
+		return volume, fmt.Errorf("%s: %q", ErrVolumeExists, name)
+	}
+
+	volume.Path = filepath.Join(dir, name)
+	if opts.Path != "" {
+		if opts.Backend != Directory {
+			return volume, fmt.Errorf("%s: path requires the dir backend", ErrInvalidVolume)
+		}
+
+		info, err := os.Stat(opts.Path)
+		if err != nil {
+			return volume, err
+		}
+		if !info.IsDir() {
+			return volume, fmt.Errorf("%s: %q is not a directory", ErrInvalidVolume, opts.Path)
+		}
+		volume.Path, volume.External = opts.Path, true
+	}
+
+	var cmd *exec.Cmd
+	switch opts.Backend {
+	case Directory:
+		if !volume.External {
+			if err := os.Mkdir(volume.Path, 0755); err != nil {
+				return volume, err
+			}
+		}
+	case Btrfs:
+		cmd = exec.Command("btrfs", "subvolume", "create", volume.Path)
+	case ZFS:
+		root := opts.ZFSRoot
+		if root == "" {
+			root = DefaultZfsRoot()
+		}
+		volume.Dataset = root + "/" + name
+		cmd = exec.Command("zfs", "create", "-p", "-o", "mountpoint="+volume.Path, volume.Dataset)
+	default:
+		return volume, fmt.Errorf("%s: %q", ErrInvalidVolume, opts.Backend)
+	}
+
+	if cmd != nil {
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return volume, fmt.Errorf("%s: %s", ErrCreateVolumeFailed, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if err := saveVolume(path, volume); err != nil {
+		removeVolumeData(volume)
+		return volume, err
+	}
+	return volume, nil
+}
+
+// removeVolumeData removes the data of a volume, keeping external
+// directories.
+func removeVolumeData(volume Volume) error {
+	var cmd *exec.Cmd
+	switch {
+	case volume.External:
+		return nil
+	case volume.Backend == Btrfs:
+		cmd = exec.Command("btrfs", "subvolume", "delete", volume.Path)
+	case volume.Backend == ZFS:
+		cmd = exec.Command("zfs", "destroy", "-r", volume.Dataset)
+	default:
+		return os.RemoveAll(volume.Path)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", ErrDeleteVolumeFailed, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// LoadVolume returns the named volume of lxcpath.
+func LoadVolume(name string, lxcpath ...string) (Volume, error) {
+	return loadVolume(profileConfigPath(lxcpath), name)
+}
+
+// Volumes returns the volumes of lxcpath, sorted by name.
+func Volumes(lxcpath ...string) ([]Volume, error) {
+	path := profileConfigPath(lxcpath)
+
+	matches, err := filepath.Glob(filepath.Join(volumeDir(path), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	var volumes []Volume
+	for _, match := range matches {
+		volume, err := loadVolume(path, strings.TrimSuffix(filepath.Base(match), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// DeleteVolume removes the named volume of lxcpath and its data. It fails
+// with ErrVolumeInUse while the volume is attached to a defined container,
+// attachments of containers destroyed since are dropped.
+func DeleteVolume(name string, lxcpath ...string) error {
+	path := profileConfigPath(lxcpath)
+
+	volume, err := loadVolume(path, name)
+	if err != nil {
+		return err
+	}
+
+	defined, err := definedUUIDs(path)
+	if err != nil {
+		return err
+	}
+
+	if !volumeOrphaned(volume, defined) {
+		return fmt.Errorf("%s: %q", ErrVolumeInUse, name)
+	}
+
+	if err := removeVolumeData(volume); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(volumeDir(path), name+".json"))
+}
+
+// definedUUIDs returns the UUIDs of the defined containers of lxcpath by
+// name, empty for containers without UUID.
+func definedUUIDs(lxcpath string) (map[string]string, error) {
+	names, err := DefinedContainerNamesE(lxcpath)
+	if err != nil {
+		return nil, err
+	}
+
+	defined := make(map[string]string, len(names))
+	for _, name := range names {
+		if defined[name], err = readUUID(lxcpath, name); err != nil {
+			return nil, err
+		}
+	}
+	return defined, nil
+}
+
+// volumeOrphaned returns whether none of the containers the volume is
+// attached to is among the defined ones, given by name with their UUIDs.
+// Renamed containers are found by their UUID.
+func volumeOrphaned(volume Volume, defined map[string]string) bool {
+	for _, a := range volume.Attachments {
+		for name, uuid := range defined {
+			if a.attachedTo(name, uuid) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// OrphanVolumes returns the volumes of lxcpath which aren't attached to any
+// defined container, either never attached, detached from all of them or
+// left behind by destroyed containers.
+func OrphanVolumes(lxcpath ...string) ([]Volume, error) {
+	volumes, err := Volumes(lxcpath...)
+	if err != nil {
+		return nil, err
+	}
+
+	defined, err := definedUUIDs(profileConfigPath(lxcpath))
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Volume
+	for _, volume := range volumes {
+		if volumeOrphaned(volume, defined) {
+			orphans = append(orphans, volume)
+		}
+	}
+	return orphans, nil
+}
+
+// volumeMountEntry returns the lxc.mount.entry bind mounting the volume.
+func volumeMountEntry(volume Volume, a VolumeAttachment) string {
+	options := "bind,create=dir"
+	if a.ReadOnly {
+		options += ",ro"
+	}
+	if a.Idmapped {
+		options += ",idmap=container"
+	}
+	return fmt.Sprintf("%s %s none %s 0 0", volume.Path, strings.TrimPrefix(a.Target, "/"), options)
+}
+
+// AttachVolume bind mounts the named volume of the lxcpath of the container
+// into it, from the next start on. For unprivileged containers the volume
+// is mounted idmapped if requested and supported, otherwise its files are
+// shifted on disk to the idmap of the container. External volumes are never
+// shifted, they require idmapped mounts. All containers using a
+// shifted volume need the same idmap, ErrVolumeIDMapMismatch is returned
+// otherwise.
+func (c *Container) AttachVolume(name string, opts VolumeAttachOptions) (err error) {
+	finish, err := c.operation("AttachVolume", name, opts.Target)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(opts.Target, "/") {
+		return fmt.Errorf("%s: target %q is not absolute", ErrInvalidVolume, opts.Target)
+	}
+
+	volume, err := loadVolume(c.configPath(), name)
+	if err != nil {
+		return err
+	}
+
+	uuid, err := c.uuid()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range volume.Attachments {
+		if a.attachedTo(c.name(), uuid) {
+			return fmt.Errorf("%s: %q is attached already", ErrVolumeInUse, name)
+		}
+	}
+
+	idmap, err := c.idMap()
+	if err != nil {
+		return err
+	}
+
+	previous := volume
+
+	attachment := VolumeAttachment{Container: c.name(), UUID: uuid, Target: opts.Target, ReadOnly: opts.ReadOnly}
+	if len(idmap) > 0 {
+		if volume.External {
+			// shifting would chown an arbitrary directory of the host
+			if !IdmappedMountsSupported() {
+				return fmt.Errorf("%s: external volume %q requires idmapped mounts", ErrInvalidVolume, name)
+			}
+			attachment.Idmapped = true
+		} else if opts.UseIdmappedMounts && IdmappedMountsSupported() {
+			attachment.Idmapped = true
+		} else if len(volume.IDMap) == 0 {
+			if err := ShiftRootfs(volume.Path, idmap); err != nil {
+				return err
+			}
+			volume.IDMap = idmap
+			attachment.Shifted = true
+		} else if reflect.DeepEqual(volume.IDMap, idmap) {
+			attachment.Shifted = true
+		} else {
+			return fmt.Errorf("%s: %q", ErrVolumeIDMapMismatch, name)
+		}
+	}
+
+	// undo restores the volume as it was before on failure
+	undo := func(err error) error {
+		saveVolume(c.configPath(), previous)
+		if len(previous.IDMap) == 0 && len(volume.IDMap) > 0 {
+			UnshiftRootfs(volume.Path, volume.IDMap)
+		}
+		return err
+	}
+
+	volume.Attachments = append(volume.Attachments, attachment)
+	if err := saveVolume(c.configPath(), volume); err != nil {
+		return undo(err)
+	}
+
+	entry := volumeMountEntry(volume, attachment)
+	if err := c.setConfigItem("lxc.mount.entry", entry); err != nil {
+		return undo(err)
+	}
+
+	if err := c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config")); err != nil {
+		c.removeConfigValue("lxc.mount.entry", entry)
+		return undo(err)
+	}
+	return nil
+}
+
+// DetachVolume removes the mount of the named volume from the container.
+// The files of the volume are shifted back once no container relies on
+// them being shifted.
+func (c *Container) DetachVolume(name string) (err error) {
+	finish, err := c.operation("DetachVolume", name)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return err
+	}
+
+	volume, err := loadVolume(c.configPath(), name)
+	if err != nil {
+		return err
+	}
+
+	uuid, err := readUUID(c.configPath(), c.name())
+	if err != nil {
+		return err
+	}
+
+	var (
+		attachments []VolumeAttachment
+		detached    *VolumeAttachment
+		shifted     bool
+	)
+	for i, a := range volume.Attachments {
+		if a.attachedTo(c.name(), uuid) {
+			detached = &volume.Attachments[i]
+			continue
+		}
+		attachments = append(attachments, a)
+		shifted = shifted || a.Shifted
+	}
+
+	if detached == nil {
+		return fmt.Errorf("%s: %q is not attached", ErrVolumeNotFound, name)
+	}
+
+	if err := c.removeConfigValue("lxc.mount.entry", volumeMountEntry(volume, *detached)); err != nil {
+		return err
+	}
+
+	if err := c.saveConfigFile(filepath.Join(c.configPath(), c.name(), "config")); err != nil {
+		return err
+	}
+
+	if !shifted && len(volume.IDMap) > 0 {
+		if err := UnshiftRootfs(volume.Path, volume.IDMap); err != nil {
+			return err
+		}
+		volume.IDMap = nil
+	}
+
+	volume.Attachments = attachments
+	return saveVolume(c.configPath(), volume)
+}
+
+// AttachedVolumes returns the volumes of the lxcpath of the container
+// attached to it.
+func (c *Container) AttachedVolumes() ([]Volume, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.makeSure(isDefined); err != nil {
+		return nil, err
+	}
+
+	volumes, err := Volumes(c.configPath())
+	if err != nil {
+		return nil, err
+	}
+
+	uuid, err := readUUID(c.configPath(), c.name())
+	if err != nil {
+		return nil, err
+	}
+
+	var attached []Volume
+	for _, volume := range volumes {
+		for _, a := range volume.Attachments {
+			if a.attachedTo(c.name(), uuid) {
+				attached = append(attached, volume)
+				break
+			}
+		}
+	}
+	return attached, nil
+}
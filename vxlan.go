@@ -0,0 +1,187 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// vxlanMaxVNI is the largest 24 bit VXLAN network identifier.
+const vxlanMaxVNI = 1<<24 - 1
+
+// allZeroMAC is the FDB entry flooding broadcast and unknown traffic to a
+// peer.
+const allZeroMAC = "00:00:00:00:00:00"
+
+// VXLAN is an L2 overlay connecting the containers of several hosts. Each
+// host bridges a VXLAN interface with the veths of its containers and
+// floods broadcast traffic to the list of peers, so no multicast is needed
+// in the underlay.
+type VXLAN struct {
+	mu    sync.Mutex
+	opts  VXLANOptions
+	peers []string
+}
+
+// NewVXLAN returns the VXLAN overlay described by opts. It doesn't touch the
+// host until Up is called.
+func NewVXLAN(opts VXLANOptions) (*VXLAN, error) {
+	if opts.VNI <= 0 || opts.VNI > vxlanMaxVNI {
+		return nil, fmt.Errorf("%s: vni %d", ErrInvalidNetwork, opts.VNI)
+	}
+
+	if opts.Local != "" {
+		if _, err := netip.ParseAddr(opts.Local); err != nil {
+			return nil, fmt.Errorf("%s: %v", ErrInvalidNetwork, err)
+		}
+	}
+
+	if err := validatePeers(opts.Peers); err != nil {
+		return nil, err
+	}
+
+	if opts.Bridge == "" {
+		opts.Bridge = fmt.Sprintf("vxbr%d", opts.VNI)
+	}
+
+	if opts.Port == 0 {
+		opts.Port = 4789
+	}
+
+	if opts.MTU == 0 {
+		opts.MTU = 1450
+	}
+
+	return &VXLAN{opts: opts}, nil
+}
+
+func validatePeers(peers []string) error {
+	for _, peer := range peers {
+		if _, err := netip.ParseAddr(peer); err != nil {
+			return fmt.Errorf("%s: %v", ErrInvalidNetwork, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the VXLAN interface.
+func (v *VXLAN) Name() string {
+	return fmt.Sprintf("vxlan%d", v.opts.VNI)
+}
+
+// Bridge returns the name of the bridge the containers are attached to.
+func (v *VXLAN) Bridge() string {
+	return v.opts.Bridge
+}
+
+// upCommands returns the commands creating the interfaces, the bridge only
+// if it doesn't exist yet.
+func (v *VXLAN) upCommands(bridgeExists bool) [][]string {
+	var commands [][]string
+	if !bridgeExists {
+		commands = append(commands, []string{"ip", "link", "add", v.opts.Bridge, "type", "bridge"})
+	}
+
+	add := []string{"ip", "link", "add", v.Name(), "type", "vxlan", "id", strconv.Itoa(v.opts.VNI), "dstport", strconv.Itoa(v.opts.Port)}
+	if v.opts.Local != "" {
+		add = append(add, "local", v.opts.Local)
+	}
+	if v.opts.Device != "" {
+		add = append(add, "dev", v.opts.Device)
+	}
+
+	commands = append(commands,
+		add,
+		[]string{"ip", "link", "set", v.Name(), "mtu", strconv.Itoa(v.opts.MTU), "master", v.opts.Bridge, "up"},
+		[]string{"ip", "link", "set", v.opts.Bridge, "up"},
+	)
+	return commands
+}
+
+// peerCommands returns the commands changing the flooding entries from the
+// old to the new peers.
+func (v *VXLAN) peerCommands(old []string, new []string) [][]string {
+	keep := make(map[string]bool)
+	for _, peer := range new {
+		keep[peer] = true
+	}
+
+	present := make(map[string]bool)
+	var commands [][]string
+	for _, peer := range old {
+		present[peer] = true
+		if !keep[peer] {
+			commands = append(commands, []string{"bridge", "fdb", "del", allZeroMAC, "dev", v.Name(), "dst", peer})
+		}
+	}
+
+	for _, peer := range new {
+		if !present[peer] {
+			present[peer] = true
+			commands = append(commands, []string{"bridge", "fdb", "append", allZeroMAC, "dev", v.Name(), "dst", peer})
+		}
+	}
+	return commands
+}
+
+// Up creates the bridge, unless it exists, and the VXLAN interface and adds
+// the peers.
+func (v *VXLAN) Up() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, err := os.Stat(filepath.Join(sysClassNet, v.opts.Bridge))
+	commands := v.upCommands(err == nil)
+
+	if err := runCommands(append(commands, v.peerCommands(nil, v.opts.Peers)...)); err != nil {
+		return err
+	}
+
+	v.peers = v.opts.Peers
+	return nil
+}
+
+// Down removes the VXLAN interface and the bridge.
+func (v *VXLAN) Down() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.peers = nil
+	return runCommands([][]string{
+		{"ip", "link", "del", v.Name()},
+		{"ip", "link", "del", v.opts.Bridge},
+	})
+}
+
+// SetPeers replaces the peers of the overlay, e.g. when hosts join or leave.
+func (v *VXLAN) SetPeers(peers []string) error {
+	if err := validatePeers(peers); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := runCommands(v.peerCommands(v.peers, peers)); err != nil {
+		return err
+	}
+
+	v.opts.Peers = peers
+	v.peers = peers
+	return nil
+}
+
+// Attach adds a veth connected to the bridge of the overlay to the
+// container and returns its index. The device is created on the next start.
+func (v *VXLAN) Attach(c *Container) (int, error) {
+	return c.AddNetwork(Network{Type: NetworkVeth, Link: v.opts.Bridge, MTU: v.opts.MTU, Up: true})
+}
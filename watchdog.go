@@ -0,0 +1,223 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Watchdog monitors the heartbeat of a running container.
+type Watchdog struct {
+	c    *Container
+	opts WatchdogOptions
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// openInRoot opens path below root with O_PATH, resolving symlinks inside
+// root so a guest can't point them at files of the host. Without openat2 the
+// parents are resolved by resolvePath and a symlink as last component is
+// refused.
+func openInRoot(root string, path string) (*os.File, error) {
+	dir, err := os.OpenFile(root, unix.O_PATH|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	fd, err := unix.Openat2(int(dir.Fd()), path, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err == unix.ENOSYS {
+		resolved, err := resolvePath(root, path)
+		if err != nil {
+			return nil, err
+		}
+
+		fd, err = unix.Open(resolved, unix.O_PATH|unix.O_CLOEXEC|unix.O_NOFOLLOW, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var st unix.Stat_t
+		if err := unix.Fstat(fd, &st); err != nil || st.Mode&unix.S_IFMT == unix.S_IFLNK {
+			unix.Close(fd)
+			return nil, fmt.Errorf("%s: %q is a symlink", ErrUnsafeRootfsPath, path)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// checkHeartbeatFile returns an error if the given file below root wasn't
+// modified within timeout. Modifications before since are not required, so
+// a freshly (re)started guest gets a grace period.
+func checkHeartbeatFile(root string, path string, since time.Time, timeout time.Duration) error {
+	last := since
+	if f, err := openInRoot(root, path); err == nil {
+		var st unix.Stat_t
+		if err := unix.Fstat(int(f.Fd()), &st); err == nil && st.Mode&unix.S_IFMT == unix.S_IFREG {
+			if mtime := time.Unix(st.Mtim.Unix()); mtime.After(last) {
+				last = mtime
+			}
+		}
+		f.Close()
+	}
+
+	if time.Since(last) > timeout {
+		return fmt.Errorf("%s: %q", ErrWatchdogTimeout, path)
+	}
+	return nil
+}
+
+// pingHeartbeatSocket connects to the given unix socket below root and waits
+// for the first byte of the answer.
+func pingHeartbeatSocket(root string, path string, timeout time.Duration) error {
+	f, err := openInRoot(root, path)
+	if err != nil {
+		return fmt.Errorf("%s: %q", ErrWatchdogTimeout, path)
+	}
+	defer f.Close()
+
+	// connect through the opened socket, not through the path again
+	conn, err := net.DialTimeout("unix", fmt.Sprintf("/proc/self/fd/%d", f.Fd()), timeout)
+	if err != nil {
+		return fmt.Errorf("%s: %q", ErrWatchdogTimeout, path)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		return fmt.Errorf("%s: %q", ErrWatchdogTimeout, path)
+	}
+
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return fmt.Errorf("%s: %q", ErrWatchdogTimeout, path)
+	}
+	return nil
+}
+
+// check returns an error if the guest is considered hung.
+func (w *Watchdog) check(since time.Time) error {
+	pid := w.c.InitPid()
+	if pid <= 0 {
+		// the container stopped since it was seen running
+		return nil
+	}
+	root := fmt.Sprintf("/proc/%d/root", pid)
+
+	if w.opts.HeartbeatSocket != "" {
+		// Give the guest some time to set up the socket.
+		if time.Since(since) < w.opts.Timeout {
+			return nil
+		}
+
+		if err := pingHeartbeatSocket(root, w.opts.HeartbeatSocket, w.opts.Timeout); err != nil {
+			return err
+		}
+	}
+
+	if w.opts.HeartbeatFile != "" {
+		return checkHeartbeatFile(root, w.opts.HeartbeatFile, since, w.opts.Timeout)
+	}
+	return nil
+}
+
+func (w *Watchdog) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	since := time.Now()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if !w.c.Running() {
+			since = time.Now()
+			continue
+		}
+
+		err := w.check(since)
+		if err == nil {
+			continue
+		}
+
+		if w.opts.OnFailure != nil {
+			w.opts.OnFailure(w.c, err)
+		}
+
+		if w.opts.Action == WatchdogRestart {
+			if err := w.c.Stop(); err != nil && w.opts.OnFailure != nil {
+				w.opts.OnFailure(w.c, err)
+			}
+
+			if err := w.c.Start(); err != nil && w.opts.OnFailure != nil {
+				w.opts.OnFailure(w.c, err)
+			}
+		}
+		since = time.Now()
+	}
+}
+
+// Stop stops monitoring the container and waits for the watchdog to exit.
+func (w *Watchdog) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// Watchdog starts monitoring the heartbeat of the container in the
+// background. The guest is considered hung if the heartbeat file wasn't
+// touched or the heartbeat socket didn't answer within the timeout while the
+// container is RUNNING. Call Stop on the returned Watchdog to stop monitoring.
+func (c *Container) Watchdog(opts WatchdogOptions) (*Watchdog, error) {
+	if opts.HeartbeatFile == "" && opts.HeartbeatSocket == "" {
+		return nil, ErrInsufficientNumberOfArguments
+	}
+
+	if opts.Interval <= 0 || opts.Timeout <= 0 {
+		return nil, ErrInsufficientNumberOfArguments
+	}
+
+	c.mu.RLock()
+	if err := c.makeSure(isDefined); err != nil {
+		c.mu.RUnlock()
+		return nil, err
+	}
+	c.mu.RUnlock()
+
+	w := &Watchdog{
+		c:    c,
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+
+	return w, nil
+}
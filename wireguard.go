@@ -0,0 +1,314 @@
+// Copyright © 2013, 2014, The Go-LXC Authors. All rights reserved.
+// Use of this source code is governed by a LGPLv2.1
+// license that can be found in the LICENSE file.
+
+// +build linux,cgo
+
+package lxc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// WireGuardPeer is another host of a WireGuard mesh.
+type WireGuardPeer struct {
+	// PublicKey is the public key of the host, as printed by wg pubkey.
+	PublicKey string
+	// Endpoint is the address and port the host is reached at, e.g.
+	// 192.0.2.2:51820.
+	Endpoint string
+	// HostIndex is the number of the container subnet of the host.
+	HostIndex int
+}
+
+// WireGuardMesh connects the containers of several hosts over encrypted
+// WireGuard tunnels. Every host gets its own container subnet, routed
+// through the tunnel to the host owning it, and hands out the addresses of
+// the subnet to the containers attached to its bridge.
+type WireGuardMesh struct {
+	mu     sync.Mutex
+	opts   WireGuardOptions
+	subnet netip.Prefix
+	peers  []WireGuardPeer
+	leases map[string]netip.Addr
+}
+
+// meshSubnet returns the index-th subnet with the prefix length bits of the
+// IPv4 network.
+func meshSubnet(network netip.Prefix, bits int, index int) (netip.Prefix, error) {
+	if !network.Addr().Is4() || bits < network.Bits() || bits > 30 {
+		return netip.Prefix{}, fmt.Errorf("%s: /%d subnets of %s", ErrInvalidNetwork, bits, network)
+	}
+
+	if index < 0 || index >= 1<<(bits-network.Bits()) {
+		return netip.Prefix{}, fmt.Errorf("%s: subnet %d of %s", ErrInvalidNetwork, index, network)
+	}
+
+	base := network.Masked().Addr().As4()
+	n := binary.BigEndian.Uint32(base[:]) + uint32(index)<<(32-bits)
+
+	var addr [4]byte
+	binary.BigEndian.PutUint32(addr[:], n)
+	return netip.PrefixFrom(netip.AddrFrom4(addr), bits), nil
+}
+
+// NewWireGuardMesh returns the mesh described by opts. It doesn't touch the
+// host until Up is called.
+func NewWireGuardMesh(opts WireGuardOptions) (*WireGuardMesh, error) {
+	if opts.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("%s: no private key", ErrInvalidNetwork)
+	}
+
+	if opts.Interface == "" {
+		opts.Interface = "wg-lxc"
+	}
+
+	if opts.ListenPort == 0 {
+		opts.ListenPort = 51820
+	}
+
+	if opts.HostBits == 0 {
+		opts.HostBits = 24
+	}
+
+	if opts.Bridge == "" {
+		opts.Bridge = "wgbr0"
+	}
+
+	if opts.MTU == 0 {
+		opts.MTU = 1420
+	}
+
+	subnet, err := meshSubnet(opts.Subnet, opts.HostBits, opts.HostIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &WireGuardMesh{opts: opts, subnet: subnet, leases: make(map[string]netip.Addr)}
+	if err := m.validatePeers(opts.Peers); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *WireGuardMesh) validatePeers(peers []WireGuardPeer) error {
+	for _, peer := range peers {
+		if peer.PublicKey == "" {
+			return fmt.Errorf("%s: peer without public key", ErrInvalidNetwork)
+		}
+
+		if peer.HostIndex == m.opts.HostIndex {
+			return fmt.Errorf("%s: peer %s uses subnet %d of this host", ErrInvalidNetwork, peer.PublicKey, peer.HostIndex)
+		}
+
+		if _, err := meshSubnet(m.opts.Subnet, m.opts.HostBits, peer.HostIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subnet returns the container subnet of the host.
+func (m *WireGuardMesh) Subnet() netip.Prefix {
+	return m.subnet
+}
+
+// Gateway returns the address of the bridge, the default gateway of the
+// containers.
+func (m *WireGuardMesh) Gateway() netip.Addr {
+	return m.subnet.Addr().Next()
+}
+
+// upCommands returns the commands creating the interfaces of the host, the
+// bridge only if it doesn't exist yet.
+func (m *WireGuardMesh) upCommands(bridgeExists bool) [][]string {
+	commands := [][]string{
+		{"ip", "link", "add", m.opts.Interface, "type", "wireguard"},
+		{"wg", "set", m.opts.Interface, "listen-port", strconv.Itoa(m.opts.ListenPort), "private-key", m.opts.PrivateKeyFile},
+		{"ip", "link", "set", m.opts.Interface, "up"},
+	}
+
+	if !bridgeExists {
+		commands = append(commands, []string{"ip", "link", "add", m.opts.Bridge, "type", "bridge"})
+	}
+
+	return append(commands,
+		[]string{"ip", "addr", "replace", netip.PrefixFrom(m.Gateway(), m.subnet.Bits()).String(), "dev", m.opts.Bridge},
+		[]string{"ip", "link", "set", m.opts.Bridge, "up"},
+	)
+}
+
+// peerCommands returns the commands changing the tunnels and routes from the
+// old to the new peers.
+func (m *WireGuardMesh) peerCommands(old []WireGuardPeer, new []WireGuardPeer) [][]string {
+	keep := make(map[WireGuardPeer]bool)
+	for _, peer := range new {
+		keep[peer] = true
+	}
+
+	var commands [][]string
+	for _, peer := range old {
+		if keep[peer] {
+			continue
+		}
+
+		subnet, _ := meshSubnet(m.opts.Subnet, m.opts.HostBits, peer.HostIndex)
+		commands = append(commands,
+			[]string{"ip", "route", "del", subnet.String(), "dev", m.opts.Interface},
+			[]string{"wg", "set", m.opts.Interface, "peer", peer.PublicKey, "remove"},
+		)
+	}
+
+	present := make(map[WireGuardPeer]bool)
+	for _, peer := range old {
+		present[peer] = true
+	}
+
+	for _, peer := range new {
+		if present[peer] {
+			continue
+		}
+		present[peer] = true
+
+		subnet, _ := meshSubnet(m.opts.Subnet, m.opts.HostBits, peer.HostIndex)
+		set := []string{"wg", "set", m.opts.Interface, "peer", peer.PublicKey, "allowed-ips", subnet.String()}
+		if peer.Endpoint != "" {
+			set = append(set, "endpoint", peer.Endpoint)
+		}
+
+		commands = append(commands,
+			set,
+			[]string{"ip", "route", "replace", subnet.String(), "dev", m.opts.Interface},
+		)
+	}
+	return commands
+}
+
+// Up creates the WireGuard interface and the bridge, unless it exists, sets
+// up the tunnels and routes to the peers and enables IPv4 forwarding.
+func (m *WireGuardMesh) Up() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := os.Stat(filepath.Join(sysClassNet, m.opts.Bridge))
+	commands := m.upCommands(err == nil)
+
+	if err := runCommands(append(commands, m.peerCommands(nil, m.opts.Peers)...)); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1\n"), 0644); err != nil {
+		return err
+	}
+
+	m.peers = m.opts.Peers
+	return nil
+}
+
+// Down removes the WireGuard interface, with it the routes to the peers, and
+// the bridge.
+func (m *WireGuardMesh) Down() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.peers = nil
+	return runCommands([][]string{
+		{"ip", "link", "del", m.opts.Interface},
+		{"ip", "link", "del", m.opts.Bridge},
+	})
+}
+
+// SetPeers replaces the peers of the mesh, e.g. when hosts join or leave.
+func (m *WireGuardMesh) SetPeers(peers []WireGuardPeer) error {
+	if err := m.validatePeers(peers); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := runCommands(m.peerCommands(m.peers, peers)); err != nil {
+		return err
+	}
+
+	m.opts.Peers = peers
+	m.peers = peers
+	return nil
+}
+
+// lease returns the address of the container, allocating the lowest free
+// one of the subnet after the gateway.
+//
+// Caller needs to hold the lock
+func (m *WireGuardMesh) lease(name string) (netip.Addr, error) {
+	if addr, ok := m.leases[name]; ok {
+		return addr, nil
+	}
+
+	used := make(map[netip.Addr]bool)
+	for _, addr := range m.leases {
+		used[addr] = true
+	}
+
+	for addr := m.Gateway().Next(); m.subnet.Contains(addr); addr = addr.Next() {
+		// skip the broadcast address
+		if !m.subnet.Contains(addr.Next()) {
+			break
+		}
+
+		if !used[addr] {
+			m.leases[name] = addr
+			return addr, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("%s: subnet %s is full", ErrInvalidNetwork, m.subnet)
+}
+
+// Attach adds a veth connected to the bridge of the host to the container,
+// with an address of the container subnet of the host and the bridge as
+// gateway, and returns its index. The device is created on the next start.
+func (m *WireGuardMesh) Attach(c *Container) (int, error) {
+	if !VersionAtLeast(2, 1, 0) {
+		return -1, ErrNotSupported
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	addr, err := m.lease(c.Name())
+	if err != nil {
+		return -1, err
+	}
+
+	index, err := c.AddNetwork(Network{Type: NetworkVeth, Link: m.opts.Bridge, MTU: m.opts.MTU, Up: true})
+	if err != nil {
+		delete(m.leases, c.Name())
+		return -1, err
+	}
+
+	items := []KeyValue{
+		{networkKey(index, "ipv4.address"), netip.PrefixFrom(addr, m.subnet.Bits()).String()},
+		{networkKey(index, "ipv4.gateway"), m.Gateway().String()},
+	}
+	for _, kv := range items {
+		if err := c.SetConfigItem(kv.Key, kv.Value); err != nil {
+			return -1, err
+		}
+	}
+	return index, nil
+}
+
+// Release frees the address leased to the container by Attach.
+func (m *WireGuardMesh) Release(c *Container) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.leases, c.Name())
+}